@@ -0,0 +1,253 @@
+#!/usr/bin/env python3
+"""
+All-in EV: luck-adjusted results for hands that were all-in before the river.
+
+When the money went in with an all-in and at least two players reached
+showdown with known cards, `allin_result` takes each live player's equity at
+that moment (the board as it stood after the last action) and turns it into
+an expected result: every main/side pot is shared by the equity of the
+players eligible for it, minus what the player put in. Rake is taken off
+proportionally, so EV and actual results are on the same footing.
+
+`session_summaries` groups a player's hands into sessions (gaps longer than
+`gap_minutes` start a new one) and reports actual vs EV-adjusted net per
+session; the difference is the luck.
+
+Example:
+    python3 allin_ev.py --db hands.sqlite --player Hero
+    python3 pokertools.py ev --db hands.sqlite --player Hero --gap 45
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+
+from equity import calculate_equity
+from handdb import HandDB, default_db_path
+from handhistory import (
+    BOARD_SIZES,
+    DATE_PATTERN,
+    FORCED_ACTIONS,
+    TIMESTAMP_FORMAT,
+    Hand,
+)
+from pots import build_pots
+
+
+DEFAULT_EV_ITERATIONS = 20_000
+EV_EXHAUSTIVE_LIMIT = 500_000
+DEFAULT_SESSION_GAP = 30
+
+
+@dataclass
+class AllInResult:
+    site: str
+    hand_id: str
+    street: str
+    board: List[str]
+    equities: Dict[str, float]
+    net: Dict[str, float]
+    ev_net: Dict[str, float]
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+def _cents(amount: float) -> int:
+    return int(round(amount * 100))
+
+
+def allin_result(
+    hand: Hand, iterations: int = DEFAULT_EV_ITERATIONS
+) -> Optional[AllInResult]:
+    """EV-adjusted results for an all-in hand, or None if it does not qualify"""
+    folded = {action.player for action in hand.actions if action.kind == "fold"}
+    dealt = [player for player in hand.players if not player.sitting_out]
+    live = [player.name for player in dealt if player.name not in folded]
+    if len(live) < 2 or any(not hand.player(name).hole_cards for name in live):
+        return None
+    if not any(action.all_in for action in hand.actions if action.player in live):
+        return None
+
+    voluntary = [action for action in hand.actions if action.kind not in FORCED_ACTIONS]
+    street = voluntary[-1].street if voluntary else "preflop"
+    board = hand.board[: BOARD_SIZES[street]]
+    dead = "".join(
+        card
+        for player in hand.players
+        if player.name in folded
+        for card in player.hole_cards
+    )
+
+    contributions = hand.contributions()
+    dead_money = hand.dead_money()
+    names = list(contributions)
+    pots = build_pots(
+        [_cents(contributions[name]) for name in names],
+        [name not in live for name in names],
+        [_cents(dead_money[name]) for name in names],
+    )
+    pot_total = sum(pot.amount for pot in pots)
+    paid_out = _cents(sum(hand.collected.values()))
+    after_rake = paid_out / pot_total if pot_total else 1.0
+
+    cache: Dict[Tuple[str, ...], List[float]] = {}
+    expected = {name: 0.0 for name in names}
+    equities: Dict[str, float] = {}
+    for pot in pots:
+        eligible = tuple(names[idx] for idx in pot.eligible)
+        if len(eligible) == 1:
+            shares = [1.0]
+        else:
+            if eligible not in cache:
+                cache[eligible] = calculate_equity(
+                    ["".join(hand.player(name).hole_cards) for name in eligible],
+                    board="".join(board),
+                    dead=dead,
+                    game=hand.game,
+                    iterations=iterations,
+                    exhaustive_limit=EV_EXHAUSTIVE_LIMIT,
+                    workers=1,
+                    seed=0,
+                ).equities
+            shares = cache[eligible]
+            # The main pot (every live player eligible) is the headline equity
+            if len(eligible) == len(live):
+                equities = dict(zip(eligible, shares))
+        for name, share in zip(eligible, shares):
+            expected[name] += pot.amount * share * after_rake / 100
+
+    net = hand.net()
+    ev_net = {
+        name: round(expected[name] - contributions[name], 2)
+        if name in live
+        else net[name]
+        for name in names
+    }
+    return AllInResult(
+        site=hand.site,
+        hand_id=hand.hand_id,
+        street=street,
+        board=board,
+        equities={name: round(share, 4) for name, share in equities.items()},
+        net=net,
+        ev_net=ev_net,
+    )
+
+
+@dataclass
+class SessionSummary:
+    start: str
+    end: str
+    hands: int = 0
+    allins: int = 0
+    net: float = 0.0
+    ev_net: float = 0.0
+    net_bb: float = 0.0
+    ev_net_bb: float = 0.0
+
+    @property
+    def luck(self) -> float:
+        """Actual minus expected: positive means the player ran above EV"""
+        return round(self.net - self.ev_net, 2)
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        for name in ("net", "ev_net", "net_bb", "ev_net_bb"):
+            data[name] = round(data[name], 2)
+        data["luck"] = self.luck
+        return data
+
+
+def _started(hand: Hand) -> Optional[datetime]:
+    match = DATE_PATTERN.search(hand.started_at or "")
+    if not match:
+        return None
+    return datetime.strptime(match.group(1), TIMESTAMP_FORMAT)
+
+
+def session_summaries(
+    hands: Iterable[Hand],
+    player: str,
+    gap_minutes: int = DEFAULT_SESSION_GAP,
+    iterations: int = DEFAULT_EV_ITERATIONS,
+) -> List[SessionSummary]:
+    """Actual vs all-in EV results per session, hands in chronological order"""
+    sessions: List[SessionSummary] = []
+    last_time: Optional[datetime] = None
+    gap = timedelta(minutes=gap_minutes)
+    for hand in hands:
+        seat = hand.player(player)
+        if seat is None or seat.sitting_out:
+            continue
+        moment = _started(hand)
+        label = moment.strftime(TIMESTAMP_FORMAT) if moment else ""
+        if not sessions or (moment and last_time and moment - last_time > gap):
+            sessions.append(SessionSummary(start=label, end=label))
+        if moment:
+            last_time = moment
+            sessions[-1].end = label
+        current = sessions[-1]
+        net = hand.net().get(player, 0.0)
+        ev_net = net
+        result = allin_result(hand, iterations)
+        if result is not None and player in result.equities:
+            current.allins += 1
+            ev_net = result.ev_net[player]
+        current.hands += 1
+        current.net += net
+        current.ev_net += ev_net
+        if hand.big_blind:
+            current.net_bb += net / hand.big_blind
+            current.ev_net_bb += ev_net / hand.big_blind
+    return sessions
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--player", required=True, help="Whose results to report")
+    parser.add_argument(
+        "--gap",
+        type=int,
+        default=DEFAULT_SESSION_GAP,
+        help="Minutes without a hand that end a session",
+    )
+    parser.add_argument("--iterations", type=int, default=DEFAULT_EV_ITERATIONS)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        hands = db.hands(
+            "h.id IN (SELECT hand_pk FROM hand_players WHERE name = ?)",
+            (args.player,),
+        )
+        sessions = session_summaries(hands, args.player, args.gap, args.iterations)
+    if args.json:
+        print(json.dumps([session.to_dict() for session in sessions], indent=2))
+        return
+    print(
+        f"{'Start':<19} {'End':<19} {'Hands':>6} {'All-ins':>7} "
+        f"{'Net':>10} {'EV net':>10} {'Luck':>10}"
+    )
+    for session in sessions:
+        print(
+            f"{session.start or '-':<19} {session.end or '-':<19} "
+            f"{session.hands:>6} {session.allins:>7} {session.net:>+10.2f} "
+            f"{session.ev_net:>+10.2f} {session.luck:>+10.2f}"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="All-in EV adjusted results")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
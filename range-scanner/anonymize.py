@@ -0,0 +1,140 @@
+#!/usr/bin/env python3
+"""
+Anonymize parsed hand histories before sharing them.
+
+An `Anonymizer` replaces player names, table names, hand/tournament ids, and
+timestamps in `handhistory.Hand` objects. Aliases are consistent for the
+lifetime of the anonymizer, so the same opponent stays "Player3" across every
+hand of a session. With a `salt`, aliases are derived from a keyed hash instead
+and stay stable across sessions without storing the mapping.
+
+Timestamps are stripped by default; `timestamps="shift"` keeps the gaps
+between hands but moves the session to 2000/01/01.
+
+Example:
+    python3 anonymize.py hands/session.txt --keep-hero > shared.jsonl
+"""
+
+from __future__ import annotations
+
+import argparse
+import copy
+import hashlib
+import hmac
+import json
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional
+
+from handhistory import (
+    DATE_PATTERN,
+    TIMESTAMP_FORMAT,
+    Hand,
+    iter_history_files,
+    parse_file,
+)
+
+
+SHIFT_BASE = datetime(2000, 1, 1)
+TIMESTAMP_MODES = ("strip", "shift", "keep")
+
+
+class Anonymizer:
+    """Consistent pseudonyms for names, tables, ids, and times"""
+
+    def __init__(
+        self,
+        salt: Optional[str] = None,
+        keep_hero: bool = False,
+        timestamps: str = "strip",
+    ):
+        if timestamps not in TIMESTAMP_MODES:
+            raise ValueError(f"timestamps must be one of {', '.join(TIMESTAMP_MODES)}")
+        self.salt = salt
+        self.keep_hero = keep_hero
+        self.timestamps = timestamps
+        self.aliases: Dict[str, Dict[str, str]] = {
+            kind: {} for kind in ("player", "table", "hand", "tournament")
+        }
+        self._first_time: Optional[datetime] = None
+
+    def alias(self, kind: str, value: str, prefix: str) -> str:
+        mapping = self.aliases[kind]
+        if value not in mapping:
+            if self.salt is not None:
+                digest = hmac.new(
+                    self.salt.encode(), f"{kind}:{value}".encode(), hashlib.sha256
+                ).hexdigest()
+                mapping[value] = f"{prefix}-{digest[:8]}"
+            else:
+                mapping[value] = f"{prefix}{len(mapping) + 1}"
+        return mapping[value]
+
+    def player(self, name: str, hero: Optional[str]) -> str:
+        if name == hero:
+            return name if self.keep_hero else "Hero"
+        return self.alias("player", name, "Player")
+
+    def timestamp(self, value: str) -> str:
+        if self.timestamps == "keep" or not value:
+            return value
+        if self.timestamps == "strip":
+            return ""
+        match = DATE_PATTERN.search(value)
+        if not match:
+            return ""
+        moment = datetime.strptime(match.group(1), TIMESTAMP_FORMAT)
+        if self._first_time is None:
+            self._first_time = moment
+        return (SHIFT_BASE + (moment - self._first_time)).strftime(TIMESTAMP_FORMAT)
+
+    def anonymize(self, hand: Hand) -> Hand:
+        """Return an anonymized copy; the original hand is left untouched"""
+        result = copy.deepcopy(hand)
+        hero = hand.hero
+
+        def rename(name: str) -> str:
+            return self.player(name, hero)
+
+        for player in result.players:
+            player.name = rename(player.name)
+        for action in result.actions:
+            action.player = rename(action.player)
+        result.collected = {
+            rename(name): value for name, value in hand.collected.items()
+        }
+        result.uncalled = {rename(name): value for name, value in hand.uncalled.items()}
+        if hero:
+            result.hero = rename(hero)
+        if hand.table:
+            result.table = self.alias("table", hand.table, "Table")
+        result.hand_id = self.alias("hand", hand.hand_id, "Hand")
+        if hand.tournament_id is not None:
+            result.tournament_id = self.alias(
+                "tournament", hand.tournament_id, "Tournament"
+            )
+        result.started_at = self.timestamp(hand.started_at)
+        return result
+
+    def anonymize_all(self, hands: Iterable[Hand]) -> List[Hand]:
+        return [self.anonymize(hand) for hand in hands]
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Anonymize hand histories")
+    parser.add_argument("paths", nargs="+", type=Path, help="Files or directories")
+    parser.add_argument("--salt", help="Derive stable hashed aliases from this key")
+    parser.add_argument(
+        "--keep-hero", action="store_true", help="Keep the hero's own screen name"
+    )
+    parser.add_argument("--timestamps", choices=TIMESTAMP_MODES, default="strip")
+    args = parser.parse_args()
+
+    anonymizer = Anonymizer(args.salt, args.keep_hero, args.timestamps)
+    for path in iter_history_files(args.paths):
+        for hand in parse_file(path):
+            print(json.dumps(anonymizer.anonymize(hand).to_dict()))
+
+
+if __name__ == "__main__":
+    main()
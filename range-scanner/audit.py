@@ -0,0 +1,194 @@
+#!/usr/bin/env python3
+"""
+Append-only audit log of access to sensitive HTTP routes.
+
+`sensitive_action` names the routes worth a record: hand exports, hand
+searches, replays, and GraphQL queries (they show every player's hole cards),
+the bankroll, ledger, and exchange-rate routes, API key management, and
+reading this log. The API server records each such request once its status is
+known, refused ones (401, 403) included, with the key that made it (its name
+and id, or "anonymous" when `--auth` is off), the client address, and the
+resource with any `key=` parameter removed.
+
+The log lives in the hand database (migration 8), where triggers reject any
+UPDATE or DELETE on it, so entries can only be added. Admins read it with
+`GET /api/audit?actor=ops&action=export&since=2024-01-01` or the CLI.
+
+Example:
+    python3 pokertools.py audit --db hands.sqlite --action export
+    python3 pokertools.py audit --actor ops --since 2024-01-01 --json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import re
+from dataclasses import asdict, astuple, dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import List, Optional, Pattern, Sequence, Tuple
+from urllib.parse import parse_qsl, urlencode, urlparse
+
+from handdb import HandDB, default_db_path
+from money import stamp
+
+
+ANONYMOUS = "anonymous"
+DEFAULT_ENTRIES = 100
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+
+# (methods, path pattern, action); the first match wins
+SENSITIVE_ROUTES: List[Tuple[Sequence[str], Pattern, str]] = [
+    (("GET",), re.compile(r"^/api/export$"), "export"),
+    (("GET",), re.compile(r"^/api/hands/[^/]+/replay$"), "hand_replay"),
+    (("GET",), re.compile(r"^/api/hands$"), "hand_search"),
+    (("GET", "POST"), re.compile(r"^/api/graphql$"), "graphql"),
+    (("GET", "POST", "DELETE"), re.compile(r"^/api/keys(/\d+)?$"), "api_keys"),
+    (("GET",), re.compile(r"^/api/audit$"), "audit_read"),
+    (("GET", "POST"), re.compile(r"^/api/(bankroll|ledger|rates)(/|$)"), "money"),
+]
+
+
+def sensitive_action(method: str, path: str) -> Optional[str]:
+    """The audit action for a request, or None when it is not logged"""
+    for methods, pattern, action in SENSITIVE_ROUTES:
+        if method in methods and pattern.match(path):
+            return action
+    return None
+
+
+def redact(target: str) -> str:
+    """Request path and query without the `key` parameter"""
+    parsed = urlparse(target)
+    query = [(name, value) for name, value in parse_qsl(parsed.query) if name != "key"]
+    return parsed.path + (f"?{urlencode(query)}" if query else "")
+
+
+@dataclass
+class AuditEntry:
+    id: int
+    at: str
+    actor: str
+    key_id: Optional[int]
+    remote: str
+    method: str
+    action: str
+    resource: str
+    status: int
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+ENTRY_COLUMNS = "id, at, actor, key_id, remote, method, action, resource, status"
+
+
+class AuditLog:
+    """The audit log on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+
+    def record(
+        self,
+        actor: str,
+        method: str,
+        action: str,
+        resource: str,
+        status: int,
+        key_id: Optional[int] = None,
+        remote: str = "",
+    ) -> AuditEntry:
+        entry = AuditEntry(
+            0,
+            datetime.now().strftime(STORED_FORMAT),
+            actor,
+            key_id,
+            remote,
+            method,
+            action,
+            redact(resource),
+            status,
+        )
+        with self.conn:
+            cursor = self.conn.execute(
+                "INSERT INTO audit_log "
+                "(at, actor, key_id, remote, method, action, resource, status) "
+                "VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+                astuple(entry)[1:],
+            )
+        entry.id = cursor.lastrowid
+        return entry
+
+    def entries(
+        self,
+        actor: Optional[str] = None,
+        action: Optional[str] = None,
+        since: Optional[str] = None,
+        until: Optional[str] = None,
+        limit: int = DEFAULT_ENTRIES,
+    ) -> List[AuditEntry]:
+        """Newest first"""
+        clauses, params = [], []
+        if actor:
+            clauses.append("actor = ?")
+            params.append(actor)
+        if action:
+            clauses.append("action = ?")
+            params.append(action)
+        if since:
+            clauses.append("at >= ?")
+            params.append(stamp(since))
+        if until:
+            clauses.append("at <= ?")
+            params.append(stamp(until))
+        if limit < 1:
+            raise ValueError("limit must be positive")
+        where = f"WHERE {' AND '.join(clauses)}" if clauses else ""
+        rows = self.conn.execute(
+            f"SELECT {ENTRY_COLUMNS} FROM audit_log {where} "
+            "ORDER BY id DESC LIMIT ?",
+            (*params, limit),
+        ).fetchall()
+        return [AuditEntry(*row) for row in rows]
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--actor", help="Key name, or anonymous")
+    parser.add_argument(
+        "--action", choices=sorted({action for _, _, action in SENSITIVE_ROUTES})
+    )
+    parser.add_argument("--since", help="Entries at or after this time")
+    parser.add_argument("--until", help="Entries at or before this time")
+    parser.add_argument("--limit", type=int, default=DEFAULT_ENTRIES)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        with HandDB(args.db) as db:
+            entries = AuditLog(db).entries(
+                args.actor, args.action, args.since, args.until, args.limit
+            )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps([entry.to_dict() for entry in entries], indent=2))
+        return
+    for entry in entries:
+        print(
+            f"{entry.at}  {entry.status}  {entry.actor:<16} {entry.remote:<15} "
+            f"{entry.method:<6} {entry.action:<12} {entry.resource}"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Audit log of sensitive access")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
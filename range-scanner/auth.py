@@ -0,0 +1,256 @@
+#!/usr/bin/env python3
+"""
+Role-based access for the HTTP API: roles, hashed API keys, and route rules.
+
+Roles are ordered and each may do everything the ones before it may:
+
+- viewer: health, calculators (equity, odds, strength, blockers, variance,
+  payouts, deal), range heatmaps, fair-deal audits, and the tournament clock
+  and seating reads and SSE streams
+- player: dealing provably fair hands and the preflop trainer
+- commentator: hand database reads (hands, stats, lines, graphs, replays,
+  notes, charts, table pace), the DuckDB range queries, solver jobs, and
+  the background job queue
+- producer: running the tournament (clock, structure, seating) and editing
+  notes and charts
+- admin: money (bankroll, ledger, rates, export), the API keys themselves,
+  and the audit log
+
+`required_role` maps a request to the least role that may make it; routes no
+rule covers need admin, so a new endpoint is closed until someone opens it.
+Keys live in the hand database (migration 7) as sha256 digests only; the
+secret is shown once, when the key is created. They are random 32-byte
+tokens, so a fast hash is enough: there is nothing to brute-force. A key's
+`last_used_at` is only written when the stored time is over
+LAST_USED_RESOLUTION seconds old, so authenticated reads stay reads.
+
+`api serve --auth` turns enforcement on. Clients send the key as
+`Authorization: Bearer <key>` or `X-API-Key: <key>`, or as `?key=` where a
+header cannot be set (browser EventSource streams).
+
+Example:
+    python3 pokertools.py keys create --name ops --role admin
+    python3 pokertools.py api serve --hands-db hands.sqlite --auth
+    curl -H "X-API-Key: pt_..." localhost:8080/api/stats?player=Hero
+"""
+
+from __future__ import annotations
+
+import argparse
+import hashlib
+import json
+import re
+import secrets
+from dataclasses import asdict, dataclass
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import List, Optional, Pattern, Sequence, Tuple
+
+from handdb import HandDB, default_db_path
+
+
+ROLES = ("viewer", "player", "commentator", "producer", "admin")
+KEY_PREFIX = "pt_"
+# Characters of the secret kept in clear to tell keys apart in listings
+SHOWN_CHARACTERS = 8
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+LAST_USED_RESOLUTION = 60
+PUBLIC = None
+
+# (methods, path pattern, least role); the first match wins
+ROUTE_RULES: List[Tuple[Sequence[str], Pattern, Optional[str]]] = [
+    (("GET",), re.compile(r"^/health$"), PUBLIC),
+    (
+        ("GET", "POST", "DELETE"),
+        re.compile(r"^/api/(keys(/\d+)?|audit)$"),
+        "admin",
+    ),
+    (
+        ("GET", "POST"),
+        re.compile(r"^/api/(bankroll|ledger|rates|export)(/|$)"),
+        "admin",
+    ),
+    (("POST", "PUT"), re.compile(r"^/api/tourney/"), "producer"),
+    (("PUT",), re.compile(r"^/api/players/[^/]+/notes$"), "producer"),
+    (("POST",), re.compile(r"^/api/charts$"), "producer"),
+    (
+        ("GET",),
+        re.compile(
+            r"^/(ranges$|api/(hands|stats|lines|graphs|players|charts|pace)(/|$))"
+        ),
+        "commentator",
+    ),
+    (("GET", "POST"), re.compile(r"^/api/graphql(/schema)?$"), "commentator"),
+    (("GET", "POST"), re.compile(r"^/api/solver(/|$)"), "commentator"),
+    (("GET", "POST", "DELETE"), re.compile(r"^/api/jobs(/|$)"), "commentator"),
+    (("POST",), re.compile(r"^/api/fairdeal/verify$"), "viewer"),
+    (("GET", "POST"), re.compile(r"^/api/(fairdeal|trainer)(/|$)"), "player"),
+    (
+        ("GET", "POST"),
+        re.compile(
+            r"^/api/(equity|equity/stream|strength|blockers|variance|payouts|deal)$"
+        ),
+        "viewer",
+    ),
+    (("GET",), re.compile(r"^/api/range/heatmap$"), "viewer"),
+    (("GET",), re.compile(r"^/api/tourney/"), "viewer"),
+]
+
+
+def required_role(method: str, path: str) -> Optional[str]:
+    """Least role allowed to make the request; None when it needs no key"""
+    for methods, pattern, role in ROUTE_RULES:
+        if method in methods and pattern.match(path):
+            return role
+    return "admin"
+
+
+def allows(role: str, required: Optional[str]) -> bool:
+    if required is None:
+        return True
+    return ROLES.index(role) >= ROLES.index(required)
+
+
+def _digest(secret: str) -> str:
+    return hashlib.sha256(secret.encode("utf-8")).hexdigest()
+
+
+def _now() -> str:
+    return datetime.now().strftime(STORED_FORMAT)
+
+
+@dataclass
+class ApiKey:
+    id: int
+    name: str
+    role: str
+    prefix: str
+    created_at: str
+    last_used_at: Optional[str] = None
+    revoked_at: Optional[str] = None
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+KEY_COLUMNS = "id, name, role, prefix, created_at, last_used_at, revoked_at"
+
+
+class ApiKeys:
+    """API keys on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+
+    def create(self, name: str, role: str) -> Tuple[ApiKey, str]:
+        """The new key and its secret, which is not stored anywhere"""
+        name = str(name).strip()
+        if not name:
+            raise ValueError("A key needs a name")
+        if role not in ROLES:
+            raise ValueError(f"Role must be one of {', '.join(ROLES)}")
+        secret = KEY_PREFIX + secrets.token_urlsafe(32)
+        with self.conn:
+            cursor = self.conn.execute(
+                "INSERT INTO api_keys (name, role, key_hash, prefix, created_at) "
+                "VALUES (?, ?, ?, ?, ?)",
+                (name, role, _digest(secret), secret[:SHOWN_CHARACTERS], _now()),
+            )
+        return self.get(cursor.lastrowid), secret
+
+    def get(self, key_id: int) -> ApiKey:
+        row = self.conn.execute(
+            f"SELECT {KEY_COLUMNS} FROM api_keys WHERE id = ?", (key_id,)
+        ).fetchone()
+        if row is None:
+            raise KeyError(f"No API key {key_id}")
+        return ApiKey(*row)
+
+    def list(self) -> List[ApiKey]:
+        rows = self.conn.execute(
+            f"SELECT {KEY_COLUMNS} FROM api_keys ORDER BY id"
+        ).fetchall()
+        return [ApiKey(*row) for row in rows]
+
+    def revoke(self, key_id: int) -> ApiKey:
+        key = self.get(key_id)
+        if not key.revoked_at:
+            with self.conn:
+                self.conn.execute(
+                    "UPDATE api_keys SET revoked_at = ? WHERE id = ?", (_now(), key_id)
+                )
+        return self.get(key_id)
+
+    def authenticate(self, secret: Optional[str]) -> Optional[ApiKey]:
+        """The live key behind `secret`, or None"""
+        if not secret:
+            return None
+        row = self.conn.execute(
+            f"SELECT {KEY_COLUMNS} FROM api_keys "
+            "WHERE key_hash = ? AND revoked_at IS NULL",
+            (_digest(secret),),
+        ).fetchone()
+        if row is None:
+            return None
+        key = ApiKey(*row)
+        now = datetime.now()
+        stale = now - timedelta(seconds=LAST_USED_RESOLUTION)
+        # Stored times sort as strings
+        if key.last_used_at is None or key.last_used_at < stale.strftime(
+            STORED_FORMAT
+        ):
+            key.last_used_at = now.strftime(STORED_FORMAT)
+            with self.conn:
+                self.conn.execute(
+                    "UPDATE api_keys SET last_used_at = ? WHERE id = ?",
+                    (key.last_used_at, key.id),
+                )
+        return key
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    actions = parser.add_subparsers(dest="action", required=True)
+    create = actions.add_parser("create", help="Create a key; prints the secret once")
+    create.add_argument("--name", required=True, help="Who or what uses it")
+    create.add_argument("--role", choices=ROLES, required=True)
+    actions.add_parser("list", help="All keys, without secrets")
+    revoke = actions.add_parser("revoke", help="Disable a key for good")
+    revoke.add_argument("key_id", type=int)
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        keys = ApiKeys(db)
+        try:
+            if args.action == "create":
+                key, secret = keys.create(args.name, args.role)
+                output = {**key.to_dict(), "key": secret}
+            elif args.action == "revoke":
+                output = keys.revoke(args.key_id).to_dict()
+            else:
+                output = [key.to_dict() for key in keys.list()]
+        except KeyError as exc:
+            raise SystemExit(f"error: {exc.args[0]}") from None
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    if args.json or args.action != "list":
+        print(json.dumps(output, indent=2))
+        return
+    for key in output:
+        status = f"revoked {key['revoked_at']}" if key["revoked_at"] else "active"
+        print(
+            f"#{key['id']:<4} {key['prefix']}... {key['name']:<20} "
+            f"{key['role']:<12} {status}"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="API keys and roles")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
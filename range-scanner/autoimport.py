@@ -0,0 +1,173 @@
+#!/usr/bin/env python3
+"""
+Headless auto-import: watch hand history folders and keep the database current.
+
+Poker clients append each finished hand to a session file. The watcher polls
+the configured folders every `--interval` seconds (stdlib only, so polling
+rather than OS file events) and re-imports any `.txt` file whose size or
+modification time changed. A file is only read once it has settled: unchanged
+since the previous poll, or last written more than SETTLE_SECONDS ago, so a
+hand the client is still writing is never stored half-finished. Hands already
+in the database are skipped by their (site, hand_id) key.
+
+After every poll that stored new hands the watcher prints a HUD line for each
+player in them (or only the `--player` names): hands, VPIP / PFR / 3-bet, and
+bb/100 over the whole database.
+
+Example:
+    python3 pokertools.py autoimport ~/PokerStars/HandHistory/Hero \\
+        --db hands.sqlite --interval 5 --player Villain1 --player Villain2
+"""
+
+from __future__ import annotations
+
+import argparse
+import sys
+import time
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Callable, Dict, Iterable, List, Optional, Sequence, Set, Tuple
+
+from handdb import HandDB, default_db_path
+from handhistory import iter_history_files, parse_file
+from stats import ALL_GROUP, PlayerStats, load_stats
+
+
+DEFAULT_INTERVAL = 5.0
+# A file untouched for this long is complete even if we never saw it change
+SETTLE_SECONDS = 2.0
+
+
+@dataclass
+class PollResult:
+    files: List[Path] = field(default_factory=list)
+    inserted: int = 0
+    # Players dealt into the newly stored hands
+    players: Set[str] = field(default_factory=set)
+    errors: List[Tuple[str, str]] = field(default_factory=list)
+
+
+class FolderWatcher:
+    """Imports new and grown history files under `paths` on each `poll`"""
+
+    def __init__(
+        self,
+        db: HandDB,
+        paths: Sequence[Path],
+        settle: float = SETTLE_SECONDS,
+        clock: Callable[[], float] = time.time,
+    ):
+        self.db = db
+        self.paths = [Path(path) for path in paths]
+        self.settle = settle
+        self.clock = clock
+        # (size, mtime) when each file was last imported / last seen
+        self.imported: Dict[Path, Tuple[int, float]] = {}
+        self.seen: Dict[Path, Tuple[int, float]] = {}
+
+    def _ready(self) -> List[Path]:
+        ready = []
+        now = self.clock()
+        for path in iter_history_files(self.paths):
+            try:
+                info = path.stat()
+            except OSError:
+                continue
+            signature = (info.st_size, info.st_mtime)
+            previous = self.seen.get(path)
+            self.seen[path] = signature
+            if self.imported.get(path) == signature:
+                continue
+            if previous == signature or now - info.st_mtime >= self.settle:
+                ready.append(path)
+        return ready
+
+    def poll(self) -> PollResult:
+        result = PollResult()
+        for path in self._ready():
+            try:
+                hands = parse_file(path)
+            except OSError as exc:
+                result.errors.append((str(path), str(exc)))
+                continue
+            self.imported[path] = self.seen[path]
+            result.files.append(path)
+            for hand in hands:
+                if self.db.insert_hand(hand):
+                    result.inserted += 1
+                    result.players.update(player.name for player in hand.players)
+        return result
+
+
+def hud_line(name: str, stats: PlayerStats) -> str:
+    def pct(value: Optional[float]) -> str:
+        return "-" if value is None else f"{value:.0f}"
+
+    rate = "-" if stats.bb_per_100 is None else f"{stats.bb_per_100:+.1f}"
+    noun = "hand " if stats.hands == 1 else "hands"
+    return (
+        f"{name:<20} {stats.hands:>7,} {noun}  VPIP {pct(stats.vpip_pct):>3}  "
+        f"PFR {pct(stats.pfr_pct):>3}  3B {pct(stats.three_bet_pct):>3}  "
+        f"{rate} bb/100"
+    )
+
+
+def hud_lines(db: HandDB, players: Iterable[str]) -> List[str]:
+    names = sorted(players)
+    if not names:
+        return []
+    table = load_stats(db, names)
+    return [hud_line(name, table[name][ALL_GROUP]) for name in names if name in table]
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("paths", nargs="+", type=Path, help="Folders to watch")
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument(
+        "--interval", type=float, default=DEFAULT_INTERVAL, help="Seconds per poll"
+    )
+    parser.add_argument(
+        "--player", action="append", help="Only show these players (repeatable)"
+    )
+    parser.add_argument(
+        "--once", action="store_true", help="Import what is there and exit"
+    )
+
+
+def run(args: argparse.Namespace):
+    if args.interval <= 0:
+        raise SystemExit("error: --interval must be positive")
+    with HandDB(args.db) as db:
+        # A one-off import has nobody still writing to wait for
+        watcher = FolderWatcher(db, args.paths, 0.0 if args.once else SETTLE_SECONDS)
+        names = ", ".join(str(path) for path in args.paths)
+        print(f"Watching {names} -> {db.path} (Ctrl+C to stop)")
+        try:
+            while True:
+                result = watcher.poll()
+                for path, error in result.errors:
+                    print(f"warning: {path}: {error}", file=sys.stderr)
+                if result.inserted:
+                    stamp = time.strftime("%H:%M:%S")
+                    noun = "hand" if result.inserted == 1 else "hands"
+                    print(f"[{stamp}] {result.inserted:,} new {noun}")
+                    shown = result.players
+                    if args.player:
+                        shown = shown & set(args.player)
+                    for line in hud_lines(db, shown):
+                        print(f"  {line}")
+                if args.once:
+                    break
+                time.sleep(args.interval)
+        except KeyboardInterrupt:
+            pass
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Auto-import hand histories")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,320 @@
+#!/usr/bin/env python3
+"""
+Backup and restore of the hand database and the files kept next to it.
+
+`create_backup` writes one .tar.gz holding a consistent snapshot of the hand
+database (SQLite's online backup, so the API server and autoimport can keep
+writing while it runs), any extra files named with `--include` (the equity
+cache, a seating or tournament state file, notify rules; SQLite files among
+them are snapshotted the same way), and a `manifest.json` with each member's
+size and SHA-256. A `<archive>.sha256` file next to the archive, in
+`sha256sum` format, covers the archive itself.
+
+`verify_backup` checks the archive checksum (when the .sha256 file is there)
+and every member against the manifest. `restore_backup` verifies first, then
+checks the database with `PRAGMA integrity_check`, refuses a schema newer than
+this code knows, and only then moves it into place; an existing database is
+kept unless `force` is given. Extra files are restored next to the database
+or into `files_dir`.
+
+Example:
+    python3 pokertools.py backup create --db hands.sqlite \\
+        --include equity_cache.sqlite --include seating.json --output pre-event.tar.gz
+    python3 pokertools.py backup verify pre-event.tar.gz
+    python3 pokertools.py backup restore pre-event.tar.gz --db hands.sqlite --force
+"""
+
+from __future__ import annotations
+
+import argparse
+import hashlib
+import io
+import json
+import os
+import sqlite3
+import tarfile
+import tempfile
+from dataclasses import asdict, dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import List, Optional, Sequence
+
+from handdb import MIGRATIONS, default_db_path
+
+
+MANIFEST_NAME = "manifest.json"
+DATABASE_NAME = "hands.sqlite"
+FILES_PREFIX = "files/"
+BACKUP_FORMAT = 1
+SQLITE_HEADER = b"SQLite format 3\x00"
+CHUNK_SIZE = 1 << 20
+
+
+@dataclass
+class BackupFile:
+    name: str
+    size: int
+    sha256: str
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+@dataclass
+class Manifest:
+    created_at: str
+    schema_version: int
+    files: List[BackupFile] = field(default_factory=list)
+    format: int = BACKUP_FORMAT
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "Manifest":
+        if not isinstance(data, dict) or data.get("format") != BACKUP_FORMAT:
+            raise ValueError("Not a pokertools backup manifest")
+        return cls(
+            data["created_at"],
+            int(data["schema_version"]),
+            [BackupFile(**entry) for entry in data["files"]],
+        )
+
+
+def file_sha256(path: Path) -> str:
+    digest = hashlib.sha256()
+    with open(path, "rb") as handle:
+        for chunk in iter(lambda: handle.read(CHUNK_SIZE), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+def checksum_path(archive: Path) -> Path:
+    return archive.with_name(archive.name + ".sha256")
+
+
+def _is_sqlite(path: Path) -> bool:
+    with open(path, "rb") as handle:
+        return handle.read(len(SQLITE_HEADER)) == SQLITE_HEADER
+
+
+def _schema_version(path: Path) -> int:
+    conn = sqlite3.connect(path.as_posix())
+    try:
+        return conn.execute("PRAGMA user_version").fetchone()[0]
+    finally:
+        conn.close()
+
+
+def _snapshot(source: Path, target: Path):
+    """Consistent copy of a live SQLite file"""
+    src = sqlite3.connect(source.as_posix())
+    dst = sqlite3.connect(target.as_posix())
+    try:
+        src.backup(dst)
+    finally:
+        dst.close()
+        src.close()
+
+
+def create_backup(
+    db_path: Path, output: Path, include: Sequence[Path] = ()
+) -> Manifest:
+    db_path, output = Path(db_path), Path(output)
+    if not db_path.exists():
+        raise FileNotFoundError(f"Hand database {db_path} not found")
+    names = [Path(path).name for path in include]
+    if len(set(names)) != len(names):
+        raise ValueError("Included files must have different names")
+    for path in include:
+        if not Path(path).is_file():
+            raise FileNotFoundError(f"{path} is not a file")
+    with tempfile.TemporaryDirectory() as tmp:
+        staged = [(DATABASE_NAME, Path(tmp) / DATABASE_NAME)]
+        _snapshot(db_path, staged[0][1])
+        created_at = datetime.now().strftime("%Y-%m-%d %H:%M:%S")
+        manifest = Manifest(created_at, _schema_version(staged[0][1]))
+        for path in map(Path, include):
+            copy = Path(tmp) / f"file-{len(staged)}"
+            if _is_sqlite(path):
+                _snapshot(path, copy)
+            else:
+                copy.write_bytes(path.read_bytes())
+            staged.append((FILES_PREFIX + path.name, copy))
+        partial = output.with_name(output.name + ".partial")
+        with tarfile.open(partial, "w:gz") as archive:
+            for name, path in staged:
+                archive.add(path.as_posix(), arcname=name)
+                manifest.files.append(
+                    BackupFile(name, path.stat().st_size, file_sha256(path))
+                )
+            data = json.dumps(manifest.to_dict(), indent=2).encode("utf-8")
+            info = tarfile.TarInfo(MANIFEST_NAME)
+            info.size = len(data)
+            archive.addfile(info, io.BytesIO(data))
+        os.replace(partial, output)
+    checksum_path(output).write_text(f"{file_sha256(output)}  {output.name}\n")
+    return manifest
+
+
+def _safe_name(name: str) -> bool:
+    """The database, or an included file without any directory part"""
+    if name == DATABASE_NAME:
+        return True
+    base = name[len(FILES_PREFIX) :]
+    if not name.startswith(FILES_PREFIX) or base in ("", ".", ".."):
+        return False
+    return "/" not in base and "\\" not in base
+
+
+def _member_sha256(archive: tarfile.TarFile, name: str) -> str:
+    handle = archive.extractfile(name)
+    if handle is None:
+        raise ValueError(f"{name} in the backup is not a file")
+    digest = hashlib.sha256()
+    for chunk in iter(lambda: handle.read(CHUNK_SIZE), b""):
+        digest.update(chunk)
+    return digest.hexdigest()
+
+
+def verify_backup(archive_path: Path) -> Manifest:
+    """The manifest of an intact backup; ValueError on any mismatch"""
+    archive_path = Path(archive_path)
+    sidecar = checksum_path(archive_path)
+    if sidecar.exists():
+        expected = sidecar.read_text().split()[0]
+        if file_sha256(archive_path) != expected:
+            raise ValueError(f"{archive_path.name} does not match {sidecar.name}")
+    try:
+        with tarfile.open(archive_path, "r:gz") as archive:
+            members = set(archive.getnames())
+            manifest_file = archive.extractfile(MANIFEST_NAME)
+            manifest = Manifest.from_dict(json.load(manifest_file))
+            listed = {entry.name for entry in manifest.files}
+            if not all(map(_safe_name, listed)):
+                raise ValueError("The backup's manifest names unsafe paths")
+            if DATABASE_NAME not in listed or members != listed | {MANIFEST_NAME}:
+                raise ValueError("The backup's files do not match its manifest")
+            for entry in manifest.files:
+                if _member_sha256(archive, entry.name) != entry.sha256:
+                    raise ValueError(f"{entry.name} is corrupt (checksum mismatch)")
+    except (tarfile.TarError, KeyError, EOFError, OSError) as exc:
+        raise ValueError(f"Cannot read {archive_path.name}: {exc}") from None
+    return manifest
+
+
+def _check_database(path: Path):
+    conn = sqlite3.connect(path.as_posix())
+    try:
+        (status,) = conn.execute("PRAGMA integrity_check").fetchone()
+    finally:
+        conn.close()
+    version = _schema_version(path)
+    if status != "ok":
+        raise ValueError(f"The backed-up database fails its integrity check: {status}")
+    if version > len(MIGRATIONS):
+        raise ValueError(
+            f"The backup has schema version {version}; this code knows "
+            f"{len(MIGRATIONS)}"
+        )
+
+
+def restore_backup(
+    archive_path: Path,
+    db_path: Path,
+    force: bool = False,
+    files_dir: Optional[Path] = None,
+) -> List[Path]:
+    """Verify, then put the database and extra files in place; returns paths"""
+    db_path = Path(db_path)
+    files_dir = Path(files_dir) if files_dir else db_path.parent
+    manifest = verify_backup(archive_path)
+    targets = []
+    for entry in manifest.files:
+        if entry.name == DATABASE_NAME:
+            targets.append(db_path)
+        else:
+            targets.append(files_dir / entry.name[len(FILES_PREFIX) :])
+    existing = [path for path in targets if path.exists()]
+    if existing and not force:
+        names = ", ".join(path.as_posix() for path in existing)
+        raise FileExistsError(f"Not replacing {names} without --force")
+    staged = []
+    try:
+        with tarfile.open(archive_path, "r:gz") as archive:
+            for entry, target in zip(manifest.files, targets):
+                target.parent.mkdir(parents=True, exist_ok=True)
+                partial = target.with_name(target.name + ".restoring")
+                partial.write_bytes(archive.extractfile(entry.name).read())
+                staged.append((partial, target))
+        _check_database(staged[targets.index(db_path)][0])
+        for partial, target in staged:
+            os.replace(partial, target)
+    finally:
+        for partial, _ in staged:
+            partial.unlink(missing_ok=True)
+    return targets
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    actions = parser.add_subparsers(dest="action", required=True)
+    create = actions.add_parser("create", help="Snapshot the database into an archive")
+    create.add_argument("--db", type=Path, default=default_db_path())
+    create.add_argument(
+        "--include",
+        type=Path,
+        action="append",
+        default=[],
+        help="Another file to back up (repeatable)",
+    )
+    create.add_argument("--output", type=Path, help="Archive path (.tar.gz)")
+    verify = actions.add_parser("verify", help="Check an archive's checksums")
+    verify.add_argument("archive", type=Path)
+    restore = actions.add_parser("restore", help="Restore an archive")
+    restore.add_argument("archive", type=Path)
+    restore.add_argument("--db", type=Path, default=default_db_path())
+    restore.add_argument(
+        "--files-dir", type=Path, help="Where included files go (default: by --db)"
+    )
+    restore.add_argument("--force", action="store_true", help="Replace existing files")
+    for sub in (create, verify, restore):
+        sub.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        if args.action == "create":
+            output = args.output or Path(
+                datetime.now().strftime("pokertools-backup-%Y%m%d-%H%M%S.tar.gz")
+            )
+            manifest = create_backup(args.db, output, args.include)
+            result = {"archive": output.as_posix(), **manifest.to_dict()}
+        elif args.action == "verify":
+            result = {"archive": args.archive.as_posix(), "ok": True}
+            result.update(verify_backup(args.archive).to_dict())
+        else:
+            paths = restore_backup(args.archive, args.db, args.force, args.files_dir)
+            result = {"restored": [path.as_posix() for path in paths]}
+    except (OSError, ValueError) as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(result, indent=2))
+        return
+    if args.action == "restore":
+        for path in result["restored"]:
+            print(f"restored {path}")
+        return
+    verb = "wrote" if args.action == "create" else "verified"
+    print(f"{verb} {result['archive']} (schema {result['schema_version']})")
+    for entry in result["files"]:
+        print(f"  {entry['name']:<32} {entry['size']:>12,} {entry['sha256'][:16]}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Back up and restore the hand DB")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,417 @@
+#!/usr/bin/env python3
+"""
+Session tracking and bankroll management, stored in the hand database.
+
+Live players log sessions by hand (start/end, venue, game, stakes, buy-in,
+cash-out, and optionally the number of hands) plus deposits and withdrawals.
+`Bankroll` turns those into a running balance, and per-stakes win rates in
+$/hour and, when hand counts are known, bb/100. Nothing here needs hand
+histories; the tables live next to them (migration 2 in `handdb.py`).
+
+Sessions and transactions may record the currency they were in (migration 6).
+Totals over more than one currency are converted to the one asked for, each
+amount at the exchange-rate snapshot of its own time (`money.py`); results in
+big blinds need no rates at all.
+
+Example:
+    python3 pokertools.py bankroll deposit 1000
+    python3 pokertools.py bankroll add --stakes 1/2 --buy-in 200 --cash-out 345 \\
+        --start "2024-01-05 20:00" --end "2024-01-05 23:30" --venue Aria
+    python3 pokertools.py bankroll summary --currency USD --unit bb
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+
+from handdb import HandDB, default_db_path
+from handhistory import CURRENCY_SYMBOLS
+from money import UNITS, ExchangeRates, normalize_currency, output_currency
+
+
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+SESSION_COLUMNS = (
+    "id, started_at, ended_at, venue, game, small_blind, big_blind, "
+    "buy_in, cash_out, hands, notes, currency"
+)
+
+
+def parse_time(value: str) -> str:
+    """Accept ISO-ish input ("2024-01-05 20:00", "2024-01-05T20:00:00")"""
+    try:
+        moment = datetime.fromisoformat(str(value).strip())
+    except ValueError:
+        raise ValueError(f"Invalid time: {value!r}") from None
+    return moment.strftime(STORED_FORMAT)
+
+
+def parse_stakes(value: str) -> Tuple[float, float]:
+    """"1/2", "$1/$2", or "1€/2€" -> (1.0, 2.0)"""
+    parts = "".join(
+        char for char in str(value) if char not in CURRENCY_SYMBOLS
+    ).split("/")
+    try:
+        small_blind, big_blind = (float(part) for part in parts)
+    except ValueError:
+        raise ValueError(f"Stakes must look like 1/2, got {value!r}") from None
+    if big_blind <= 0:
+        raise ValueError("Big blind must be positive")
+    return small_blind, big_blind
+
+
+@dataclass
+class BankrollSession:
+    id: Optional[int]
+    started_at: str
+    ended_at: str
+    venue: str = ""
+    game: str = ""
+    small_blind: Optional[float] = None
+    big_blind: Optional[float] = None
+    buy_in: float = 0.0
+    cash_out: float = 0.0
+    hands: Optional[int] = None
+    notes: str = ""
+    currency: str = ""
+
+    @property
+    def result(self) -> float:
+        return round(self.cash_out - self.buy_in, 2)
+
+    @property
+    def hours(self) -> float:
+        start = datetime.strptime(self.started_at, STORED_FORMAT)
+        end = datetime.strptime(self.ended_at, STORED_FORMAT)
+        return (end - start).total_seconds() / 3600
+
+    @property
+    def stakes(self) -> str:
+        if self.big_blind is None:
+            return "-"
+        label = f"{self.small_blind:g}/{self.big_blind:g}"
+        return f"{label} {self.currency}" if self.currency else label
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        data["result"] = self.result
+        data["hours"] = round(self.hours, 2)
+        data["stakes"] = self.stakes
+        return data
+
+
+@dataclass
+class StakeSummary:
+    sessions: int = 0
+    hours: float = 0.0
+    result: float = 0.0
+    hands: int = 0
+    # Result and big blinds of the sessions that recorded a hand count
+    counted_result_bb: float = 0.0
+    # In big blinds of the stakes; None when no blinds were logged
+    result_bb: Optional[float] = None
+
+    @property
+    def per_hour(self) -> Optional[float]:
+        return round(self.result / self.hours, 2) if self.hours else None
+
+    @property
+    def bb_per_hour(self) -> Optional[float]:
+        if self.result_bb is None or not self.hours:
+            return None
+        return round(self.result_bb / self.hours, 2)
+
+    @property
+    def bb_per_100(self) -> Optional[float]:
+        if not self.hands:
+            return None
+        return round(self.counted_result_bb / self.hands * 100, 2)
+
+    def to_dict(self) -> dict:
+        return {
+            "sessions": self.sessions,
+            "hours": round(self.hours, 2),
+            "result": round(self.result, 2),
+            "hands": self.hands,
+            "per_hour": self.per_hour,
+            "result_bb": None if self.result_bb is None else round(self.result_bb, 2),
+            "bb_per_hour": self.bb_per_hour,
+            "bb_per_100": self.bb_per_100,
+        }
+
+
+class Bankroll:
+    """Sessions and transactions on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+        self.rates = ExchangeRates(db)
+
+    def add_session(
+        self,
+        started_at: str,
+        ended_at: str,
+        buy_in: float,
+        cash_out: float,
+        stakes: Optional[str] = None,
+        venue: str = "",
+        game: str = "",
+        hands: Optional[int] = None,
+        notes: str = "",
+        currency: str = "",
+    ) -> BankrollSession:
+        small_blind, big_blind = parse_stakes(stakes) if stakes else (None, None)
+        session = BankrollSession(
+            id=None,
+            started_at=parse_time(started_at),
+            ended_at=parse_time(ended_at),
+            venue=venue,
+            game=game,
+            small_blind=small_blind,
+            big_blind=big_blind,
+            buy_in=float(buy_in),
+            cash_out=float(cash_out),
+            hands=None if hands is None else int(hands),
+            notes=notes,
+            currency=normalize_currency(currency),
+        )
+        if session.hours < 0:
+            raise ValueError("Session ends before it starts")
+        if session.buy_in < 0 or session.cash_out < 0:
+            raise ValueError("Buy-in and cash-out cannot be negative")
+        with self.conn:
+            cursor = self.conn.execute(
+                """
+                INSERT INTO bankroll_sessions (
+                    started_at, ended_at, venue, game, small_blind, big_blind,
+                    buy_in, cash_out, hands, notes, currency
+                ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+                """,
+                tuple(asdict(session).values())[1:],
+            )
+        session.id = cursor.lastrowid
+        return session
+
+    def delete_session(self, session_id: int) -> bool:
+        with self.conn:
+            cursor = self.conn.execute(
+                "DELETE FROM bankroll_sessions WHERE id = ?", (session_id,)
+            )
+        return bool(cursor.rowcount)
+
+    def sessions(self) -> List[BankrollSession]:
+        rows = self.conn.execute(
+            f"SELECT {SESSION_COLUMNS} FROM bankroll_sessions ORDER BY started_at, id"
+        ).fetchall()
+        return [BankrollSession(*row) for row in rows]
+
+    def add_transaction(
+        self, amount: float, at: str, note: str = "", currency: str = ""
+    ) -> int:
+        """Deposit (positive) or withdrawal (negative)"""
+        if not amount:
+            raise ValueError("Amount must be non-zero")
+        with self.conn:
+            cursor = self.conn.execute(
+                "INSERT INTO bankroll_transactions (at, amount, note, currency) "
+                "VALUES (?, ?, ?, ?)",
+                (parse_time(at), float(amount), note, normalize_currency(currency)),
+            )
+        return cursor.lastrowid
+
+    def _events(self) -> List[Tuple[str, str, float, str]]:
+        """(at, label, amount, currency) for every session and transaction"""
+        events = [
+            (
+                session.ended_at,
+                f"session {session.stakes} {session.venue}".strip(),
+                session.result,
+                session.currency,
+            )
+            for session in self.sessions()
+        ]
+        events += self.conn.execute(
+            "SELECT at, CASE WHEN amount > 0 THEN 'deposit' ELSE 'withdrawal' END "
+            "|| CASE WHEN note != '' THEN ': ' || note ELSE '' END, amount, currency "
+            "FROM bankroll_transactions"
+        ).fetchall()
+        return sorted(events, key=lambda event: event[0])
+
+    def history(self, currency: Optional[str] = None) -> List[Dict]:
+        """Every session and transaction in time order with the running balance
+
+        Amounts and the balance are in `currency`, which is required once the
+        events span more than one.
+        """
+        events = self._events()
+        target = output_currency((event[3] for event in events), currency)
+        balance = 0.0
+        history = []
+        for at, label, amount, original in events:
+            amount = round(self.rates.convert(amount, original, target, at), 2)
+            balance = round(balance + amount, 2)
+            history.append(
+                {
+                    "at": at,
+                    "event": label,
+                    "amount": amount,
+                    "currency": original,
+                    "balance": balance,
+                }
+            )
+        return history
+
+    def balance(self, currency: Optional[str] = None) -> float:
+        history = self.history(currency)
+        return history[-1]["balance"] if history else 0.0
+
+    def by_stakes(self, currency: Optional[str] = None) -> Dict[str, StakeSummary]:
+        sessions = self.sessions()
+        target = output_currency((session.currency for session in sessions), currency)
+        summaries: Dict[str, StakeSummary] = {}
+        for session in sessions:
+            summary = summaries.setdefault(session.stakes, StakeSummary())
+            summary.sessions += 1
+            summary.hours += session.hours
+            summary.result += self.rates.convert(
+                session.result, session.currency, target, session.ended_at
+            )
+            if session.big_blind:
+                result_bb = session.result / session.big_blind
+                summary.result_bb = (summary.result_bb or 0.0) + result_bb
+                if session.hands:
+                    summary.hands += session.hands
+                    summary.counted_result_bb += result_bb
+        return summaries
+
+    def summary(self, currency: Optional[str] = None) -> dict:
+        sessions = self.sessions()
+        target = output_currency((session.currency for session in sessions), currency)
+        stakes = self.by_stakes(currency)
+        return {
+            "currency": target,
+            "balance": self.balance(currency),
+            "sessions": len(sessions),
+            "result": round(sum(summary.result for summary in stakes.values()), 2),
+            "hours": round(sum(session.hours for session in sessions), 2),
+            "stakes": {label: summary.to_dict() for label, summary in stakes.items()},
+        }
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    actions = parser.add_subparsers(dest="action", required=True)
+    add = actions.add_parser("add", help="Log a session")
+    add.add_argument("--start", required=True, help='e.g. "2024-01-05 20:00"')
+    add.add_argument("--end", required=True)
+    add.add_argument("--buy-in", type=float, required=True)
+    add.add_argument("--cash-out", type=float, required=True)
+    add.add_argument("--stakes", help="e.g. 1/2")
+    add.add_argument("--venue", default="")
+    add.add_argument("--game", default="")
+    add.add_argument("--hands", type=int, help="Hands played, enables bb/100")
+    add.add_argument("--notes", default="")
+    add.add_argument("--currency", default="", help="e.g. EUR")
+    remove = actions.add_parser("remove", help="Delete a session by id")
+    remove.add_argument("session_id", type=int)
+    for name, help_text in (("deposit", "Add money"), ("withdraw", "Take money out")):
+        transaction = actions.add_parser(name, help=help_text)
+        transaction.add_argument("amount", type=float)
+        transaction.add_argument("--at", default=datetime.now().isoformat(" "))
+        transaction.add_argument("--note", default="")
+        transaction.add_argument("--currency", default="", help="e.g. EUR")
+    actions.add_parser("list", help="All sessions")
+    history = actions.add_parser("history", help="Balance over time")
+    summary = actions.add_parser("summary", help="Balance and per-stakes win rates")
+    summary.add_argument(
+        "--unit", choices=UNITS, default="money", help="Per-stakes results in"
+    )
+    for report in (history, summary):
+        report.add_argument("--currency", help="Convert totals to this currency")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        bankroll = Bankroll(db)
+        try:
+            if args.action == "add":
+                output = bankroll.add_session(
+                    args.start,
+                    args.end,
+                    args.buy_in,
+                    args.cash_out,
+                    args.stakes,
+                    args.venue,
+                    args.game,
+                    args.hands,
+                    args.notes,
+                    args.currency,
+                ).to_dict()
+            elif args.action == "remove":
+                output = {"removed": bankroll.delete_session(args.session_id)}
+            elif args.action in ("deposit", "withdraw"):
+                sign = 1 if args.action == "deposit" else -1
+                bankroll.add_transaction(
+                    sign * args.amount, args.at, args.note, args.currency
+                )
+                output = {"balance": bankroll.balance(args.currency or None)}
+            elif args.action == "list":
+                output = [session.to_dict() for session in bankroll.sessions()]
+            elif args.action == "history":
+                output = bankroll.history(args.currency)
+            else:
+                output = bankroll.summary(args.currency)
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    if args.json or args.action in ("add", "remove", "deposit", "withdraw"):
+        print(json.dumps(output, indent=2))
+    elif args.action == "list":
+        for session in output:
+            print(
+                f"#{session['id']:<4} {session['started_at']} "
+                f"{session['hours']:>5.1f}h {session['stakes']:>8} "
+                f"{session['venue']:<16} {session['result']:>+10.2f}"
+            )
+    elif args.action == "history":
+        for event in output:
+            print(
+                f"{event['at']}  {event['event']:<32} {event['amount']:>+10.2f}"
+                f" {event['balance']:>12.2f}"
+            )
+    else:
+        print(f"Balance: {output['balance']:.2f} {output['currency']}".rstrip())
+        print(f"Sessions: {output['sessions']} ({output['hours']:.1f}h)")
+        if args.unit == "bb":
+            result_key, hourly_key, hourly = "result_bb", "bb_per_hour", "bb/hour"
+        else:
+            result_key, hourly_key = "result", "per_hour"
+            hourly = f"{output['currency'] or '$'}/hour"
+        print(
+            f"{'Stakes':<14} {'Sessions':>8} {'Hours':>7} {'Result':>10} "
+            f"{hourly:>8} {'bb/100':>8}"
+        )
+        for stakes, summary in output["stakes"].items():
+            result = summary[result_key]
+            per_hour = summary[hourly_key]
+            bb_100 = summary["bb_per_100"]
+            print(
+                f"{stakes:<14} {summary['sessions']:>8} {summary['hours']:>7.1f} "
+                f"{'-' if result is None else f'{result:+.2f}':>10} "
+                f"{'-' if per_hour is None else f'{per_hour:+.2f}':>8} "
+                f"{'-' if bb_100 is None else f'{bb_100:+.2f}':>8}"
+            )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Session and bankroll tracking")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
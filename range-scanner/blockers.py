@@ -0,0 +1,190 @@
+#!/usr/bin/env python3
+"""
+Blockers and combinatorics: what the hero's cards do to a villain's range.
+
+`blocker_report` counts a hold'em range on a board twice: with only the board
+removed, and with the hero's two cards removed as well. Both counts are
+weighted combos, broken down by the made-hand class each combo has on the
+board.
+
+Against the hero's own hand the range splits into value (combos that beat the
+hero), bluffs (combos the hero beats), and chops. The report gives the value
+share of the value + bluff combos before and after blockers, so a bluff
+catcher that holds the nut-flush blocker shows exactly how much it shifts the
+ratio, and which of the hero's cards removes what.
+
+Example:
+    python3 pokertools.py blockers "AA, KK, AK, KQs, QJs, JTs, T9s" \\
+        --board Kh8h4c2s7d --hero AhQc
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import dataclass, field
+from typing import Dict, List, Sequence, Union
+
+from cards import CardLike, format_cards, parse_cards, to_card
+from hand_evaluator import CLASS_SHIFT, HandClass
+from hand_range import Range
+from lookup_evaluator import evaluate_indices
+
+
+@dataclass
+class Split:
+    """Weighted value / bluff / chop combos against the hero"""
+
+    value: float = 0.0
+    bluffs: float = 0.0
+    chops: float = 0.0
+
+    @property
+    def value_share(self) -> float:
+        """Value combos as a share of value + bluffs, 0-100"""
+        total = self.value + self.bluffs
+        return self.value / total * 100 if total else 0.0
+
+    def to_dict(self) -> dict:
+        return {
+            "value": round(self.value, 2),
+            "bluffs": round(self.bluffs, 2),
+            "chops": round(self.chops, 2),
+            "value_share": round(self.value_share, 2),
+        }
+
+
+@dataclass
+class BlockerReport:
+    range: str
+    board: str
+    hero: str
+    hero_class: str
+    # {hand class: [combos with the board removed, combos also without hero]}
+    classes: Dict[str, List[float]] = field(default_factory=dict)
+    before: Split = field(default_factory=Split)
+    after: Split = field(default_factory=Split)
+    # {hero card: weighted combos it removes; one holding both counts twice}
+    blocked_by: Dict[str, float] = field(default_factory=dict)
+
+    def to_dict(self) -> dict:
+        return {
+            "range": self.range,
+            "board": self.board,
+            "hero": self.hero,
+            "hero_class": self.hero_class,
+            "classes": {
+                name: {"combos": round(total, 2), "live": round(live, 2)}
+                for name, (total, live) in self.classes.items()
+            },
+            "before": self.before.to_dict(),
+            "after": self.after.to_dict(),
+            "value_share_shift": round(
+                self.after.value_share - self.before.value_share, 2
+            ),
+            "blocked_by": {
+                card: round(count, 2) for card, count in self.blocked_by.items()
+            },
+        }
+
+
+def _indices(value: Union[str, Sequence[CardLike]]) -> List[int]:
+    cards = parse_cards(value) if isinstance(value, str) else map(to_card, value)
+    return [card.index for card in cards]
+
+
+def blocker_report(
+    hand_range: Union[str, Range],
+    board: Union[str, Sequence[CardLike]],
+    hero: Union[str, Sequence[CardLike]],
+) -> BlockerReport:
+    board_cards, hero_cards = _indices(board), _indices(hero)
+    if not 3 <= len(board_cards) <= 5:
+        raise ValueError("The board must be 3-5 cards")
+    if len(hero_cards) != 2:
+        raise ValueError("The hero holds two cards")
+    if len(set(board_cards + hero_cards)) != len(board_cards) + len(hero_cards):
+        raise ValueError("The hero's cards and the board overlap")
+    parsed = Range.parse(hand_range) if isinstance(hand_range, str) else hand_range
+    mine = evaluate_indices([*hero_cards, *board_cards])
+    report = BlockerReport(
+        str(hand_range),
+        format_cards(board_cards),
+        format_cards(hero_cards),
+        HandClass(mine >> CLASS_SHIFT).label,
+        classes={hand.label: [0.0, 0.0] for hand in reversed(HandClass)},
+        blocked_by={format_cards([card]): 0.0 for card in hero_cards},
+    )
+    for combo, weight in parsed.weighted_combos():
+        if set(combo) & set(board_cards):
+            continue
+        strength = evaluate_indices([*combo, *board_cards])
+        counts = report.classes[HandClass(strength >> CLASS_SHIFT).label]
+        blockers = [card for card in hero_cards if card in combo]
+        splits = [report.before] if blockers else [report.before, report.after]
+        counts[0] += weight
+        if not blockers:
+            counts[1] += weight
+        for card in blockers:
+            report.blocked_by[format_cards([card])] += weight
+        for split in splits:
+            if strength > mine:
+                split.value += weight
+            elif strength < mine:
+                split.bluffs += weight
+            else:
+                split.chops += weight
+    report.classes = {
+        name: counts for name, counts in report.classes.items() if counts[0]
+    }
+    if not report.classes:
+        raise ValueError("No combos of that range are live on this board")
+    return report
+
+
+def format_report(report: BlockerReport) -> str:
+    lines = [
+        f"{report.range} on {report.board}, hero {report.hero} "
+        f"({report.hero_class})",
+        f"{'Class':<16} {'Combos':>8} {'Live':>8}",
+    ]
+    for name, (total, live) in report.classes.items():
+        lines.append(f"{name:<16} {total:>8g} {live:>8g}")
+    for label, split in (("Before blockers", report.before), ("After", report.after)):
+        lines.append(
+            f"{label}: {split.value:g} value / {split.bluffs:g} bluffs "
+            f"/ {split.chops:g} chops, {split.value_share:.1f}% value"
+        )
+    removed = ", ".join(
+        f"{card} removes {count:g}" for card, count in report.blocked_by.items()
+    )
+    lines.append(f"Blockers: {removed}")
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("range", help='Villain range, e.g. "AA, KK, AK, KQs"')
+    parser.add_argument("--board", required=True, help="Flop, turn, or river")
+    parser.add_argument("--hero", required=True, help="Hero hole cards")
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        report = blocker_report(args.range, args.board, args.hero)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(report.to_dict(), indent=2))
+    else:
+        print(format_report(report))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Blocker and combo analysis")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
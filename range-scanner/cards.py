@@ -0,0 +1,211 @@
+"""
+Card, rank, suit, and deck primitives shared by the evaluator, equity, and range tools.
+
+Cards are stored as small integers (rank * 4 + suit) so they can double as bit
+positions in a 52-bit mask; `Card` wraps that index with parsing/formatting.
+
+Example:
+    hole = parse_cards("As Td")         # [Card('As'), Card('Td')]
+    board = Deck(seed=42, dead=hole).shuffle().deal(3)
+"""
+
+from __future__ import annotations
+
+import random
+from enum import IntEnum
+from typing import Iterable, List, Optional, Sequence, Union
+
+
+RANK_CHARS = "23456789TJQKA"
+SUIT_CHARS = "cdhs"
+FULL_DECK_MASK = (1 << 52) - 1
+# Short-deck (6+) hold'em strips the deuces through fives: 36 cards remain
+SHORT_DECK_MASK = FULL_DECK_MASK & ~((1 << 16) - 1)
+
+
+class Rank(IntEnum):
+    """Card rank, ordered low to high (deuce = 0, ace = 12)"""
+
+    TWO = 0
+    THREE = 1
+    FOUR = 2
+    FIVE = 3
+    SIX = 4
+    SEVEN = 5
+    EIGHT = 6
+    NINE = 7
+    TEN = 8
+    JACK = 9
+    QUEEN = 10
+    KING = 11
+    ACE = 12
+
+    @property
+    def char(self) -> str:
+        return RANK_CHARS[self]
+
+    @classmethod
+    def parse(cls, value: str) -> "Rank":
+        index = RANK_CHARS.find(value.upper())
+        if len(value) != 1 or index < 0:
+            raise ValueError(f"Invalid rank: {value!r}")
+        return cls(index)
+
+
+class Suit(IntEnum):
+    """Card suit; the numeric order only matters for indexing"""
+
+    CLUBS = 0
+    DIAMONDS = 1
+    HEARTS = 2
+    SPADES = 3
+
+    @property
+    def char(self) -> str:
+        return SUIT_CHARS[self]
+
+    @classmethod
+    def parse(cls, value: str) -> "Suit":
+        index = SUIT_CHARS.find(value.lower())
+        if len(value) != 1 or index < 0:
+            raise ValueError(f"Invalid suit: {value!r}")
+        return cls(index)
+
+
+class Card:
+    """A single playing card backed by its 0-51 index"""
+
+    __slots__ = ("index",)
+
+    def __init__(self, index: int):
+        if not 0 <= index < 52:
+            raise ValueError(f"Card index out of range: {index}")
+        self.index = index
+
+    @classmethod
+    def of(cls, rank: Rank, suit: Suit) -> "Card":
+        return cls(int(rank) * 4 + int(suit))
+
+    @classmethod
+    def parse(cls, text: str) -> "Card":
+        """Parse two-character notation such as 'As' or 'td'"""
+        text = text.strip()
+        if len(text) != 2:
+            raise ValueError(f"Invalid card: {text!r}")
+        return cls.of(Rank.parse(text[0]), Suit.parse(text[1]))
+
+    @property
+    def rank(self) -> Rank:
+        return Rank(self.index >> 2)
+
+    @property
+    def suit(self) -> Suit:
+        return Suit(self.index & 3)
+
+    @property
+    def mask(self) -> int:
+        return 1 << self.index
+
+    def __eq__(self, other) -> bool:
+        return isinstance(other, Card) and other.index == self.index
+
+    def __lt__(self, other: "Card") -> bool:
+        return self.index < other.index
+
+    def __hash__(self) -> int:
+        return self.index
+
+    def __str__(self) -> str:
+        return RANK_CHARS[self.index >> 2] + SUIT_CHARS[self.index & 3]
+
+    def __repr__(self) -> str:
+        return f"Card('{self}')"
+
+
+CardLike = Union[Card, str, int]
+
+
+def to_card(value: CardLike) -> Card:
+    """Coerce a Card, card string, or index into a Card"""
+    if isinstance(value, Card):
+        return value
+    if isinstance(value, int):
+        return Card(value)
+    return Card.parse(value)
+
+
+def parse_cards(text: str) -> List[Card]:
+    """Parse a run of cards: 'AsKd', 'As Kd', 'As,Kd' and '[As Kd]' all work"""
+    cleaned = "".join(ch for ch in text if ch not in " ,[]\t\n")
+    if len(cleaned) % 2:
+        raise ValueError(f"Invalid card string: {text!r}")
+    cards = [Card.parse(cleaned[i : i + 2]) for i in range(0, len(cleaned), 2)]
+    if len(set(cards)) != len(cards):
+        raise ValueError(f"Duplicate card in: {text!r}")
+    return cards
+
+
+def format_cards(cards: Iterable[CardLike], sep: str = "") -> str:
+    return sep.join(str(to_card(card)) for card in cards)
+
+
+def cards_to_mask(cards: Iterable[CardLike]) -> int:
+    mask = 0
+    for card in cards:
+        mask |= 1 << to_card(card).index
+    return mask
+
+
+def mask_to_cards(mask: int) -> List[Card]:
+    cards = []
+    while mask:
+        low = mask & -mask
+        cards.append(Card(low.bit_length() - 1))
+        mask ^= low
+    return cards
+
+
+def deck_mask(short_deck: bool = False) -> int:
+    """Mask of every card in the full or short deck"""
+    return SHORT_DECK_MASK if short_deck else FULL_DECK_MASK
+
+
+class Deck:
+    """Ordered deck with optional deterministic shuffling
+
+    Passing a seed makes every shuffle reproducible, which the simulators rely on
+    when comparing runs. Dead cards can be removed before dealing, and
+    `short_deck=True` builds the 36-card six-through-ace deck.
+    """
+
+    def __init__(
+        self,
+        seed: Optional[int] = None,
+        dead: Sequence[CardLike] = (),
+        short_deck: bool = False,
+    ):
+        self._rng = random.Random(seed)
+        self.short_deck = short_deck
+        live_mask = deck_mask(short_deck) & ~cards_to_mask(dead)
+        self.cards: List[Card] = mask_to_cards(live_mask)
+
+    def __len__(self) -> int:
+        return len(self.cards)
+
+    def shuffle(self) -> "Deck":
+        self._rng.shuffle(self.cards)
+        return self
+
+    def deal(self, count: int = 1) -> List[Card]:
+        if count > len(self.cards):
+            raise ValueError(f"Cannot deal {count} cards from {len(self.cards)}")
+        dealt, self.cards = self.cards[:count], self.cards[count:]
+        return dealt
+
+    def remove(self, cards: Iterable[CardLike]):
+        mask = cards_to_mask(cards)
+        self.cards = [card for card in self.cards if not mask & card.mask]
+
+    @property
+    def mask(self) -> int:
+        return cards_to_mask(self.cards)
@@ -0,0 +1,481 @@
+#!/usr/bin/env python3
+"""
+Preflop charts and a trainer that quizzes them, stored in the hand database.
+
+A chart covers one spot: a position, a situation, the position it is played
+against (`versus`), and a stack depth in big blinds. Situations:
+
+- `open`: first in (actions such as raise or limp)
+- `3bet`: facing an open from `versus` (3bet, call)
+- `defend`: facing a 3-bet from `versus` after opening (4bet, call)
+
+Each action maps to range notation (`hand_range.py`, weights allowed for
+mixed strategies) and whatever is left folds. `ChartBook` stores charts
+(migration 4 in `handdb.py`) and imports/exports JSON (a list of chart
+objects) or CSV (one `position,situation,versus,stack_bb,action,range` row
+per action). `find` picks the chart with the nearest stack depth.
+
+`Trainer` deals a random chart and combo, grades the answer (any action the
+chart takes at least `ACCEPT_FREQUENCY` of the time counts), and records it,
+so `accuracy` can report progress per day and per chart.
+
+Example:
+    python3 pokertools.py charts import charts.json
+    python3 pokertools.py charts show 3
+    python3 pokertools.py charts train --rounds 20 --situation open
+    python3 pokertools.py charts accuracy
+"""
+
+from __future__ import annotations
+
+import argparse
+import csv
+import io
+import json
+import random
+from dataclasses import dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional
+
+from cards import format_cards
+from handdb import HandDB, default_db_path
+from hand_range import PREFLOP_ORDER, Range, class_combos, hand_class_of
+from pushfold import format_grid
+
+
+SITUATIONS = ("open", "3bet", "defend")
+POSITIONS = ("UTG", "UTG+1", "UTG+2", "LJ", "HJ", "CO", "BTN", "SB", "BB")
+DEFAULT_STACK_BB = 100.0
+# An answer is right when the chart plays that action at least this often
+ACCEPT_FREQUENCY = 0.25
+CSV_FIELDS = ("position", "situation", "versus", "stack_bb", "action", "range")
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+
+
+@dataclass
+class Chart:
+    position: str
+    situation: str
+    # {action: range notation}; fold is whatever no action covers
+    actions: Dict[str, str]
+    versus: str = ""
+    stack_bb: float = DEFAULT_STACK_BB
+    id: Optional[int] = None
+    _ranges: Dict[str, Range] = field(default_factory=dict, repr=False, compare=False)
+
+    def __post_init__(self):
+        self.position = self.position.strip().upper()
+        self.versus = (self.versus or "").strip().upper()
+        self.situation = self.situation.strip().lower()
+        self.stack_bb = float(self.stack_bb)
+        if self.position not in POSITIONS:
+            raise ValueError(f"Position must be one of {', '.join(POSITIONS)}")
+        if self.situation not in SITUATIONS:
+            raise ValueError(f"Situation must be one of {', '.join(SITUATIONS)}")
+        if self.situation != "open" and self.versus not in POSITIONS:
+            raise ValueError(f"A {self.situation} chart needs a versus position")
+        if self.stack_bb <= 0:
+            raise ValueError("Stack depth must be positive")
+        actions = {}
+        for action, text in self.actions.items():
+            action = action.strip().lower()
+            if not action or action == "fold":
+                raise ValueError("Name the non-fold actions; fold is the rest")
+            actions[action] = text
+            self._ranges[action] = Range.parse(text)
+        if not actions:
+            raise ValueError("A chart needs at least one action besides fold")
+        self.actions = actions
+
+    @property
+    def name(self) -> str:
+        versus = f" vs {self.versus}" if self.versus else ""
+        return f"{self.position} {self.situation}{versus} {self.stack_bb:g}bb"
+
+    def frequencies(self, hand_class: str) -> Dict[str, float]:
+        """How often each action (and fold) is taken with a hand class"""
+        combos = class_combos(hand_class)
+        freqs = {
+            action: sum(parsed.weight(combo) for combo in combos) / len(combos)
+            for action, parsed in self._ranges.items()
+        }
+        total = sum(freqs.values())
+        if total > 1:
+            freqs = {action: freq / total for action, freq in freqs.items()}
+        freqs["fold"] = max(0.0, 1 - min(total, 1.0))
+        return freqs
+
+    def grid(self) -> Dict[str, Dict[str, float]]:
+        return {name: self.frequencies(name) for name in PREFLOP_ORDER}
+
+    def to_dict(self) -> dict:
+        return {
+            "id": self.id,
+            "name": self.name,
+            "position": self.position,
+            "situation": self.situation,
+            "versus": self.versus,
+            "stack_bb": self.stack_bb,
+            "actions": self.actions,
+        }
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "Chart":
+        if not isinstance(data, dict) or not isinstance(data.get("actions"), dict):
+            raise ValueError("A chart needs position, situation, and actions")
+        try:
+            return cls(
+                str(data["position"]),
+                str(data["situation"]),
+                {str(key): str(value) for key, value in data["actions"].items()},
+                str(data.get("versus") or ""),
+                data.get("stack_bb") or DEFAULT_STACK_BB,
+            )
+        except KeyError as exc:
+            raise ValueError(f"Chart is missing {exc.args[0]}") from None
+
+
+def grid_labels(chart: Chart) -> Dict[str, str]:
+    """First letter of the main action, with its percentage when mixed"""
+    labels = {}
+    for name, freqs in chart.grid().items():
+        action, freq = max(freqs.items(), key=lambda item: item[1])
+        letter = "." if action == "fold" else action[0].upper()
+        labels[name] = letter if freq >= 0.99 else f"{letter}{round(freq * 100)}"
+    return labels
+
+
+class ChartBook:
+    """Preflop charts on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+
+    def save(self, chart: Chart) -> Chart:
+        """Insert, or replace the chart for the same spot"""
+        with self.conn:
+            self.conn.execute(
+                """
+                INSERT INTO preflop_charts (
+                    position, situation, versus, stack_bb, actions
+                ) VALUES (?, ?, ?, ?, ?)
+                ON CONFLICT (position, situation, versus, stack_bb)
+                DO UPDATE SET actions = excluded.actions
+                """,
+                (
+                    chart.position,
+                    chart.situation,
+                    chart.versus,
+                    chart.stack_bb,
+                    json.dumps(chart.actions),
+                ),
+            )
+        chart.id = self.conn.execute(
+            """
+            SELECT id FROM preflop_charts
+            WHERE position = ? AND situation = ? AND versus = ? AND stack_bb = ?
+            """,
+            (chart.position, chart.situation, chart.versus, chart.stack_bb),
+        ).fetchone()[0]
+        return chart
+
+    def _charts(self, where: str = "", params=()) -> List[Chart]:
+        rows = self.conn.execute(
+            f"""
+            SELECT id, position, situation, versus, stack_bb, actions
+            FROM preflop_charts {where}
+            ORDER BY situation, position, versus, stack_bb
+            """,
+            params,
+        ).fetchall()
+        return [
+            Chart(position, situation, json.loads(actions), versus, stack, chart_id)
+            for chart_id, position, situation, versus, stack, actions in rows
+        ]
+
+    def get(self, chart_id: int) -> Chart:
+        charts = self._charts("WHERE id = ?", (chart_id,))
+        if not charts:
+            raise KeyError(f"No chart {chart_id}")
+        return charts[0]
+
+    def all(
+        self, position: Optional[str] = None, situation: Optional[str] = None
+    ) -> List[Chart]:
+        clauses, params = [], []
+        if position:
+            clauses.append("position = ?")
+            params.append(position.upper())
+        if situation:
+            clauses.append("situation = ?")
+            params.append(situation.lower())
+        where = f"WHERE {' AND '.join(clauses)}" if clauses else ""
+        return self._charts(where, params)
+
+    def find(
+        self,
+        position: str,
+        situation: str,
+        versus: str = "",
+        stack_bb: float = DEFAULT_STACK_BB,
+    ) -> Optional[Chart]:
+        """The chart for a spot at the nearest stack depth"""
+        charts = [
+            chart
+            for chart in self.all(position, situation)
+            if chart.versus == (versus or "").upper()
+        ]
+        if not charts:
+            return None
+        return min(charts, key=lambda chart: abs(chart.stack_bb - stack_bb))
+
+    def delete(self, chart_id: int) -> bool:
+        with self.conn:
+            cursor = self.conn.execute(
+                "DELETE FROM preflop_charts WHERE id = ?", (chart_id,)
+            )
+        return bool(cursor.rowcount)
+
+    def import_charts(self, charts: Iterable[Chart]) -> List[Chart]:
+        return [self.save(chart) for chart in charts]
+
+    def export_json(self) -> str:
+        charts = [chart.to_dict() for chart in self.all()]
+        for chart in charts:
+            del chart["id"], chart["name"]
+        return json.dumps(charts, indent=2)
+
+    def export_csv(self) -> str:
+        output = io.StringIO()
+        writer = csv.writer(output)
+        writer.writerow(CSV_FIELDS)
+        for chart in self.all():
+            for action, text in chart.actions.items():
+                writer.writerow(
+                    [
+                        chart.position,
+                        chart.situation,
+                        chart.versus,
+                        f"{chart.stack_bb:g}",
+                        action,
+                        text,
+                    ]
+                )
+        return output.getvalue()
+
+
+def parse_json(text: str) -> List[Chart]:
+    data = json.loads(text)
+    if isinstance(data, dict):
+        data = data.get("charts", [data])
+    if not isinstance(data, list):
+        raise ValueError("Expected a list of charts")
+    return [Chart.from_dict(item) for item in data]
+
+
+def parse_csv(text: str) -> List[Chart]:
+    """One row per action; rows sharing a spot make one chart"""
+    spots: Dict[tuple, Dict[str, str]] = {}
+    reader = csv.DictReader(io.StringIO(text))
+    missing = set(CSV_FIELDS) - set(reader.fieldnames or ())
+    if missing:
+        raise ValueError(f"CSV is missing columns: {', '.join(sorted(missing))}")
+    for row in reader:
+        key = (
+            row["position"],
+            row["situation"],
+            row["versus"] or "",
+            float(row["stack_bb"] or DEFAULT_STACK_BB),
+        )
+        spots.setdefault(key, {})[row["action"]] = row["range"]
+    return [
+        Chart(position, situation, actions, versus, stack_bb)
+        for (position, situation, versus, stack_bb), actions in spots.items()
+    ]
+
+
+def load_file(path: Path) -> List[Chart]:
+    text = Path(path).read_text()
+    if Path(path).suffix.lower() == ".csv":
+        return parse_csv(text)
+    return parse_json(text)
+
+
+class Trainer:
+    """Random chart spots, graded and recorded for accuracy tracking"""
+
+    def __init__(self, db: HandDB, rng: Optional[random.Random] = None):
+        self.conn = db.conn
+        self.book = ChartBook(db)
+        self.rng = rng or random.Random()
+
+    def question(
+        self, position: Optional[str] = None, situation: Optional[str] = None
+    ) -> dict:
+        charts = self.book.all(position, situation)
+        if not charts:
+            raise ValueError("No charts match; import some first")
+        chart = self.rng.choice(charts)
+        # Deal a real combo so hands turn up as often as at the table
+        first, second = self.rng.sample(range(52), 2)
+        combo = (max(first, second), min(first, second))
+        return {
+            "chart_id": chart.id,
+            "chart": chart.name,
+            "position": chart.position,
+            "situation": chart.situation,
+            "versus": chart.versus,
+            "stack_bb": chart.stack_bb,
+            "hand": format_cards(combo),
+            "hand_class": hand_class_of(combo),
+            "options": [*chart.actions, "fold"],
+        }
+
+    def answer(self, chart_id: int, hand_class: str, action: str) -> dict:
+        chart = self.book.get(chart_id)
+        freqs = chart.frequencies(hand_class)
+        action = action.strip().lower()
+        if action not in freqs:
+            raise ValueError(f"Answer must be one of {', '.join(freqs)}")
+        expected = max(freqs, key=freqs.get)
+        correct = action == expected or freqs[action] >= ACCEPT_FREQUENCY
+        with self.conn:
+            self.conn.execute(
+                """
+                INSERT INTO trainer_answers (
+                    chart_id, chart, hand_class, answer, expected, correct,
+                    answered_at
+                ) VALUES (?, ?, ?, ?, ?, ?, ?)
+                """,
+                (
+                    chart.id,
+                    chart.name,
+                    hand_class,
+                    action,
+                    expected,
+                    int(correct),
+                    datetime.now().strftime(STORED_FORMAT),
+                ),
+            )
+        return {
+            "correct": correct,
+            "expected": expected,
+            "frequencies": {key: round(value, 3) for key, value in freqs.items()},
+        }
+
+    def accuracy(self) -> dict:
+        def grouped(column: str) -> List[dict]:
+            rows = self.conn.execute(
+                f"""
+                SELECT {column}, COUNT(*), SUM(correct) FROM trainer_answers
+                GROUP BY 1 ORDER BY 1
+                """
+            ).fetchall()
+            return [
+                {
+                    "key": key,
+                    "answered": answered,
+                    "correct": correct,
+                    "accuracy": round(correct / answered * 100, 1),
+                }
+                for key, answered, correct in rows
+            ]
+
+        answered, correct = self.conn.execute(
+            "SELECT COUNT(*), COALESCE(SUM(correct), 0) FROM trainer_answers"
+        ).fetchone()
+        return {
+            "answered": answered,
+            "correct": correct,
+            "accuracy": round(correct / answered * 100, 1) if answered else None,
+            "by_day": grouped("substr(answered_at, 1, 10)"),
+            "by_chart": grouped("chart"),
+        }
+
+
+def _train(trainer: Trainer, rounds: int, position, situation):
+    right = 0
+    for round_number in range(1, rounds + 1):
+        quiz = trainer.question(position, situation)
+        options = "/".join(quiz["options"])
+        reply = input(f"{round_number}. {quiz['chart']}: {quiz['hand']} [{options}] ")
+        reply = reply.strip().lower()
+        if reply in ("", "q", "quit"):
+            break
+        # Accept any unambiguous prefix
+        matches = [option for option in quiz["options"] if option.startswith(reply)]
+        if len(matches) != 1:
+            print(f"   pick one of {options}")
+            continue
+        result = trainer.answer(quiz["chart_id"], quiz["hand_class"], matches[0])
+        right += result["correct"]
+        mix = ", ".join(
+            f"{action} {freq:.0%}"
+            for action, freq in result["frequencies"].items()
+            if freq
+        )
+        print(f"   {'right' if result['correct'] else 'wrong'} ({mix})")
+    print(f"Session: {right} right. Overall: {trainer.accuracy()['accuracy']}%")
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    actions = parser.add_subparsers(dest="action", required=True)
+    importer = actions.add_parser("import", help="Load charts from JSON or CSV")
+    importer.add_argument("file", type=Path)
+    exporter = actions.add_parser("export", help="Write every chart to JSON or CSV")
+    exporter.add_argument("file", type=Path)
+    listing = actions.add_parser("list", help="Stored charts")
+    listing.add_argument("--position")
+    listing.add_argument("--situation", choices=SITUATIONS)
+    show = actions.add_parser("show", help="One chart as a 13x13 grid")
+    show.add_argument("id", type=int)
+    remove = actions.add_parser("delete", help="Remove a chart")
+    remove.add_argument("id", type=int)
+    train = actions.add_parser("train", help="Quiz random spots")
+    train.add_argument("--rounds", type=int, default=20)
+    train.add_argument("--position")
+    train.add_argument("--situation", choices=SITUATIONS)
+    actions.add_parser("accuracy", help="Trainer accuracy by day and chart")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        book = ChartBook(db)
+        try:
+            if args.action == "import":
+                charts = book.import_charts(load_file(args.file))
+                print(f"Imported {len(charts)} chart(s)")
+            elif args.action == "export":
+                if args.file.suffix.lower() == ".csv":
+                    args.file.write_text(book.export_csv())
+                else:
+                    args.file.write_text(book.export_json())
+            elif args.action == "list":
+                for chart in book.all(args.position, args.situation):
+                    print(f"{chart.id:>4}  {chart.name}")
+            elif args.action == "show":
+                chart = book.get(args.id)
+                print(f"{chart.name}: " + ", ".join(chart.actions))
+                print(format_grid(grid_labels(chart)))
+            elif args.action == "delete":
+                print(json.dumps({"removed": book.delete(args.id)}))
+            elif args.action == "train":
+                _train(Trainer(db), args.rounds, args.position, args.situation)
+            else:
+                print(json.dumps(Trainer(db).accuracy(), indent=2))
+        except KeyError as exc:
+            raise SystemExit(f"error: {exc.args[0]}") from None
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Preflop charts and trainer")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
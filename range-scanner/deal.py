@@ -0,0 +1,159 @@
+#!/usr/bin/env python3
+"""
+Final-table deal numbers.
+
+Given the stacks still in and the payouts still to be won, three ways to
+split the money:
+
+- ICM chop: each player takes their Malmuth-Harville equity (`icm.py`)
+- chip chop: the prize pool split by chip share, which overpays big stacks
+- save: everyone locks in `save` now and the rest is played for, paid in the
+  same proportions as the remaining payouts; a player's number is the save
+  plus their ICM share of what is left. The save defaults to the smallest
+  remaining payout, what everyone has already locked up.
+
+Amounts are rounded down to `rounding`, with the leftover going to the
+biggest number, so every deal adds up to the prize pool exactly.
+
+Example:
+    python3 pokertools.py deal --stacks 2500000 1400000 600000 \\
+        --payouts 52000 31000 19500
+    python3 pokertools.py deal --stacks 90 60 30 --payouts 500 300 200 --save 250
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import dataclass
+from typing import List, Optional, Sequence
+
+from icm import DEFAULT_ICM_ITERATIONS, icm_equities
+from payouts import DEFAULT_ROUNDING, round_amounts
+
+
+@dataclass
+class Deal:
+    stacks: List[float]
+    payouts: List[float]
+    save: float
+    icm: List[float]
+    chip_chop: List[float]
+    save_deal: List[float]
+    exact: bool = True
+
+    @property
+    def prize_pool(self) -> float:
+        return sum(self.payouts)
+
+    @property
+    def played_for(self) -> float:
+        """What is left on the table after the save"""
+        return self.prize_pool - self.save * len(self.stacks)
+
+    def to_dict(self) -> dict:
+        total = sum(self.stacks)
+        return {
+            "prize_pool": round(self.prize_pool, 2),
+            "save": round(self.save, 2),
+            "played_for": round(self.played_for, 2),
+            "exact": self.exact,
+            "players": [
+                {
+                    "stack": stack,
+                    "chip_share": round(stack / total, 4),
+                    "icm": round(icm, 2),
+                    "chip_chop": round(chop, 2),
+                    "save_deal": round(save, 2),
+                }
+                for stack, icm, chop, save in zip(
+                    self.stacks, self.icm, self.chip_chop, self.save_deal
+                )
+            ],
+        }
+
+
+def calculate_deal(
+    stacks: Sequence[float],
+    payouts: Sequence[float],
+    save: Optional[float] = None,
+    rounding: float = DEFAULT_ROUNDING,
+    iterations: int = DEFAULT_ICM_ITERATIONS,
+    seed: Optional[int] = None,
+) -> Deal:
+    """Deal numbers per player; `payouts` are the places still to be paid"""
+    if rounding < 0:
+        raise ValueError("rounding cannot be negative")
+    # Places nobody is left to finish in are already paid out
+    payouts = [float(payout) for payout in payouts][: len(stacks)]
+    result = icm_equities(stacks, payouts, iterations=iterations, seed=seed)
+    pool = result.prize_pool
+    if not pool:
+        raise ValueError("Nothing left to deal")
+    locked = min(payouts) if len(payouts) == len(stacks) else 0.0
+    save = locked if save is None else float(save)
+    if not 0 <= save * len(stacks) <= pool:
+        raise ValueError(f"save must be between 0 and {pool / len(stacks):g}")
+    left = (pool - save * len(stacks)) / pool
+    save_deal = [save + equity * left for equity in result.equities]
+    return Deal(
+        result.stacks,
+        payouts,
+        save,
+        round_amounts(result.equities, pool, rounding),
+        round_amounts(result.chip_chop, pool, rounding),
+        round_amounts(save_deal, pool, rounding),
+        result.exact,
+    )
+
+
+def format_deal(deal: Deal) -> str:
+    data = deal.to_dict()
+    lines = [
+        f"Prize pool {data['prize_pool']:,.2f}; save {data['save']:,.2f} each, "
+        f"{data['played_for']:,.2f} played for",
+        f"{'Seat':>4} {'Stack':>12} {'Chips %':>8} {'ICM':>11} {'Chip chop':>11} "
+        f"{'Save deal':>11}",
+    ]
+    for seat, player in enumerate(data["players"], start=1):
+        lines.append(
+            f"{seat:>4} {player['stack']:>12,.0f} {player['chip_share']:>8.2%} "
+            f"{player['icm']:>11,.2f} {player['chip_chop']:>11,.2f} "
+            f"{player['save_deal']:>11,.2f}"
+        )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--stacks", type=float, nargs="+", required=True)
+    parser.add_argument(
+        "--payouts",
+        type=float,
+        nargs="+",
+        required=True,
+        help="Payouts still to be won: 1st, 2nd, ...",
+    )
+    parser.add_argument("--save", type=float, help="Locked in per player")
+    parser.add_argument("--rounding", type=float, default=DEFAULT_ROUNDING)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        deal = calculate_deal(args.stacks, args.payouts, args.save, args.rounding)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(deal.to_dict(), indent=2))
+    else:
+        print(format_deal(deal))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="ICM, chip-chop, and save deals")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,643 @@
+#!/usr/bin/env python3
+"""
+Equity calculator for hands and ranges on arbitrary boards.
+
+Up to nine players each hold a fixed hand ("AsKs"), a `hand_range.Range`, or a
+weighted list of combos; card removal between them is exact. When the number of
+(combo assignment x board runout) outcomes is small enough the calculator
+enumerates every one of them; otherwise it switches to Monte Carlo and reports
+a 95% confidence interval per player. Results also carry split-pot frequencies
+and, for hold'em ranges, equity per starting hand class. Work is spread over a
+multiprocessing pool, falling back to sequential mode where forking isn't
+allowed.
+
+Monte Carlo runs in shards of SAMPLE_SHARD samples, shard i seeded with
+seed + i and merged in shard order, so a seeded run gives the same answer on
+any number of workers. After each round of shards a `progress` callback gets
+the result so far, and sampling stops early once every player's 95% interval
+is within `target_ci`.
+
+Example:
+    python3 equity.py AsKs QdQc --board Ah7c2d
+    python3 equity.py AhAd "QQ+,AKs" --iterations 200000
+    python3 equity.py "[10%]" "22+,A2s+" JhTh --board 9h8c2d --breakdown
+    python3 equity.py AsKs "QQ+,AK" --target-ci 0.002 --progress
+"""
+
+from __future__ import annotations
+
+import argparse
+import math
+import random
+import re
+import sys
+from dataclasses import dataclass, field
+from itertools import combinations, product
+from multiprocessing import Pool, cpu_count
+from typing import Callable, Dict, Iterable, List, Optional, Sequence, Tuple, Union
+
+from cards import (
+    CardLike,
+    cards_to_mask,
+    deck_mask,
+    mask_to_cards,
+    parse_cards,
+    to_card,
+)
+from game_evaluators import Evaluator, HandScore, get_evaluator
+from hand_range import Range, hand_class_of
+
+
+Combo = Tuple[int, ...]
+WeightedCombos = List[Tuple[Combo, float]]
+PlayerSpec = Union[
+    str, Sequence[CardLike], Sequence[Tuple[Union[str, Sequence[CardLike]], float]]
+]
+
+# Above this many evaluated outcomes the calculator samples instead of enumerating
+DEFAULT_EXHAUSTIVE_LIMIT = 2_000_000
+DEFAULT_ITERATIONS = 100_000
+# Minimum work per worker before a process pool is worth its startup cost
+MIN_PARALLEL_OUTCOMES = 50_000
+# Monte Carlo samples per seeded shard; progress is reported between rounds
+SAMPLE_SHARD = 10_000
+Z_95 = 1.96
+# Nine-handed is the biggest table any supported game deals
+MAX_PLAYERS = 9
+HAND_PATTERN = re.compile(r"^([2-9TJQKA][cdhs])+$")
+
+
+@dataclass
+class EquityResult:
+    """Per-player equity (pot share), outright wins, ties, and 95% intervals
+
+    `split_pots` maps how many players shared the (high) pot to how often that
+    happened. `hand_classes` breaks each hold'em player's equity down by starting
+    hand class: class -> (share of the player's outcomes, equity with it).
+    """
+
+    equities: List[float]
+    wins: List[float]
+    ties: List[float]
+    ci95: List[float]
+    iterations: int
+    exhaustive: bool
+    game: str = "holdem"
+    board: str = ""
+    split_pots: Dict[int, float] = field(default_factory=dict)
+    hand_classes: List[Dict[str, Tuple[float, float]]] = field(default_factory=list)
+
+    @property
+    def tie_frequency(self) -> float:
+        return sum(self.split_pots.values())
+
+    def to_dict(self) -> dict:
+        players = []
+        for idx in range(len(self.equities)):
+            entry = {
+                "equity": round(self.equities[idx], 6),
+                "win": round(self.wins[idx], 6),
+                "tie": round(self.ties[idx], 6),
+                "ci95": round(self.ci95[idx], 6),
+            }
+            if self.hand_classes:
+                entry["hand_classes"] = {
+                    label: {"frequency": round(freq, 6), "equity": round(equity, 6)}
+                    for label, (freq, equity) in self.hand_classes[idx].items()
+                }
+            players.append(entry)
+        return {
+            "game": self.game,
+            "board": self.board,
+            "exhaustive": self.exhaustive,
+            "iterations": self.iterations,
+            "tie_frequency": round(self.tie_frequency, 6),
+            "split_pots": {
+                str(ways): round(freq, 6) for ways, freq in self.split_pots.items()
+            },
+            "players": players,
+        }
+
+
+@dataclass
+class _Tally:
+    """Accumulates weighted pot shares; mergeable across workers"""
+
+    players: int
+    share: List[float] = field(default_factory=list)
+    share_sq: List[float] = field(default_factory=list)
+    wins: List[float] = field(default_factory=list)
+    ties: List[float] = field(default_factory=list)
+    splits: Dict[int, float] = field(default_factory=dict)
+    # Per player: hand class -> [weight, weighted share]
+    by_class: List[Dict[str, List[float]]] = field(default_factory=list)
+    weight: float = 0.0
+    samples: int = 0
+
+    def __post_init__(self):
+        for name in ("share", "share_sq", "wins", "ties"):
+            if not getattr(self, name):
+                setattr(self, name, [0.0] * self.players)
+        if not self.by_class:
+            self.by_class = [{} for _ in range(self.players)]
+
+    def add(
+        self,
+        shares: List[float],
+        weight: float = 1.0,
+        split_ways: int = 1,
+        classes: Optional[List[str]] = None,
+    ):
+        self.weight += weight
+        self.samples += 1
+        if split_ways > 1:
+            self.splits[split_ways] = self.splits.get(split_ways, 0.0) + weight
+        for idx, label in enumerate(classes or ()):
+            entry = self.by_class[idx].setdefault(label, [0.0, 0.0])
+            entry[0] += weight
+            entry[1] += shares[idx] * weight
+        for idx, value in enumerate(shares):
+            if not value:
+                continue
+            self.share[idx] += value * weight
+            self.share_sq[idx] += value * value * weight
+            if value == 1.0:
+                self.wins[idx] += weight
+            else:
+                self.ties[idx] += weight
+
+    def merge(self, other: "_Tally"):
+        self.weight += other.weight
+        self.samples += other.samples
+        for idx in range(self.players):
+            self.share[idx] += other.share[idx]
+            self.share_sq[idx] += other.share_sq[idx]
+            self.wins[idx] += other.wins[idx]
+            self.ties[idx] += other.ties[idx]
+            for label, (weight, share) in other.by_class[idx].items():
+                entry = self.by_class[idx].setdefault(label, [0.0, 0.0])
+                entry[0] += weight
+                entry[1] += share
+        for ways, weight in other.splits.items():
+            self.splits[ways] = self.splits.get(ways, 0.0) + weight
+
+
+def settle(scores: Sequence[HandScore], split: bool = False) -> List[float]:
+    """Fraction of the pot each player wins at showdown"""
+    shares = [0.0] * len(scores)
+    best_low = max(score.low for score in scores) if split else 0
+    high_pot = 0.5 if best_low > 0 else 1.0
+
+    best = max(score.main for score in scores)
+    winners = [idx for idx, score in enumerate(scores) if score.main == best]
+    for idx in winners:
+        shares[idx] += high_pot / len(winners)
+
+    if best_low > 0:
+        low_winners = [
+            idx for idx, score in enumerate(scores) if score.low == best_low
+        ]
+        for idx in low_winners:
+            shares[idx] += 0.5 / len(low_winners)
+    return shares
+
+
+def split_ways(scores: Sequence[HandScore]) -> int:
+    """How many players share the best main-pot score"""
+    best = max(score.main for score in scores)
+    return sum(1 for score in scores if score.main == best)
+
+
+def _class_labels(players: List[WeightedCombos]) -> Optional[List[Dict[Combo, str]]]:
+    """Starting-hand class of every combo, for two-card games only"""
+    if any(len(combos[0][0]) != 2 for combos in players):
+        return None
+    return [{combo: hand_class_of(combo) for combo, _ in combos} for combos in players]
+
+
+def _showdown(
+    tally: _Tally,
+    evaluator: Evaluator,
+    hands: List[Combo],
+    runout: Sequence[int],
+    labels: Optional[List[Dict[Combo, str]]],
+    weight: float = 1.0,
+):
+    scores = [evaluator.score(hand, runout) for hand in hands]
+    classes = (
+        [labels[idx][hand] for idx, hand in enumerate(hands)] if labels else None
+    )
+    tally.add(settle(scores, evaluator.split), weight, split_ways(scores), classes)
+
+
+def _combo(cards: Union[str, Sequence[CardLike]]) -> Combo:
+    if isinstance(cards, str):
+        return tuple(card.index for card in parse_cards(cards))
+    return tuple(to_card(card).index for card in cards)
+
+
+def _is_weighted_pair(item) -> bool:
+    """True for (combo, weight) entries as opposed to a bare card"""
+    return (
+        isinstance(item, (tuple, list))
+        and len(item) == 2
+        and isinstance(item[0], (str, tuple, list))
+        and isinstance(item[1], (int, float))
+    )
+
+
+def normalize_player(spec: PlayerSpec, hole_cards: int) -> WeightedCombos:
+    """Turn a hand string, card list, range object, or weighted combos into combos"""
+    if hasattr(spec, "weighted_combos"):
+        combos = [(tuple(combo), weight) for combo, weight in spec.weighted_combos()]
+    elif isinstance(spec, str):
+        combos = [(_combo(spec), 1.0)]
+    elif spec and _is_weighted_pair(spec[0]):
+        combos = [(_combo(combo), float(weight)) for combo, weight in spec]
+    else:
+        combos = [(_combo(spec), 1.0)]
+
+    combos = [(combo, weight) for combo, weight in combos if weight > 0]
+    if not combos:
+        raise ValueError("Player range is empty")
+    for combo, _ in combos:
+        if len(combo) != hole_cards or len(set(combo)) != hole_cards:
+            raise ValueError(f"Expected {hole_cards} distinct hole cards per combo")
+    return combos
+
+
+def _live_combos(players: List[WeightedCombos], dead: int) -> List[WeightedCombos]:
+    live = []
+    for combos in players:
+        filtered = [
+            (combo, weight)
+            for combo, weight in combos
+            if not dead & cards_to_mask(combo)
+        ]
+        if not filtered:
+            raise ValueError("A player's range is blocked by the board/dead cards")
+        live.append(filtered)
+    return live
+
+
+def _assignments(players: List[WeightedCombos]):
+    """Every non-conflicting combo assignment with its joint weight"""
+    for choice in product(*players):
+        mask = 0
+        weight = 1.0
+        for combo, combo_weight in choice:
+            combo_mask = cards_to_mask(combo)
+            if mask & combo_mask:
+                break
+            mask |= combo_mask
+            weight *= combo_weight
+        else:
+            yield [combo for combo, _ in choice], mask, weight
+
+
+def _enumerate_task(args) -> _Tally:
+    """Worker: enumerate runouts whose lowest new card is in `first_cards`"""
+    players, board, dead, game, missing, first_cards = args
+    evaluator = get_evaluator(game)
+    labels = _class_labels(players)
+    tally = _Tally(len(players))
+    base_mask = dead | cards_to_mask(board)
+    for hands, hands_mask, weight in _assignments(players):
+        used = base_mask | hands_mask
+        if missing == 0:
+            _showdown(tally, evaluator, hands, board, labels, weight)
+            continue
+        available = deck_mask(evaluator.short_deck) & ~used
+        deck = [card.index for card in mask_to_cards(available)]
+        for first in first_cards:
+            if not available & (1 << first):
+                continue
+            higher = [card for card in deck if card > first]
+            for rest in combinations(higher, missing - 1):
+                runout = list(board) + [first, *rest]
+                _showdown(tally, evaluator, hands, runout, labels, weight)
+    return tally
+
+
+def _sample_task(args) -> _Tally:
+    """Worker: Monte Carlo with rejection sampling for card removal"""
+    players, board, dead, game, iterations, seed = args
+    evaluator = get_evaluator(game)
+    rng = random.Random(seed)
+    labels = _class_labels(players)
+    tally = _Tally(len(players))
+    missing = evaluator.board_cards - len(board)
+    base_mask = dead | cards_to_mask(board)
+    available = deck_mask(evaluator.short_deck) & ~base_mask
+    deck = [card.index for card in mask_to_cards(available)]
+    cumulative = []
+    for combos in players:
+        running, weights = 0.0, []
+        for _, weight in combos:
+            running += weight
+            weights.append(running)
+        cumulative.append(weights)
+
+    done = 0
+    attempts = 0
+    while done < iterations:
+        attempts += 1
+        if attempts > 10_000 and not done:
+            raise ValueError("No valid deal: the players' ranges always conflict")
+        hands = []
+        used = 0
+        for combos, weights in zip(players, cumulative):
+            combo = rng.choices(combos, cum_weights=weights)[0][0]
+            combo_mask = cards_to_mask(combo)
+            if used & combo_mask:
+                break
+            used |= combo_mask
+            hands.append(combo)
+        else:
+            runout = list(board)
+            while len(runout) < len(board) + missing:
+                card = deck[rng.randrange(len(deck))]
+                if used & (1 << card):
+                    continue
+                used |= 1 << card
+                runout.append(card)
+            _showdown(tally, evaluator, hands, runout, labels)
+            done += 1
+    return tally
+
+
+def _run_tasks(worker, tasks: List, workers: int) -> _Tally:
+    results: List[_Tally] = []
+    if workers > 1 and len(tasks) > 1:
+        try:
+            with Pool(min(workers, len(tasks))) as pool:
+                results = pool.map(worker, tasks)
+        except PermissionError:
+            results = []
+    if not results:
+        results = [worker(task) for task in tasks]
+    total = results[0]
+    for tally in results[1:]:
+        total.merge(tally)
+    return total
+
+
+def _run_rounds(worker, tasks: List, workers: int):
+    """Yield the tallies of `workers` tasks at a time, in task order"""
+    pool = None
+    if workers > 1 and len(tasks) > 1:
+        try:
+            pool = Pool(min(workers, len(tasks)))
+        except PermissionError:
+            pool = None
+    try:
+        for start in range(0, len(tasks), workers):
+            batch = tasks[start : start + workers]
+            yield pool.map(worker, batch) if pool else [worker(t) for t in batch]
+    finally:
+        if pool is not None:
+            pool.terminate()
+
+
+def _outcome_count(players: List[WeightedCombos], deck_size: int, missing: int) -> int:
+    assignments = 1
+    for combos in players:
+        assignments *= len(combos)
+    return assignments * math.comb(deck_size, missing)
+
+
+def _card_list(cards: Union[str, Sequence[CardLike]]) -> List:
+    if isinstance(cards, str):
+        return parse_cards(cards)
+    return [to_card(card) for card in cards]
+
+
+def _result(
+    tally: _Tally,
+    exhaustive: bool,
+    evaluator: Evaluator,
+    board_cards: List,
+    combos: List[WeightedCombos],
+) -> EquityResult:
+    """Equities, intervals, and breakdowns from a (possibly partial) tally"""
+    equities = [share / tally.weight for share in tally.share]
+    ci95 = []
+    for idx, mean in enumerate(equities):
+        if exhaustive:
+            ci95.append(0.0)
+            continue
+        variance = max(tally.share_sq[idx] / tally.weight - mean * mean, 0.0)
+        ci95.append(Z_95 * math.sqrt(variance / tally.samples))
+
+    hand_classes = []
+    if _class_labels(combos):
+        for entries in tally.by_class:
+            hand_classes.append(
+                {
+                    label: (weight / tally.weight, share / weight)
+                    for label, (weight, share) in sorted(
+                        entries.items(), key=lambda item: -item[1][0]
+                    )
+                }
+            )
+
+    return EquityResult(
+        equities=equities,
+        wins=[wins / tally.weight for wins in tally.wins],
+        ties=[ties / tally.weight for ties in tally.ties],
+        ci95=ci95,
+        iterations=tally.samples,
+        exhaustive=exhaustive,
+        game=evaluator.game,
+        board="".join(str(card) for card in board_cards),
+        split_pots={
+            ways: weight / tally.weight for ways, weight in sorted(tally.splits.items())
+        },
+        hand_classes=hand_classes,
+    )
+
+
+def calculate_equity(
+    players: Sequence[PlayerSpec],
+    board: Union[str, Sequence[CardLike]] = "",
+    dead: Union[str, Sequence[CardLike]] = "",
+    game: str = "holdem",
+    iterations: int = DEFAULT_ITERATIONS,
+    exhaustive_limit: int = DEFAULT_EXHAUSTIVE_LIMIT,
+    workers: Optional[int] = None,
+    seed: Optional[int] = None,
+    target_ci: Optional[float] = None,
+    progress: Optional[Callable[[EquityResult], None]] = None,
+) -> EquityResult:
+    """Compute each player's share of the pot
+
+    `iterations` only applies when sampling, and is then a cap: with
+    `target_ci` sampling stops as soon as every 95% interval is that tight.
+    Set `exhaustive_limit=0` to force Monte Carlo or a huge value to force
+    enumeration. `progress` is called with the partial result between rounds
+    of Monte Carlo shards.
+    """
+    evaluator: Evaluator = get_evaluator(game)
+    if evaluator.board_cards == 0:
+        raise ValueError(f"Equity needs a community-card game, not {game}")
+    if len(players) < 2:
+        raise ValueError("Equity needs at least two players")
+    if len(players) > MAX_PLAYERS:
+        raise ValueError(f"Equity supports at most {MAX_PLAYERS} players")
+    if target_ci is not None and target_ci <= 0:
+        raise ValueError("target_ci must be positive")
+
+    board_cards = _card_list(board)
+    dead_cards = _card_list(dead)
+    if len(board_cards) > evaluator.board_cards:
+        raise ValueError(f"Board has more than {evaluator.board_cards} cards")
+    board_idx = [card.index for card in board_cards]
+    dead_mask = cards_to_mask(dead_cards)
+    if dead_mask & cards_to_mask(board_idx):
+        raise ValueError("Dead cards overlap the board")
+
+    combos = [normalize_player(spec, evaluator.hole_cards) for spec in players]
+    combos = _live_combos(combos, dead_mask | cards_to_mask(board_idx))
+    missing = evaluator.board_cards - len(board_idx)
+    known = dead_mask | cards_to_mask(board_idx)
+    deck_size = bin(deck_mask(evaluator.short_deck) & ~known).count("1")
+    deck_size -= evaluator.hole_cards * len(players)
+    outcomes = _outcome_count(combos, deck_size, missing)
+    workers = workers or cpu_count()
+
+    exhaustive = outcomes <= exhaustive_limit
+    if exhaustive:
+        first_cards = [None] if missing == 0 else list(range(52))
+        task_count = workers if outcomes >= MIN_PARALLEL_OUTCOMES else 1
+        chunks = [first_cards[idx::task_count] for idx in range(task_count)]
+        tasks = [
+            (combos, board_idx, dead_mask, game, missing, chunk)
+            for chunk in chunks
+            if chunk
+        ]
+        tally = _run_tasks(_enumerate_task, tasks, workers)
+        if not tally.weight:
+            raise ValueError("No valid deal: the players' hands all conflict")
+        return _result(tally, True, evaluator, board_cards, combos)
+
+    if iterations <= 0:
+        raise ValueError("iterations must be positive")
+    base_seed = seed if seed is not None else random.randrange(1 << 30)
+    shards = [SAMPLE_SHARD] * (iterations // SAMPLE_SHARD)
+    if iterations % SAMPLE_SHARD:
+        shards.append(iterations % SAMPLE_SHARD)
+    tasks = [
+        (combos, board_idx, dead_mask, game, count, base_seed + idx)
+        for idx, count in enumerate(shards)
+    ]
+    if iterations < MIN_PARALLEL_OUTCOMES:
+        workers = 1
+    tally = _Tally(len(combos))
+    for tallies in _run_rounds(_sample_task, tasks, workers):
+        for shard in tallies:
+            tally.merge(shard)
+        result = _result(tally, False, evaluator, board_cards, combos)
+        if progress is not None:
+            progress(result)
+        if target_ci is not None and max(result.ci95) <= target_ci:
+            break
+    return result
+
+
+def parse_player_arg(text: str) -> PlayerSpec:
+    """CLI helper: 'AsKs' or 'AsAd2h3h' is a hand, anything else range notation"""
+    if HAND_PATTERN.match(text):
+        return text
+    return Range.parse(text)
+
+
+def format_result(
+    result: EquityResult, labels: Iterable[str], breakdown: bool = False
+) -> str:
+    mode = "exhaustive" if result.exhaustive else "monte carlo"
+    lines = [
+        f"Game: {result.game}  Board: {result.board or '-'}  "
+        f"({mode}, {result.iterations:,} outcomes)"
+    ]
+    for idx, label in enumerate(labels):
+        interval = f" +/- {result.ci95[idx] * 100:.2f}%" if result.ci95[idx] else ""
+        lines.append(
+            f"  {label:<20} equity {result.equities[idx] * 100:6.2f}%{interval}"
+            f"  win {result.wins[idx] * 100:6.2f}%  tie {result.ties[idx] * 100:6.2f}%"
+        )
+    if result.split_pots:
+        splits = ", ".join(
+            f"{ways}-way {freq * 100:.2f}%" for ways, freq in result.split_pots.items()
+        )
+        lines.append(f"  split pots: {splits}")
+    if breakdown and result.hand_classes:
+        for label, classes in zip(labels, result.hand_classes):
+            if len(classes) < 2:
+                continue
+            lines.append(f"  {label} by hand class:")
+            for hand_class, (freq, equity) in classes.items():
+                lines.append(
+                    f"    {hand_class:<4} {freq * 100:6.2f}% of hands"
+                    f"  equity {equity * 100:6.2f}%"
+                )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument(
+        "players", nargs="+", help="Hands such as AsKs or ranges such as 'QQ+,AK'"
+    )
+    parser.add_argument("--board", default="")
+    parser.add_argument("--dead", default="")
+    parser.add_argument("--game", default="holdem")
+    parser.add_argument("--iterations", type=int, default=DEFAULT_ITERATIONS)
+    parser.add_argument(
+        "--exhaustive-limit", type=int, default=DEFAULT_EXHAUSTIVE_LIMIT
+    )
+    parser.add_argument("--workers", type=int)
+    parser.add_argument("--seed", type=int)
+    parser.add_argument(
+        "--target-ci", type=float, help="Stop sampling once every 95%% CI is this tight"
+    )
+    parser.add_argument(
+        "--progress", action="store_true", help="Print Monte Carlo progress"
+    )
+    parser.add_argument(
+        "--breakdown", action="store_true", help="Show equity by starting hand class"
+    )
+
+
+def _print_progress(result: EquityResult):
+    shares = "  ".join(f"{equity * 100:6.2f}%" for equity in result.equities)
+    widest = max(result.ci95) * 100
+    line = f"  {result.iterations:>10,} samples  {shares}  +/- {widest:.3f}%"
+    print(line, file=sys.stderr)
+
+
+def run(args: argparse.Namespace):
+    try:
+        result = calculate_equity(
+            [parse_player_arg(player) for player in args.players],
+            board=args.board,
+            dead=args.dead,
+            game=args.game,
+            iterations=args.iterations,
+            exhaustive_limit=args.exhaustive_limit,
+            workers=args.workers,
+            seed=args.seed,
+            target_ci=args.target_ci,
+            progress=_print_progress if args.progress else None,
+        )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    print(format_result(result, args.players, args.breakdown))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Poker equity calculator")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,231 @@
+"""
+Cache of equity results keyed by a suit-isomorphic canonical form.
+
+AsKs vs QdQc and AhKh vs QsQc are the same matchup with the suits renamed, so
+they share one cache entry: `canonical_key` relabels the suits of every
+player's combos, the board, and the dead cards under all 24 suit permutations
+and keeps the smallest form. Player order is kept, so the cached equities map
+straight back onto the query's players. The key also covers the game and the
+work limits (iterations, exhaustive limit, seed, target interval), so a
+request with a bigger budget is computed again rather than served a rougher
+sample.
+
+`EquityCache` holds the most recent `size` results in memory (LRU) and, given a
+path, also writes them to a small SQLite file so they survive restarts.
+`/api/equity` serves repeat matchups from it without running the engine.
+
+Example:
+    cache = EquityCache(path=Path("equity_cache.sqlite"))
+    result, hit = cache.calculate(["AsKs", "QdQc"])
+    result, hit = cache.calculate(["AhKh", "QsQc"])   # hit is True
+"""
+
+from __future__ import annotations
+
+import hashlib
+import json
+import sqlite3
+import threading
+from collections import OrderedDict
+from dataclasses import asdict, replace
+from itertools import permutations
+from pathlib import Path
+from typing import Callable, List, Optional, Sequence, Tuple, Union
+
+from cards import CardLike, format_cards, parse_cards, to_card
+from equity import (
+    DEFAULT_EXHAUSTIVE_LIMIT,
+    DEFAULT_ITERATIONS,
+    EquityResult,
+    PlayerSpec,
+    calculate_equity,
+    normalize_player,
+)
+from game_evaluators import get_evaluator
+
+
+DEFAULT_CACHE_SIZE = 4096
+SUIT_ORDERS = list(permutations(range(4)))
+
+
+def _indices(cards: Union[str, Sequence[CardLike]]) -> List[int]:
+    cards = parse_cards(cards) if isinstance(cards, str) else map(to_card, cards)
+    return [card.index for card in cards]
+
+
+def _relabel(cards: Sequence[int], order: Tuple[int, ...]) -> Tuple[int, ...]:
+    relabeled = ((card & ~3) | order[card & 3] for card in cards)
+    return tuple(sorted(relabeled, reverse=True))
+
+
+def canonical_form(
+    players: Sequence[PlayerSpec],
+    board: Union[str, Sequence[CardLike]] = "",
+    dead: Union[str, Sequence[CardLike]] = "",
+    game: str = "holdem",
+) -> list:
+    """[game, board, dead, players] with suits relabeled to the smallest form"""
+    evaluator = get_evaluator(game)
+    combos = [normalize_player(spec, evaluator.hole_cards) for spec in players]
+    board_idx, dead_idx = _indices(board), _indices(dead)
+    best = None
+    for order in SUIT_ORDERS:
+        form = (
+            _relabel(board_idx, order),
+            _relabel(dead_idx, order),
+            tuple(
+                tuple(
+                    sorted(
+                        (_relabel(combo, order), round(weight, 6))
+                        for combo, weight in player
+                    )
+                )
+                for player in combos
+            ),
+        )
+        if best is None or form < best:
+            best = form
+    return [evaluator.game, *best]
+
+
+def canonical_key(
+    players: Sequence[PlayerSpec],
+    board: Union[str, Sequence[CardLike]] = "",
+    dead: Union[str, Sequence[CardLike]] = "",
+    game: str = "holdem",
+    **limits,
+) -> str:
+    """sha256 of the canonical form plus the work limits"""
+    form = canonical_form(players, board, dead, game)
+    text = json.dumps([form, sorted(limits.items())], separators=(",", ":"))
+    return hashlib.sha256(text.encode("utf-8")).hexdigest()
+
+
+def _dump(result: EquityResult) -> str:
+    return json.dumps(asdict(result))
+
+
+def _load(text: str) -> EquityResult:
+    data = json.loads(text)
+    data["split_pots"] = {int(ways): freq for ways, freq in data["split_pots"].items()}
+    data["hand_classes"] = [
+        {label: tuple(values) for label, values in classes.items()}
+        for classes in data["hand_classes"]
+    ]
+    return EquityResult(**data)
+
+
+class EquityCache:
+    """LRU of equity results, optionally persisted to SQLite; thread-safe"""
+
+    def __init__(self, size: int = DEFAULT_CACHE_SIZE, path: Optional[Path] = None):
+        if size <= 0:
+            raise ValueError("Cache size must be positive")
+        self.size = size
+        self.path = path
+        self.entries: OrderedDict[str, EquityResult] = OrderedDict()
+        self.hits = 0
+        self.misses = 0
+        self.lock = threading.Lock()
+        self.conn: Optional[sqlite3.Connection] = None
+        if path is not None:
+            self.conn = sqlite3.connect(Path(path).as_posix(), check_same_thread=False)
+            self.conn.execute(
+                "CREATE TABLE IF NOT EXISTS equity_cache "
+                "(key TEXT PRIMARY KEY, result TEXT NOT NULL)"
+            )
+            self.conn.commit()
+
+    def _remember(self, key: str, result: EquityResult):
+        self.entries[key] = result
+        self.entries.move_to_end(key)
+        while len(self.entries) > self.size:
+            self.entries.popitem(last=False)
+
+    def get(self, key: str) -> Optional[EquityResult]:
+        with self.lock:
+            result = self.entries.get(key)
+            if result is not None:
+                self.entries.move_to_end(key)
+            elif self.conn is not None:
+                row = self.conn.execute(
+                    "SELECT result FROM equity_cache WHERE key = ?", (key,)
+                ).fetchone()
+                if row:
+                    result = _load(row[0])
+                    self._remember(key, result)
+            if result is None:
+                self.misses += 1
+            else:
+                self.hits += 1
+            return result
+
+    def put(self, key: str, result: EquityResult):
+        with self.lock:
+            self._remember(key, result)
+            if self.conn is not None:
+                self.conn.execute(
+                    "INSERT OR REPLACE INTO equity_cache (key, result) VALUES (?, ?)",
+                    (key, _dump(result)),
+                )
+                self.conn.commit()
+
+    def calculate(
+        self,
+        players: Sequence[PlayerSpec],
+        board: Union[str, Sequence[CardLike]] = "",
+        dead: Union[str, Sequence[CardLike]] = "",
+        game: str = "holdem",
+        iterations: int = DEFAULT_ITERATIONS,
+        exhaustive_limit: int = DEFAULT_EXHAUSTIVE_LIMIT,
+        workers: Optional[int] = None,
+        seed: Optional[int] = None,
+        target_ci: Optional[float] = None,
+        progress: Optional[Callable[[EquityResult], None]] = None,
+    ) -> Tuple[EquityResult, bool]:
+        """`calculate_equity` through the cache; returns (result, cache hit)"""
+        key = canonical_key(
+            players,
+            board,
+            dead,
+            game,
+            iterations=iterations,
+            exhaustive_limit=exhaustive_limit,
+            seed=seed,
+            target_ci=target_ci,
+        )
+        cached = self.get(key)
+        if cached is not None:
+            # The stored board may be another suit relabeling of this one
+            shown = format_cards(_indices(board)) if board else ""
+            return replace(cached, board=shown), True
+        result = calculate_equity(
+            players,
+            board=board,
+            dead=dead,
+            game=game,
+            iterations=iterations,
+            exhaustive_limit=exhaustive_limit,
+            workers=workers,
+            seed=seed,
+            target_ci=target_ci,
+            progress=progress,
+        )
+        self.put(key, result)
+        return result, False
+
+    def stats(self) -> dict:
+        with self.lock:
+            return {
+                "entries": len(self.entries),
+                "size": self.size,
+                "hits": self.hits,
+                "misses": self.misses,
+                "persistent": self.conn is not None,
+            }
+
+    def close(self):
+        if self.conn is not None:
+            self.conn.close()
+            self.conn = None
+
@@ -0,0 +1,361 @@
+#!/usr/bin/env python3
+"""
+CSV and Excel (.xlsx) export of player stats, sessions, and rake.
+
+Four tables come out of the hand database:
+
+- `stats`: one row per player (and per position/stakes group with `--by`)
+- `sessions`: the live sessions logged with `bankroll.py`
+- `rake`: hands, raked hands, pot, and rake per stakes
+- `pace`: hands per hour and hand duration per table session (`pace.py`)
+
+Columns are configurable per table (`--columns stats=player,hands,vpip_pct`);
+`available_columns` lists what each table can export. CSV holds one table per
+file; an .xlsx workbook gets one sheet per table. The workbook is written by
+hand with `zipfile` (inline strings, no styles), which every spreadsheet
+program opens, so no third-party package is needed.
+
+Example:
+    python3 pokertools.py export --format xlsx --output club.xlsx
+    python3 pokertools.py export --table stats --columns stats=player,hands,bb_per_100
+    curl -o club.xlsx "http://localhost:8080/api/export?format=xlsx"
+"""
+
+from __future__ import annotations
+
+import argparse
+import csv
+import io
+import sys
+import zipfile
+from dataclasses import fields
+from pathlib import Path
+from typing import Callable, Dict, List, Optional, Sequence, Tuple
+from xml.sax.saxutils import escape
+
+from bankroll import Bankroll, BankrollSession
+from handdb import HandDB, default_db_path
+from pace import TableSession, load_pace
+from stats import BREAKDOWNS, PlayerStats, load_stats
+
+
+FORMATS = ("csv", "xlsx")
+CONTENT_TYPES = {
+    "csv": "text/csv; charset=utf-8",
+    "xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+STATS_COLUMNS = ["player", "group"] + list(PlayerStats().to_dict())
+SESSION_COLUMNS = [item.name for item in fields(BankrollSession)] + [
+    "result",
+    "hours",
+    "stakes",
+]
+MAIN_NS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+RELATIONSHIPS_NS = (
+    "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+PACKAGE_NS = "http://schemas.openxmlformats.org/package/2006/relationships"
+RAKE_COLUMNS = ["stakes", "hands", "raked_hands", "pot", "rake", "rake_per_hand"]
+PACE_COLUMNS = [item.name for item in fields(TableSession)]
+DEFAULT_COLUMNS = {
+    "stats": [
+        "player",
+        "group",
+        "hands",
+        "vpip_pct",
+        "pfr_pct",
+        "three_bet_pct",
+        "cbet_pct",
+        "wtsd_pct",
+        "wsd_pct",
+        "net",
+        "bb_per_100",
+    ],
+    "sessions": [
+        "started_at",
+        "ended_at",
+        "venue",
+        "game",
+        "stakes",
+        "buy_in",
+        "cash_out",
+        "result",
+        "hours",
+    ],
+    "rake": RAKE_COLUMNS,
+    "pace": PACE_COLUMNS,
+}
+
+Rows = List[Dict[str, object]]
+
+
+def _stats_rows(
+    db: HandDB, players: Optional[Sequence[str]], by: Optional[str]
+) -> Rows:
+    rows: Rows = []
+    for player, groups in load_stats(db, players, by).items():
+        for group, counters in groups.items():
+            rows.append({"player": player, "group": group, **counters.to_dict()})
+    return rows
+
+
+def _session_rows(db: HandDB, players, by) -> Rows:
+    return [session.to_dict() for session in Bankroll(db).sessions()]
+
+
+def _rake_rows(db: HandDB, players, by) -> Rows:
+    rows: Rows = []
+    for tournament, small_blind, big_blind, hands, raked, pot, rake in db.conn.execute(
+        """
+        SELECT tournament_id IS NOT NULL, small_blind, big_blind, COUNT(*),
+               SUM(rake > 0), SUM(total_pot), SUM(rake)
+        FROM hands
+        GROUP BY 1, 2, 3
+        ORDER BY 1, 3, 2
+        """
+    ):
+        rows.append(
+            {
+                "stakes": f"{'T' if tournament else ''}{small_blind:g}/{big_blind:g}",
+                "hands": hands,
+                "raked_hands": raked,
+                "pot": round(pot or 0, 2),
+                "rake": round(rake or 0, 2),
+                "rake_per_hand": round((rake or 0) / hands, 4),
+            }
+        )
+    return rows
+
+
+def _pace_rows(db: HandDB, players, by) -> Rows:
+    return [session.to_dict() for session in load_pace(db)]
+
+
+TABLES: Dict[str, Tuple[Callable[..., Rows], List[str]]] = {
+    "stats": (_stats_rows, STATS_COLUMNS),
+    "sessions": (_session_rows, SESSION_COLUMNS),
+    "rake": (_rake_rows, RAKE_COLUMNS),
+    "pace": (_pace_rows, PACE_COLUMNS),
+}
+
+
+def available_columns(table: str) -> List[str]:
+    return list(TABLES[table][1])
+
+
+def parse_columns(specs: Sequence[str]) -> Dict[str, List[str]]:
+    """["stats=player,hands", ...] -> {"stats": ["player", "hands"]}"""
+    columns: Dict[str, List[str]] = {}
+    for spec in specs:
+        table, _, names = spec.partition("=")
+        if table not in TABLES:
+            raise ValueError(f"Unknown table {table!r}; expected table=col,col")
+        chosen = [name.strip() for name in names.split(",") if name.strip()]
+        unknown = [name for name in chosen if name not in TABLES[table][1]]
+        if unknown or not chosen:
+            raise ValueError(
+                f"Unknown {table} columns {', '.join(unknown) or '(none)'}; "
+                f"available: {', '.join(TABLES[table][1])}"
+            )
+        columns[table] = chosen
+    return columns
+
+
+def collect(
+    db: HandDB,
+    tables: Sequence[str],
+    columns: Optional[Dict[str, List[str]]] = None,
+    players: Optional[Sequence[str]] = None,
+    by: Optional[str] = None,
+) -> Dict[str, Tuple[List[str], List[List[object]]]]:
+    """{table: (header, rows)} ready to be written in any format"""
+    columns = columns or {}
+    sheets = {}
+    for table in tables:
+        if table not in TABLES:
+            raise ValueError(f"Unknown table {table!r}; expected {', '.join(TABLES)}")
+        rows_for, _ = TABLES[table]
+        header = columns.get(table, DEFAULT_COLUMNS[table])
+        sheets[table] = (
+            header,
+            [[row.get(name) for name in header] for row in rows_for(db, players, by)],
+        )
+    return sheets
+
+
+def to_csv(header: List[str], rows: List[List[object]]) -> str:
+    buffer = io.StringIO()
+    writer = csv.writer(buffer)
+    writer.writerow(header)
+    writer.writerows(["" if value is None else value for value in row] for row in rows)
+    return buffer.getvalue()
+
+
+def _column_letter(index: int) -> str:
+    letters = ""
+    index += 1
+    while index:
+        index, remainder = divmod(index - 1, 26)
+        letters = chr(ord("A") + remainder) + letters
+    return letters
+
+
+def _cell(ref: str, value: object) -> str:
+    if value is None:
+        return ""
+    if isinstance(value, (int, float)) and not isinstance(value, bool):
+        return f'<c r="{ref}"><v>{value}</v></c>'
+    text = escape(str(value))
+    return f'<c r="{ref}" t="inlineStr"><is><t xml:space="preserve">{text}</t></is></c>'
+
+
+def _sheet_xml(header: List[str], rows: List[List[object]]) -> str:
+    lines = []
+    for number, row in enumerate([header] + rows, start=1):
+        cells = "".join(
+            _cell(f"{_column_letter(idx)}{number}", value)
+            for idx, value in enumerate(row)
+        )
+        lines.append(f'<row r="{number}">{cells}</row>')
+    return (
+        '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>'
+        f'<worksheet xmlns="{MAIN_NS}">'
+        f"<sheetData>{''.join(lines)}</sheetData></worksheet>"
+    )
+
+
+def to_xlsx(sheets: Dict[str, Tuple[List[str], List[List[object]]]]) -> bytes:
+    """Minimal Office Open XML workbook, one worksheet per table"""
+    sheet_type = f"{RELATIONSHIPS_NS}/worksheet"
+    names = list(sheets)
+    overrides = "".join(
+        f'<Override PartName="/xl/worksheets/sheet{idx}.xml" '
+        'ContentType="application/vnd.openxmlformats-officedocument.'
+        'spreadsheetml.worksheet+xml"/>'
+        for idx in range(1, len(names) + 1)
+    )
+    files = {
+        "[Content_Types].xml": (
+            '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>'
+            '<Types xmlns="http://schemas.openxmlformats.org/package/2006/'
+            'content-types">'
+            '<Default Extension="rels" ContentType="application/'
+            'vnd.openxmlformats-package.relationships+xml"/>'
+            '<Default Extension="xml" ContentType="application/xml"/>'
+            '<Override PartName="/xl/workbook.xml" ContentType="application/'
+            'vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>'
+            f"{overrides}</Types>"
+        ),
+        "_rels/.rels": (
+            '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>'
+            f'<Relationships xmlns="{PACKAGE_NS}">'
+            f'<Relationship Id="rId1" Type="{RELATIONSHIPS_NS}/officeDocument" '
+            'Target="xl/workbook.xml"/></Relationships>'
+        ),
+        "xl/workbook.xml": (
+            '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>'
+            f'<workbook xmlns="{MAIN_NS}" xmlns:r="{RELATIONSHIPS_NS}"><sheets>'
+            + "".join(
+                f'<sheet name="{escape(name)}" sheetId="{idx}" r:id="rId{idx}"/>'
+                for idx, name in enumerate(names, start=1)
+            )
+            + "</sheets></workbook>"
+        ),
+        "xl/_rels/workbook.xml.rels": (
+            '<?xml version="1.0" encoding="UTF-8" standalone="yes"?>'
+            f'<Relationships xmlns="{PACKAGE_NS}">'
+            + "".join(
+                f'<Relationship Id="rId{idx}" Type="{sheet_type}" '
+                f'Target="worksheets/sheet{idx}.xml"/>'
+                for idx in range(1, len(names) + 1)
+            )
+            + "</Relationships>"
+        ),
+    }
+    for idx, name in enumerate(names, start=1):
+        files[f"xl/worksheets/sheet{idx}.xml"] = _sheet_xml(*sheets[name])
+    buffer = io.BytesIO()
+    with zipfile.ZipFile(buffer, "w", zipfile.ZIP_DEFLATED) as archive:
+        for path, content in files.items():
+            archive.writestr(path, content)
+    return buffer.getvalue()
+
+
+def export(
+    db: HandDB,
+    fmt: str,
+    tables: Sequence[str],
+    columns: Optional[Dict[str, List[str]]] = None,
+    players: Optional[Sequence[str]] = None,
+    by: Optional[str] = None,
+) -> bytes:
+    """One CSV table or an xlsx workbook of all `tables`, as bytes"""
+    if fmt not in FORMATS:
+        raise ValueError(f"format must be one of {', '.join(FORMATS)}")
+    if fmt == "csv" and len(tables) != 1:
+        raise ValueError("CSV holds a single table; pick one with table=")
+    sheets = collect(db, tables, columns, players, by)
+    if fmt == "xlsx":
+        return to_xlsx(sheets)
+    return to_csv(*sheets[tables[0]]).encode("utf-8")
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--format", choices=FORMATS, default="csv")
+    parser.add_argument(
+        "--table",
+        action="append",
+        choices=sorted(TABLES),
+        help="Table to export (repeatable; xlsx defaults to all, csv to stats)",
+    )
+    parser.add_argument(
+        "--columns",
+        action="append",
+        default=[],
+        metavar="TABLE=COL,COL",
+        help="Columns for one table (repeatable)",
+    )
+    parser.add_argument(
+        "--player", action="append", help="Limit stats to this player (repeatable)"
+    )
+    parser.add_argument("--by", choices=sorted(BREAKDOWNS), help="Break stats down")
+    parser.add_argument(
+        "--output",
+        type=Path,
+        help="File to write (CSV: a directory when exporting several tables)",
+    )
+
+
+def run(args: argparse.Namespace):
+    tables = args.table or (list(TABLES) if args.format == "xlsx" else ["stats"])
+    several_csv = args.format == "csv" and len(tables) > 1
+    if args.output is None and (args.format == "xlsx" or several_csv):
+        raise SystemExit("error: --output is required for xlsx or several tables")
+    with HandDB(args.db) as db:
+        try:
+            columns = parse_columns(args.columns)
+            if several_csv:
+                sheets = collect(db, tables, columns, args.player, args.by)
+            else:
+                data = export(db, args.format, tables, columns, args.player, args.by)
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    if several_csv:
+        args.output.mkdir(parents=True, exist_ok=True)
+        for table, sheet in sheets.items():
+            (args.output / f"{table}.csv").write_text(to_csv(*sheet))
+    elif args.output is not None:
+        args.output.write_bytes(data)
+    else:
+        sys.stdout.write(data.decode("utf-8"))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Export stats, sessions, and rake")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
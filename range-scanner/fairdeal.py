@@ -0,0 +1,240 @@
+#!/usr/bin/env python3
+"""
+Provably fair dealing with a commit-reveal scheme.
+
+Before a hand the dealer shuffles with the operating system's cryptographic
+RNG (`secrets`) and publishes only a commitment:
+
+    commitment = sha256("<server_seed>:<deck>")
+
+where `server_seed` is 32 random bytes in hex and `deck` the shuffled cards
+as one string ("As7d2c..."). Players then add a `client_seed` of their own,
+unknown to the dealer when it committed, and the cards actually dealt are the
+committed deck re-shuffled by a Fisher-Yates shuffle keyed on
+sha256("<server_seed>:<client_seed>"). After the hand the dealer reveals the
+server seed and deck; anyone can recompute the commitment and the dealt order
+(`verify`), so the dealer cannot have swapped the deck mid-hand, and could not
+have stacked it for a given player without knowing their seed.
+
+`FairHand` is one hand through those steps; the HTTP service keeps the open
+ones behind `/api/fairdeal`. A hand may record its `owner` (the API key or
+client that opened it); then only that owner can deal from it or reveal it,
+so nobody else can end a hand early and publish the deck mid-hand. The client
+seed cannot change once cards are out.
+
+Example:
+    python3 pokertools.py fairdeal demo --client-seed "alice+bob"
+    python3 pokertools.py fairdeal verify --commitment 3b1f... \\
+        --server-seed 9c0e... --deck AsKd... --client-seed "alice+bob"
+"""
+
+from __future__ import annotations
+
+import argparse
+import hashlib
+import hmac
+import json
+import secrets
+from dataclasses import dataclass, field
+from typing import Dict, Iterator, List, Optional
+
+from cards import Card, deck_mask, format_cards, mask_to_cards, parse_cards
+
+
+SEED_BYTES = 32
+
+
+def commitment_for(server_seed: str, deck: str) -> str:
+    return hashlib.sha256(f"{server_seed}:{deck}".encode("utf-8")).hexdigest()
+
+
+def _keyed_ints(key: bytes) -> Iterator[int]:
+    """Endless 32-bit integers from HMAC-SHA256(key, counter)"""
+    counter = 0
+    while True:
+        block = hmac.new(key, counter.to_bytes(8, "big"), hashlib.sha256).digest()
+        for offset in range(0, len(block), 4):
+            yield int.from_bytes(block[offset : offset + 4], "big")
+        counter += 1
+
+
+def keyed_shuffle(cards: List[Card], server_seed: str, client_seed: str) -> List[Card]:
+    """Deterministic Fisher-Yates over `cards`, keyed on both seeds"""
+    key = hashlib.sha256(f"{server_seed}:{client_seed}".encode("utf-8")).digest()
+    stream = _keyed_ints(key)
+    result = list(cards)
+    for last in range(len(result) - 1, 0, -1):
+        span = last + 1
+        # Rejection sampling keeps every position equally likely
+        limit = (1 << 32) - (1 << 32) % span
+        value = next(stream)
+        while value >= limit:
+            value = next(stream)
+        pick = value % span
+        result[last], result[pick] = result[pick], result[last]
+    return result
+
+
+@dataclass
+class FairHand:
+    """One hand: committed, then dealt, then revealed"""
+
+    id: str
+    server_seed: str
+    deck: List[Card]
+    commitment: str
+    client_seed: Optional[str] = None
+    dealt: List[Card] = field(default_factory=list)
+    revealed: bool = False
+    owner: Optional[str] = None
+
+    @classmethod
+    def new(cls, short_deck: bool = False, owner: Optional[str] = None) -> "FairHand":
+        deck = mask_to_cards(deck_mask(short_deck))
+        secrets.SystemRandom().shuffle(deck)
+        server_seed = secrets.token_hex(SEED_BYTES)
+        commitment = commitment_for(server_seed, format_cards(deck))
+        return cls(secrets.token_hex(8), server_seed, deck, commitment, owner=owner)
+
+    def check_owner(self, owner: Optional[str]):
+        """Raises PermissionError unless `owner` opened this hand"""
+        if self.owner is not None and owner != self.owner:
+            raise PermissionError(f"Hand {self.id} belongs to another client")
+
+    @property
+    def order(self) -> List[Card]:
+        """The dealing order once the client seed is in"""
+        if self.client_seed is None:
+            raise ValueError("Add the client seed before dealing")
+        return keyed_shuffle(self.deck, self.server_seed, self.client_seed)
+
+    def seed(self, client_seed: str):
+        if self.dealt or self.revealed:
+            raise ValueError("The client seed cannot change once cards are out")
+        if self.client_seed is not None:
+            raise ValueError("The client seed is already set for this hand")
+        self.client_seed = str(client_seed)
+
+    def deal(self, count: int = 1) -> List[Card]:
+        if self.revealed:
+            raise ValueError("The hand is over")
+        if count < 1:
+            raise ValueError("count must be positive")
+        order = self.order
+        if len(self.dealt) + count > len(order):
+            raise ValueError(f"Only {len(order) - len(self.dealt)} cards left")
+        cards = order[len(self.dealt) : len(self.dealt) + count]
+        self.dealt += cards
+        return cards
+
+    def reveal(self) -> Dict:
+        """End the hand and publish everything needed to verify it"""
+        if self.client_seed is None:
+            self.client_seed = ""
+        self.revealed = True
+        return self.to_dict()
+
+    def to_dict(self) -> Dict:
+        data = {
+            "id": self.id,
+            "commitment": self.commitment,
+            "client_seed": self.client_seed,
+            "cards_dealt": len(self.dealt),
+            "revealed": self.revealed,
+        }
+        # Which cards went out stays secret until the hand is over
+        if self.revealed:
+            data.update(
+                dealt=format_cards(self.dealt),
+                server_seed=self.server_seed,
+                deck=format_cards(self.deck),
+                order=format_cards(self.order),
+            )
+        return data
+
+
+def verify(
+    commitment: str,
+    server_seed: str,
+    deck: str,
+    client_seed: str = "",
+    dealt: str = "",
+) -> Dict:
+    """Check a revealed hand; `problems` is empty when everything matches"""
+    problems = []
+    try:
+        cards = parse_cards(deck)
+    except ValueError as exc:
+        return {"valid": False, "problems": [str(exc)]}
+    if len(cards) not in (36, 52):
+        problems.append(f"The deck has {len(cards)} cards")
+    if not hmac.compare_digest(commitment_for(server_seed, deck), commitment.lower()):
+        problems.append("The seed and deck do not match the commitment")
+    order = format_cards(keyed_shuffle(cards, server_seed, client_seed))
+    dealt = "".join(dealt.split())
+    if dealt and not order.startswith(dealt):
+        problems.append("The dealt cards are not the keyed order of the deck")
+    return {"valid": not problems, "problems": problems, "order": order}
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    actions = parser.add_subparsers(dest="action", required=True)
+    demo = actions.add_parser("demo", help="Commit, deal a hand, reveal, verify")
+    demo.add_argument("--client-seed", default="")
+    demo.add_argument("--players", type=int, default=2)
+    check = actions.add_parser("verify", help="Check a revealed hand")
+    check.add_argument("--commitment", required=True)
+    check.add_argument("--server-seed", required=True)
+    check.add_argument("--deck", required=True, help="The committed deck")
+    check.add_argument("--client-seed", default="")
+    check.add_argument("--dealt", default="", help="Cards dealt, in order")
+
+
+def run(args: argparse.Namespace):
+    if args.action == "verify":
+        result = verify(
+            args.commitment, args.server_seed, args.deck, args.client_seed, args.dealt
+        )
+        if args.json:
+            print(json.dumps(result, indent=2))
+        else:
+            print("valid" if result["valid"] else "INVALID")
+            for problem in result["problems"]:
+                print(f"  {problem}")
+        if not result["valid"]:
+            raise SystemExit(1)
+        return
+    if not 2 <= args.players <= 10:
+        raise SystemExit("error: players must be 2-10")
+    hand = FairHand.new()
+    lines = [f"commitment {hand.commitment}"]
+    hand.seed(args.client_seed)
+    for seat in range(1, args.players + 1):
+        lines.append(f"seat {seat}: {format_cards(hand.deal(2))}")
+    lines.append(f"board: {format_cards(hand.deal(5))}")
+    revealed = hand.reveal()
+    result = verify(
+        revealed["commitment"],
+        revealed["server_seed"],
+        revealed["deck"],
+        revealed["client_seed"],
+        revealed["dealt"],
+    )
+    if args.json:
+        print(json.dumps({**revealed, "verified": result["valid"]}, indent=2))
+        return
+    lines.append(f"server seed {revealed['server_seed']}")
+    lines.append(f"deck {revealed['deck']}")
+    lines.append("verified" if result["valid"] else "NOT verified")
+    print("\n".join(lines))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Provably fair dealing")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,224 @@
+"""
+Game-aware evaluators behind a common interface.
+
+Every evaluator turns a player's hole cards plus the shared board (empty for
+stud games) into a `HandScore`: `main` decides the main pot and `low` the low
+half of split games. Both are higher-is-better integers, so showdown code can
+compare any game the same way without knowing whether it is a high or a low
+game. Pick an evaluator with `get_evaluator("razz")` etc.
+
+Supported games:
+- holdem, shortdeck: best 5 of hole + board
+- omaha, omaha8: exactly two hole plus three board cards; omaha8 adds an 8-or-better low
+- stud: best 5 of 7 for high
+- razz: A-5 lowball, best 5 of 7, straights and flushes don't count
+- 27lowball: 2-7 lowball on 5 cards, aces high, straights and flushes count against
+"""
+
+from __future__ import annotations
+
+from itertools import combinations
+from typing import Dict, List, NamedTuple, Sequence
+
+from cards import CardLike, to_card
+from hand_evaluator import CLASS_SHIFT, HandClass, HandValue, _pack, evaluate_mask
+from lookup_evaluator import evaluate_indices
+from omaha_evaluator import (
+    describe_low,
+    low_strength,
+    omaha_high_indices,
+    omaha_low_indices,
+)
+
+
+# One step above the strongest packed high strength
+MAX_STRENGTH = (HandClass.STRAIGHT_FLUSH + 1) << CLASS_SHIFT
+LOWBALL_LABELS = "23456789TJQKA"
+RAZZ_LABELS = "A23456789TJQK"
+# Distinct ranks per razz pair structure: none, pair, two pair, trips, boat, quads
+RAZZ_DISTINCT_RANKS = [5, 4, 3, 3, 2, 2]
+
+
+class HandScore(NamedTuple):
+    """Comparable scores for one player; `low` is only used by split games"""
+
+    main: int
+    low: int = 0
+
+
+class Evaluator:
+    """Base class: subclasses set the card counts and implement `score`"""
+
+    game = ""
+    hole_cards = 2
+    board_cards = 5
+    short_deck = False
+    split = False
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        raise NotImplementedError
+
+    def describe(self, score: HandScore) -> str:
+        return HandValue(score.main, self.short_deck).describe()
+
+    def evaluate(
+        self, hole: Sequence[CardLike], board: Sequence[CardLike] = ()
+    ) -> HandScore:
+        """Validate card counts, then score card strings/Card objects"""
+        hole_idx = [to_card(card).index for card in hole]
+        board_idx = [to_card(card).index for card in board]
+        if len(hole_idx) != self.hole_cards:
+            raise ValueError(
+                f"{self.game} needs {self.hole_cards} hole cards, got {len(hole_idx)}"
+            )
+        if len(board_idx) > self.board_cards:
+            raise ValueError(
+                f"{self.game} allows at most {self.board_cards} board cards"
+            )
+        if len(set(hole_idx + board_idx)) != len(hole_idx) + len(board_idx):
+            raise ValueError("Duplicate cards between hole cards and board")
+        return self.score(hole_idx, board_idx)
+
+
+class HoldemEvaluator(Evaluator):
+    game = "holdem"
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        return HandScore(evaluate_indices(list(hole) + list(board), self.short_deck))
+
+
+class ShortDeckEvaluator(HoldemEvaluator):
+    game = "shortdeck"
+    short_deck = True
+
+
+class OmahaEvaluator(Evaluator):
+    game = "omaha"
+    hole_cards = 4
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        return HandScore(omaha_high_indices(list(hole), list(board)))
+
+
+class OmahaHiLoEvaluator(OmahaEvaluator):
+    game = "omaha8"
+    split = True
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        hole, board = list(hole), list(board)
+        return HandScore(
+            omaha_high_indices(hole, board),
+            low_strength(omaha_low_indices(hole, board)),
+        )
+
+    def describe(self, score: HandScore) -> str:
+        return f"{HandValue(score.main).describe()} / {describe_low(score.low)}"
+
+
+class StudEvaluator(Evaluator):
+    game = "stud"
+    hole_cards = 7
+    board_cards = 0
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        return HandScore(evaluate_indices(hole))
+
+
+def _razz_packed(cards: Sequence[int]) -> int:
+    """Pack a 5-card A-5 low so that smaller is better
+
+    Pair structure dominates (unpaired beats one pair beats two pair ...), then
+    the ranks ordered by multiplicity and height, aces counting as one.
+    """
+    counts: Dict[int, int] = {}
+    for card in cards:
+        rank = (card >> 2) + 1
+        ace_low = 0 if rank == 13 else rank
+        counts[ace_low] = counts.get(ace_low, 0) + 1
+    shape = sorted(counts.values(), reverse=True)
+    structure = {
+        (1, 1, 1, 1, 1): 0,
+        (2, 1, 1, 1): 1,
+        (2, 2, 1): 2,
+        (3, 1, 1): 3,
+        (3, 2): 4,
+        (4, 1): 5,
+    }[tuple(shape)]
+    ordered = sorted(counts, key=lambda rank: (counts[rank], rank), reverse=True)
+    return _pack(structure, ordered)
+
+
+class RazzEvaluator(Evaluator):
+    game = "razz"
+    hole_cards = 7
+    board_cards = 0
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        best = min(_razz_packed(combo) for combo in combinations(hole, 5))
+        return HandScore(MAX_STRENGTH - best)
+
+    def describe(self, score: HandScore) -> str:
+        packed = MAX_STRENGTH - score.main
+        ranks = [(packed >> shift) & 0xF for shift in (16, 12, 8, 4, 0)]
+        structure = packed >> CLASS_SHIFT
+        distinct = RAZZ_DISTINCT_RANKS[structure]
+        labels = "-".join(RAZZ_LABELS[rank] for rank in ranks[:distinct])
+        return labels if structure == 0 else f"{labels} (paired)"
+
+
+def _deuce_seven_high(cards: Sequence[int]) -> int:
+    """High strength with aces always high, so A-2-3-4-5 is not a straight"""
+    strength = evaluate_mask(sum(1 << card for card in cards))
+    hand_class = strength >> CLASS_SHIFT
+    top_rank = (strength >> 16) & 0xF
+    if hand_class in (HandClass.STRAIGHT, HandClass.STRAIGHT_FLUSH) and top_rank == 3:
+        demoted = (
+            HandClass.FLUSH
+            if hand_class == HandClass.STRAIGHT_FLUSH
+            else HandClass.HIGH_CARD
+        )
+        return _pack(demoted, [12, 3, 2, 1, 0])
+    return strength
+
+
+class DeuceSevenEvaluator(Evaluator):
+    game = "27lowball"
+    hole_cards = 5
+    board_cards = 0
+
+    def score(self, hole: Sequence[int], board: Sequence[int] = ()) -> HandScore:
+        return HandScore(MAX_STRENGTH - _deuce_seven_high(hole))
+
+    def describe(self, score: HandScore) -> str:
+        high = MAX_STRENGTH - score.main
+        if high >> CLASS_SHIFT != HandClass.HIGH_CARD:
+            return HandValue(high).describe()
+        ranks = [(high >> shift) & 0xF for shift in (16, 12, 8, 4, 0)]
+        return "-".join(LOWBALL_LABELS[rank] for rank in ranks)
+
+
+EVALUATORS: Dict[str, Evaluator] = {
+    evaluator.game: evaluator
+    for evaluator in (
+        HoldemEvaluator(),
+        ShortDeckEvaluator(),
+        OmahaEvaluator(),
+        OmahaHiLoEvaluator(),
+        StudEvaluator(),
+        RazzEvaluator(),
+        DeuceSevenEvaluator(),
+    )
+}
+
+
+def get_evaluator(game: str) -> Evaluator:
+    try:
+        return EVALUATORS[game.lower()]
+    except KeyError:
+        raise ValueError(
+            f"Unknown game {game!r}; expected one of {', '.join(EVALUATORS)}"
+        ) from None
+
+
+def available_games() -> List[str]:
+    return list(EVALUATORS)
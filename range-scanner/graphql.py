@@ -0,0 +1,749 @@
+#!/usr/bin/env python3
+"""
+GraphQL queries over the hand database: hands, players, and table sessions.
+
+A client asks for exactly the nested data it needs in one request, e.g. a
+player's table sessions with each session's hands and their actions, instead
+of stitching `/api/stats`, `/api/pace`, and `/api/hands` responses together.
+`POST /api/graphql` takes {"query", "variables", "operationName"} (or
+`GET /api/graphql?query=...`) and `GET /api/graphql/schema` returns the schema
+below as SDL.
+
+This is a read-only subset of GraphQL: queries with variables, arguments,
+aliases, nested selections, and `__typename`. Fragments, directives,
+mutations, subscriptions, and introspection are refused. The whole query is
+checked against the schema before anything runs, and any error (a bad query
+or a failing field) returns `{"data": null, "errors": [...]}`; a hand or
+player that does not exist is `null`, not an error.
+
+The schema loops back on itself (hand -> seats -> player -> hands), so a
+query may resolve at most MAX_COST objects. Its cost is estimated before it
+runs, each list counting its `limit` (or LIST_SIZES for lists without one)
+times the cost of its selections, and the objects actually resolved are
+counted as it runs; a query over budget either way is an error.
+
+    type Query {
+      hand(id: String!, site: String): Hand
+      hands(query: String, limit: Int, offset: Int): [Hand!]!
+      player(name: String!): Player
+      sessions(table: String, since: String, until: String): [TableSession!]!
+    }
+
+`hands(query:)` takes the `handquery` filter language; `limit` defaults to
+50 and is capped at `handquery.MAX_PER_PAGE`. A player's `sessions` are the
+table sessions (`pace.py`) of the hands they were dealt into, and a session's
+`hands` are only that player's hands when reached through a player.
+
+Example:
+    python3 pokertools.py graphql --db hands.sqlite \\
+        '{ player(name: "Hero") { stats { vpipPct } sessions { table
+           hands(limit: 5) { handId board actions(street: "preflop") { kind } } } } }'
+    python3 pokertools.py graphql --schema
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional, Tuple
+
+from handdb import HandDB, default_db_path
+from handhistory import Hand
+from handquery import MAX_PER_PAGE, QueryError, compile_query, register_functions
+from pace import TableSession, load_pace, table_sessions
+from players import PlayerNotes
+from replay import find_hand
+from stats import compute_stats
+
+
+DEFAULT_LIMIT = 50
+MAX_DEPTH = 12
+MAX_COST = 50_000
+# Typical lengths of lists that take no limit, for estimating a query's cost
+LIST_SIZES = {"Seat": 9, "Action": 20, "TableSession": 20}
+# Commas are insignificant in GraphQL, like whitespace and comments
+IGNORED_PATTERN = re.compile(r"(?:[\s,]+|#[^\n]*)*")
+TOKEN_PATTERN = re.compile(
+    r"(?P<punct>\.\.\.|[{}()\[\]:$!=@])"
+    r'|(?P<string>"(?:[^"\\\n]|\\.)*")'
+    r"|(?P<number>-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?)"
+    r"|(?P<name>[_A-Za-z][_0-9A-Za-z]*)"
+)
+SCALARS = {"String": str, "Int": int, "Float": float, "Boolean": bool}
+
+
+class GraphQLError(ValueError):
+    """A query the schema cannot run"""
+
+
+@dataclass
+class Variable:
+    name: str
+
+
+@dataclass
+class Selection:
+    name: str
+    alias: Optional[str] = None
+    arguments: Dict[str, Any] = field(default_factory=dict)
+    selections: List["Selection"] = field(default_factory=list)
+
+    @property
+    def key(self) -> str:
+        return self.alias or self.name
+
+
+@dataclass
+class Operation:
+    name: Optional[str]
+    variables: Dict[str, Tuple[str, Any]]  # name -> (type, default)
+    selections: List[Selection]
+
+
+def tokenize(text: str) -> List[Tuple[str, str]]:
+    tokens = []
+    position = IGNORED_PATTERN.match(text).end()
+    while position < len(text):
+        match = TOKEN_PATTERN.match(text, position)
+        if not match:
+            character = text[position]
+            raise GraphQLError(f"Unexpected character at {position}: {character!r}")
+        kind = match.lastgroup
+        tokens.append((kind, match.group(kind)))
+        position = IGNORED_PATTERN.match(text, match.end()).end()
+    return tokens
+
+
+class _Parser:
+    def __init__(self, text: str):
+        self.tokens = tokenize(text)
+        self.position = 0
+
+    def peek(self, value: Optional[str] = None) -> bool:
+        if self.position >= len(self.tokens):
+            return False
+        return value is None or self.tokens[self.position][1] == value
+
+    def peek_kind(self) -> str:
+        return self.tokens[self.position][0] if self.peek() else ""
+
+    def take(self, kind: Optional[str] = None, value: Optional[str] = None) -> str:
+        if self.position >= len(self.tokens):
+            raise GraphQLError("Unexpected end of query")
+        found_kind, found = self.tokens[self.position]
+        if (kind and found_kind != kind) or (value and found != value):
+            raise GraphQLError(f"Expected {value or kind}, found {found!r}")
+        self.position += 1
+        return found
+
+    def document(self) -> List[Operation]:
+        operations = []
+        while self.peek():
+            operations.append(self.operation())
+        if not operations:
+            raise GraphQLError("The query is empty")
+        return operations
+
+    def operation(self) -> Operation:
+        if self.peek("{"):
+            return Operation(None, {}, self.selection_set(0))
+        keyword = self.take("name")
+        if keyword != "query":
+            raise GraphQLError(f"Only queries are supported, not {keyword!r}")
+        name = self.take("name") if self.peek_kind() == "name" else None
+        variables = {}
+        if self.peek("("):
+            self.take(value="(")
+            while not self.peek(")"):
+                self.take(value="$")
+                variable = self.take("name")
+                self.take(value=":")
+                kind = self.type_reference()
+                default = None
+                if self.peek("="):
+                    self.take(value="=")
+                    default = self.value(constant=True)
+                variables[variable] = (kind, default)
+            self.take(value=")")
+        self.refuse_directives()
+        return Operation(name, variables, self.selection_set(0))
+
+    def type_reference(self) -> str:
+        if self.peek("["):
+            self.take(value="[")
+            kind = f"[{self.type_reference()}]"
+            self.take(value="]")
+        else:
+            kind = self.take("name")
+        if self.peek("!"):
+            self.take(value="!")
+            kind += "!"
+        return kind
+
+    def refuse_directives(self):
+        if self.peek("@"):
+            raise GraphQLError("Directives are not supported")
+
+    def selection_set(self, depth: int) -> List[Selection]:
+        if depth > MAX_DEPTH:
+            raise GraphQLError(f"Queries nest at most {MAX_DEPTH} levels")
+        self.take(value="{")
+        selections = []
+        while not self.peek("}"):
+            if self.peek("..."):
+                raise GraphQLError("Fragments are not supported")
+            name = self.take("name")
+            alias = None
+            if self.peek(":"):
+                self.take(value=":")
+                alias, name = name, self.take("name")
+            selection = Selection(name, alias)
+            if self.peek("("):
+                self.take(value="(")
+                while not self.peek(")"):
+                    argument = self.take("name")
+                    self.take(value=":")
+                    selection.arguments[argument] = self.value()
+                self.take(value=")")
+            self.refuse_directives()
+            if self.peek("{"):
+                selection.selections = self.selection_set(depth + 1)
+            selections.append(selection)
+        self.take(value="}")
+        if not selections:
+            raise GraphQLError("A selection set cannot be empty")
+        return selections
+
+    def value(self, constant: bool = False) -> Any:
+        kind = self.peek_kind()
+        token = self.tokens[self.position][1] if kind else ""
+        if token == "$" and not constant:
+            self.take(value="$")
+            return Variable(self.take("name"))
+        if token == "[":
+            self.take(value="[")
+            items = []
+            while not self.peek("]"):
+                items.append(self.value(constant))
+            self.take(value="]")
+            return items
+        if token == "{":
+            self.take(value="{")
+            fields = {}
+            while not self.peek("}"):
+                name = self.take("name")
+                self.take(value=":")
+                fields[name] = self.value(constant)
+            self.take(value="}")
+            return fields
+        self.take()
+        if kind == "string":
+            return json.loads(token)
+        if kind == "number":
+            return float(token) if re.search(r"[.eE]", token) else int(token)
+        if kind == "name":
+            return {"true": True, "false": False, "null": None}.get(token, token)
+        raise GraphQLError(f"Expected a value, found {token!r}")
+
+
+def parse(text: str) -> List[Operation]:
+    return _Parser(text).document()
+
+
+# Schema: type -> field -> (type, {argument: type}, resolve(parent, args, ctx))
+Resolver = Callable[[Any, Dict[str, Any], "_Context"], Any]
+FieldSpec = Tuple[str, Dict[str, str], Resolver]
+
+
+@dataclass
+class _Context:
+    db: HandDB
+    hands: Dict[int, Hand] = field(default_factory=dict)
+    resolved: int = 0
+
+    def charge(self):
+        """Count one resolved object against MAX_COST"""
+        self.resolved += 1
+        if self.resolved > MAX_COST:
+            raise GraphQLError(f"Query resolves more than {MAX_COST:,} objects")
+
+    def hand(self, hand_pk: int) -> Hand:
+        if hand_pk not in self.hands:
+            self.hands[hand_pk] = self.db.load(hand_pk)
+        return self.hands[hand_pk]
+
+
+@dataclass
+class _Seat:
+    hand: Hand
+    name: str
+
+
+@dataclass
+class _Session:
+    session: TableSession
+    player: Optional[str] = None
+
+
+def _attribute(name: str) -> Resolver:
+    return lambda parent, args, ctx: getattr(parent, name)
+
+
+def _limit(args: Dict[str, Any]) -> Tuple[int, int]:
+    limit = args.get("limit", DEFAULT_LIMIT)
+    offset = args.get("offset", 0)
+    if limit < 1 or offset < 0:
+        raise GraphQLError("limit must be positive and offset not negative")
+    return min(limit, MAX_PER_PAGE), offset
+
+
+def _hands(ctx: _Context, where: str, params: list, args: Dict) -> List[Hand]:
+    """Newest first, a page at a time"""
+    limit, offset = _limit(args)
+    rows = ctx.db.conn.execute(
+        f"SELECT h.id FROM hands h WHERE {where} "
+        "ORDER BY h.started_at DESC, h.id DESC LIMIT ? OFFSET ?",
+        params + [limit, offset],
+    ).fetchall()
+    return [ctx.hand(hand_pk) for (hand_pk,) in rows]
+
+
+def _player_filter(name: str) -> Tuple[str, list]:
+    return "h.id IN (SELECT hand_pk FROM hand_players WHERE name = ?)", [name]
+
+
+def _query_hand(parent, args, ctx: _Context) -> Optional[Hand]:
+    try:
+        return find_hand(ctx.db, args["id"], args.get("site"))
+    except KeyError:
+        return None
+
+
+def _query_hands(parent, args, ctx: _Context) -> List[Hand]:
+    condition, params = compile_query(args.get("query") or "")
+    register_functions(ctx.db.conn)
+    where = (
+        "h.id IN (SELECT h.id FROM hands h JOIN hand_players hp "
+        f"ON hp.hand_pk = h.id WHERE {condition})"
+    )
+    return _hands(ctx, where, params, args)
+
+
+def _query_player(parent, args, ctx: _Context) -> Optional[str]:
+    name = args["name"]
+    dealt = ctx.db.conn.execute(
+        "SELECT 1 FROM hand_players WHERE name = ? LIMIT 1", (name,)
+    ).fetchone()
+    if dealt or PlayerNotes(ctx.db).get(name):
+        return name
+    return None
+
+
+def _query_sessions(parent, args, ctx: _Context) -> List[_Session]:
+    sessions = load_pace(
+        ctx.db, args.get("table"), args.get("since"), args.get("until")
+    )
+    return [_Session(session) for session in sessions]
+
+
+def _player_sessions(name: str, args, ctx: _Context) -> List[_Session]:
+    rows = ctx.db.conn.execute(
+        """
+        SELECT h.site, h.table_name, h.started_at
+        FROM hands h JOIN hand_players hp ON hp.hand_pk = h.id
+        WHERE hp.name = ? AND h.started_at IS NOT NULL
+          AND COALESCE(h.table_name, '') != ''
+        """,
+        (name,),
+    ).fetchall()
+    return [_Session(session, name) for session in table_sessions(rows)]
+
+
+def _session_hands(parent: _Session, args, ctx: _Context) -> List[Hand]:
+    session = parent.session
+    where = "h.site = ? AND h.table_name = ? AND h.started_at BETWEEN ? AND ?"
+    params = [session.site, session.table, session.started_at, session.ended_at]
+    if parent.player:
+        player_where, player_params = _player_filter(parent.player)
+        where += f" AND {player_where}"
+        params += player_params
+    return _hands(ctx, where, params, args)
+
+
+def _player_stats(name: str, args, ctx: _Context) -> Optional[dict]:
+    where, params = _player_filter(name)
+    hands = ctx.db.hands(where, params)
+    table = compute_stats(hands, [name], ev=args.get("ev", False))
+    groups = table.get(name)
+    return groups["all"].to_dict() if groups else None
+
+
+def _notes(name: str, ctx: _Context):
+    return PlayerNotes(ctx.db).get(name)
+
+
+def _note_field(attribute: str, empty: Any) -> Resolver:
+    def resolve(name, args, ctx):
+        note = _notes(name, ctx)
+        return getattr(note, attribute) if note else empty
+
+    return resolve
+
+
+def _actions(hand: Hand, args, ctx) -> list:
+    street = args.get("street")
+    return hand.actions_on(street) if street else hand.actions
+
+
+def _seat_value(method: str) -> Resolver:
+    return lambda seat, args, ctx: getattr(seat.hand, method)().get(seat.name, 0.0)
+
+
+def _key(name: str) -> Resolver:
+    return lambda parent, args, ctx: parent[name]
+
+
+def _session_attribute(name: str) -> Resolver:
+    return lambda parent, args, ctx: getattr(parent.session, name)
+
+
+def _seat_attribute(name: str) -> Resolver:
+    return lambda seat, args, ctx: getattr(seat.hand.player(seat.name), name)
+
+
+STAT_FIELDS = (
+    ("hands", "Int!"),
+    ("vpipPct", "Float"),
+    ("pfrPct", "Float"),
+    ("threeBetPct", "Float"),
+    ("foldToThreeBetPct", "Float"),
+    ("limpPct", "Float"),
+    ("cbetPct", "Float"),
+    ("wtsdPct", "Float"),
+    ("wsdPct", "Float"),
+    ("net", "Float!"),
+    ("netBb", "Float!"),
+    ("bbPer100", "Float"),
+    ("allins", "Int!"),
+    ("evNetBb", "Float!"),
+    ("evBbPer100", "Float"),
+)
+
+
+def _snake(name: str) -> str:
+    return re.sub(r"(?<=[a-z])(?=[A-Z0-9])", "_", name).lower()
+
+
+SCHEMA: Dict[str, Dict[str, FieldSpec]] = {
+    "Query": {
+        "hand": ("Hand", {"id": "String!", "site": "String"}, _query_hand),
+        "hands": (
+            "[Hand!]!",
+            {"query": "String", "limit": "Int", "offset": "Int"},
+            _query_hands,
+        ),
+        "player": ("Player", {"name": "String!"}, _query_player),
+        "sessions": (
+            "[TableSession!]!",
+            {"table": "String", "since": "String", "until": "String"},
+            _query_sessions,
+        ),
+    },
+    "Player": {
+        "name": ("String!", {}, lambda name, args, ctx: name),
+        "notes": ("String!", {}, _note_field("notes", "")),
+        "color": ("String", {}, _note_field("color", None)),
+        "tags": ("[String!]!", {}, _note_field("tags", [])),
+        "stats": ("Stats", {"ev": "Boolean"}, _player_stats),
+        "sessions": ("[TableSession!]!", {}, _player_sessions),
+        "hands": (
+            "[Hand!]!",
+            {"limit": "Int", "offset": "Int"},
+            lambda name, args, ctx: _hands(ctx, *_player_filter(name), args),
+        ),
+    },
+    "Stats": {name: (kind, {}, _key(_snake(name))) for name, kind in STAT_FIELDS},
+    "TableSession": {
+        "site": ("String!", {}, _session_attribute("site")),
+        "table": ("String!", {}, _session_attribute("table")),
+        "startedAt": ("String!", {}, _session_attribute("started_at")),
+        "endedAt": ("String!", {}, _session_attribute("ended_at")),
+        "handCount": ("Int!", {}, _session_attribute("hands")),
+        "hours": ("Float!", {}, _session_attribute("hours")),
+        "handsPerHour": ("Float!", {}, _session_attribute("hands_per_hour")),
+        "avgHandSeconds": ("Float!", {}, _session_attribute("avg_hand_seconds")),
+        "medianHandSeconds": (
+            "Float!",
+            {},
+            _session_attribute("median_hand_seconds"),
+        ),
+        "breakSeconds": ("Float!", {}, _session_attribute("break_seconds")),
+        "hands": ("[Hand!]!", {"limit": "Int", "offset": "Int"}, _session_hands),
+    },
+    "Hand": {
+        "site": ("String!", {}, _attribute("site")),
+        "handId": ("String!", {}, _attribute("hand_id")),
+        "game": ("String!", {}, _attribute("game")),
+        "limit": ("String!", {}, _attribute("limit")),
+        "tournamentId": ("String", {}, _attribute("tournament_id")),
+        "table": ("String!", {}, _attribute("table")),
+        "startedAt": ("String", {}, _attribute("started_at")),
+        "currency": ("String!", {}, _attribute("currency")),
+        "smallBlind": ("Float!", {}, _attribute("small_blind")),
+        "bigBlind": ("Float!", {}, _attribute("big_blind")),
+        "ante": ("Float!", {}, _attribute("ante")),
+        "hero": ("String", {}, _attribute("hero")),
+        "board": ("[String!]!", {}, _attribute("board")),
+        "runouts": ("[[String!]!]!", {}, _attribute("runouts")),
+        "totalPot": ("Float!", {}, _attribute("total_pot")),
+        "rake": ("Float!", {}, _attribute("rake")),
+        "showdown": ("Boolean!", {}, _attribute("showdown")),
+        "seats": (
+            "[Seat!]!",
+            {},
+            lambda hand, args, ctx: [_Seat(hand, seat.name) for seat in hand.players],
+        ),
+        "actions": ("[Action!]!", {"street": "String"}, _actions),
+    },
+    "Seat": {
+        "seat": ("Int!", {}, _seat_attribute("seat")),
+        "name": ("String!", {}, _attribute("name")),
+        "player": ("Player!", {}, _attribute("name")),
+        "position": ("String!", {}, _seat_attribute("position")),
+        "stack": ("Float!", {}, _seat_attribute("stack")),
+        "holeCards": ("[String!]!", {}, _seat_attribute("hole_cards")),
+        "sittingOut": ("Boolean!", {}, _seat_attribute("sitting_out")),
+        "contributed": ("Float!", {}, _seat_value("contributions")),
+        "collected": (
+            "Float!",
+            {},
+            lambda seat, args, ctx: seat.hand.collected.get(seat.name, 0.0),
+        ),
+        "net": ("Float!", {}, _seat_value("net")),
+    },
+    "Action": {
+        "player": ("String!", {}, _attribute("player")),
+        "street": ("String!", {}, _attribute("street")),
+        "kind": ("String!", {}, _attribute("kind")),
+        "amount": ("Float!", {}, _attribute("amount")),
+        "raiseBy": ("Float!", {}, _attribute("raise_by")),
+        "allIn": ("Boolean!", {}, _attribute("all_in")),
+    },
+}
+
+
+def _base(kind: str) -> str:
+    return kind.strip("[]!")
+
+
+def schema_sdl() -> str:
+    """The schema in GraphQL SDL, for clients and code generators"""
+    blocks = []
+    for type_name, fields in SCHEMA.items():
+        lines = [f"type {type_name} {{"]
+        for name, (kind, arguments, _) in fields.items():
+            signature = ", ".join(f"{arg}: {type_}" for arg, type_ in arguments.items())
+            lines.append(f"  {name}{f'({signature})' if signature else ''}: {kind}")
+        lines.append("}")
+        blocks.append("\n".join(lines))
+    return "\n\n".join(blocks) + "\n"
+
+
+def _coerce(value: Any, kind: str, what: str) -> Any:
+    if value is None:
+        if kind.endswith("!"):
+            raise GraphQLError(f"{what} cannot be null")
+        return None
+    kind = kind.rstrip("!")
+    if kind.startswith("["):
+        items = value if isinstance(value, list) else [value]
+        return [_coerce(item, kind[1:-1], what) for item in items]
+    expected = SCALARS.get(kind)
+    if expected is float and isinstance(value, int) and not isinstance(value, bool):
+        return float(value)
+    if expected is None or type(value) is not expected:  # bool is not an Int
+        raise GraphQLError(f"{what} must be of type {kind}")
+    return value
+
+
+def _validate(type_name: str, selections: List[Selection], variables: Dict):
+    fields = SCHEMA[type_name]
+    for selection in selections:
+        if selection.name == "__typename":
+            continue
+        if selection.name not in fields:
+            raise GraphQLError(
+                f"Cannot query field {selection.name!r} on type {type_name!r}"
+            )
+        kind, arguments, _ = fields[selection.name]
+        for argument, argument_kind in arguments.items():
+            if argument_kind.endswith("!") and argument not in selection.arguments:
+                raise GraphQLError(
+                    f"Argument {argument!r} of {selection.name} is required"
+                )
+        for argument, value in selection.arguments.items():
+            if argument not in arguments:
+                raise GraphQLError(
+                    f"Unknown argument {argument!r} on {type_name}.{selection.name}"
+                )
+            if isinstance(value, Variable) and value.name not in variables:
+                raise GraphQLError(f"Variable ${value.name} is not defined")
+        base = _base(kind)
+        if base in SCALARS and selection.selections:
+            raise GraphQLError(f"{type_name}.{selection.name} has no subfields")
+        if base not in SCALARS:
+            if not selection.selections:
+                raise GraphQLError(
+                    f"{type_name}.{selection.name} needs a selection of subfields"
+                )
+            _validate(base, selection.selections, variables)
+
+
+def _list_size(
+    base: str, arguments: Dict[str, str], selection: Selection, variables: Dict
+) -> int:
+    if "limit" not in arguments:
+        return LIST_SIZES[base]
+    limit = selection.arguments.get("limit")
+    if isinstance(limit, Variable):
+        limit = variables.get(limit.name)
+    if not isinstance(limit, int) or isinstance(limit, bool):
+        limit = DEFAULT_LIMIT  # a bad limit fails when the field runs
+    return max(1, min(limit, MAX_PER_PAGE))
+
+
+def _cost(type_name: str, selections: List[Selection], variables: Dict) -> int:
+    """Estimated objects a validated query resolves, from its list sizes"""
+    cost = 0
+    for selection in selections:
+        if selection.name == "__typename":
+            continue
+        kind, arguments, _ = SCHEMA[type_name][selection.name]
+        base = _base(kind)
+        if base in SCALARS:
+            continue
+        count = 1
+        if kind.startswith("["):
+            count = _list_size(base, arguments, selection, variables)
+        cost += count * (1 + _cost(base, selection.selections, variables))
+    return cost
+
+
+def _arguments(
+    spec: FieldSpec, selection: Selection, variables: Dict[str, Any]
+) -> Dict[str, Any]:
+    _, arguments, _ = spec
+    values = {}
+    for argument, kind in arguments.items():
+        value = selection.arguments.get(argument)
+        if isinstance(value, Variable):
+            value = variables.get(value.name)
+        what = f"Argument {argument!r} of {selection.name}"
+        value = _coerce(value, kind, what)
+        if value is not None:
+            values[argument] = value
+    return values
+
+
+def _complete(kind: str, value: Any, selection: Selection, ctx, variables) -> Any:
+    if value is None:
+        if kind.endswith("!"):
+            raise GraphQLError(f"{selection.name} returned null")
+        return None
+    kind = kind.rstrip("!")
+    if kind.startswith("["):
+        return [
+            _complete(kind[1:-1], item, selection, ctx, variables) for item in value
+        ]
+    if kind in SCALARS:
+        return value
+    ctx.charge()
+    return _execute(kind, value, selection.selections, ctx, variables)
+
+
+def _execute(type_name, parent, selections, ctx, variables) -> Dict[str, Any]:
+    result = {}
+    for selection in selections:
+        if selection.name == "__typename":
+            result[selection.key] = type_name
+            continue
+        spec = SCHEMA[type_name][selection.name]
+        args = _arguments(spec, selection, variables)
+        value = spec[2](parent, args, ctx)
+        result[selection.key] = _complete(spec[0], value, selection, ctx, variables)
+    return result
+
+
+def _operation(operations: List[Operation], name: Optional[str]) -> Operation:
+    if name is not None:
+        for operation in operations:
+            if operation.name == name:
+                return operation
+        raise GraphQLError(f"No operation named {name!r}")
+    if len(operations) > 1:
+        raise GraphQLError("Several operations need an operationName")
+    return operations[0]
+
+
+def execute(
+    db: HandDB,
+    query: str,
+    variables: Optional[Dict[str, Any]] = None,
+    operation_name: Optional[str] = None,
+) -> Dict[str, Any]:
+    """Run one query; returns a GraphQL response with "data" or "errors" """
+    try:
+        operation = _operation(parse(query), operation_name)
+        given = dict(variables or {})
+        values = {}
+        for name, (kind, default) in operation.variables.items():
+            value = given.get(name, default)
+            values[name] = _coerce(value, kind, f"Variable ${name}")
+        _validate("Query", operation.selections, values)
+        cost = _cost("Query", operation.selections, values)
+        if cost > MAX_COST:
+            raise GraphQLError(
+                f"Query could resolve {cost:,} objects; the limit is {MAX_COST:,}"
+            )
+        data = _execute("Query", None, operation.selections, _Context(db), values)
+    except (GraphQLError, QueryError, ValueError) as exc:
+        return {"data": None, "errors": [{"message": str(exc)}]}
+    return {"data": data}
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("query", nargs="?", help="GraphQL query")
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--variables", help="Variables as a JSON object")
+    parser.add_argument("--operation", help="Operation to run from the query")
+    parser.add_argument("--schema", action="store_true", help="Print the schema")
+
+
+def run(args: argparse.Namespace):
+    if args.schema:
+        print(schema_sdl(), end="")
+        return
+    if not args.query:
+        raise SystemExit("error: give a query or --schema")
+    try:
+        variables = json.loads(args.variables) if args.variables else {}
+    except json.JSONDecodeError as exc:
+        raise SystemExit(f"error: --variables is not JSON: {exc}") from None
+    with HandDB(args.db) as db:
+        response = execute(db, args.query, variables, args.operation)
+    print(json.dumps(response, indent=2))
+    if "errors" in response:
+        raise SystemExit(1)
+
+
+def main():
+    parser = argparse.ArgumentParser(description="GraphQL over the hand database")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,269 @@
+#!/usr/bin/env python3
+"""
+Results and tournament graph data: cumulative series per hand, as JSON or PNG.
+
+`results_series` walks a player's hands in order and accumulates, after each
+hand:
+
+- net: actual winnings (the green line of a tracker's results graph)
+- ev: all-in EV adjusted winnings (`allin_ev.allin_result`)
+- showdown / non_showdown: winnings from hands the player took to showdown
+  against at least one other player, and from every other hand
+
+in money or, with `unit="bb"`, big blinds. `tournament_series` follows the
+player's chip stack through one tournament, in chips and big blinds, ending
+with the stack after the last hand. Both return plain arrays for charts and
+overlays; `line_chart_png` draws any set of series as a stdlib-only PNG line
+chart (no plotting library needed) with a grey zero line.
+
+Example:
+    python3 pokertools.py graphs results --player Hero --unit bb --png hero.png
+    python3 pokertools.py graphs tournament --player Hero --tournament 3471828291
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Sequence, Tuple
+
+from allin_ev import DEFAULT_EV_ITERATIONS, allin_result
+from handdb import HandDB, default_db_path
+from handhistory import Hand
+from pushfold import encode_png
+
+
+UNITS = ("money", "bb")
+CHART_WIDTH, CHART_HEIGHT, CHART_MARGIN = 800, 400, 20
+BACKGROUND = (255, 255, 255)
+AXIS_COLOR = (170, 170, 170)
+# Tracker conventions: green net, orange EV, blue showdown, red non-showdown
+SERIES_COLORS = {
+    "net": (30, 150, 60),
+    "ev": (230, 140, 20),
+    "showdown": (40, 90, 200),
+    "non_showdown": (200, 40, 40),
+    "stack": (30, 150, 60),
+    "stack_bb": (40, 90, 200),
+}
+FALLBACK_COLOR = (90, 90, 90)
+
+
+@dataclass
+class Series:
+    """Hand ids and one value per hand for each named series"""
+
+    hands: List[str] = field(default_factory=list)
+    series: Dict[str, List[float]] = field(default_factory=dict)
+
+    def to_dict(self) -> dict:
+        return {
+            "hands": self.hands,
+            "series": {
+                name: [round(value, 2) for value in values]
+                for name, values in self.series.items()
+            },
+        }
+
+
+def _went_to_showdown(hand: Hand, player: str) -> bool:
+    folded = {action.player for action in hand.actions if action.kind == "fold"}
+    dealt = [seat.name for seat in hand.players if not seat.sitting_out]
+    live = [name for name in dealt if name not in folded]
+    return player in live and len(live) >= 2
+
+
+def results_series(
+    hands: Iterable[Hand],
+    player: str,
+    unit: str = "money",
+    iterations: int = DEFAULT_EV_ITERATIONS,
+) -> Series:
+    if unit not in UNITS:
+        raise ValueError(f"unit must be one of {', '.join(UNITS)}")
+    names = ("net", "ev", "showdown", "non_showdown")
+    result = Series(series={name: [] for name in names})
+    totals = dict.fromkeys(names, 0.0)
+    for hand in hands:
+        seat = hand.player(player)
+        if seat is None or seat.sitting_out:
+            continue
+        scale = 1.0
+        if unit == "bb":
+            if not hand.big_blind:
+                continue
+            scale = 1 / hand.big_blind
+        net = hand.net().get(player, 0.0)
+        ev_net = net
+        allin = allin_result(hand, iterations)
+        if allin is not None and player in allin.equities:
+            ev_net = allin.ev_net[player]
+        totals["net"] += net * scale
+        totals["ev"] += ev_net * scale
+        street = "showdown" if _went_to_showdown(hand, player) else "non_showdown"
+        totals[street] += net * scale
+        result.hands.append(hand.hand_id)
+        for name in names:
+            result.series[name].append(totals[name])
+    return result
+
+
+def tournament_series(hands: Iterable[Hand], player: str) -> Series:
+    """Stack at the start of each hand, then after the last one"""
+    result = Series(series={"stack": [], "stack_bb": []})
+    last: Optional[Tuple[Hand, float]] = None
+    for hand in hands:
+        seat = hand.player(player)
+        if seat is None:
+            continue
+        result.hands.append(hand.hand_id)
+        result.series["stack"].append(seat.stack)
+        result.series["stack_bb"].append(
+            seat.stack / hand.big_blind if hand.big_blind else 0.0
+        )
+        last = (hand, seat.stack + hand.net().get(player, 0.0))
+    if last is not None:
+        hand, final = last
+        result.hands.append("final")
+        result.series["stack"].append(final)
+        result.series["stack_bb"].append(
+            final / hand.big_blind if hand.big_blind else 0.0
+        )
+    return result
+
+
+def load_results(
+    db: HandDB,
+    player: str,
+    unit: str = "money",
+    iterations: int = DEFAULT_EV_ITERATIONS,
+) -> Series:
+    hands = db.hands(
+        "h.id IN (SELECT hand_pk FROM hand_players WHERE name = ?)", (player,)
+    )
+    return results_series(hands, player, unit, iterations)
+
+
+def load_tournament(db: HandDB, player: str, tournament_id: str) -> Series:
+    hands = db.hands(
+        "h.tournament_id = ? AND h.id IN "
+        "(SELECT hand_pk FROM hand_players WHERE name = ?)",
+        (tournament_id, player),
+    )
+    series = tournament_series(hands, player)
+    if not series.hands:
+        raise KeyError(f"No hands for {player} in tournament {tournament_id}")
+    return series
+
+
+def _draw_line(
+    pixels: List[bytearray],
+    start: Tuple[int, int],
+    end: Tuple[int, int],
+    color: Tuple[int, int, int],
+):
+    """Bresenham, two pixels thick"""
+    (x0, y0), (x1, y1) = start, end
+    dx, dy = abs(x1 - x0), -abs(y1 - y0)
+    step_x, step_y = (1 if x0 < x1 else -1), (1 if y0 < y1 else -1)
+    error = dx + dy
+    while True:
+        for y in (y0, y0 + 1):
+            if 0 <= y < len(pixels):
+                pixels[y][x0 * 3 : x0 * 3 + 3] = bytes(color)
+        if (x0, y0) == (x1, y1):
+            return
+        doubled = 2 * error
+        if doubled >= dy:
+            error += dy
+            x0 += step_x
+        if doubled <= dx:
+            error += dx
+            y0 += step_y
+
+
+def line_chart_png(
+    series: Dict[str, Sequence[float]],
+    width: int = CHART_WIDTH,
+    height: int = CHART_HEIGHT,
+) -> bytes:
+    """Every series on shared axes; x is the hand index"""
+    values = [value for points in series.values() for value in points]
+    if not values:
+        raise ValueError("Nothing to draw")
+    low, high = min(values + [0.0]), max(values + [0.0])
+    span = (high - low) or 1.0
+    count = max(len(points) for points in series.values())
+    inner_w, inner_h = width - 2 * CHART_MARGIN, height - 2 * CHART_MARGIN
+
+    def point(index: int, value: float) -> Tuple[int, int]:
+        x = CHART_MARGIN + (index * inner_w // max(count - 1, 1))
+        y = CHART_MARGIN + int((high - value) / span * inner_h)
+        return x, y
+
+    pixels = [bytearray(bytes(BACKGROUND) * width) for _ in range(height)]
+    left, right = CHART_MARGIN, width - CHART_MARGIN
+    top, bottom = CHART_MARGIN, height - CHART_MARGIN
+    _draw_line(pixels, (left, top), (left, bottom), AXIS_COLOR)
+    _draw_line(pixels, point(0, 0.0), (right, point(0, 0.0)[1]), AXIS_COLOR)
+    for name, points in series.items():
+        color = SERIES_COLORS.get(name, FALLBACK_COLOR)
+        if len(points) == 1:
+            _draw_line(pixels, point(0, points[0]), point(0, points[0]), color)
+        for index in range(1, len(points)):
+            start = point(index - 1, points[index - 1])
+            _draw_line(pixels, start, point(index, points[index]), color)
+    return encode_png(width, height, pixels)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    subparsers = parser.add_subparsers(dest="graph", required=True)
+    results = subparsers.add_parser("results", help="Cumulative winnings by hand")
+    results.add_argument("--player", required=True)
+    results.add_argument("--unit", choices=UNITS, default="money")
+    results.add_argument("--iterations", type=int, default=DEFAULT_EV_ITERATIONS)
+    tournament = subparsers.add_parser("tournament", help="Chip stack by hand")
+    tournament.add_argument("--player", required=True)
+    tournament.add_argument("--tournament", required=True, help="Tournament id")
+    for sub in (results, tournament):
+        sub.add_argument("--png", type=Path, help="Also draw the chart to this file")
+        sub.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        with HandDB(args.db) as db:
+            if args.graph == "results":
+                data = load_results(db, args.player, args.unit, args.iterations)
+            else:
+                data = load_tournament(db, args.player, args.tournament)
+    except KeyError as exc:
+        raise SystemExit(f"error: {exc.args[0]}") from None
+    if not data.hands:
+        raise SystemExit(f"error: no hands for {args.player}")
+    if args.png:
+        args.png.write_bytes(line_chart_png(data.series))
+    if args.json:
+        print(json.dumps(data.to_dict(), indent=2))
+        return
+    # Winnings carry a sign, stacks do not
+    spec = "+,.2f" if args.graph == "results" else ",.2f"
+    finals = ", ".join(
+        f"{name} {values[-1]:{spec}}" for name, values in data.series.items()
+    )
+    print(f"{len(data.hands):,} points for {args.player}: {finals}")
+    if args.png:
+        print(f"Chart written to {args.png}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Results and tournament graphs")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
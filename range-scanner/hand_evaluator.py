@@ -0,0 +1,255 @@
+"""
+Hand evaluator for 5, 6, and 7 card poker hands.
+
+`evaluate` returns a `HandValue` whose integer `strength` is directly comparable:
+a higher strength always beats a lower one and equal strengths split the pot.
+The strength packs the hand class into the top bits and the five deciding ranks
+(most significant first) into 4-bit nibbles below it.
+
+Short-deck (6+) hold'em is a mode flag rather than a separate evaluator: with
+`short_deck=True` A-6-7-8-9 is the lowest straight and a flush outranks a full
+house. Those two classes swap places inside the packed strength, and
+`HandValue.short_deck` lets `hand_class` map them back.
+
+Example:
+    value = evaluate(parse_cards("AsKsQsJsTs2d3c"))
+    value.hand_class      # HandClass.STRAIGHT_FLUSH
+    value.describe()      # 'Straight Flush, A high'
+"""
+
+from __future__ import annotations
+
+import argparse
+from enum import IntEnum
+from typing import Iterable, List, NamedTuple, Sequence, Tuple
+
+from cards import RANK_CHARS, SHORT_DECK_MASK, CardLike, cards_to_mask, parse_cards
+
+
+CLASS_SHIFT = 20
+
+# Rank bitmask (bit 12 = ace) for each straight, highest first; the wheel is last
+STRAIGHT_MASKS = [(0x1F << low, low + 4) for low in range(8, -1, -1)] + [
+    (0x100F, 3)
+]
+# Short deck has no deuces-fives, so the wheel becomes A-6-7-8-9 (nine high)
+SHORT_DECK_STRAIGHT_MASKS = [(0x1F << low, low + 4) for low in range(8, 3, -1)] + [
+    (0x10F0, 7)
+]
+
+
+class HandClass(IntEnum):
+    """Hand categories in ascending order of strength"""
+
+    HIGH_CARD = 0
+    PAIR = 1
+    TWO_PAIR = 2
+    TRIPS = 3
+    STRAIGHT = 4
+    FLUSH = 5
+    FULL_HOUSE = 6
+    QUADS = 7
+    STRAIGHT_FLUSH = 8
+
+    @property
+    def label(self) -> str:
+        return HAND_CLASS_LABELS[self]
+
+
+HAND_CLASS_LABELS = {
+    HandClass.HIGH_CARD: "High Card",
+    HandClass.PAIR: "Pair",
+    HandClass.TWO_PAIR: "Two Pair",
+    HandClass.TRIPS: "Three of a Kind",
+    HandClass.STRAIGHT: "Straight",
+    HandClass.FLUSH: "Flush",
+    HandClass.FULL_HOUSE: "Full House",
+    HandClass.QUADS: "Four of a Kind",
+    HandClass.STRAIGHT_FLUSH: "Straight Flush",
+}
+
+
+def _class_slot(hand_class: int, short_deck: bool) -> int:
+    """Position of a class in the strength ordering for the given mode"""
+    if short_deck and hand_class in (HandClass.FLUSH, HandClass.FULL_HOUSE):
+        return HandClass.FLUSH + HandClass.FULL_HOUSE - hand_class
+    return hand_class
+
+
+class HandValue(NamedTuple):
+    """Comparable result of an evaluation"""
+
+    strength: int
+    short_deck: bool = False
+
+    @property
+    def hand_class(self) -> HandClass:
+        return HandClass(_class_slot(self.strength >> CLASS_SHIFT, self.short_deck))
+
+    @property
+    def ranks(self) -> Tuple[int, ...]:
+        """The deciding ranks (0 = deuce ... 12 = ace), most significant first"""
+        return tuple((self.strength >> shift) & 0xF for shift in (16, 12, 8, 4, 0))
+
+    def describe(self) -> str:
+        hand_class = self.hand_class
+        top = RANK_CHARS[self.ranks[0]]
+        if hand_class in (HandClass.STRAIGHT, HandClass.STRAIGHT_FLUSH):
+            return f"{hand_class.label}, {top} high"
+        if hand_class in (HandClass.TWO_PAIR, HandClass.FULL_HOUSE):
+            return f"{hand_class.label}, {top}{RANK_CHARS[self.ranks[1]]}"
+        if hand_class in (HandClass.HIGH_CARD, HandClass.FLUSH):
+            return f"{hand_class.label}, {top} high"
+        return f"{hand_class.label}, {top}"
+
+
+def _pack(hand_class: int, ranks: Sequence[int]) -> int:
+    value = hand_class
+    for idx in range(5):
+        value = (value << 4) | (ranks[idx] if idx < len(ranks) else 0)
+    return value
+
+
+def _top_ranks(rank_mask: int, count: int) -> List[int]:
+    """Highest `count` ranks set in a 13-bit rank mask"""
+    ranks = []
+    rank = 12
+    while rank >= 0 and len(ranks) < count:
+        if rank_mask & (1 << rank):
+            ranks.append(rank)
+        rank -= 1
+    return ranks
+
+
+def _straight_high(rank_mask: int, short_deck: bool = False) -> int:
+    """High rank of the best straight in the mask, or -1"""
+    straights = SHORT_DECK_STRAIGHT_MASKS if short_deck else STRAIGHT_MASKS
+    for straight, high in straights:
+        if rank_mask & straight == straight:
+            return high
+    return -1
+
+
+def evaluate_mask(mask: int, short_deck: bool = False) -> int:
+    """Evaluate a 52-bit card mask holding 5 to 7 cards and return the strength"""
+    suit_masks = [0, 0, 0, 0]
+    counts = [0] * 13
+    remaining = mask
+    while remaining:
+        low = remaining & -remaining
+        index = low.bit_length() - 1
+        remaining ^= low
+        rank = index >> 2
+        counts[rank] += 1
+        suit_masks[index & 3] |= 1 << rank
+
+    for suit_mask in suit_masks:
+        if bin(suit_mask).count("1") >= 5:
+            high = _straight_high(suit_mask, short_deck)
+            if high >= 0:
+                return _pack(HandClass.STRAIGHT_FLUSH, [high])
+            flush = _pack(
+                _class_slot(HandClass.FLUSH, short_deck), _top_ranks(suit_mask, 5)
+            )
+            break
+    else:
+        flush = 0
+
+    quads: List[int] = []
+    trips: List[int] = []
+    pairs: List[int] = []
+    rank_mask = 0
+    for rank in range(12, -1, -1):
+        count = counts[rank]
+        if not count:
+            continue
+        rank_mask |= 1 << rank
+        if count == 4:
+            quads.append(rank)
+        elif count == 3:
+            trips.append(rank)
+        elif count == 2:
+            pairs.append(rank)
+
+    if quads:
+        quad = quads[0]
+        kicker = _top_ranks(rank_mask & ~(1 << quad), 1)
+        return _pack(HandClass.QUADS, [quad] + kicker)
+
+    # Seven cards can't hold both a flush and a full house, so checking the
+    # full house first is safe in either mode.
+    if trips and (len(trips) > 1 or pairs):
+        # A second set of trips plays as the pair of a full house
+        pair = max(trips[1:] + pairs)
+        return _pack(_class_slot(HandClass.FULL_HOUSE, short_deck), [trips[0], pair])
+
+    if flush:
+        return flush
+
+    high = _straight_high(rank_mask, short_deck)
+    if high >= 0:
+        return _pack(HandClass.STRAIGHT, [high])
+
+    if trips:
+        kickers = _top_ranks(rank_mask & ~(1 << trips[0]), 2)
+        return _pack(HandClass.TRIPS, [trips[0]] + kickers)
+
+    if len(pairs) >= 2:
+        top, second = pairs[0], pairs[1]
+        kicker = _top_ranks(rank_mask & ~(1 << top) & ~(1 << second), 1)
+        return _pack(HandClass.TWO_PAIR, [top, second] + kicker)
+
+    if pairs:
+        kickers = _top_ranks(rank_mask & ~(1 << pairs[0]), 3)
+        return _pack(HandClass.PAIR, [pairs[0]] + kickers)
+
+    return _pack(HandClass.HIGH_CARD, _top_ranks(rank_mask, 5))
+
+
+def evaluate(cards: Iterable[CardLike], short_deck: bool = False) -> HandValue:
+    """Evaluate 5, 6, or 7 cards"""
+    card_list = list(cards)
+    if not 5 <= len(card_list) <= 7:
+        raise ValueError(f"Expected 5-7 cards, got {len(card_list)}")
+    mask = cards_to_mask(card_list)
+    if bin(mask).count("1") != len(card_list):
+        raise ValueError("Duplicate cards in hand")
+    if short_deck and mask & ~SHORT_DECK_MASK:
+        raise ValueError("Short deck hands can't contain deuces through fives")
+    return HandValue(evaluate_mask(mask, short_deck), short_deck)
+
+
+def compare(
+    first: Iterable[CardLike], second: Iterable[CardLike], short_deck: bool = False
+) -> int:
+    """Return 1 if `first` wins, -1 if `second` wins, 0 on a split"""
+    a = evaluate(first, short_deck).strength
+    b = evaluate(second, short_deck).strength
+    return (a > b) - (a < b)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("hands", nargs="+", help="5-7 cards each, e.g. AsKsQsJsTs")
+    parser.add_argument("--short-deck", action="store_true", help="6+ hold'em")
+
+
+def run(args: argparse.Namespace):
+    try:
+        values = [evaluate(parse_cards(hand), args.short_deck) for hand in args.hands]
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    best = max(value.strength for value in values)
+    for text, value in zip(args.hands, values):
+        # With several hands, star the winner(s)
+        mark = " *" if len(values) > 1 and value.strength == best else ""
+        print(f"{text:<16} {value.describe()}{mark}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Evaluate poker hands")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,432 @@
+#!/usr/bin/env python3
+"""
+Hold'em range notation and the `Range` type.
+
+A range is a set of two-card combos, each with a weight in (0, 1]. The parser
+understands the usual shorthand, separated by commas or spaces:
+- pairs and hand classes: "QQ", "AKs", "AKo", "AK" (suited + offsuit)
+- plus notation: "22+" (22-AA), "A2s+" (A2s-AKs), "KTo+" (KTo-KQo)
+- dash ranges: "QQ-88", "A5s-A2s", "76s-54s" (connectors stepping down together)
+- specific combos: "AsKs"
+- top percentages by preflop strength: "[25%]" or "25%"
+- weights on any term: "AKo:0.5", "[10%]:0.25"
+Later terms override the weight of combos named earlier.
+
+`range count RANGE` expands and counts a range; `range show RANGE` draws it
+as a 13x13 heatmap (`heatmap.py`): colored in the terminal, or written as SVG,
+PNG, or JSON with `--format`, optionally shaded by one action's frequencies
+from a `solve --export` strategy.
+
+Example:
+    python3 hand_range.py count "22+, A2s+, KTo+, 76s-54s" --dead AhKd
+    python3 pokertools.py range show "22+,ATs+" --format svg --output range.svg
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import re
+import sys
+from itertools import combinations
+from pathlib import Path
+from typing import Dict, Iterable, Iterator, List, Optional, Tuple, Union
+
+from cards import RANK_CHARS, Card, CardLike, cards_to_mask, parse_cards, to_card
+
+
+Combo = Tuple[int, int]
+TOTAL_COMBOS = 1326
+
+# All 169 starting hands ordered by all-in equity against a random hand
+# (60k Monte Carlo samples per class, generated with lookup_evaluator).
+PREFLOP_ORDER = """
+AA KK QQ JJ TT 99 88 AKs 77 AQs AJs AKo ATs AQo AJo KQs 66 ATo A9s KJs A8s KQo
+KTs A9o KJo A7s 55 A5s K9s A6s QJs KTo A8o QTs A4s A7o K8s A3s QJo JTs K9o Q9s
+A5o A2s A6o K7s QTo 44 A4o K6s K5s Q8s K8o J9s A3o K7o JTo A2o Q9o K4s Q7s T9s
+K6o Q8o Q6s J8s J9o K3s 33 K2s K5o Q5s K4o T8s J7s Q7o T9o Q4s K3o J8o Q3s Q6o
+J6s 98s K2o 22 T7s J5s Q5o Q2s T8o J4s J7o Q4o 97s T6s J6o J3s Q3o 98o 87s T7o
+J2s T5s Q2o J5o 96s 97o T4s J4o T6o 86s 76s 95s T3s J3o 87o T2s 85s J2o 96o 94s
+T5o T4o 93s 75s 86o 84s 65s 95o T3o 76o 92s 64s 74s 85o 54s T2o 83s 94o 75o 65o
+93o 73s 82s 53s 63s 84o 92o 43s 74o 54o 72s 64o 52s 62s 83o 42s 73o 53o 82o 63o
+32s 43o 72o 52o 62o 42o 32o
+""".split()
+
+CLASS_PATTERN = re.compile(r"^([2-9TJQKA])([2-9TJQKA])([so]?)$")
+COMBO_PATTERN = re.compile(r"^([2-9TJQKA][cdhs]){2}$")
+PERCENT_PATTERN = re.compile(r"^\[?(\d+(?:\.\d+)?)%\]?$")
+
+
+def _rank(char: str) -> int:
+    return RANK_CHARS.index(char)
+
+
+def make_combo(first: int, second: int) -> Combo:
+    """Canonical combo key: higher card index first"""
+    return (first, second) if first > second else (second, first)
+
+
+def class_combos(hand_class: str) -> List[Combo]:
+    """Every combo of a hand class such as 'QQ', 'AKs', 'AKo', or 'AK'"""
+    match = CLASS_PATTERN.match(hand_class)
+    if not match:
+        raise ValueError(f"Invalid hand class: {hand_class!r}")
+    high, low, suffix = _rank(match.group(1)), _rank(match.group(2)), match.group(3)
+    if high == low:
+        if suffix:
+            raise ValueError(f"Pairs can't be suited or offsuit: {hand_class!r}")
+        cards = [high * 4 + suit for suit in range(4)]
+        return [make_combo(a, b) for a, b in combinations(cards, 2)]
+    combos = []
+    for first_suit in range(4):
+        for second_suit in range(4):
+            suited = first_suit == second_suit
+            if (suffix == "s" and not suited) or (suffix == "o" and suited):
+                continue
+            combos.append(make_combo(high * 4 + first_suit, low * 4 + second_suit))
+    return combos
+
+
+def hand_class_of(combo: Combo) -> str:
+    """'AKs' / 'AKo' / 'QQ' for a combo"""
+    first, second = make_combo(*combo)
+    high, low = first >> 2, second >> 2
+    if high == low:
+        return RANK_CHARS[high] * 2
+    suffix = "s" if first & 3 == second & 3 else "o"
+    return f"{RANK_CHARS[high]}{RANK_CHARS[low]}{suffix}"
+
+
+def _normalize_class(hand_class: str) -> Tuple[int, int, str]:
+    match = CLASS_PATTERN.match(hand_class)
+    if not match:
+        raise ValueError(f"Invalid hand class: {hand_class!r}")
+    high, low = _rank(match.group(1)), _rank(match.group(2))
+    if low > high:
+        high, low = low, high
+    return high, low, match.group(3)
+
+
+def _class_name(high: int, low: int, suffix: str) -> str:
+    if high == low:
+        return RANK_CHARS[high] * 2
+    return f"{RANK_CHARS[high]}{RANK_CHARS[low]}{suffix}"
+
+
+def _expand_plus(hand_class: str) -> List[str]:
+    high, low, suffix = _normalize_class(hand_class)
+    if high == low:
+        return [_class_name(rank, rank, "") for rank in range(low, 13)]
+    return [_class_name(high, kicker, suffix) for kicker in range(low, high)]
+
+
+def _expand_dash(start: str, end: str) -> List[str]:
+    high_a, low_a, suffix_a = _normalize_class(start)
+    high_b, low_b, suffix_b = _normalize_class(end)
+    if suffix_a != suffix_b:
+        raise ValueError(f"Mismatched suits in range {start}-{end}")
+    if high_a == low_a and high_b == low_b:
+        top, bottom = max(high_a, high_b), min(high_a, high_b)
+        return [_class_name(rank, rank, "") for rank in range(bottom, top + 1)]
+    if high_a == high_b:
+        top, bottom = max(low_a, low_b), min(low_a, low_b)
+        return [
+            _class_name(high_a, kicker, suffix_a) for kicker in range(bottom, top + 1)
+        ]
+    if high_a - low_a == high_b - low_b:
+        if high_a < high_b:
+            high_a, high_b = high_b, high_a
+        gap = high_a - low_a
+        return [
+            _class_name(high, high - gap, suffix_a)
+            for high in range(high_b, high_a + 1)
+        ]
+    raise ValueError(f"Unsupported range {start}-{end}")
+
+
+def top_percent_classes(percent: float) -> List[str]:
+    """Strongest hand classes covering roughly `percent` of all combos"""
+    target = TOTAL_COMBOS * percent / 100
+    selected = []
+    covered = 0
+    for hand_class in PREFLOP_ORDER:
+        if covered >= target:
+            break
+        selected.append(hand_class)
+        covered += len(class_combos(hand_class))
+    return selected
+
+
+class Range:
+    """Weighted set of hold'em combos"""
+
+    def __init__(self, weights: Optional[Dict[Combo, float]] = None):
+        self._weights: Dict[Combo, float] = {}
+        for combo, weight in (weights or {}).items():
+            self.set(combo, weight)
+
+    @classmethod
+    def parse(cls, text: str) -> "Range":
+        result = cls()
+        for term in re.split(r"[,\s]+", text.strip()):
+            if not term:
+                continue
+            weight = 1.0
+            if ":" in term:
+                term, weight_text = term.rsplit(":", 1)
+                try:
+                    weight = float(weight_text)
+                except ValueError:
+                    raise ValueError(f"Invalid weight: {weight_text!r}") from None
+            for combo in cls._expand_term(term):
+                result.set(combo, weight)
+        return result
+
+    @classmethod
+    def from_combos(
+        cls, combos: Iterable[Union[str, Tuple[CardLike, CardLike]]], weight=1.0
+    ) -> "Range":
+        """Build a range from explicit combos such as 'AsKs' or (Card, Card)"""
+        result = cls()
+        for combo in combos:
+            if isinstance(combo, str):
+                cards = parse_cards(combo)
+            else:
+                cards = [to_card(card) for card in combo]
+            if len(cards) != 2 or cards[0] == cards[1]:
+                raise ValueError(f"Invalid combo: {combo!r}")
+            result.set(make_combo(cards[0].index, cards[1].index), weight)
+        return result
+
+    @classmethod
+    def top_percent(cls, percent: float) -> "Range":
+        return cls.parse(f"{percent}%")
+
+    @staticmethod
+    def _expand_term(term: str) -> List[Combo]:
+        if COMBO_PATTERN.match(term):
+            first, second = parse_cards(term)
+            return [make_combo(first.index, second.index)]
+        percent = PERCENT_PATTERN.match(term)
+        if percent:
+            classes = top_percent_classes(float(percent.group(1)))
+        elif term.endswith("+"):
+            classes = _expand_plus(term[:-1])
+        elif "-" in term:
+            start, end = term.split("-", 1)
+            classes = _expand_dash(start, end)
+        else:
+            classes = [term]
+        combos: List[Combo] = []
+        for hand_class in classes:
+            combos.extend(class_combos(hand_class))
+        return combos
+
+    def set(self, combo: Combo, weight: float):
+        if not 0 <= weight <= 1:
+            raise ValueError(f"Weight must be between 0 and 1, got {weight}")
+        combo = make_combo(*combo)
+        if weight == 0:
+            self._weights.pop(combo, None)
+        else:
+            self._weights[combo] = weight
+
+    def weight(self, combo: Combo) -> float:
+        return self._weights.get(make_combo(*combo), 0.0)
+
+    def weighted_combos(self) -> List[Tuple[Combo, float]]:
+        return sorted(self._weights.items(), reverse=True)
+
+    def combos(self) -> List[Combo]:
+        return sorted(self._weights, reverse=True)
+
+    def combo_count(self, dead: Union[str, Iterable[CardLike]] = ()) -> float:
+        """Weighted number of combos left once `dead` cards are removed"""
+        dead_cards = parse_cards(dead) if isinstance(dead, str) else dead
+        dead_mask = cards_to_mask(dead_cards)
+        return sum(
+            weight
+            for (first, second), weight in self._weights.items()
+            if not dead_mask & ((1 << first) | (1 << second))
+        )
+
+    def without(self, dead: Union[str, Iterable[CardLike]]) -> "Range":
+        """Copy with every combo touching a dead card removed"""
+        dead_cards = parse_cards(dead) if isinstance(dead, str) else dead
+        dead_mask = cards_to_mask(dead_cards)
+        return Range(
+            {
+                combo: weight
+                for combo, weight in self._weights.items()
+                if not dead_mask & ((1 << combo[0]) | (1 << combo[1]))
+            }
+        )
+
+    def percent(self) -> float:
+        """Share of all 1326 combos, counting partial weights"""
+        return sum(self._weights.values()) / TOTAL_COMBOS * 100
+
+    def hand_classes(self) -> Dict[str, Tuple[float, int]]:
+        """Map hand class -> (weighted combos present, combos in the class)"""
+        present: Dict[str, float] = {}
+        for combo, weight in self._weights.items():
+            hand_class = hand_class_of(combo)
+            present[hand_class] = present.get(hand_class, 0.0) + weight
+        return {
+            hand_class: (present[hand_class], len(class_combos(hand_class)))
+            for hand_class in PREFLOP_ORDER
+            if hand_class in present
+        }
+
+    def union(self, other: "Range") -> "Range":
+        """Combos in either range, keeping the larger weight"""
+        merged = dict(self._weights)
+        for combo, weight in other._weights.items():
+            merged[combo] = max(weight, merged.get(combo, 0.0))
+        return Range(merged)
+
+    def intersect(self, other: "Range") -> "Range":
+        """Combos in both ranges, keeping the smaller weight"""
+        return Range(
+            {
+                combo: min(weight, other._weights[combo])
+                for combo, weight in self._weights.items()
+                if combo in other._weights
+            }
+        )
+
+    def minus(self, other: "Range") -> "Range":
+        """Reduce each weight by the other range's weight for the same combo"""
+        return Range(
+            {
+                combo: max(weight - other._weights.get(combo, 0.0), 0.0)
+                for combo, weight in self._weights.items()
+            }
+        )
+
+    __or__ = union
+    __and__ = intersect
+    __sub__ = minus
+
+    def __len__(self) -> int:
+        return len(self._weights)
+
+    def __iter__(self) -> Iterator[Combo]:
+        return iter(self.combos())
+
+    def __contains__(self, combo) -> bool:
+        return make_combo(*combo) in self._weights
+
+    def __eq__(self, other) -> bool:
+        return isinstance(other, Range) and other._weights == self._weights
+
+    def __str__(self) -> str:
+        """Hand classes in strength order; partial classes list their combos"""
+        parts = []
+        classes = self.hand_classes()
+        for hand_class, (_, total) in classes.items():
+            combos = [c for c in class_combos(hand_class) if c in self._weights]
+            weights = {self._weights[c] for c in combos}
+            if len(combos) == total and len(weights) == 1:
+                weight = weights.pop()
+                label = hand_class if weight == 1 else f"{hand_class}:{weight:g}"
+                parts.append(label)
+                continue
+            for combo in combos:
+                label = f"{Card(combo[0])}{Card(combo[1])}"
+                weight = self._weights[combo]
+                parts.append(label if weight == 1 else f"{label}:{weight:g}")
+        return ",".join(parts)
+
+    def __repr__(self) -> str:
+        return f"Range('{self}')"
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    views = parser.add_subparsers(dest="view", required=True)
+    count = views.add_parser("count", help="Expand and count a range")
+    show = views.add_parser("show", help="Draw a range as a 13x13 heatmap")
+    for view in (count, show):
+        view.add_argument(
+            "range",
+            nargs="+",
+            metavar="RANGE",
+            help='Range notation, e.g. "22+, A2s+, KTo+"',
+        )
+        view.add_argument("--dead", default="", help="Cards removed first")
+    show.add_argument(
+        "--format",
+        choices=("text", "svg", "png", "json"),
+        default="text",
+        help="png needs --output",
+    )
+    show.add_argument("--output", type=Path, help="Write the heatmap here")
+    show.add_argument("--title", default="", help="SVG title")
+    show.add_argument("--no-color", action="store_true", help="Plain text output")
+    show.add_argument(
+        "--solver",
+        type=Path,
+        help="Shade by an action's frequencies in a `solve --export` file",
+    )
+    show.add_argument("--node", default="", help="Solver node (root: '')")
+    show.add_argument("--action", help="Solver action, e.g. b50")
+
+
+def run(args: argparse.Namespace):
+    try:
+        hand_range = Range.parse(" ".join(args.range))
+        if args.dead:
+            hand_range = hand_range.without(args.dead)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.view == "show":
+        _show(hand_range, args)
+        return
+    print(f"Range: {hand_range}")
+    print(f"Combos: {hand_range.combo_count():g} ({hand_range.percent():.1f}% of 1326)")
+
+
+def _show(hand_range: Range, args: argparse.Namespace):
+    # Imported here: heatmap imports pushfold, which imports this module
+    from heatmap import Heatmap, solver_frequencies
+
+    frequencies = None
+    try:
+        if args.solver:
+            if not args.action:
+                raise ValueError("--solver needs --action")
+            strategy = json.loads(args.solver.read_text())
+            frequencies = solver_frequencies(strategy, args.node, args.action)
+    except (OSError, ValueError) as exc:
+        raise SystemExit(f"error: {exc}") from None
+    heatmap = Heatmap.of(hand_range, frequencies)
+    if args.format == "png":
+        if not args.output:
+            raise SystemExit("error: --format png needs --output")
+        args.output.write_bytes(heatmap.png())
+        print(f"wrote {args.output}")
+        return
+    if args.format == "svg":
+        text = heatmap.svg(args.title)
+    elif args.format == "json":
+        text = json.dumps(heatmap.to_dict(), indent=2) + "\n"
+    else:
+        color = not args.no_color and not args.output and sys.stdout.isatty()
+        text = heatmap.text(color) + "\n"
+    if args.output:
+        args.output.write_text(text)
+        print(f"wrote {args.output}")
+        return
+    sys.stdout.write(text)
+    if args.format == "text":
+        print(f"Combos: {heatmap.combos:g} ({heatmap.percent:.1f}% of 1326)")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Expand, count, and draw a range")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
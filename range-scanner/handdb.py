@@ -0,0 +1,665 @@
+#!/usr/bin/env python3
+"""
+SQLite-backed store for normalized hands.
+
+`HandDB` persists `handhistory.Hand` objects into three tables: `hands` (one row
+per hand), `hand_players` (seat, position, stack, cards, and money per player),
+and `actions` (every action in order). Players, stakes, dates, and positions
+are indexed so stats and search never need to reparse text files. Importing
+the same hand twice is a no-op thanks to the (site, hand_id) key.
+
+The schema is versioned with `PRAGMA user_version`; `MIGRATIONS` holds one SQL
+script per version and `migrate()` applies whatever a database is missing.
+Append new scripts, never edit shipped ones.
+
+`import_histories` is the bulk importer behind `hands import`: it parses files
+across a process pool, stores each file's hands in one transaction (hands
+already stored are counted as duplicates, not errors), collects per-file
+problems without stopping the batch, and reports progress as it goes, so
+multi-million-hand archives import in one run.
+
+Example:
+    python3 pokertools.py hands import --db hands.sqlite hands/ --workers 8
+    python3 pokertools.py hands summary --db hands.sqlite
+"""
+
+from __future__ import annotations
+
+import argparse
+import os
+import sqlite3
+import sys
+import time
+from dataclasses import dataclass, field
+from multiprocessing import Pool, cpu_count
+from pathlib import Path
+from typing import Callable, Iterable, Iterator, List, Optional, Sequence, Tuple
+
+from handhistory import (
+    DATE_PATTERN,
+    HAND_SPLIT_PATTERN,
+    Action,
+    Hand,
+    Player,
+    iter_history_files,
+    parse_hand,
+)
+
+
+# Every command's --db defaults to this variable, then ./hands.sqlite
+DB_ENV = "POKERTOOLS_DB"
+# Seconds between progress lines during an import
+PROGRESS_INTERVAL = 2.0
+
+
+def default_db_path() -> Path:
+    return Path(os.environ.get(DB_ENV, "hands.sqlite"))
+
+
+MIGRATIONS: List[str] = [
+    # 1: initial schema
+    """
+    CREATE TABLE hands (
+        id INTEGER PRIMARY KEY,
+        site TEXT NOT NULL,
+        hand_id TEXT NOT NULL,
+        game TEXT NOT NULL,
+        limit_type TEXT NOT NULL,
+        tournament_id TEXT,
+        table_name TEXT,
+        max_seats INTEGER,
+        button_seat INTEGER,
+        small_blind REAL,
+        big_blind REAL,
+        ante REAL,
+        started_at TEXT,
+        hero TEXT,
+        board TEXT,
+        total_pot REAL,
+        rake REAL,
+        showdown INTEGER NOT NULL DEFAULT 0,
+        UNIQUE (site, hand_id)
+    );
+    CREATE TABLE hand_players (
+        hand_pk INTEGER NOT NULL REFERENCES hands(id) ON DELETE CASCADE,
+        seat INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        position TEXT,
+        stack REAL,
+        hole_cards TEXT,
+        sitting_out INTEGER NOT NULL DEFAULT 0,
+        contributed REAL NOT NULL DEFAULT 0,
+        collected REAL NOT NULL DEFAULT 0,
+        net REAL NOT NULL DEFAULT 0,
+        PRIMARY KEY (hand_pk, seat)
+    );
+    CREATE TABLE actions (
+        hand_pk INTEGER NOT NULL REFERENCES hands(id) ON DELETE CASCADE,
+        seq INTEGER NOT NULL,
+        player TEXT NOT NULL,
+        street TEXT NOT NULL,
+        kind TEXT NOT NULL,
+        amount REAL NOT NULL DEFAULT 0,
+        raise_by REAL NOT NULL DEFAULT 0,
+        all_in INTEGER NOT NULL DEFAULT 0,
+        PRIMARY KEY (hand_pk, seq)
+    );
+    CREATE INDEX idx_hand_players_name ON hand_players(name);
+    CREATE INDEX idx_hand_players_position ON hand_players(position);
+    CREATE INDEX idx_hands_stakes ON hands(big_blind);
+    CREATE INDEX idx_hands_started_at ON hands(started_at);
+    """,
+    # 2: bankroll sessions and deposits/withdrawals (bankroll.py)
+    """
+    CREATE TABLE bankroll_sessions (
+        id INTEGER PRIMARY KEY,
+        started_at TEXT NOT NULL,
+        ended_at TEXT NOT NULL,
+        venue TEXT NOT NULL DEFAULT '',
+        game TEXT NOT NULL DEFAULT '',
+        small_blind REAL,
+        big_blind REAL,
+        buy_in REAL NOT NULL,
+        cash_out REAL NOT NULL,
+        hands INTEGER,
+        notes TEXT NOT NULL DEFAULT ''
+    );
+    CREATE TABLE bankroll_transactions (
+        id INTEGER PRIMARY KEY,
+        at TEXT NOT NULL,
+        amount REAL NOT NULL,
+        note TEXT NOT NULL DEFAULT ''
+    );
+    CREATE INDEX idx_bankroll_sessions_started_at ON bankroll_sessions(started_at);
+    """,
+    # 3: per-player notes, color label, and tags (players.py)
+    """
+    CREATE TABLE player_notes (
+        name TEXT PRIMARY KEY,
+        notes TEXT NOT NULL DEFAULT '',
+        color TEXT,
+        updated_at TEXT NOT NULL
+    );
+    CREATE TABLE player_tags (
+        name TEXT NOT NULL REFERENCES player_notes(name) ON DELETE CASCADE,
+        tag TEXT NOT NULL,
+        PRIMARY KEY (name, tag)
+    );
+    CREATE INDEX idx_player_tags_tag ON player_tags(tag);
+    """,
+    # 4: preflop charts and trainer answers (charts.py)
+    """
+    CREATE TABLE preflop_charts (
+        id INTEGER PRIMARY KEY,
+        position TEXT NOT NULL,
+        situation TEXT NOT NULL,
+        versus TEXT NOT NULL DEFAULT '',
+        stack_bb REAL NOT NULL DEFAULT 100,
+        actions TEXT NOT NULL,
+        UNIQUE (position, situation, versus, stack_bb)
+    );
+    CREATE TABLE trainer_answers (
+        id INTEGER PRIMARY KEY,
+        chart_id INTEGER REFERENCES preflop_charts(id) ON DELETE SET NULL,
+        chart TEXT NOT NULL,
+        hand_class TEXT NOT NULL,
+        answer TEXT NOT NULL,
+        expected TEXT NOT NULL,
+        correct INTEGER NOT NULL,
+        answered_at TEXT NOT NULL
+    );
+    CREATE INDEX idx_trainer_answers_answered_at ON trainer_answers(answered_at);
+    """,
+    # 5: home game ledger (ledger.py)
+    """
+    CREATE TABLE ledger_games (
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL DEFAULT '',
+        played_at TEXT NOT NULL,
+        settled_at TEXT
+    );
+    CREATE TABLE ledger_entries (
+        id INTEGER PRIMARY KEY,
+        game_id INTEGER NOT NULL REFERENCES ledger_games(id) ON DELETE CASCADE,
+        player TEXT NOT NULL,
+        kind TEXT NOT NULL CHECK (kind IN ('buy_in', 'cash_out')),
+        amount REAL NOT NULL,
+        at TEXT NOT NULL
+    );
+    CREATE INDEX idx_ledger_entries_game ON ledger_entries(game_id);
+    CREATE INDEX idx_ledger_entries_player ON ledger_entries(player);
+    """,
+    # 6: currencies and exchange-rate snapshots (money.py); '' is unspecified
+    """
+    ALTER TABLE hands ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+    ALTER TABLE bankroll_sessions ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+    ALTER TABLE bankroll_transactions ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+    ALTER TABLE ledger_games ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+    CREATE TABLE exchange_rates (
+        base TEXT NOT NULL,
+        quote TEXT NOT NULL,
+        rate REAL NOT NULL CHECK (rate > 0),
+        as_of TEXT NOT NULL,
+        PRIMARY KEY (base, quote, as_of)
+    );
+    """,
+    # 7: API keys for the HTTP API, stored as sha256 digests (auth.py)
+    """
+    CREATE TABLE api_keys (
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        role TEXT NOT NULL,
+        key_hash TEXT NOT NULL UNIQUE,
+        prefix TEXT NOT NULL,
+        created_at TEXT NOT NULL,
+        last_used_at TEXT,
+        revoked_at TEXT
+    );
+    """,
+    # 8: append-only log of access to sensitive routes (audit.py)
+    """
+    CREATE TABLE audit_log (
+        id INTEGER PRIMARY KEY,
+        at TEXT NOT NULL,
+        actor TEXT NOT NULL,
+        key_id INTEGER,
+        remote TEXT NOT NULL DEFAULT '',
+        method TEXT NOT NULL,
+        action TEXT NOT NULL,
+        resource TEXT NOT NULL,
+        status INTEGER NOT NULL
+    );
+    CREATE INDEX idx_audit_log_at ON audit_log(at);
+    CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log is append-only');
+    END;
+    CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log is append-only');
+    END;
+    """,
+    # 9: hands run more than once: every run's board and what each player
+    # collected from each run, both "|"-separated by run
+    """
+    ALTER TABLE hands ADD COLUMN runouts TEXT NOT NULL DEFAULT '';
+    ALTER TABLE hand_players ADD COLUMN run_collected TEXT NOT NULL DEFAULT '';
+    """,
+]
+
+
+def normalize_timestamp(value: str) -> Optional[str]:
+    """Sites print 2024/01/05 9:05:00; store sortable 2024-01-05 09:05:00"""
+    match = DATE_PATTERN.search(value or "")
+    if not match:
+        return None
+    date, time = match.group(1).split(" ")
+    hours, rest = time.split(":", 1)
+    return f"{date.replace('/', '-')} {int(hours):02d}:{rest}"
+
+
+class HandDB:
+    """Durable hand storage; use as a context manager or call close()"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self.conn = sqlite3.connect(self.path.as_posix())
+        self.conn.execute("PRAGMA foreign_keys = ON")
+        self.migrate()
+
+    def __enter__(self) -> "HandDB":
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self):
+        self.conn.close()
+
+    @property
+    def schema_version(self) -> int:
+        return self.conn.execute("PRAGMA user_version").fetchone()[0]
+
+    def migrate(self) -> int:
+        """Apply pending migrations; returns the resulting schema version"""
+        version = self.schema_version
+        if version > len(MIGRATIONS):
+            raise RuntimeError(
+                f"{self.path} has schema v{version}, newer than this code supports"
+            )
+        for number, script in enumerate(MIGRATIONS[version:], start=version + 1):
+            self.conn.executescript(
+                f"BEGIN; {script}; PRAGMA user_version = {number}; COMMIT;"
+            )
+        return self.schema_version
+
+    def insert_hand(self, hand: Hand) -> bool:
+        """Store one hand; returns False when it was already present"""
+        with self.conn:
+            return self._insert(hand)
+
+    def insert_hands(self, hands: Iterable[Hand]) -> int:
+        inserted = 0
+        with self.conn:
+            for hand in hands:
+                inserted += self._insert(hand)
+        return inserted
+
+    def _insert(self, hand: Hand) -> bool:
+        cursor = self.conn.execute(
+            """
+            INSERT OR IGNORE INTO hands (
+                site, hand_id, game, limit_type, tournament_id, table_name,
+                max_seats, button_seat, small_blind, big_blind, ante, started_at,
+                hero, board, total_pot, rake, showdown, currency, runouts
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            """,
+            (
+                hand.site,
+                hand.hand_id,
+                hand.game,
+                hand.limit,
+                hand.tournament_id,
+                hand.table,
+                hand.max_seats,
+                hand.button_seat,
+                hand.small_blind,
+                hand.big_blind,
+                hand.ante,
+                normalize_timestamp(hand.started_at),
+                hand.hero,
+                " ".join(hand.board),
+                hand.total_pot,
+                hand.rake,
+                int(hand.showdown),
+                hand.currency,
+                "|".join(" ".join(board) for board in hand.runouts),
+            ),
+        )
+        if not cursor.rowcount:
+            return False
+        hand_pk = cursor.lastrowid
+        contributed = hand.contributions()
+        net = hand.net()
+        self.conn.executemany(
+            """
+            INSERT INTO hand_players (
+                hand_pk, seat, name, position, stack, hole_cards, sitting_out,
+                contributed, collected, net, run_collected
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            """,
+            [
+                (
+                    hand_pk,
+                    player.seat,
+                    player.name,
+                    player.position,
+                    player.stack,
+                    " ".join(player.hole_cards),
+                    int(player.sitting_out),
+                    contributed.get(player.name, 0.0),
+                    hand.collected.get(player.name, 0.0),
+                    net.get(player.name, 0.0),
+                    "|".join(
+                        str(run.get(player.name, 0.0)) for run in hand.run_collected
+                    ),
+                )
+                for player in hand.players
+            ],
+        )
+        self.conn.executemany(
+            """
+            INSERT INTO actions (
+                hand_pk, seq, player, street, kind, amount, raise_by, all_in
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+            """,
+            [
+                (
+                    hand_pk,
+                    seq,
+                    action.player,
+                    action.street,
+                    action.kind,
+                    action.amount,
+                    action.raise_by,
+                    int(action.all_in),
+                )
+                for seq, action in enumerate(hand.actions)
+            ],
+        )
+        return True
+
+    def count(self) -> int:
+        return self.conn.execute("SELECT COUNT(*) FROM hands").fetchone()[0]
+
+    def get_hand(self, site: str, hand_id: str) -> Optional[Hand]:
+        row = self.conn.execute(
+            "SELECT id FROM hands WHERE site = ? AND hand_id = ?", (site, hand_id)
+        ).fetchone()
+        return self.load(row[0]) if row else None
+
+    def load(self, hand_pk: int) -> Hand:
+        """Rebuild a `Hand` from its stored rows"""
+        row = self.conn.execute(
+            """
+            SELECT site, hand_id, game, limit_type, tournament_id, table_name,
+                   max_seats, button_seat, small_blind, big_blind, ante,
+                   started_at, hero, board, total_pot, rake, showdown, currency,
+                   runouts
+            FROM hands WHERE id = ?
+            """,
+            (hand_pk,),
+        ).fetchone()
+        if row is None:
+            raise KeyError(hand_pk)
+        hand = Hand(
+            site=row[0],
+            hand_id=row[1],
+            game=row[2],
+            limit=row[3],
+            tournament_id=row[4],
+            table=row[5] or "",
+            max_seats=row[6] or 0,
+            button_seat=row[7] or 0,
+            small_blind=row[8] or 0.0,
+            big_blind=row[9] or 0.0,
+            ante=row[10] or 0.0,
+            started_at=(row[11] or "").replace("-", "/"),
+            hero=row[12],
+            board=(row[13] or "").split(),
+            total_pot=row[14] or 0.0,
+            rake=row[15] or 0.0,
+            showdown=bool(row[16]),
+            currency=row[17],
+            runouts=[board.split() for board in row[18].split("|") if board],
+        )
+        for seat, name, position, stack, cards, sitting_out, collected, runs in (
+            self.conn.execute(
+                """
+                SELECT seat, name, position, stack, hole_cards, sitting_out, collected,
+                       run_collected
+                FROM hand_players WHERE hand_pk = ? ORDER BY seat
+                """,
+                (hand_pk,),
+            )
+        ):
+            hand.players.append(
+                Player(
+                    seat,
+                    name,
+                    stack,
+                    (cards or "").split(),
+                    position or "",
+                    bool(sitting_out),
+                )
+            )
+            if collected:
+                hand.collected[name] = collected
+            for run, amount in enumerate(runs.split("|") if runs else []):
+                if run == len(hand.run_collected):
+                    hand.run_collected.append({})
+                if float(amount):
+                    hand.run_collected[run][name] = float(amount)
+        for player, street, kind, amount, raise_by, all_in in self.conn.execute(
+            """
+            SELECT player, street, kind, amount, raise_by, all_in
+            FROM actions WHERE hand_pk = ? ORDER BY seq
+            """,
+            (hand_pk,),
+        ):
+            hand.actions.append(
+                Action(player, street, kind, amount, raise_by, bool(all_in))
+            )
+        # Uncalled bets are not stored separately: recover them from the totals
+        stored = dict(
+            self.conn.execute(
+                "SELECT name, contributed FROM hand_players WHERE hand_pk = ?",
+                (hand_pk,),
+            ).fetchall()
+        )
+        for name, amount in hand.contributions().items():
+            refund = round(amount - stored.get(name, amount), 2)
+            if refund > 0:
+                hand.uncalled[name] = refund
+        return hand
+
+    def hand_keys(
+        self, where: str = "", params: Sequence = ()
+    ) -> Iterator[Tuple[int, str, str]]:
+        """(pk, site, hand_id) of matching hands in chronological order"""
+        query = "SELECT id, site, hand_id FROM hands h"
+        if where:
+            query += f" WHERE {where}"
+        query += " ORDER BY started_at, id"
+        yield from self.conn.execute(query, tuple(params))
+
+    def hands(self, where: str = "", params: Sequence = ()) -> Iterator[Hand]:
+        for hand_pk, _, _ in list(self.hand_keys(where, params)):
+            yield self.load(hand_pk)
+
+    def summary(self) -> dict:
+        total = self.count()
+        sites = self.conn.execute(
+            "SELECT site, COUNT(*) FROM hands GROUP BY site ORDER BY 2 DESC"
+        ).fetchall()
+        stakes = self.conn.execute(
+            """
+            SELECT small_blind, big_blind, COUNT(*) FROM hands
+            GROUP BY small_blind, big_blind ORDER BY big_blind
+            """
+        ).fetchall()
+        span = self.conn.execute(
+            "SELECT MIN(started_at), MAX(started_at) FROM hands"
+        ).fetchone()
+        players = self.conn.execute(
+            "SELECT COUNT(DISTINCT name) FROM hand_players"
+        ).fetchone()[0]
+        return {
+            "hands": total,
+            "players": players,
+            "sites": dict(sites),
+            "stakes": {f"{sb:g}/{bb:g}": count for sb, bb, count in stakes},
+            "first_hand": span[0],
+            "last_hand": span[1],
+            "schema_version": self.schema_version,
+        }
+
+
+@dataclass
+class ImportReport:
+    files: int = 0
+    total_files: int = 0
+    parsed: int = 0
+    inserted: int = 0
+    # (file, problem) for unreadable files and hands that failed to parse
+    errors: List[Tuple[str, str]] = field(default_factory=list)
+
+    @property
+    def duplicates(self) -> int:
+        return self.parsed - self.inserted
+
+    def to_dict(self) -> dict:
+        return {
+            "files": self.files,
+            "parsed": self.parsed,
+            "inserted": self.inserted,
+            "duplicates": self.duplicates,
+            "errors": [{"file": path, "error": text} for path, text in self.errors],
+        }
+
+
+def _parse_task(path: Path) -> Tuple[Path, List[Hand], Optional[str]]:
+    """Worker: one file's hands and a description of what failed, if anything"""
+    try:
+        text = Path(path).read_text(encoding="utf-8", errors="ignore")
+    except OSError as exc:
+        return path, [], str(exc)
+    hands: List[Hand] = []
+    failed: List[str] = []
+    for block in HAND_SPLIT_PATTERN.split(text):
+        if not block.strip():
+            continue
+        try:
+            hands.append(parse_hand(block))
+        except ValueError as exc:
+            failed.append(str(exc))
+    if not failed:
+        return path, hands, None
+    noun = "hand" if len(failed) == 1 else "hands"
+    return path, hands, f"{len(failed)} {noun} not parsed (first: {failed[0]})"
+
+
+def import_histories(
+    db: HandDB,
+    paths: Iterable[Path],
+    workers: Optional[int] = None,
+    progress: Optional[Callable[[ImportReport], None]] = None,
+) -> ImportReport:
+    """Parse every history file under `paths` and store the hands"""
+    files = list(iter_history_files(paths))
+    report = ImportReport(total_files=len(files))
+    workers = min(workers or cpu_count(), max(len(files), 1))
+    pool = None
+    if workers > 1:
+        try:
+            pool = Pool(workers)
+        except PermissionError:
+            pool = None
+    try:
+        results = (
+            pool.imap_unordered(_parse_task, files, chunksize=4)
+            if pool
+            else map(_parse_task, files)
+        )
+        for path, hands, error in results:
+            report.files += 1
+            report.parsed += len(hands)
+            report.inserted += db.insert_hands(hands)
+            if error:
+                report.errors.append((str(path), error))
+            if progress is not None:
+                progress(report)
+    finally:
+        if pool is not None:
+            pool.terminate()
+    return report
+
+
+def _print_progress(report: ImportReport, last: List[float]):
+    # At most one line per PROGRESS_INTERVAL, plus the final file
+    now = time.monotonic()
+    if now - last[0] < PROGRESS_INTERVAL and report.files < report.total_files:
+        return
+    last[0] = now
+    print(
+        f"  {report.files:,}/{report.total_files:,} files, "
+        f"{report.parsed:,} hands, {report.inserted:,} new",
+        file=sys.stderr,
+    )
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    subparsers = parser.add_subparsers(dest="action", required=True)
+    import_parser = subparsers.add_parser("import", help="Parse and store hands")
+    import_parser.add_argument("paths", nargs="+", type=Path)
+    import_parser.add_argument("--workers", type=int, help="Parser processes")
+    import_parser.add_argument(
+        "--quiet", action="store_true", help="No progress lines"
+    )
+    subparsers.add_parser("summary", help="Show what the database holds")
+    subparsers.add_parser("migrate", help="Upgrade the schema")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        if args.action == "import":
+            last = [0.0]
+            report = import_histories(
+                db,
+                args.paths,
+                args.workers,
+                None if args.quiet else lambda done: _print_progress(done, last),
+            )
+            for path, error in report.errors:
+                print(f"warning: {path}: {error}", file=sys.stderr)
+            print(
+                f"Parsed {report.parsed:,} hands from {report.files:,} files, "
+                f"stored {report.inserted:,} new, skipped {report.duplicates:,} "
+                f"duplicates ({db.path})"
+            )
+        elif args.action == "summary":
+            for key, value in db.summary().items():
+                print(f"{key:<15} {value}")
+        else:
+            print(f"{db.path} at schema v{db.migrate()}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="SQLite hand database")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,570 @@
+#!/usr/bin/env python3
+"""
+Hand history parsers for PokerStars, GGPoker, and Winamax text exports.
+
+Every site's text is normalized into the same `Hand`: seats and starting
+stacks, every action per street, the board, shown cards, and what each player
+collected. The site is detected from the first line, so mixed files work:
+
+    hands = parse_file("HH20240101.txt")
+    for hand in hands:
+        print(hand.site, hand.hand_id, hand.board, hand.net())
+
+Amounts are floats in the units the site prints (chips or currency, with the
+symbol stripped); `Hand.currency` is the ISO code of a cash game's money (from
+the header's symbol or trailing code) and empty for tournament chips. For a
+raise, `Action.amount` is the raise-to total for the street and
+`Action.raise_by` the increment; every other amount is what the action put
+in. `Hand.contributions()` turns that into chips committed per player,
+uncalled bets already refunded.
+
+Antes and bomb pot posts are dead money: they go in the pot without counting
+toward the preflop bet, so a bomb pot (everyone posts, no preflop betting)
+and a big-blind ante add up the same way as classic antes. A straddle,
+Mississippi (from the button) or UTG, and any re-straddle is a live blind
+that preflop raises and calls build on; all of them are `FORCED_ACTIONS`.
+
+A hand run twice (or three times) prints "*** FIRST FLOP ***", "*** SECOND
+TURN ***", ... and one "*** FIRST SHOW DOWN ***" section per run.
+`Hand.board` stays the first run's board; `Hand.runouts` holds every run's
+full board and `Hand.run_collected` what each run paid, both empty for a hand
+dealt once. `Hand.collected` is still the total over all runs.
+
+Example:
+    python3 handhistory.py hands/ --json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import re
+from collections import Counter
+from dataclasses import asdict, dataclass, field
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+
+
+STREETS = ["preflop", "flop", "turn", "river"]
+# Posted before the cards are dealt, never a voluntary decision
+FORCED_ACTIONS = ("ante", "bomb_pot", "small_blind", "big_blind", "straddle")
+# Forced posts that do not count toward the street's bet
+DEAD_POSTS = ("ante", "bomb_pot")
+# Community cards showing once each street is dealt
+BOARD_SIZES = {"preflop": 0, "flop": 3, "turn": 4, "river": 5}
+AMOUNT = r"[$€£]?([\d,]+(?:\.\d+)?)[$€£]?"
+HAND_SPLIT_PATTERN = re.compile(r"\n\s*\n+")
+BRACKET_PATTERN = re.compile(r"\[([^\]]*)\]")
+TABLE_PATTERN = re.compile(r"^Table:? '([^']*)' (\d+)-max.*?Seat #(\d+) is the button")
+STREET_PATTERN = re.compile(
+    r"^\*\*\* (?:(FIRST|SECOND|THIRD) )?"
+    r"(HOLE CARDS|PRE-FLOP|ANTE/BLINDS|FLOP|TURN|RIVER|SHOW ?DOWN|SUMMARY)"
+)
+# Run labels of a hand dealt more than once, in order
+RUN_ORDINALS = ("FIRST", "SECOND", "THIRD")
+DEALT_PATTERN = re.compile(r"^Dealt to (.+?) \[([^\]]+)\]")
+COLLECTED_PATTERN = re.compile(rf"^(.+?) collected {AMOUNT} from")
+UNCALLED_PATTERN = re.compile(rf"^Uncalled bet \({AMOUNT}\) returned to (.+)$")
+TOTAL_PATTERN = re.compile(rf"^Total pot {AMOUNT}.*?\| Rake {AMOUNT}")
+SUMMARY_SHOWN_PATTERN = re.compile(
+    r"^Seat \d+: (.+?) (?:\([^)]*\) )*(?:showed|mucked|shows) \[([^\]]+)\]"
+)
+BLINDS_PATTERN = re.compile(
+    rf"\({AMOUNT}/{AMOUNT}(?:\({AMOUNT}\))?(?: [A-Z]{{3}})?\)"
+)
+DATE_PATTERN = re.compile(r"(\d{4}/\d{2}/\d{2} \d{1,2}:\d{2}:\d{2})")
+CURRENCY_SYMBOLS = {"$": "USD", "€": "EUR", "£": "GBP"}
+CURRENCY_CODE_PATTERN = re.compile(r" ([A-Z]{3})\)")
+TIMESTAMP_FORMAT = "%Y/%m/%d %H:%M:%S"
+
+VERB_PATTERNS = [
+    (re.compile(rf"^posts (?:the )?ante {AMOUNT}"), "ante"),
+    (re.compile(rf"^posts small blind {AMOUNT}"), "small_blind"),
+    (re.compile(rf"^posts big blind {AMOUNT}"), "big_blind"),
+    (re.compile(rf"^posts (?:a )?bomb pot {AMOUNT}"), "bomb_pot"),
+    (re.compile(rf"^(?:posts (?:a )?straddle|straddles) {AMOUNT}"), "straddle"),
+    (re.compile(r"^folds"), "fold"),
+    (re.compile(r"^checks"), "check"),
+    (re.compile(rf"^calls {AMOUNT}"), "call"),
+    (re.compile(rf"^bets {AMOUNT}"), "bet"),
+    (re.compile(rf"^raises {AMOUNT} to {AMOUNT}"), "raise"),
+]
+SHOWS_PATTERN = re.compile(r"^shows \[([^\]]+)\]")
+
+GAME_NAMES = {
+    "hold'em": "holdem",
+    "holdem": "holdem",
+    "6+ hold'em": "shortdeck",
+    "omaha": "omaha",
+    "omaha hi/lo": "omaha8",
+    "5 card omaha": "omaha5",
+    "omaha5": "omaha5",
+}
+
+# Seats after the big blind, latest first; the first seat after the BB is UTG
+LATE_POSITIONS = ["CO", "HJ", "LJ", "UTG+2", "UTG+1"]
+
+
+def parse_amount(text: str) -> float:
+    return float(text.replace(",", ""))
+
+
+@dataclass
+class Action:
+    """One action; see the module docstring for what `amount` means"""
+
+    player: str
+    street: str
+    kind: str
+    amount: float = 0.0
+    raise_by: float = 0.0
+    all_in: bool = False
+
+
+@dataclass
+class Player:
+    seat: int
+    name: str
+    stack: float
+    hole_cards: List[str] = field(default_factory=list)
+    position: str = ""
+    sitting_out: bool = False
+
+
+@dataclass
+class Hand:
+    """Site-independent view of one hand"""
+
+    site: str
+    hand_id: str
+    game: str = "holdem"
+    limit: str = "no limit"
+    tournament_id: Optional[str] = None
+    table: str = ""
+    max_seats: int = 0
+    button_seat: int = 0
+    small_blind: float = 0.0
+    big_blind: float = 0.0
+    ante: float = 0.0
+    currency: str = ""
+    started_at: str = ""
+    hero: Optional[str] = None
+    players: List[Player] = field(default_factory=list)
+    actions: List[Action] = field(default_factory=list)
+    board: List[str] = field(default_factory=list)
+    collected: Dict[str, float] = field(default_factory=dict)
+    uncalled: Dict[str, float] = field(default_factory=dict)
+    total_pot: float = 0.0
+    rake: float = 0.0
+    showdown: bool = False
+    runouts: List[List[str]] = field(default_factory=list)
+    run_collected: List[Dict[str, float]] = field(default_factory=list)
+
+    @property
+    def is_tournament(self) -> bool:
+        return self.tournament_id is not None
+
+    @property
+    def run_count(self) -> int:
+        return max(len(self.runouts), 1)
+
+    @property
+    def is_bomb_pot(self) -> bool:
+        return any(action.kind == "bomb_pot" for action in self.actions)
+
+    def player(self, name: str) -> Optional[Player]:
+        for player in self.players:
+            if player.name == name:
+                return player
+        return None
+
+    def actions_on(self, street: str) -> List[Action]:
+        return [action for action in self.actions if action.street == street]
+
+    def contributions(self) -> Dict[str, float]:
+        """Chips each player put in, after uncalled bets were returned"""
+        totals: Counter = Counter()
+        for street in STREETS:
+            committed: Counter = Counter()
+            for action in self.actions_on(street):
+                if action.kind in DEAD_POSTS:
+                    totals[action.player] += action.amount
+                elif action.kind == "raise":
+                    committed[action.player] = action.amount
+                elif action.amount:
+                    committed[action.player] += action.amount
+            totals.update(committed)
+        for name, amount in self.uncalled.items():
+            totals[name] -= amount
+        return {player.name: round(totals[player.name], 2) for player in self.players}
+
+    def dead_money(self) -> Dict[str, float]:
+        """Antes and bomb pot posts per player: the dead part of contributions"""
+        totals: Counter = Counter()
+        for action in self.actions:
+            if action.kind in DEAD_POSTS:
+                totals[action.player] += action.amount
+        return {player.name: round(totals[player.name], 2) for player in self.players}
+
+    def net(self) -> Dict[str, float]:
+        """Profit per player for the hand (rake already excluded by the site)"""
+        put_in = self.contributions()
+        return {
+            name: round(self.collected.get(name, 0.0) - amount, 2)
+            for name, amount in put_in.items()
+        }
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        data["contributions"] = self.contributions()
+        data["net"] = self.net()
+        return data
+
+
+def assign_positions(players: List[Player], button_seat: int):
+    """Label dealt-in players BTN, SB, BB, UTG ... CO clockwise from the button"""
+    active = sorted(
+        (player for player in players if not player.sitting_out),
+        key=lambda player: player.seat,
+    )
+    if not active:
+        return
+    start = next(
+        (idx for idx, player in enumerate(active) if player.seat >= button_seat), 0
+    )
+    ordered = active[start:] + active[:start]
+    if len(ordered) == 2:
+        labels = ["BTN", "BB"]
+    else:
+        rest = len(ordered) - 3
+        early = ["UTG"] if rest else []
+        labels = ["BTN", "SB", "BB"] + early + LATE_POSITIONS[: max(rest - 1, 0)][::-1]
+    for player, label in zip(ordered, labels):
+        player.position = label
+
+
+class SiteParser:
+    """Shared line-oriented parsing; subclasses handle headers and seat lines"""
+
+    site = ""
+    # Separator between player name and verb on action lines
+    name_separator = ": "
+    seat_pattern = re.compile(
+        rf"^Seat (\d+): (.+?) \({AMOUNT} in chips(?:, [^)]*)?\)( is sitting out)?"
+    )
+
+    def detect(self, first_line: str) -> bool:
+        raise NotImplementedError
+
+    def parse_header(self, hand: Hand, line: str):
+        raise NotImplementedError
+
+    def parse(self, text: str) -> Hand:
+        lines = [line.strip() for line in text.strip().splitlines() if line.strip()]
+        if not lines or not self.detect(lines[0]):
+            raise ValueError(f"Not a {self.site} hand history")
+        hand = Hand(site=self.site, hand_id="")
+        self.parse_header(hand, lines[0])
+        if not hand.is_tournament:
+            hand.currency = detect_currency(lines[0])
+
+        street = "preflop"
+        in_summary = False
+        names: List[str] = []
+        for line in lines[1:]:
+            table = TABLE_PATTERN.match(line)
+            if table:
+                hand.table = table.group(1)
+                hand.max_seats = int(table.group(2))
+                hand.button_seat = int(table.group(3))
+                continue
+            street_match = STREET_PATTERN.match(line)
+            if street_match:
+                street, in_summary = self._enter_street(hand, street_match, line)
+                continue
+            if in_summary:
+                self._parse_summary_line(hand, line)
+                continue
+            if line.startswith("Seat "):
+                seat = self.seat_pattern.match(line)
+                if seat:
+                    hand.players.append(self._player(seat))
+                    names = sorted(
+                        (player.name for player in hand.players), key=len, reverse=True
+                    )
+                    continue
+            self._parse_line(hand, line, street, names)
+
+        if not hand.uncalled:
+            _infer_uncalled(hand)
+        assign_positions(hand.players, hand.button_seat)
+        return hand
+
+    def _player(self, match: re.Match) -> Player:
+        return Player(
+            seat=int(match.group(1)),
+            name=match.group(2),
+            stack=parse_amount(match.group(3)),
+            sitting_out=match.re.groups >= 4 and bool(match.group(4)),
+        )
+
+    def _enter_street(self, hand: Hand, match: re.Match, line: str) -> Tuple[str, bool]:
+        ordinal, label = match.groups()
+        if label == "SUMMARY":
+            return "showdown", True
+        if label.startswith("SHOW"):
+            hand.showdown = True
+            if ordinal:
+                hand.run_collected.append({})
+            return "showdown", False
+        if label in ("FLOP", "TURN", "RIVER"):
+            groups = BRACKET_PATTERN.findall(line)
+            run = RUN_ORDINALS.index(ordinal) if ordinal else 0
+            if ordinal:
+                while len(hand.runouts) <= run:
+                    hand.runouts.append([])
+                for card in " ".join(groups).split():
+                    if card not in hand.runouts[run]:
+                        hand.runouts[run].append(card)
+            if run == 0:
+                cards = groups[0] if label == "FLOP" else groups[-1]
+                for card in cards.split():
+                    if card not in hand.board:
+                        hand.board.append(card)
+            return label.lower(), False
+        return "preflop", False
+
+    def _parse_summary_line(self, hand: Hand, line: str):
+        total = TOTAL_PATTERN.match(line)
+        if total:
+            hand.total_pot = parse_amount(total.group(1))
+            hand.rake = parse_amount(total.group(2))
+            return
+        shown = SUMMARY_SHOWN_PATTERN.match(line)
+        if shown:
+            player = hand.player(shown.group(1))
+            if player and not player.hole_cards:
+                player.hole_cards = shown.group(2).split()
+
+    def _parse_line(self, hand: Hand, line: str, street: str, names: List[str]):
+        dealt = DEALT_PATTERN.match(line)
+        if dealt:
+            player = hand.player(dealt.group(1))
+            if player:
+                player.hole_cards = dealt.group(2).split()
+                hand.hero = player.name
+            return
+        uncalled = UNCALLED_PATTERN.match(line)
+        if uncalled:
+            name = uncalled.group(2).strip()
+            hand.uncalled[name] = hand.uncalled.get(name, 0.0) + parse_amount(
+                uncalled.group(1)
+            )
+            return
+        collected = COLLECTED_PATTERN.match(line)
+        if collected and hand.player(collected.group(1)):
+            name, amount = collected.group(1), parse_amount(collected.group(2))
+            hand.collected[name] = hand.collected.get(name, 0.0) + amount
+            if hand.run_collected:
+                run = hand.run_collected[-1]
+                run[name] = run.get(name, 0.0) + amount
+            return
+
+        for name in names:
+            prefix = name + self.name_separator
+            if line.startswith(prefix):
+                self._parse_verb(hand, name, line[len(prefix) :], street)
+                return
+
+    def _parse_verb(self, hand: Hand, name: str, rest: str, street: str):
+        shows = SHOWS_PATTERN.match(rest)
+        if shows:
+            hand.player(name).hole_cards = shows.group(1).split()
+            return
+        for pattern, kind in VERB_PATTERNS:
+            match = pattern.match(rest)
+            if not match:
+                continue
+            action = Action(name, street, kind, all_in="all-in" in rest)
+            if kind == "raise":
+                action.raise_by = parse_amount(match.group(1))
+                action.amount = parse_amount(match.group(2))
+            elif match.groups():
+                action.amount = parse_amount(match.group(1))
+            hand.actions.append(action)
+            return
+
+
+class PokerStarsParser(SiteParser):
+    site = "pokerstars"
+    header_pattern = re.compile(
+        r"^PokerStars (?:Zoom )?Hand #(\d+):\s+(?:Tournament #(\d+), .*?)?"
+        r"(6\+ Hold'em|Hold'em|Omaha Hi/Lo|5 Card Omaha|Omaha) "
+        r"(No Limit|Pot Limit|Limit)"
+    )
+
+    def detect(self, first_line: str) -> bool:
+        return first_line.startswith("PokerStars ")
+
+    def parse_header(self, hand: Hand, line: str):
+        match = self.header_pattern.match(line)
+        if not match:
+            raise ValueError(f"Unrecognized {self.site} header: {line!r}")
+        hand.hand_id = match.group(1)
+        hand.tournament_id = match.group(2)
+        hand.game = GAME_NAMES[match.group(3).lower()]
+        hand.limit = match.group(4).lower()
+        _parse_blinds(hand, line[match.end() :])
+
+
+class GGPokerParser(PokerStarsParser):
+    site = "ggpoker"
+    header_pattern = re.compile(
+        r"^Poker Hand #(\w+):\s+(?:Tournament #(\d+), .*?)?"
+        r"(6\+ Hold'em|Hold'em|Omaha Hi/Lo|5 Card Omaha|Omaha) "
+        r"(No Limit|Pot Limit|Limit)"
+    )
+
+    def detect(self, first_line: str) -> bool:
+        return first_line.startswith("Poker Hand #")
+
+
+class WinamaxParser(SiteParser):
+    site = "winamax"
+    name_separator = " "
+    seat_pattern = re.compile(rf"^Seat (\d+): (.+?) \({AMOUNT}(?:, [^)]*)?\)$")
+    header_pattern = re.compile(
+        r"^Winamax Poker - (.*?) - HandId: #([\w-]+) - "
+        r"(Holdem|Omaha5|Omaha) (no limit|pot limit|fixed limit) \(([^)]*)\)"
+    )
+
+    def detect(self, first_line: str) -> bool:
+        return first_line.startswith("Winamax Poker")
+
+    def parse_header(self, hand: Hand, line: str):
+        match = self.header_pattern.match(line)
+        if not match:
+            raise ValueError(f"Unrecognized {self.site} header: {line!r}")
+        kind, hand.hand_id, game, limit, blinds = match.groups()
+        hand.game = GAME_NAMES[game.lower()]
+        hand.limit = limit.replace("fixed ", "")
+        if kind.startswith("Tournament"):
+            # Winamax hand ids start with the tournament id
+            hand.tournament_id = hand.hand_id.split("-")[0]
+        amounts = [
+            parse_amount(part.strip("$€£ ")) for part in blinds.split("/") if part
+        ]
+        if len(amounts) == 3:
+            hand.ante, hand.small_blind, hand.big_blind = amounts
+        elif len(amounts) == 2:
+            hand.small_blind, hand.big_blind = amounts
+        date = DATE_PATTERN.search(line)
+        hand.started_at = date.group(1) if date else ""
+
+
+def _infer_uncalled(hand: Hand):
+    """Refund the unmatched part of the last bet for sites that don't print it"""
+    for street in reversed(STREETS):
+        committed: Counter = Counter()
+        for action in hand.actions_on(street):
+            if action.kind == "raise":
+                committed[action.player] = action.amount
+            elif action.kind not in DEAD_POSTS and action.amount:
+                committed[action.player] += action.amount
+        if not committed:
+            continue
+        amounts = committed.most_common(2) + [("", 0.0)]
+        (top, first), (_, second) = amounts[0], amounts[1]
+        if first > second:
+            hand.uncalled[top] = round(first - second, 2)
+        return
+
+
+def detect_currency(header: str) -> str:
+    """"USD" for "($0.01/$0.02 USD)" or "($0.02/$0.05)", "" without money"""
+    code = CURRENCY_CODE_PATTERN.search(header)
+    if code:
+        return code.group(1)
+    for symbol, currency in CURRENCY_SYMBOLS.items():
+        if symbol in header:
+            return currency
+    return ""
+
+
+def _parse_blinds(hand: Hand, rest: str):
+    blinds = BLINDS_PATTERN.search(rest)
+    if blinds:
+        hand.small_blind = parse_amount(blinds.group(1))
+        hand.big_blind = parse_amount(blinds.group(2))
+        if blinds.group(3):
+            hand.ante = parse_amount(blinds.group(3))
+    date = DATE_PATTERN.search(rest)
+    hand.started_at = date.group(1) if date else ""
+
+
+PARSERS: List[SiteParser] = [PokerStarsParser(), GGPokerParser(), WinamaxParser()]
+
+
+def detect_site(text: str) -> Optional[SiteParser]:
+    first_line = text.strip().splitlines()[0].strip() if text.strip() else ""
+    first_line = first_line.lstrip("\ufeff")
+    for parser in PARSERS:
+        if parser.detect(first_line):
+            return parser
+    return None
+
+
+def parse_hand(text: str) -> Hand:
+    parser = detect_site(text.lstrip("\ufeff"))
+    if parser is None:
+        raise ValueError("Unknown hand history format")
+    return parser.parse(text.lstrip("\ufeff"))
+
+
+def parse_hands(text: str, strict: bool = False) -> List[Hand]:
+    """Parse every hand in a blob of text; unknown blocks are skipped"""
+    hands = []
+    for block in HAND_SPLIT_PATTERN.split(text):
+        if not block.strip():
+            continue
+        try:
+            hands.append(parse_hand(block))
+        except ValueError:
+            if strict:
+                raise
+    return hands
+
+
+def parse_file(path: Path, strict: bool = False) -> List[Hand]:
+    text = Path(path).read_text(encoding="utf-8", errors="ignore")
+    return parse_hands(text, strict)
+
+
+def iter_history_files(paths: Iterable[Path]) -> Iterable[Path]:
+    for path in paths:
+        path = Path(path)
+        if path.is_dir():
+            yield from sorted(path.rglob("*.txt"))
+        else:
+            yield path
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Parse site hand histories")
+    parser.add_argument("paths", nargs="+", type=Path, help="Files or directories")
+    parser.add_argument("--json", action="store_true", help="Print one JSON per hand")
+    args = parser.parse_args()
+
+    sites: Counter = Counter()
+    for path in iter_history_files(args.paths):
+        for hand in parse_file(path):
+            sites[hand.site] += 1
+            if args.json:
+                print(json.dumps(hand.to_dict()))
+    if not args.json:
+        for site, count in sites.most_common():
+            print(f"{site:<12} {count:>8,} hands")
+
+
+if __name__ == "__main__":
+    main()
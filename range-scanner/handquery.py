@@ -0,0 +1,400 @@
+#!/usr/bin/env python3
+"""
+Filter language and paginated search over the SQLite hand database.
+
+A query is comparisons joined with `and`/`or` (and binds tighter), optionally
+negated with `not` and grouped with parentheses:
+
+    position=BTN and pot>50bb and line=check-raise-flop
+    player="Bob Smith" and (cards=AKs or range=QQ+) and net<0
+
+Each comparison is evaluated for one player in a hand: the hero by default, or
+whoever `player=` names (`player=*` means anyone). Fields:
+
+- player, position, site, game, cards (a hand class such as AKo or exact
+  cards such as AhKd, in any case), range (range notation the hole cards must
+  fall in)
+- date (YYYY-MM-DD prefix compare), bb (big blind), showdown (true/false)
+- post: a forced post anyone made in the hand (post=straddle, post=bomb_pot,
+  post=ante)
+- pot, net, stack: numbers, or big blinds with a `bb` suffix (pot>50bb)
+- line: the player's actions on one street, e.g. line=check-raise-flop;
+  `~` matches a contiguous part of the line (line~raise-preflop)
+
+Example:
+    python3 handquery.py --db hands.sqlite "position=BTN and pot>50bb"
+"""
+
+from __future__ import annotations
+
+import argparse
+import re
+import sqlite3
+from functools import lru_cache
+from pathlib import Path
+from typing import Dict, List, Optional, Sequence, Tuple
+
+from cards import parse_cards
+from handdb import HandDB, default_db_path
+from handhistory import FORCED_ACTIONS, STREETS
+from hand_range import (
+    CLASS_PATTERN,
+    COMBO_PATTERN,
+    Range,
+    hand_class_of,
+    make_combo,
+)
+
+
+DEFAULT_PER_PAGE = 50
+MAX_PER_PAGE = 500
+TOKEN_PATTERN = re.compile(
+    r'\s*(?:(\()|(\))|(>=|<=|!=|=|>|<|~)|"([^"]*)"|([^\s()=!<>~"]+))'
+)
+KEYWORDS = {"and", "or", "not"}
+
+TEXT_FIELDS = {
+    "player": "hp.name",
+    "position": "hp.position",
+    "site": "h.site",
+    "game": "h.game",
+}
+NUMBER_FIELDS = {
+    "pot": "h.total_pot",
+    "net": "hp.net",
+    "stack": "hp.stack",
+    "bb": "h.big_blind",
+}
+COMPARE_OPS = {"=", "!=", ">", ">=", "<", "<="}
+
+
+@lru_cache(maxsize=64)
+def _parsed_range(text: str) -> Range:
+    return Range.parse(text)
+
+
+def _hole_combo(hole_cards: Optional[str]) -> Optional[Tuple[int, int]]:
+    cards = (hole_cards or "").split()
+    if len(cards) != 2:
+        return None
+    # A bad row is treated as unknown cards rather than failing the query
+    try:
+        first, second = parse_cards("".join(cards))
+    except ValueError:
+        return None
+    return make_combo(first.index, second.index)
+
+
+def _hole_class(hole_cards: Optional[str]) -> Optional[str]:
+    combo = _hole_combo(hole_cards)
+    return hand_class_of(combo) if combo else None
+
+
+def _in_range(hole_cards: Optional[str], range_text: str) -> int:
+    combo = _hole_combo(hole_cards)
+    return int(combo is not None and _parsed_range(range_text).weight(combo) > 0)
+
+
+def _hand_text(value: str) -> str:
+    """Ranks upper case, suits and s/o lower, the way cards.parse_cards reads"""
+    return "".join(ch.lower() if ch.lower() in "cdhso" else ch.upper() for ch in value)
+
+
+def register_functions(conn: sqlite3.Connection):
+    """SQL helpers the compiled queries rely on"""
+    conn.create_function("hand_class", 1, _hole_class, deterministic=True)
+    conn.create_function("in_range", 2, _in_range, deterministic=True)
+
+
+class QueryError(ValueError):
+    pass
+
+
+def tokenize(text: str) -> List[Tuple[str, str]]:
+    tokens: List[Tuple[str, str]] = []
+    position = 0
+    text = text.strip()
+    while position < len(text):
+        match = TOKEN_PATTERN.match(text, position)
+        if not match or match.end() == position:
+            raise QueryError(f"Unexpected input at {text[position:]!r}")
+        position = match.end()
+        lparen, rparen, op, quoted, word = match.groups()
+        if lparen:
+            tokens.append(("(", lparen))
+        elif rparen:
+            tokens.append((")", rparen))
+        elif op:
+            tokens.append(("op", op))
+        elif quoted is not None:
+            tokens.append(("value", quoted))
+        elif word.lower() in KEYWORDS:
+            tokens.append((word.lower(), word))
+        else:
+            tokens.append(("value", word))
+    return tokens
+
+
+class _Compiler:
+    """Recursive-descent parser that emits a SQL condition with parameters"""
+
+    def __init__(self, text: str):
+        self.tokens = tokenize(text)
+        self.index = 0
+        self.params: List = []
+        self.names_player = False
+
+    def compile(self) -> str:
+        if not self.tokens:
+            return "1"
+        sql = self._or()
+        if self.index != len(self.tokens):
+            raise QueryError(f"Unexpected {self.tokens[self.index][1]!r}")
+        return sql
+
+    def _peek(self) -> Optional[str]:
+        return self.tokens[self.index][0] if self.index < len(self.tokens) else None
+
+    def _take(self, kind: str) -> str:
+        if self._peek() != kind:
+            found = self.tokens[self.index][1] if self._peek() else "end of query"
+            raise QueryError(f"Expected {kind}, found {found!r}")
+        self.index += 1
+        return self.tokens[self.index - 1][1]
+
+    def _or(self) -> str:
+        parts = [self._and()]
+        while self._peek() == "or":
+            self.index += 1
+            parts.append(self._and())
+        return parts[0] if len(parts) == 1 else "(" + " OR ".join(parts) + ")"
+
+    def _and(self) -> str:
+        parts = [self._not()]
+        while self._peek() == "and":
+            self.index += 1
+            parts.append(self._not())
+        return parts[0] if len(parts) == 1 else "(" + " AND ".join(parts) + ")"
+
+    def _not(self) -> str:
+        if self._peek() == "not":
+            self.index += 1
+            return f"NOT {self._not()}"
+        if self._peek() == "(":
+            self.index += 1
+            inner = self._or()
+            self._take(")")
+            return f"({inner})"
+        return self._comparison()
+
+    def _comparison(self) -> str:
+        name = self._take("value").lower()
+        op = self._take("op")
+        value = self._take("value")
+        if name == "player":
+            self.names_player = True
+            if value == "*":
+                return "1"
+        if name in TEXT_FIELDS:
+            return self._text(TEXT_FIELDS[name], op, value, upper=name == "position")
+        if name in NUMBER_FIELDS:
+            return self._number(name, op, value)
+        if name == "cards":
+            return self._cards(op, value)
+        if name == "range":
+            if op not in ("=", "!="):
+                raise QueryError("range only supports = and !=")
+            try:
+                _parsed_range(value)
+            except ValueError as exc:
+                raise QueryError(str(exc)) from None
+            self.params.append(value)
+            expected = 1 if op == "=" else 0
+            return f"in_range(hp.hole_cards, ?) = {expected}"
+        if name == "date":
+            return self._compare("substr(h.started_at, 1, ?)", op, value, [len(value)])
+        if name == "showdown":
+            flag = value.lower() in ("1", "true", "yes")
+            if op not in ("=", "!="):
+                raise QueryError("showdown only supports = and !=")
+            return f"h.showdown {op} {int(flag)}"
+        if name == "post":
+            if op not in ("=", "!="):
+                raise QueryError("post only supports = and !=")
+            if value.lower() not in FORCED_ACTIONS:
+                raise QueryError(f"post must be one of {', '.join(FORCED_ACTIONS)}")
+            self.params.append(value.lower())
+            posted = (
+                "EXISTS (SELECT 1 FROM actions a WHERE a.hand_pk = h.id AND a.kind = ?)"
+            )
+            return posted if op == "=" else f"NOT {posted}"
+        if name == "line":
+            return self._line(op, value)
+        raise QueryError(f"Unknown field {name!r}")
+
+    def _cards(self, op: str, value: str) -> str:
+        if op == "~":
+            return self._text("hand_class(hp.hole_cards)", op, value)
+        if op not in ("=", "!="):
+            raise QueryError("cards only supports =, != and ~")
+        hand = _hand_text(value)
+        if not (CLASS_PATTERN.match(hand) or COMBO_PATTERN.match(hand)):
+            raise QueryError(f"cards needs a hand class or two cards, got {value!r}")
+        try:
+            _parsed_range(hand)
+        except ValueError as exc:
+            raise QueryError(str(exc)) from None
+        self.params.append(hand)
+        expected = 1 if op == "=" else 0
+        return f"in_range(hp.hole_cards, ?) = {expected}"
+
+    def _compare(self, column: str, op: str, value, leading: Sequence = ()) -> str:
+        if op not in COMPARE_OPS:
+            raise QueryError(f"Operator {op!r} is not supported here")
+        self.params.extend(leading)
+        self.params.append(value)
+        return f"{column} {op} ?"
+
+    def _text(self, column: str, op: str, value: str, upper: bool = False) -> str:
+        value = value.upper() if upper else value
+        if op == "~":
+            self.params.append(f"%{value}%")
+            return f"{column} LIKE ?"
+        if op not in ("=", "!="):
+            raise QueryError(f"Operator {op!r} is not supported for text")
+        return self._compare(column, op, value)
+
+    def _number(self, name: str, op: str, value: str) -> str:
+        column = NUMBER_FIELDS[name]
+        in_bb = value.lower().endswith("bb") and name != "bb"
+        try:
+            number = float(value[:-2] if in_bb else value)
+        except ValueError:
+            raise QueryError(f"{name} needs a number, got {value!r}") from None
+        condition = self._compare(column, op, number)
+        return f"{condition} * h.big_blind" if in_bb else condition
+
+    def _line(self, op: str, value: str) -> str:
+        actions, _, street = value.lower().rpartition("-")
+        if street not in STREETS or not actions:
+            raise QueryError(f"line needs <actions>-<street>, got {value!r}")
+        placeholders = ", ".join("?" for _ in FORCED_ACTIONS)
+        line = (
+            "(SELECT group_concat(kind, '-') FROM (SELECT a.kind FROM actions a "
+            "WHERE a.hand_pk = h.id AND a.player = hp.name AND a.street = ? "
+            f"AND a.kind NOT IN ({placeholders}) ORDER BY a.seq))"
+        )
+        self.params.extend([street, *FORCED_ACTIONS])
+        if op == "~":
+            # Pad with dashes so the pattern only matches whole actions
+            self.params.append(f"%-{actions}-%")
+            return f"('-' || {line} || '-') LIKE ?"
+        if op not in ("=", "!="):
+            raise QueryError("line only supports =, != and ~")
+        self.params.append(actions)
+        return f"COALESCE({line}, '') {op} ?"
+
+
+def compile_query(text: str) -> Tuple[str, List]:
+    """Translate a query into a SQL condition over `hands h` / `hand_players hp`"""
+    compiler = _Compiler(text)
+    condition = compiler.compile()
+    if not compiler.names_player:
+        condition = f"hp.name = h.hero AND {condition}"
+    return condition, compiler.params
+
+
+def search(
+    db: HandDB, query: str, page: int = 1, per_page: int = DEFAULT_PER_PAGE
+) -> Dict:
+    """One page of (hand, player) matches, newest first"""
+    if page < 1:
+        raise QueryError("page starts at 1")
+    per_page = max(1, min(per_page, MAX_PER_PAGE))
+    condition, params = compile_query(query)
+    register_functions(db.conn)
+    base = f"""
+        FROM hands h JOIN hand_players hp ON hp.hand_pk = h.id
+        WHERE {condition}
+    """
+    total = db.conn.execute(f"SELECT COUNT(*) {base}", params).fetchone()[0]
+    rows = db.conn.execute(
+        f"""
+        SELECT h.site, h.hand_id, h.started_at, h.small_blind, h.big_blind,
+               h.board, h.total_pot, hp.name, hp.position, hp.hole_cards, hp.net
+        {base}
+        ORDER BY h.started_at DESC, h.id DESC, hp.seat
+        LIMIT ? OFFSET ?
+        """,
+        params + [per_page, (page - 1) * per_page],
+    ).fetchall()
+    hands = [
+        {
+            "site": site,
+            "hand_id": hand_id,
+            "started_at": started_at,
+            "stakes": f"{small_blind:g}/{big_blind:g}",
+            "board": board,
+            "pot": pot,
+            "player": name,
+            "position": position,
+            "cards": cards,
+            "net": net,
+        }
+        for (
+            site,
+            hand_id,
+            started_at,
+            small_blind,
+            big_blind,
+            board,
+            pot,
+            name,
+            position,
+            cards,
+            net,
+        ) in rows
+    ]
+    return {
+        "query": query,
+        "total": total,
+        "page": page,
+        "per_page": per_page,
+        "pages": (total + per_page - 1) // per_page,
+        "hands": hands,
+    }
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("query", nargs="?", default="", help="Filter expression")
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--page", type=int, default=1)
+    parser.add_argument("--per-page", type=int, default=DEFAULT_PER_PAGE)
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        try:
+            result = search(db, args.query, args.page, args.per_page)
+        except QueryError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    print(
+        f"{result['total']:,} matches (page {result['page']}/{max(result['pages'], 1)})"
+    )
+    for hand in result["hands"]:
+        print(
+            f"  {hand['started_at'] or '-':<19} {hand['site']:<10}"
+            f" {hand['hand_id']:<22} {hand['stakes']:>9} {hand['player']:<16}"
+            f" {hand['position'] or '-':<5}"
+            f" {hand['cards'] or '--':<6} {hand['board'] or '-':<15} {hand['net']:+g}"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Search the hand database")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,327 @@
+#!/usr/bin/env python3
+"""
+PokerStars-style hand history text from `handhistory.Hand` objects.
+
+`format_hand` writes the header with stakes, the table and seats, forced
+posts, hole cards, every street's actions and board, returned bets, the
+showdown, and the summary with button and blind labels, so hands from any
+source (another site's export, the hand database, or a live table) can be
+imported into trackers that read PokerStars files (PT4, HM3). A hand run more
+than once gets FIRST/SECOND street and show down sections like PokerStars
+prints them. `handhistory.parse_hand` reads the output back into an equal
+`Hand`, apart from the site.
+
+PokerStars hand and tournament ids are numeric: other ids (GGPoker's
+HD1234567, Winamax's 1234567-89-1700000000) are replaced with a number derived
+from the site and id, so the same hand always gets the same id. The model
+keeps neither the tournament buy-in nor the blind level, so tournament headers
+carry only the blinds. Times are written as stored and labelled ET.
+
+Example:
+    python3 handwriter.py --db hands.sqlite 230000000001 > hand.txt
+    python3 pokertools.py history --from HH20240101.txt --output stars.txt
+"""
+
+from __future__ import annotations
+
+import argparse
+import hashlib
+from pathlib import Path
+from typing import Dict, List
+
+from handdb import HandDB, default_db_path
+from handhistory import (
+    BOARD_SIZES,
+    CURRENCY_SYMBOLS,
+    FORCED_ACTIONS,
+    RUN_ORDINALS,
+    STREETS,
+    Hand,
+    parse_file,
+)
+from replay import find_hand
+
+
+GAME_LABELS = {
+    "holdem": "Hold'em",
+    "shortdeck": "6+ Hold'em",
+    "omaha": "Omaha",
+    "omaha8": "Omaha Hi/Lo",
+    "omaha5": "5 Card Omaha",
+}
+SYMBOLS = {currency: symbol for symbol, currency in CURRENCY_SYMBOLS.items()}
+VERBS = {
+    "ante": "posts the ante",
+    "bomb_pot": "posts bomb pot",
+    "small_blind": "posts small blind",
+    "big_blind": "posts big blind",
+    "straddle": "posts straddle",
+    "fold": "folds",
+    "check": "checks",
+    "call": "calls",
+    "bet": "bets",
+}
+RUN_WORDS = {2: "twice", 3: "three times"}
+# Hands are separated by blank lines in PokerStars files
+HAND_SEPARATOR = "\n\n\n"
+
+
+def stars_id(site: str, value: str) -> str:
+    """`value` when it is already numeric, else a stable number for it"""
+    if value.isdigit():
+        return value
+    digest = hashlib.sha1(f"{site}:{value}".encode()).hexdigest()
+    return str(int(digest[:12], 16))
+
+
+class _Writer:
+    def __init__(self, hand: Hand):
+        if hand.game not in GAME_LABELS:
+            raise ValueError(f"No PokerStars name for game {hand.game!r}")
+        self.hand = hand
+        self.symbol = "" if hand.is_tournament else SYMBOLS.get(hand.currency, "")
+        self.lines: List[str] = []
+
+    def amount(self, value: float) -> str:
+        text = f"{value:.2f}"
+        if text.endswith(".00"):
+            text = text[:-3]
+        return f"{self.symbol}{text}"
+
+    def write(self) -> str:
+        self._header()
+        hand = self.hand
+        for action in hand.actions_on("preflop"):
+            if action.kind in FORCED_ACTIONS:
+                self._action(action)
+        self.lines.append("*** HOLE CARDS ***")
+        hero = hand.player(hand.hero) if hand.hero else None
+        if hero and hero.hole_cards:
+            self.lines.append(f"Dealt to {hero.name} [{' '.join(hero.hole_cards)}]")
+        runouts = hand.runouts or [hand.board]
+        shared = _shared_length(runouts)
+        for street in STREETS:
+            size = BOARD_SIZES[street]
+            if size > shared:
+                break
+            if size:
+                self._board(street, runouts[0][:size])
+            for action in hand.actions_on(street):
+                if street != "preflop" or action.kind not in FORCED_ACTIONS:
+                    self._action(action)
+        for name, amount in hand.uncalled.items():
+            refund = self.amount(amount)
+            self.lines.append(f"Uncalled bet ({refund}) returned to {name}")
+        if len(runouts) > 1:
+            for ordinal, board in zip(RUN_ORDINALS, runouts):
+                for street in STREETS[1:]:
+                    if shared < BOARD_SIZES[street] <= len(board):
+                        self._board(street, board[: BOARD_SIZES[street]], ordinal)
+        self._showdown()
+        self._summary()
+        return "\n".join(self.lines) + "\n"
+
+    def _header(self):
+        hand = self.hand
+        game = f"{GAME_LABELS[hand.game]} {hand.limit.title()}"
+        blinds = f"{self.amount(hand.small_blind)}/{self.amount(hand.big_blind)}"
+        hand_id = stars_id(hand.site, hand.hand_id)
+        if hand.is_tournament:
+            tournament = stars_id(hand.site, hand.tournament_id)
+            header = f"PokerStars Hand #{hand_id}: Tournament #{tournament}, {game}"
+            header += f" - ({blinds})"
+        else:
+            code = f" {hand.currency}" if hand.currency else ""
+            header = f"PokerStars Hand #{hand_id}:  {game} ({blinds}{code})"
+        if hand.started_at:
+            header += f" - {hand.started_at} ET"
+        self.lines.append(header)
+        seats = max([hand.max_seats] + [player.seat for player in hand.players])
+        table = hand.table or hand_id
+        self.lines.append(
+            f"Table '{table}' {seats}-max Seat #{hand.button_seat} is the button"
+        )
+        for player in hand.players:
+            stack = self.amount(player.stack)
+            line = f"Seat {player.seat}: {player.name} ({stack} in chips)"
+            if player.sitting_out:
+                line += " is sitting out"
+            self.lines.append(line)
+
+    def _board(self, street: str, board: List[str], ordinal: str = ""):
+        label = f"{ordinal} {street.upper()}".strip()
+        if street == "flop":
+            cards = f"[{' '.join(board)}]"
+        else:
+            cards = f"[{' '.join(board[:-1])}] [{board[-1]}]"
+        self.lines.append(f"*** {label} *** {cards}")
+
+    def _action(self, action):
+        if action.kind == "raise":
+            verb = f"raises {self.amount(action.raise_by)} to"
+            text = f"{verb} {self.amount(action.amount)}"
+        elif action.kind in ("fold", "check"):
+            text = VERBS[action.kind]
+        else:
+            text = f"{VERBS[action.kind]} {self.amount(action.amount)}"
+        if action.all_in:
+            text += " and is all-in"
+        self.lines.append(f"{action.player}: {text}")
+
+    def _showdown(self):
+        hand = self.hand
+        if not hand.showdown:
+            self._collected(hand.collected)
+            return
+        runs = hand.run_collected if len(hand.run_collected) > 1 else [hand.collected]
+        for run, collected in enumerate(runs):
+            ordinal = f"{RUN_ORDINALS[run]} " if len(runs) > 1 else ""
+            self.lines.append(f"*** {ordinal}SHOW DOWN ***")
+            if run == 0:
+                for name in self._shown():
+                    cards = " ".join(hand.player(name).hole_cards)
+                    self.lines.append(f"{name}: shows [{cards}]")
+            self._collected(collected)
+
+    def _collected(self, collected: Dict[str, float]):
+        for name, amount in collected.items():
+            self.lines.append(f"{name} collected {self.amount(amount)} from pot")
+
+    def _shown(self) -> List[str]:
+        """Players whose cards were shown at showdown, in action order"""
+        folded = self._folds()
+        live = {action.player for action in self.hand.actions} - set(folded)
+        # GGPoker prints a show down section even when everyone else folded
+        if not self.hand.showdown or len(live) < 2:
+            return []
+        shown: List[str] = []
+        for action in self.hand.actions:
+            name = action.player
+            if name in live and name not in shown and self.hand.player(name).hole_cards:
+                shown.append(name)
+        return shown
+
+    def _folds(self) -> Dict[str, str]:
+        return {
+            action.player: action.street
+            for action in self.hand.actions
+            if action.kind == "fold"
+        }
+
+    def _summary(self):
+        hand = self.hand
+        self.lines.append("*** SUMMARY ***")
+        total = hand.total_pot or round(sum(hand.contributions().values()), 2)
+        self.lines.append(
+            f"Total pot {self.amount(total)} | Rake {self.amount(hand.rake)}"
+        )
+        if len(hand.runouts) > 1:
+            self.lines.append(f"Hand was run {RUN_WORDS.get(len(hand.runouts), '')}")
+            for ordinal, board in zip(RUN_ORDINALS, hand.runouts):
+                self.lines.append(f"{ordinal} Board [{' '.join(board)}]")
+        elif hand.board:
+            self.lines.append(f"Board [{' '.join(hand.board)}]")
+        folds, shown = self._folds(), self._shown()
+        dead = hand.dead_money()
+        put_in = hand.contributions()
+        for player in hand.players:
+            labels = "".join(f" ({label})" for label in self._labels(player))
+            line = f"Seat {player.seat}: {player.name}{labels}"
+            won = hand.collected.get(player.name)
+            if player.name in folds:
+                street = folds[player.name]
+                if street == "preflop":
+                    line += " folded before Flop"
+                    if put_in[player.name] <= dead[player.name]:
+                        line += " (didn't bet)"
+                else:
+                    line += f" folded on the {street.title()}"
+            elif player.name in shown:
+                line += f" showed [{' '.join(player.hole_cards)}] and "
+                line += f"won ({self.amount(won)})" if won else "lost"
+            elif won:
+                line += f" collected ({self.amount(won)})"
+            elif hand.showdown and any(
+                action.player == player.name for action in hand.actions
+            ):
+                line += " mucked"
+            self.lines.append(line)
+
+    def _labels(self, player) -> List[str]:
+        labels = ["button"] if player.seat == self.hand.button_seat else []
+        for action in self.hand.actions_on("preflop"):
+            if action.player == player.name and action.kind in (
+                "small_blind",
+                "big_blind",
+            ):
+                labels.append(action.kind.replace("_", " "))
+        return labels
+
+
+def _shared_length(runouts: List[List[str]]) -> int:
+    """Board cards every run has in common: the streets dealt before the split"""
+    if len(runouts) == 1:
+        return len(runouts[0])
+    shared = 0
+    for cards in zip(*runouts):
+        if len(set(cards)) > 1:
+            break
+        shared += 1
+    # A run splits on a street boundary
+    return max(size for size in BOARD_SIZES.values() if size <= shared)
+
+
+def format_hand(hand: Hand) -> str:
+    """One hand as PokerStars hand history text"""
+    return _Writer(hand).write()
+
+
+def format_hands(hands: List[Hand]) -> str:
+    text = HAND_SEPARATOR.join(format_hand(hand).rstrip("\n") for hand in hands)
+    return f"{text}\n" if text else ""
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument(
+        "hand_ids", nargs="*", help="Stored hand ids; every stored hand by default"
+    )
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--site", help="Site name when an id is ambiguous")
+    parser.add_argument(
+        "--from",
+        dest="source",
+        type=Path,
+        help="Rewrite a hand history file from any supported site instead",
+    )
+    parser.add_argument("--output", type=Path, help="File to write (default stdout)")
+
+
+def run(args: argparse.Namespace):
+    if args.source is not None:
+        hands = parse_file(args.source)
+    else:
+        with HandDB(args.db) as db:
+            try:
+                hands = [find_hand(db, hand_id, args.site) for hand_id in args.hand_ids]
+            except (KeyError, ValueError) as exc:
+                raise SystemExit(f"error: {exc.args[0]}") from None
+            if not args.hand_ids:
+                hands = list(db.hands())
+    try:
+        text = format_hands(hands)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.output is not None:
+        args.output.write_text(text)
+    else:
+        print(text, end="")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Write PokerStars hand histories")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,159 @@
+"""
+13x13 heatmaps of a range as SVG, PNG, and terminal text.
+
+Each cell of the grid (pairs on the diagonal, suited hands above it, offsuit
+below, as in `pushfold.grid_classes`) is shaded by how much of its hand class
+the range holds: the weights of the combos present over the combos in the
+class, so "AKs" is 1.0, "AKs:0.5" is 0.5, and "AsKs" alone is 0.25. Given
+per-combo frequencies, such as one action's from a `solve --export` strategy
+(`solver_frequencies`), each combo counts its weight times its frequency
+instead, which maps where the range takes that action.
+
+`Heatmap.svg` draws labeled cells with the held share of partial ones,
+`Heatmap.png` uses the chart PNG writer in `pushfold.py`, and `Heatmap.text`
+colors cell backgrounds with 24-bit ANSI escapes or, without color, marks
+partial cells "~AKs" and empty ones ".". `pokertools range show` prints or
+writes them and `GET /api/range/heatmap` serves them.
+
+Example:
+    python3 pokertools.py range show "22+, ATs+, KQo:0.5"
+    python3 pokertools.py range show "QQ+,AK" --format svg --output range.svg
+    python3 pokertools.py range show "TT+,AK,KQ" --solver strategy.json \\
+        --node x --action b50 --format png --output bets.png
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+from html import escape
+from typing import Dict, List, Optional
+
+from cards import parse_cards
+from hand_range import TOTAL_COMBOS, Combo, Range, class_combos, make_combo
+from pushfold import grid_classes, grid_png, shade_color
+
+
+FORMATS = ("text", "svg", "png", "json")
+SVG_CELL = 40
+SVG_TITLE_HEIGHT = 28
+
+
+def _label(name: str, shade: float) -> str:
+    """Uncolored cell: the class when held whole, "~" when partly, "." when not"""
+    if shade >= 1:
+        return name
+    return f"~{name}" if shade > 0 else "."
+
+
+@dataclass
+class Heatmap:
+    notation: str
+    combos: float
+    shades: Dict[str, float]
+
+    @classmethod
+    def of(
+        cls, hand_range: Range, frequencies: Optional[Dict[Combo, float]] = None
+    ) -> "Heatmap":
+        shades = {}
+        total = 0.0
+        for row in grid_classes():
+            for name in row:
+                combos = class_combos(name)
+                held = sum(
+                    hand_range.weight(combo)
+                    * (1.0 if frequencies is None else frequencies.get(combo, 0.0))
+                    for combo in combos
+                )
+                shades[name] = round(held / len(combos), 4)
+                total += held
+        return cls(str(hand_range), round(total, 4), shades)
+
+    @property
+    def percent(self) -> float:
+        return self.combos / TOTAL_COMBOS * 100
+
+    def to_dict(self) -> dict:
+        return {
+            "range": self.notation,
+            "combos": self.combos,
+            "percent": round(self.percent, 2),
+            "grid": [
+                [{"hand": name, "shade": self.shades[name]} for name in row]
+                for row in grid_classes()
+            ],
+        }
+
+    def text(self, color: bool = True) -> str:
+        if not color:
+            return "\n".join(
+                "".join(_label(name, shade).rjust(5) for name, shade in row)
+                for row in self._rows()
+            )
+        lines = []
+        for row in self._rows():
+            cells = []
+            for name, shade in row:
+                red, green, blue = shade_color(shade)
+                cells.append(f"\x1b[48;2;{red};{green};{blue}m\x1b[30m{name:^5}")
+            lines.append("".join(cells) + "\x1b[0m")
+        return "\n".join(lines)
+
+    def svg(self, title: str = "") -> str:
+        size = SVG_CELL * 13
+        top = SVG_TITLE_HEIGHT if title else 0
+        parts = [
+            f'<svg xmlns="http://www.w3.org/2000/svg" width="{size}" '
+            f'height="{size + top}" viewBox="0 0 {size} {size + top}" '
+            'font-family="sans-serif" text-anchor="middle">'
+        ]
+        if title:
+            parts.append(
+                f'<text x="{size // 2}" y="{top - 9}" font-size="15">'
+                f"{escape(title)}</text>"
+            )
+        for row_index, row in enumerate(self._rows()):
+            for column, (name, shade) in enumerate(row):
+                x, y = column * SVG_CELL, top + row_index * SVG_CELL
+                fill = "#{:02x}{:02x}{:02x}".format(*shade_color(shade))
+                parts.append(
+                    f'<rect x="{x}" y="{y}" width="{SVG_CELL}" height="{SVG_CELL}" '
+                    f'fill="{fill}" stroke="#a0a0a0"><title>{name} '
+                    f"{shade:.0%}</title></rect>"
+                )
+                middle = x + SVG_CELL // 2
+                parts.append(
+                    f'<text x="{middle}" y="{y + 18}" font-size="12">{name}</text>'
+                )
+                if 0 < shade < 1:
+                    parts.append(
+                        f'<text x="{middle}" y="{y + 32}" font-size="9">'
+                        f"{shade:.0%}</text>"
+                    )
+        parts.append("</svg>")
+        return "\n".join(parts) + "\n"
+
+    def png(self) -> bytes:
+        return grid_png(self.shades)
+
+    def _rows(self) -> List[List[tuple]]:
+        return [[(name, self.shades[name]) for name in row] for row in grid_classes()]
+
+
+def solver_frequencies(
+    strategy: Dict[str, dict], node: str, action: str
+) -> Dict[Combo, float]:
+    """Per-combo frequency of `action` at `node` in a `solve --export` file"""
+    if node not in strategy:
+        raise ValueError(f"No node {node!r} in the strategy")
+    actions = strategy[node]["actions"]
+    if action not in actions:
+        raise ValueError(
+            f"{action!r} is not an action at node {node!r} ({', '.join(actions)})"
+        )
+    index = actions.index(action)
+    frequencies = {}
+    for label, shares in strategy[node]["combos"].items():
+        first, second = parse_cards(label)
+        frequencies[make_combo(first.index, second.index)] = shares[index]
+    return frequencies
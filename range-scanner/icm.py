@@ -0,0 +1,209 @@
+#!/usr/bin/env python3
+"""
+Independent Chip Model (Malmuth-Harville) tournament equity.
+
+Harville's model says a player finishes first with probability equal to their
+share of the chips, and each later place goes the same way among whoever is
+left. `icm_equities` turns stacks and a payout structure into each player's
+expected prize:
+
+- up to `exact_limit` (10) players, exactly: a walk over every set of players
+  that could fill the places above, so only paid places cost work
+- larger fields, by sampling finishing orders from the same model; the
+  sampling error shrinks with `iterations`
+
+Players with no chips finish after everyone else and split those places.
+`chip_chop` is the naive deal (prize pool by chip share) for comparison.
+
+Example:
+    python3 icm.py --stacks 5000 3000 2000 --payouts 50 30 20
+    python3 pokertools.py icm --stacks 12000 8000 5000 3000 --payouts 500 300 200
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import random
+from dataclasses import dataclass
+from typing import List, Optional, Sequence
+
+
+DEFAULT_EXACT_LIMIT = 10
+DEFAULT_ICM_ITERATIONS = 100_000
+
+
+def _validate(stacks: Sequence[float], payouts: Sequence[float]):
+    if len(stacks) < 2:
+        raise ValueError("ICM needs at least two players")
+    if any(stack < 0 for stack in stacks) or not any(stacks):
+        raise ValueError("Stacks must be non-negative and not all zero")
+    if not payouts or any(payout < 0 for payout in payouts):
+        raise ValueError("Payouts must be a non-empty list of non-negative amounts")
+
+
+def _exact(stacks: Sequence[float], payouts: Sequence[float]) -> List[float]:
+    """Walk the places in order, keyed by the set of players already placed"""
+    count = len(stacks)
+    equities = [0.0] * count
+    # {bitmask of players already placed: probability of that set}
+    layer = {0: 1.0}
+    for prize in payouts[:count]:
+        following = {}
+        for placed, chance in layer.items():
+            left = [idx for idx in range(count) if not placed & (1 << idx)]
+            total = sum(stacks[idx] for idx in left)
+            for idx in left:
+                share = stacks[idx] / total if total else 1 / len(left)
+                if not share:
+                    continue
+                equities[idx] += chance * share * prize
+                key = placed | (1 << idx)
+                following[key] = following.get(key, 0.0) + chance * share
+        layer = following
+    return equities
+
+
+def _sampled(
+    stacks: Sequence[float],
+    payouts: Sequence[float],
+    iterations: int,
+    seed: Optional[int],
+) -> List[float]:
+    rng = random.Random(seed)
+    count = len(stacks)
+    places = min(len(payouts), count)
+    equities = [0.0] * count
+    for _ in range(iterations):
+        left = list(range(count))
+        weights = list(stacks)
+        for place in range(places):
+            total = sum(weights)
+            if total > 0:
+                target = rng.random() * total
+                pick = 0
+                while pick < len(left) - 1 and target >= weights[pick]:
+                    target -= weights[pick]
+                    pick += 1
+                # Never hand a place to a busted player while chips remain
+                while not weights[pick]:
+                    pick -= 1
+            else:
+                pick = rng.randrange(len(left))
+            equities[left[pick]] += payouts[place]
+            del left[pick], weights[pick]
+    return [value / iterations for value in equities]
+
+
+@dataclass
+class IcmResult:
+    stacks: List[float]
+    payouts: List[float]
+    equities: List[float]
+    exact: bool
+    iterations: int = 0
+
+    @property
+    def prize_pool(self) -> float:
+        return sum(self.payouts[: len(self.stacks)])
+
+    @property
+    def chip_chop(self) -> List[float]:
+        total = sum(self.stacks)
+        return [self.prize_pool * stack / total for stack in self.stacks]
+
+    def to_dict(self) -> dict:
+        total = sum(self.stacks)
+        return {
+            "exact": self.exact,
+            "iterations": self.iterations,
+            "prize_pool": round(self.prize_pool, 2),
+            "players": [
+                {
+                    "stack": stack,
+                    "chip_share": round(stack / total, 4),
+                    "equity": round(equity, 2),
+                    "equity_share": round(equity / self.prize_pool, 4)
+                    if self.prize_pool
+                    else 0.0,
+                    "chip_chop": round(chop, 2),
+                }
+                for stack, equity, chop in zip(
+                    self.stacks, self.equities, self.chip_chop
+                )
+            ],
+        }
+
+
+def icm_equities(
+    stacks: Sequence[float],
+    payouts: Sequence[float],
+    exact_limit: int = DEFAULT_EXACT_LIMIT,
+    iterations: int = DEFAULT_ICM_ITERATIONS,
+    seed: Optional[int] = None,
+) -> IcmResult:
+    """Expected prize per player, in payout units"""
+    _validate(stacks, payouts)
+    stacks = [float(stack) for stack in stacks]
+    payouts = [float(payout) for payout in payouts]
+    if len(stacks) <= exact_limit:
+        return IcmResult(stacks, payouts, _exact(stacks, payouts), exact=True)
+    if iterations <= 0:
+        raise ValueError("iterations must be positive")
+    equities = _sampled(stacks, payouts, iterations, seed)
+    return IcmResult(stacks, payouts, equities, exact=False, iterations=iterations)
+
+
+def format_result(result: IcmResult) -> str:
+    method = "exact" if result.exact else f"{result.iterations:,} samples"
+    lines = [
+        f"Prize pool {result.prize_pool:,.2f} ({method})",
+        f"{'Seat':>4} {'Stack':>10} {'Chips %':>8} {'ICM $':>10} {'ICM %':>7} "
+        f"{'Chip chop':>10}",
+    ]
+    for seat, player in enumerate(result.to_dict()["players"], start=1):
+        lines.append(
+            f"{seat:>4} {player['stack']:>10,.0f} {player['chip_share']:>8.2%} "
+            f"{player['equity']:>10,.2f} {player['equity_share']:>7.2%} "
+            f"{player['chip_chop']:>10,.2f}"
+        )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--stacks", type=float, nargs="+", required=True)
+    parser.add_argument(
+        "--payouts", type=float, nargs="+", required=True, help="1st, 2nd, ..."
+    )
+    parser.add_argument(
+        "--exact-limit",
+        type=int,
+        default=DEFAULT_EXACT_LIMIT,
+        help="Largest field computed exactly; bigger ones are sampled",
+    )
+    parser.add_argument("--iterations", type=int, default=DEFAULT_ICM_ITERATIONS)
+    parser.add_argument("--seed", type=int)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        result = icm_equities(
+            args.stacks, args.payouts, args.exact_limit, args.iterations, args.seed
+        )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(result.to_dict(), indent=2))
+    else:
+        print(format_result(result))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="ICM (Malmuth-Harville) equity")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,355 @@
+"""
+Background job queue for long equity and solver runs, kept in SQLite.
+
+`JobQueue` takes jobs of the kinds it has a `JobKind` for, checks their
+parameters when they are submitted (so a bad request fails at once, not in a
+worker), and runs them oldest first on `workers` threads. Each job moves from
+queued to running to done, failed, or cancelled; its parameters, latest
+progress, result or error, and checkpoint are all rows in the jobs table, so
+with a path the queue survives a restart: jobs that were running go back in
+the queue and pick up from their last checkpoint (a solver's regrets), and
+jobs without one start over. Without a path the table is in memory.
+
+A runner gets the checked parameters and a `JobControl`, through which it
+reports progress (optionally with a new checkpoint) and learns of
+cancellation: `report` raises `JobCancelled` once the job has been cancelled,
+and `cancelled` can be polled as a stop condition. Every change bumps the
+job's version, which the API's event stream uses to send only what is new.
+Only the newest MAX_FINISHED_JOBS finished jobs are kept.
+
+A job may record its `owner` (the API key that submitted it), and only that
+owner can cancel it when `cancel` is given one. `submit` raises `QueueFull`
+once MAX_UNFINISHED_JOBS jobs are queued or running, or
+MAX_UNFINISHED_PER_OWNER of them belong to the submitting owner.
+
+Example:
+    queue = JobQueue({"solver": JobKind(check_spot, solve)}, Path("jobs.sqlite"))
+    job = queue.submit("solver", {"board": "Ks7d4c2h2s", ...})
+    queue.get(job.id).status      # "queued", "running", then "done"
+"""
+
+from __future__ import annotations
+
+import json
+import sqlite3
+import threading
+import uuid
+from dataclasses import asdict, dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Callable, Dict, List, Optional
+
+
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+FINISHED = ("done", "failed", "cancelled")
+STATUSES = ("queued", "running") + FINISHED
+MAX_FINISHED_JOBS = 200
+MAX_UNFINISHED_JOBS = 100
+MAX_UNFINISHED_PER_OWNER = 10
+DEFAULT_LIST_LIMIT = 50
+COLUMNS = (
+    "id, kind, status, params, created_at, started_at, finished_at, "
+    "progress, result, error, version, checkpoint, owner"
+)
+
+
+class JobCancelled(Exception):
+    """Raised into a runner that reports progress after a cancel"""
+
+
+class QueueFull(Exception):
+    """Too many unfinished jobs to take another"""
+
+
+@dataclass
+class JobKind:
+    # params -> the params to store; raises ValueError
+    check: Callable[[dict], dict]
+    # (params, control) -> the result
+    run: Callable[[dict, "JobControl"], dict]
+
+
+@dataclass
+class Job:
+    id: str
+    kind: str
+    status: str
+    params: dict
+    created_at: str
+    started_at: Optional[str] = None
+    finished_at: Optional[str] = None
+    progress: Optional[dict] = None
+    result: Optional[dict] = None
+    error: Optional[str] = None
+    version: int = 0
+    checkpoint: Optional[str] = None
+    owner: Optional[str] = None
+
+    @property
+    def finished(self) -> bool:
+        return self.status in FINISHED
+
+    def to_dict(self, result: bool = True) -> dict:
+        data = asdict(self)
+        data["resumable"] = data.pop("checkpoint") is not None
+        if not result:
+            del data["result"]
+        return data
+
+    @classmethod
+    def from_row(cls, row) -> "Job":
+        values = list(row)
+        for index in (3, 7, 8):
+            values[index] = json.loads(values[index]) if values[index] else None
+        return cls(*values)
+
+
+class JobControl:
+    """A running job as its runner sees it"""
+
+    def __init__(self, queue: "JobQueue", job: Job):
+        self.queue = queue
+        self.job = job
+
+    @property
+    def checkpoint(self) -> Optional[str]:
+        return self.job.checkpoint
+
+    def cancelled(self) -> bool:
+        return self.queue.cancel_requested(self.job.id)
+
+    def report(self, progress: dict, checkpoint: Optional[str] = None):
+        if self.cancelled():
+            raise JobCancelled(self.job.id)
+        self.queue._update(self.job.id, progress=progress, checkpoint=checkpoint)
+        if checkpoint is not None:
+            self.job.checkpoint = checkpoint
+
+
+def _now() -> str:
+    return datetime.now().strftime(STORED_FORMAT)
+
+
+class JobQueue:
+    """Persistent queue of background jobs; thread-safe"""
+
+    def __init__(
+        self,
+        kinds: Dict[str, JobKind],
+        path: Optional[Path] = None,
+        workers: int = 1,
+    ):
+        if workers < 0:
+            raise ValueError("workers must not be negative")
+        self.kinds = kinds
+        self.path = path
+        self.lock = threading.Lock()
+        self.wakeup = threading.Condition(self.lock)
+        self.cancelling: set = set()
+        self.closed = False
+        target = Path(path).as_posix() if path is not None else ":memory:"
+        self.conn = sqlite3.connect(target, check_same_thread=False)
+        self.conn.execute(
+            "CREATE TABLE IF NOT EXISTS jobs ("
+            "id TEXT PRIMARY KEY, kind TEXT NOT NULL, status TEXT NOT NULL, "
+            "params TEXT NOT NULL, created_at TEXT NOT NULL, started_at TEXT, "
+            "finished_at TEXT, progress TEXT, result TEXT, error TEXT, "
+            "version INTEGER NOT NULL DEFAULT 0, checkpoint TEXT, owner TEXT)"
+        )
+        columns = [row[1] for row in self.conn.execute("PRAGMA table_info(jobs)")]
+        if "owner" not in columns:
+            # Queues written before jobs had owners
+            self.conn.execute("ALTER TABLE jobs ADD COLUMN owner TEXT")
+        # Jobs cut off by a restart run again, from their checkpoint if any
+        self.conn.execute(
+            "UPDATE jobs SET status = 'queued', version = version + 1 "
+            "WHERE status = 'running'"
+        )
+        self.conn.commit()
+        self.threads = [
+            threading.Thread(target=self._work, daemon=True) for _ in range(workers)
+        ]
+        for thread in self.threads:
+            thread.start()
+
+    def submit(self, kind: str, params: dict, owner: Optional[str] = None) -> Job:
+        if kind not in self.kinds:
+            raise ValueError(f"kind must be one of {', '.join(sorted(self.kinds))}")
+        if not isinstance(params, dict):
+            raise ValueError("Job parameters must be a JSON object")
+        checked = self.kinds[kind].check(params)
+        job = Job(uuid.uuid4().hex[:12], kind, "queued", checked, _now())
+        job.owner = owner
+        with self.wakeup:
+            total, owned = self.conn.execute(
+                "SELECT COUNT(*), COALESCE(SUM(owner IS ? AND owner IS NOT NULL), 0) "
+                "FROM jobs WHERE status IN ('queued', 'running')",
+                (owner,),
+            ).fetchone()
+            if total >= MAX_UNFINISHED_JOBS:
+                raise QueueFull(f"{total} jobs are already waiting or running")
+            if owned >= MAX_UNFINISHED_PER_OWNER:
+                raise QueueFull(f"This key already has {owned} unfinished jobs")
+            self.conn.execute(
+                f"INSERT INTO jobs ({COLUMNS}) VALUES ({', '.join('?' * 13)})",
+                (job.id, kind, job.status, json.dumps(job.params), job.created_at)
+                + (None,) * 5
+                + (0, None, owner),
+            )
+            self.conn.commit()
+            self.wakeup.notify()
+        return job
+
+    def get(self, job_id: str) -> Job:
+        with self.lock:
+            row = self.conn.execute(
+                f"SELECT {COLUMNS} FROM jobs WHERE id = ?", (job_id,)
+            ).fetchone()
+        if row is None:
+            raise KeyError(f"No job {job_id!r}")
+        return Job.from_row(row)
+
+    def jobs(
+        self,
+        status: Optional[str] = None,
+        kind: Optional[str] = None,
+        limit: int = DEFAULT_LIST_LIMIT,
+    ) -> List[Job]:
+        """Newest first"""
+        if status is not None and status not in STATUSES:
+            raise ValueError(f"status must be one of {', '.join(STATUSES)}")
+        clauses, params = [], []
+        for column, value in (("status", status), ("kind", kind)):
+            if value is not None:
+                clauses.append(f"{column} = ?")
+                params.append(value)
+        where = f"WHERE {' AND '.join(clauses)} " if clauses else ""
+        with self.lock:
+            rows = self.conn.execute(
+                f"SELECT {COLUMNS} FROM jobs {where}"
+                "ORDER BY created_at DESC, rowid DESC LIMIT ?",
+                params + [limit],
+            ).fetchall()
+        return [Job.from_row(row) for row in rows]
+
+    def cancel(self, job_id: str, owner: Optional[str] = None) -> Job:
+        """Cancel a job; given an owner, only one of that owner's jobs"""
+        job = self.get(job_id)
+        if owner is not None and job.owner != owner:
+            raise PermissionError(f"Job {job_id} belongs to another key")
+        if job.finished:
+            raise ValueError(f"Job {job_id} is already {job.status}")
+        with self.lock:
+            self.cancelling.add(job_id)
+        if job.status == "queued":
+            self._finish(job_id, "cancelled", queued=True)
+        return self.get(job_id)
+
+    def cancel_requested(self, job_id: str) -> bool:
+        with self.lock:
+            return job_id in self.cancelling
+
+    def run_next(self, block: bool = False) -> Optional[Job]:
+        """Run the oldest queued job on this thread; None when there is none"""
+        job = self._claim(block)
+        if job is None:
+            return None
+        control = JobControl(self, job)
+        try:
+            result = self.kinds[job.kind].run(job.params, control)
+        except JobCancelled:
+            self._finish(job.id, "cancelled")
+        except Exception as exc:  # pylint: disable=broad-except
+            self._finish(job.id, "failed", error=str(exc) or type(exc).__name__)
+        else:
+            status = "cancelled" if control.cancelled() else "done"
+            self._finish(job.id, status, result=result)
+        return self.get(job.id)
+
+    def close(self):
+        with self.wakeup:
+            self.closed = True
+            self.wakeup.notify_all()
+        for thread in self.threads:
+            thread.join(timeout=0.1)
+        with self.lock:
+            self.conn.close()
+
+    def _work(self):
+        while True:
+            try:
+                if self.run_next(block=True) is None:
+                    return
+            except sqlite3.ProgrammingError:
+                # Closed under a running job; it resumes on the next start
+                return
+
+    def _claim(self, block: bool) -> Optional[Job]:
+        with self.wakeup:
+            while not self.closed:
+                row = self.conn.execute(
+                    f"SELECT {COLUMNS} FROM jobs WHERE status = 'queued' "
+                    "ORDER BY created_at, rowid LIMIT 1"
+                ).fetchone()
+                if row is not None:
+                    job = Job.from_row(row)
+                    job.status, job.started_at = "running", _now()
+                    self.conn.execute(
+                        "UPDATE jobs SET status = 'running', started_at = ?, "
+                        "version = version + 1 WHERE id = ?",
+                        (job.started_at, job.id),
+                    )
+                    self.conn.commit()
+                    return job
+                if not block:
+                    return None
+                self.wakeup.wait()
+            return None
+
+    def _update(self, job_id: str, **values):
+        columns = {
+            name: json.dumps(value) if name == "progress" else value
+            for name, value in values.items()
+            if value is not None
+        }
+        if not columns:
+            return
+        assignments = ", ".join(f"{name} = ?" for name in columns)
+        with self.lock:
+            self.conn.execute(
+                f"UPDATE jobs SET {assignments}, version = version + 1 WHERE id = ?",
+                list(columns.values()) + [job_id],
+            )
+            self.conn.commit()
+
+    def _finish(
+        self,
+        job_id: str,
+        status: str,
+        result: Optional[dict] = None,
+        error: Optional[str] = None,
+        queued: bool = False,
+    ):
+        with self.lock:
+            finished = self.conn.execute(
+                "UPDATE jobs SET status = ?, finished_at = ?, result = ?, error = ?, "
+                "checkpoint = NULL, version = version + 1 WHERE id = ? AND status = ?",
+                (
+                    status,
+                    _now(),
+                    json.dumps(result) if result is not None else None,
+                    error,
+                    job_id,
+                    "queued" if queued else "running",
+                ),
+            )
+            if finished.rowcount:
+                self.cancelling.discard(job_id)
+            # Forget the oldest finished jobs
+            self.conn.execute(
+                "DELETE FROM jobs WHERE id IN (SELECT id FROM jobs WHERE status IN "
+                "('done', 'failed', 'cancelled') ORDER BY finished_at DESC, rowid DESC "
+                "LIMIT -1 OFFSET ?)",
+                (MAX_FINISHED_JOBS,),
+            )
+            self.conn.commit()
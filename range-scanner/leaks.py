@@ -0,0 +1,260 @@
+#!/usr/bin/env python3
+"""
+Leak detection: compare a player's stats with baseline ranges.
+
+Each `Baseline` names a `PlayerStats` counter and its sample (e.g. folds to a
+3-bet out of times facing one), optionally restricted to some positions, and
+the range a solid regular sits in. A stat is only flagged when the sample is
+big enough and the Wilson score interval of the observed frequency lies
+entirely outside that range, so a 3/4 fold-to-3-bet is not called a leak.
+
+Baselines can be overridden or extended with a JSON file keyed by name:
+
+    {"cbet": {"low": 55}, "sb_limp": {"count": "limps", "chances": "open_chances",
+     "positions": ["SB"], "low": 0, "high": 20, "description": "Limping the SB"}}
+
+Example:
+    python3 pokertools.py leaks --db hands.sqlite --player Hero
+    python3 leaks.py --db hands.sqlite --min-hands 500 --baselines club.json --json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import math
+from dataclasses import asdict, dataclass, fields, replace
+from pathlib import Path
+from typing import Dict, List, Optional, Sequence, Tuple
+
+from handdb import HandDB, default_db_path
+from stats import ALL_GROUP, PlayerStats, load_stats
+
+
+EARLY_POSITIONS = ("UTG", "UTG+1", "UTG+2")
+# Two-sided 95%
+DEFAULT_Z = 1.96
+DEFAULT_MIN_SAMPLE = 30
+
+
+@dataclass
+class Baseline:
+    """Acceptable range (percent) for `count` out of `chances`"""
+
+    name: str
+    description: str
+    count: str
+    chances: str
+    low: float
+    high: float
+    positions: Optional[List[str]] = None
+    min_sample: int = DEFAULT_MIN_SAMPLE
+
+
+DEFAULT_BASELINES = [
+    Baseline("vpip", "VPIP", "vpip", "hands", 18, 32, min_sample=100),
+    Baseline("pfr", "Preflop raise", "pfr", "hands", 14, 26, min_sample=100),
+    Baseline("three_bet", "3-bet", "three_bets", "three_bet_chances", 5, 12),
+    Baseline(
+        "fold_to_three_bet",
+        "Fold to 3-bet",
+        "folds_to_three_bet",
+        "fold_to_three_bet_chances",
+        35,
+        60,
+    ),
+    Baseline(
+        "ep_limp",
+        "Limping from early position",
+        "limps",
+        "open_chances",
+        0,
+        3,
+        positions=list(EARLY_POSITIONS),
+    ),
+    Baseline("cbet", "Flop c-bet", "cbets", "cbet_chances", 50, 80),
+    Baseline("wtsd", "Went to showdown", "showdowns", "saw_flop", 24, 34),
+]
+
+
+def load_baselines(path: Optional[Path] = None) -> List[Baseline]:
+    """Defaults, with entries from a JSON file replacing or adding by name"""
+    baselines = {baseline.name: baseline for baseline in DEFAULT_BASELINES}
+    if path is None:
+        return list(baselines.values())
+    counters = {item.name for item in fields(PlayerStats)}
+    for name, spec in json.loads(Path(path).read_text()).items():
+        if name in baselines:
+            baseline = replace(baselines[name], **spec)
+        else:
+            baseline = Baseline(name=name, **{"description": name, **spec})
+        for counter in (baseline.count, baseline.chances):
+            if counter not in counters:
+                raise ValueError(f"{name}: unknown stat {counter!r}")
+        if baseline.low > baseline.high:
+            raise ValueError(f"{name}: low is above high")
+        baselines[name] = baseline
+    return list(baselines.values())
+
+
+def wilson_interval(
+    count: int, sample: int, z: float = DEFAULT_Z
+) -> Tuple[float, float]:
+    """Score interval for a binomial proportion, in percent"""
+    if not sample:
+        return 0.0, 100.0
+    share = count / sample
+    denominator = 1 + z**2 / sample
+    centre = (share + z**2 / (2 * sample)) / denominator
+    margin = z * math.sqrt(share * (1 - share) / sample + z**2 / (4 * sample**2))
+    margin /= denominator
+    return max(0.0, centre - margin) * 100, min(1.0, centre + margin) * 100
+
+
+@dataclass
+class Leak:
+    player: str
+    name: str
+    description: str
+    direction: str
+    observed: float
+    count: int
+    sample: int
+    low: float
+    high: float
+    interval: Tuple[float, float]
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        data["observed"] = round(self.observed, 1)
+        data["interval"] = [round(bound, 1) for bound in self.interval]
+        return data
+
+
+def _counters(groups: Dict[str, PlayerStats], baseline: Baseline) -> PlayerStats:
+    if not baseline.positions:
+        return groups[ALL_GROUP]
+    total = PlayerStats()
+    for position in baseline.positions:
+        if position in groups:
+            total += groups[position]
+    return total
+
+
+def find_leaks(
+    table: Dict[str, Dict[str, PlayerStats]],
+    baselines: Sequence[Baseline] = DEFAULT_BASELINES,
+    z: float = DEFAULT_Z,
+    min_hands: int = 0,
+) -> List[Leak]:
+    """Significant deviations per player; `table` must be broken down by position"""
+    leaks = []
+    for player, groups in table.items():
+        if groups[ALL_GROUP].hands < min_hands:
+            continue
+        for baseline in baselines:
+            counters = _counters(groups, baseline)
+            count = int(getattr(counters, baseline.count))
+            sample = int(getattr(counters, baseline.chances))
+            if sample < max(baseline.min_sample, 1):
+                continue
+            low, high = wilson_interval(count, sample, z)
+            if low > baseline.high:
+                direction = "high"
+            elif high < baseline.low:
+                direction = "low"
+            else:
+                continue
+            leaks.append(
+                Leak(
+                    player=player,
+                    name=baseline.name,
+                    description=baseline.description,
+                    direction=direction,
+                    observed=count / sample * 100,
+                    count=count,
+                    sample=sample,
+                    low=baseline.low,
+                    high=baseline.high,
+                    interval=(low, high),
+                )
+            )
+    return leaks
+
+
+def leak_report(
+    db: HandDB,
+    players: Optional[Sequence[str]] = None,
+    baselines: Sequence[Baseline] = DEFAULT_BASELINES,
+    z: float = DEFAULT_Z,
+    min_hands: int = 0,
+) -> dict:
+    """JSON-ready report: every checked baseline and the leaks found"""
+    table = load_stats(db, players, by="position")
+    return {
+        "confidence_z": z,
+        "baselines": [asdict(baseline) for baseline in baselines],
+        "hands": {player: groups[ALL_GROUP].hands for player, groups in table.items()},
+        "leaks": [
+            leak.to_dict() for leak in find_leaks(table, baselines, z, min_hands)
+        ],
+    }
+
+
+def format_report(report: dict) -> str:
+    by_player: Dict[str, List[dict]] = {}
+    for leak in report["leaks"]:
+        by_player.setdefault(leak["player"], []).append(leak)
+    players = len(report["hands"])
+    lines = [f"Leak report ({players} players, z={report['confidence_z']})"]
+    for player, leaks in sorted(by_player.items()):
+        lines.append(f"\n{player} ({report['hands'][player]} hands)")
+        for leak in leaks:
+            verb = "too high" if leak["direction"] == "high" else "too low"
+            low, high = leak["interval"]
+            lines.append(
+                f"  {leak['description']} {verb}: {leak['observed']:.1f}% "
+                f"({leak['count']}/{leak['sample']}, likely {low:.1f}-{high:.1f}%) "
+                f"vs {leak['low']:g}-{leak['high']:g}%"
+            )
+    if not by_player:
+        lines.append("No significant leaks.")
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument(
+        "--player", action="append", help="Limit to this player (repeatable)"
+    )
+    parser.add_argument("--baselines", type=Path, help="JSON baseline overrides")
+    parser.add_argument(
+        "--z", type=float, default=DEFAULT_Z, help="Confidence (z-score) to flag at"
+    )
+    parser.add_argument(
+        "--min-hands", type=int, default=0, help="Skip players with fewer hands"
+    )
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        baselines = load_baselines(args.baselines)
+    except (TypeError, ValueError) as exc:
+        raise SystemExit(f"error: {exc}") from None
+    with HandDB(args.db) as db:
+        report = leak_report(db, args.player, baselines, args.z, args.min_hands)
+    if args.json:
+        print(json.dumps(report, indent=2))
+    else:
+        print(format_report(report))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Flag statistically significant leaks")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
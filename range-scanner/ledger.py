@@ -0,0 +1,435 @@
+#!/usr/bin/env python3
+"""
+Home game ledger: buy-ins, cash-outs, and settling up.
+
+Each cash game night is a `LedgerGame` with any number of buy-in and cash-out
+entries per player, stored in the hand database (migration 5 in `handdb.py`)
+so the history of every game stays around. The books balance when the money
+cashed out matches the money bought in; only then can a game be settled.
+
+`settle_up` turns the per-player nets into transfers. A group of players whose
+nets add up to zero can settle among themselves with one transfer fewer than
+its size, so the fewest transfers come from splitting the table into as many
+zero-sum groups as possible. Up to `MAX_EXACT_PLAYERS` players that split is
+found exactly over every subset; bigger games fall back to paying the largest
+debt to the largest creditor, which never needs more than one transfer per
+player.
+
+A game is played in one currency (`--currency`, migration 6). Lifetime player
+results over games in different currencies convert each game's nets at the
+exchange rate of the night it was played (`money.py`).
+
+Example:
+    python3 pokertools.py ledger new --name "Friday 1/2" --currency EUR
+    python3 pokertools.py ledger buy-in 1 Alice 200
+    python3 pokertools.py ledger cash-out 1 Alice 340
+    python3 pokertools.py ledger settle 1
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional
+
+from bankroll import parse_time
+from handdb import HandDB, default_db_path
+from money import ExchangeRates, normalize_currency, output_currency
+
+
+KINDS = ("buy_in", "cash_out")
+MAX_EXACT_PLAYERS = 12
+
+
+@dataclass
+class Transfer:
+    payer: str
+    payee: str
+    amount: float
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+def _zero_sum_groups(cents: List[int]) -> List[List[int]]:
+    """Split indexes into the most groups that each add up to zero"""
+    count = len(cents)
+    full = (1 << count) - 1
+    totals = [0] * (full + 1)
+    best = [0] * (full + 1)
+    last = [0] * (full + 1)
+    for mask in range(1, full + 1):
+        low = (mask & -mask).bit_length() - 1
+        totals[mask] = totals[mask & (mask - 1)] + cents[low]
+        for idx in range(count):
+            if mask & (1 << idx) and best[mask ^ (1 << idx)] >= best[mask]:
+                best[mask], last[mask] = best[mask ^ (1 << idx)], idx
+        best[mask] += totals[mask] == 0
+    # Replay the additions; every time the running total is zero a group closes
+    order = []
+    mask = full
+    while mask:
+        order.append(last[mask])
+        mask ^= 1 << last[mask]
+    groups, group, running = [], [], 0
+    for idx in reversed(order):
+        group.append(idx)
+        running += cents[idx]
+        if not running:
+            groups.append(group)
+            group = []
+    return groups
+
+
+def _greedy(nets: Dict[str, int]) -> List[Transfer]:
+    debtors = sorted(
+        ([name, -net] for name, net in nets.items() if net < 0),
+        key=lambda item: (-item[1], item[0]),
+    )
+    creditors = sorted(
+        ([name, net] for name, net in nets.items() if net > 0),
+        key=lambda item: (-item[1], item[0]),
+    )
+    transfers = []
+    while debtors and creditors:
+        debtor, creditor = debtors[0], creditors[0]
+        amount = min(debtor[1], creditor[1])
+        transfers.append(Transfer(debtor[0], creditor[0], amount / 100))
+        debtor[1] -= amount
+        creditor[1] -= amount
+        if not debtor[1]:
+            debtors.pop(0)
+        if not creditor[1]:
+            creditors.pop(0)
+        debtors.sort(key=lambda item: (-item[1], item[0]))
+        creditors.sort(key=lambda item: (-item[1], item[0]))
+    return transfers
+
+
+def settle_up(nets: Dict[str, float]) -> List[Transfer]:
+    """Fewest transfers that zero every net (positive nets are owed money)"""
+    cents = {name: round(net * 100) for name, net in nets.items() if round(net * 100)}
+    if sum(cents.values()):
+        raise ValueError(f"Nets are off by {sum(cents.values()) / 100:.2f}")
+    names = sorted(cents)
+    if len(names) > MAX_EXACT_PLAYERS:
+        return _greedy(cents)
+    transfers = []
+    for group in _zero_sum_groups([cents[name] for name in names]):
+        transfers += _greedy({names[idx]: cents[names[idx]] for idx in group})
+    return transfers
+
+
+@dataclass
+class PlayerLine:
+    bought_in: float = 0.0
+    cashed_out: float = 0.0
+
+    @property
+    def net(self) -> float:
+        return round(self.cashed_out - self.bought_in, 2)
+
+    def to_dict(self) -> dict:
+        return {
+            "bought_in": round(self.bought_in, 2),
+            "cashed_out": round(self.cashed_out, 2),
+            "net": self.net,
+        }
+
+
+@dataclass
+class LedgerGame:
+    id: int
+    name: str
+    played_at: str
+    settled_at: Optional[str] = None
+    currency: str = ""
+    players: Dict[str, PlayerLine] = field(default_factory=dict)
+
+    @property
+    def bought_in(self) -> float:
+        return round(sum(line.bought_in for line in self.players.values()), 2)
+
+    @property
+    def cashed_out(self) -> float:
+        return round(sum(line.cashed_out for line in self.players.values()), 2)
+
+    @property
+    def discrepancy(self) -> float:
+        """Cashed out minus bought in; zero when the books balance"""
+        return round(self.cashed_out - self.bought_in, 2)
+
+    @property
+    def balanced(self) -> bool:
+        return bool(self.players) and not self.discrepancy
+
+    def transfers(self) -> List[Transfer]:
+        if not self.balanced:
+            raise ValueError(self.problem())
+        return settle_up({name: line.net for name, line in self.players.items()})
+
+    def problem(self) -> str:
+        if not self.players:
+            return f"Game {self.id} has no entries"
+        side = "more" if self.discrepancy > 0 else "less"
+        return (
+            f"Books are off by {abs(self.discrepancy):.2f}: "
+            f"{side} was cashed out than bought in"
+        )
+
+    def to_dict(self) -> dict:
+        return {
+            "id": self.id,
+            "name": self.name,
+            "played_at": self.played_at,
+            "settled_at": self.settled_at,
+            "currency": self.currency,
+            "bought_in": self.bought_in,
+            "cashed_out": self.cashed_out,
+            "discrepancy": self.discrepancy,
+            "balanced": self.balanced,
+            "players": {name: line.to_dict() for name, line in self.players.items()},
+            "transfers": [transfer.to_dict() for transfer in self.transfers()]
+            if self.balanced
+            else None,
+        }
+
+
+class Ledger:
+    """Home games on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+        self.rates = ExchangeRates(db)
+
+    def create_game(
+        self, name: str = "", played_at: Optional[str] = None, currency: str = ""
+    ) -> int:
+        at = parse_time(played_at or datetime.now().isoformat(" "))
+        with self.conn:
+            cursor = self.conn.execute(
+                "INSERT INTO ledger_games (name, played_at, currency) VALUES (?, ?, ?)",
+                (name.strip(), at, normalize_currency(currency)),
+            )
+        return cursor.lastrowid
+
+    def add_entry(
+        self,
+        game_id: int,
+        player: str,
+        kind: str,
+        amount: float,
+        at: Optional[str] = None,
+    ) -> LedgerGame:
+        """Record a buy-in (rebuys are more of them) or a cash-out"""
+        game = self.game(game_id)
+        if game.settled_at:
+            raise ValueError(f"Game {game_id} was settled at {game.settled_at}")
+        player = str(player).strip()
+        if not player:
+            raise ValueError("Player name is required")
+        if kind not in KINDS:
+            raise ValueError(f"Kind must be one of {', '.join(KINDS)}")
+        if float(amount) <= 0:
+            raise ValueError("Amount must be positive")
+        with self.conn:
+            self.conn.execute(
+                "INSERT INTO ledger_entries (game_id, player, kind, amount, at) "
+                "VALUES (?, ?, ?, ?, ?)",
+                (
+                    game_id,
+                    player,
+                    kind,
+                    float(amount),
+                    parse_time(at or datetime.now().isoformat(" ")),
+                ),
+            )
+        return self.game(game_id)
+
+    def game(self, game_id: int) -> LedgerGame:
+        row = self.conn.execute(
+            "SELECT id, name, played_at, settled_at, currency FROM ledger_games "
+            "WHERE id = ?",
+            (game_id,),
+        ).fetchone()
+        if row is None:
+            raise KeyError(f"No game {game_id}")
+        game = LedgerGame(*row)
+        for player, kind, amount in self.conn.execute(
+            "SELECT player, kind, SUM(amount) FROM ledger_entries WHERE game_id = ? "
+            "GROUP BY player, kind ORDER BY player",
+            (game_id,),
+        ):
+            line = game.players.setdefault(player, PlayerLine())
+            if kind == "buy_in":
+                line.bought_in = amount
+            else:
+                line.cashed_out = amount
+        return game
+
+    def games(self) -> List[LedgerGame]:
+        ids = self.conn.execute(
+            "SELECT id FROM ledger_games ORDER BY played_at, id"
+        ).fetchall()
+        return [self.game(game_id) for (game_id,) in ids]
+
+    def settle(self, game_id: int) -> LedgerGame:
+        """Check the books and mark the game settled; no entries after this"""
+        game = self.game(game_id)
+        if not game.balanced:
+            raise ValueError(game.problem())
+        if not game.settled_at:
+            with self.conn:
+                self.conn.execute(
+                    "UPDATE ledger_games SET settled_at = ? WHERE id = ?",
+                    (parse_time(datetime.now().isoformat(" ")), game_id),
+                )
+        return self.game(game_id)
+
+    def delete_game(self, game_id: int) -> bool:
+        with self.conn:
+            cursor = self.conn.execute(
+                "DELETE FROM ledger_games WHERE id = ?", (game_id,)
+            )
+        return bool(cursor.rowcount)
+
+    def players(self, currency: Optional[str] = None) -> List[Dict]:
+        """Lifetime results per player over every game, best first
+
+        Games in other currencies than `currency` are converted at the rate of
+        the day they were played; mixed currencies need `currency` set.
+        """
+        rows = self.conn.execute(
+            """
+            SELECT e.player, g.played_at, g.currency,
+                   SUM(CASE WHEN e.kind = 'buy_in' THEN e.amount ELSE 0 END),
+                   SUM(CASE WHEN e.kind = 'cash_out' THEN e.amount ELSE 0 END)
+            FROM ledger_entries e JOIN ledger_games g ON g.id = e.game_id
+            GROUP BY e.player, g.id
+            """
+        ).fetchall()
+        target = output_currency((row[2] for row in rows), currency)
+        lines: Dict[str, PlayerLine] = {}
+        games: Dict[str, int] = {}
+        for player, played_at, original, bought_in, cashed_out in rows:
+            rate = self.rates.rate(original, target, played_at)
+            line = lines.setdefault(player, PlayerLine())
+            line.bought_in += bought_in * rate
+            line.cashed_out += cashed_out * rate
+            games[player] = games.get(player, 0) + 1
+        totals = [
+            {
+                "player": player,
+                "games": games[player],
+                "currency": target,
+                **line.to_dict(),
+            }
+            for player, line in lines.items()
+        ]
+        return sorted(totals, key=lambda total: (-total["net"], total["player"]))
+
+
+def format_game(game: dict) -> str:
+    status = f"settled {game['settled_at']}" if game["settled_at"] else "open"
+    name = " ".join(filter(None, [game["name"] or "Home game", game["currency"]]))
+    lines = [
+        f"#{game['id']} {name} ({game['played_at']}, {status})",
+        f"{'Player':<16} {'Bought in':>10} {'Cashed out':>10} {'Net':>10}",
+    ]
+    for name, line in game["players"].items():
+        lines.append(
+            f"{name:<16} {line['bought_in']:>10.2f} {line['cashed_out']:>10.2f} "
+            f"{line['net']:>+10.2f}"
+        )
+    if game["transfers"] is None:
+        lines.append(f"Unbalanced: off by {game['discrepancy']:+.2f}")
+    for transfer in game["transfers"] or []:
+        lines.append(
+            f"{transfer['payer']} pays {transfer['payee']} {transfer['amount']:.2f}"
+        )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    actions = parser.add_subparsers(dest="action", required=True)
+    new = actions.add_parser("new", help="Start a game")
+    new.add_argument("--name", default="")
+    new.add_argument("--at", help='e.g. "2024-01-05 20:00" (default: now)')
+    new.add_argument("--currency", default="", help="e.g. EUR")
+    for name, help_text in (
+        ("buy-in", "Record a buy-in or rebuy"),
+        ("cash-out", "Record a cash-out"),
+    ):
+        entry = actions.add_parser(name, help=help_text)
+        entry.add_argument("game_id", type=int)
+        entry.add_argument("player")
+        entry.add_argument("amount", type=float)
+    for name, help_text in (
+        ("show", "Players, nets, and the transfers to settle"),
+        ("settle", "Check the books and close the game"),
+        ("remove", "Delete a game"),
+    ):
+        actions.add_parser(name, help=help_text).add_argument("game_id", type=int)
+    actions.add_parser("list", help="All games")
+    players = actions.add_parser("players", help="Lifetime results per player")
+    players.add_argument("--currency", help="Convert results to this currency")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        ledger = Ledger(db)
+        try:
+            if args.action == "new":
+                game_id = ledger.create_game(args.name, args.at, args.currency)
+                output = ledger.game(game_id).to_dict()
+            elif args.action in ("buy-in", "cash-out"):
+                kind = args.action.replace("-", "_")
+                game = ledger.add_entry(args.game_id, args.player, kind, args.amount)
+                output = game.to_dict()
+            elif args.action == "show":
+                output = ledger.game(args.game_id).to_dict()
+            elif args.action == "settle":
+                output = ledger.settle(args.game_id).to_dict()
+            elif args.action == "remove":
+                output = {"removed": ledger.delete_game(args.game_id)}
+            elif args.action == "list":
+                output = [game.to_dict() for game in ledger.games()]
+            else:
+                output = ledger.players(args.currency)
+        except KeyError as exc:
+            raise SystemExit(f"error: {exc.args[0]}") from None
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    if args.json or args.action == "remove":
+        print(json.dumps(output, indent=2))
+    elif args.action == "list":
+        for game in output:
+            status = "settled" if game["settled_at"] else "open"
+            print(
+                f"#{game['id']:<4} {game['played_at']} {game['name']:<20} "
+                f"{len(game['players']):>3} players {game['bought_in']:>10.2f} "
+                f"{status}"
+            )
+    elif args.action == "players":
+        for total in output:
+            print(
+                f"{total['player']:<16} {total['games']:>4} games "
+                f"{total['net']:>+10.2f} {total['currency']}".rstrip()
+            )
+    else:
+        print(format_game(output))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Home game ledger")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,260 @@
+#!/usr/bin/env python3
+"""
+Per-street action frequencies by betting line and board texture.
+
+Every postflop decision a player makes is filed under a spot label built from
+what led up to it:
+
+- pot type: limped, SRP (single raised), 3BP, or 4BP+ by preflop raises
+- role: PFA (the last preflop raiser) or caller
+- position: IP or OOP heads-up (whoever acts first on the flop is OOP), MW
+  when three or more players saw the flop
+- the line on earlier streets from the player's side: x (checked through),
+  B (the player made the last bet or raise and was called), b (someone else
+  did and the player called)
+- what the player faces now: unopened (may check or bet), vs bet, vs raise
+
+so "SRP PFA IP flop:x | turn vs bet" is facing a turn probe after checking
+back the flop, and "SRP PFA IP | flop unopened" is the c-bet spot. Each spot
+counts folds, checks, calls, bets, and raises; `--by texture` (or one texture
+axis: height, suits, pairing, connectedness) splits them further by the flop
+as `texture.classify` sees it. Spots seen fewer than `--min-samples` times are
+left out, since a 3-for-4 frequency says nothing.
+
+Example:
+    python3 pokertools.py lines --db hands.sqlite --player Hero --street turn
+    python3 pokertools.py lines --player Hero --by suits --min-samples 30
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Sequence, Tuple
+
+from handdb import HandDB, default_db_path
+from handhistory import FORCED_ACTIONS, Hand
+from texture import classify
+
+
+STREETS = ("flop", "turn", "river")
+ACTIONS = ("fold", "check", "call", "bet", "raise")
+GROUPINGS = ("texture", "height", "suits", "pairing", "connectedness")
+ALL_BOARDS = "all"
+POT_TYPES = {0: "limped", 1: "SRP", 2: "3BP"}
+DEFAULT_MIN_SAMPLES = 10
+
+
+@dataclass
+class SpotCounts:
+    counts: Dict[str, int] = field(default_factory=lambda: dict.fromkeys(ACTIONS, 0))
+
+    @property
+    def samples(self) -> int:
+        return sum(self.counts.values())
+
+    def frequency(self, action: str) -> float:
+        """Share of decisions, 0-100"""
+        return self.counts[action] / self.samples * 100 if self.samples else 0.0
+
+    def to_dict(self) -> dict:
+        return {
+            "samples": self.samples,
+            "counts": dict(self.counts),
+            "frequencies": {
+                action: round(self.frequency(action), 2)
+                for action in ACTIONS
+                if self.counts[action]
+            },
+        }
+
+
+# {spot label: {board group: counts}}
+LineTable = Dict[str, Dict[str, SpotCounts]]
+
+
+def _board_group(hand: Hand, by: Optional[str]) -> str:
+    if by is None:
+        return ALL_BOARDS
+    texture = classify("".join(hand.board[:3]))
+    return texture.bucket if by == "texture" else getattr(texture, by)
+
+
+def hand_decisions(hand: Hand) -> List[Tuple[str, str, str, str]]:
+    """(player, street, spot label, action) for every postflop decision"""
+    if len(hand.board) < 3:
+        return []
+    raises = 0
+    aggressor = None
+    folded = set()
+    for action in hand.actions_on("preflop"):
+        if action.kind == "raise":
+            raises += 1
+            aggressor = action.player
+        elif action.kind == "fold":
+            folded.add(action.player)
+    pot = POT_TYPES.get(raises, "4BP+")
+    dealt = [player.name for player in hand.players if not player.sitting_out]
+    flop_players = [name for name in dealt if name not in folded]
+    flop_order = []
+    for action in hand.actions_on("flop"):
+        if action.player not in flop_order:
+            flop_order.append(action.player)
+
+    def position(name: str) -> str:
+        if len(flop_players) > 2:
+            return "MW"
+        return "OOP" if flop_order and flop_order[0] == name else "IP"
+
+    # {player: ["flop:x", "turn:B", ...]} for streets already finished
+    history: Dict[str, List[str]] = {name: [] for name in flop_players}
+    decisions = []
+    for street in STREETS:
+        bets = 0
+        last_aggressor = None
+        for action in hand.actions_on(street):
+            if action.kind in FORCED_ACTIONS or action.kind not in ACTIONS:
+                continue
+            name = action.player
+            if name not in history:
+                continue
+            facing = ("unopened", "vs bet")[bets] if bets < 2 else "vs raise"
+            role = "PFA" if name == aggressor else "caller"
+            line = " ".join(history[name])
+            spot = " ".join(filter(None, [pot, role, position(name), line]))
+            decisions.append((name, street, f"{spot} | {street} {facing}", action.kind))
+            if action.kind in ("bet", "raise"):
+                bets += 1
+                last_aggressor = name
+            elif action.kind == "fold":
+                folded.add(name)
+        for name in history:
+            if name in folded:
+                continue
+            if last_aggressor is None:
+                history[name].append(f"{street}:x")
+            else:
+                mark = "B" if name == last_aggressor else "b"
+                history[name].append(f"{street}:{mark}")
+    return decisions
+
+
+def compute_lines(
+    hands: Iterable[Hand],
+    players: Optional[Sequence[str]] = None,
+    street: Optional[str] = None,
+    by: Optional[str] = None,
+) -> LineTable:
+    if street is not None and street not in STREETS:
+        raise ValueError(f"street must be one of {', '.join(STREETS)}")
+    if by is not None and by not in GROUPINGS:
+        raise ValueError(f"by must be one of {', '.join(GROUPINGS)}")
+    wanted = set(players) if players else None
+    table: LineTable = {}
+    for hand in hands:
+        for name, spot_street, spot, action in hand_decisions(hand):
+            if wanted is not None and name not in wanted:
+                continue
+            if street is not None and spot_street != street:
+                continue
+            groups = table.setdefault(spot, {})
+            counts = groups.setdefault(_board_group(hand, by), SpotCounts())
+            counts.counts[action] += 1
+    return table
+
+
+def load_lines(
+    db: HandDB,
+    players: Optional[Sequence[str]] = None,
+    street: Optional[str] = None,
+    by: Optional[str] = None,
+) -> LineTable:
+    """Line frequencies straight from the database, seen-flop hands only"""
+    where, params = "h.board IS NOT NULL AND h.board != ''", ()
+    if players:
+        placeholders = ", ".join("?" for _ in players)
+        where += (
+            " AND h.id IN (SELECT hand_pk FROM hand_players "
+            f"WHERE name IN ({placeholders}))"
+        )
+        params = tuple(players)
+    return compute_lines(db.hands(where, params), players, street, by)
+
+
+def filter_samples(table: LineTable, min_samples: int) -> LineTable:
+    """Drop board groups, then spots, with fewer than `min_samples` decisions"""
+    kept: LineTable = {}
+    for spot, groups in table.items():
+        groups = {
+            group: counts
+            for group, counts in groups.items()
+            if counts.samples >= min_samples
+        }
+        if groups:
+            kept[spot] = groups
+    return kept
+
+
+def lines_to_dict(table: LineTable) -> dict:
+    return {
+        spot: {group: counts.to_dict() for group, counts in groups.items()}
+        for spot, groups in sorted(table.items())
+    }
+
+
+def _share(counts: SpotCounts, action: str) -> str:
+    if not counts.counts[action]:
+        return f"{'-':>6}"
+    return f"{counts.frequency(action):>5.1f}%"
+
+
+def format_lines(table: LineTable) -> str:
+    header = f"{'Spot':<44} {'Board':<36} {'N':>5} " + " ".join(
+        f"{action.capitalize():>6}" for action in ACTIONS
+    )
+    lines = [header]
+    for spot, groups in sorted(table.items()):
+        for group, counts in sorted(groups.items(), key=lambda item: -item[1].samples):
+            shares = " ".join(_share(counts, action) for action in ACTIONS)
+            lines.append(f"{spot:<44} {group:<36} {counts.samples:>5} {shares}")
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument(
+        "--player", action="append", help="Limit to this player (repeatable)"
+    )
+    parser.add_argument("--street", choices=STREETS, help="Only this street")
+    parser.add_argument("--by", choices=GROUPINGS, help="Split by flop texture")
+    parser.add_argument(
+        "--min-samples",
+        type=int,
+        default=DEFAULT_MIN_SAMPLES,
+        help="Hide spots with fewer decisions",
+    )
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        table = load_lines(db, args.player, args.street, args.by)
+    table = filter_samples(table, args.min_samples)
+    if args.json:
+        print(json.dumps(lines_to_dict(table), indent=2))
+    elif not table:
+        print(f"No spot has {args.min_samples}+ decisions; lower --min-samples")
+    else:
+        print(format_lines(table))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Action frequencies by line")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,153 @@
+"""
+Listening addresses for the HTTP API: IPv4 or IPv6, plain or TLS, public or admin.
+
+`api serve --listen SPEC` may be given several times, and `--admin-listen SPEC`
+adds listeners for the admin routes. A spec is `HOST:PORT`, with IPv6 hosts in
+brackets (`[::]:9000`, `[::1]:9001`) and an empty host meaning every IPv4
+address (`:9000`), optionally followed by `,cert=FILE,key=FILE` to serve that
+listener over TLS with its own certificate.
+
+Once any admin listener exists, the public listeners answer 404 for every
+route `auth.required_role` reserves for admins (money, exports, API keys, the
+audit log, and routes no rule covers), so those are only reachable on the
+admin addresses, typically loopback or a management network. Without admin
+listeners every listener serves every route, as a single `--host/--port`
+always has. Role checks still apply on both kinds of listener under `--auth`.
+
+Example:
+    python3 pokertools.py api serve --hands-db hands.sqlite --auth \\
+        --listen "[::]:9443,cert=server.pem,key=server.key" \\
+        --admin-listen 127.0.0.1:9001
+"""
+
+from __future__ import annotations
+
+import socket
+import ssl
+from dataclasses import asdict, dataclass
+from http.server import ThreadingHTTPServer
+from pathlib import Path
+from typing import List, Optional, Sequence
+
+from auth import required_role
+
+
+DEFAULT_HOST = "0.0.0.0"
+# Seconds a client gets to finish the TLS handshake
+TLS_HANDSHAKE_TIMEOUT = 10
+
+
+@dataclass
+class Listener:
+    host: str
+    port: int
+    admin: bool = False
+    cert: Optional[str] = None
+    key: Optional[str] = None
+
+    @property
+    def tls(self) -> bool:
+        return self.cert is not None
+
+    @property
+    def ipv6(self) -> bool:
+        return ":" in self.host
+
+    @property
+    def url(self) -> str:
+        host = f"[{self.host}]" if self.ipv6 else self.host
+        return f"{'https' if self.tls else 'http'}://{host}:{self.port}"
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+def parse_listener(spec: str, admin: bool = False) -> Listener:
+    """`HOST:PORT[,cert=FILE,key=FILE]`, IPv6 hosts in brackets"""
+    address, *options = spec.strip().split(",")
+    if address.startswith("["):
+        host, bracket, port = address[1:].partition("]:")
+        if not bracket or not host:
+            raise ValueError(f"Bad IPv6 listener {spec!r}: use [HOST]:PORT")
+    else:
+        host, colon, port = address.rpartition(":")
+        if not colon or ":" in host:
+            raise ValueError(f"Bad listener {spec!r}: use HOST:PORT or [HOST]:PORT")
+        host = host or DEFAULT_HOST
+    if not port.isdigit() or int(port) > 65535:
+        raise ValueError(f"Bad port in listener {spec!r}")
+    settings = {}
+    for option in options:
+        name, equals, value = option.partition("=")
+        if name not in ("cert", "key") or not equals or not value:
+            raise ValueError(f"Bad listener option {option!r}: use cert=FILE,key=FILE")
+        settings[name] = value
+    if "key" in settings and "cert" not in settings:
+        raise ValueError(f"Listener {spec!r} has a key but no cert")
+    return Listener(host, int(port), admin, settings.get("cert"), settings.get("key"))
+
+
+def check_listeners(listeners: Sequence[Listener]):
+    if not listeners:
+        raise ValueError("Nothing to listen on")
+    if all(listener.admin for listener in listeners):
+        raise ValueError("Admin listeners need a public listener beside them")
+    for listener in listeners:
+        for path in filter(None, (listener.cert, listener.key)):
+            if not Path(path).is_file():
+                raise ValueError(f"TLS file {path} not found")
+    addresses = [(listener.host, listener.port) for listener in listeners]
+    for address in addresses:
+        if address[1] and addresses.count(address) > 1:
+            raise ValueError(f"{address[0]}:{address[1]} is listed twice")
+
+
+class APIServer(ThreadingHTTPServer):
+    """One listener; `admin_elsewhere` hides admin routes from a public one"""
+
+    daemon_threads = True
+
+    def __init__(self, listener: Listener, handler, admin_elsewhere: bool = False):
+        self.listener = listener
+        self.admin_elsewhere = admin_elsewhere and not listener.admin
+        self.address_family = socket.AF_INET6 if listener.ipv6 else socket.AF_INET
+        self.context: Optional[ssl.SSLContext] = None
+        if listener.tls:
+            self.context = ssl.SSLContext(ssl.PROTOCOL_TLS_SERVER)
+            self.context.load_cert_chain(listener.cert, listener.key)
+        super().__init__((listener.host, listener.port), handler)
+        if listener.port == 0:
+            self.listener.port = self.server_address[1]
+
+    def exposes(self, method: str, path: str) -> bool:
+        return not self.admin_elsewhere or required_role(method, path) != "admin"
+
+    def finish_request(self, request, client_address):
+        """Runs in the request's thread, so a slow TLS handshake blocks no one"""
+        if self.context is None:
+            super().finish_request(request, client_address)
+            return
+        request.settimeout(TLS_HANDSHAKE_TIMEOUT)
+        try:
+            conn = self.context.wrap_socket(request, server_side=True)
+        except (ssl.SSLError, OSError):
+            return
+        try:
+            conn.settimeout(None)
+            super().finish_request(conn, client_address)
+        finally:
+            conn.close()
+
+
+def make_servers(listeners: Sequence[Listener], handler) -> List[APIServer]:
+    check_listeners(listeners)
+    admin_elsewhere = any(listener.admin for listener in listeners)
+    servers: List[APIServer] = []
+    try:
+        for listener in listeners:
+            servers.append(APIServer(listener, handler, admin_elsewhere))
+    except BaseException:
+        for server in servers:
+            server.server_close()
+        raise
+    return servers
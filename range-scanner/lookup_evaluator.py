@@ -0,0 +1,178 @@
+#!/usr/bin/env python3
+"""
+Lookup-table hand evaluator for Monte Carlo workloads.
+
+Two precomputed tables replace the branchy reference evaluator:
+- RANK_TABLE maps a base-5 encoding of the rank multiset (5-7 cards, at most
+  four of each rank; 49,205 keys for 7 cards) to the best non-flush strength.
+- FLUSH_TABLE maps a 13-bit rank mask of a single suit to its flush or
+  straight-flush strength.
+A per-card suit counter (4 bits per suit) tells which table applies, so a
+7-card evaluation is two small sums and one or two list lookups. Strengths are
+identical to `hand_evaluator.evaluate_mask`, so results can be mixed freely.
+Short-deck mode uses its own pair of tables (built separately on first use).
+
+Tables are generated on first use (~1s). Run this file directly to benchmark:
+    python3 lookup_evaluator.py --samples 1000000
+"""
+
+from __future__ import annotations
+
+import argparse
+import random
+import time
+from itertools import combinations_with_replacement
+from typing import Dict, List, Optional, Sequence
+
+from hand_evaluator import (
+    HandClass,
+    _class_slot,
+    _pack,
+    _straight_high,
+    _top_ranks,
+    evaluate_mask,
+)
+
+
+RANK_KEY = [5 ** (card >> 2) for card in range(52)]
+SUIT_KEY = [1 << ((card & 3) * 4) for card in range(52)]
+RANK_BIT = [1 << (card >> 2) for card in range(52)]
+
+RANK_TABLE: Dict[int, int] = {}
+FLUSH_TABLE: List[int] = []
+SHORT_RANK_TABLE: Dict[int, int] = {}
+SHORT_FLUSH_TABLE: List[int] = []
+# Suit-counter sum -> flushing suit (or -1); 7 cards need at most 3 bits per suit
+FLUSH_SUIT: List[int] = []
+
+
+def _build_rank_table(short_deck: bool = False) -> Dict[int, int]:
+    table: Dict[int, int] = {}
+    lowest_rank = 4 if short_deck else 0
+    for size in (5, 6, 7):
+        for ranks in combinations_with_replacement(range(lowest_rank, 13), size):
+            if any(ranks.count(rank) > 4 for rank in set(ranks)):
+                continue
+            # Consecutive suits keep duplicate ranks distinct and cap each suit
+            # at two cards, so the reference evaluator never sees a flush.
+            mask = 0
+            for idx, rank in enumerate(ranks):
+                mask |= 1 << (rank * 4 + idx % 4)
+            key = sum(5**rank for rank in ranks)
+            table[key] = evaluate_mask(mask, short_deck)
+    return table
+
+
+def _build_flush_table(short_deck: bool = False) -> List[int]:
+    table = [0] * (1 << 13)
+    flush_slot = _class_slot(HandClass.FLUSH, short_deck)
+    for mask in range(1 << 13):
+        if bin(mask).count("1") < 5:
+            continue
+        high = _straight_high(mask, short_deck)
+        if high >= 0:
+            table[mask] = _pack(HandClass.STRAIGHT_FLUSH, [high])
+        else:
+            table[mask] = _pack(flush_slot, _top_ranks(mask, 5))
+    return table
+
+
+def _build_flush_suit_table() -> List[int]:
+    table = [-1] * (1 << 16)
+    for key in range(1 << 16):
+        for suit in range(4):
+            if (key >> (suit * 4)) & 0xF >= 5:
+                table[key] = suit
+                break
+    return table
+
+
+def build_tables(short_deck: bool = False):
+    """Populate the tables for a mode; safe to call more than once"""
+    global RANK_TABLE, FLUSH_TABLE, SHORT_RANK_TABLE, SHORT_FLUSH_TABLE, FLUSH_SUIT
+    if not FLUSH_SUIT:
+        FLUSH_SUIT = _build_flush_suit_table()
+    if short_deck and not SHORT_RANK_TABLE:
+        SHORT_FLUSH_TABLE = _build_flush_table(short_deck=True)
+        SHORT_RANK_TABLE = _build_rank_table(short_deck=True)
+    elif not short_deck and not RANK_TABLE:
+        FLUSH_TABLE = _build_flush_table()
+        RANK_TABLE = _build_rank_table()
+
+
+def evaluate_indices(cards: Sequence[int], short_deck: bool = False) -> int:
+    """Evaluate 5-7 card indices (0-51) and return the comparable strength"""
+    if short_deck:
+        return _evaluate_short_deck(cards)
+    if not RANK_TABLE:
+        build_tables()
+    rank_key = 0
+    suit_key = 0
+    for card in cards:
+        rank_key += RANK_KEY[card]
+        suit_key += SUIT_KEY[card]
+    suit = FLUSH_SUIT[suit_key]
+    if suit < 0:
+        return RANK_TABLE[rank_key]
+    flush_mask = 0
+    for card in cards:
+        if card & 3 == suit:
+            flush_mask |= RANK_BIT[card]
+    # Four of a kind or a full house can't coexist with a 5-card flush in 7
+    # cards, so the flush table result is always the best hand here.
+    return FLUSH_TABLE[flush_mask]
+
+
+def _evaluate_short_deck(cards: Sequence[int]) -> int:
+    if not SHORT_RANK_TABLE:
+        build_tables(short_deck=True)
+    rank_key = 0
+    suit_key = 0
+    for card in cards:
+        rank_key += RANK_KEY[card]
+        suit_key += SUIT_KEY[card]
+    suit = FLUSH_SUIT[suit_key]
+    if suit < 0:
+        return SHORT_RANK_TABLE[rank_key]
+    flush_mask = 0
+    for card in cards:
+        if card & 3 == suit:
+            flush_mask |= RANK_BIT[card]
+    return SHORT_FLUSH_TABLE[flush_mask]
+
+
+def benchmark(samples: int, seed: Optional[int] = 1) -> float:
+    """Return 7-card evaluations per second over random hands"""
+    build_tables()
+    rng = random.Random(seed)
+    hands = [rng.sample(range(52), 7) for _ in range(samples)]
+    start = time.perf_counter()
+    for hand in hands:
+        evaluate_indices(hand)
+    elapsed = time.perf_counter() - start
+    return samples / elapsed if elapsed > 0 else 0.0
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Lookup evaluator benchmark")
+    parser.add_argument("--samples", type=int, default=1_000_000)
+    args = parser.parse_args()
+
+    start = time.perf_counter()
+    build_tables()
+    print(f"Tables built in {time.perf_counter() - start:.2f}s")
+
+    reference = random.Random(2)
+    reference_hands = [reference.sample(range(52), 7) for _ in range(50_000)]
+    ref_start = time.perf_counter()
+    for hand in reference_hands:
+        evaluate_mask(sum(1 << card for card in hand))
+    ref_rate = len(reference_hands) / (time.perf_counter() - ref_start)
+
+    rate = benchmark(args.samples)
+    print(f"Reference evaluator: {ref_rate:,.0f} evals/s")
+    print(f"Lookup evaluator:    {rate:,.0f} evals/s ({rate / ref_rate:.1f}x)")
+
+
+if __name__ == "__main__":
+    main()
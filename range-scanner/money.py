@@ -0,0 +1,232 @@
+#!/usr/bin/env python3
+"""
+Currencies and exchange-rate snapshots for the money in the hand database.
+
+Amounts stay in the currency they were played in: hands carry the one their
+site printed (`Hand.currency`), and bankroll sessions, transactions, and
+ledger games record one when they are logged. An empty currency means
+unspecified (rows from before currencies were tracked, play money, tournament
+chips) and is never converted: it counts as whatever currency a report is in,
+which is how every total was added up before.
+
+Rates are snapshots: `set_rate("EUR", "USD", 1.08, "2024-01-05")` says a euro
+bought 1.08 dollars from then on. An amount converts at the latest snapshot
+at or before its own time (the earliest one if it predates them all), so a
+session keeps the value it had when it was played however rates move later.
+A pair also resolves inverted (USD->EUR from EUR->USD) or crossed through one
+shared currency (GBP->EUR via USD).
+
+Reports that total several currencies need a target: `output_currency`
+refuses to add EUR and GBP results into one number without one.
+
+Example:
+    python3 pokertools.py money rate EUR USD 1.08 --at 2024-01-05
+    python3 pokertools.py money convert 100 GBP EUR --at "2024-03-01 20:00"
+    python3 pokertools.py bankroll summary --currency USD
+"""
+
+from __future__ import annotations
+
+import argparse
+import bisect
+import json
+import re
+from dataclasses import asdict, dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+
+from handdb import HandDB, default_db_path, normalize_timestamp
+from handhistory import CURRENCY_SYMBOLS
+
+
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+CODE_PATTERN = re.compile(r"^[A-Z]{3}$")
+# The two units every money report can be shown in
+UNITS = ("money", "bb")
+
+# {(base, quote): ([as_of, ...], [rate, ...])} in time order
+Pairs = Dict[Tuple[str, str], Tuple[List[str], List[float]]]
+
+
+def normalize_currency(value: Optional[str]) -> str:
+    """"eur", "€", or "EUR" -> "EUR"; None or "" stays unspecified"""
+    code = str(value or "").strip()
+    code = CURRENCY_SYMBOLS.get(code, code).upper()
+    if code and not CODE_PATTERN.match(code):
+        raise ValueError(f"Currency must be a 3-letter code like EUR, got {value!r}")
+    return code
+
+
+def stamp(at: Optional[str]) -> str:
+    """Stored sortable time for hand ("2024/01/05 9:05:00") or ISO input"""
+    if at is None:
+        return datetime.now().strftime(STORED_FORMAT)
+    if "/" in str(at):
+        at = normalize_timestamp(str(at)) or at
+    try:
+        moment = datetime.fromisoformat(str(at).strip().replace("/", "-"))
+    except ValueError:
+        raise ValueError(f"Invalid time: {at!r}") from None
+    return moment.strftime(STORED_FORMAT)
+
+
+def output_currency(currencies: Iterable[str], target: Optional[str] = None) -> str:
+    """The currency a total is in; mixed currencies need an explicit target"""
+    if target:
+        return normalize_currency(target)
+    found = sorted({currency for currency in currencies if currency})
+    if len(found) > 1:
+        raise ValueError(
+            f"Amounts are in {', '.join(found)}; choose a currency to convert to"
+        )
+    return found[0] if found else ""
+
+
+@dataclass
+class RateSnapshot:
+    base: str
+    quote: str
+    rate: float
+    as_of: str
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+class ExchangeRates:
+    """Rate snapshots on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+        # Loaded on first use, dropped when a rate is added
+        self._pairs: Optional[Pairs] = None
+
+    def set_rate(
+        self, base: str, quote: str, rate: float, as_of: Optional[str] = None
+    ) -> RateSnapshot:
+        """One `base` buys `rate` of `quote` from `as_of` (default: now)"""
+        base, quote = normalize_currency(base), normalize_currency(quote)
+        if not base or not quote or base == quote:
+            raise ValueError("A rate needs two different currencies")
+        if float(rate) <= 0:
+            raise ValueError("Rate must be positive")
+        snapshot = RateSnapshot(base, quote, float(rate), stamp(as_of))
+        with self.conn:
+            self.conn.execute(
+                "INSERT OR REPLACE INTO exchange_rates (base, quote, rate, as_of) "
+                "VALUES (?, ?, ?, ?)",
+                (snapshot.base, snapshot.quote, snapshot.rate, snapshot.as_of),
+            )
+        self._pairs = None
+        return snapshot
+
+    def snapshots(self) -> List[RateSnapshot]:
+        rows = self.conn.execute(
+            "SELECT base, quote, rate, as_of FROM exchange_rates "
+            "ORDER BY base, quote, as_of"
+        ).fetchall()
+        return [RateSnapshot(*row) for row in rows]
+
+    def _load(self) -> Pairs:
+        if self._pairs is None:
+            self._pairs = {}
+            for snapshot in self.snapshots():
+                times, rates = self._pairs.setdefault(
+                    (snapshot.base, snapshot.quote), ([], [])
+                )
+                times.append(snapshot.as_of)
+                rates.append(snapshot.rate)
+        return self._pairs
+
+    def _direct(self, base: str, quote: str, at: str) -> Optional[float]:
+        pairs = self._load()
+        for key, invert in (((base, quote), False), ((quote, base), True)):
+            if key not in pairs:
+                continue
+            times, rates = pairs[key]
+            index = max(bisect.bisect_right(times, at) - 1, 0)
+            return 1 / rates[index] if invert else rates[index]
+        return None
+
+    def rate(self, base: str, quote: str, at: Optional[str] = None) -> float:
+        """What one `base` was worth in `quote` at `at` (default: now)"""
+        base, quote = normalize_currency(base), normalize_currency(quote)
+        if not base or not quote or base == quote:
+            return 1.0
+        at = stamp(at)
+        direct = self._direct(base, quote, at)
+        if direct is not None:
+            return direct
+        for middle in sorted({code for pair in self._load() for code in pair}):
+            first = self._direct(base, middle, at)
+            second = self._direct(middle, quote, at)
+            if first is not None and second is not None:
+                return first * second
+        raise ValueError(f"No exchange rate from {base} to {quote}; add one first")
+
+    def convert(
+        self, amount: float, base: str, quote: str, at: Optional[str] = None
+    ) -> float:
+        return amount * self.rate(base, quote, at)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    actions = parser.add_subparsers(dest="action", required=True)
+    rate = actions.add_parser("rate", help="Record an exchange-rate snapshot")
+    rate.add_argument("base", help="e.g. EUR")
+    rate.add_argument("quote", help="e.g. USD")
+    rate.add_argument("rate", type=float, help="Units of quote per base")
+    rate.add_argument("--at", help="Effective from (default: now)")
+    actions.add_parser("rates", help="Every stored snapshot")
+    convert = actions.add_parser("convert", help="Convert an amount")
+    convert.add_argument("amount", type=float)
+    convert.add_argument("base")
+    convert.add_argument("quote")
+    convert.add_argument("--at", help="Rate as of this time (default: now)")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        rates = ExchangeRates(db)
+        try:
+            if args.action == "rate":
+                output = rates.set_rate(args.base, args.quote, args.rate, args.at)
+                output = output.to_dict()
+            elif args.action == "rates":
+                output = [snapshot.to_dict() for snapshot in rates.snapshots()]
+            else:
+                value = rates.convert(args.amount, args.base, args.quote, args.at)
+                output = {
+                    "amount": args.amount,
+                    "from": normalize_currency(args.base),
+                    "to": normalize_currency(args.quote),
+                    "converted": round(value, 2),
+                }
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    if args.json or args.action == "rate":
+        print(json.dumps(output, indent=2))
+    elif args.action == "rates":
+        for snapshot in output:
+            print(
+                f"{snapshot['as_of']}  1 {snapshot['base']} = "
+                f"{snapshot['rate']:g} {snapshot['quote']}"
+            )
+    else:
+        print(
+            f"{output['amount']:,.2f} {output['from']} = "
+            f"{output['converted']:,.2f} {output['to']}"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Currencies and exchange rates")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,502 @@
+#!/usr/bin/env python3
+"""
+Webhook alerts for notable hands and tournament events (Discord, Slack, or
+signed JSON for other systems).
+
+A JSON config names the webhooks and the rules that trigger a message:
+
+    {
+      "webhooks": ["https://discord.com/api/webhooks/...",
+                   {"url": "https://hooks.slack.com/services/...", "format": "slack"},
+                   {"url": "https://league.example/hooks", "secret": "..."}],
+      "rules": [
+        {"type": "big_pot", "min_bb": 150},
+        {"type": "bad_beat", "min_equity": 0.8},
+        {"type": "elimination"},
+        {"type": "hand_completed", "players": ["Alice"]},
+        {"type": "query", "label": "River check-raise",
+         "query": "player=* and line=check-raise-river and pot>60bb"}
+      ]
+    }
+
+Rule types:
+- `big_pot`: the final pot is at least `min_bb` big blinds
+- `bad_beat`: a player lost an all-in they were at least `min_equity` to win
+  when the money went in (`allin_ev.allin_result`)
+- `elimination`: a tournament player lost their whole stack in a hand, or the
+  seating state recorded a bust
+- `hand_completed`: every new hand, for systems that keep their own copy
+- `query`: any hand matching a `handquery` filter (`"query"`, with an optional
+  `"label"` for the message), so each club writes its own "interesting hand"
+  rules in the search language instead of code; like a search, it is about the
+  hero unless it says `player=` (`player=*` for anyone); database checks only.
+  A query that runs longer than its `"max_seconds"` (default 5) is stopped,
+  reported as a warning, and skipped for that check, so one slow filter does
+  not hold up the other rules or the `--watch` loop
+
+Any rule may add `"players": [...]` to only fire for hands those players were
+dealt into. A webhook's format is guessed from its URL unless given; Discord
+gets `{"content": ...}` and Slack `{"text": ...}`. The `json` format, the
+default for a webhook with a `secret`, posts `{"event", "text", "hand_id",
+"data", "sent_at"}` with the event's details (pot, board, collected, net, or
+the eliminated player) so a league website or payout bot can act on it. A
+webhook with a `secret` is signed: `X-Pokertools-Timestamp` carries the Unix
+time and `X-Pokertools-Signature` is `sha256=` and the hex HMAC-SHA256 of
+"<timestamp>.<body>" under the secret, which receivers check with
+`verify_signature`. A failed post is reported and skipped so one dead webhook
+does not stop the rest.
+
+`notify --watch 15` polls the hand database (and optionally the seating file)
+for new hands and busts and posts as they arrive.
+
+Example:
+    python3 pokertools.py notify --config notify.json --db hands.sqlite --dry-run
+    python3 pokertools.py notify --config notify.json --db hands.sqlite \\
+        --seating seating.json --watch 15
+"""
+
+from __future__ import annotations
+
+import argparse
+import hashlib
+import hmac
+import json
+import sqlite3
+import sys
+import time
+import urllib.request
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Callable, Dict, Iterable, List, Optional, Tuple
+
+from allin_ev import allin_result
+from handdb import HandDB
+from handhistory import Hand
+from handquery import compile_query, register_functions
+
+
+RULE_TYPES = ("big_pot", "bad_beat", "elimination", "hand_completed", "query")
+FORMATS = ("discord", "slack", "json")
+DEFAULT_MIN_BB = 100.0
+DEFAULT_MIN_EQUITY = 0.8
+DEFAULT_QUERY_SECONDS = 5.0
+# SQLite virtual machine steps between checks of a query rule's deadline
+PROGRESS_STEPS = 1000
+# Discord rejects longer message content
+MAX_MESSAGE = 2000
+POST_TIMEOUT = 10
+SIGNATURE_HEADER = "X-Pokertools-Signature"
+TIMESTAMP_HEADER = "X-Pokertools-Timestamp"
+# Signed posts older than this are refused by `verify_signature`
+SIGNATURE_TOLERANCE = 300
+
+
+class QueryTimeout(Exception):
+    """A query rule ran past its max_seconds"""
+
+
+@dataclass
+class Rule:
+    type: str
+    min_bb: float = DEFAULT_MIN_BB
+    min_equity: float = DEFAULT_MIN_EQUITY
+    players: List[str] = field(default_factory=list)
+    query: str = ""
+    label: str = ""
+    max_seconds: float = DEFAULT_QUERY_SECONDS
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "Rule":
+        if not isinstance(data, dict) or data.get("type") not in RULE_TYPES:
+            raise ValueError(f"Rule type must be one of {', '.join(RULE_TYPES)}")
+        rule = cls(
+            data["type"],
+            float(data.get("min_bb", DEFAULT_MIN_BB)),
+            float(data.get("min_equity", DEFAULT_MIN_EQUITY)),
+            [str(name) for name in data.get("players", [])],
+            str(data.get("query", "")),
+            str(data.get("label", "")),
+            float(data.get("max_seconds", DEFAULT_QUERY_SECONDS)),
+        )
+        if rule.min_bb <= 0 or not 0 < rule.min_equity <= 1:
+            raise ValueError("min_bb must be positive and min_equity in (0, 1]")
+        if rule.max_seconds <= 0:
+            raise ValueError("max_seconds must be positive")
+        if rule.type == "query":
+            if not rule.query.strip():
+                raise ValueError("A query rule needs a query")
+            compile_query(rule.query)
+        return rule
+
+    def watches(self, names: Iterable[str]) -> bool:
+        return not self.players or bool(set(self.players) & set(names))
+
+
+@dataclass
+class Alert:
+    rule: str
+    text: str
+    hand_id: Optional[str] = None
+    data: Dict = field(default_factory=dict)
+
+
+@dataclass
+class Webhook:
+    url: str
+    format: str
+    secret: Optional[str] = None
+
+    @classmethod
+    def from_config(cls, value) -> "Webhook":
+        if isinstance(value, str):
+            value = {"url": value}
+        url = str(value.get("url", ""))
+        if not url.startswith(("https://", "http://")):
+            raise ValueError(f"Webhook URL must be http(s): {url!r}")
+        secret = value.get("secret") or None
+        guess = "slack" if "slack.com" in url else "discord"
+        kind = value.get("format") or ("json" if secret else guess)
+        if kind not in FORMATS:
+            raise ValueError(f"Webhook format must be one of {', '.join(FORMATS)}")
+        return cls(url, kind, str(secret) if secret else None)
+
+    def payload(self, text: str) -> dict:
+        text = text[:MAX_MESSAGE]
+        return {"content": text} if self.format == "discord" else {"text": text}
+
+    def message(self, alert: Alert, now: Optional[float] = None) -> dict:
+        if self.format != "json":
+            return self.payload(alert.text)
+        return {
+            "event": alert.rule,
+            "text": alert.text,
+            "hand_id": alert.hand_id,
+            "data": alert.data,
+            "sent_at": int(time.time() if now is None else now),
+        }
+
+    def headers(self, body: bytes, now: Optional[float] = None) -> Dict[str, str]:
+        """Signature headers for a signed webhook; none without a secret"""
+        if not self.secret:
+            return {}
+        timestamp = str(int(time.time() if now is None else now))
+        return {
+            TIMESTAMP_HEADER: timestamp,
+            SIGNATURE_HEADER: sign(self.secret, timestamp, body),
+        }
+
+
+def encode(body: dict) -> bytes:
+    return json.dumps(body).encode("utf-8")
+
+
+def sign(secret: str, timestamp: str, body: bytes) -> str:
+    digest = hmac.new(
+        secret.encode("utf-8"), timestamp.encode("ascii") + b"." + body, hashlib.sha256
+    )
+    return f"sha256={digest.hexdigest()}"
+
+
+def verify_signature(
+    secret: str,
+    body: bytes,
+    timestamp: str,
+    signature: str,
+    now: Optional[float] = None,
+    tolerance: float = SIGNATURE_TOLERANCE,
+) -> bool:
+    """Whether a received post was signed with `secret` and is recent"""
+    try:
+        age = abs((time.time() if now is None else now) - int(timestamp))
+    except ValueError:
+        return False
+    if age > tolerance:
+        return False
+    return hmac.compare_digest(sign(secret, timestamp, body), signature)
+
+
+def load_config(path: Path):
+    """(webhooks, rules) from a JSON config file"""
+    try:
+        data = json.loads(Path(path).read_text())
+    except (OSError, json.JSONDecodeError) as exc:
+        raise ValueError(f"Cannot read {path}: {exc}") from None
+    webhooks = [Webhook.from_config(hook) for hook in data.get("webhooks", [])]
+    rules = [Rule.from_dict(rule) for rule in data.get("rules", [])]
+    if not rules:
+        raise ValueError("The config has no rules")
+    return webhooks, rules
+
+
+def _label(hand: Hand) -> str:
+    where = f"tournament {hand.tournament_id}" if hand.is_tournament else hand.table
+    return f"Hand #{hand.hand_id} ({where})" if where else f"Hand #{hand.hand_id}"
+
+
+def _summary(hand: Hand) -> dict:
+    """What a JSON webhook receives about the hand behind an alert"""
+    return {
+        "site": hand.site,
+        "hand_id": hand.hand_id,
+        "tournament_id": hand.tournament_id,
+        "table": hand.table,
+        "started_at": hand.started_at,
+        "big_blind": hand.big_blind,
+        "total_pot": hand.total_pot,
+        "board": hand.board,
+        "collected": hand.collected,
+        "net": hand.net(),
+    }
+
+
+def hand_alerts(hand: Hand, rules: List[Rule]) -> List[Alert]:
+    dealt = [player.name for player in hand.players if not player.sitting_out]
+    alerts = []
+    for rule in rules:
+        if not rule.watches(dealt):
+            continue
+        if rule.type == "big_pot" and hand.big_blind:
+            size = hand.total_pot / hand.big_blind
+            if size >= rule.min_bb:
+                winners = ", ".join(sorted(hand.collected)) or "nobody"
+                text = f"{_label(hand)}: {size:,.0f} bb pot won by {winners}"
+                if hand.board:
+                    text += f" on {' '.join(hand.board)}"
+                alerts.append(Alert(rule.type, text, hand.hand_id, _summary(hand)))
+        elif rule.type == "bad_beat":
+            result = allin_result(hand)
+            if result is None:
+                continue
+            for name, equity in result.equities.items():
+                if equity >= rule.min_equity and result.net.get(name, 0) < 0:
+                    cards = " ".join(hand.player(name).hole_cards)
+                    data = dict(_summary(hand), player=name, equity=equity)
+                    alerts.append(
+                        Alert(
+                            rule.type,
+                            f"{_label(hand)}: bad beat, {name} ({cards}) lost "
+                            f"all-in on the {result.street} as a "
+                            f"{equity * 100:.0f}% favourite",
+                            hand.hand_id,
+                            data,
+                        )
+                    )
+        elif rule.type == "elimination" and hand.is_tournament:
+            put_in = hand.contributions()
+            for player in hand.players:
+                busted = player.stack > 0 and put_in.get(player.name, 0) >= player.stack
+                if busted and not hand.collected.get(player.name):
+                    alerts.append(
+                        Alert(
+                            rule.type,
+                            f"{player.name} is eliminated: {_label(hand)}",
+                            hand.hand_id,
+                            dict(_summary(hand), player=player.name),
+                        )
+                    )
+        elif rule.type == "hand_completed":
+            winners = ", ".join(sorted(hand.collected)) or "nobody"
+            text = f"{_label(hand)} completed, won by {winners}"
+            alerts.append(Alert(rule.type, text, hand.hand_id, _summary(hand)))
+    return alerts
+
+
+def seating_alerts(events: Iterable[dict], rules: List[Rule]) -> List[Alert]:
+    """Elimination alerts for `bust` events from the seating state"""
+    alerts = []
+    for event in events:
+        if event.get("type") != "bust":
+            continue
+        player = event["player"]
+        if any(rule.type == "elimination" and rule.watches([player]) for rule in rules):
+            text = f"{player} is eliminated (table {event['table']})"
+            data = {"player": player, "table": event["table"]}
+            alerts.append(Alert("elimination", text, data=data))
+    return alerts
+
+
+def _post(url: str, body: dict, headers: Optional[Dict[str, str]] = None):
+    data = encode(body)
+    request = urllib.request.Request(
+        url,
+        data=data,
+        headers={
+            "Content-Type": "application/json",
+            "User-Agent": "pokertools",
+            **(headers or {}),
+        },
+        method="POST",
+    )
+    with urllib.request.urlopen(request, timeout=POST_TIMEOUT):
+        pass
+
+
+def send(
+    alerts: List[Alert],
+    webhooks: List[Webhook],
+    post: Callable[[str, dict], None] = _post,
+) -> List[str]:
+    """Post every alert to every webhook; returns the failures
+
+    Signed webhooks get their signature headers as a third argument to `post`.
+    """
+    failures = []
+    for alert in alerts:
+        for hook in webhooks:
+            try:
+                body = hook.message(alert)
+                headers = hook.headers(encode(body))
+                if headers:
+                    post(hook.url, body, headers)
+                else:
+                    post(hook.url, body)
+            except Exception as exc:  # pylint: disable=broad-except
+                failures.append(f"{hook.url}: {exc}")
+    return failures
+
+
+def query_matches(db: HandDB, rule: Rule, after: int = 0) -> List[int]:
+    """Keys of hands stored after `after` that match a query rule's filter
+
+    Raises QueryTimeout once the query has run for the rule's max_seconds.
+    """
+    condition, params = compile_query(rule.query)
+    register_functions(db.conn)
+    deadline = time.monotonic() + rule.max_seconds
+    # A true return interrupts the running statement
+    db.conn.set_progress_handler(lambda: time.monotonic() > deadline, PROGRESS_STEPS)
+    try:
+        rows = db.conn.execute(
+            f"""
+            SELECT DISTINCT h.id FROM hands h
+            JOIN hand_players hp ON hp.hand_pk = h.id
+            WHERE h.id > ? AND ({condition})
+            """,
+            [after] + params,
+        ).fetchall()
+    except sqlite3.OperationalError as exc:
+        if "interrupted" not in str(exc):
+            raise
+        raise QueryTimeout(
+            f"query rule {rule.label or rule.query!r} ran past "
+            f"{rule.max_seconds:g}s and was skipped"
+        ) from None
+    finally:
+        db.conn.set_progress_handler(None, PROGRESS_STEPS)
+    return [hand_pk for (hand_pk,) in rows]
+
+
+def query_alert(hand: Hand, rule: Rule) -> Optional[Alert]:
+    dealt = [player.name for player in hand.players if not player.sitting_out]
+    if not rule.watches(dealt):
+        return None
+    text = f"{_label(hand)}: {rule.label or rule.query}"
+    return Alert(rule.type, text, hand.hand_id, dict(_summary(hand), query=rule.query))
+
+
+def check_database(
+    db: HandDB,
+    rules: List[Rule],
+    after: int = 0,
+    warnings: Optional[List[str]] = None,
+) -> Tuple[List[Alert], int]:
+    """Alerts for hands stored after primary key `after`, and the newest key
+
+    Query rules that time out are skipped, with a message added to `warnings`.
+    """
+    # Listed before the query rules run, so every listed hand gets checked
+    keys = list(db.hand_keys("h.id > ?", (after,)))
+    matches = {}
+    for index, rule in enumerate(rules):
+        if rule.type != "query":
+            continue
+        try:
+            matches[index] = set(query_matches(db, rule, after))
+        except QueryTimeout as exc:
+            if warnings is not None:
+                warnings.append(str(exc))
+    alerts = []
+    last = after
+    for hand_pk, _, _ in keys:
+        hand = db.load(hand_pk)
+        alerts += hand_alerts(hand, rules)
+        for index, found in matches.items():
+            alert = hand_pk in found and query_alert(hand, rules[index])
+            if alert:
+                alerts.append(alert)
+        last = max(last, hand_pk)
+    return alerts, last
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--config", type=Path, required=True, help="Rules JSON")
+    parser.add_argument("--db", type=Path, help="Hand database to check")
+    parser.add_argument("--seating", type=Path, help="Seating state to watch")
+    parser.add_argument(
+        "--since", type=int, default=0, help="Only hands stored after this id"
+    )
+    parser.add_argument("--watch", type=float, help="Poll every N seconds")
+    parser.add_argument(
+        "--dry-run", action="store_true", help="Print alerts, post nothing"
+    )
+
+
+def _seating_events(path: Optional[Path], after: int) -> Tuple[List[dict], int]:
+    if path is None or not path.exists():
+        return [], after
+    events = json.loads(path.read_text()).get("events", [])
+    fresh = [event for event in events if event.get("version", 0) > after]
+    return fresh, max([after] + [event["version"] for event in fresh])
+
+
+def run(args: argparse.Namespace):
+    if not args.db and not args.seating:
+        raise SystemExit("error: give --db, --seating, or both")
+    try:
+        webhooks, rules = load_config(args.config)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if not webhooks and not args.dry_run:
+        raise SystemExit("error: the config has no webhooks; use --dry-run")
+    db = HandDB(args.db) if args.db else None
+    last_hand = args.since
+    last_event = 0
+    if args.watch:
+        # Busts already in the file when watching starts are not news
+        _, last_event = _seating_events(args.seating, 0)
+    try:
+        while True:
+            alerts: List[Alert] = []
+            warnings: List[str] = []
+            if db is not None:
+                found, last_hand = check_database(db, rules, last_hand, warnings)
+                alerts += found
+            for warning in warnings:
+                print(f"warning: {warning}", file=sys.stderr)
+            events, last_event = _seating_events(args.seating, last_event)
+            alerts += seating_alerts(events, rules)
+            for alert in alerts:
+                print(f"[{alert.rule}] {alert.text}")
+            if not args.dry_run:
+                for failure in send(alerts, webhooks):
+                    print(f"warning: {failure}", file=sys.stderr)
+            if not args.watch:
+                break
+            time.sleep(args.watch)
+    except KeyboardInterrupt:
+        pass
+    finally:
+        if db is not None:
+            db.close()
+    if db is not None:
+        print(f"checked hands up to id {last_hand}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Webhook alerts for big hands")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
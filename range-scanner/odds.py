@@ -0,0 +1,238 @@
+#!/usr/bin/env python3
+"""
+Pot odds, outs, and implied-odds helpers for hold'em decisions.
+
+Amounts are in any consistent unit (chips or big blinds). `pot` always means
+everything already in the middle, including the bet being faced, so facing a
+50 bet into 100 is `pot=150, call=50`: 3-to-1 pot odds, 25% required equity.
+
+Outs are counted against the hero's current hand class, and only cards whose
+improvement uses the hole cards count (pairing the board is not an out). An out
+is *tainted* when the card that improves the hero also lets some opponent
+holding make a higher hand class (a flush card that pairs the board, a straight
+card that puts three of a suit out); `OutsResult.discounted` counts those as
+half an out.
+
+Example:
+    python3 odds.py --hole JhTh --board 9c8d2s --pot 150 --call 50
+"""
+
+from __future__ import annotations
+
+import argparse
+import math
+from collections import Counter
+from dataclasses import dataclass, field
+from itertools import combinations
+from typing import List, Optional, Sequence, Union
+
+from cards import Card, CardLike, cards_to_mask, mask_to_cards, parse_cards, to_card
+from hand_evaluator import CLASS_SHIFT, HandClass
+from lookup_evaluator import evaluate_indices
+
+
+# Weight of a tainted out in the discounted count
+TAINTED_OUT_WEIGHT = 0.5
+
+
+def pot_odds(pot: float, call: float) -> float:
+    """Pot odds as a ratio: 3.0 means 3-to-1"""
+    if call <= 0:
+        raise ValueError("Call amount must be positive")
+    return pot / call
+
+
+def required_equity(pot: float, call: float) -> float:
+    """Equity needed for a call to break even with no future betting"""
+    if call <= 0:
+        raise ValueError("Call amount must be positive")
+    return call / (pot + call)
+
+
+def implied_odds_needed(pot: float, call: float, equity: float) -> float:
+    """Extra amount that must be won on later streets to make a call break even
+
+    Zero when the call is already profitable on direct pot odds.
+    """
+    if not 0 < equity <= 1:
+        raise ValueError("Equity must be in (0, 1]")
+    needed = call * (1 - equity) / equity - pot
+    return max(needed, 0.0)
+
+
+def draw_probability(outs: float, unseen: int, cards_to_come: int) -> float:
+    """Exact chance of hitting at least one of `outs` in the next cards
+
+    `outs` may be fractional (a discounted count); the result is interpolated.
+    """
+    if not 0 <= outs <= unseen:
+        raise ValueError(f"Outs must be between 0 and {unseen}")
+    if cards_to_come not in (1, 2):
+        raise ValueError("Cards to come must be 1 or 2")
+
+    def exact(whole: int) -> float:
+        return 1 - math.comb(unseen - whole, cards_to_come) / math.comb(
+            unseen, cards_to_come
+        )
+
+    low = math.floor(outs)
+    if low == outs:
+        return exact(low)
+    return exact(low) + (exact(low + 1) - exact(low)) * (outs - low)
+
+
+def rule_of_2_and_4(outs: float, cards_to_come: int) -> float:
+    """Quick equity estimate: 2% per out with one card to come, 4% with two"""
+    if cards_to_come not in (1, 2):
+        raise ValueError("Cards to come must be 1 or 2")
+    return min(outs * 2 * cards_to_come, 100.0) / 100
+
+
+@dataclass
+class OutsResult:
+    """Clean and tainted outs for the next card"""
+
+    current_class: HandClass
+    clean: List[Card] = field(default_factory=list)
+    tainted: List[Card] = field(default_factory=list)
+
+    @property
+    def count(self) -> int:
+        return len(self.clean) + len(self.tainted)
+
+    @property
+    def discounted(self) -> float:
+        return len(self.clean) + TAINTED_OUT_WEIGHT * len(self.tainted)
+
+
+def _hand_class(cards: Sequence[int]) -> HandClass:
+    return HandClass(evaluate_indices(cards) >> CLASS_SHIFT)
+
+
+def _board_class(board: List[int]) -> HandClass:
+    """Hand class the board plays on its own"""
+    if len(board) >= 5:
+        return _hand_class(board)
+    counts = sorted(Counter(card >> 2 for card in board).values(), reverse=True)
+    if counts[0] == 4:
+        return HandClass.QUADS
+    if counts[0] == 3:
+        return HandClass.TRIPS
+    if counts[0] == 2:
+        return HandClass.TWO_PAIR if counts[1:2] == [2] else HandClass.PAIR
+    return HandClass.HIGH_CARD
+
+
+def _opponent_can_beat(board: List[int], hero_class: HandClass, unseen: List[int]):
+    for combo in combinations(unseen, 2):
+        if _hand_class(board + list(combo)) > hero_class:
+            return True
+    return False
+
+
+def count_outs(
+    hole: Union[str, Sequence[CardLike]],
+    board: Union[str, Sequence[CardLike]],
+    min_class: Optional[HandClass] = None,
+    dead: Union[str, Sequence[CardLike]] = (),
+) -> OutsResult:
+    """Cards that lift the hero to a better hand class on the next street
+
+    With `min_class` only improvements reaching that class count, e.g.
+    `HandClass.FLUSH` for a pure flush draw.
+    """
+    hole_cards = parse_cards(hole) if isinstance(hole, str) else hole
+    board_cards = parse_cards(board) if isinstance(board, str) else board
+    dead_cards = parse_cards(dead) if isinstance(dead, str) else dead
+    hole_idx = [to_card(card).index for card in hole_cards]
+    board_idx = [to_card(card).index for card in board_cards]
+    if len(hole_idx) != 2:
+        raise ValueError("Outs need exactly two hole cards")
+    if len(board_idx) not in (3, 4):
+        raise ValueError("Outs need a flop or turn board")
+    known = cards_to_mask(hole_idx + board_idx)
+    if bin(known).count("1") != len(hole_idx) + len(board_idx):
+        raise ValueError("Duplicate cards between hole cards and board")
+
+    current = _hand_class(hole_idx + board_idx)
+    target = max(current + 1, min_class or 0)
+    unseen_mask = ((1 << 52) - 1) & ~known & ~cards_to_mask(dead_cards)
+    unseen = [card.index for card in mask_to_cards(unseen_mask)]
+    result = OutsResult(current)
+    for card in unseen:
+        new_board = board_idx + [card]
+        improved = _hand_class(hole_idx + new_board)
+        if improved < target or improved <= _board_class(new_board):
+            continue
+        rest = [other for other in unseen if other != card]
+        if _opponent_can_beat(new_board, improved, rest):
+            result.tainted.append(Card(card))
+        else:
+            result.clean.append(Card(card))
+    return result
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--hole", help="Hero hole cards, e.g. JhTh")
+    parser.add_argument("--board", help="Flop or turn, e.g. 9c8d2s")
+    parser.add_argument("--pot", type=float, required=True, help="Pot incl. bet")
+    parser.add_argument("--call", type=float, required=True)
+    parser.add_argument(
+        "--min-class",
+        choices=[hand_class.name.lower() for hand_class in HandClass],
+        help="Only count outs reaching this hand class",
+    )
+
+
+def run(args: argparse.Namespace):
+    needed = required_equity(args.pot, args.call)
+    print(
+        f"Pot odds {pot_odds(args.pot, args.call):.2f}:1, "
+        f"need {needed * 100:.1f}% equity"
+    )
+    if not (args.hole and args.board):
+        return
+
+    try:
+        min_class = HandClass[args.min_class.upper()] if args.min_class else None
+        outs = count_outs(args.hole, args.board, min_class)
+    except KeyError as exc:
+        raise SystemExit(f"error: unknown hand class {exc.args[0]}") from None
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    board_size = len(parse_cards(args.board))
+    to_come = 5 - board_size
+    unseen = 52 - 2 - board_size
+    print(
+        f"Outs: {outs.count} ({len(outs.tainted)} tainted, "
+        f"{outs.discounted:g} discounted) over {outs.current_class.label}"
+    )
+    if outs.clean:
+        print(f"  clean:   {' '.join(str(card) for card in outs.clean)}")
+    if outs.tainted:
+        print(f"  tainted: {' '.join(str(card) for card in outs.tainted)}")
+    next_card = draw_probability(outs.discounted, unseen, 1)
+    print(
+        f"Next card {next_card * 100:.1f}% "
+        f"(rule of 2: {rule_of_2_and_4(outs.discounted, 1) * 100:.1f}%)"
+    )
+    if to_come == 2:
+        by_river = draw_probability(outs.discounted, unseen, 2)
+        print(
+            f"By the river {by_river * 100:.1f}% "
+            f"(rule of 4: {rule_of_2_and_4(outs.discounted, 2) * 100:.1f}%)"
+        )
+    # Implied odds are judged on the next card only: the opponent bets again
+    implied = implied_odds_needed(args.pot, args.call, max(next_card, 1e-9))
+    verdict = "call is profitable now" if not implied else f"need {implied:g} more"
+    print(f"Implied odds: {verdict}")
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Pot odds and outs calculator")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
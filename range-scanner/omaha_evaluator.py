@@ -0,0 +1,123 @@
+"""
+Omaha and Omaha Hi-Lo evaluation.
+
+Omaha hands must use exactly two hole cards and exactly three board cards, so
+the best hand is the maximum over every 2-from-hole x 3-from-board split (60
+splits for 4-card Omaha on a full board). High strengths are the same integers
+produced by `hand_evaluator`/`lookup_evaluator`.
+
+Low hands follow 8-or-better rules: five distinct ranks of eight or lower, aces
+play low, straights and flushes are ignored. A low is represented as a 9-bit
+mask of ace-low ranks (bit 1 = ace ... bit 8 = eight); for two lows of five
+cards, the numerically smaller mask is the better low. `low_strength` turns that
+into a higher-is-better integer so callers can compare it like a high strength.
+
+Example:
+    high, low = evaluate_omaha_hilo(parse_cards("As2dKhQh"), parse_cards("3c5d8hKsJd"))
+    describe_low(low)     # '8-5-3-2-A'
+"""
+
+from __future__ import annotations
+
+from itertools import combinations
+from typing import Iterable, List, Optional, Tuple
+
+from cards import CardLike, to_card
+from hand_evaluator import HandValue
+from lookup_evaluator import evaluate_indices
+
+
+LOW_MASK_LIMIT = 0x1FF
+LOW_LABELS = "A2345678"
+
+
+def _indices(cards: Iterable[CardLike]) -> List[int]:
+    return [to_card(card).index for card in cards]
+
+
+def _validate(hole: List[int], board: List[int]):
+    if len(hole) < 4:
+        raise ValueError(f"Omaha needs at least 4 hole cards, got {len(hole)}")
+    if not 3 <= len(board) <= 5:
+        raise ValueError(f"Board must have 3-5 cards, got {len(board)}")
+    if len(set(hole + board)) != len(hole) + len(board):
+        raise ValueError("Duplicate cards between hole cards and board")
+
+
+def omaha_high_indices(hole: List[int], board: List[int]) -> int:
+    best = 0
+    board_triples = list(combinations(board, 3))
+    for pair in combinations(hole, 2):
+        for triple in board_triples:
+            strength = evaluate_indices(pair + triple)
+            if strength > best:
+                best = strength
+    return best
+
+
+def _low_rank_bit(card: int) -> int:
+    """Ace-low rank bit for cards eight or lower, else 0"""
+    rank = card >> 2
+    if rank == 12:
+        return 1 << 1
+    if rank <= 6:
+        return 1 << (rank + 2)
+    return 0
+
+
+def omaha_low_indices(hole: List[int], board: List[int]) -> Optional[int]:
+    """Best 8-or-better low mask, or None when no qualifying low exists"""
+    hole_masks = set()
+    for first, second in combinations(hole, 2):
+        a, b = _low_rank_bit(first), _low_rank_bit(second)
+        if a and b and a != b:
+            hole_masks.add(a | b)
+    if not hole_masks:
+        return None
+
+    board_masks = set()
+    for first, second, third in combinations(board, 3):
+        a, b, c = _low_rank_bit(first), _low_rank_bit(second), _low_rank_bit(third)
+        if a and b and c and len({a, b, c}) == 3:
+            board_masks.add(a | b | c)
+
+    best: Optional[int] = None
+    for hole_mask in hole_masks:
+        for board_mask in board_masks:
+            if hole_mask & board_mask:
+                continue
+            candidate = hole_mask | board_mask
+            if best is None or candidate < best:
+                best = candidate
+    return best
+
+
+def low_strength(low_mask: Optional[int]) -> int:
+    """Higher-is-better low score; 0 means no qualifying low"""
+    return 0 if low_mask is None else LOW_MASK_LIMIT - low_mask
+
+
+def describe_low(strength: int) -> str:
+    """Format a low strength as '8-6-4-2-A'"""
+    if not strength:
+        return "No low"
+    mask = LOW_MASK_LIMIT - strength
+    labels = [LOW_LABELS[bit - 1] for bit in range(8, 0, -1) if mask & (1 << bit)]
+    return "-".join(labels)
+
+
+def evaluate_omaha(hole: Iterable[CardLike], board: Iterable[CardLike]) -> HandValue:
+    """Best Omaha high hand using exactly two hole and three board cards"""
+    hole_idx, board_idx = _indices(hole), _indices(board)
+    _validate(hole_idx, board_idx)
+    return HandValue(omaha_high_indices(hole_idx, board_idx))
+
+
+def evaluate_omaha_hilo(
+    hole: Iterable[CardLike], board: Iterable[CardLike]
+) -> Tuple[HandValue, int]:
+    """Return (high value, low strength); low strength is 0 without a qualifier"""
+    hole_idx, board_idx = _indices(hole), _indices(board)
+    _validate(hole_idx, board_idx)
+    high = HandValue(omaha_high_indices(hole_idx, board_idx))
+    return high, low_strength(omaha_low_indices(hole_idx, board_idx))
@@ -0,0 +1,197 @@
+#!/usr/bin/env python3
+"""
+Hands per hour and dealer pace per table, from hand start times.
+
+Hand histories record when each hand started, not when it ended, so a hand's
+duration is the time from its start to the next hand's start on the same
+table: dealing, shuffling, and the pause between hands together, which is
+the figure a card room staffs by. `table_sessions` walks each table's hands
+in order and:
+
+- starts a new session after a gap longer than `session_gap` (the table
+  closed or the player left), 30 minutes by default
+- counts gaps longer than `break_gap` inside a session as breaks (a dealer
+  push, a tournament break), 10 minutes by default, which are kept out of the
+  average and median hand duration but not out of hands per hour
+
+For each session it reports the hands, first and last hand start, hands per
+hour over the whole session, the average and median hand duration, the
+longest gap, and the total break time. `pace` is also an export table and
+`GET /api/pace` serves the same rows.
+
+Example:
+    python3 pokertools.py pace --db hands.sqlite
+    python3 pokertools.py pace --table "NLHWhite12" --since 2024-01-01 --json
+    python3 pokertools.py export --table pace --output pace.csv
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass
+from datetime import datetime
+from pathlib import Path
+from statistics import median
+from typing import Iterable, List, Optional, Tuple
+
+from handdb import HandDB, default_db_path
+from money import stamp
+
+
+STORED_FORMAT = "%Y-%m-%d %H:%M:%S"
+DEFAULT_SESSION_GAP = 30 * 60
+DEFAULT_BREAK_GAP = 10 * 60
+
+
+@dataclass
+class TableSession:
+    site: str
+    table: str
+    started_at: str
+    ended_at: str
+    hands: int
+    hours: float
+    hands_per_hour: float
+    avg_hand_seconds: float
+    median_hand_seconds: float
+    longest_gap_seconds: float
+    break_seconds: float
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+def _session(
+    site: str, table: str, starts: List[datetime], break_gap: float
+) -> TableSession:
+    gaps = [(b - a).total_seconds() for a, b in zip(starts, starts[1:])]
+    dealing = [gap for gap in gaps if gap <= break_gap]
+    span = (starts[-1] - starts[0]).total_seconds()
+    return TableSession(
+        site=site,
+        table=table,
+        started_at=starts[0].strftime(STORED_FORMAT),
+        ended_at=starts[-1].strftime(STORED_FORMAT),
+        hands=len(starts),
+        hours=round(span / 3600, 2),
+        hands_per_hour=round(len(gaps) / span * 3600, 1) if span else 0.0,
+        avg_hand_seconds=round(sum(dealing) / len(dealing), 1) if dealing else 0.0,
+        median_hand_seconds=round(median(dealing), 1) if dealing else 0.0,
+        longest_gap_seconds=max(gaps, default=0.0),
+        break_seconds=sum((gap for gap in gaps if gap > break_gap), 0.0),
+    )
+
+
+def table_sessions(
+    starts: Iterable[Tuple[str, str, str]],
+    session_gap: float = DEFAULT_SESSION_GAP,
+    break_gap: float = DEFAULT_BREAK_GAP,
+) -> List[TableSession]:
+    """(site, table, started_at) rows -> pace per table session"""
+    if break_gap <= 0 or session_gap < break_gap:
+        raise ValueError("Gaps must be positive and the session gap the longer")
+    by_table = {}
+    for site, table, started_at in starts:
+        moment = datetime.strptime(started_at, STORED_FORMAT)
+        by_table.setdefault((site, table), []).append(moment)
+    sessions: List[TableSession] = []
+    for (site, table), moments in sorted(by_table.items()):
+        moments.sort()
+        current = [moments[0]]
+        for moment in moments[1:]:
+            if (moment - current[-1]).total_seconds() > session_gap:
+                sessions.append(_session(site, table, current, break_gap))
+                current = []
+            current.append(moment)
+        sessions.append(_session(site, table, current, break_gap))
+    return sessions
+
+
+def load_pace(
+    db: HandDB,
+    table: Optional[str] = None,
+    since: Optional[str] = None,
+    until: Optional[str] = None,
+    session_gap: float = DEFAULT_SESSION_GAP,
+    break_gap: float = DEFAULT_BREAK_GAP,
+) -> List[TableSession]:
+    clauses = ["started_at IS NOT NULL", "COALESCE(table_name, '') != ''"]
+    params: List[str] = []
+    if table:
+        clauses.append("table_name = ?")
+        params.append(table)
+    if since:
+        clauses.append("started_at >= ?")
+        params.append(stamp(since))
+    if until:
+        clauses.append("started_at <= ?")
+        params.append(stamp(until))
+    rows = db.conn.execute(
+        "SELECT site, table_name, started_at FROM hands "
+        f"WHERE {' AND '.join(clauses)}",
+        params,
+    ).fetchall()
+    return table_sessions(rows, session_gap, break_gap)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--table", help="Only this table")
+    parser.add_argument("--since", help="Hands started at or after this time")
+    parser.add_argument("--until", help="Hands started at or before this time")
+    parser.add_argument(
+        "--session-gap",
+        type=float,
+        default=DEFAULT_SESSION_GAP / 60,
+        help="Minutes without a hand that end a session",
+    )
+    parser.add_argument(
+        "--break-gap",
+        type=float,
+        default=DEFAULT_BREAK_GAP / 60,
+        help="Minutes without a hand counted as a break, not dealing",
+    )
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        with HandDB(args.db) as db:
+            sessions = load_pace(
+                db,
+                args.table,
+                args.since,
+                args.until,
+                args.session_gap * 60,
+                args.break_gap * 60,
+            )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps([session.to_dict() for session in sessions], indent=2))
+        return
+    if not sessions:
+        print("No hands with a table and start time")
+        return
+    print(
+        f"{'Table':<24} {'Started':<19} {'Hands':>6} {'Hours':>6} {'Hands/h':>8} "
+        f"{'Avg s':>6} {'Med s':>6} {'Breaks':>7}"
+    )
+    for session in sessions:
+        print(
+            f"{session.table[:24]:<24} {session.started_at:<19} "
+            f"{session.hands:>6} {session.hours:>6.2f} {session.hands_per_hour:>8.1f} "
+            f"{session.avg_hand_seconds:>6.0f} {session.median_hand_seconds:>6.0f} "
+            f"{session.break_seconds / 60:>6.0f}m"
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Hands per hour and dealer pace")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
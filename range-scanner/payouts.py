@@ -0,0 +1,266 @@
+#!/usr/bin/env python3
+"""
+Tournament payout structures.
+
+The prize pool is every entry (re-entries included) times the buy-in less
+rake, topped up to the guarantee if there is one. The buy-in is what a player
+pays, rake included; rake is an amount per entry or a percentage of the
+buy-in ("10%"). How the pool is split:
+
+- `standard`: about `paid_fraction` (15%) of the entries get paid, each place
+  weighted 1/place so first takes the biggest share, with every min-cash at
+  least `min_cash` buy-ins
+- `flat`: an even split between the paid places
+- `wta`: winner takes all
+- `custom`: a list of percentages, first place first
+
+Re-entries grow the pool, but never more places are paid than there are
+unique players. Bubble protection refunds the buy-in to the first
+`bubble_refunds` places out of the money, funded pro rata by the paid places.
+Amounts are rounded down to `rounding` and the leftover goes to first place.
+
+Example:
+    python3 pokertools.py payouts --entrants 180 --reentries 40 --buy-in 110 \\
+        --rake 10 --bubble-refunds 1
+    python3 pokertools.py payouts --entrants 9 --buy-in 50 --model custom \\
+        --percentages 65 35
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import dataclass, field
+from typing import List, Optional, Sequence, Union
+
+
+MODELS = ("standard", "flat", "wta", "custom")
+DEFAULT_PAID_FRACTION = 0.15
+DEFAULT_MIN_CASH = 1.5
+DEFAULT_ROUNDING = 1.0
+
+
+def parse_rake(rake: Union[str, float, None], buy_in: float) -> float:
+    """Rake per entry from an amount or a "10%" share of the buy-in"""
+    if rake in (None, ""):
+        return 0.0
+    text = str(rake).strip()
+    try:
+        if text.endswith("%"):
+            amount = buy_in * float(text[:-1]) / 100
+        else:
+            amount = float(text)
+    except ValueError:
+        raise ValueError(f"Invalid rake: {rake!r}") from None
+    if not 0 <= amount <= buy_in:
+        raise ValueError("Rake must be between 0 and the buy-in")
+    return amount
+
+
+def standard_shares(
+    places: int, pool: float, buy_in: float, min_cash: float = DEFAULT_MIN_CASH
+) -> List[float]:
+    """1/place weights, with places below the min-cash floor lifted to it"""
+    floor = min(min_cash * buy_in, pool / places)
+    floored = 0
+    while True:
+        weights = [1 / place for place in range(1, places - floored + 1)]
+        left = pool - floor * floored
+        amounts = [left * weight / sum(weights) for weight in weights]
+        if floored == places - 1 or amounts[-1] >= floor:
+            break
+        floored += 1
+    return [amount / pool for amount in amounts] + [floor / pool] * floored
+
+
+@dataclass
+class PayoutTable:
+    entrants: int
+    entries: int
+    buy_in: float
+    rake_per_entry: float
+    prize_pool: float
+    overlay: float
+    model: str
+    amounts: List[float]
+    bubble: List[float] = field(default_factory=list)
+
+    @property
+    def rake_total(self) -> float:
+        return self.rake_per_entry * self.entries
+
+    def to_dict(self) -> dict:
+        return {
+            "entrants": self.entrants,
+            "entries": self.entries,
+            "buy_in": self.buy_in,
+            "rake_total": round(self.rake_total, 2),
+            "prize_pool": round(self.prize_pool, 2),
+            "overlay": round(self.overlay, 2),
+            "model": self.model,
+            "paid": len(self.amounts),
+            "places": [
+                {
+                    "place": place,
+                    "amount": round(amount, 2),
+                    "percent": round(amount / self.prize_pool * 100, 2),
+                    "buy_ins": round(amount / self.buy_in, 2) if self.buy_in else None,
+                }
+                for place, amount in enumerate(self.amounts + self.bubble, start=1)
+            ],
+            "bubble_refunds": len(self.bubble),
+        }
+
+
+def round_amounts(amounts: List[float], total: float, rounding: float) -> List[float]:
+    """Round down to `rounding`; the leftover goes to the biggest amount"""
+    if rounding <= 0:
+        return list(amounts)
+    rounded = [amount // rounding * rounding for amount in amounts]
+    rounded[amounts.index(max(amounts))] += total - sum(rounded)
+    return rounded
+
+
+def calculate_payouts(
+    entrants: int,
+    buy_in: float,
+    rake: Union[str, float, None] = 0.0,
+    reentries: int = 0,
+    model: str = "standard",
+    percentages: Optional[Sequence[float]] = None,
+    paid_fraction: float = DEFAULT_PAID_FRACTION,
+    min_cash: float = DEFAULT_MIN_CASH,
+    bubble_refunds: int = 0,
+    rounding: float = DEFAULT_ROUNDING,
+    guarantee: float = 0.0,
+) -> PayoutTable:
+    if entrants < 1 or reentries < 0:
+        raise ValueError("Need at least one entrant and no negative re-entries")
+    if buy_in < 0 or guarantee < 0 or rounding < 0:
+        raise ValueError("Buy-in, guarantee, and rounding cannot be negative")
+    if model not in MODELS:
+        raise ValueError(f"Model must be one of {', '.join(MODELS)}")
+    if not 0 < paid_fraction <= 1 or bubble_refunds < 0:
+        raise ValueError("paid_fraction must be in (0, 1]; bubble_refunds >= 0")
+    rake_per_entry = parse_rake(rake, buy_in)
+    entries = entrants + reentries
+    collected = entries * (buy_in - rake_per_entry)
+    pool = max(collected, guarantee)
+    if pool <= 0:
+        raise ValueError("The prize pool is empty")
+
+    if model == "wta":
+        shares = [1.0]
+    elif model == "custom":
+        if not percentages or any(value <= 0 for value in percentages):
+            raise ValueError("custom needs positive percentages, first place first")
+        if abs(sum(percentages) - 100) > 0.01:
+            raise ValueError(f"Percentages add up to {sum(percentages):g}, not 100")
+        if list(percentages) != sorted(percentages, reverse=True):
+            raise ValueError("Percentages must not increase down the places")
+        shares = [value / 100 for value in percentages]
+    else:
+        places = max(1, round(entries * paid_fraction))
+        places = min(places, entrants)
+        if model == "flat":
+            shares = [1 / places] * places
+        else:
+            shares = standard_shares(places, pool, buy_in, min_cash)
+    if len(shares) > entrants:
+        raise ValueError(f"Cannot pay {len(shares)} places with {entrants} players")
+
+    bubble = []
+    refunds = min(bubble_refunds, entrants - len(shares))
+    if refunds:
+        refund_total = refunds * buy_in
+        # Paid places fund the refunds in proportion to their share
+        shares = [share * (pool - refund_total) / pool for share in shares]
+        if pool * min(shares) <= buy_in:
+            raise ValueError("Bubble refunds would leave a min-cash below the buy-in")
+        bubble = [float(buy_in)] * refunds
+    amounts = round_amounts(
+        [share * pool for share in shares], pool - sum(bubble), rounding
+    )
+    return PayoutTable(
+        entrants,
+        entries,
+        buy_in,
+        rake_per_entry,
+        pool,
+        pool - collected,
+        model,
+        amounts,
+        bubble,
+    )
+
+
+def format_table(table: PayoutTable) -> str:
+    data = table.to_dict()
+    lines = [
+        f"{data['entries']} entries ({data['entrants']} players), "
+        f"prize pool {data['prize_pool']:,.2f}"
+        + (f" incl. {data['overlay']:,.2f} overlay" if table.overlay else "")
+        + f", rake {data['rake_total']:,.2f}, {data['paid']} paid",
+        f"{'Place':>6} {'Amount':>12} {'Pool %':>7} {'Buy-ins':>8}",
+    ]
+    for row in data["places"]:
+        note = "  (bubble refund)" if row["place"] > data["paid"] else ""
+        buy_ins = "-" if row["buy_ins"] is None else f"{row['buy_ins']:.2f}"
+        lines.append(
+            f"{row['place']:>6} {row['amount']:>12,.2f} {row['percent']:>7.2f} "
+            f"{buy_ins:>8}{note}"
+        )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--entrants", type=int, required=True, help="Unique players")
+    parser.add_argument("--reentries", type=int, default=0)
+    parser.add_argument("--buy-in", type=float, required=True)
+    parser.add_argument("--rake", default="0", help="Per entry, or a %% of buy-in")
+    parser.add_argument("--model", choices=MODELS, default="standard")
+    parser.add_argument("--percentages", type=float, nargs="+", help="For custom")
+    parser.add_argument("--paid-fraction", type=float, default=DEFAULT_PAID_FRACTION)
+    parser.add_argument(
+        "--min-cash",
+        type=float,
+        default=DEFAULT_MIN_CASH,
+        help="Smallest standard payout, in buy-ins",
+    )
+    parser.add_argument("--bubble-refunds", type=int, default=0)
+    parser.add_argument("--rounding", type=float, default=DEFAULT_ROUNDING)
+    parser.add_argument("--guarantee", type=float, default=0.0)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        table = calculate_payouts(
+            args.entrants,
+            args.buy_in,
+            args.rake,
+            args.reentries,
+            args.model,
+            args.percentages,
+            args.paid_fraction,
+            args.min_cash,
+            args.bubble_refunds,
+            args.rounding,
+            args.guarantee,
+        )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(table.to_dict(), indent=2))
+    else:
+        print(format_table(table))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Tournament payout structures")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
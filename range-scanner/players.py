@@ -0,0 +1,208 @@
+#!/usr/bin/env python3
+"""
+Per-player notes, color labels, and tags stored in the hand database.
+
+Each annotated player has free-text notes, at most one color label (one of
+`COLORS` or a #rrggbb value, the way trackers color-code opponents), and any
+number of short tags ("fish", "reg", "tilts"). Tables come from migration 3
+in `handdb.py`. `PlayerNotes.update` only touches the fields it is given, so a
+client can recolor a player without resending the notes.
+
+Example:
+    python3 pokertools.py notes set Villain --note "Overfolds rivers" \\
+        --color red --tag reg --tag nit
+    python3 pokertools.py notes list --tag reg
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import re
+from dataclasses import asdict, dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional
+
+from handdb import HandDB, default_db_path
+
+
+COLORS = ("red", "orange", "yellow", "green", "blue", "purple", "gray")
+HEX_COLOR = re.compile(r"^#[0-9a-fA-F]{6}$")
+MAX_NOTES_LENGTH = 10_000
+MAX_TAG_LENGTH = 32
+
+
+def normalize_color(color: Optional[str]) -> Optional[str]:
+    """A known color name or #rrggbb, lowercased; "" or None clears it"""
+    if not color:
+        return None
+    color = color.strip().lower()
+    if color not in COLORS and not HEX_COLOR.match(color):
+        raise ValueError(f"Color must be one of {', '.join(COLORS)} or #rrggbb")
+    return color
+
+
+def normalize_tags(tags: Iterable[str]) -> List[str]:
+    cleaned = sorted({str(tag).strip().lower() for tag in tags} - {""})
+    for tag in cleaned:
+        if len(tag) > MAX_TAG_LENGTH:
+            raise ValueError(f"Tag {tag!r} is longer than {MAX_TAG_LENGTH}")
+    return cleaned
+
+
+@dataclass
+class PlayerNote:
+    name: str
+    notes: str = ""
+    color: Optional[str] = None
+    tags: List[str] = field(default_factory=list)
+    updated_at: Optional[str] = None
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+class PlayerNotes:
+    """Notes and tags on top of an open `HandDB`"""
+
+    def __init__(self, db: HandDB):
+        self.conn = db.conn
+
+    def get(self, name: str) -> Optional[PlayerNote]:
+        row = self.conn.execute(
+            "SELECT notes, color, updated_at FROM player_notes WHERE name = ?",
+            (name,),
+        ).fetchone()
+        if row is None:
+            return None
+        return PlayerNote(name, row[0], row[1], self._tags(name), row[2])
+
+    def _tags(self, name: str) -> List[str]:
+        rows = self.conn.execute(
+            "SELECT tag FROM player_tags WHERE name = ? ORDER BY tag", (name,)
+        )
+        return [tag for (tag,) in rows]
+
+    def update(
+        self,
+        name: str,
+        notes: Optional[str] = None,
+        color: Optional[str] = None,
+        tags: Optional[Iterable[str]] = None,
+        clear_color: bool = False,
+    ) -> PlayerNote:
+        """Create or update; fields left as None keep their current value"""
+        if not name:
+            raise ValueError("Player name is required")
+        current = self.get(name) or PlayerNote(name)
+        if notes is not None:
+            if len(notes) > MAX_NOTES_LENGTH:
+                raise ValueError(f"Notes are longer than {MAX_NOTES_LENGTH}")
+            current.notes = notes
+        if clear_color:
+            current.color = None
+        elif color is not None:
+            current.color = normalize_color(color)
+        if tags is not None:
+            current.tags = normalize_tags(tags)
+        current.updated_at = datetime.now().strftime("%Y-%m-%d %H:%M:%S")
+        with self.conn:
+            self.conn.execute(
+                """
+                INSERT INTO player_notes (name, notes, color, updated_at)
+                VALUES (?, ?, ?, ?)
+                ON CONFLICT (name) DO UPDATE SET
+                    notes = excluded.notes,
+                    color = excluded.color,
+                    updated_at = excluded.updated_at
+                """,
+                (name, current.notes, current.color, current.updated_at),
+            )
+            self.conn.execute("DELETE FROM player_tags WHERE name = ?", (name,))
+            self.conn.executemany(
+                "INSERT INTO player_tags (name, tag) VALUES (?, ?)",
+                [(name, tag) for tag in current.tags],
+            )
+        return current
+
+    def delete(self, name: str) -> bool:
+        # player_tags rows go with it (ON DELETE CASCADE)
+        with self.conn:
+            cursor = self.conn.execute(
+                "DELETE FROM player_notes WHERE name = ?", (name,)
+            )
+        return bool(cursor.rowcount)
+
+    def all(self, tag: Optional[str] = None) -> List[PlayerNote]:
+        """Every annotated player, or only those carrying `tag`"""
+        where, params = "", ()
+        if tag:
+            where = "WHERE name IN (SELECT name FROM player_tags WHERE tag = ?)"
+            params = (tag.strip().lower(),)
+        rows = self.conn.execute(
+            f"SELECT name FROM player_notes {where} ORDER BY name", params
+        ).fetchall()
+        return [self.get(name) for (name,) in rows]
+
+    def lookup(self, names: Iterable[str]) -> Dict[str, dict]:
+        """{name: note dict} for the given players that have notes"""
+        notes = {}
+        for name in names:
+            note = self.get(name)
+            if note is not None:
+                notes[name] = note.to_dict()
+        return notes
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    actions = parser.add_subparsers(dest="action", required=True)
+    show = actions.add_parser("show", help="Notes for one player")
+    show.add_argument("name")
+    update = actions.add_parser("set", help="Create or update a player's notes")
+    update.add_argument("name")
+    update.add_argument("--note", help="Replace the notes text")
+    update.add_argument("--color", help=f"{', '.join(COLORS)} or #rrggbb")
+    update.add_argument("--clear-color", action="store_true")
+    update.add_argument(
+        "--tag", action="append", help="Replace tags with these (repeatable)"
+    )
+    update.add_argument("--clear-tags", action="store_true")
+    remove = actions.add_parser("delete", help="Forget a player's notes")
+    remove.add_argument("name")
+    listing = actions.add_parser("list", help="Annotated players")
+    listing.add_argument("--tag", help="Only players with this tag")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        notes = PlayerNotes(db)
+        try:
+            if args.action == "show":
+                note = notes.get(args.name)
+                if note is None:
+                    raise SystemExit(f"error: no notes for {args.name!r}")
+                output = note.to_dict()
+            elif args.action == "set":
+                tags = [] if args.clear_tags else args.tag
+                output = notes.update(
+                    args.name, args.note, args.color, tags, args.clear_color
+                ).to_dict()
+            elif args.action == "delete":
+                output = {"removed": notes.delete(args.name)}
+            else:
+                output = [note.to_dict() for note in notes.all(args.tag)]
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+    print(json.dumps(output, indent=2))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Player notes and tags")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
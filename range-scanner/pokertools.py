@@ -0,0 +1,118 @@
+#!/usr/bin/env python3
+"""
+Single entry point for the pokertools command-line tools.
+
+Every subcommand lives in its own module, which exposes
+`add_arguments(parser)` and `run(args)`; this file only wires them together.
+Commands that read the hand database share one default for `--db`: the
+POKERTOOLS_DB environment variable, else ./hands.sqlite.
+
+Example:
+    python3 pokertools.py hands import ~/HandHistories
+    python3 pokertools.py equity AsKs QQ
+    python3 pokertools.py replay 230000000001 --db hands.sqlite
+"""
+
+from __future__ import annotations
+
+import argparse
+
+import allin_ev
+import audit
+import auth
+import autoimport
+import backup
+import bankroll
+import blockers
+import charts
+import deal
+import equity
+import export
+import fairdeal
+import graphql
+import graphs
+import hand_evaluator
+import hand_range
+import handdb
+import handquery
+import handwriter
+import icm
+import leaks
+import ledger
+import lines
+import money
+import notify
+import odds
+import pace
+import payouts
+import players
+import pushfold
+import range_query_service
+import replay
+import seating
+import sim
+import solver
+import stats
+import strength
+import texture
+import tourney
+import twitchbot
+import variance
+
+
+COMMANDS = {
+    "api": (range_query_service, "HTTP API for equity, ranges, and the hand DB"),
+    "audit": (audit, "Append-only log of access to sensitive API routes"),
+    "autoimport": (autoimport, "Watch history folders and import new hands"),
+    "backup": (backup, "Back up and restore the hand database and state files"),
+    "bankroll": (bankroll, "Live sessions, balance, and win rates"),
+    "blockers": (blockers, "Range combos by class and the hero's blocker effects"),
+    "charts": (charts, "Preflop charts and a quiz trainer"),
+    "clock": (tourney, "Blind structures and a tournament clock"),
+    "deal": (deal, "ICM, chip-chop, and save deals at a final table"),
+    "equity": (equity, "Equity of hands or ranges, exact or Monte Carlo"),
+    "ev": (allin_ev, "Actual vs all-in EV results per session"),
+    "eval": (hand_evaluator, "Evaluate and compare made hands"),
+    "export": (export, "Stats, sessions, and rake as CSV or xlsx"),
+    "fairdeal": (fairdeal, "Provably fair commit-reveal dealing and audits"),
+    "graphql": (graphql, "GraphQL queries over hands, players, and sessions"),
+    "graphs": (graphs, "Results and tournament graphs as JSON or PNG"),
+    "hands": (handdb, "Import hand histories into the SQLite database"),
+    "history": (handwriter, "Write hands as PokerStars hand history text"),
+    "icm": (icm, "Tournament equity (ICM) for stacks and payouts"),
+    "keys": (auth, "API keys and the roles the HTTP API checks"),
+    "leaks": (leaks, "Flag stats that fall outside baseline ranges"),
+    "ledger": (ledger, "Home game buy-ins, cash-outs, and settling up"),
+    "lines": (lines, "Action frequencies by betting line and board texture"),
+    "money": (money, "Currencies and exchange-rate snapshots"),
+    "notes": (players, "Player notes, color labels, and tags"),
+    "notify": (notify, "Webhook alerts for big pots, bad beats, busts, and hands"),
+    "odds": (odds, "Pot odds, outs, and draw probabilities"),
+    "pace": (pace, "Hands per hour and dealer pace per table"),
+    "payouts": (payouts, "Tournament payout structures"),
+    "pushfold": (pushfold, "Nash push/fold ranges and charts"),
+    "range": (hand_range, "Expand, count, and draw a range"),
+    "replay": (replay, "Replay a stored hand as text frames"),
+    "search": (handquery, "Search stored hands with a filter expression"),
+    "seating": (seating, "Tournament seat draw, balancing, and table breaks"),
+    "sim": (sim, "Deal random hands and tally outcomes and coolers"),
+    "solve": (solver, "CFR+ solver for heads-up river spots"),
+    "stats": (stats, "VPIP, PFR, 3-bet and win rate per player"),
+    "strength": (strength, "Where a holding ranks on a board or against a range"),
+    "texture": (texture, "Board texture classes and flop buckets"),
+    "twitch": (twitchbot, "Twitch chat bot for !equity and !stats"),
+    "variance": (variance, "Downswings and risk of ruin for a win rate"),
+}
+
+
+def main():
+    parser = argparse.ArgumentParser(prog="pokertools", description="Poker toolkit")
+    subparsers = parser.add_subparsers(dest="command", required=True)
+    for name, (module, help_text) in COMMANDS.items():
+        module.add_arguments(subparsers.add_parser(name, help=help_text))
+    args = parser.parse_args()
+    COMMANDS[args.command][0].run(args)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,210 @@
+"""
+Main/side pot construction and showdown payouts for multi-way all-ins.
+
+Players are identified by seat-order index. Amounts are integer chips: the
+total each player put in during the hand, whether they folded, and (at
+showdown) a comparable score per player. Any uncalled part of the largest bet
+goes back to its owner before pots are built.
+
+Antes and bomb pot posts are dead money: they are in each player's total but
+match no bet, so callers pass them as `dead` too. With a big-blind ante, a
+raise to 75 over a BB who put in 30 + a 30 ante gets 45 back, not 15.
+Straddles are live bets and need nothing special.
+
+A hand run more than once splits every pot evenly between the runs (odd chips
+to the first run) and settles each share on that run's scores: `settle_runs`.
+
+Odd chips follow the usual card-room rule: they go one at a time to the
+winners closest to the left of the button. In split (hi-lo) games each pot is
+halved first and the odd chip of the halving goes to the high side.
+
+Example:
+    pots = build_pots([100, 300, 300, 50], folded=[False, False, False, True])
+    # [Pot(amount=350, eligible=[0, 1, 2]), Pot(amount=400, eligible=[1, 2])]
+    payouts = settle(pots, scores=[90, 40, 40, 0], button=3)
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+from typing import List, Sequence, Tuple, Union
+
+from game_evaluators import HandScore
+
+
+Score = Union[int, HandScore]
+
+
+@dataclass
+class Pot:
+    """One pot and the players still able to win it"""
+
+    amount: int
+    eligible: List[int]
+
+
+def return_uncalled(
+    contributions: Sequence[int], dead: Sequence[int] = ()
+) -> Tuple[List[int], int, int]:
+    """Strip the uncalled part of the biggest bet
+
+    `dead` is the part of each contribution that matched no bet (antes).
+    Returns (contributions after the refund, refunded player or -1, refund).
+    """
+    amounts = list(contributions)
+    dead = list(dead) or [0] * len(amounts)
+    if len(dead) != len(amounts):
+        raise ValueError("dead must have one entry per player")
+    if any(amount < 0 for amount in amounts + dead):
+        raise ValueError("Contributions cannot be negative")
+    if any(posted > amount for posted, amount in zip(dead, amounts)):
+        raise ValueError("Dead money cannot exceed a player's contribution")
+    if len(amounts) < 2:
+        return amounts, -1, 0
+    bets = [amount - posted for amount, posted in zip(amounts, dead)]
+    ordered = sorted(range(len(bets)), key=lambda idx: -bets[idx])
+    top, second = ordered[0], ordered[1]
+    refund = bets[top] - bets[second]
+    if refund <= 0:
+        return amounts, -1, 0
+    amounts[top] -= refund
+    return amounts, top, refund
+
+
+def build_pots(
+    contributions: Sequence[int],
+    folded: Sequence[bool] = (),
+    dead: Sequence[int] = (),
+) -> List[Pot]:
+    """Split contributions into a main pot and side pots
+
+    Each all-in level of a live player closes a pot; folded players' chips
+    fill the pots up to what they put in but they are never eligible.
+    """
+    amounts, _, _ = return_uncalled(contributions, dead)
+    folded = list(folded) or [False] * len(amounts)
+    if len(folded) != len(amounts):
+        raise ValueError("folded must have one entry per player")
+    live = [idx for idx in range(len(amounts)) if not folded[idx]]
+    if not live:
+        raise ValueError("At least one player must still be in the hand")
+
+    pots: List[Pot] = []
+    previous = 0
+    for level in sorted({amounts[idx] for idx in live}):
+        amount = sum(min(chips, level) - min(chips, previous) for chips in amounts)
+        eligible = [idx for idx in live if amounts[idx] >= level]
+        if amount:
+            pots.append(Pot(amount, eligible))
+        previous = level
+    # Dead money above the top live level (only possible via folds) stays in play
+    leftover = sum(max(chips - previous, 0) for chips in amounts)
+    if leftover:
+        if pots:
+            pots[-1].amount += leftover
+        else:
+            pots.append(Pot(leftover, live))
+    return pots
+
+
+def _split(amount: int, winners: List[int], order: List[int], payouts: List[int]):
+    share, odd = divmod(amount, len(winners))
+    for idx in winners:
+        payouts[idx] += share
+    for idx in [seat for seat in order if seat in winners][:odd]:
+        payouts[idx] += 1
+
+
+def _as_score(score: Score) -> HandScore:
+    return score if isinstance(score, HandScore) else HandScore(int(score))
+
+
+def settle(
+    pots: Sequence[Pot],
+    scores: Sequence[Score],
+    button: int = 0,
+    split: bool = False,
+    rake: int = 0,
+) -> List[int]:
+    """Chips each player collects from `pots`
+
+    `scores` are higher-is-better (ints or `HandScore`s; for split games a
+    `low` of 0 means no qualifying low). `rake` is taken from the main pot
+    first, then from side pots in order.
+    """
+    players = len(scores)
+    order = [(button + offset) % players for offset in range(1, players + 1)]
+    payouts = [0] * players
+    hand_scores = [_as_score(score) for score in scores]
+    remaining_rake = rake
+    for pot in pots:
+        taken = min(remaining_rake, pot.amount)
+        remaining_rake -= taken
+        amount = pot.amount - taken
+        if not pot.eligible:
+            raise ValueError("Pot has no eligible players")
+
+        best = max(hand_scores[idx].main for idx in pot.eligible)
+        high_winners = [idx for idx in pot.eligible if hand_scores[idx].main == best]
+        best_low = max(hand_scores[idx].low for idx in pot.eligible) if split else 0
+        if not best_low:
+            _split(amount, high_winners, order, payouts)
+            continue
+        low_winners = [idx for idx in pot.eligible if hand_scores[idx].low == best_low]
+        low_half = amount // 2
+        _split(amount - low_half, high_winners, order, payouts)
+        _split(low_half, low_winners, order, payouts)
+    return payouts
+
+
+def settle_runs(
+    pots: Sequence[Pot],
+    run_scores: Sequence[Sequence[Score]],
+    button: int = 0,
+    split: bool = False,
+    rake: int = 0,
+) -> List[List[int]]:
+    """Chips each player collects from each run of a hand run several times
+
+    `run_scores` holds one list of scores per run. Rake comes out of the pots
+    first, as in `settle`; the rest of each pot is shared between the runs.
+    """
+    runs = len(run_scores)
+    if runs < 1:
+        raise ValueError("A hand needs at least one run")
+    remaining_rake = rake
+    shares: List[List[Pot]] = [[] for _ in range(runs)]
+    for pot in pots:
+        taken = min(remaining_rake, pot.amount)
+        remaining_rake -= taken
+        share, odd = divmod(pot.amount - taken, runs)
+        for run in range(runs):
+            shares[run].append(Pot(share + (run < odd), list(pot.eligible)))
+    return [
+        settle(run_pots, scores, button, split)
+        for run_pots, scores in zip(shares, run_scores)
+    ]
+
+
+def resolve(
+    contributions: Sequence[int],
+    scores: Sequence[Score],
+    folded: Sequence[bool] = (),
+    button: int = 0,
+    split: bool = False,
+    rake: int = 0,
+    dead: Sequence[int] = (),
+) -> List[int]:
+    """Build the pots and settle them, refunding any uncalled bet"""
+    _, refunded, refund = return_uncalled(contributions, dead)
+    pots = build_pots(contributions, folded, dead)
+    payouts = settle(pots, scores, button, split, rake)
+    if refund:
+        payouts[refunded] += refund
+    return payouts
+
+
+def net_results(contributions: Sequence[int], payouts: Sequence[int]) -> List[int]:
+    """Per-player profit: what they collected minus what they put in"""
+    return [paid - put for put, paid in zip(contributions, payouts)]
+
@@ -0,0 +1,550 @@
+#!/usr/bin/env python3
+"""
+Nash push/fold solver for short-stacked all-in-or-fold spots.
+
+The pusher is first in; everyone behind (down to the big blind) either calls
+the all-in or folds. `solve` finds the equilibrium ranges by fictitious play
+over the 169 hand classes: every round each player best-responds to the
+others' average strategy and the averages converge on the Nash ranges.
+Several players behind are handled with the usual approximation that only
+one of them ever calls (the first caller ends the action).
+
+Results are in chips unless `payouts` is given, in which case every outcome
+is valued with ICM (`icm.py`) over the spot's stacks plus `others`, the
+stacks of the players who are not in the hand.
+
+Hand-vs-hand equity comes from an `EquityTable` of class-vs-class all-in
+equity (Monte Carlo, card removal respected) that takes a while to build, so
+it is saved to `preflop_equity.json` and reused.
+
+`nash_chart` solves heads-up SB-vs-BB spots over a list of stack depths and
+reports, for every hand, the deepest stack it is still pushed (or called)
+with: the classic Nash chart. Charts print as 13x13 ASCII grids or PNGs.
+
+Example:
+    python3 pokertools.py pushfold --stacks 10 10
+    python3 pokertools.py pushfold --stacks 15 12 20 --ante 0.1 \\
+        --payouts 50 30 20 --others 30
+    python3 pushfold.py --chart --png nash.png
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import random
+import struct
+import zlib
+from dataclasses import dataclass, field
+from multiprocessing import Pool, cpu_count
+from operator import mul
+from pathlib import Path
+from typing import Dict, List, Optional, Sequence, Tuple
+
+from cards import RANK_CHARS
+from hand_range import PREFLOP_ORDER, class_combos
+from icm import icm_equities
+from lookup_evaluator import build_tables, evaluate_indices
+
+
+DEFAULT_TABLE_PATH = Path("preflop_equity.json")
+DEFAULT_TABLE_SAMPLES = 500
+DEFAULT_SOLVER_ITERATIONS = 300
+DEFAULT_CHART_DEPTHS = tuple(range(1, 21))
+SMALL_BLIND = 0.5
+BIG_BLIND = 1.0
+# A hand is in the range once the equilibrium plays it at least this often
+IN_RANGE = 0.5
+# Averaged frequencies within this of 0 or 1 count as pure strategies
+MIXED = 0.1
+
+
+def _disjoint_pairs(first: str, second: str) -> List[Tuple[Tuple, Tuple]]:
+    return [
+        (a, b)
+        for a in class_combos(first)
+        for b in class_combos(second)
+        if not set(a) & set(b)
+    ]
+
+
+def _equity_row(args) -> List[float]:
+    """Equity of class `index` against every later class (Monte Carlo)"""
+    index, classes, samples, seed = args
+    build_tables()
+    rng = random.Random(None if seed is None else seed * 1000 + index)
+    row = []
+    for other in classes[index:]:
+        pairs = _disjoint_pairs(classes[index], other)
+        share = 0.0
+        for sample in range(samples):
+            # Cycle through the combo pairs so every suit pattern is covered
+            (a, b), (c, d) = pairs[sample % len(pairs)]
+            used = {a, b, c, d}
+            board = []
+            while len(board) < 5:
+                card = rng.randrange(52)
+                if card not in used:
+                    used.add(card)
+                    board.append(card)
+            mine = evaluate_indices([a, b, *board])
+            theirs = evaluate_indices([c, d, *board])
+            share += 1.0 if mine > theirs else 0.5 if mine == theirs else 0.0
+        row.append(share / samples)
+    return row
+
+
+@dataclass
+class EquityTable:
+    """All-in equity of every hand class against every other (ties count half)"""
+
+    classes: List[str]
+    equity: List[List[float]]
+    samples: int
+    weights: List[List[int]] = field(default_factory=list, repr=False)
+
+    def __post_init__(self):
+        if not self.weights:
+            masks = [
+                [(1 << first) | (1 << second) for first, second in class_combos(name)]
+                for name in self.classes
+            ]
+            self.weights = [
+                [sum(1 for a in mine for b in theirs if not a & b) for theirs in masks]
+                for mine in masks
+            ]
+
+    @classmethod
+    def build(
+        cls,
+        samples: int = DEFAULT_TABLE_SAMPLES,
+        seed: Optional[int] = None,
+        workers: Optional[int] = None,
+    ) -> "EquityTable":
+        workers = workers or cpu_count()
+        classes = list(PREFLOP_ORDER)
+        tasks = [(index, classes, samples, seed) for index in range(len(classes))]
+        if workers > 1:
+            with Pool(workers) as pool:
+                rows = pool.map(_equity_row, tasks)
+        else:
+            rows = [_equity_row(task) for task in tasks]
+        size = len(classes)
+        equity = [[0.5] * size for _ in range(size)]
+        for first, row in enumerate(rows):
+            for offset, value in enumerate(row):
+                second = first + offset
+                equity[first][second] = value
+                equity[second][first] = 1 - value
+        return cls(classes, equity, samples)
+
+    def save(self, path: Path):
+        Path(path).write_text(
+            json.dumps(
+                {
+                    "samples": self.samples,
+                    "classes": self.classes,
+                    "equity": [
+                        [round(value, 4) for value in row] for row in self.equity
+                    ],
+                }
+            )
+        )
+
+    @classmethod
+    def load(cls, path: Path) -> "EquityTable":
+        data = json.loads(Path(path).read_text())
+        if data["classes"] != list(PREFLOP_ORDER):
+            raise ValueError(f"{path} was built for a different class order")
+        return cls(data["classes"], data["equity"], data["samples"])
+
+    @classmethod
+    def load_or_build(
+        cls,
+        path: Path = DEFAULT_TABLE_PATH,
+        samples: int = DEFAULT_TABLE_SAMPLES,
+        workers: Optional[int] = None,
+    ) -> "EquityTable":
+        path = Path(path)
+        if path.exists():
+            return cls.load(path)
+        table = cls.build(samples, workers=workers)
+        table.save(path)
+        return table
+
+
+@dataclass
+class PushFoldSpot:
+    """Stacks in big blinds, pusher first and big blind last"""
+
+    stacks: List[float]
+    ante: float = 0.0
+    small_blind: float = SMALL_BLIND
+    payouts: Optional[List[float]] = None
+    others: List[float] = field(default_factory=list)
+
+    def __post_init__(self):
+        if len(self.stacks) < 2:
+            raise ValueError("A spot needs the pusher and at least one caller")
+        if any(stack <= 0 for stack in self.stacks):
+            raise ValueError("Stacks in the hand must be positive")
+        if self.ante < 0 or any(stack < 0 for stack in self.others):
+            raise ValueError("Antes and other stacks cannot be negative")
+        if self.others and not self.payouts:
+            raise ValueError("Other stacks only matter with payouts (ICM)")
+
+    def outcomes(self) -> Dict[object, List[float]]:
+        """Value of each outcome to each player in the hand
+
+        Keys: "fold" (pusher folds; the big blind takes the pot), "steal"
+        (everyone folds to the push), and ("win", j) / ("lose", j) for the
+        pusher's showdown against caller j.
+        """
+        count = len(self.stacks)
+        antes = [min(self.ante, stack) for stack in self.stacks]
+        behind = [stack - ante for stack, ante in zip(self.stacks, antes)]
+        posts = [0.0] * count
+        posts[-1] = min(BIG_BLIND, behind[-1])
+        posts[-2] = min(self.small_blind, behind[-2])
+        others = [stack - min(self.ante, stack) for stack in self.others]
+        dead = sum(antes) + sum(min(self.ante, stack) for stack in self.others)
+        after_blinds = [left - post for left, post in zip(behind, posts)]
+
+        def value(stacks: List[float]) -> List[float]:
+            if not self.payouts:
+                return stacks
+            return icm_equities(stacks + others, self.payouts).equities[:count]
+
+        fold = list(after_blinds)
+        fold[-1] += dead + sum(posts)
+        steal = list(after_blinds)
+        steal[0] += dead + sum(posts)
+        results = {"fold": value(fold), "steal": value(steal)}
+        for caller in range(1, count):
+            matched = min(behind[0], behind[caller])
+            pot = dead + sum(posts) - posts[0] - posts[caller] + 2 * matched
+            base = list(after_blinds)
+            base[0] = behind[0] - matched
+            base[caller] = behind[caller] - matched
+            for key, winner in (("win", 0), ("lose", caller)):
+                stacks = list(base)
+                stacks[winner] += pot
+                results[(key, caller)] = value(stacks)
+        return results
+
+
+@dataclass
+class PushFoldSolution:
+    classes: List[str]
+    push: List[float]
+    calls: List[List[float]]
+    iterations: int
+
+    def push_range(self) -> List[str]:
+        return [name for name, freq in zip(self.classes, self.push) if freq >= IN_RANGE]
+
+    def call_range(self, caller: int = 1) -> List[str]:
+        freqs = self.calls[caller - 1]
+        return [name for name, freq in zip(self.classes, freqs) if freq >= IN_RANGE]
+
+    def to_dict(self) -> dict:
+        return {
+            "iterations": self.iterations,
+            "push": {
+                name: round(freq, 3) for name, freq in zip(self.classes, self.push)
+            },
+            "calls": [
+                {name: round(freq, 3) for name, freq in zip(self.classes, freqs)}
+                for freqs in self.calls
+            ],
+            "push_range": self.push_range(),
+            "call_ranges": [
+                self.call_range(caller) for caller in range(1, len(self.calls) + 1)
+            ],
+        }
+
+
+def solve(
+    spot: PushFoldSpot,
+    table: EquityTable,
+    iterations: int = DEFAULT_SOLVER_ITERATIONS,
+) -> PushFoldSolution:
+    """Approximate Nash ranges by fictitious play"""
+    size = len(table.classes)
+    weights = table.weights
+    weighted_equity = [
+        list(map(mul, weight_row, equity_row))
+        for weight_row, equity_row in zip(weights, table.equity)
+    ]
+    row_weight = [sum(row) for row in weights]
+    values = spot.outcomes()
+    callers = range(1, len(spot.stacks))
+
+    push = [1.0] * size
+    calls = [[1.0] * size for _ in callers]
+    for step in range(iterations):
+        # Pusher's best response to the average calling ranges
+        push_best = []
+        for hand in range(size):
+            reach = 1.0
+            push_ev = 0.0
+            for caller in callers:
+                freqs = calls[caller - 1]
+                called = sum(map(mul, freqs, weights[hand])) / row_weight[hand]
+                won = sum(map(mul, freqs, weighted_equity[hand])) / row_weight[hand]
+                push_ev += reach * (
+                    won * values[("win", caller)][0]
+                    + (called - won) * values[("lose", caller)][0]
+                )
+                reach *= 1 - called
+            push_ev += reach * values["steal"][0]
+            push_best.append(1.0 if push_ev > values["fold"][0] else 0.0)
+
+        # Callers' best responses to the average pushing range
+        call_best = []
+        for caller in callers:
+            best = []
+            for hand in range(size):
+                faced = sum(map(mul, push, weights[hand]))
+                if not faced:
+                    best.append(0.0)
+                    continue
+                equity = sum(map(mul, push, weighted_equity[hand])) / faced
+                call_ev = (
+                    equity * values[("lose", caller)][caller]
+                    + (1 - equity) * values[("win", caller)][caller]
+                )
+                best.append(1.0 if call_ev > values["steal"][caller] else 0.0)
+            call_best.append(best)
+
+        rate = 1 / (step + 2)
+        push = [freq + (best - freq) * rate for freq, best in zip(push, push_best)]
+        calls = [
+            [freq + (best - freq) * rate for freq, best in zip(freqs, bests)]
+            for freqs, bests in zip(calls, call_best)
+        ]
+    return PushFoldSolution(list(table.classes), push, calls, iterations)
+
+
+def nash_chart(
+    table: EquityTable,
+    depths: Sequence[float] = DEFAULT_CHART_DEPTHS,
+    ante: float = 0.0,
+    iterations: int = DEFAULT_SOLVER_ITERATIONS,
+) -> Dict[str, Dict[str, Optional[float]]]:
+    """Heads-up: the deepest stack (bb) each hand is pushed / called with"""
+    push: Dict[str, Optional[float]] = {name: None for name in table.classes}
+    call: Dict[str, Optional[float]] = {name: None for name in table.classes}
+    for depth in sorted(depths):
+        solution = solve(PushFoldSpot([depth, depth], ante), table, iterations)
+        for name in solution.push_range():
+            push[name] = depth
+        for name in solution.call_range():
+            call[name] = depth
+    return {"depths": list(sorted(depths)), "push": push, "call": call}
+
+
+def grid_classes() -> List[List[str]]:
+    """13x13 layout: pairs on the diagonal, suited above it, offsuit below"""
+    ranks = RANK_CHARS[::-1]
+    rows = []
+    for row, high in enumerate(ranks):
+        cells = []
+        for column, low in enumerate(ranks):
+            if row == column:
+                cells.append(high * 2)
+            elif column > row:
+                cells.append(f"{high}{low}s")
+            else:
+                cells.append(f"{low}{high}o")
+        rows.append(cells)
+    return rows
+
+
+def format_grid(labels: Dict[str, str]) -> str:
+    width = max(4, *(len(label) for label in labels.values())) + 1
+    return "\n".join(
+        "".join(labels.get(name, "").rjust(width) for name in row)
+        for row in grid_classes()
+    )
+
+
+def range_labels(freqs: Dict[str, float]) -> Dict[str, str]:
+    """Class name when in range, "~" for a mixed strategy, "." when not"""
+    labels = {}
+    for name, freq in freqs.items():
+        if freq >= 1 - MIXED:
+            labels[name] = name
+        elif freq > MIXED:
+            labels[name] = f"~{name}"
+        else:
+            labels[name] = "."
+    return labels
+
+
+def chart_labels(depths: Dict[str, Optional[float]], top: float) -> Dict[str, str]:
+    return {
+        name: "." if depth is None else f"{depth:g}+" if depth >= top else f"{depth:g}"
+        for name, depth in depths.items()
+    }
+
+
+# 3x5 bitmaps for the characters a grid label can contain
+GLYPHS = {
+    "A": ("010", "101", "111", "101", "101"),
+    "K": ("101", "110", "100", "110", "101"),
+    "Q": ("010", "101", "101", "110", "011"),
+    "J": ("001", "001", "001", "101", "010"),
+    "T": ("111", "010", "010", "010", "010"),
+    "9": ("111", "101", "111", "001", "111"),
+    "8": ("111", "101", "111", "101", "111"),
+    "7": ("111", "001", "010", "010", "010"),
+    "6": ("111", "100", "111", "101", "111"),
+    "5": ("111", "100", "111", "001", "111"),
+    "4": ("101", "101", "111", "001", "001"),
+    "3": ("111", "001", "111", "001", "111"),
+    "2": ("111", "001", "111", "100", "111"),
+    "s": ("000", "011", "010", "001", "110"),
+    "o": ("000", "010", "101", "101", "010"),
+}
+CELL_WIDTH, CELL_HEIGHT, GLYPH_SCALE = 44, 28, 2
+
+
+def encode_png(width: int, height: int, pixels: List[bytearray]) -> bytes:
+    def chunk(kind: bytes, data: bytes) -> bytes:
+        body = kind + data
+        return struct.pack(">I", len(data)) + body + struct.pack(">I", zlib.crc32(body))
+
+    raw = b"".join(b"\x00" + bytes(row) for row in pixels)
+    return (
+        b"\x89PNG\r\n\x1a\n"
+        + chunk(b"IHDR", struct.pack(">IIBBBBB", width, height, 8, 2, 0, 0, 0))
+        + chunk(b"IDAT", zlib.compress(raw, 9))
+        + chunk(b"IEND", b"")
+    )
+
+
+def shade_color(shade: float) -> Tuple[int, int, int]:
+    """RGB for a 0-1 cell value: white through to green"""
+    shade = min(max(shade, 0.0), 1.0)
+    return (int(255 - 200 * shade), int(255 - 75 * shade), int(255 - 200 * shade))
+
+
+def grid_png(shades: Dict[str, float]) -> bytes:
+    """13x13 chart as PNG; each cell is shaded by a 0-1 value and labeled"""
+    width, height = CELL_WIDTH * 13, CELL_HEIGHT * 13
+    pixels = [bytearray(width * 3) for _ in range(height)]
+    for row, names in enumerate(grid_classes()):
+        for column, name in enumerate(names):
+            color = shade_color(shades.get(name, 0.0))
+            top, left = row * CELL_HEIGHT, column * CELL_WIDTH
+            for y in range(top, top + CELL_HEIGHT):
+                for x in range(left, left + CELL_WIDTH):
+                    border = y == top or x == left
+                    pixels[y][x * 3 : x * 3 + 3] = bytes(
+                        (160, 160, 160) if border else color
+                    )
+            text_left = left + (CELL_WIDTH - len(name) * 4 * GLYPH_SCALE) // 2
+            text_top = top + (CELL_HEIGHT - 5 * GLYPH_SCALE) // 2
+            for index, char in enumerate(name):
+                for glyph_row, bits in enumerate(GLYPHS[char]):
+                    for glyph_column, bit in enumerate(bits):
+                        if bit != "1":
+                            continue
+                        for dy in range(GLYPH_SCALE):
+                            for dx in range(GLYPH_SCALE):
+                                y = text_top + glyph_row * GLYPH_SCALE + dy
+                                x = (
+                                    text_left
+                                    + (index * 4 + glyph_column) * GLYPH_SCALE
+                                    + dx
+                                )
+                                pixels[y][x * 3 : x * 3 + 3] = b"\x00\x00\x00"
+    return encode_png(width, height, pixels)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument(
+        "--stacks",
+        type=float,
+        nargs="+",
+        default=[10.0, 10.0],
+        help="Stacks in bb from the pusher to the big blind",
+    )
+    parser.add_argument("--ante", type=float, default=0.0, help="Per player, in bb")
+    parser.add_argument("--payouts", type=float, nargs="+", help="Value with ICM")
+    parser.add_argument(
+        "--others", type=float, nargs="*", default=[], help="Stacks not in the hand"
+    )
+    parser.add_argument(
+        "--chart",
+        action="store_true",
+        help="Heads-up Nash chart over --depths instead of one spot",
+    )
+    parser.add_argument(
+        "--depths", type=float, nargs="+", default=list(DEFAULT_CHART_DEPTHS)
+    )
+    parser.add_argument("--iterations", type=int, default=DEFAULT_SOLVER_ITERATIONS)
+    parser.add_argument("--table", type=Path, default=DEFAULT_TABLE_PATH)
+    parser.add_argument(
+        "--table-samples",
+        type=int,
+        default=DEFAULT_TABLE_SAMPLES,
+        help="Samples per class matchup when the equity table is first built",
+    )
+    parser.add_argument("--workers", type=int, help="Default: one per CPU")
+    parser.add_argument("--png", type=Path, help="Also write the grid as a PNG")
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    if not args.table.exists():
+        print(f"Building {args.table} ({args.table_samples} samples per matchup)...")
+    table = EquityTable.load_or_build(args.table, args.table_samples, args.workers)
+    try:
+        if args.chart:
+            chart = nash_chart(table, args.depths, args.ante, args.iterations)
+            top = max(chart["depths"])
+            labels = chart_labels(chart["push"], top)
+            shades = {
+                name: (depth or 0) / top for name, depth in chart["push"].items()
+            }
+            output = chart
+            header = f"Heads-up push chart (max bb, ante {args.ante:g})"
+            footer = "Call chart:\n" + format_grid(chart_labels(chart["call"], top))
+        else:
+            spot = PushFoldSpot(
+                args.stacks, args.ante, payouts=args.payouts, others=args.others
+            )
+            solution = solve(spot, table, args.iterations)
+            output = solution.to_dict()
+            freqs = dict(zip(solution.classes, solution.push))
+            labels = range_labels(freqs)
+            shades = freqs
+            stacks = " vs ".join(f"{stack:g}" for stack in args.stacks)
+            header = f"Push range, {stacks} bb ({len(solution.push_range())} classes)"
+            footer = "\n".join(
+                f"Caller {caller} calls: "
+                + ", ".join(solution.call_range(caller) or ["nothing"])
+                for caller in range(1, len(solution.calls) + 1)
+            )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.png:
+        args.png.write_bytes(grid_png(shades))
+    if args.json:
+        print(json.dumps(output, indent=2))
+    else:
+        print(header)
+        print(format_grid(labels))
+        print(footer)
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Nash push/fold ranges and charts")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,169 @@
+"""
+Configurable rake model applied when a hand is settled.
+
+A model takes a percentage of each pot up to a cap, optionally skipping hands
+that end before the flop ("no flop, no drop"). Per-stakes tiers override the
+default percentage/cap by big blind, the way most card rooms publish their
+rake tables. Rake is always a whole number of chips, rounded down.
+
+`settle_hand` resolves a hand's pots with `pots.py`, takes the rake, and
+returns a `HandSettlement`; feed settlements into a `RakeLedger` to report
+rake per hand and per stakes.
+
+Example:
+    model = RakeModel.from_dict({"percent": 5, "cap": 300, "tiers": [
+        {"max_big_blind": 100, "percent": 5, "cap": 150},
+    ]})
+    result = model.settle_hand([600, 600], scores=[7, 3], big_blind=100)
+    # result.rake == 60, result.payouts == [1140, 0]
+"""
+
+from __future__ import annotations
+
+import json
+from collections import defaultdict
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional, Sequence
+
+from pots import Pot, Score, build_pots, net_results, return_uncalled, settle
+
+
+@dataclass
+class RakeTier:
+    """Percentage and cap used for stakes up to `max_big_blind`"""
+
+    max_big_blind: float
+    percent: float
+    cap: Optional[int] = None
+
+
+@dataclass
+class HandSettlement:
+    """Outcome of one hand after rake"""
+
+    pots: List[Pot]
+    payouts: List[int]
+    net: List[int]
+    rake: int
+    pot_total: int
+
+
+@dataclass
+class RakeModel:
+    """Percentage-with-cap rake, optionally tiered by big blind"""
+
+    percent: float = 5.0
+    cap: Optional[int] = None
+    no_flop_no_drop: bool = True
+    tiers: List[RakeTier] = field(default_factory=list)
+
+    def __post_init__(self):
+        self.tiers = sorted(self.tiers, key=lambda tier: tier.max_big_blind)
+        for percent in [self.percent] + [tier.percent for tier in self.tiers]:
+            if not 0 <= percent <= 100:
+                raise ValueError(f"Rake percent must be 0-100, got {percent}")
+
+    @classmethod
+    def from_dict(cls, data: Dict) -> "RakeModel":
+        return cls(
+            percent=float(data.get("percent", 5.0)),
+            cap=data.get("cap"),
+            no_flop_no_drop=bool(data.get("no_flop_no_drop", True)),
+            tiers=[
+                RakeTier(
+                    float(tier["max_big_blind"]),
+                    float(tier["percent"]),
+                    tier.get("cap"),
+                )
+                for tier in data.get("tiers", [])
+            ],
+        )
+
+    @classmethod
+    def load(cls, path: Path) -> "RakeModel":
+        return cls.from_dict(json.loads(Path(path).read_text()))
+
+    def terms(self, big_blind: Optional[float] = None) -> RakeTier:
+        """Percentage and cap that apply at the given stakes"""
+        if big_blind is not None:
+            for tier in self.tiers:
+                if big_blind <= tier.max_big_blind:
+                    return tier
+        return RakeTier(float("inf"), self.percent, self.cap)
+
+    def rake_for(
+        self, pot: int, big_blind: Optional[float] = None, saw_flop: bool = True
+    ) -> int:
+        if pot <= 0 or (self.no_flop_no_drop and not saw_flop):
+            return 0
+        terms = self.terms(big_blind)
+        rake = int(pot * terms.percent / 100)
+        if terms.cap is not None:
+            rake = min(rake, int(terms.cap))
+        return rake
+
+    def settle_hand(
+        self,
+        contributions: Sequence[int],
+        scores: Sequence[Score],
+        folded: Sequence[bool] = (),
+        button: int = 0,
+        big_blind: Optional[float] = None,
+        saw_flop: bool = True,
+        split: bool = False,
+        dead: Sequence[int] = (),
+    ) -> HandSettlement:
+        """Build pots, take the rake, and pay the winners"""
+        _, refunded, refund = return_uncalled(contributions, dead)
+        pots = build_pots(contributions, folded, dead)
+        total = sum(pot.amount for pot in pots)
+        rake = self.rake_for(total, big_blind, saw_flop)
+        payouts = settle(pots, scores, button, split, rake)
+        if refund:
+            payouts[refunded] += refund
+        return HandSettlement(
+            pots=pots,
+            payouts=payouts,
+            net=net_results(contributions, payouts),
+            rake=rake,
+            pot_total=total,
+        )
+
+
+@dataclass
+class RakeLedger:
+    """Rake recorded per hand, with per-stakes totals for reporting"""
+
+    hands: List[Dict] = field(default_factory=list)
+
+    def record(
+        self,
+        hand_id: str,
+        settlement: HandSettlement,
+        big_blind: Optional[float] = None,
+    ):
+        self.hands.append(
+            {
+                "hand_id": hand_id,
+                "big_blind": big_blind,
+                "pot": settlement.pot_total,
+                "rake": settlement.rake,
+            }
+        )
+
+    @property
+    def total(self) -> int:
+        return sum(hand["rake"] for hand in self.hands)
+
+    def by_stakes(self) -> Dict[Optional[float], Dict[str, float]]:
+        summary: Dict[Optional[float], Dict[str, float]] = defaultdict(
+            lambda: {"hands": 0, "raked_hands": 0, "pot": 0, "rake": 0}
+        )
+        for hand in self.hands:
+            entry = summary[hand["big_blind"]]
+            entry["hands"] += 1
+            entry["raked_hands"] += 1 if hand["rake"] else 0
+            entry["pot"] += hand["pot"]
+            entry["rake"] += hand["rake"]
+        return dict(summary)
@@ -6,25 +6,276 @@ Every response mirrors the old JSON structure so the viewer/tests can consume it
 - `all`: summary for every combo matching the filters
 - `by_pot_size`, `by_bb_size`, `by_stack_bucket`, `by_tournament_stage`: bucketed views
 
+`GET /api/hands?q=...&page=N` searches the SQLite hand database (`--hands-db`)
+with the `handquery` filter language and returns one page of matches;
+`GET /api/stats?player=Hero&by=position` returns VPIP/PFR/3-bet/c-bet/WTSD
+(`&ev=1` adds all-in EV), `GET /api/lines?player=Hero&street=turn&by=texture`
+returns action frequencies per betting line (`lines.py`, `&min_samples=`),
+`GET /api/graphs/results?player=Hero&unit=bb` returns cumulative net, all-in
+EV, showdown, and non-showdown winnings per hand and
+`GET /api/graphs/tournament?player=Hero&tournament=<id>` the chip stack per
+hand (`graphs.py`; `&format=png` draws the chart instead),
+`GET /api/pace?table=&since=&until=` returns hands per hour and hand
+duration per table session (`pace.py`),
+`POST /api/graphql` ({"query", "variables", "operationName"}, or
+`GET /api/graphql?query=`) answers GraphQL queries over hands, players, and
+sessions (`graphql.py`; `GET /api/graphql/schema` returns the schema),
+and `GET /api/hands/<hand_id>/replay` returns the replayer timeline for one
+hand. `GET /api/bankroll` (balance, history,
+per-stakes win rates) and `GET|POST /api/bankroll/sessions`,
+`POST /api/bankroll/transactions` manage live sessions in the same database.
+Sessions, transactions, and ledger games take a "currency"; `?currency=USD` on
+`/api/bankroll`, `/api/ledger`, and `/api/stats` converts their money at the
+exchange-rate snapshots listed and added at `GET|POST /api/rates`
+({"base", "quote", "rate", "as_of"}).
+`GET|PUT /api/players/<name>/notes` reads or updates a player's notes, color
+label, and tags (`GET /api/players?tag=reg` lists annotated players); the
+stats response carries the notes of the players it covers.
+`GET /api/charts` lists the stored preflop charts (`GET /api/charts/<id>` adds
+per-class frequencies, `POST /api/charts` saves one), and the trainer deals
+spots with `GET /api/trainer/question`, grades them with
+`POST /api/trainer/answer`, and reports accuracy at `GET /api/trainer/stats`.
+The home game ledger lists games and lifetime player results at
+`GET /api/ledger`; `POST /api/ledger/games` starts a game,
+`POST /api/ledger/games/<id>/entries` records a buy-in or cash-out
+({"player", "kind": "buy_in" | "cash_out", "amount"}),
+`GET /api/ledger/games/<id>` returns the nets and the transfers that settle
+them, and `POST /api/ledger/games/<id>/settle` closes a balanced game.
+`GET /api/export?format=xlsx` downloads stats, sessions, and rake as a
+workbook (`format=csv&table=stats` for a single CSV; `columns=stats=a,b`).
+
+`serve --auth` requires an API key on every route but `/health` (`auth.py`):
+the `Authorization: Bearer` or `X-API-Key` header, or `?key=` for
+EventSource streams. Each key has a role (viewer, player, commentator,
+producer, admin) and routes need the least role `auth.required_role` gives
+them, answering 401 without a valid key and 403 with too small a role. Admins
+list and create keys at `GET|POST /api/keys` ({"name", "role"}; the secret is
+in the response only) and revoke them with `DELETE /api/keys/<id>`.
+Exports, hand searches and replays, money routes, and key management are
+recorded in the append-only audit log (`audit.py`), whatever their status,
+which admins query at `GET /api/audit?actor=&action=&since=&until=&limit=`.
+
+`serve --rate-limit N` allows each client address, and each API key, N
+requests a minute (bursts of `--rate-burst`) to the equity, hand search and
+replay, stats, and GraphQL routes, to `/api/jobs` and `/api/solver`, and to
+every PUT and DELETE (`ratelimit.py`); beyond that they get 429 with a
+Retry-After header, so one client cannot tie up the equity workers.
+
+`serve --listen SPEC` (repeatable; it replaces `--host/--port`) binds each
+listener given as `HOST:PORT` or `[IPv6]:PORT`, with `,cert=FILE,key=FILE`
+for TLS on that listener alone. `--admin-listen SPEC` adds listeners that
+serve the admin routes (money, exports, keys, audit); the public listeners
+then answer 404 for them (`listeners.py`).
+
+`GET /api/variance?win_rate=5&std_dev=90&bankroll=3000` runs the `variance`
+risk-of-ruin simulator (capped at MAX_VARIANCE_STEPS simulated 100-hand
+steps) and returns its figures with percentile bands as JSON series.
+
+`GET /api/strength?hand=AsQd&board=Ah7c2d&range=QQ%2B,AK` ranks a holding
+against all live hands, or the range, by current strength (`strength.py`).
+`POST /api/blockers` ({"range", "board", "hero"}) counts the range's combos
+by hand class and its value / bluff split against the hero before and after
+the hero's blockers (`blockers.py`).
+`GET /api/range/heatmap?range=22%2B,ATs%2B&format=svg` draws a range on the
+13x13 grid (`heatmap.py`) as JSON cell shades, SVG (`&title=`), or PNG.
+
+`POST /api/solver` starts a background CFR+ river solve (`solver.py`) from a
+JSON spot (board, oop, ip, pot, stack, bet_sizes, iterations, ...) and
+returns its id; `GET /api/solver/<id>?node=x` reports progress and
+exploitability plus the action frequencies at a node (`&strategy=1` adds the
+full per-combo strategy), all as of the solve's latest progress report.
+
+`POST /api/jobs` queues the same work to outlive the request (`jobs.py`):
+{"kind": "equity", ...the /api/equity body} with a budget up to
+`--job-equity-budget`, or {"kind": "solver", ...the /api/solver spot} with up
+to MAX_JOB_SOLVER_ITERATIONS iterations. It answers 202 with the job's id;
+`GET /api/jobs/<id>` returns its status, progress, and, once done, result,
+`GET /api/jobs/<id>/stream` sends each change as a Server-Sent Event until the
+job ends, `GET /api/jobs?status=&kind=` lists jobs, and `DELETE /api/jobs/<id>`
+cancels one (with `--auth`, only the key that submitted it or an admin may).
+A full queue answers 503 (`jobs.MAX_UNFINISHED_JOBS`, and per key
+`jobs.MAX_UNFINISHED_PER_OWNER`). Jobs run on `--job-workers` threads;
+`serve --jobs FILE` keeps the queue in SQLite, so jobs outlast a restart and
+solves resume from their last checkpoint.
+
+`GET /api/tourney/clock` returns the tournament clock (level, blinds, time
+left, next level and break) and `POST /api/tourney/clock` controls it with
+{"action": "start" | "pause" | "adjust" (+ "seconds") | "level" (+ "index") |
+"next" | "previous"}; `GET|PUT /api/tourney/structure` reads or replaces the
+blind structure (`serve --blind-structure` loads one at startup). Venue
+displays subscribe to `GET /api/tourney/clock/stream`, a Server-Sent Events
+feed of the clock state every second.
+
+`GET /api/tourney/seating` returns the tables, seats, buttons, and big
+blinds; `POST /api/tourney/seating` runs the seat draw
+({"action": "draw", "players": [...], "seats": 9}), busts a player
+({"action": "bust", "player"}, which rebalances and breaks tables and returns
+the moves), seats a late registration ("add"), or sets a table's "button".
+Floor displays follow `GET /api/tourney/seating/stream`: a snapshot, then
+every draw, bust, move, and table break as an SSE event whose id is the
+seating version (`Last-Event-ID` replays what a reconnecting display missed).
+
+`POST /api/payouts` builds a payout table (`payouts.py`) from entrants,
+buy_in, rake, reentries, model (standard | flat | wta | custom with
+percentages), bubble_refunds, and guarantee. `POST /api/deal` takes the
+remaining stacks and payouts (optionally a save per player) and returns the
+ICM chop, chip chop, and save deal numbers (`deal.py`).
+
+Provably fair dealing (`fairdeal.py`): `POST /api/fairdeal` shuffles a deck
+and returns only its id and sha256 commitment; the game host then posts the
+players' `client_seed` to `/api/fairdeal/<id>/seed`, draws cards with
+`POST /api/fairdeal/<id>/deal` ({"count": 2}), and ends the hand with
+`POST /api/fairdeal/<id>/reveal`, which publishes the server seed and deck.
+Only the API key (or, without `--auth`, the client address) that opened a
+hand may deal from or reveal it; anyone else gets 403.
+`POST /api/fairdeal/verify` checks a revealed hand for anyone auditing it.
+
+`POST /api/equity` runs the equity engine on a JSON body of players (hands or
+range notation), board, dead cards, and game on `--equity-workers`
+processes. Each request is capped by the server's compute budget
+(`--equity-budget`): exhaustive enumeration only happens below it, and
+Monte Carlo never runs more iterations than it allows; "target_ci" stops
+sampling early once every player's 95% interval is that tight.
+`POST /api/equity/stream` takes the same body and answers with Server-Sent
+Events: a "progress" event with the running result after each round of
+Monte Carlo shards, then the final "result". Results are cached by
+their suit-isomorphic form (`equity_cache.py`, `--equity-cache-size`, and
+`--equity-cache FILE` to keep them across restarts), so a repeat matchup, or
+the same one with other suits, is answered without running the engine; the
+response's "cached" flag says which.
+
 Example:
     python3 range_query_service.py serve --db range_analysis.duckdb --port 8080
     curl "http://localhost:8080/ranges?position=BTN&stage=preflop&action=raise"
+    curl -X POST localhost:8080/api/equity \\
+        -d '{"players": ["AsKs", "QQ+"], "board": "Ah7c2d", "iterations": 50000}'
+    curl "http://localhost:8080/api/hands?q=position%3DBTN%20and%20pot%3E50bb"
+    curl "http://localhost:8080/api/variance?win_rate=5&std_dev=90&bankroll=3000"
 """
 
 from __future__ import annotations
 
 import argparse
 import json
-import duckdb
+import math
+import re
+import threading
+import time
+import uuid
 from dataclasses import dataclass
-from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from datetime import datetime
+from http.server import BaseHTTPRequestHandler
 from pathlib import Path
 from statistics import median
-from typing import Dict, List, Optional, Tuple
-from urllib.parse import parse_qs, urlparse
+from typing import Callable, Dict, List, Optional, Sequence, Tuple
+from urllib.parse import parse_qs, unquote, urlparse
+
+from audit import ANONYMOUS, DEFAULT_ENTRIES, AuditLog, sensitive_action
+from auth import ApiKey, ApiKeys, allows, required_role
+from bankroll import Bankroll
+from blockers import blocker_report
+from cards import format_cards
+from charts import Chart, ChartBook, Trainer
+from deal import calculate_deal
+from equity import EquityResult, calculate_equity, parse_player_arg
+from equity_cache import DEFAULT_CACHE_SIZE, EquityCache
+from export import CONTENT_TYPES, TABLES, export, parse_columns
+from fairdeal import FairHand, verify
+from graphql import execute, schema_sdl
+from graphs import Series, line_chart_png, load_results, load_tournament
+from hand_range import Range
+from handdb import HandDB
+from handquery import DEFAULT_PER_PAGE, search
+from heatmap import Heatmap
+from jobs import (
+    DEFAULT_LIST_LIMIT,
+    JobCancelled,
+    JobControl,
+    JobKind,
+    JobQueue,
+    QueueFull,
+)
+from ledger import Ledger
+from lines import DEFAULT_MIN_SAMPLES, filter_samples, lines_to_dict, load_lines
+from listeners import Listener, check_listeners, make_servers, parse_listener
+from money import ExchangeRates
+from pace import load_pace
+from payouts import (
+    DEFAULT_MIN_CASH,
+    DEFAULT_PAID_FRACTION,
+    DEFAULT_ROUNDING,
+    calculate_payouts,
+)
+from players import PlayerNotes
+from ratelimit import DEFAULT_BURST, RateLimiter, is_limited
+from replay import build_replay, find_hand
+from solver import DEFAULT_REPORT_EVERY, Solver, SolverConfig
+from stats import load_stats, stats_to_dict
+from strength import hand_strength
+from seating import Seating
+from tourney import BlindStructure, TournamentClock, standard_structure
+from variance import DEFAULT_HANDS, DEFAULT_TARGET_RISK, HANDS_PER_STEP, simulate
 
 
 HAND_RANK_ORDER = "AKQJT98765432"
+# Outcomes (enumerated or sampled) a single /api/equity request may evaluate
+DEFAULT_EQUITY_BUDGET = 500_000
+MAX_REQUEST_BYTES = 64 * 1024
+# Trajectories x 100-hand steps a single /api/variance request may simulate
+MAX_VARIANCE_STEPS = 2_000_000
+# Iterations one /api/solver job may run, solves running at once, and
+# finished jobs kept for polling
+MAX_SOLVER_ITERATIONS = 5_000
+MAX_SOLVER_JOBS = 2
+MAX_SOLVER_HISTORY = 20
+SOLVER_PATH = re.compile(r"^/api/solver/([^/]+)$")
+# Queued jobs may run far longer than a request: budgets for those, seconds
+# between solver checkpoints, and between checks for news on a job's stream
+DEFAULT_JOB_EQUITY_BUDGET = 50_000_000
+MAX_JOB_SOLVER_ITERATIONS = 100_000
+JOB_CHECKPOINT_INTERVAL = 30.0
+JOB_STREAM_INTERVAL = 0.5
+JOB_PATH = re.compile(r"^/api/jobs/([0-9a-f]+)(/stream)?$")
+# Open (and recently revealed) fair-deal hands kept in memory
+MAX_FAIR_HANDS = 200
+FAIR_HAND_PATH = re.compile(r"^/api/fairdeal/([0-9a-f]+)$")
+FAIR_ACTION_PATH = re.compile(r"^/api/fairdeal/([0-9a-f]+)/(seed|deal|reveal)$")
+# Seconds between tournament clock updates on the event stream
+CLOCK_STREAM_INTERVAL = 1.0
+# Seconds between checks for new seating events on their stream
+SEATING_STREAM_INTERVAL = 0.5
+REPLAY_PATH = re.compile(r"^/api/hands/([^/]+)/replay$")
+NOTES_PATH = re.compile(r"^/api/players/([^/]+)/notes$")
+CHART_PATH = re.compile(r"^/api/charts/(\d+)$")
+LEDGER_GAME_PATH = re.compile(r"^/api/ledger/games/(\d+)$")
+LEDGER_POST_PATH = re.compile(r"^/api/ledger/games/(\d+)/(entries|settle)$")
+KEY_PATH = re.compile(r"^/api/keys/(\d+)$")
+HAND_DB_GET_PATTERNS = (REPLAY_PATH, NOTES_PATH, CHART_PATH, LEDGER_GAME_PATH)
+HAND_DB_GET_PATHS = (
+    "/api/hands",
+    "/api/stats",
+    "/api/lines",
+    "/api/graphs/results",
+    "/api/graphs/tournament",
+    "/api/bankroll",
+    "/api/bankroll/sessions",
+    "/api/export",
+    "/api/players",
+    "/api/charts",
+    "/api/trainer/question",
+    "/api/trainer/stats",
+    "/api/ledger",
+    "/api/rates",
+    "/api/keys",
+    "/api/audit",
+    "/api/pace",
+    "/api/graphql",
+    "/api/graphql/schema",
+)
+BANKROLL_POST_PATHS = ("/api/bankroll/sessions", "/api/bankroll/transactions")
+CHART_POST_PATHS = ("/api/charts", "/api/trainer/answer")
+ALLOWED_METHODS = "GET, POST, PUT, DELETE, OPTIONS"
+ALLOWED_HEADERS = "Content-Type, Authorization, X-API-Key"
 
 
 def hand_rank_key(hand: str) -> Tuple[int, int]:
@@ -91,6 +342,9 @@ class RangeQueryService:
         if not (filters.position and filters.stage and filters.action):
             raise ValueError("position, stage, and action filters are required")
 
+        # Imported here so the rest of pokertools runs without duckdb installed
+        import duckdb
+
         with duckdb.connect(self.db_path.as_posix()) as conn:
             where_clause, params = self._build_where(filters)
 
@@ -194,25 +448,1000 @@ class RangeQueryService:
         }
 
 
+class EquityService:
+    """Runs equity requests within a per-request compute budget."""
+
+    def __init__(
+        self,
+        budget: int = DEFAULT_EQUITY_BUDGET,
+        workers: int = 1,
+        cache: Optional[EquityCache] = None,
+    ):
+        if budget <= 0:
+            raise ValueError("Equity budget must be positive")
+        self.budget = budget
+        self.workers = workers
+        self.cache = cache
+
+    def compute(
+        self,
+        payload: Dict,
+        progress: Optional[Callable[[EquityResult], None]] = None,
+    ) -> Dict:
+        specs, request = self.prepare(payload)
+        if self.cache is not None:
+            result, cached = self.cache.calculate(specs, **request, progress=progress)
+        else:
+            result = calculate_equity(specs, **request, progress=progress)
+            cached = False
+        response = result.to_dict()
+        response["budget"] = request["exhaustive_limit"]
+        response["cached"] = cached
+        return response
+
+    def prepare(self, payload: Dict) -> Tuple[List, Dict]:
+        """Checked (player specs, engine arguments) for a JSON equity request"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        players = payload.get("players")
+        if not isinstance(players, list) or len(players) < 2:
+            raise ValueError("players must be a list of at least two hands/ranges")
+
+        budget = self.budget
+        if payload.get("budget") is not None:
+            budget = min(budget, self._positive_int(payload["budget"], "budget"))
+        iterations = budget
+        if payload.get("iterations") is not None:
+            iterations = min(
+                budget, self._positive_int(payload["iterations"], "iterations")
+            )
+
+        seed = payload.get("seed")
+        if seed is not None and (isinstance(seed, bool) or not isinstance(seed, int)):
+            raise ValueError("seed must be an integer")
+        target_ci = payload.get("target_ci")
+        if target_ci is not None and (
+            isinstance(target_ci, bool)
+            or not isinstance(target_ci, (int, float))
+            or target_ci <= 0
+        ):
+            raise ValueError("target_ci must be a positive number")
+
+        request = dict(
+            board=str(payload.get("board") or ""),
+            dead=str(payload.get("dead") or ""),
+            game=str(payload.get("game") or "holdem"),
+            iterations=iterations,
+            exhaustive_limit=budget,
+            workers=self.workers,
+            seed=seed,
+            target_ci=target_ci,
+        )
+        return [self._player(player) for player in players], request
+
+    @staticmethod
+    def _player(spec):
+        """A hand/range string, or a list of [combo, weight] pairs"""
+        if isinstance(spec, str):
+            return parse_player_arg(spec)
+        if isinstance(spec, list) and all(
+            isinstance(item, list) and len(item) == 2 for item in spec
+        ):
+            return [(str(combo), float(weight)) for combo, weight in spec]
+        raise ValueError(f"Invalid player: {spec!r}")
+
+    @staticmethod
+    def _positive_int(value, name: str) -> int:
+        if isinstance(value, bool) or not isinstance(value, int) or value <= 0:
+            raise ValueError(f"{name} must be a positive integer")
+        return value
+
+
+def solver_spot(
+    payload: Dict, max_iterations: int
+) -> Tuple[SolverConfig, int, float]:
+    """(config, iterations, target exploitability) from a JSON solver spot"""
+    if not isinstance(payload, dict):
+        raise ValueError("Request body must be a JSON object")
+    iterations = payload.get("iterations", 300)
+    if isinstance(iterations, bool) or not isinstance(iterations, int):
+        raise ValueError("iterations must be an integer")
+    if not 0 < iterations <= max_iterations:
+        raise ValueError(f"iterations must be 1-{max_iterations}")
+    try:
+        config = SolverConfig(
+            str(payload["board"]),
+            [str(payload["oop"]), str(payload["ip"])],
+            float(payload["pot"]),
+            float(payload.get("stack") or 0),
+            **{
+                key: payload[key]
+                for key in (
+                    "bet_sizes",
+                    "raise_sizes",
+                    "max_raises",
+                    "allin",
+                    "buckets",
+                )
+                if key in payload
+            },
+        )
+    except KeyError as exc:
+        raise ValueError(f"{exc.args[0]} is required") from None
+    except TypeError as exc:
+        raise ValueError(str(exc)) from None
+    return config, iterations, float(payload.get("target") or 0)
+
+
+def _solver_snapshot(solver: Solver) -> Dict:
+    """A solve's results as of its latest report, for polls to read"""
+    return {
+        "iteration": solver.iteration,
+        "progress": solver.last_progress.to_dict(),
+        "nodes": {
+            path: solver.summary(path)
+            for path, node in solver.nodes.items()
+            if node.player is not None
+        },
+        "strategy": solver.strategy(),
+    }
+
+
+class SolverService:
+    """Runs solver jobs on background threads; clients poll for progress.
+
+    The solving thread updates regrets in place, so polls read a snapshot it
+    takes at each progress report rather than the solver itself.
+    """
+
+    def __init__(
+        self,
+        max_iterations: int = MAX_SOLVER_ITERATIONS,
+        max_jobs: int = MAX_SOLVER_JOBS,
+    ):
+        self.max_iterations = max_iterations
+        self.max_jobs = max_jobs
+        self.jobs: Dict[str, Dict] = {}
+        self.lock = threading.Lock()
+
+    def start(self, payload: Dict) -> Dict:
+        config, iterations, target = solver_spot(payload, self.max_iterations)
+        solver = Solver(config)
+        with self.lock:
+            statuses = [job["status"] for job in self.jobs.values()]
+            if statuses.count("running") >= self.max_jobs:
+                raise ValueError(f"{self.max_jobs} solves are already running")
+            job_id = uuid.uuid4().hex[:12]
+            job = {
+                "status": "running",
+                "solver": solver,
+                "error": None,
+                "snapshot": None,
+            }
+            self.jobs[job_id] = job
+            # Forget the oldest finished jobs
+            finished = [
+                key for key, old in self.jobs.items() if old["status"] != "running"
+            ]
+            for key in finished[: max(0, len(self.jobs) - MAX_SOLVER_HISTORY)]:
+                del self.jobs[key]
+
+        def report(progress):
+            snapshot = _solver_snapshot(solver)
+            with self.lock:
+                job["snapshot"] = snapshot
+
+        def work():
+            try:
+                solver.run(iterations, report, DEFAULT_REPORT_EVERY, target)
+                status, error = "done", None
+            except Exception as exc:  # pylint: disable=broad-except
+                status, error = "failed", str(exc)
+            with self.lock:
+                job["status"], job["error"] = status, error
+
+        threading.Thread(target=work, daemon=True).start()
+        return {"id": job_id, "status": "running", "iterations": iterations}
+
+    def status(self, job_id: str, query: Dict[str, List[str]]) -> Dict:
+        with self.lock:
+            job = self.jobs.get(job_id)
+            if job is None:
+                raise KeyError(f"No solver job {job_id!r}")
+            status, error, snapshot = job["status"], job["error"], job["snapshot"]
+        response = {
+            "id": job_id,
+            "status": status,
+            "iteration": snapshot["iteration"] if snapshot else 0,
+            "progress": snapshot["progress"] if snapshot else None,
+        }
+        if error:
+            response["error"] = error
+        if snapshot:
+            node = query.get("node", [""])[0]
+            if node not in snapshot["nodes"]:
+                # Unknown paths raise here; the rest are terminal nodes
+                job["solver"].node(node)
+                raise ValueError(f"{node!r} is a terminal node")
+            response["node"] = snapshot["nodes"][node]
+            if query.get("strategy", ["0"])[0] not in ("", "0", "false"):
+                response["strategy"] = snapshot["strategy"]
+        return response
+
+
+class JobService:
+    """Queued equity and solver jobs (`jobs.py`), which outlive requests."""
+
+    def __init__(
+        self,
+        equity_service: EquityService,
+        path: Optional[Path] = None,
+        workers: int = 1,
+        equity_budget: int = DEFAULT_JOB_EQUITY_BUDGET,
+        max_iterations: int = MAX_JOB_SOLVER_ITERATIONS,
+    ):
+        # Same engine workers and cache as /api/equity, with a bigger budget
+        self.equity_service = EquityService(
+            equity_budget, equity_service.workers, equity_service.cache
+        )
+        self.max_iterations = max_iterations
+        self.queue = JobQueue(
+            {
+                "equity": JobKind(self._check_equity, self._run_equity),
+                "solver": JobKind(self._check_solver, self._run_solver),
+            },
+            path,
+            workers,
+        )
+
+    def submit(self, payload: Dict, owner: Optional[str] = None) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        params = {key: value for key, value in payload.items() if key != "kind"}
+        job = self.queue.submit(str(payload.get("kind") or ""), params, owner)
+        return job.to_dict(result=False)
+
+    def jobs(self, query: Dict[str, List[str]]) -> Dict:
+        try:
+            limit = int(query.get("limit", [DEFAULT_LIST_LIMIT])[0])
+        except ValueError:
+            raise ValueError("limit must be an integer") from None
+        jobs = self.queue.jobs(
+            query.get("status", [None])[0], query.get("kind", [None])[0], limit
+        )
+        return {"jobs": [job.to_dict(result=False) for job in jobs]}
+
+    def job(self, job_id: str) -> Dict:
+        return self.queue.get(job_id).to_dict()
+
+    def cancel(self, job_id: str, owner: Optional[str] = None) -> Dict:
+        return self.queue.cancel(job_id, owner).to_dict(result=False)
+
+    def _check_equity(self, params: Dict) -> Dict:
+        self.equity_service.prepare(params)
+        return params
+
+    def _run_equity(self, params: Dict, control: JobControl) -> Dict:
+        return self.equity_service.compute(
+            params, lambda partial: control.report(partial.to_dict())
+        )
+
+    def _check_solver(self, params: Dict) -> Dict:
+        solver_spot(params, self.max_iterations)
+        return params
+
+    def _run_solver(self, params: Dict, control: JobControl) -> Dict:
+        config, iterations, target = solver_spot(params, self.max_iterations)
+        if control.checkpoint:
+            state = json.loads(control.checkpoint)
+            solver = Solver.from_state(state, "The job's checkpoint")
+        else:
+            solver = Solver(config)
+        saved = time.monotonic()
+
+        def report(progress):
+            nonlocal saved
+            checkpoint = None
+            if time.monotonic() - saved >= JOB_CHECKPOINT_INTERVAL:
+                checkpoint, saved = json.dumps(solver.state()), time.monotonic()
+            control.report(progress.to_dict(), checkpoint)
+
+        if solver.iteration < iterations:
+            solver.run(
+                iterations - solver.iteration,
+                report,
+                DEFAULT_REPORT_EVERY,
+                target,
+                control.cancelled,
+            )
+        if control.cancelled():
+            raise JobCancelled(control.job.id)
+        return {
+            "iteration": solver.iteration,
+            "progress": solver.last_progress.to_dict()
+            if solver.last_progress
+            else None,
+            "node": solver.summary(""),
+            "strategy": solver.strategy(),
+        }
+
+
+class FairDealService:
+    """Committed hands between the shuffle and the reveal."""
+
+    def __init__(self, max_hands: int = MAX_FAIR_HANDS):
+        self.max_hands = max_hands
+        self.hands: Dict[str, FairHand] = {}
+        self.lock = threading.Lock()
+
+    def new_hand(self, payload: Dict, owner: Optional[str] = None) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        hand = FairHand.new(bool(payload.get("short_deck")), owner)
+        with self.lock:
+            self.hands[hand.id] = hand
+            # Forget the oldest hands, revealed ones first
+            for key in sorted(self.hands, key=lambda key: not self.hands[key].revealed):
+                if len(self.hands) <= self.max_hands:
+                    break
+                del self.hands[key]
+        return hand.to_dict()
+
+    def hand(self, hand_id: str) -> FairHand:
+        hand = self.hands.get(hand_id)
+        if hand is None:
+            raise KeyError(f"No hand {hand_id!r}")
+        return hand
+
+    def act(
+        self, hand_id: str, action: str, payload: Dict, owner: Optional[str] = None
+    ) -> Dict:
+        """Seed, deal from, or reveal a hand; only its owner deals or reveals"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        with self.lock:
+            hand = self.hand(hand_id)
+            if action != "seed":
+                hand.check_owner(owner)
+            if action == "seed":
+                if payload.get("client_seed") is None:
+                    raise ValueError("client_seed is required")
+                hand.seed(str(payload["client_seed"]))
+                return hand.to_dict()
+            if action == "deal":
+                count = payload.get("count", 1)
+                if isinstance(count, bool) or not isinstance(count, int):
+                    raise ValueError("count must be an integer")
+                cards = hand.deal(count)
+                return {**hand.to_dict(), "cards": format_cards(cards)}
+            return hand.reveal()
+
+    @staticmethod
+    def verify(payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        missing = [
+            name
+            for name in ("commitment", "server_seed", "deck")
+            if not payload.get(name)
+        ]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        return verify(
+            str(payload["commitment"]),
+            str(payload["server_seed"]),
+            str(payload["deck"]),
+            str(payload.get("client_seed") or ""),
+            str(payload.get("dealt") or ""),
+        )
+
+
+class TourneyService:
+    """The tournament clock and seating shared by every request."""
+
+    def __init__(self, structure: Optional[BlindStructure] = None):
+        self.clock = TournamentClock(structure or standard_structure())
+        self.seating = Seating()
+
+    def control(self, payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        action = payload.get("action")
+        try:
+            if action in ("start", "resume"):
+                return self.clock.start()
+            if action == "pause":
+                return self.clock.pause()
+            if action == "adjust":
+                return self.clock.adjust(float(payload["seconds"]))
+            if action == "level":
+                return self.clock.set_level(int(payload["index"]))
+            if action == "next":
+                return self.clock.next_level()
+            if action == "previous":
+                return self.clock.previous_level()
+        except KeyError as exc:
+            raise ValueError(f"{action} needs {exc.args[0]}") from None
+        except TypeError:
+            raise ValueError(f"Invalid value for {action}") from None
+        raise ValueError(
+            "action must be start, pause, resume, adjust, level, next, or previous"
+        )
+
+    def structure(self) -> Dict:
+        return self.clock.structure.to_dict()
+
+    def seat(self, payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        action = payload.get("action")
+        required = {
+            "draw": ("players",),
+            "bust": ("player",),
+            "add": ("player",),
+            "button": ("table", "seat"),
+        }
+        if action not in required:
+            raise ValueError("action must be draw, bust, add, or button")
+        missing = [name for name in required[action] if payload.get(name) is None]
+        if missing:
+            raise ValueError(f"{action} needs {', '.join(missing)}")
+        moves = []
+        try:
+            if action == "draw":
+                if not isinstance(payload["players"], list):
+                    raise ValueError("players must be a list of names")
+                self.seating.draw(payload["players"], payload.get("seats"))
+            elif action == "bust":
+                moves = self.seating.bust(str(payload["player"]))
+            elif action == "add":
+                self.seating.add(str(payload["player"]))
+            else:
+                self.seating.set_button(int(payload["table"]), int(payload["seat"]))
+        except TypeError:
+            raise ValueError(f"Invalid value for {action}") from None
+        return {
+            **self.seating.snapshot(),
+            "moves": [move.to_dict() for move in moves],
+        }
+
+    def replace_structure(self, payload: Dict) -> Dict:
+        """Load a new structure; the clock restarts paused at level 1"""
+        self.clock.load(BlindStructure.from_dict(payload))
+        return self.clock.snapshot()
+
+
+class HandDBService:
+    """Hand DB endpoints; opens SQLite per request (thread safety)."""
+
+    def __init__(self, db_path: Path):
+        self.db_path = Path(db_path)
+        if not self.db_path.exists():
+            raise FileNotFoundError(f"Hand database {self.db_path} not found.")
+
+    def search(self, query: Dict[str, List[str]]) -> Dict:
+        def get_int(name: str, default: int) -> int:
+            value = query.get(name, [None])[0]
+            if value is None:
+                return default
+            try:
+                return int(value)
+            except ValueError:
+                raise ValueError(f"Invalid integer for {name}: {value}") from None
+
+        with HandDB(self.db_path) as db:
+            return search(
+                db,
+                query.get("q", [""])[0],
+                page=get_int("page", 1),
+                per_page=get_int("per_page", DEFAULT_PER_PAGE),
+            )
+
+    def stats(self, query: Dict[str, List[str]]) -> Dict:
+        players = [name for name in query.get("player", []) if name]
+        by = query.get("by", [None])[0] or None
+        ev = query.get("ev", ["0"])[0] in ("1", "true")
+        currency = query.get("currency", [None])[0]
+        with HandDB(self.db_path) as db:
+            table = load_stats(db, players, by, ev, currency)
+            notes = PlayerNotes(db).lookup(table)
+        return {"players": stats_to_dict(table), "notes": notes}
+
+    def lines(self, query: Dict[str, List[str]]) -> Dict:
+        players = [name for name in query.get("player", []) if name]
+        street = query.get("street", [None])[0] or None
+        by = query.get("by", [None])[0] or None
+        try:
+            min_samples = int(query.get("min_samples", [DEFAULT_MIN_SAMPLES])[0])
+        except ValueError:
+            raise ValueError("min_samples must be an integer") from None
+        with HandDB(self.db_path) as db:
+            table = load_lines(db, players, street, by)
+        return {"spots": lines_to_dict(filter_samples(table, min_samples))}
+
+    def graph(self, kind: str, query: Dict[str, List[str]]) -> Tuple[Series, bool]:
+        """(series, draw as PNG) for /api/graphs/results and /tournament"""
+        player = query.get("player", [""])[0]
+        if not player:
+            raise ValueError("player is required")
+        png = query.get("format", ["json"])[0] == "png"
+        with HandDB(self.db_path) as db:
+            if kind == "results":
+                unit = query.get("unit", ["money"])[0]
+                series = load_results(db, player, unit)
+            else:
+                tournament_id = query.get("tournament", [""])[0]
+                if not tournament_id:
+                    raise ValueError("tournament is required")
+                series = load_tournament(db, player, tournament_id)
+        if not series.hands:
+            raise KeyError(f"No hands for {player}")
+        return series, png
+
+    def pace(self, query: Dict[str, List[str]]) -> Dict:
+        def get(name: str) -> Optional[str]:
+            return query.get(name, [None])[0]
+
+        with HandDB(self.db_path) as db:
+            sessions = load_pace(db, get("table"), get("since"), get("until"))
+        return {"sessions": [session.to_dict() for session in sessions]}
+
+    def graphql(self, payload: Dict) -> Tuple[int, Dict]:
+        """(status, response): 400 when the query has errors"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        query = payload.get("query")
+        variables = payload.get("variables") or {}
+        if not isinstance(query, str) or not isinstance(variables, dict):
+            raise ValueError("query must be a string and variables an object")
+        with HandDB(self.db_path) as db:
+            response = execute(db, query, variables, payload.get("operationName"))
+        return (400 if "errors" in response else 200), response
+
+    def graphql_get(self, query: Dict[str, List[str]]) -> Tuple[int, Dict]:
+        try:
+            variables = json.loads(query.get("variables", ["{}"])[0] or "{}")
+        except json.JSONDecodeError:
+            raise ValueError("variables must be a JSON object") from None
+        payload = {
+            "query": query.get("query", [None])[0],
+            "variables": variables,
+            "operationName": query.get("operationName", [None])[0],
+        }
+        return self.graphql(payload)
+
+    def replay(self, hand_id: str, query: Dict[str, List[str]]) -> Dict:
+        site = query.get("site", [None])[0]
+        equity = query.get("equity", ["1"])[0] not in ("0", "false")
+        with HandDB(self.db_path) as db:
+            hand = find_hand(db, hand_id, site)
+        frames = build_replay(hand, equity)
+        return {
+            "site": hand.site,
+            "hand_id": hand.hand_id,
+            "frames": [frame.to_dict() for frame in frames],
+        }
+
+    def export(self, query: Dict[str, List[str]]) -> Tuple[bytes, str]:
+        """(file contents, format) for /api/export"""
+        fmt = query.get("format", ["csv"])[0]
+        default_tables = list(TABLES) if fmt == "xlsx" else ["stats"]
+        tables = [name for name in query.get("table", []) if name] or default_tables
+        columns = parse_columns(query.get("columns", []))
+        players = [name for name in query.get("player", []) if name]
+        by = query.get("by", [None])[0] or None
+        with HandDB(self.db_path) as db:
+            return export(db, fmt, tables, columns, players, by), fmt
+
+    def players(self, query: Dict[str, List[str]]) -> Dict:
+        tag = query.get("tag", [None])[0]
+        with HandDB(self.db_path) as db:
+            notes = PlayerNotes(db).all(tag)
+        return {"players": [note.to_dict() for note in notes]}
+
+    def player_notes(self, name: str) -> Dict:
+        with HandDB(self.db_path) as db:
+            note = PlayerNotes(db).get(name)
+        if note is None:
+            raise KeyError(f"No notes for player {name!r}")
+        return note.to_dict()
+
+    def update_player_notes(self, name: str, payload: Dict) -> Dict:
+        """Apply the fields present in a JSON body; null/"" color clears it"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        tags = payload.get("tags")
+        if tags is not None and not isinstance(tags, list):
+            raise ValueError("tags must be a list of strings")
+        notes = payload.get("notes")
+        with HandDB(self.db_path) as db:
+            return (
+                PlayerNotes(db)
+                .update(
+                    name,
+                    None if notes is None else str(notes),
+                    payload.get("color") or None,
+                    tags,
+                    clear_color="color" in payload and not payload["color"],
+                )
+                .to_dict()
+            )
+
+    def charts(self, query: Dict[str, List[str]]) -> Dict:
+        with HandDB(self.db_path) as db:
+            charts = ChartBook(db).all(
+                query.get("position", [None])[0], query.get("situation", [None])[0]
+            )
+        return {"charts": [chart.to_dict() for chart in charts]}
+
+    def chart(self, chart_id: int) -> Dict:
+        with HandDB(self.db_path) as db:
+            chart = ChartBook(db).get(chart_id)
+        return {**chart.to_dict(), "grid": chart.grid()}
+
+    def save_chart(self, payload: Dict) -> Dict:
+        with HandDB(self.db_path) as db:
+            return ChartBook(db).save(Chart.from_dict(payload)).to_dict()
+
+    def trainer_question(self, query: Dict[str, List[str]]) -> Dict:
+        with HandDB(self.db_path) as db:
+            return Trainer(db).question(
+                query.get("position", [None])[0], query.get("situation", [None])[0]
+            )
+
+    def trainer_answer(self, payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        missing = [
+            name for name in ("chart_id", "hand", "action") if not payload.get(name)
+        ]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        with HandDB(self.db_path) as db:
+            return Trainer(db).answer(
+                int(payload["chart_id"]), str(payload["hand"]), str(payload["action"])
+            )
+
+    def trainer_stats(self) -> Dict:
+        with HandDB(self.db_path) as db:
+            return Trainer(db).accuracy()
+
+    def ledger(self, query: Dict[str, List[str]]) -> Dict:
+        currency = query.get("currency", [None])[0]
+        with HandDB(self.db_path) as db:
+            ledger = Ledger(db)
+            return {
+                "games": [game.to_dict() for game in ledger.games()],
+                "players": ledger.players(currency),
+            }
+
+    def ledger_game(self, game_id: int) -> Dict:
+        with HandDB(self.db_path) as db:
+            return Ledger(db).game(game_id).to_dict()
+
+    def record_ledger(self, game_id: Optional[int], action: str, payload: Dict) -> Dict:
+        """Start a game (no id), add an entry to one, or settle it"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        with HandDB(self.db_path) as db:
+            ledger = Ledger(db)
+            if game_id is None:
+                game_id = ledger.create_game(
+                    str(payload.get("name") or ""),
+                    payload.get("played_at"),
+                    str(payload.get("currency") or ""),
+                )
+                return ledger.game(game_id).to_dict()
+            if action == "settle":
+                return ledger.settle(game_id).to_dict()
+            missing = [
+                name for name in ("player", "kind", "amount") if not payload.get(name)
+            ]
+            if missing:
+                raise ValueError(f"Missing fields: {', '.join(missing)}")
+            return ledger.add_entry(
+                game_id,
+                str(payload["player"]),
+                str(payload["kind"]),
+                float(payload["amount"]),
+                payload.get("at"),
+            ).to_dict()
+
+    def bankroll(self, query: Dict[str, List[str]]) -> Dict:
+        currency = query.get("currency", [None])[0]
+        with HandDB(self.db_path) as db:
+            bankroll = Bankroll(db)
+            return {
+                **bankroll.summary(currency),
+                "history": bankroll.history(currency),
+            }
+
+    def rates(self) -> Dict:
+        with HandDB(self.db_path) as db:
+            snapshots = ExchangeRates(db).snapshots()
+        return {"rates": [snapshot.to_dict() for snapshot in snapshots]}
+
+    def add_rate(self, payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        missing = [name for name in ("base", "quote", "rate") if not payload.get(name)]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        with HandDB(self.db_path) as db:
+            snapshot = ExchangeRates(db).set_rate(
+                str(payload["base"]),
+                str(payload["quote"]),
+                float(payload["rate"]),
+                payload.get("as_of"),
+            )
+        return snapshot.to_dict()
+
+    def authenticate(self, secret: Optional[str]) -> Optional[ApiKey]:
+        with HandDB(self.db_path) as db:
+            return ApiKeys(db).authenticate(secret)
+
+    def api_keys(self) -> Dict:
+        with HandDB(self.db_path) as db:
+            keys = ApiKeys(db).list()
+        return {"keys": [key.to_dict() for key in keys]}
+
+    def create_key(self, payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        with HandDB(self.db_path) as db:
+            key, secret = ApiKeys(db).create(
+                str(payload.get("name") or ""), str(payload.get("role") or "")
+            )
+        return {**key.to_dict(), "key": secret}
+
+    def revoke_key(self, key_id: int) -> Dict:
+        with HandDB(self.db_path) as db:
+            return ApiKeys(db).revoke(key_id).to_dict()
+
+    def audit(self, key: Optional[ApiKey], method: str, action: str, **request):
+        """Append one audit entry for a request by `key` (None: no key)"""
+        with HandDB(self.db_path) as db:
+            AuditLog(db).record(
+                key.name if key else ANONYMOUS,
+                method,
+                action,
+                key_id=key.id if key else None,
+                **request,
+            )
+
+    def audit_entries(self, query: Dict[str, List[str]]) -> Dict:
+        def get(name: str) -> Optional[str]:
+            return query.get(name, [None])[0]
+
+        with HandDB(self.db_path) as db:
+            entries = AuditLog(db).entries(
+                get("actor"),
+                get("action"),
+                get("since"),
+                get("until"),
+                int(get("limit") or DEFAULT_ENTRIES),
+            )
+        return {"entries": [entry.to_dict() for entry in entries]}
+
+    def bankroll_sessions(self) -> Dict:
+        with HandDB(self.db_path) as db:
+            sessions = Bankroll(db).sessions()
+        return {"sessions": [session.to_dict() for session in sessions]}
+
+    def record_bankroll(self, path: str, payload: Dict) -> Dict:
+        """Create a session or a deposit/withdrawal from a JSON body"""
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        required = ("amount",)
+        if path == "/api/bankroll/sessions":
+            required = ("start", "end", "buy_in", "cash_out")
+        missing = [name for name in required if payload.get(name) is None]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        with HandDB(self.db_path) as db:
+            bankroll = Bankroll(db)
+            if path == "/api/bankroll/transactions":
+                currency = str(payload.get("currency") or "")
+                bankroll.add_transaction(
+                    float(payload["amount"]),
+                    payload.get("at") or datetime.now().isoformat(" "),
+                    str(payload.get("note") or ""),
+                    currency,
+                )
+                return {"balance": bankroll.balance(currency or None)}
+            return bankroll.add_session(
+                payload["start"],
+                payload["end"],
+                float(payload["buy_in"]),
+                float(payload["cash_out"]),
+                payload.get("stakes"),
+                str(payload.get("venue") or ""),
+                str(payload.get("game") or ""),
+                payload.get("hands"),
+                str(payload.get("notes") or ""),
+                str(payload.get("currency") or ""),
+            ).to_dict()
+
+
 class _APIRequestHandler(BaseHTTPRequestHandler):
-    """HTTP handler that serves /ranges and /health endpoints."""
+    """HTTP handler for /ranges, /api/hands, /api/stats, /api/equity, /health."""
 
-    def __init__(self, service: RangeQueryService, *args, **kwargs):
+    def __init__(
+        self,
+        service: RangeQueryService,
+        equity_service: EquityService,
+        hand_service: Optional[HandDBService],
+        solver_service: SolverService,
+        tourney_service: TourneyService,
+        fairdeal_service: FairDealService,
+        job_service: JobService,
+        require_auth: bool,
+        rate_limiter: Optional[RateLimiter],
+        *args,
+        **kwargs,
+    ):
         self.service = service
+        self.equity_service = equity_service
+        self.hand_service = hand_service
+        self.solver_service = solver_service
+        self.tourney_service = tourney_service
+        self.fairdeal_service = fairdeal_service
+        self.job_service = job_service
+        self.require_auth = require_auth
+        self.rate_limiter = rate_limiter
+        # The key that authorized the current request, when auth is on
+        self.api_key: Optional[ApiKey] = None
         super().__init__(*args, **kwargs)
 
     def do_OPTIONS(self):
         self.send_response(200)
         self.send_header("Access-Control-Allow-Origin", "*")
-        self.send_header("Access-Control-Allow-Methods", "GET, OPTIONS")
-        self.send_header("Access-Control-Allow-Headers", "Content-Type")
+        self.send_header("Access-Control-Allow-Methods", ALLOWED_METHODS)
+        self.send_header("Access-Control-Allow-Headers", ALLOWED_HEADERS)
         self.end_headers()
 
+    def _exposed(self, parsed) -> bool:
+        """Admin routes are 404 on a public listener when an admin one exists"""
+        exposes = getattr(self.server, "exposes", None)
+        if exposes is None or exposes(self.command, parsed.path):
+            return True
+        self._send_response(404, {"error": "not found"})
+        return False
+
+    def _authorize(self, parsed) -> bool:
+        """Check the request's key against its route; False once refused"""
+        if not self.require_auth:
+            return True
+        required = required_role(self.command, parsed.path)
+        if required is None:
+            return True
+        secret = self.headers.get("X-API-Key")
+        authorization = self.headers.get("Authorization", "")
+        if authorization.lower().startswith("bearer "):
+            secret = authorization[len("bearer ") :].strip()
+        if not secret:
+            secret = parse_qs(parsed.query).get("key", [None])[0]
+        self.api_key = self.hand_service.authenticate(secret)
+        if self.api_key is None:
+            self._send_response(401, {"error": "a valid API key is required"})
+            return False
+        if not allows(self.api_key.role, required):
+            self._send_response(403, {"error": f"requires the {required} role"})
+            return False
+        return True
+
+    def _client(self) -> str:
+        """Who is calling: the API key under --auth, else the client address"""
+        if self.api_key is not None:
+            return f"key:{self.api_key.id}"
+        return f"ip:{self.client_address[0]}"
+
+    def _admit(self, parsed) -> bool:
+        """Charge a limited route to its client's buckets; False once refused"""
+        if self.rate_limiter is None or not is_limited(parsed.path, self.command):
+            return True
+        clients = [f"ip:{self.client_address[0]}"]
+        if self.api_key is not None:
+            clients.append(f"key:{self.api_key.id}")
+        allowed, retry_after = self.rate_limiter.acquire(clients)
+        if not allowed:
+            self._send_response(
+                429,
+                {"error": "rate limit exceeded", "retry_after": round(retry_after, 1)},
+                {"Retry-After": str(math.ceil(retry_after))},
+            )
+        return allowed
+
     def do_GET(self):
         parsed = urlparse(self.path)
+        if not self._exposed(parsed):
+            return
+        if not self._authorize(parsed) or not self._admit(parsed):
+            return
         if parsed.path == "/health":
             self._send_response(200, {"status": "ok"})
             return
+        hand_db = parsed.path in HAND_DB_GET_PATHS or any(
+            pattern.match(parsed.path) for pattern in HAND_DB_GET_PATTERNS
+        )
+        if hand_db:
+            self._query_hand_db(parsed.path, parse_qs(parsed.query))
+            return
+        if parsed.path == "/api/variance":
+            try:
+                result = self._simulate_variance(parse_qs(parsed.query))
+                self._send_response(200, result)
+            except ValueError as exc:
+                self._send_response(400, {"error": str(exc)})
+            return
+        if parsed.path == "/api/strength":
+            query = parse_qs(parsed.query)
+            try:
+                if "hand" not in query:
+                    raise ValueError("hand is required")
+                strength = hand_strength(
+                    query["hand"][0],
+                    query.get("board", [""])[0],
+                    query.get("range", [None])[0],
+                )
+                self._send_response(200, strength.to_dict())
+            except ValueError as exc:
+                self._send_response(400, {"error": str(exc)})
+            return
+        if parsed.path == "/api/range/heatmap":
+            self._range_heatmap(parse_qs(parsed.query))
+            return
+        if parsed.path == "/api/tourney/clock":
+            self._send_response(200, self.tourney_service.clock.snapshot())
+            return
+        if parsed.path == "/api/tourney/structure":
+            self._send_response(200, self.tourney_service.structure())
+            return
+        if parsed.path == "/api/tourney/clock/stream":
+            self._stream_clock()
+            return
+        if parsed.path == "/api/tourney/seating":
+            self._send_response(200, self.tourney_service.seating.snapshot())
+            return
+        fair = FAIR_HAND_PATH.match(parsed.path)
+        if fair:
+            try:
+                hand = self.fairdeal_service.hand(fair.group(1))
+                self._send_response(200, hand.to_dict())
+            except KeyError as exc:
+                self._send_response(404, {"error": exc.args[0]})
+            return
+        if parsed.path == "/api/tourney/seating/stream":
+            self._stream_seating()
+            return
+        if parsed.path == "/api/jobs":
+            try:
+                query = parse_qs(parsed.query)
+                self._send_response(200, self.job_service.jobs(query))
+            except ValueError as exc:
+                self._send_response(400, {"error": str(exc)})
+            return
+        job = JOB_PATH.match(parsed.path)
+        if job:
+            try:
+                if job.group(2):
+                    self._stream_job(job.group(1))
+                else:
+                    self._send_response(200, self.job_service.job(job.group(1)))
+            except KeyError as exc:
+                self._send_response(404, {"error": exc.args[0]})
+            return
+        solve = SOLVER_PATH.match(parsed.path)
+        if solve:
+            try:
+                job_id = unquote(solve.group(1))
+                query = parse_qs(parsed.query, keep_blank_values=True)
+                self._send_response(200, self.solver_service.status(job_id, query))
+            except KeyError as exc:
+                self._send_response(404, {"error": exc.args[0]})
+            except ValueError as exc:
+                self._send_response(400, {"error": str(exc)})
+            return
         if parsed.path != "/ranges":
             self._send_response(404, {"error": "not found"})
             return
@@ -226,6 +1455,468 @@ class _APIRequestHandler(BaseHTTPRequestHandler):
         except Exception as exc:  # pylint: disable=broad-except
             self._send_response(500, {"error": str(exc)})
 
+    def _query_hand_db(self, path: str, query: Dict[str, List[str]]):
+        if self.hand_service is None:
+            self._send_response(503, {"error": "no hand database configured"})
+            return
+        replay = REPLAY_PATH.match(path)
+        notes = NOTES_PATH.match(path)
+        chart = CHART_PATH.match(path)
+        ledger = LEDGER_GAME_PATH.match(path)
+        try:
+            if replay:
+                hand_id = unquote(replay.group(1))
+                self._send_response(200, self.hand_service.replay(hand_id, query))
+            elif notes:
+                name = unquote(notes.group(1))
+                self._send_response(200, self.hand_service.player_notes(name))
+            elif chart:
+                self._send_response(200, self.hand_service.chart(int(chart.group(1))))
+            elif ledger:
+                game_id = int(ledger.group(1))
+                self._send_response(200, self.hand_service.ledger_game(game_id))
+            elif path == "/api/ledger":
+                self._send_response(200, self.hand_service.ledger(query))
+            elif path == "/api/rates":
+                self._send_response(200, self.hand_service.rates())
+            elif path == "/api/keys":
+                self._send_response(200, self.hand_service.api_keys())
+            elif path == "/api/audit":
+                self._send_response(200, self.hand_service.audit_entries(query))
+            elif path == "/api/charts":
+                self._send_response(200, self.hand_service.charts(query))
+            elif path == "/api/trainer/question":
+                self._send_response(200, self.hand_service.trainer_question(query))
+            elif path == "/api/trainer/stats":
+                self._send_response(200, self.hand_service.trainer_stats())
+            elif path == "/api/players":
+                self._send_response(200, self.hand_service.players(query))
+            elif path == "/api/hands":
+                self._send_response(200, self.hand_service.search(query))
+            elif path == "/api/stats":
+                self._send_response(200, self.hand_service.stats(query))
+            elif path == "/api/lines":
+                self._send_response(200, self.hand_service.lines(query))
+            elif path == "/api/pace":
+                self._send_response(200, self.hand_service.pace(query))
+            elif path == "/api/graphql":
+                self._send_response(*self.hand_service.graphql_get(query))
+            elif path == "/api/graphql/schema":
+                self._send_response(200, {"schema": schema_sdl()})
+            elif path.startswith("/api/graphs/"):
+                kind = path.rsplit("/", 1)[1]
+                series, png = self.hand_service.graph(kind, query)
+                if png:
+                    self._send_image(line_chart_png(series.series))
+                else:
+                    self._send_response(200, series.to_dict())
+            elif path == "/api/bankroll":
+                self._send_response(200, self.hand_service.bankroll(query))
+            elif path == "/api/export":
+                data, fmt = self.hand_service.export(query)
+                self._send_file(data, fmt)
+            else:
+                self._send_response(200, self.hand_service.bankroll_sessions())
+        except KeyError as exc:
+            self._send_response(404, {"error": exc.args[0]})
+        except ValueError as exc:
+            self._send_response(400, {"error": str(exc)})
+        except Exception as exc:  # pylint: disable=broad-except
+            self._send_response(500, {"error": str(exc)})
+
+    def do_POST(self):
+        parsed = urlparse(self.path)
+        if not self._exposed(parsed):
+            return
+        if not self._authorize(parsed) or not self._admit(parsed):
+            return
+        known = (
+            "/api/blockers",
+            "/api/deal",
+            "/api/equity",
+            "/api/equity/stream",
+            "/api/fairdeal",
+            "/api/fairdeal/verify",
+            "/api/payouts",
+            "/api/solver",
+            "/api/tourney/clock",
+            "/api/tourney/seating",
+            "/api/ledger/games",
+            "/api/rates",
+            "/api/keys",
+            "/api/graphql",
+            "/api/jobs",
+            *BANKROLL_POST_PATHS,
+            *CHART_POST_PATHS,
+        )
+        ledger = LEDGER_POST_PATH.match(parsed.path)
+        fair = FAIR_ACTION_PATH.match(parsed.path)
+        if parsed.path not in known and not ledger and not fair:
+            self._send_response(404, {"error": "not found"})
+            return
+
+        try:
+            payload = self._read_json()
+            if payload is None:
+                return
+            if parsed.path == "/api/equity":
+                self._send_response(200, self.equity_service.compute(payload))
+            elif parsed.path == "/api/equity/stream":
+                self._stream_equity(payload)
+            elif parsed.path == "/api/blockers":
+                self._send_response(200, self._blocker_report(payload))
+            elif parsed.path == "/api/deal":
+                self._send_response(200, self._calculate_deal(payload))
+            elif parsed.path == "/api/fairdeal":
+                result = self.fairdeal_service.new_hand(payload, self._client())
+                self._send_response(201, result)
+            elif parsed.path == "/api/fairdeal/verify":
+                self._send_response(200, self.fairdeal_service.verify(payload))
+            elif fair:
+                hand_id, action = fair.groups()
+                result = self.fairdeal_service.act(
+                    hand_id, action, payload, self._client()
+                )
+                self._send_response(200, result)
+            elif parsed.path == "/api/payouts":
+                self._send_response(200, self._calculate_payouts(payload))
+            elif parsed.path == "/api/solver":
+                self._send_response(202, self.solver_service.start(payload))
+            elif parsed.path == "/api/jobs":
+                owner = str(self.api_key.id) if self.api_key else None
+                self._send_response(202, self.job_service.submit(payload, owner))
+            elif parsed.path == "/api/tourney/clock":
+                self._send_response(200, self.tourney_service.control(payload))
+            elif parsed.path == "/api/tourney/seating":
+                self._send_response(200, self.tourney_service.seat(payload))
+            elif self.hand_service is None:
+                self._send_response(503, {"error": "no hand database configured"})
+            elif parsed.path == "/api/charts":
+                self._send_response(201, self.hand_service.save_chart(payload))
+            elif parsed.path == "/api/trainer/answer":
+                self._send_response(200, self.hand_service.trainer_answer(payload))
+            elif ledger:
+                game_id, action = int(ledger.group(1)), ledger.group(2)
+                result = self.hand_service.record_ledger(game_id, action, payload)
+                self._send_response(201 if action == "entries" else 200, result)
+            elif parsed.path == "/api/ledger/games":
+                result = self.hand_service.record_ledger(None, "new", payload)
+                self._send_response(201, result)
+            elif parsed.path == "/api/rates":
+                self._send_response(201, self.hand_service.add_rate(payload))
+            elif parsed.path == "/api/keys":
+                self._send_response(201, self.hand_service.create_key(payload))
+            elif parsed.path == "/api/graphql":
+                self._send_response(*self.hand_service.graphql(payload))
+            else:
+                result = self.hand_service.record_bankroll(parsed.path, payload)
+                self._send_response(201, result)
+        except KeyError as exc:
+            self._send_response(404, {"error": exc.args[0]})
+        except ValueError as exc:
+            self._send_response(400, {"error": str(exc)})
+        except PermissionError as exc:
+            self._send_response(403, {"error": str(exc)})
+        except QueueFull as exc:
+            self._send_response(503, {"error": str(exc)})
+        except Exception as exc:  # pylint: disable=broad-except
+            self._send_response(500, {"error": str(exc)})
+
+    def do_PUT(self):
+        parsed = urlparse(self.path)
+        if not self._exposed(parsed):
+            return
+        if not self._authorize(parsed) or not self._admit(parsed):
+            return
+        notes = NOTES_PATH.match(parsed.path)
+        if not notes and parsed.path != "/api/tourney/structure":
+            self._send_response(404, {"error": "not found"})
+            return
+        if notes and self.hand_service is None:
+            self._send_response(503, {"error": "no hand database configured"})
+            return
+
+        try:
+            payload = self._read_json()
+            if payload is None:
+                return
+            if notes:
+                name = unquote(notes.group(1))
+                result = self.hand_service.update_player_notes(name, payload)
+            else:
+                result = self.tourney_service.replace_structure(payload)
+            self._send_response(200, result)
+        except ValueError as exc:
+            self._send_response(400, {"error": str(exc)})
+        except Exception as exc:  # pylint: disable=broad-except
+            self._send_response(500, {"error": str(exc)})
+
+    def do_DELETE(self):
+        parsed = urlparse(self.path)
+        if not self._exposed(parsed):
+            return
+        if not self._authorize(parsed) or not self._admit(parsed):
+            return
+        job = JOB_PATH.match(parsed.path)
+        if job and not job.group(2):
+            # Keys cancel their own jobs; admins, or anyone without --auth, any
+            owner = None
+            if self.api_key is not None and self.api_key.role != "admin":
+                owner = str(self.api_key.id)
+            try:
+                self._send_response(200, self.job_service.cancel(job.group(1), owner))
+            except KeyError as exc:
+                self._send_response(404, {"error": exc.args[0]})
+            except PermissionError as exc:
+                self._send_response(403, {"error": str(exc)})
+            except ValueError as exc:
+                self._send_response(400, {"error": str(exc)})
+            return
+        key = KEY_PATH.match(parsed.path)
+        if not key:
+            self._send_response(404, {"error": "not found"})
+            return
+        if self.hand_service is None:
+            self._send_response(503, {"error": "no hand database configured"})
+            return
+        try:
+            self._send_response(200, self.hand_service.revoke_key(int(key.group(1))))
+        except KeyError as exc:
+            self._send_response(404, {"error": exc.args[0]})
+        except Exception as exc:  # pylint: disable=broad-except
+            self._send_response(500, {"error": str(exc)})
+
+    def _stream_clock(self):
+        """Server-Sent Events: the clock state every CLOCK_STREAM_INTERVAL"""
+        self.send_response(200)
+        self.send_header("Access-Control-Allow-Origin", "*")
+        self.send_header("Content-Type", "text/event-stream")
+        self.send_header("Cache-Control", "no-cache")
+        self.end_headers()
+        try:
+            while True:
+                state = self.tourney_service.clock.snapshot()
+                self.wfile.write(f"data: {json.dumps(state)}\n\n".encode("utf-8"))
+                self.wfile.flush()
+                time.sleep(CLOCK_STREAM_INTERVAL)
+        except (BrokenPipeError, ConnectionResetError):
+            return
+
+    def _stream_seating(self):
+        """Server-Sent Events: a snapshot, then each seating event by version"""
+        seating = self.tourney_service.seating
+        try:
+            seen = int(self.headers.get("Last-Event-ID") or -1)
+        except ValueError:
+            seen = -1
+        self.send_response(200)
+        self.send_header("Access-Control-Allow-Origin", "*")
+        self.send_header("Content-Type", "text/event-stream")
+        self.send_header("Cache-Control", "no-cache")
+        self.end_headers()
+        try:
+            if seen < 0:
+                state = seating.snapshot()
+                seen = state["version"]
+                message = f"event: snapshot\nid: {seen}\ndata: {json.dumps(state)}\n\n"
+                self.wfile.write(message.encode("utf-8"))
+                self.wfile.flush()
+            while True:
+                for event in seating.events_since(seen):
+                    seen = event["version"]
+                    message = (
+                        f"event: {event['type']}\nid: {seen}\n"
+                        f"data: {json.dumps(event)}\n\n"
+                    )
+                    self.wfile.write(message.encode("utf-8"))
+                self.wfile.flush()
+                time.sleep(SEATING_STREAM_INTERVAL)
+        except (BrokenPipeError, ConnectionResetError):
+            return
+
+    def _stream_job(self, job_id: str):
+        """Server-Sent Events: the job on each change, by version, until it ends"""
+        job = self.job_service.queue.get(job_id)
+        try:
+            seen = int(self.headers.get("Last-Event-ID") or -1)
+        except ValueError:
+            seen = -1
+        self.send_response(200)
+        self.send_header("Access-Control-Allow-Origin", "*")
+        self.send_header("Content-Type", "text/event-stream")
+        self.send_header("Cache-Control", "no-cache")
+        self.end_headers()
+        try:
+            while True:
+                if job.version > seen:
+                    seen = job.version
+                    name = job.status if job.finished else "progress"
+                    data = json.dumps(job.to_dict(result=job.finished))
+                    message = f"event: {name}\nid: {seen}\ndata: {data}\n\n"
+                    self.wfile.write(message.encode("utf-8"))
+                    self.wfile.flush()
+                if job.finished:
+                    return
+                time.sleep(JOB_STREAM_INTERVAL)
+                job = self.job_service.queue.get(job_id)
+        except (BrokenPipeError, ConnectionResetError, KeyError):
+            return
+
+    def _stream_equity(self, payload: Dict):
+        """Server-Sent Events: "progress" per round of shards, then "result"""
+        # Nothing is sent before the first event, so bad input is still a 400
+        started = False
+
+        def send_event(name: str, data: Dict):
+            nonlocal started
+            if not started:
+                self.send_response(200)
+                self.send_header("Access-Control-Allow-Origin", "*")
+                self.send_header("Content-Type", "text/event-stream")
+                self.send_header("Cache-Control", "no-cache")
+                self.end_headers()
+                started = True
+            message = f"event: {name}\ndata: {json.dumps(data)}\n\n"
+            self.wfile.write(message.encode("utf-8"))
+            self.wfile.flush()
+
+        try:
+            result = self.equity_service.compute(
+                payload, lambda partial: send_event("progress", partial.to_dict())
+            )
+            send_event("result", result)
+        except ValueError as exc:
+            if not started:
+                self._send_response(400, {"error": str(exc)})
+            else:
+                send_event("error", {"error": str(exc)})
+        except (BrokenPipeError, ConnectionResetError):
+            # The client went away; stopping here also stops the sampling
+            return
+
+    def _range_heatmap(self, query: Dict[str, List[str]]):
+        fmt = query.get("format", ["json"])[0]
+        try:
+            if "range" not in query:
+                raise ValueError("range is required")
+            if fmt not in ("json", "svg", "png"):
+                raise ValueError("format must be json, svg, or png")
+            hand_range = Range.parse(query["range"][0])
+            if query.get("dead"):
+                hand_range = hand_range.without(query["dead"][0])
+        except ValueError as exc:
+            self._send_response(400, {"error": str(exc)})
+            return
+        heatmap = Heatmap.of(hand_range)
+        if fmt == "png":
+            self._send_image(heatmap.png())
+        elif fmt == "svg":
+            title = query.get("title", [""])[0]
+            self._send_image(heatmap.svg(title).encode("utf-8"), "image/svg+xml")
+        else:
+            self._send_response(200, heatmap.to_dict())
+
+    def _read_json(self):
+        """Request body as JSON; None once a 413 has been sent"""
+        length = int(self.headers.get("Content-Length") or 0)
+        if length > MAX_REQUEST_BYTES:
+            self._send_response(413, {"error": "request body too large"})
+            return None
+        try:
+            payload = json.loads(self.rfile.read(length) or b"{}")
+        except json.JSONDecodeError as exc:
+            raise ValueError(f"Invalid JSON: {exc}") from None
+        if payload is None:
+            raise ValueError("Request body must be a JSON object")
+        return payload
+
+    @staticmethod
+    def _simulate_variance(query: Dict[str, List[str]]) -> Dict:
+        def number(name: str, cast=float, default=None):
+            value = query.get(name, [None])[0]
+            if value is None:
+                if default is None:
+                    raise ValueError(f"{name} is required")
+                return default
+            try:
+                return cast(value)
+            except ValueError:
+                raise ValueError(f"Invalid number for {name}: {value}") from None
+
+        hands = number("hands", int, DEFAULT_HANDS)
+        max_trials = MAX_VARIANCE_STEPS // max(1, hands // HANDS_PER_STEP)
+        trials = number("trials", int, min(1_000, max_trials))
+        if trials > max_trials:
+            raise ValueError(
+                f"trials x hands/{HANDS_PER_STEP} exceeds {MAX_VARIANCE_STEPS:,}"
+            )
+        downswings = [int(size) for size in query.get("downswing", []) if size]
+        return simulate(
+            number("win_rate"),
+            number("std_dev"),
+            number("bankroll"),
+            hands=hands,
+            trials=trials,
+            target_risk=number("target_risk", float, DEFAULT_TARGET_RISK),
+            seed=number("seed", int, None) if "seed" in query else None,
+            **({"downswings": downswings} if downswings else {}),
+        ).to_dict()
+
+    @staticmethod
+    def _calculate_payouts(payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        missing = [name for name in ("entrants", "buy_in") if name not in payload]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        percentages = payload.get("percentages")
+        try:
+            return calculate_payouts(
+                int(payload["entrants"]),
+                float(payload["buy_in"]),
+                payload.get("rake") or 0,
+                int(payload.get("reentries") or 0),
+                str(payload.get("model") or "standard"),
+                [float(value) for value in percentages] if percentages else None,
+                float(payload.get("paid_fraction") or DEFAULT_PAID_FRACTION),
+                float(payload.get("min_cash") or DEFAULT_MIN_CASH),
+                int(payload.get("bubble_refunds") or 0),
+                float(payload.get("rounding", DEFAULT_ROUNDING)),
+                float(payload.get("guarantee") or 0),
+            ).to_dict()
+        except TypeError:
+            raise ValueError("Payout fields must be numbers") from None
+
+    @staticmethod
+    def _blocker_report(payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        missing = [name for name in ("range", "board", "hero") if not payload.get(name)]
+        if missing:
+            raise ValueError(f"Missing fields: {', '.join(missing)}")
+        fields = [payload[name] for name in ("range", "board", "hero")]
+        if not all(isinstance(value, str) for value in fields):
+            raise ValueError("range, board, and hero must be strings")
+        return blocker_report(*fields).to_dict()
+
+    @staticmethod
+    def _calculate_deal(payload: Dict) -> Dict:
+        if not isinstance(payload, dict):
+            raise ValueError("Request body must be a JSON object")
+        stacks, payouts = payload.get("stacks"), payload.get("payouts")
+        if not isinstance(stacks, list) or not isinstance(payouts, list):
+            raise ValueError("stacks and payouts must be lists of numbers")
+        save = payload.get("save")
+        try:
+            return calculate_deal(
+                [float(stack) for stack in stacks],
+                [float(payout) for payout in payouts],
+                None if save is None else float(save),
+                float(payload.get("rounding", DEFAULT_ROUNDING)),
+            ).to_dict()
+        except TypeError:
+            raise ValueError("Deal fields must be numbers") from None
+
     def _parse_filters(self, query: Dict[str, List[str]]) -> RangeQueryFilters:
         def get(name: str) -> Optional[str]:
             return query.get(name, [None])[0]
@@ -264,39 +1955,149 @@ class _APIRequestHandler(BaseHTTPRequestHandler):
             limit=get_int("limit"),
         )
 
-    def _send_response(self, status_code: int, payload: Dict):
+    def _send_response(
+        self, status_code: int, payload: Dict, headers: Optional[Dict] = None
+    ):
         body = json.dumps(payload, indent=2).encode("utf-8")
         self.send_response(status_code)
+        for name, value in (headers or {}).items():
+            self.send_header(name, value)
         self.send_header("Access-Control-Allow-Origin", "*")
-        self.send_header("Access-Control-Allow-Methods", "GET, OPTIONS")
-        self.send_header("Access-Control-Allow-Headers", "Content-Type")
+        self.send_header("Access-Control-Allow-Methods", ALLOWED_METHODS)
+        self.send_header("Access-Control-Allow-Headers", ALLOWED_HEADERS)
         self.send_header("Content-Type", "application/json")
         self.send_header("Content-Length", str(len(body)))
         self.end_headers()
         self.wfile.write(body)
 
+    def _send_file(self, data: bytes, fmt: str):
+        self.send_response(200)
+        self.send_header("Access-Control-Allow-Origin", "*")
+        self.send_header("Content-Type", CONTENT_TYPES[fmt])
+        self.send_header(
+            "Content-Disposition", f'attachment; filename="pokertools.{fmt}"'
+        )
+        self.send_header("Content-Length", str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+
+    def _send_image(self, data: bytes, content_type: str = "image/png"):
+        self.send_response(200)
+        self.send_header("Access-Control-Allow-Origin", "*")
+        self.send_header("Content-Type", content_type)
+        self.send_header("Content-Length", str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+
+    def log_request(self, code="-", size="-"):
+        """Called by send_response: audit sensitive requests with their status"""
+        action = sensitive_action(self.command, urlparse(self.path).path)
+        if action is None or self.hand_service is None:
+            return
+        self.hand_service.audit(
+            self.api_key,
+            self.command,
+            action,
+            resource=self.path,
+            status=int(code),
+            remote=self.client_address[0],
+        )
+
     def log_message(self, format, *args):  # noqa: A003
         """Silence noisy default logging."""
         return
 
 
-def make_handler(service: RangeQueryService):
+def make_handler(
+    service: RangeQueryService,
+    equity_service: EquityService,
+    hand_service: Optional[HandDBService] = None,
+    solver_service: Optional[SolverService] = None,
+    tourney_service: Optional[TourneyService] = None,
+    fairdeal_service: Optional[FairDealService] = None,
+    require_auth: bool = False,
+    rate_limiter: Optional[RateLimiter] = None,
+    job_service: Optional[JobService] = None,
+):
+    """`require_auth` checks API keys, which live in the hand database"""
+    if require_auth and hand_service is None:
+        raise ValueError("API keys need a hand database")
+    solver_service = solver_service or SolverService()
+    tourney_service = tourney_service or TourneyService()
+    fairdeal_service = fairdeal_service or FairDealService()
+    job_service = job_service or JobService(equity_service)
+
     def handler(*args, **kwargs):
-        _APIRequestHandler(service, *args, **kwargs)
+        _APIRequestHandler(
+            service,
+            equity_service,
+            hand_service,
+            solver_service,
+            tourney_service,
+            fairdeal_service,
+            job_service,
+            require_auth,
+            rate_limiter,
+            *args,
+            **kwargs,
+        )
 
     return handler
 
 
-def run_server(db_path: Path, host: str, port: int):
+def run_server(
+    db_path: Path,
+    host: str,
+    port: int,
+    equity_budget: int = DEFAULT_EQUITY_BUDGET,
+    equity_workers: int = 1,
+    hands_db: Optional[Path] = None,
+    blind_structure: Optional[Path] = None,
+    cache_size: int = DEFAULT_CACHE_SIZE,
+    cache_path: Optional[Path] = None,
+    require_auth: bool = False,
+    rate_limit: float = 0,
+    rate_burst: int = DEFAULT_BURST,
+    listeners: Sequence[Listener] = (),
+    jobs_path: Optional[Path] = None,
+    job_workers: int = 1,
+    job_equity_budget: int = DEFAULT_JOB_EQUITY_BUDGET,
+):
+    """Serve on `listeners`, or on host:port alone when there are none"""
     service = RangeQueryService(db_path)
-    handler = make_handler(service)
-    httpd = ThreadingHTTPServer((host, port), handler)
-    print(f"Range query service listening on http://{host}:{port} (db={db_path})")
+    hand_service = HandDBService(hands_db) if hands_db else None
+    structure = BlindStructure.load(blind_structure) if blind_structure else None
+    equity_service = EquityService(
+        equity_budget, equity_workers, EquityCache(cache_size, cache_path)
+    )
+    handler = make_handler(
+        service,
+        equity_service,
+        hand_service,
+        tourney_service=TourneyService(structure),
+        require_auth=require_auth,
+        rate_limiter=RateLimiter(rate_limit, rate_burst) if rate_limit else None,
+        job_service=JobService(
+            equity_service, jobs_path, job_workers, job_equity_budget
+        ),
+    )
+    servers = make_servers(listeners or [Listener(host, port)], handler)
+    threads = []
+    for httpd in servers:
+        kind = "admin routes" if httpd.listener.admin else f"db={db_path}"
+        print(f"Range query service listening on {httpd.listener.url} ({kind})")
+        threads.append(threading.Thread(target=httpd.serve_forever, daemon=True))
+        threads[-1].start()
     try:
-        httpd.serve_forever()
+        for thread in threads:
+            while thread.is_alive():
+                thread.join(1)
     except KeyboardInterrupt:
         print("\nShutting down...")
-        httpd.server_close()
+    finally:
+        for httpd in servers:
+            httpd.shutdown()
+            httpd.server_close()
 
 
 def run_cli_query(db_path: Path, filters: RangeQueryFilters):
@@ -305,19 +2106,100 @@ def run_cli_query(db_path: Path, filters: RangeQueryFilters):
     print(json.dumps(result, indent=2))
 
 
-def parse_args():
-    parser = argparse.ArgumentParser(description="Range Query Service")
+def add_arguments(parser: argparse.ArgumentParser):
     parser.add_argument(
         "--db",
         type=Path,
         default=Path("range_analysis.duckdb"),
         help="DuckDB database path",
     )
-    subparsers = parser.add_subparsers(dest="command")
+    subparsers = parser.add_subparsers(dest="mode")
 
     serve_parser = subparsers.add_parser("serve", help="Start HTTP server (default)")
     serve_parser.add_argument("--host", default="127.0.0.1")
     serve_parser.add_argument("--port", type=int, default=8080)
+    serve_parser.add_argument(
+        "--listen",
+        action="append",
+        default=[],
+        metavar="SPEC",
+        help="HOST:PORT or [IPv6]:PORT, with ,cert=FILE,key=FILE for TLS "
+        "(repeatable; replaces --host/--port)",
+    )
+    serve_parser.add_argument(
+        "--admin-listen",
+        action="append",
+        default=[],
+        metavar="SPEC",
+        help="A listener for the admin routes, which public listeners then hide",
+    )
+    serve_parser.add_argument(
+        "--equity-budget",
+        type=int,
+        default=DEFAULT_EQUITY_BUDGET,
+        help="Max outcomes evaluated per /api/equity request",
+    )
+    serve_parser.add_argument(
+        "--equity-workers",
+        type=int,
+        default=1,
+        help="Processes per equity request",
+    )
+    serve_parser.add_argument(
+        "--equity-cache-size",
+        type=int,
+        default=DEFAULT_CACHE_SIZE,
+        help="Equity results kept in memory",
+    )
+    serve_parser.add_argument(
+        "--equity-cache",
+        type=Path,
+        help="SQLite file that keeps equity results across restarts",
+    )
+    serve_parser.add_argument(
+        "--hands-db",
+        type=Path,
+        help="SQLite hand database backing /api/hands and /api/stats",
+    )
+    serve_parser.add_argument(
+        "--blind-structure",
+        type=Path,
+        help="Blind structure JSON for the tournament clock (default: standard)",
+    )
+    serve_parser.add_argument(
+        "--jobs",
+        type=Path,
+        help="SQLite file that keeps /api/jobs across restarts (default: memory)",
+    )
+    serve_parser.add_argument(
+        "--job-workers",
+        type=int,
+        default=1,
+        help="Queued equity and solver jobs run at once",
+    )
+    serve_parser.add_argument(
+        "--job-equity-budget",
+        type=int,
+        default=DEFAULT_JOB_EQUITY_BUDGET,
+        help="Max outcomes evaluated per queued equity job",
+    )
+    serve_parser.add_argument(
+        "--auth",
+        action="store_true",
+        help="Require API keys with a role (keys live in --hands-db)",
+    )
+    serve_parser.add_argument(
+        "--rate-limit",
+        type=float,
+        default=0,
+        help="Equity, hands, and stats requests per minute per client (0: off)",
+    )
+    serve_parser.add_argument(
+        "--rate-burst",
+        type=int,
+        default=DEFAULT_BURST,
+        help="Requests a client may make at once before --rate-limit applies",
+    )
 
     query_parser = subparsers.add_parser("query", help="Run a single query via CLI")
     query_parser.add_argument("--position", required=True)
@@ -334,13 +2216,9 @@ def parse_args():
     query_parser.add_argument("--cards")
     query_parser.add_argument("--limit", type=int)
 
-    return parser.parse_args()
 
-
-def main():
-    args = parse_args()
-    filters = RangeQueryFilters()
-    if args.command == "query":
+def run(args: argparse.Namespace):
+    if args.mode == "query":
         filters = RangeQueryFilters(
             position=args.position,
             stage=args.stage,
@@ -360,7 +2238,60 @@ def main():
     else:
         host = getattr(args, "host", "127.0.0.1")
         port = getattr(args, "port", 8080)
-        run_server(args.db, host, port)
+        budget = getattr(args, "equity_budget", DEFAULT_EQUITY_BUDGET)
+        workers = getattr(args, "equity_workers", 1)
+        hands_db = getattr(args, "hands_db", None)
+        structure = getattr(args, "blind_structure", None)
+        cache_size = getattr(args, "equity_cache_size", DEFAULT_CACHE_SIZE)
+        cache_path = getattr(args, "equity_cache", None)
+        require_auth = getattr(args, "auth", False)
+        rate_limit = getattr(args, "rate_limit", 0)
+        rate_burst = getattr(args, "rate_burst", DEFAULT_BURST)
+        jobs_path = getattr(args, "jobs", None)
+        job_workers = getattr(args, "job_workers", 1)
+        job_budget = getattr(args, "job_equity_budget", DEFAULT_JOB_EQUITY_BUDGET)
+        if job_workers < 1 or job_budget < 1:
+            raise SystemExit(
+                "error: --job-workers and --job-equity-budget must be positive"
+            )
+        if rate_limit < 0 or rate_burst < 1:
+            raise SystemExit("error: --rate-limit and --rate-burst must be positive")
+        if require_auth and not hands_db:
+            raise SystemExit("error: --auth needs --hands-db, where the keys live")
+        try:
+            listeners = [parse_listener(spec) for spec in getattr(args, "listen", [])]
+            admin = getattr(args, "admin_listen", [])
+            if admin and not listeners:
+                listeners.append(Listener(host, port))
+            listeners += [parse_listener(spec, admin=True) for spec in admin]
+            if listeners:
+                check_listeners(listeners)
+        except ValueError as exc:
+            raise SystemExit(f"error: {exc}") from None
+        run_server(
+            args.db,
+            host,
+            port,
+            budget,
+            workers,
+            hands_db,
+            structure,
+            cache_size,
+            cache_path,
+            require_auth,
+            rate_limit,
+            rate_burst,
+            listeners,
+            jobs_path,
+            job_workers,
+            job_budget,
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Range Query Service")
+    add_arguments(parser)
+    run(parser.parse_args())
 
 
 if __name__ == "__main__":
@@ -0,0 +1,99 @@
+"""
+Token-bucket rate limiting for the expensive HTTP API routes.
+
+Each client gets a bucket of `burst` tokens that refills at `per_minute`
+tokens a minute; a request takes one token or is refused with the seconds
+until the next one. A request is charged to the bucket of its client address
+and, when it carries an API key, to that key's bucket as well, so neither
+many keys from one address nor one key spread over many addresses gets past
+the limit. Only the routes in `LIMITED_ROUTES` are limited: equity (which
+shares the engine's worker processes with live consumers), hand search and
+replay, stats, GraphQL queries, and starting solves and background jobs,
+which would otherwise get around the equity limit. Polling a job or a solve
+by id is not limited, but every PUT and DELETE (notes, the blind structure,
+revoking keys, cancelling jobs) is.
+
+Buckets that have refilled completely are indistinguishable from new ones,
+so they are dropped once more than `MAX_BUCKETS` are held.
+
+Example:
+    limiter = RateLimiter(per_minute=60, burst=10)
+    allowed, retry_after = limiter.acquire(["ip:10.0.0.2", "key:3"])
+"""
+
+from __future__ import annotations
+
+import re
+import threading
+import time
+from dataclasses import dataclass
+from typing import Callable, Dict, Iterable, Tuple
+
+
+LIMITED_ROUTES = re.compile(
+    r"^/api/(equity(/stream)?|hands(/[^/]+/replay)?|stats|graphql|jobs|solver)$"
+)
+DEFAULT_BURST = 10
+MAX_BUCKETS = 10_000
+
+
+def is_limited(path: str, method: str = "GET") -> bool:
+    return method in ("PUT", "DELETE") or bool(LIMITED_ROUTES.match(path))
+
+
+@dataclass
+class TokenBucket:
+    tokens: float
+    updated: float
+
+
+class RateLimiter:
+    """Token buckets by client; safe to share between request threads"""
+
+    def __init__(
+        self,
+        per_minute: float,
+        burst: int = DEFAULT_BURST,
+        clock: Callable[[], float] = time.monotonic,
+    ):
+        if per_minute <= 0 or burst < 1:
+            raise ValueError("Rate limit and burst must be positive")
+        self.rate = per_minute / 60
+        self.burst = burst
+        self.clock = clock
+        self._buckets: Dict[str, TokenBucket] = {}
+        self._lock = threading.Lock()
+
+    def _refill(self, client: str, now: float) -> TokenBucket:
+        bucket = self._buckets.get(client)
+        if bucket is None:
+            bucket = self._buckets[client] = TokenBucket(self.burst, now)
+        bucket.tokens = min(
+            self.burst, bucket.tokens + (now - bucket.updated) * self.rate
+        )
+        bucket.updated = now
+        return bucket
+
+    def acquire(self, clients: Iterable[str]) -> Tuple[bool, float]:
+        """Take a token from every client's bucket, or from none of them
+
+        Returns whether the request may go ahead and, when not, the seconds
+        until it could.
+        """
+        with self._lock:
+            now = self.clock()
+            buckets = [self._refill(client, now) for client in clients]
+            short = max((1 - bucket.tokens for bucket in buckets), default=0.0)
+            if short > 0:
+                return False, short / self.rate
+            for bucket in buckets:
+                bucket.tokens -= 1
+            if len(self._buckets) > MAX_BUCKETS:
+                self._prune(now)
+            return True, 0.0
+
+    def _prune(self, now: float):
+        for client in list(self._buckets):
+            bucket = self._buckets[client]
+            if bucket.tokens + (now - bucket.updated) * self.rate >= self.burst:
+                del self._buckets[client]
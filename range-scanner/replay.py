@@ -0,0 +1,331 @@
+#!/usr/bin/env python3
+"""
+Step-by-step replay timeline for a stored hand.
+
+`build_replay` walks a `handhistory.Hand` and emits one `Frame` per event:
+seating, forced bets, every action, board reveals, returned bets, shown cards,
+and pot collection. Each frame carries the pot, every stack, the stack changes
+the event caused, and the board so far, which is all an animated replayer
+needs. A hand run more than once reveals the shared board as usual, then gets
+one "run" frame per run with that run's board and best shown hand, followed by
+what that run paid. Decision and board frames also carry each live player's
+equity at that moment; players whose cards were never shown count as a random
+hand (hold'em only).
+
+Example:
+    python3 replay.py --db hands.sqlite 230000000001
+    python3 pokertools.py replay 230000000001 --json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+
+from equity import calculate_equity
+from game_evaluators import get_evaluator
+from handdb import HandDB, default_db_path
+from handhistory import BOARD_SIZES, DEAD_POSTS, FORCED_ACTIONS, STREETS, Hand
+from hand_range import Range
+
+
+# Sampling budget per decision point; spots below the limit are enumerated
+DEFAULT_REPLAY_ITERATIONS = 10_000
+REPLAY_EXHAUSTIVE_LIMIT = 200_000
+VERBS = {
+    "ante": "posts ante",
+    "bomb_pot": "posts bomb pot",
+    "small_blind": "posts small blind",
+    "big_blind": "posts big blind",
+    "straddle": "straddles",
+    "fold": "folds",
+    "check": "checks",
+    "call": "calls",
+    "bet": "bets",
+    "raise": "raises to",
+}
+
+
+@dataclass
+class Frame:
+    step: int
+    kind: str  # start, post, action, board, uncalled, show, run, collect
+    street: str
+    text: str
+    pot: float
+    stacks: Dict[str, float]
+    board: List[str]
+    player: Optional[str] = None
+    action: Optional[str] = None
+    deltas: Dict[str, float] = field(default_factory=dict)
+    equities: Optional[Dict[str, float]] = None
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+class _Replay:
+    def __init__(self, hand: Hand, equity: bool, iterations: int):
+        self.hand = hand
+        self.equity = equity
+        self.iterations = iterations
+        self.stacks = {player.name: player.stack for player in hand.players}
+        self.pot = 0.0
+        self.board: List[str] = []
+        self.street = "preflop"
+        # Sitting-out players may still post antes but are never live
+        self.folded = {player.name for player in hand.players if player.sitting_out}
+        self.frames: List[Frame] = []
+        self._equity_cache: Dict[Tuple, Optional[Dict[str, float]]] = {}
+
+    def emit(
+        self,
+        kind: str,
+        text: str,
+        deltas: Optional[Dict[str, float]] = None,
+        player: Optional[str] = None,
+        action: Optional[str] = None,
+        equities: Optional[Dict[str, float]] = None,
+    ):
+        deltas = {name: round(value, 2) for name, value in (deltas or {}).items()}
+        for name, delta in deltas.items():
+            self.stacks[name] = round(self.stacks.get(name, 0.0) + delta, 2)
+            self.pot = round(self.pot - delta, 2)
+        self.frames.append(
+            Frame(
+                step=len(self.frames),
+                kind=kind,
+                street=self.street,
+                text=text,
+                pot=self.pot,
+                stacks=dict(self.stacks),
+                board=list(self.board),
+                player=player,
+                action=action,
+                deltas=deltas,
+                equities=equities,
+            )
+        )
+
+    def run(self) -> List[Frame]:
+        hand = self.hand
+        stakes = f"{hand.small_blind:g}/{hand.big_blind:g}"
+        self.emit("start", f"{hand.site} hand {hand.hand_id}: {hand.game} {stakes}")
+        shared = _shared_cards(hand)
+        for street in STREETS:
+            actions = hand.actions_on(street)
+            size = BOARD_SIZES.get(street)
+            if size and shared < size:
+                break
+            self.street = street
+            if size:
+                self.board = hand.board[:size]
+                text = f"{street.upper()} [{' '.join(self.board)}]"
+                self.emit("board", text, equities=self._equities())
+            committed: Dict[str, float] = {}
+            for action in actions:
+                self._action(action, committed)
+        for name, amount in hand.uncalled.items():
+            text = f"{amount:g} returned to {name}"
+            self.emit("uncalled", text, {name: amount}, player=name)
+        live = [name for name in self.stacks if name not in self.folded]
+        if len(live) >= 2:
+            for name in live:
+                cards = hand.player(name).hole_cards
+                if cards:
+                    self.emit("show", f"{name} shows {' '.join(cards)}", player=name)
+        if len(hand.runouts) > 1 and len(hand.run_collected) == len(hand.runouts):
+            for run, board in enumerate(hand.runouts):
+                self._run(run, board, live)
+                self._collect(hand.run_collected[run])
+        else:
+            self._collect(hand.collected)
+        return self.frames
+
+    def _collect(self, collected: Dict[str, float]):
+        for name, amount in collected.items():
+            text = f"{name} collects {amount:g}"
+            self.emit("collect", text, {name: amount}, player=name)
+
+    def _run(self, run: int, board: List[str], live: List[str]):
+        self.board = list(board)
+        text = f"RUN {run + 1} [{' '.join(board)}]"
+        winners = self._run_winners(board, live)
+        if winners:
+            text += f": {winners}"
+        self.emit("run", text)
+
+    def _run_winners(self, board: List[str], live: List[str]) -> str:
+        """Best shown hand on one run's board, or "" when it can't be scored"""
+        try:
+            evaluator = get_evaluator(self.hand.game)
+            if evaluator.split:
+                return ""
+            scores = {
+                name: evaluator.evaluate(self.hand.player(name).hole_cards, board)
+                for name in live
+            }
+        except ValueError:
+            return ""
+        best = max(scores.values())
+        names = [name for name, score in scores.items() if score == best]
+        return f"{' and '.join(names)} with {evaluator.describe(best)}"
+
+    def _action(self, action, committed: Dict[str, float]):
+        name = action.player
+        if name not in self.stacks:
+            return
+        before = committed.get(name, 0.0)
+        if action.kind in DEAD_POSTS:
+            paid = action.amount
+        elif action.kind == "raise":
+            paid = action.amount - before
+            committed[name] = action.amount
+        else:
+            paid = action.amount
+            committed[name] = before + paid
+        equities = None
+        if action.kind not in FORCED_ACTIONS:
+            equities = self._equities()
+        text = f"{name} {VERBS[action.kind]}"
+        if action.amount:
+            text += f" {action.amount:g}"
+        if action.all_in:
+            text += " (all-in)"
+        kind = "post" if action.kind in FORCED_ACTIONS else "action"
+        deltas = {name: -paid} if paid else {}
+        self.emit(kind, text, deltas, name, action.kind, equities)
+        if action.kind == "fold":
+            self.folded.add(name)
+
+    def _equities(self) -> Optional[Dict[str, float]]:
+        if not self.equity:
+            return None
+        live = tuple(name for name in self.stacks if name not in self.folded)
+        key = (len(self.board), live)
+        if key not in self._equity_cache:
+            self._equity_cache[key] = self._compute_equities(live)
+        return self._equity_cache[key]
+
+    def _compute_equities(self, live: Tuple[str, ...]) -> Optional[Dict[str, float]]:
+        hand = self.hand
+        cards = {name: hand.player(name).hole_cards for name in live}
+        if len(live) < 2 or not any(cards.values()):
+            return None
+        if not all(cards.values()) and hand.game != "holdem":
+            return None
+        dead = [
+            card
+            for name in self.stacks
+            if name in self.folded
+            for card in hand.player(name).hole_cards
+        ]
+        players = [
+            "".join(cards[name]) if cards[name] else Range.top_percent(100)
+            for name in live
+        ]
+        try:
+            result = calculate_equity(
+                players,
+                board="".join(self.board),
+                dead="".join(dead),
+                game=hand.game,
+                iterations=self.iterations,
+                exhaustive_limit=REPLAY_EXHAUSTIVE_LIMIT,
+                workers=1,
+                seed=0,
+            )
+        except ValueError:
+            return None
+        return {name: round(share, 4) for name, share in zip(live, result.equities)}
+
+
+def _shared_cards(hand: Hand) -> int:
+    """Board cards all runs have in common; all of them for a hand dealt once"""
+    if len(hand.runouts) < 2:
+        return len(hand.board)
+    shared = 0
+    for cards in zip(*hand.runouts):
+        if len(set(cards)) > 1:
+            break
+        shared += 1
+    return shared
+
+
+def build_replay(
+    hand: Hand, equity: bool = True, iterations: int = DEFAULT_REPLAY_ITERATIONS
+) -> List[Frame]:
+    """Replay timeline for one hand; `equity=False` skips the equity engine"""
+    return _Replay(hand, equity, iterations).run()
+
+
+def find_hand(db: HandDB, hand_id: str, site: Optional[str] = None) -> Hand:
+    """Look a hand up by the site's id; `site` disambiguates collisions"""
+    where, params = "h.hand_id = ?", [hand_id]
+    if site:
+        where += " AND h.site = ?"
+        params.append(site)
+    keys = list(db.hand_keys(where, params))
+    if not keys:
+        raise KeyError(f"hand {hand_id} not found")
+    if len(keys) > 1:
+        sites = ", ".join(key[1] for key in keys)
+        raise ValueError(f"hand {hand_id} exists on several sites ({sites})")
+    return db.load(keys[0][0])
+
+
+def format_frames(frames: List[Frame]) -> str:
+    lines = []
+    for frame in frames:
+        lines.append(
+            f"[{frame.step:>2}] {frame.street.upper():<7} {frame.text:<40}"
+            f" pot {frame.pot:g}"
+        )
+        if frame.deltas or frame.kind == "start":
+            stacks = " | ".join(
+                f"{name} {stack:g}" for name, stack in frame.stacks.items()
+            )
+            lines.append(f"     stacks: {stacks}")
+        if frame.equities:
+            shares = " | ".join(
+                f"{name} {share * 100:.1f}%" for name, share in frame.equities.items()
+            )
+            lines.append(f"     equity: {shares}")
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("hand_id", help="Site hand id, e.g. 230000000001")
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument("--site", help="Site name when the id is ambiguous")
+    parser.add_argument(
+        "--no-equity", action="store_true", help="Skip equity at decision points"
+    )
+    parser.add_argument("--iterations", type=int, default=DEFAULT_REPLAY_ITERATIONS)
+    parser.add_argument("--json", action="store_true", help="Print frames as JSON")
+
+
+def run(args: argparse.Namespace):
+    with HandDB(args.db) as db:
+        try:
+            hand = find_hand(db, args.hand_id, args.site)
+        except (KeyError, ValueError) as exc:
+            raise SystemExit(f"error: {exc.args[0]}") from None
+    frames = build_replay(hand, not args.no_equity, args.iterations)
+    if args.json:
+        print(json.dumps([frame.to_dict() for frame in frames], indent=2))
+    else:
+        print(format_frames(frames))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Replay a stored hand")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,340 @@
+#!/usr/bin/env python3
+"""
+Multi-table tournament seating: the seat draw, balancing, and breaking tables.
+
+`Seating.draw` spreads the field over as few tables as fit, at random seats,
+with table sizes at most one apart. As players bust (`bust`) or register late
+(`add`) the tables are kept in line the way floors do it:
+
+- when everyone left fits at one table fewer, the highest-numbered table
+  breaks and its players are redrawn into the open seats of the shortest
+  tables
+- otherwise, while the biggest table has two or more players over the
+  smallest, one player moves from the biggest to a random open seat at the
+  smallest: the one due the big blind next, never the big blind, so nobody
+  skips or pays the blinds twice by moving
+
+Each table tracks its button (`set_button`) so the big blind is known. Every
+change bumps `version` and is appended to `events`, which the HTTP service
+streams to floor displays (`GET /api/tourney/seating/stream`). The CLI keeps
+the state in a JSON file between commands.
+
+Example:
+    python3 pokertools.py seating draw --count 50 --seats 9
+    python3 pokertools.py seating bust "Player 17"
+    python3 pokertools.py seating show
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import random
+import threading
+from collections import deque
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Dict, List, Optional, Sequence, Tuple
+
+
+DEFAULT_SEATS = 9
+MAX_SEATS = 10
+# Events kept for displays that reconnect
+MAX_EVENTS = 500
+
+
+@dataclass
+class Move:
+    player: str
+    from_table: int
+    from_seat: int
+    to_table: int
+    to_seat: int
+    reason: str
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+class Seating:
+    """Thread-safe seat assignments for a multi-table tournament"""
+
+    def __init__(
+        self, seats_per_table: int = DEFAULT_SEATS, rng: Optional[random.Random] = None
+    ):
+        if not 2 <= seats_per_table <= MAX_SEATS:
+            raise ValueError(f"Seats per table must be 2-{MAX_SEATS}")
+        self.lock = threading.Lock()
+        self.rng = rng or random.Random()
+        self.seats_per_table = seats_per_table
+        # {table: {seat: player}}
+        self.tables: Dict[int, Dict[int, str]] = {}
+        self.buttons: Dict[int, int] = {}
+        self.version = 0
+        self.events: deque = deque(maxlen=MAX_EVENTS)
+
+    def _event(self, kind: str, **details):
+        self.version += 1
+        self.events.append({"version": self.version, "type": kind, **details})
+
+    @property
+    def player_count(self) -> int:
+        return sum(len(seats) for seats in self.tables.values())
+
+    def locate(self, player: str) -> Tuple[int, int]:
+        for table, seats in self.tables.items():
+            for seat, name in seats.items():
+                if name == player:
+                    return table, seat
+        raise KeyError(f"{player} is not seated")
+
+    def big_blind_seat(self, table: int) -> Optional[int]:
+        """Seat posting the big blind this hand (None below two players)"""
+        return self._blind_order(table)[0]
+
+    def _blind_order(self, table: int) -> Tuple[Optional[int], Optional[int]]:
+        """(big blind, next big blind) seats at a table"""
+        occupied = sorted(self.tables[table])
+        if len(occupied) < 2:
+            return None, None
+        button = self.buttons.get(table, occupied[0])
+        # Clockwise from the seat after the button, ending at the button
+        order = [seat for seat in occupied if seat > button]
+        order += [seat for seat in occupied if seat <= button]
+        if len(occupied) == 2:
+            # Heads-up the button posts the small blind
+            big_blind = order[0] if order[-1] == button else order[1]
+            return big_blind, next(seat for seat in order if seat != big_blind)
+        return order[1], order[2]
+
+    def draw(self, players: Sequence[str], seats_per_table: Optional[int] = None):
+        """Random seats for the whole field, replacing any current seating"""
+        names = [str(player).strip() for player in players]
+        if len(names) < 2 or "" in names:
+            raise ValueError("A draw needs at least two named players")
+        if len(set(names)) != len(names):
+            raise ValueError("Player names must be unique")
+        seats = seats_per_table or self.seats_per_table
+        if not 2 <= seats <= MAX_SEATS:
+            raise ValueError(f"Seats per table must be 2-{MAX_SEATS}")
+        with self.lock:
+            self.seats_per_table = seats
+            count = -(-len(names) // seats)
+            self.rng.shuffle(names)
+            self.tables, self.buttons = {}, {}
+            for table in range(1, count + 1):
+                group = names[table - 1 :: count]
+                chosen = self.rng.sample(range(1, seats + 1), len(group))
+                self.tables[table] = dict(zip(chosen, group))
+                self.buttons[table] = self.rng.choice(chosen)
+            self._event("draw", players=len(names), tables=count)
+            return self._snapshot()
+
+    def _open_seat(self, exclude: Optional[int] = None) -> Tuple[int, int]:
+        """A random empty seat at the shortest table (lowest number on ties)"""
+        candidates = [
+            table
+            for table, seats in self.tables.items()
+            if table != exclude and len(seats) < self.seats_per_table
+        ]
+        if not candidates:
+            table = max(self.tables, default=0) + 1
+            self.tables[table] = {}
+        else:
+            table = min(
+                candidates, key=lambda number: (len(self.tables[number]), number)
+            )
+        empty = [
+            seat
+            for seat in range(1, self.seats_per_table + 1)
+            if seat not in self.tables[table]
+        ]
+        return table, self.rng.choice(empty)
+
+    def _move(self, player: str, to_table: int, to_seat: int, reason: str) -> Move:
+        from_table, from_seat = self.locate(player)
+        del self.tables[from_table][from_seat]
+        self.tables[to_table][to_seat] = player
+        move = Move(player, from_table, from_seat, to_table, to_seat, reason)
+        self._event("move", **move.to_dict())
+        return move
+
+    def _rebalance(self) -> List[Move]:
+        moves: List[Move] = []
+        while len(self.tables) > 1:
+            if self.player_count <= (len(self.tables) - 1) * self.seats_per_table:
+                broken = max(self.tables)
+                for seat in sorted(self.tables[broken]):
+                    player = self.tables[broken][seat]
+                    table, open_seat = self._open_seat(exclude=broken)
+                    moves.append(self._move(player, table, open_seat, "break"))
+                del self.tables[broken]
+                self.buttons.pop(broken, None)
+                self._event("break", table=broken)
+                continue
+            sizes = {table: len(seats) for table, seats in self.tables.items()}
+            biggest = min(sizes, key=lambda table: (-sizes[table], table))
+            smallest = min(sizes, key=lambda table: (sizes[table], table))
+            if sizes[biggest] - sizes[smallest] <= 1:
+                break
+            seat = self._blind_order(biggest)[1]
+            table, open_seat = self._open_seat(exclude=biggest)
+            moves.append(
+                self._move(self.tables[biggest][seat], table, open_seat, "balance")
+            )
+        return moves
+
+    def bust(self, player: str) -> List[Move]:
+        """Remove a player; returns the moves that rebalanced the tables"""
+        with self.lock:
+            table, seat = self.locate(player)
+            del self.tables[table][seat]
+            self._event("bust", player=player, table=table, seat=seat)
+            return self._rebalance()
+
+    def add(self, player: str) -> Tuple[int, int]:
+        """Seat a late registration at the shortest table"""
+        player = str(player).strip()
+        if not player:
+            raise ValueError("Player name is required")
+        with self.lock:
+            if any(player in seats.values() for seats in self.tables.values()):
+                raise ValueError(f"{player} is already seated")
+            table, seat = self._open_seat()
+            self.tables[table][seat] = player
+            self.buttons.setdefault(table, seat)
+            self._event("seat", player=player, table=table, seat=seat)
+            return table, seat
+
+    def set_button(self, table: int, seat: int):
+        with self.lock:
+            if table not in self.tables:
+                raise KeyError(f"No table {table}")
+            if not 1 <= seat <= self.seats_per_table:
+                raise ValueError(f"Seat must be 1-{self.seats_per_table}")
+            self.buttons[table] = seat
+            self._event("button", table=table, seat=seat)
+
+    def events_since(self, version: int) -> List[dict]:
+        with self.lock:
+            return [event for event in self.events if event["version"] > version]
+
+    def snapshot(self) -> dict:
+        with self.lock:
+            return self._snapshot()
+
+    def _snapshot(self) -> dict:
+        return {
+            "version": self.version,
+            "seats_per_table": self.seats_per_table,
+            "players": self.player_count,
+            "tables": [
+                {
+                    "table": table,
+                    "button": self.buttons.get(table),
+                    "big_blind": self.big_blind_seat(table),
+                    "seats": {
+                        str(seat): name for seat, name in sorted(seats.items())
+                    },
+                }
+                for table, seats in sorted(self.tables.items())
+            ],
+        }
+
+    def to_dict(self) -> dict:
+        return {**self.snapshot(), "events": list(self.events)}
+
+    @classmethod
+    def from_dict(cls, data: dict, rng: Optional[random.Random] = None) -> "Seating":
+        seating = cls(int(data.get("seats_per_table") or DEFAULT_SEATS), rng)
+        for table in data.get("tables", []):
+            number = int(table["table"])
+            seating.tables[number] = {
+                int(seat): name for seat, name in table["seats"].items()
+            }
+            if table.get("button") is not None:
+                seating.buttons[number] = int(table["button"])
+        seating.version = int(data.get("version") or 0)
+        seating.events.extend(data.get("events", []))
+        return seating
+
+
+def format_seating(state: dict) -> str:
+    lines = [f"{state['players']} players at {len(state['tables'])} tables"]
+    for table in state["tables"]:
+        lines.append(f"Table {table['table']} ({len(table['seats'])} players)")
+        for seat, name in table["seats"].items():
+            marks = ""
+            if int(seat) == table["button"]:
+                marks += " [D]"
+            if int(seat) == table["big_blind"]:
+                marks += " [BB]"
+            lines.append(f"  {seat:>2}  {name}{marks}")
+    return "\n".join(lines)
+
+
+def format_move(move: Move) -> str:
+    return (
+        f"{move.player}: table {move.from_table} seat {move.from_seat} -> "
+        f"table {move.to_table} seat {move.to_seat} ({move.reason})"
+    )
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument(
+        "--state", type=Path, default=Path("seating.json"), help="Seating file"
+    )
+    parser.add_argument("--seats", type=int, default=DEFAULT_SEATS)
+    parser.add_argument("--count", type=int, help='Draw "Player 1".."Player N"')
+    parser.add_argument("--seed", type=int)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    parser.add_argument("action", choices=("draw", "bust", "add", "show"))
+    parser.add_argument("players", nargs="*", help="Names to draw, bust, or add")
+
+
+def run(args: argparse.Namespace):
+    rng = random.Random(args.seed)
+    moves: List[Move] = []
+    lines: List[str] = []
+    try:
+        if args.action == "draw":
+            players = args.players or [
+                f"Player {number}" for number in range(1, (args.count or 0) + 1)
+            ]
+            seating = Seating(args.seats, rng)
+            seating.draw(players)
+        else:
+            if not args.state.exists():
+                raise SystemExit(f"error: {args.state} not found; run draw first")
+            seating = Seating.from_dict(json.loads(args.state.read_text()), rng)
+            for player in args.players:
+                if args.action == "bust":
+                    busted = seating.bust(player)
+                    moves += busted
+                    lines.append(f"{player} is out")
+                    lines += [format_move(move) for move in busted]
+                elif args.action == "add":
+                    table, seat = seating.add(player)
+                    lines.append(f"{player}: table {table} seat {seat}")
+    except KeyError as exc:
+        raise SystemExit(f"error: {exc.args[0]}") from None
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    args.state.write_text(json.dumps(seating.to_dict(), indent=2))
+    if args.json:
+        state = seating.snapshot()
+        print(json.dumps({**state, "moves": [move.to_dict() for move in moves]}))
+        return
+    if args.action in ("draw", "show"):
+        lines.append(format_seating(seating.snapshot()))
+    print("\n".join(lines))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Tournament seating")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,278 @@
+#!/usr/bin/env python3
+"""
+Deal simulator: random hands to showdown, tallied.
+
+`simulate` deals `hands` random hands of any supported game to `players`
+seats, everyone to showdown, and counts:
+
+- how the hero (seat 1) did: win, tie (split pot), or lose
+- hand class frequencies for the hero's final hand and for the winning hand
+- coolers: hands where the two best hands both make `cooler_class` or better,
+  and how often the hero lost holding one
+
+The hero can be pinned to exact cards ("AsKs") or, in two-card games, a range
+("AKs", "QQ+"; one weighted combo is drawn per hand). The board can be fixed
+too. Unconstrained hold'em also prints the textbook seven-card class
+frequencies next to the simulated hero column, which makes it a quick sanity
+check for the evaluators. Class and cooler counts need a high-hand game, so
+razz and 2-7 only report win/tie/lose.
+
+Example:
+    python3 pokertools.py sim --hero AKs --opponents 4 --hands 20000
+    python3 pokertools.py sim --game omaha --opponents 5 --cooler-class "full house"
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import random
+from dataclasses import dataclass, field
+from typing import Dict, Optional
+
+from cards import deck_mask, mask_to_cards, parse_cards
+from equity import normalize_player, parse_player_arg
+from game_evaluators import get_evaluator
+from hand_evaluator import CLASS_SHIFT, HandClass
+
+DEFAULT_HANDS = 10_000
+DEFAULT_OPPONENTS = 1
+DEFAULT_COOLER_CLASS = HandClass.STRAIGHT
+# Games whose main score packs a HandClass (higher is better)
+HIGH_GAMES = ("holdem", "shortdeck", "omaha", "omaha8", "stud")
+# Best-of-seven class odds in percent (7 random cards, 52-card deck)
+SEVEN_CARD_PERCENT = {
+    HandClass.HIGH_CARD: 17.41,
+    HandClass.PAIR: 43.82,
+    HandClass.TWO_PAIR: 23.50,
+    HandClass.TRIPS: 4.83,
+    HandClass.STRAIGHT: 4.62,
+    HandClass.FLUSH: 3.03,
+    HandClass.FULL_HOUSE: 2.60,
+    HandClass.QUADS: 0.168,
+    HandClass.STRAIGHT_FLUSH: 0.0311,
+}
+
+
+def parse_hand_class(text: str) -> HandClass:
+    """"flush", "full house", "full_house", or a HandClass name"""
+    name = str(text).strip().upper().replace(" ", "_").replace("-", "_")
+    aliases = {"SET": "TRIPS", "THREE_OF_A_KIND": "TRIPS", "FOUR_OF_A_KIND": "QUADS"}
+    try:
+        return HandClass[aliases.get(name, name)]
+    except KeyError:
+        names = ", ".join(hand.label.lower() for hand in HandClass)
+        raise ValueError(f"Unknown hand class {text!r}; expected one of {names}")
+
+
+@dataclass
+class SimResult:
+    game: str
+    players: int
+    hands: int
+    hero: str
+    board: str
+    wins: int = 0
+    ties: int = 0
+    losses: int = 0
+    hero_classes: Dict[str, int] = field(default_factory=dict)
+    winning_classes: Dict[str, int] = field(default_factory=dict)
+    cooler_class: Optional[str] = None
+    coolers: int = 0
+    hero_coolered: int = 0
+
+    def _percent(self, count: int) -> float:
+        return round(count / self.hands * 100, 3) if self.hands else 0.0
+
+    def to_dict(self) -> dict:
+        data = {
+            "game": self.game,
+            "players": self.players,
+            "hands": self.hands,
+            "hero": self.hero,
+            "board": self.board,
+            "outcomes": {
+                "win": self._percent(self.wins),
+                "tie": self._percent(self.ties),
+                "lose": self._percent(self.losses),
+            },
+        }
+        if self.cooler_class is not None:
+            data["hero_classes"] = {
+                name: self._percent(count) for name, count in self.hero_classes.items()
+            }
+            data["winning_classes"] = {
+                name: self._percent(count)
+                for name, count in self.winning_classes.items()
+            }
+            data["coolers"] = {
+                "class": self.cooler_class,
+                "rate": self._percent(self.coolers),
+                "hero_lost": self._percent(self.hero_coolered),
+            }
+        return data
+
+
+def simulate(
+    game: str = "holdem",
+    opponents: int = DEFAULT_OPPONENTS,
+    hands: int = DEFAULT_HANDS,
+    hero: Optional[str] = None,
+    board: str = "",
+    cooler_class: HandClass = DEFAULT_COOLER_CLASS,
+    seed: Optional[int] = None,
+) -> SimResult:
+    evaluator = get_evaluator(game)
+    players = opponents + 1
+    if opponents < 1 or hands < 1:
+        raise ValueError("Need at least one opponent and one hand")
+    board_cards = [card.index for card in parse_cards(board)] if board else []
+    if len(board_cards) > evaluator.board_cards:
+        raise ValueError(f"{evaluator.game} has at most {evaluator.board_cards} board")
+    needed = players * evaluator.hole_cards + evaluator.board_cards
+    if needed > len(mask_to_cards(deck_mask(evaluator.short_deck))):
+        raise ValueError(f"Not enough cards for {players} players of {game}")
+    hero_combos = []
+    if hero:
+        hero_combos = [
+            (combo, weight)
+            for combo, weight in normalize_player(
+                parse_player_arg(hero), evaluator.hole_cards
+            )
+            if not set(combo) & set(board_cards)
+        ]
+        if not hero_combos:
+            raise ValueError("Every hero combo collides with the board")
+
+    rng = random.Random(seed)
+    high = evaluator.game in HIGH_GAMES
+    labels = [hand.label for hand in HandClass]
+    result = SimResult(
+        evaluator.game,
+        players,
+        hands,
+        hero or "random",
+        board,
+        hero_classes=dict.fromkeys(labels, 0) if high else {},
+        winning_classes=dict.fromkeys(labels, 0) if high else {},
+        cooler_class=cooler_class.label if high else None,
+    )
+    full_deck = [card.index for card in mask_to_cards(deck_mask(evaluator.short_deck))]
+    live = [card for card in full_deck if card not in board_cards]
+    size = evaluator.hole_cards
+    for _ in range(hands):
+        if hero_combos:
+            combos, weights = zip(*hero_combos)
+            hero_cards = list(rng.choices(combos, weights)[0])
+            deck = [card for card in live if card not in hero_cards]
+        else:
+            deck = list(live)
+        rng.shuffle(deck)
+        if not hero_combos:
+            hero_cards, deck = deck[:size], deck[size:]
+        holes = [hero_cards]
+        for _ in range(opponents):
+            holes.append(deck[:size])
+            deck = deck[size:]
+        runout = board_cards + deck[: evaluator.board_cards - len(board_cards)]
+        scores = [evaluator.score(hole, runout) for hole in holes]
+
+        best = max(score.main for score in scores)
+        winners = [seat for seat, score in enumerate(scores) if score.main == best]
+        if winners == [0]:
+            result.wins += 1
+        elif 0 in winners:
+            result.ties += 1
+        else:
+            result.losses += 1
+        if not high:
+            continue
+        classes = [HandClass(score.main >> CLASS_SHIFT) for score in scores]
+        result.hero_classes[classes[0].label] += 1
+        result.winning_classes[HandClass(best >> CLASS_SHIFT).label] += 1
+        top_two = sorted(classes, reverse=True)[:2]
+        if top_two[1] >= cooler_class:
+            result.coolers += 1
+            if classes[0] >= cooler_class and 0 not in winners:
+                result.hero_coolered += 1
+    return result
+
+
+def format_result(result: SimResult) -> str:
+    data = result.to_dict()
+    outcomes = data["outcomes"]
+    title = (
+        f"{data['game']}: hero {data['hero']} vs {data['players'] - 1} opponents, "
+        f"{data['hands']:,} hands"
+    )
+    if data["board"]:
+        title += f", board {data['board']}"
+    lines = [
+        title,
+        f"Win {outcomes['win']:.2f}%  Tie {outcomes['tie']:.2f}%  "
+        f"Lose {outcomes['lose']:.2f}%",
+    ]
+    if "hero_classes" not in data:
+        return "\n".join(lines)
+    reference = data["game"] == "holdem" and data["hero"] == "random"
+    reference = reference and not data["board"]
+    header = f"{'Class':<16} {'Hero %':>8} {'Winner %':>9}"
+    lines.append(header + (f" {'Expected %':>10}" if reference else ""))
+    for hand in HandClass:
+        line = (
+            f"{hand.label:<16} {data['hero_classes'][hand.label]:>8.3f} "
+            f"{data['winning_classes'][hand.label]:>9.3f}"
+        )
+        if reference:
+            line += f" {SEVEN_CARD_PERCENT[hand]:>10.3f}"
+        lines.append(line)
+    coolers = data["coolers"]
+    lines.append(
+        f"Coolers ({coolers['class']} or better vs the same): "
+        f"{coolers['rate']:.2f}% of hands, hero lost one {coolers['hero_lost']:.2f}%"
+    )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--game", default="holdem")
+    parser.add_argument("--hero", help="Exact cards (AsKs) or a range (AKs, QQ+)")
+    parser.add_argument("--opponents", type=int, default=DEFAULT_OPPONENTS)
+    parser.add_argument("--hands", type=int, default=DEFAULT_HANDS)
+    parser.add_argument("--board", default="", help="Fixed board cards")
+    parser.add_argument(
+        "--cooler-class",
+        default=DEFAULT_COOLER_CLASS.label,
+        help="Both best hands at least this make a cooler",
+    )
+    parser.add_argument("--seed", type=int)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        result = simulate(
+            args.game,
+            args.opponents,
+            args.hands,
+            args.hero,
+            args.board,
+            parse_hand_class(args.cooler_class),
+            args.seed,
+        )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(result.to_dict(), indent=2))
+    else:
+        print(format_result(result))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Random deal simulator")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
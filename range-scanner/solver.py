@@ -0,0 +1,588 @@
+#!/usr/bin/env python3
+"""
+Counterfactual regret minimization (CFR+) solver for heads-up river spots.
+
+A spot is a five-card board, the out-of-position (OOP) and in-position (IP)
+ranges, the pot, and the effective stack behind. `SolverConfig` sets the
+abstraction:
+
+- `bet_sizes` / `raise_sizes`: fractions of the pot offered when betting or
+  raising (plus an all-in when `allin` is set), with at most `max_raises`
+  raises per street
+- `buckets`: 0 gives every combo its own strategy; N groups each range into N
+  strength buckets that share one, which shrinks the tree's information sets
+  (card removal still applies at showdown)
+
+`Solver.run` does vectorized CFR+ passes (regret matching+, alternating
+updates, linearly weighted averages) and reports `SolverProgress` to a
+callback every `report_every` iterations, including exploitability in percent
+of the pot. `Solver.save` / `Solver.load` write the regrets and averages to
+JSON (`state` / `from_state` keep them in memory, for job checkpoints) so a
+solve can be resumed, and `Solver.strategy` exports the average strategy per
+node (action path such as "x-b50", "" is the root) and combo.
+
+Only river spots are solved; earlier streets would need chance nodes for the
+cards to come.
+
+Example:
+    python3 pokertools.py solve --board Ks7d4c2h2s --oop "QQ+,AK,KQ,77,44" \\
+        --ip "TT+,AK,KQ,KJ,QJs,JTs" --pot 10 --stack 20 --bets 0.5 1 \\
+        --iterations 300 --save river.json
+    python3 pokertools.py solve --resume river.json --iterations 200 \\
+        --node x --export strategy.json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import time
+from dataclasses import asdict, dataclass, field
+from operator import mul
+from pathlib import Path
+from typing import Callable, Dict, List, Optional, Tuple
+
+from cards import format_cards, parse_cards
+from hand_range import Range, hand_class_of
+from lookup_evaluator import evaluate_indices
+
+
+DEFAULT_BET_SIZES = (0.5, 1.0)
+DEFAULT_RAISE_SIZES = (1.0,)
+DEFAULT_MAX_RAISES = 2
+DEFAULT_SOLVER_ITERATIONS = 300
+DEFAULT_REPORT_EVERY = 50
+OOP, IP = 0, 1
+
+
+@dataclass
+class SolverConfig:
+    board: str
+    ranges: List[str]
+    pot: float
+    stack: float
+    bet_sizes: List[float] = field(default_factory=lambda: list(DEFAULT_BET_SIZES))
+    raise_sizes: List[float] = field(
+        default_factory=lambda: list(DEFAULT_RAISE_SIZES)
+    )
+    max_raises: int = DEFAULT_MAX_RAISES
+    allin: bool = True
+    buckets: int = 0
+
+    def __post_init__(self):
+        if len(parse_cards(self.board)) != 5:
+            raise ValueError("The solver handles river spots: the board needs 5 cards")
+        if len(self.ranges) != 2:
+            raise ValueError("ranges must be [OOP range, IP range]")
+        if self.pot <= 0 or self.stack < 0:
+            raise ValueError("pot must be positive and stack non-negative")
+        if any(size <= 0 for size in [*self.bet_sizes, *self.raise_sizes]):
+            raise ValueError("Bet and raise sizes must be positive pot fractions")
+        if self.max_raises < 0 or self.buckets < 0:
+            raise ValueError("max_raises and buckets cannot be negative")
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+@dataclass
+class Node:
+    path: str
+    # Acting player, or None at a terminal
+    player: Optional[int]
+    committed: Tuple[float, float]
+    actions: List[str] = field(default_factory=list)
+    children: List["Node"] = field(default_factory=list)
+    # Terminal only: the player who folded, None for a showdown
+    folder: Optional[int] = None
+
+
+@dataclass
+class SolverProgress:
+    iteration: int
+    target: int
+    exploitability: float
+    elapsed: float
+
+    def to_dict(self) -> dict:
+        return {
+            "iteration": self.iteration,
+            "target": self.target,
+            "exploitability_pct": round(self.exploitability, 3),
+            "elapsed": round(self.elapsed, 2),
+        }
+
+
+def _size_label(prefix: str, size: float) -> str:
+    return f"{prefix}{round(size * 100)}"
+
+
+class _Hands:
+    """One player's combos on the board, sorted weakest to strongest"""
+
+    def __init__(self, range_text: str, board: List[int], buckets: int):
+        dead = 0
+        for card in board:
+            dead |= 1 << card
+        rows = []
+        for (first, second), weight in Range.parse(range_text).weighted_combos():
+            if dead & ((1 << first) | (1 << second)):
+                continue
+            strength = evaluate_indices([first, second, *board])
+            rows.append((strength, first, second, weight))
+        if not rows:
+            raise ValueError(f"Range {range_text!r} has no combos on this board")
+        rows.sort()
+        self.strengths = [row[0] for row in rows]
+        self.cards = [(row[1], row[2]) for row in rows]
+        self.weights = [row[3] for row in rows]
+        self.labels = [format_cards(cards) for cards in self.cards]
+        self.index = {cards: idx for idx, cards in enumerate(self.cards)}
+        if buckets:
+            # Equal-sized strength groups; ties never straddle a bucket edge
+            self.bucket_of = []
+            count = 0
+            for idx, strength in enumerate(self.strengths):
+                if idx and strength != self.strengths[idx - 1]:
+                    count = min(idx * buckets // len(rows), buckets - 1)
+                self.bucket_of.append(count)
+            self.bucket_count = self.bucket_of[-1] + 1
+        else:
+            self.bucket_of = list(range(len(rows)))
+            self.bucket_count = len(rows)
+
+
+class Solver:
+    def __init__(self, config: SolverConfig):
+        self.config = config
+        board = [card.index for card in parse_cards(config.board)]
+        self.hands = [_Hands(text, board, config.buckets) for text in config.ranges]
+        self.root = self._build("", OOP, (0.0, 0.0), 0)
+        self.nodes: Dict[str, Node] = {}
+        self._index(self.root)
+        # {path: [bucket][action]} for the acting player
+        self.regrets: Dict[str, List[List[float]]] = {}
+        self.strategy_sums: Dict[str, List[List[float]]] = {}
+        for path, node in self.nodes.items():
+            if node.player is not None:
+                count = self.hands[node.player].bucket_count
+                self.regrets[path] = [[0.0] * len(node.actions) for _ in range(count)]
+                self.strategy_sums[path] = [
+                    [0.0] * len(node.actions) for _ in range(count)
+                ]
+        self.iteration = 0
+        self.last_progress: Optional[SolverProgress] = None
+
+    # Tree
+
+    def _build(
+        self, path: str, player: int, committed: Tuple[float, float], raises: int
+    ) -> Node:
+        config = self.config
+        opponent = 1 - player
+        node = Node(path, player, committed)
+        to_call = committed[opponent] - committed[player]
+        pot = config.pot + sum(committed)
+        options: List[Tuple[str, Node]] = []
+
+        def child(label: str) -> str:
+            return f"{path}-{label}" if path else label
+
+        def wager(label: str, total: float, raises_after: int):
+            if total >= config.stack:
+                label, total = "a", config.stack
+            if label in (name for name, _ in options):
+                return
+            after = list(committed)
+            after[player] = total
+            options.append(
+                (label, self._build(child(label), opponent, tuple(after), raises_after))
+            )
+
+        if to_call == 0:
+            if player == IP:
+                options.append(("x", Node(child("x"), None, committed)))
+            else:
+                options.append(("x", self._build(child("x"), IP, committed, raises)))
+            if committed[player] < config.stack:
+                for size in config.bet_sizes:
+                    total = committed[player] + size * pot
+                    wager(_size_label("b", size), total, raises)
+                if config.allin:
+                    wager("a", config.stack, raises)
+        else:
+            options.append(("f", Node(child("f"), None, committed, folder=player)))
+            called = (committed[opponent], committed[opponent])
+            options.append(("c", Node(child("c"), None, called)))
+            if raises < config.max_raises and committed[opponent] < config.stack:
+                for size in config.raise_sizes:
+                    total = committed[opponent] + size * (pot + to_call)
+                    wager(_size_label("r", size), total, raises + 1)
+                if config.allin:
+                    wager("a", config.stack, raises + 1)
+        node.actions = [label for label, _ in options]
+        node.children = [branch for _, branch in options]
+        return node
+
+    def _index(self, node: Node):
+        self.nodes[node.path] = node
+        for branch in node.children:
+            self._index(branch)
+
+    # Terminal values
+
+    def _met(self, player: int, reach: List[float]) -> List[float]:
+        """Opponent reach each of `player`'s combos can meet (card removal)"""
+        mine, theirs = self.hands[player], self.hands[1 - player]
+        total = sum(reach)
+        per_card = [0.0] * 52
+        for (first, second), weight in zip(theirs.cards, reach):
+            per_card[first] += weight
+            per_card[second] += weight
+        met = []
+        for cards in mine.cards:
+            # The same two cards were subtracted once per card
+            same = theirs.index.get(cards)
+            overlap = reach[same] if same is not None else 0.0
+            met.append(total - per_card[cards[0]] - per_card[cards[1]] + overlap)
+        return met
+
+    def _showdown(self, player: int, reach: List[float]):
+        """Opponent reach each of `player`'s combos beats and loses to"""
+        mine, theirs = self.hands[player], self.hands[1 - player]
+
+        def sweep(order, beats):
+            below_total = 0.0
+            below_card = [0.0] * 52
+            result = [0.0] * len(mine.cards)
+            pointer = 0
+            others = list(order(range(len(theirs.cards))))
+            for idx in order(range(len(mine.cards))):
+                strength = mine.strengths[idx]
+                while pointer < len(others) and beats(
+                    strength, theirs.strengths[others[pointer]]
+                ):
+                    other = others[pointer]
+                    weight = reach[other]
+                    below_total += weight
+                    first, second = theirs.cards[other]
+                    below_card[first] += weight
+                    below_card[second] += weight
+                    pointer += 1
+                first, second = mine.cards[idx]
+                result[idx] = below_total - below_card[first] - below_card[second]
+            return result
+
+        wins = sweep(lambda indices: indices, lambda mine, other: other < mine)
+        losses = sweep(reversed, lambda mine, other: other > mine)
+        return wins, losses
+
+    def _terminal(self, node: Node, player: int, reach: List[float]) -> List[float]:
+        own = node.committed[player]
+        pot = self.config.pot + sum(node.committed)
+        met = self._met(player, reach)
+        if node.folder is not None:
+            gain = -own if node.folder == player else pot - own
+            return [gain * weight for weight in met]
+        wins, losses = self._showdown(player, reach)
+        return [
+            win * (pot - own) - loss * own + (seen - win - loss) * (pot / 2 - own)
+            for win, loss, seen in zip(wins, losses, met)
+        ]
+
+    # CFR+
+
+    def _current(self, node: Node) -> List[List[float]]:
+        """Regret-matching strategy per bucket"""
+        strategies = []
+        for regrets in self.regrets[node.path]:
+            positive = [max(regret, 0.0) for regret in regrets]
+            total = sum(positive)
+            if total > 0:
+                strategies.append([value / total for value in positive])
+            else:
+                strategies.append([1 / len(regrets)] * len(regrets))
+        return strategies
+
+    def _average(self, node: Node) -> List[List[float]]:
+        strategies = []
+        for sums in self.strategy_sums[node.path]:
+            total = sum(sums)
+            if total > 0:
+                strategies.append([value / total for value in sums])
+            else:
+                strategies.append([1 / len(sums)] * len(sums))
+        return strategies
+
+    def _cfr(self, node: Node, traverser: int, reach: List[List[float]]):
+        if node.player is None:
+            return self._terminal(node, traverser, reach[1 - traverser])
+        player = node.player
+        buckets = self.hands[player].bucket_of
+        strategy = self._current(node)
+        action_values = []
+        for action, branch in enumerate(node.children):
+            scaled = list(reach)
+            scaled[player] = [
+                weight * strategy[bucket][action]
+                for weight, bucket in zip(reach[player], buckets)
+            ]
+            action_values.append(self._cfr(branch, traverser, scaled))
+        if player != traverser:
+            return [sum(values) for values in zip(*action_values)]
+
+        values = [
+            sum(value * share for value, share in zip(values, strategy[bucket]))
+            for values, bucket in zip(zip(*action_values), buckets)
+        ]
+        regrets = self.regrets[node.path]
+        sums = self.strategy_sums[node.path]
+        weight = self.iteration + 1
+        for idx, bucket in enumerate(buckets):
+            for action, branch_values in enumerate(action_values):
+                regrets[bucket][action] += branch_values[idx] - values[idx]
+                sums[bucket][action] += (
+                    weight * reach[player][idx] * strategy[bucket][action]
+                )
+        for bucket_regrets in regrets:
+            for action, regret in enumerate(bucket_regrets):
+                if regret < 0:
+                    bucket_regrets[action] = 0.0
+        return values
+
+    def _best_response(self, node: Node, player: int, reach: List[List[float]]):
+        if node.player is None:
+            return self._terminal(node, player, reach[1 - player])
+        action_values = []
+        if node.player == player:
+            for branch in node.children:
+                action_values.append(self._best_response(branch, player, reach))
+            return [max(values) for values in zip(*action_values)]
+        strategy = self._average(node)
+        buckets = self.hands[node.player].bucket_of
+        for action, branch in enumerate(node.children):
+            scaled = list(reach)
+            scaled[node.player] = [
+                weight * strategy[bucket][action]
+                for weight, bucket in zip(reach[node.player], buckets)
+            ]
+            action_values.append(self._best_response(branch, player, scaled))
+        return [sum(values) for values in zip(*action_values)]
+
+    def exploitability(self) -> float:
+        """How much two best responses gain over the pot, in percent of it"""
+        reach = [list(hands.weights) for hands in self.hands]
+        gained = 0.0
+        for player in (OOP, IP):
+            met = self._met(player, reach[1 - player])
+            deals = sum(weight * seen for weight, seen in zip(reach[player], met))
+            if not deals:
+                raise ValueError("The ranges block each other completely")
+            values = self._best_response(self.root, player, reach)
+            gained += sum(map(mul, reach[player], values)) / deals
+        return max(gained - self.config.pot, 0.0) / 2 / self.config.pot * 100
+
+    def run(
+        self,
+        iterations: int = DEFAULT_SOLVER_ITERATIONS,
+        progress: Optional[Callable[[SolverProgress], None]] = None,
+        report_every: int = DEFAULT_REPORT_EVERY,
+        target_exploitability: float = 0.0,
+        stop: Optional[Callable[[], bool]] = None,
+    ) -> SolverProgress:
+        """Run up to `iterations` more passes; stops early at the target"""
+        if iterations <= 0:
+            raise ValueError("iterations must be positive")
+        started = time.monotonic()
+        target = self.iteration + iterations
+        while self.iteration < target:
+            reach = [list(hands.weights) for hands in self.hands]
+            for traverser in (OOP, IP):
+                self._cfr(self.root, traverser, reach)
+            self.iteration += 1
+            done = self.iteration == target or (stop is not None and stop())
+            if done or self.iteration % report_every == 0:
+                self.last_progress = SolverProgress(
+                    self.iteration,
+                    target,
+                    self.exploitability(),
+                    time.monotonic() - started,
+                )
+                if progress is not None:
+                    progress(self.last_progress)
+                if done or self.last_progress.exploitability <= target_exploitability:
+                    break
+        return self.last_progress
+
+    # Results
+
+    def strategy(self, path: Optional[str] = None) -> Dict[str, dict]:
+        """Average strategy {path: {player, actions, combos: {combo: freqs}}}"""
+        nodes = [self.node(path)] if path is not None else self.nodes.values()
+        exported = {}
+        for node in nodes:
+            if node.player is None:
+                continue
+            hands = self.hands[node.player]
+            average = self._average(node)
+            exported[node.path] = {
+                "player": "OOP" if node.player == OOP else "IP",
+                "actions": node.actions,
+                "combos": {
+                    label: [round(share, 4) for share in average[bucket]]
+                    for label, bucket in zip(hands.labels, hands.bucket_of)
+                },
+            }
+        return exported
+
+    def node(self, path: str) -> Node:
+        if path not in self.nodes:
+            raise ValueError(f"No node {path!r}")
+        return self.nodes[path]
+
+    def summary(self, path: str = "") -> Dict[str, dict]:
+        """Action frequencies at a node, overall and per hand class"""
+        node = self.node(path)
+        if node.player is None:
+            raise ValueError(f"{path!r} is a terminal node")
+        hands = self.hands[node.player]
+        average = self._average(node)
+        totals = [0.0] * len(node.actions)
+        classes: Dict[str, List[float]] = {}
+        for cards, weight, bucket in zip(hands.cards, hands.weights, hands.bucket_of):
+            row = classes.setdefault(hand_class_of(cards), [0.0] * (len(totals) + 1))
+            for action, share in enumerate(average[bucket]):
+                totals[action] += weight * share
+                row[action] += weight * share
+            row[-1] += weight
+        overall = sum(totals)
+        return {
+            "actions": node.actions,
+            "overall": [round(value / overall, 4) for value in totals],
+            "classes": {
+                name: [round(value / row[-1], 4) for value in row[:-1]]
+                for name, row in classes.items()
+            },
+        }
+
+    def state(self) -> dict:
+        return {
+            "config": self.config.to_dict(),
+            "iteration": self.iteration,
+            "regrets": self.regrets,
+            "strategy_sums": self.strategy_sums,
+        }
+
+    @classmethod
+    def from_state(cls, data: dict, source: str = "The saved state") -> "Solver":
+        solver = cls(SolverConfig(**data["config"]))
+        if set(data["regrets"]) != set(solver.regrets):
+            raise ValueError(f"{source} does not match the rebuilt game tree")
+        solver.regrets = data["regrets"]
+        solver.strategy_sums = data["strategy_sums"]
+        solver.iteration = data["iteration"]
+        return solver
+
+    def save(self, path: Path):
+        Path(path).write_text(json.dumps(self.state()))
+
+    @classmethod
+    def load(cls, path: Path) -> "Solver":
+        return cls.from_state(json.loads(Path(path).read_text()), str(path))
+
+
+def format_summary(summary: Dict[str, dict], path: str) -> str:
+    actions = summary["actions"]
+    lines = [
+        f"Node {path or '(root)'}: "
+        + ", ".join(
+            f"{action} {share:.1%}"
+            for action, share in zip(actions, summary["overall"])
+        ),
+        f"{'Hand':<6}" + "".join(f"{action:>8}" for action in actions),
+    ]
+    for name, shares in summary["classes"].items():
+        lines.append(f"{name:<6}" + "".join(f"{share:>8.1%}" for share in shares))
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--board", help="Five-card river board")
+    parser.add_argument("--oop", help="Out-of-position range (acts first)")
+    parser.add_argument("--ip", help="In-position range")
+    parser.add_argument("--pot", type=float, help="Pot at the start of the river")
+    parser.add_argument("--stack", type=float, help="Effective stack behind")
+    parser.add_argument(
+        "--bets", type=float, nargs="+", default=list(DEFAULT_BET_SIZES)
+    )
+    parser.add_argument(
+        "--raises", type=float, nargs="+", default=list(DEFAULT_RAISE_SIZES)
+    )
+    parser.add_argument("--max-raises", type=int, default=DEFAULT_MAX_RAISES)
+    parser.add_argument("--no-allin", action="store_true")
+    parser.add_argument(
+        "--buckets", type=int, default=0, help="Strength buckets (0: every combo)"
+    )
+    parser.add_argument("--iterations", type=int, default=DEFAULT_SOLVER_ITERATIONS)
+    parser.add_argument("--report-every", type=int, default=DEFAULT_REPORT_EVERY)
+    parser.add_argument(
+        "--target", type=float, default=0.0, help="Stop at this exploitability (%%)"
+    )
+    parser.add_argument("--resume", type=Path, help="Continue a saved solve")
+    parser.add_argument("--save", type=Path, help="Save the solve to resume later")
+    parser.add_argument("--export", type=Path, help="Write the strategy as JSON")
+    parser.add_argument("--node", default="", help="Node to summarize (root)")
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        if args.resume:
+            solver = Solver.load(args.resume)
+        else:
+            if not (args.board and args.oop and args.ip and args.pot):
+                raise ValueError("--board, --oop, --ip and --pot are required")
+            solver = Solver(
+                SolverConfig(
+                    args.board,
+                    [args.oop, args.ip],
+                    args.pot,
+                    args.stack or 0.0,
+                    args.bets,
+                    args.raises,
+                    args.max_raises,
+                    not args.no_allin,
+                    args.buckets,
+                )
+            )
+
+        def report(progress: SolverProgress):
+            if not args.json:
+                print(
+                    f"iteration {progress.iteration}/{progress.target}: "
+                    f"exploitability {progress.exploitability:.2f}% of pot "
+                    f"({progress.elapsed:.1f}s)"
+                )
+
+        result = solver.run(args.iterations, report, args.report_every, args.target)
+        summary = solver.summary(args.node)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.save:
+        solver.save(args.save)
+    if args.export:
+        args.export.write_text(json.dumps(solver.strategy(), indent=2))
+    if args.json:
+        print(json.dumps({"progress": result.to_dict(), "node": summary}, indent=2))
+    else:
+        print(format_summary(summary, args.node))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="CFR+ river solver")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,377 @@
+#!/usr/bin/env python3
+"""
+Standard per-player statistics computed from the SQLite hand database.
+
+`hand_stats` counts, for one `Hand`, what each dealt-in player did:
+
+- VPIP / PFR: put money in voluntarily / raised preflop (blinds don't count)
+- 3-bet: re-raised at the first decision facing exactly one preflop raise
+- fold to 3-bet: the preflop raiser folded when the pot came back 3-bet
+- limp: called the big blind at the first decision in an unopened pot
+- C-bet: the last preflop raiser bet the flop when checked to
+- WTSD / W$SD: reached showdown after seeing the flop / won money there
+- net and big blinds won (bb/100 is derived from the latter)
+- with `ev=True`, all-in EV adjusted results from `allin_ev` alongside them
+
+`compute_stats` adds those counters up per player, overall (`"all"`) and per
+position, stakes, or currency group, so HUDs and leak reports read the same
+numbers. Net winnings are in each hand's own money unless a `currency` is
+given: then cash hands are converted at the exchange rate of the day they were
+played (`money.py`) and tournament chips stay out of the money totals. Big
+blinds never need converting.
+
+Example:
+    python3 stats.py --db hands.sqlite --player Hero --by position
+    python3 stats.py --db hands.sqlite --player Hero --by currency --currency USD
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from dataclasses import asdict, dataclass, fields
+from pathlib import Path
+from typing import Callable, Dict, Iterable, Optional, Sequence
+
+from allin_ev import allin_result
+from handdb import HandDB, default_db_path
+from handhistory import FORCED_ACTIONS, Hand, Player
+from money import ExchangeRates, normalize_currency
+
+
+ALL_GROUP = "all"
+VOLUNTARY_ACTIONS = ("call", "bet", "raise")
+
+
+def stakes_label(hand: Hand) -> str:
+    prefix = "T" if hand.is_tournament else ""
+    return f"{prefix}{hand.small_blind:g}/{hand.big_blind:g}"
+
+
+BREAKDOWNS: Dict[str, Callable[[Hand, Player], str]] = {
+    "position": lambda hand, player: player.position or "?",
+    "stakes": lambda hand, player: stakes_label(hand),
+    "currency": lambda hand, player: hand.currency
+    or ("chips" if hand.is_tournament else "-"),
+}
+
+
+def _pct(count: int, chances: int) -> Optional[float]:
+    return round(count / chances * 100, 1) if chances else None
+
+
+@dataclass
+class PlayerStats:
+    """Raw counters; percentages are derived so groups can be summed"""
+
+    hands: int = 0
+    vpip: int = 0
+    pfr: int = 0
+    three_bet_chances: int = 0
+    three_bets: int = 0
+    fold_to_three_bet_chances: int = 0
+    folds_to_three_bet: int = 0
+    open_chances: int = 0
+    limps: int = 0
+    cbet_chances: int = 0
+    cbets: int = 0
+    saw_flop: int = 0
+    showdowns: int = 0
+    showdowns_won: int = 0
+    net: float = 0.0
+    net_bb: float = 0.0
+    allins: int = 0
+    ev_net: float = 0.0
+    ev_net_bb: float = 0.0
+
+    def __iadd__(self, other: "PlayerStats") -> "PlayerStats":
+        for item in fields(self):
+            setattr(
+                self, item.name, getattr(self, item.name) + getattr(other, item.name)
+            )
+        return self
+
+    @property
+    def vpip_pct(self) -> Optional[float]:
+        return _pct(self.vpip, self.hands)
+
+    @property
+    def pfr_pct(self) -> Optional[float]:
+        return _pct(self.pfr, self.hands)
+
+    @property
+    def three_bet_pct(self) -> Optional[float]:
+        return _pct(self.three_bets, self.three_bet_chances)
+
+    @property
+    def fold_to_three_bet_pct(self) -> Optional[float]:
+        return _pct(self.folds_to_three_bet, self.fold_to_three_bet_chances)
+
+    @property
+    def limp_pct(self) -> Optional[float]:
+        return _pct(self.limps, self.open_chances)
+
+    @property
+    def cbet_pct(self) -> Optional[float]:
+        return _pct(self.cbets, self.cbet_chances)
+
+    @property
+    def wtsd_pct(self) -> Optional[float]:
+        return _pct(self.showdowns, self.saw_flop)
+
+    @property
+    def wsd_pct(self) -> Optional[float]:
+        return _pct(self.showdowns_won, self.showdowns)
+
+    @property
+    def bb_per_100(self) -> Optional[float]:
+        return round(self.net_bb / self.hands * 100, 2) if self.hands else None
+
+    @property
+    def ev_bb_per_100(self) -> Optional[float]:
+        return round(self.ev_net_bb / self.hands * 100, 2) if self.hands else None
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        data["net"] = round(self.net, 2)
+        data["net_bb"] = round(self.net_bb, 2)
+        data["ev_net"] = round(self.ev_net, 2)
+        data["ev_net_bb"] = round(self.ev_net_bb, 2)
+        for name in (
+            "vpip",
+            "pfr",
+            "three_bet",
+            "fold_to_three_bet",
+            "limp",
+            "cbet",
+            "wtsd",
+            "wsd",
+        ):
+            data[f"{name}_pct"] = getattr(self, f"{name}_pct")
+        data["bb_per_100"] = self.bb_per_100
+        data["ev_bb_per_100"] = self.ev_bb_per_100
+        return data
+
+
+def hand_stats(hand: Hand, ev: bool = False) -> Dict[str, PlayerStats]:
+    """Counters for every dealt-in player of one hand
+
+    EV fields equal the actual result unless `ev` is set and the hand was an
+    all-in the player was part of.
+    """
+    stats = {
+        player.name: PlayerStats(hands=1)
+        for player in hand.players
+        if not player.sitting_out
+    }
+    folded = set()
+    raises = 0
+    aggressor = None
+    faced_raise = set()
+    opened = False
+    raiser = None
+    for action in hand.actions_on("preflop"):
+        current = stats.get(action.player)
+        if current is None or action.kind in FORCED_ACTIONS:
+            continue
+        if not opened:
+            current.open_chances += 1
+            current.limps += action.kind == "call"
+        if raises == 2 and action.player == raiser:
+            current.fold_to_three_bet_chances = 1
+            current.folds_to_three_bet = int(action.kind == "fold")
+            raiser = None
+        if raises == 1 and action.player not in faced_raise:
+            faced_raise.add(action.player)
+            current.three_bet_chances += 1
+            current.three_bets += action.kind == "raise"
+        if action.kind in VOLUNTARY_ACTIONS:
+            current.vpip = 1
+            opened = True
+        if action.kind == "raise":
+            current.pfr = 1
+            raises += 1
+            aggressor = action.player
+            if raises == 1:
+                raiser = action.player
+        elif action.kind == "fold":
+            folded.add(action.player)
+
+    if len(hand.board) >= 3:
+        for name, current in stats.items():
+            current.saw_flop = int(name not in folded)
+        for action in hand.actions_on("flop"):
+            if action.kind in ("bet", "raise") and action.player != aggressor:
+                break
+            if action.player == aggressor:
+                stats[aggressor].cbet_chances = 1
+                stats[aggressor].cbets = int(action.kind == "bet")
+                break
+
+    folded.update(action.player for action in hand.actions if action.kind == "fold")
+    remaining = [name for name in stats if name not in folded]
+    if len(remaining) >= 2:
+        for name in remaining:
+            if stats[name].saw_flop:
+                stats[name].showdowns = 1
+                stats[name].showdowns_won = int(hand.collected.get(name, 0) > 0)
+
+    for name, amount in hand.net().items():
+        if name in stats:
+            stats[name].net = amount
+            stats[name].net_bb = amount / hand.big_blind if hand.big_blind else 0.0
+            stats[name].ev_net = stats[name].net
+            stats[name].ev_net_bb = stats[name].net_bb
+    result = allin_result(hand) if ev else None
+    if result is not None:
+        for name in result.equities:
+            current = stats[name]
+            current.allins = 1
+            current.ev_net = result.ev_net[name]
+            if hand.big_blind:
+                current.ev_net_bb = current.ev_net / hand.big_blind
+    return stats
+
+
+def compute_stats(
+    hands: Iterable[Hand],
+    players: Optional[Sequence[str]] = None,
+    by: Optional[str] = None,
+    ev: bool = False,
+    currency: Optional[str] = None,
+    rates: Optional[ExchangeRates] = None,
+) -> Dict[str, Dict[str, PlayerStats]]:
+    """{player: {"all": totals, <group>: totals, ...}} over the given hands"""
+    if by is not None and by not in BREAKDOWNS:
+        raise ValueError(f"by must be one of {', '.join(BREAKDOWNS)}")
+    if currency and rates is None:
+        raise ValueError("Converting to a currency needs exchange rates")
+    wanted = set(players) if players else None
+    table: Dict[str, Dict[str, PlayerStats]] = {}
+    for hand in hands:
+        rate = 1.0
+        if currency:
+            # Chips are not money: tournament hands only count in big blinds
+            rate = 0.0
+            if not hand.is_tournament:
+                rate = rates.rate(hand.currency, currency, hand.started_at)
+        for name, counters in hand_stats(hand, ev).items():
+            if wanted is not None and name not in wanted:
+                continue
+            counters.net *= rate
+            counters.ev_net *= rate
+            groups = table.setdefault(name, {ALL_GROUP: PlayerStats()})
+            groups[ALL_GROUP] += counters
+            if by is not None:
+                group = BREAKDOWNS[by](hand, hand.player(name))
+                groups.setdefault(group, PlayerStats())
+                groups[group] += counters
+    return table
+
+
+def load_stats(
+    db: HandDB,
+    players: Optional[Sequence[str]] = None,
+    by: Optional[str] = None,
+    ev: bool = False,
+    currency: Optional[str] = None,
+) -> Dict[str, Dict[str, PlayerStats]]:
+    """Stats straight from the database, only loading hands the players are in"""
+    where, params = "", ()
+    if players:
+        placeholders = ", ".join("?" for _ in players)
+        where = (
+            "h.id IN (SELECT hand_pk FROM hand_players "
+            f"WHERE name IN ({placeholders}))"
+        )
+        params = tuple(players)
+    currency = normalize_currency(currency)
+    rates = ExchangeRates(db) if currency else None
+    return compute_stats(db.hands(where, params), players, by, ev, currency, rates)
+
+
+def stats_to_dict(table: Dict[str, Dict[str, PlayerStats]]) -> dict:
+    return {
+        name: {group: counters.to_dict() for group, counters in groups.items()}
+        for name, groups in table.items()
+    }
+
+
+def format_stats(
+    table: Dict[str, Dict[str, PlayerStats]],
+    min_hands: int = 0,
+    ev: bool = False,
+    currency: Optional[str] = None,
+) -> str:
+    def pct(value: Optional[float]) -> str:
+        return "-" if value is None else f"{value:.1f}"
+
+    def rate(value: Optional[float]) -> str:
+        return "-" if value is None else f"{value:+.2f}"
+
+    header = (
+        f"{'Player':<18} {'Group':<8} {'Hands':>6} {'VPIP':>5} {'PFR':>5} "
+        f"{'3Bet':>5} {'CBet':>5} {'WTSD':>5} {'W$SD':>5} {'bb/100':>8}"
+    )
+    if ev:
+        header += f" {'EV bb/100':>9}"
+    if currency:
+        header += f" {f'Net {currency}':>12}"
+    lines = [header]
+    ranked = sorted(table.items(), key=lambda item: -item[1][ALL_GROUP].hands)
+    for name, groups in ranked:
+        if groups[ALL_GROUP].hands < min_hands:
+            continue
+        for group, counters in groups.items():
+            line = (
+                f"{name:<18} {group:<8} {counters.hands:>6} "
+                f"{pct(counters.vpip_pct):>5} {pct(counters.pfr_pct):>5} "
+                f"{pct(counters.three_bet_pct):>5} {pct(counters.cbet_pct):>5} "
+                f"{pct(counters.wtsd_pct):>5} {pct(counters.wsd_pct):>5} "
+                f"{rate(counters.bb_per_100):>8}"
+            )
+            if ev:
+                line += f" {rate(counters.ev_bb_per_100):>9}"
+            if currency:
+                line += f" {counters.net:>+12,.2f}"
+            lines.append(line)
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--db", type=Path, default=default_db_path())
+    parser.add_argument(
+        "--player", action="append", help="Limit to this player (repeatable)"
+    )
+    parser.add_argument("--by", choices=sorted(BREAKDOWNS), help="Break down by")
+    parser.add_argument(
+        "--min-hands", type=int, default=0, help="Hide players with fewer hands"
+    )
+    parser.add_argument(
+        "--ev", action="store_true", help="Add all-in EV adjusted results"
+    )
+    parser.add_argument("--currency", help="Net winnings converted to this currency")
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        currency = normalize_currency(args.currency)
+        with HandDB(args.db) as db:
+            table = load_stats(db, args.player, args.by, args.ev, currency)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(stats_to_dict(table), indent=2))
+    else:
+        print(format_stats(table, args.min_hands, args.ev, currency))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Per-player poker statistics")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
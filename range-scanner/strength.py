@@ -0,0 +1,230 @@
+#!/usr/bin/env python3
+"""
+Hand strength percentiles: where a hold'em holding ranks right now.
+
+`hand_strength` compares a holding with every other two-card combo the board
+and the holding leave live, or with a range ("QQ+, AK"), by current made-hand
+strength only (no cards to come; that is what equity is for). Combos are
+counted by range weight, ties split in half:
+
+    percentile = (worse + ties / 2) / total * 100
+
+so the nuts is 100 and `top_percent` (100 - percentile) reads as "top 4% of
+hands". Preflop (no board) the order is the 169 classes in
+`hand_range.PREFLOP_ORDER`, with the other combos of the holding's own class
+counted as ties.
+
+`rank_combos` sorts every combo of a range on a board, strongest first, with
+each one's percentile against all hands, for the HUD overlay and trainers.
+
+Example:
+    python3 pokertools.py strength AsQd --board Ah7c2d
+    python3 pokertools.py strength JhTh --board Ah7c2d9s --range "AK, AQ, 77, 22"
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from bisect import bisect_left, bisect_right
+from dataclasses import dataclass
+from itertools import combinations
+from typing import Dict, List, Optional, Sequence, Union
+
+from cards import CardLike, format_cards, parse_cards, to_card
+from hand_evaluator import CLASS_SHIFT, HandClass
+from hand_range import PREFLOP_ORDER, Range, hand_class_of, make_combo
+from lookup_evaluator import evaluate_indices
+
+
+PREFLOP_RANK = {hand_class: rank for rank, hand_class in enumerate(PREFLOP_ORDER)}
+
+
+@dataclass
+class HandStrength:
+    hand: str
+    board: str
+    against: str
+    better: float
+    ties: float
+    worse: float
+    hand_class: str
+
+    @property
+    def total(self) -> float:
+        return self.better + self.ties + self.worse
+
+    @property
+    def percentile(self) -> float:
+        if not self.total:
+            return 100.0
+        return (self.worse + self.ties / 2) / self.total * 100
+
+    @property
+    def top_percent(self) -> float:
+        return 100 - self.percentile
+
+    def to_dict(self) -> dict:
+        return {
+            "hand": self.hand,
+            "board": self.board,
+            "against": self.against,
+            "hand_class": self.hand_class,
+            "better": round(self.better, 2),
+            "ties": round(self.ties, 2),
+            "worse": round(self.worse, 2),
+            "percentile": round(self.percentile, 2),
+            "top_percent": round(self.top_percent, 2),
+        }
+
+
+def _cards(value: Union[str, Sequence[CardLike]]) -> List[int]:
+    cards = parse_cards(value) if isinstance(value, str) else map(to_card, value)
+    return [card.index for card in cards]
+
+
+def _strength_key(combo: Sequence[int], board: List[int]):
+    """Comparable strength: lower preflop rank is better, so it is negated"""
+    if not board:
+        return -PREFLOP_RANK[hand_class_of(make_combo(*combo))]
+    return evaluate_indices([*combo, *board])
+
+
+def _check(hole: List[int], board: List[int]):
+    if len(hole) != 2:
+        raise ValueError("A hold'em holding is two cards")
+    if len(board) not in (0, 3, 4, 5):
+        raise ValueError("The board must be empty or 3-5 cards")
+    if len(set(hole + board)) != len(hole + board):
+        raise ValueError("The holding and board share a card")
+
+
+def _opponents(against: Optional[Union[str, Range]], dead: set):
+    if against is None:
+        pairs = combinations([card for card in range(52) if card not in dead], 2)
+        return [(pair, 1.0) for pair in pairs]
+    if isinstance(against, str):
+        against = Range.parse(against)
+    return [
+        (combo, weight)
+        for combo, weight in against.weighted_combos()
+        if not dead & set(combo)
+    ]
+
+
+def hand_strength(
+    hole: Union[str, Sequence[CardLike]],
+    board: Union[str, Sequence[CardLike]] = "",
+    against: Optional[Union[str, Range]] = None,
+) -> HandStrength:
+    """Rank `hole` against all live combos, or against the range `against`"""
+    hole_cards, board_cards = _cards(hole), _cards(board) if board else []
+    _check(hole_cards, board_cards)
+    opponents = _opponents(against, set(hole_cards + board_cards))
+    if not opponents:
+        raise ValueError("No combos of that range are live")
+    mine = _strength_key(hole_cards, board_cards)
+    better = ties = worse = 0.0
+    for combo, weight in opponents:
+        theirs = _strength_key(combo, board_cards)
+        if theirs > mine:
+            better += weight
+        elif theirs == mine:
+            ties += weight
+        else:
+            worse += weight
+    if board_cards:
+        hand_class = HandClass(mine >> CLASS_SHIFT).label
+    else:
+        hand_class = hand_class_of(make_combo(*hole_cards))
+    label = "all hands" if against is None else str(against)
+    return HandStrength(
+        format_cards(hole_cards),
+        format_cards(board_cards),
+        label,
+        better,
+        ties,
+        worse,
+        hand_class,
+    )
+
+
+def rank_combos(
+    hand_range: Union[str, Range], board: Union[str, Sequence[CardLike]] = ""
+) -> List[Dict]:
+    """Every live combo of a range, strongest first, with its percentile"""
+    board_cards = _cards(board) if board else []
+    if len(board_cards) not in (0, 3, 4, 5):
+        raise ValueError("The board must be empty or 3-5 cards")
+    dead = set(board_cards)
+    everyone = sorted(
+        _strength_key(combo, board_cards) for combo, _ in _opponents(None, dead)
+    )
+    rows = []
+    for combo, weight in _opponents(hand_range, dead):
+        key = _strength_key(combo, board_cards)
+        # Percentile against every live combo, the hand itself included
+        below = bisect_left(everyone, key)
+        tied = bisect_right(everyone, key) - below
+        percentile = (below + tied / 2) / len(everyone) * 100
+        rows.append(
+            {
+                "combo": format_cards(combo),
+                "weight": weight,
+                "percentile": round(percentile, 2),
+            }
+        )
+    return sorted(rows, key=lambda row: -row["percentile"])
+
+
+def format_strength(strength: HandStrength) -> str:
+    board = f" on {strength.board}" if strength.board else " preflop"
+    return (
+        f"{strength.hand}{board} ({strength.hand_class}): "
+        f"top {strength.top_percent:.1f}% vs {strength.against} "
+        f"(beats {strength.worse:g}, ties {strength.ties:g}, "
+        f"loses to {strength.better:g} of {strength.total:g} combos)"
+    )
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("hand", nargs="?", help="Hole cards, e.g. AsQd")
+    parser.add_argument("--board", default="", help="Flop, turn, or river")
+    parser.add_argument("--range", help="Compare with this range, not all hands")
+    parser.add_argument(
+        "--list", metavar="RANGE", help="Rank every combo of RANGE instead"
+    )
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    if not args.hand and not args.list:
+        raise SystemExit("error: give a hand or --list RANGE")
+    try:
+        if args.list:
+            rows = rank_combos(args.list, args.board)
+        else:
+            strength = hand_strength(args.hand, args.board, args.range)
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.list:
+        if args.json:
+            print(json.dumps(rows, indent=2))
+        else:
+            for row in rows:
+                print(f"{row['combo']}  {row['percentile']:6.2f}")
+        return
+    if args.json:
+        print(json.dumps(strength.to_dict(), indent=2))
+    else:
+        print(format_strength(strength))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Hand strength percentiles")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,97 @@
+#!/usr/bin/env python3
+"""
+All-in EV and session luck checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from allin_ev import allin_result, session_summaries
+from handhistory import Action, Hand, Player, parse_hand
+from stats import compute_stats
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND
+
+
+def _side_pot_hand() -> Hand:
+    """Short stack all-in preflop, the other two get it in on the river"""
+    return Hand(
+        site="test",
+        hand_id="1",
+        small_blind=1,
+        big_blind=2,
+        board=["2c", "7d", "9h", "Ts", "3c"],
+        players=[
+            Player(1, "Short", 50, ["As", "Ad"], "BTN"),
+            Player(2, "Kings", 200, ["Ks", "Kd"], "SB"),
+            Player(3, "Queens", 200, ["Qs", "Qd"], "BB"),
+        ],
+        actions=[
+            Action("Short", "preflop", "raise", 50, 48, all_in=True),
+            Action("Kings", "preflop", "call", 50),
+            Action("Queens", "preflop", "call", 50),
+            Action("Kings", "flop", "bet", 100),
+            Action("Queens", "flop", "call", 100),
+            Action("Kings", "river", "bet", 50, all_in=True),
+            Action("Queens", "river", "call", 50, all_in=True),
+        ],
+        collected={"Short": 150, "Kings": 300},
+        total_pot=450,
+        showdown=True,
+    )
+
+
+def test_preflop_allin():
+    hand = parse_hand(POKERSTARS_HAND)
+    result = allin_result(hand, iterations=5_000)
+    assert result.street == "preflop" and result.board == []
+    assert set(result.equities) == {"Alice", "Carol"}
+    # AKs against QQ is close to a coin flip
+    assert 0.42 < result.equities["Alice"] < 0.50
+    assert abs(sum(result.equities.values()) - 1) < 1e-3
+    assert abs(sum(result.ev_net.values())) < 0.05
+    assert result.ev_net["Bob Smith"] == result.net["Bob Smith"] == -78
+    assert allin_result(parse_hand(GGPOKER_HAND)) is None
+
+
+def test_side_pots_on_the_river():
+    result = allin_result(_side_pot_hand())
+    # Nothing left to come, so EV is exactly what happened
+    assert result.street == "river"
+    assert result.ev_net == result.net == {"Short": 100, "Kings": 100, "Queens": -200}
+
+
+def test_sessions_and_stats():
+    first = parse_hand(POKERSTARS_HAND)
+    later = parse_hand(POKERSTARS_HAND.replace("20:15:00", "20:40:00"))
+    next_day = parse_hand(POKERSTARS_HAND.replace("2024/01/05", "2024/01/06"))
+    sessions = session_summaries([first, later, next_day], "Alice", iterations=2_000)
+    assert [session.hands for session in sessions] == [2, 1]
+    assert sessions[0].start == "2024/01/05 20:15:00"
+    assert sessions[0].end == "2024/01/05 20:40:00"
+    assert sessions[0].allins == 2 and sessions[0].net == 1762
+    assert sessions[0].luck == round(sessions[0].net - sessions[0].ev_net, 2) > 0
+
+    table = compute_stats([first, parse_hand(GGPOKER_HAND)], ev=True)
+    alice = table["Alice"]["all"]
+    assert alice.allins == 1 and alice.ev_net < alice.net
+    hero = table["Hero"]["all"]
+    assert hero.allins == 0 and hero.ev_net == hero.net
+
+
+def main():
+    print("All-in EV - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_preflop_allin,
+        test_side_pots_on_the_river,
+        test_sessions_and_stats,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll all-in EV checks passed.")
+
+
+if __name__ == "__main__":
+    main()
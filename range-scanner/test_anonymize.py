@@ -0,0 +1,74 @@
+#!/usr/bin/env python3
+"""
+Hand history anonymizer checks
+"""
+
+import json
+import sys
+
+sys.path.insert(0, ".")
+
+from anonymize import Anonymizer
+from handhistory import parse_hand
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND
+
+
+def test_names_are_replaced_everywhere():
+    hand = parse_hand(POKERSTARS_HAND)
+    shared = Anonymizer().anonymize(hand)
+    text = json.dumps(shared.to_dict())
+    for secret in ("Alice", "Bob Smith", "Carol", "Dave", "3000000001", "20:15"):
+        assert secret not in text, secret
+    assert shared.hero == "Hero"
+    assert [player.name for player in shared.players] == [
+        "Hero",
+        "Player1",
+        "Player2",
+        "Player3",
+    ]
+    # Money and cards are untouched, and the original hand is not modified
+    assert shared.net()["Hero"] == hand.net()["Alice"]
+    assert shared.board == hand.board
+    assert hand.players[0].name == "Alice"
+
+
+def test_aliases_are_consistent_across_hands():
+    anonymizer = Anonymizer()
+    first = anonymizer.anonymize(parse_hand(POKERSTARS_HAND))
+    second = anonymizer.anonymize(parse_hand(POKERSTARS_HAND))
+    assert [p.name for p in first.players] == [p.name for p in second.players]
+    assert first.hand_id == second.hand_id
+
+    salted = Anonymizer(salt="secret").anonymize(parse_hand(GGPOKER_HAND))
+    again = Anonymizer(salt="secret").anonymize(parse_hand(GGPOKER_HAND))
+    assert [p.name for p in salted.players] == [p.name for p in again.players]
+    assert salted.players[0].name.startswith("Player-")
+
+
+def test_timestamp_modes():
+    hand = parse_hand(GGPOKER_HAND)
+    assert Anonymizer().anonymize(hand).started_at == ""
+    shifter = Anonymizer(timestamps="shift", keep_hero=True)
+    assert shifter.anonymize(hand).started_at == "2000/01/01 00:00:00"
+    later = parse_hand(GGPOKER_HAND.replace("21:00:00", "21:05:30"))
+    shifted = shifter.anonymize(later)
+    assert shifted.started_at == "2000/01/01 00:05:30"
+    assert shifted.hero == "Hero" and shifted.player("Hero")
+
+
+def main():
+    print("Anonymizer - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_names_are_replaced_everywhere,
+        test_aliases_are_consistent_across_hands,
+        test_timestamp_modes,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll anonymizer checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,65 @@
+#!/usr/bin/env python3
+"""
+Audit log checks: sensitive routes, redaction, filters, and append-only rows
+"""
+
+import sqlite3
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from audit import AuditLog, redact, sensitive_action
+from handdb import HandDB
+
+
+def test_sensitive_routes():
+    assert sensitive_action("GET", "/api/export") == "export"
+    assert sensitive_action("GET", "/api/hands/42/replay") == "hand_replay"
+    assert sensitive_action("DELETE", "/api/keys/3") == "api_keys"
+    assert sensitive_action("POST", "/api/ledger/games/1/entries") == "money"
+    assert sensitive_action("GET", "/api/stats") is None
+    assert sensitive_action("POST", "/api/equity") is None
+    assert redact("/api/hands?q=pot%3E50&key=pt_secret") == "/api/hands?q=pot%3E50"
+    assert redact("/api/export?key=pt_secret") == "/api/export"
+
+
+def test_log():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        log = AuditLog(db)
+        log.record("ops", "GET", "export", "/api/export?key=pt_x", 200, 1, "10.0.0.2")
+        log.record("anonymous", "GET", "hand_search", "/api/hands", 401)
+        entry = log.record("tv", "GET", "export", "/api/export", 403, 2)
+        assert entry.id == 3 and entry.resource == "/api/export"
+        assert [e.id for e in log.entries()] == [3, 2, 1]
+        assert [e.actor for e in log.entries(action="export")] == ["tv", "ops"]
+        assert log.entries(actor="ops")[0].remote == "10.0.0.2"
+        assert log.entries(actor="ops")[0].resource == "/api/export"
+        assert len(log.entries(limit=1)) == 1
+        assert log.entries(since="2999-01-01") == []
+        for statement in ("UPDATE audit_log SET actor = 'x'", "DELETE FROM audit_log"):
+            try:
+                db.conn.execute(statement)
+            except sqlite3.IntegrityError as exc:
+                assert "append-only" in str(exc)
+                continue
+            raise AssertionError(f"allowed {statement}")
+        assert len(log.entries()) == 3
+
+
+def main():
+    print("Audit - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_sensitive_routes,
+        test_log,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll audit checks passed.")
+
+
+if __name__ == "__main__":
+    main()
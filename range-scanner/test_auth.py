@@ -0,0 +1,90 @@
+#!/usr/bin/env python3
+"""
+API key and route role checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from auth import ApiKeys, allows, required_role
+from handdb import HandDB
+
+
+def test_route_roles():
+    assert required_role("GET", "/health") is None
+    assert required_role("POST", "/api/equity") == "viewer"
+    assert required_role("GET", "/api/tourney/clock/stream") == "viewer"
+    assert required_role("POST", "/api/tourney/clock") == "producer"
+    assert required_role("POST", "/api/fairdeal/verify") == "viewer"
+    assert required_role("POST", "/api/fairdeal/ab12/deal") == "player"
+    assert required_role("GET", "/api/hands/42/replay") == "commentator"
+    assert required_role("PUT", "/api/players/Villain/notes") == "producer"
+    assert required_role("GET", "/api/export") == "admin"
+    assert required_role("DELETE", "/api/keys/3") == "admin"
+    assert required_role("DELETE", "/api/jobs/ab12") == "commentator"
+    assert required_role("GET", "/api/range/heatmap") == "viewer"
+    # Routes no rule knows about stay closed
+    assert required_role("GET", "/api/something-new") == "admin"
+    assert allows("admin", "viewer") and allows("player", "player")
+    assert not allows("commentator", "producer")
+    assert allows("viewer", None)
+
+
+def test_keys():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        keys = ApiKeys(db)
+        key, secret = keys.create("overlay", "commentator")
+        assert secret.startswith("pt_") and key.prefix == secret[:8]
+        # Only the digest is stored
+        stored = db.conn.execute("SELECT key_hash FROM api_keys").fetchone()[0]
+        assert secret not in stored and len(stored) == 64
+        found = keys.authenticate(secret)
+        assert found.id == key.id and found.role == "commentator"
+        assert keys.get(key.id).last_used_at
+        # Within LAST_USED_RESOLUTION another request writes nothing
+        changes = db.conn.total_changes
+        assert keys.authenticate(secret).id == key.id
+        assert db.conn.total_changes == changes
+        db.conn.execute(
+            "UPDATE api_keys SET last_used_at = '2000-01-01 00:00:00' WHERE id = ?",
+            (key.id,),
+        )
+        assert keys.authenticate(secret).last_used_at > "2000-01-01 00:00:00"
+        assert keys.get(key.id).last_used_at > "2000-01-01 00:00:00"
+        assert keys.authenticate(secret + "x") is None
+        assert keys.authenticate(None) is None
+        keys.revoke(key.id)
+        assert keys.authenticate(secret) is None
+        assert keys.list()[0].revoked_at
+        for name, role in (("", "admin"), ("ops", "root")):
+            try:
+                keys.create(name, role)
+            except ValueError:
+                continue
+            raise AssertionError(f"created {name!r} as {role!r}")
+        try:
+            keys.revoke(99)
+        except KeyError:
+            pass
+        else:
+            raise AssertionError("revoked a missing key")
+
+
+def main():
+    print("Auth - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_route_roles,
+        test_keys,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll auth checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,65 @@
+#!/usr/bin/env python3
+"""
+Auto-import watcher checks (a fake clock stands in for waiting on files)
+"""
+
+import os
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from autoimport import FolderWatcher, hud_lines
+from handdb import HandDB
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND
+
+
+def _write(path: Path, text: str, mtime: float):
+    path.write_text(text)
+    os.utime(path, (mtime, mtime))
+
+
+def test_watcher():
+    now = [1_000_000.0]
+    with tempfile.TemporaryDirectory() as tmp:
+        root = Path(tmp) / "histories"
+        root.mkdir()
+        session = root / "session.txt"
+        with HandDB(Path(tmp) / "hands.sqlite") as db:
+            watcher = FolderWatcher(db, [root], settle=2.0, clock=lambda: now[0])
+            assert watcher.poll().files == []
+            # Just written: waits one poll for the file to settle
+            _write(session, POKERSTARS_HAND, now[0])
+            assert watcher.poll().files == []
+            result = watcher.poll()
+            assert result.files == [session] and result.inserted == 1
+            assert "Alice" in result.players
+            # Unchanged files are not read again
+            assert watcher.poll().files == []
+            # The client appends a hand; an old mtime counts as settled at once
+            now[0] += 60
+            _write(session, f"{POKERSTARS_HAND}\n\n{GGPOKER_HAND}", now[0] - 10)
+            result = watcher.poll()
+            assert result.inserted == 1 and "Alice" not in result.players
+            assert db.summary()["hands"] == 2
+            lines = hud_lines(db, ["Hero", "Nobody"])
+            assert len(lines) == 1 and lines[0].startswith("Hero")
+            assert " 1 hand " in lines[0]
+            assert hud_lines(db, []) == []
+
+
+def main():
+    print("Auto-import - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_watcher,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll auto-import checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,130 @@
+#!/usr/bin/env python3
+"""
+Backup archive checks: round trip, checksums, and refusing bad restores
+"""
+
+import hashlib
+import io
+import json
+import sqlite3
+import sys
+import tarfile
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from backup import checksum_path, create_backup, restore_backup, verify_backup
+from handdb import MIGRATIONS, HandDB
+from handhistory import parse_hands
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND
+
+
+def _refused(call, error=ValueError) -> bool:
+    try:
+        call()
+    except error:
+        return True
+    return False
+
+
+def test_round_trip():
+    with tempfile.TemporaryDirectory() as tmp:
+        root = Path(tmp)
+        db_path = root / "hands.sqlite"
+        with HandDB(db_path) as db:
+            db.insert_hands(parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND])))
+        (root / "seating.json").write_text('{"tables": []}')
+        archive = root / "backup.tar.gz"
+        manifest = create_backup(db_path, archive, [root / "seating.json"])
+        assert manifest.schema_version == len(MIGRATIONS)
+        assert [entry.name for entry in manifest.files] == [
+            "hands.sqlite",
+            "files/seating.json",
+        ]
+        assert checksum_path(archive).read_text().endswith("  backup.tar.gz\n")
+        assert verify_backup(archive) == manifest
+
+        # An existing database is kept unless forced
+        assert _refused(lambda: restore_backup(archive, db_path), FileExistsError)
+        target = root / "restored" / "hands.sqlite"
+        restored = restore_backup(archive, target)
+        assert restored == [target, root / "restored" / "seating.json"]
+        with HandDB(target) as db:
+            assert db.count() == 2
+        assert (root / "restored" / "seating.json").read_text() == '{"tables": []}'
+        assert len(restore_backup(archive, db_path, force=True)) == 2
+
+
+def _rewrite(archive: Path, members: dict, manifest: dict):
+    """Replace an archive's members and manifest, as a tampered copy would"""
+    with tarfile.open(archive, "w:gz") as tar:
+        for name, data in {**members, "manifest.json": json.dumps(manifest)}.items():
+            info = tarfile.TarInfo(name)
+            data = data.encode() if isinstance(data, str) else data
+            info.size = len(data)
+            tar.addfile(info, io.BytesIO(data))
+    checksum_path(archive).unlink(missing_ok=True)
+
+
+def test_damaged_backups():
+    with tempfile.TemporaryDirectory() as tmp:
+        root = Path(tmp)
+        db_path = root / "hands.sqlite"
+        HandDB(db_path).close()
+        archive = root / "backup.tar.gz"
+        manifest = create_backup(db_path, archive).to_dict()
+        with tarfile.open(archive) as tar:
+            database = tar.extractfile("hands.sqlite").read()
+        # A flipped byte fails the archive checksum, and the restore with it
+        data = bytearray(archive.read_bytes())
+        data[len(data) // 2] ^= 0xFF
+        archive.write_bytes(bytes(data))
+        assert _refused(lambda: verify_backup(archive))
+        assert _refused(lambda: restore_backup(archive, root / "new.sqlite"))
+        assert not (root / "new.sqlite").exists()
+        assert _refused(lambda: verify_backup(root / "missing.tar.gz"))
+
+        _rewrite(archive, {"hands.sqlite": database + b"x"}, manifest)
+        assert _refused(lambda: verify_backup(archive))
+        _rewrite(archive, {"hands.sqlite": database, "extra": "?"}, manifest)
+        assert _refused(lambda: verify_backup(archive))
+        evil = dict(manifest, files=manifest["files"] + [dict(name="files/../x")])
+        evil["files"][-1].update(size=1, sha256=hashlib.sha256(b"x").hexdigest())
+        _rewrite(archive, {"hands.sqlite": database, "files/../x": "x"}, evil)
+        assert _refused(lambda: verify_backup(archive))
+        _rewrite(archive, {"hands.sqlite": database}, manifest)
+        assert verify_backup(archive).files[0].name == "hands.sqlite"
+
+
+def test_newer_schema_is_refused():
+    with tempfile.TemporaryDirectory() as tmp:
+        root = Path(tmp)
+        db_path = root / "hands.sqlite"
+        HandDB(db_path).close()
+        conn = sqlite3.connect(db_path.as_posix())
+        conn.execute(f"PRAGMA user_version = {len(MIGRATIONS) + 1}")
+        conn.close()
+        # Backing up never migrates; restoring needs a schema this code knows
+        archive = root / "backup.tar.gz"
+        assert create_backup(db_path, archive).schema_version == len(MIGRATIONS) + 1
+        assert _refused(lambda: restore_backup(archive, root / "old.sqlite"))
+        assert not (root / "old.sqlite").exists()
+
+
+def main():
+    print("Backup - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_round_trip,
+        test_damaged_backups,
+        test_newer_schema_is_refused,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll backup checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,152 @@
+#!/usr/bin/env python3
+"""
+Bankroll and session tracking checks
+"""
+
+import sqlite3
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from bankroll import Bankroll, parse_stakes, parse_time
+from handdb import MIGRATIONS, HandDB
+from handhistory import parse_hand
+from test_handhistory import POKERSTARS_HAND
+
+
+def _with_bankroll(check):
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        check(Bankroll(db))
+
+
+def test_sessions_and_win_rates():
+    def check(bankroll):
+        bankroll.add_session(
+            "2024-01-05 20:00", "2024-01-05 23:30", 200, 345, "1/2", "Aria", hands=100
+        )
+        bankroll.add_session("2024-01-06 20:00", "2024-01-06 22:00", 200, 100, "$1/$2")
+        bankroll.add_session("2024-01-07T18:00", "2024-01-07T20:00", 500, 0, "2/5")
+        stakes = bankroll.by_stakes()
+        low = stakes["1/2"]
+        assert low.sessions == 2 and low.result == 45 and low.hours == 5.5
+        assert low.per_hour == 8.18
+        # Only the session with a hand count feeds bb/100: +72.5bb over 100 hands
+        assert low.hands == 100 and low.bb_per_100 == 72.5
+        assert stakes["2/5"].bb_per_100 is None
+        summary = bankroll.summary()
+        assert summary["sessions"] == 3 and summary["result"] == -455
+
+    _with_bankroll(check)
+
+
+def test_balance_history():
+    def check(bankroll):
+        bankroll.add_transaction(1000, "2024-01-01 10:00", "initial")
+        bankroll.add_session("2024-01-05 20:00", "2024-01-05 23:30", 200, 345, "1/2")
+        bankroll.add_transaction(-300, "2024-01-06 09:00")
+        history = bankroll.history()
+        assert [event["balance"] for event in history] == [1000, 1145, 845]
+        assert history[0]["event"] == "deposit: initial"
+        assert history[-1]["event"] == "withdrawal"
+        assert bankroll.balance() == 845
+
+    _with_bankroll(check)
+
+
+def test_currencies():
+    def check(bankroll):
+        bankroll.add_transaction(1000, "2024-01-01 10:00", currency="usd")
+        bankroll.add_session(
+            "2024-01-05 20:00", "2024-01-05 23:00", 200, 350, "€1/€2", currency="EUR"
+        )
+        bankroll.add_session(
+            "2024-02-05 20:00",
+            "2024-02-05 22:00",
+            300,
+            100,
+            "£1/£2",
+            hands=50,
+            currency="GBP",
+        )
+        try:
+            bankroll.summary()
+        except ValueError as exc:
+            assert "EUR, GBP" in str(exc)
+        else:
+            raise AssertionError("added euros to pounds")
+        bankroll.rates.set_rate("EUR", "USD", 1.1, "2024-01-01")
+        bankroll.rates.set_rate("GBP", "USD", 1.25, "2024-01-01")
+        summary = bankroll.summary("USD")
+        assert summary["currency"] == "USD"
+        assert summary["balance"] == 1000 + 165 - 250
+        euros = summary["stakes"]["1/2 EUR"]
+        assert euros["result"] == 165 and euros["per_hour"] == 55
+        # Big blinds come from the stakes' own currency
+        assert euros["result_bb"] == 75 and euros["bb_per_hour"] == 25
+        assert summary["stakes"]["1/2 GBP"]["bb_per_100"] == -200
+        history = bankroll.history("EUR")
+        assert [event["currency"] for event in history] == ["USD", "EUR", "GBP"]
+        assert history[1]["amount"] == 150
+        try:
+            bankroll.add_session("2024-01-05", "2024-01-05", 1, 1, currency="euro")
+        except ValueError:
+            pass
+        else:
+            raise AssertionError("accepted a bad currency code")
+
+    _with_bankroll(check)
+
+
+def test_validation():
+    assert parse_stakes("$0.5/$1") == (0.5, 1.0)
+    assert parse_time("2024-01-05T20:00") == "2024-01-05 20:00:00"
+
+    def check(bankroll):
+        for args in (
+            ("2024-01-05 20:00", "2024-01-05 19:00", 100, 0, "1/2"),
+            ("yesterday", "2024-01-05 19:00", 100, 0, "1/2"),
+            ("2024-01-05 20:00", "2024-01-05 21:00", 100, 0, "1-2"),
+        ):
+            try:
+                bankroll.add_session(*args)
+            except ValueError:
+                continue
+            raise AssertionError(f"{args} should be rejected")
+        assert bankroll.sessions() == []
+
+    _with_bankroll(check)
+
+
+def test_upgrade_from_v1():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "h.sqlite"
+        conn = sqlite3.connect(path.as_posix())
+        conn.executescript(f"{MIGRATIONS[0]}; PRAGMA user_version = 1;")
+        conn.close()
+        with HandDB(path) as db:
+            assert db.schema_version == len(MIGRATIONS) >= 2
+            assert db.insert_hand(parse_hand(POKERSTARS_HAND))
+            Bankroll(db).add_transaction(50, "2024-01-01 00:00")
+            assert db.count() == 1 and Bankroll(db).balance() == 50
+
+
+def main():
+    print("Bankroll - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_sessions_and_win_rates,
+        test_balance_history,
+        test_currencies,
+        test_validation,
+        test_upgrade_from_v1,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll bankroll checks passed.")
+
+
+if __name__ == "__main__":
+    main()
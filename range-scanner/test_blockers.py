@@ -0,0 +1,59 @@
+#!/usr/bin/env python3
+"""
+Blocker and combinatorics checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from blockers import blocker_report
+
+
+def test_counts_and_shift():
+    report = blocker_report("AA, KK, AK, QQ", "Ks8h4c2s7d", "AhKh")
+    assert report.hero_class == "Pair"
+    # KK has three combos left after the board, the hero's king leaves one
+    assert report.classes == {"Three of a Kind": [3, 1], "Pair": [24, 15]}
+    # AA and KK are value, QQ bluffs, AK chops
+    before, after = report.before, report.after
+    assert (before.value, before.bluffs, before.chops) == (9, 6, 12)
+    assert (after.value, after.bluffs, after.chops) == (4, 6, 6)
+    assert before.value_share == 60 and after.value_share == 40
+    assert report.blocked_by == {"Ah": 6, "Kh": 6}
+    assert report.to_dict()["value_share_shift"] == -20
+
+
+def test_weights_and_errors():
+    report = blocker_report("AA:0.5, 76s", "Kh8h4c2s9d", "AsTc")
+    # The ace of spades blocks three half-weight AA combos; 76s misses entirely
+    assert report.before.value == 3 and report.after.value == 1.5
+    assert report.before.bluffs == 4 and report.blocked_by["Tc"] == 0
+    for bad in (
+        ("AA", "Kh8h", "AsTc"),
+        ("AA", "Kh8h4c", "Kh2c"),
+        ("AA", "Kh8h4c", "As"),
+        ("AA", "AsAhAd", "KsKd"),
+    ):
+        try:
+            blocker_report(*bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def main():
+    print("Blockers - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_counts_and_shift,
+        test_weights_and_errors,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll blocker checks passed.")
+
+
+if __name__ == "__main__":
+    main()
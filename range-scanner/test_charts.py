@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""
+Preflop chart and trainer checks
+"""
+
+import random
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from charts import Chart, ChartBook, Trainer, grid_labels, parse_csv, parse_json
+from handdb import HandDB
+
+BTN_OPEN = Chart("btn", "open", {"raise": "22+,A2s+,K9s+,ATo+,KJo+,AKo:0.5"})
+
+
+def test_frequencies():
+    assert BTN_OPEN.frequencies("AA") == {"raise": 1.0, "fold": 0.0}
+    assert BTN_OPEN.frequencies("72o") == {"raise": 0.0, "fold": 1.0}
+    # The later AKo:0.5 term overrides ATo+
+    assert BTN_OPEN.frequencies("AKo") == {"raise": 0.5, "fold": 0.5}
+    labels = grid_labels(BTN_OPEN)
+    assert (labels["AA"], labels["72o"], labels["AKo"]) == ("R", ".", "R50")
+
+    for bad in (
+        {"position": "BTN", "situation": "3bet", "actions": {"3bet": "QQ+"}},
+        {"position": "XX", "situation": "open", "actions": {"raise": "QQ+"}},
+        {"position": "CO", "situation": "open", "actions": {"fold": "72o"}},
+        {"position": "CO", "situation": "open", "actions": {"raise": "QQ++"}},
+    ):
+        try:
+            Chart.from_dict(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_book_import_export():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        book = ChartBook(db)
+        charts = parse_json(
+            """[
+              {"position": "BTN", "situation": "open", "actions": {"raise": "22+"}},
+              {"position": "BB", "situation": "3bet", "versus": "btn",
+               "stack_bb": 40, "actions": {"3bet": "TT+", "call": "22-99"}}
+            ]"""
+        )
+        saved = book.import_charts(charts)
+        assert [chart.id for chart in saved] == [1, 2]
+        # Same spot again replaces the chart instead of adding one
+        book.save(Chart("BTN", "open", {"raise": "55+"}))
+        assert len(book.all()) == 2
+        assert book.get(1).actions == {"raise": "55+"}
+
+        found = book.find("BB", "3bet", "BTN", stack_bb=100)
+        assert found is not None and found.stack_bb == 40
+        assert book.find("BB", "3bet", "CO") is None
+
+        names = {chart.name for chart in book.all()}
+        assert {chart.name for chart in parse_csv(book.export_csv())} == names
+        assert parse_json(book.export_json())[1].actions == {"raise": "55+"}
+        assert book.delete(2) and not book.delete(2)
+
+
+def test_trainer():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        chart = ChartBook(db).save(BTN_OPEN)
+        trainer = Trainer(db, random.Random(5))
+        quiz = trainer.question(situation="open")
+        assert quiz["chart_id"] == chart.id and quiz["options"] == ["raise", "fold"]
+        assert len(quiz["hand"]) == 4
+
+        assert trainer.answer(chart.id, "AA", "raise")["correct"]
+        assert not trainer.answer(chart.id, "72o", "raise")["correct"]
+        # Either side of a mixed strategy counts
+        assert trainer.answer(chart.id, "AKo", "fold")["correct"]
+        stats = trainer.accuracy()
+        assert (stats["answered"], stats["correct"]) == (3, 2)
+        assert stats["by_chart"][0]["key"] == chart.name
+        assert len(stats["by_day"]) == 1
+
+        for args in ((chart.id, "AA", "limp"), (99, "AA", "raise")):
+            try:
+                trainer.answer(*args)
+            except (KeyError, ValueError):
+                continue
+            raise AssertionError(f"accepted {args}")
+
+
+def main():
+    print("Preflop Charts - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_frequencies,
+        test_book_import_export,
+        test_trainer,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll preflop chart checks passed.")
+
+
+if __name__ == "__main__":
+    main()
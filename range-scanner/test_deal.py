@@ -0,0 +1,66 @@
+#!/usr/bin/env python3
+"""
+Final-table deal checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from deal import calculate_deal
+from payouts import round_amounts
+
+
+def test_heads_up():
+    deal = calculate_deal([3000, 1000], [70, 30])
+    assert deal.icm == [60, 40] and deal.chip_chop == [75, 25]
+    # Both have 30 locked up already; the other 40 goes by ICM share
+    assert deal.save == 30 and deal.played_for == 40
+    assert deal.save_deal == [54, 46]
+
+    bigger = calculate_deal([3000, 1000], [70, 30], save=35)
+    assert bigger.save_deal == [53, 47]
+    no_save = calculate_deal([3000, 1000], [70, 30], save=0)
+    assert no_save.save_deal == no_save.icm
+
+
+def test_rounding_keeps_the_pool():
+    deal = calculate_deal([2_500_000, 1_400_000, 600_000], [52_000, 31_000, 19_500])
+    for numbers in (deal.icm, deal.chip_chop, deal.save_deal):
+        assert sum(numbers) == 102_500
+        assert all(amount == int(amount) for amount in numbers)
+    assert deal.chip_chop[0] > deal.icm[0] > deal.save_deal[0]
+    assert round_amounts([10.6, 20.7], 31.3, 1) == [10, 21.3]
+    assert round_amounts([1.25], 1.25, 0) == [1.25]
+
+
+def test_places_already_paid():
+    # Four payouts left but only three players: fourth is already paid out
+    deal = calculate_deal([50, 30, 20], [500, 300, 200, 100])
+    assert deal.payouts == [500, 300, 200] and deal.prize_pool == 1_000
+    # With unpaid seats left there is nothing locked up yet
+    assert calculate_deal([50, 30, 20], [600, 400]).save == 0
+    for args in (([50, 30], [70, 30], 60), ([50, 30], [0, 0], None)):
+        try:
+            calculate_deal(*args)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {args}")
+
+
+def main():
+    print("Deal - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_heads_up,
+        test_rounding_keeps_the_pool,
+        test_places_already_paid,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll deal checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""
+Equity calculator regression checks against well-known matchups
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from equity import calculate_equity
+from hand_range import Range
+
+
+def approx(value: float, expected: float, tolerance: float) -> bool:
+    return abs(value - expected) <= tolerance
+
+
+def test_exhaustive_flop():
+    result = calculate_equity(["AsKs", "QdQc"], board="Ah7c2d", workers=1)
+    assert result.exhaustive
+    assert result.iterations == 990
+    assert approx(result.equities[0], 0.9121, 0.0001), result.equities
+    assert approx(sum(result.equities), 1.0, 1e-9)
+
+
+def test_river_split():
+    result = calculate_equity(["AsKd", "AhKc"], board="QsJdTc4h2s", workers=1)
+    assert result.equities == [0.5, 0.5], result.equities
+    assert result.ties == [1.0, 1.0]
+    assert result.split_pots == {2: 1.0} and result.tie_frequency == 1.0
+
+
+def test_monte_carlo_preflop():
+    result = calculate_equity(
+        ["AhAd", "KsKd"], exhaustive_limit=0, iterations=40000, seed=7, workers=1
+    )
+    assert not result.exhaustive
+    # Exact AA vs KK (no shared suits) is 81.95%
+    assert approx(result.equities[0], 0.8195, max(result.ci95[0] * 2, 0.01))
+
+
+def test_monte_carlo_shards():
+    spot = dict(board="Jh7h2c", exhaustive_limit=0, iterations=25_000, seed=11)
+    # Shards are seeded by index, so the worker count cannot change the answer
+    single = calculate_equity(["AsKs", "QhTh"], workers=1, **spot)
+    pooled = calculate_equity(["AsKs", "QhTh"], workers=3, **spot)
+    assert single.equities == pooled.equities
+    seen = []
+    early = calculate_equity(
+        ["AsKs", "QhTh"], workers=1, target_ci=0.05, progress=seen.append, **spot
+    )
+    # One 10,000-sample shard is already within 5%
+    assert early.iterations == 10_000 and len(seen) == 1
+    assert early.equities == seen[0].equities
+
+
+def test_weighted_range():
+    # A range that is 100% one combo must match that combo exactly
+    fixed = calculate_equity(["AsKs", "QdQc"], board="Ah7c2d", workers=1)
+    ranged = calculate_equity(
+        ["AsKs", [("QdQc", 1.0), ("QhQs", 0.0)]], board="Ah7c2d", workers=1
+    )
+    assert ranged.equities == fixed.equities
+
+
+def test_omaha_hilo_scoop_and_split():
+    result = calculate_equity(
+        ["AsAd2h3h", "KsKdQcJc"], board="4c5d9s", game="omaha8", workers=1
+    )
+    assert result.exhaustive
+    assert approx(result.equities[0], 0.861, 0.001), result.equities
+
+
+def test_multiway_ranges():
+    result = calculate_equity(
+        ["AsKs", Range.parse("QQ,JJ"), "9h8h"], board="Ah7c2d", workers=1
+    )
+    assert result.exhaustive
+    assert approx(sum(result.equities), 1.0, 1e-9)
+    # Class breakdown is a weighted split of the player's overall equity
+    classes = result.hand_classes[1]
+    assert set(classes) == {"QQ", "JJ"}
+    assert approx(sum(freq for freq, _ in classes.values()), 1.0, 1e-9)
+    blended = sum(freq * equity for freq, equity in classes.values())
+    assert approx(blended, result.equities[1], 1e-9)
+
+
+def test_player_limit():
+    hands = ["AsAd", "KsKd", "QsQd", "JsJd", "TsTd", "9s9d", "8s8d", "7s7d"]
+    nine = calculate_equity(hands + ["6s6d"], exhaustive_limit=0, iterations=200)
+    assert len(nine.equities) == 9
+    try:
+        calculate_equity(hands + ["6s6d", "5s5d"], iterations=200)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("ten players should be rejected")
+
+
+def main():
+    print("Equity Calculator - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_exhaustive_flop,
+        test_river_split,
+        test_weighted_range,
+        test_omaha_hilo_scoop_and_split,
+        test_multiway_ranges,
+        test_player_limit,
+        test_monte_carlo_preflop,
+        test_monte_carlo_shards,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll equity checks passed.")
+
+
+if __name__ == "__main__":
+    main()
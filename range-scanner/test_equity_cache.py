@@ -0,0 +1,86 @@
+#!/usr/bin/env python3
+"""
+Equity cache checks: suit isomorphism, LRU eviction, and persistence
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from equity_cache import EquityCache, canonical_key
+from hand_range import Range
+
+
+def test_canonical_key():
+    key = canonical_key(["AsKs", "QdQc"])
+    assert key == canonical_key(["AhKh", "QsQc"])
+    assert key == canonical_key(["KcAc", "QhQd"])
+    # Suited vs offsuit, the player order, and the work limits all matter
+    assert key != canonical_key(["AsKh", "QdQc"])
+    assert key != canonical_key(["QdQc", "AsKs"])
+    assert key != canonical_key(["AsKs", "QdQc"], iterations=10)
+    flop = canonical_key(["AsKs", "JhTh"], board="Qs9h2c")
+    assert flop == canonical_key(["AdKd", "JcTc"], board="2hQd9c")
+    assert flop != canonical_key(["AsKs", "JhTh"], board="Qs9s2c")
+    ranged = canonical_key([Range.parse("QQ+,AKs"), "JhTh"], board="9h8c2d")
+    assert ranged == canonical_key([Range.parse("AKs,QQ+"), "JsTs"], board="9s8d2c")
+
+
+def test_cache_hits_and_eviction():
+    cache = EquityCache(size=2)
+    first, hit = cache.calculate(["AsKs", "JhTh"], board="Qs9h2c", workers=1)
+    assert not hit
+    second, hit = cache.calculate(["AdKd", "JcTc"], board="2hQd9c", workers=1)
+    assert hit and second.equities == first.equities
+    assert second.board == "2hQd9c"
+    cache.calculate(["AsAd", "KsKd"], board="2c3c4c", workers=1)
+    cache.calculate(["AsAd", "KsKd"], board="2c3c5c", workers=1)
+    # The oldest entry was evicted
+    _, hit = cache.calculate(["AsKs", "JhTh"], board="Qs9h2c", workers=1)
+    assert not hit
+    assert cache.stats()["entries"] == 2 and cache.stats()["hits"] == 1
+    try:
+        EquityCache(size=0)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("accepted a zero-size cache")
+
+
+def test_persistence():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "equity_cache.sqlite"
+        cache = EquityCache(path=path)
+        first, _ = cache.calculate(
+            [Range.parse("TT+"), "AhKh"], board="Kd7h2h", workers=1
+        )
+        cache.close()
+        cache = EquityCache(path=path)
+        again, hit = cache.calculate(
+            [Range.parse("TT+"), "AsKs"], board="Kc7s2s", workers=1
+        )
+        cache.close()
+        assert hit
+        assert again.equities == first.equities
+        assert again.split_pots == first.split_pots
+        assert again.hand_classes == first.hand_classes
+
+
+def main():
+    print("Equity Cache - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_canonical_key,
+        test_cache_hits_and_eviction,
+        test_persistence,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll equity cache checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,97 @@
+#!/usr/bin/env python3
+"""
+CSV / xlsx export checks
+"""
+
+import csv
+import io
+import sys
+import tempfile
+import zipfile
+from pathlib import Path
+from xml.etree import ElementTree
+
+sys.path.insert(0, ".")
+
+from bankroll import Bankroll
+from export import MAIN_NS, collect, export, parse_columns
+from handdb import HandDB
+from handhistory import parse_hands
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND, WINAMAX_HAND
+
+
+def _db(tmp: str) -> HandDB:
+    db = HandDB(Path(tmp) / "h.sqlite")
+    sample = "\n\n".join([POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND])
+    db.insert_hands(parse_hands(sample))
+    Bankroll(db).add_session(
+        "2024-01-05 20:00", "2024-01-05 23:30", 200, 345, "1/2", venue="Aria, Vegas"
+    )
+    return db
+
+
+def test_csv_columns():
+    with tempfile.TemporaryDirectory() as tmp, _db(tmp) as db:
+        columns = parse_columns(["stats=player,hands,bb_per_100"])
+        text = export(db, "csv", ["stats"], columns, players=["Hero"]).decode()
+        rows = list(csv.reader(io.StringIO(text)))
+        assert rows == [["player", "hands", "bb_per_100"], ["Hero", "2", "1035.0"]]
+
+        sessions = export(db, "csv", ["sessions"]).decode()
+        header, row = list(csv.reader(io.StringIO(sessions)))
+        assert row[header.index("venue")] == "Aria, Vegas"
+        assert row[header.index("result")] == "145.0"
+
+    for bad in (["stats=nope"], ["nope=hands"], ["stats="]):
+        try:
+            parse_columns(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_rake_summary():
+    with tempfile.TemporaryDirectory() as tmp, _db(tmp) as db:
+        header, rows = collect(db, ["rake"])["rake"]
+        by_stakes = {row[0]: dict(zip(header, row)) for row in rows}
+    assert set(by_stakes) == {"T15/30", "0.02/0.05", "0.01/0.02"}
+    assert sum(entry["hands"] for entry in by_stakes.values()) == 3
+
+
+def test_xlsx_workbook():
+    with tempfile.TemporaryDirectory() as tmp, _db(tmp) as db:
+        data = export(db, "xlsx", ["stats", "sessions", "rake"])
+        try:
+            export(db, "csv", ["stats", "rake"])
+        except ValueError:
+            pass
+        else:
+            raise AssertionError("CSV accepted two tables")
+    with zipfile.ZipFile(io.BytesIO(data)) as archive:
+        assert archive.testzip() is None
+        workbook = ElementTree.fromstring(archive.read("xl/workbook.xml"))
+        names = [sheet.get("name") for sheet in workbook.iter(f"{{{MAIN_NS}}}sheet")]
+        assert names == ["stats", "sessions", "rake"]
+        sheet = ElementTree.fromstring(archive.read("xl/worksheets/sheet2.xml"))
+    rows = sheet.findall(f".//{{{MAIN_NS}}}row")
+    assert len(rows) == 2
+    texts = [node.text for node in rows[1].iter(f"{{{MAIN_NS}}}t")]
+    assert "Aria, Vegas" in texts
+
+
+def main():
+    print("Export - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_csv_columns,
+        test_rake_summary,
+        test_xlsx_workbook,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll export checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""
+Provably fair dealing checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from cards import deck_mask, format_cards, mask_to_cards
+from fairdeal import FairHand, commitment_for, keyed_shuffle, verify
+
+
+def test_keyed_shuffle():
+    deck = mask_to_cards(deck_mask())
+    first = keyed_shuffle(deck, "server", "client")
+    assert first == keyed_shuffle(deck, "server", "client")
+    assert sorted(first) == sorted(deck) and first != deck
+    assert first != keyed_shuffle(deck, "server", "other client")
+    # Every position stays reachable: the ace of spades moves around
+    spots = {keyed_shuffle(deck, "s", str(n)).index(deck[-1]) for n in range(200)}
+    assert len(spots) > 40
+
+
+def test_commit_deal_reveal():
+    hand = FairHand.new()
+    public = hand.to_dict()
+    assert "server_seed" not in public and "deck" not in public
+    try:
+        hand.deal(2)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("dealt before the client seed was in")
+    hand.seed("table 4, hand 17")
+    hole = hand.deal(2) + hand.deal(2)
+    board = hand.deal(5)
+    assert "dealt" not in hand.to_dict() and hand.to_dict()["cards_dealt"] == 9
+    revealed = hand.reveal()
+    assert revealed["dealt"] == format_cards(hole + board)
+    assert commitment_for(revealed["server_seed"], revealed["deck"]) == public[
+        "commitment"
+    ]
+    result = verify(
+        public["commitment"],
+        revealed["server_seed"],
+        revealed["deck"],
+        revealed["client_seed"],
+        revealed["dealt"],
+    )
+    assert result["valid"] and result["order"] == revealed["order"]
+    try:
+        hand.deal(1)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("dealt after the reveal")
+
+
+def test_owner_and_seed_locks():
+    hand = FairHand.new(owner="key:3")
+    hand.seed("players")
+    for action in (lambda: hand.deal(2), hand.reveal):
+        try:
+            hand.check_owner("key:4")
+            action()
+        except PermissionError:
+            continue
+        raise AssertionError("a stranger dealt or revealed the hand")
+    assert not hand.revealed and hand.dealt == []
+    hand.check_owner("key:3")
+    hand.deal(2)
+    # Once cards are out the seed is fixed, even before the reveal
+    try:
+        hand.seed("late")
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("changed the seed after the deal")
+    # A hand without an owner is open to anyone
+    FairHand.new().check_owner("key:4")
+
+
+def test_verify_catches_tampering():
+    hand = FairHand.new()
+    hand.seed("abc")
+    hand.deal(4)
+    revealed = hand.reveal()
+    args = [
+        revealed["commitment"],
+        revealed["server_seed"],
+        revealed["deck"],
+        revealed["client_seed"],
+        revealed["dealt"],
+    ]
+    swapped = revealed["deck"][2:4] + revealed["deck"][:2] + revealed["deck"][4:]
+    for index, value in ((2, swapped), (3, "abd"), (4, revealed["order"][4:12])):
+        tampered = list(args)
+        tampered[index] = value
+        assert not verify(*tampered)["valid"], index
+    assert not verify(args[0], args[1], "AsAs")["valid"]
+
+
+def main():
+    print("Fair Deal - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_keyed_shuffle,
+        test_commit_deal_reveal,
+        test_owner_and_seed_locks,
+        test_verify_catches_tampering,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll fair deal checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,165 @@
+#!/usr/bin/env python3
+"""
+GraphQL parser, validation, and nested query checks over a small hand database
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+import graphql
+from graphql import GraphQLError, execute, parse, schema_sdl
+from handdb import HandDB
+from handhistory import parse_hands
+from players import PlayerNotes
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND, STRADDLE_HAND
+
+
+def _database(tmp: str) -> HandDB:
+    db = HandDB(Path(tmp) / "h.sqlite")
+    db.insert_hands(parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND])))
+    db.insert_hands(parse_hands(STRADDLE_HAND))
+    PlayerNotes(db).update("Hero", notes="Opens wide", tags=["reg"])
+    return db
+
+
+def test_parse():
+    (operation,) = parse(
+        """
+        # comments and commas are ignored
+        query Recent($limit: Int = 5, $q: String!) {
+          recent: hands(query: $q, limit: $limit) { handId, board }
+        }
+        """
+    )
+    assert operation.name == "Recent"
+    assert operation.variables == {"limit": ("Int", 5), "q": ("String!", None)}
+    (selection,) = operation.selections
+    assert (selection.key, selection.name) == ("recent", "hands")
+    assert [child.name for child in selection.selections] == ["handId", "board"]
+    for bad in ("", "{ hands { site }", "{ ...Hand }", "subscription { x }", "{ ~ }"):
+        try:
+            parse(bad)
+        except GraphQLError:
+            continue
+        raise AssertionError(f"parsed {bad!r}")
+
+
+def test_nested_player_query():
+    query = """
+    query Player($name: String!) {
+      player(name: $name) {
+        name tags notes
+        stats { hands vpipPct }
+        sessions {
+          table handCount
+          hands { handId actions(street: "flop") { kind allIn } }
+        }
+      }
+    }
+    """
+    with tempfile.TemporaryDirectory() as tmp, _database(tmp) as db:
+        response = execute(db, query, {"name": "Hero"})
+        assert "errors" not in response, response
+        player = response["data"]["player"]
+        assert (player["tags"], player["notes"]) == (["reg"], "Opens wide")
+        assert player["stats"]["hands"] == 2
+        (session,) = player["sessions"]
+        assert (session["table"], session["handCount"]) == ("NLHWhite12", 2)
+        straddle, first = session["hands"]
+        assert (straddle["handId"], first["handId"]) == ("HD1234600", "HD1234567")
+        assert [action["kind"] for action in straddle["actions"]] == [
+            "check",
+            "bet",
+            "fold",
+        ]
+        assert execute(db, query, {"name": "Nobody"}) == {"data": {"player": None}}
+
+
+def test_hands_and_aliases():
+    with tempfile.TemporaryDirectory() as tmp, _database(tmp) as db:
+        response = execute(
+            db,
+            """{
+              big: hands(query: "pot>1000", limit: 5) {
+                handId __typename seats { name net }
+              }
+              missing: hand(id: "nope") { site }
+              stars: hand(id: "230000000001") { game board }
+            }""",
+        )
+        data = response["data"]
+        assert [hand["handId"] for hand in data["big"]] == ["230000000001"]
+        assert data["big"][0]["__typename"] == "Hand"
+        seats = {seat["name"]: seat["net"] for seat in data["big"][0]["seats"]}
+        assert seats["Alice"] == 881
+        assert data["missing"] is None
+        assert data["stars"]["board"] == ["Kd", "7c", "2h", "9s", "3d"]
+
+
+def test_errors():
+    with tempfile.TemporaryDirectory() as tmp, _database(tmp) as db:
+        for query, variables, message in (
+            ("{ hand { site } }", {}, "is required"),
+            ("{ hands { nope } }", {}, "Cannot query field 'nope'"),
+            ("{ hands }", {}, "needs a selection"),
+            ("{ hands { site { x } } }", {}, "has no subfields"),
+            ('{ hands(limit: "5") { site } }', {}, "of type Int"),
+            ("{ hands(limit: 0) { site } }", {}, "limit must be positive"),
+            ('{ hands(query: "pot>>1") { site } }', {}, "Expected"),
+            ("{ hands(limit: $n) { site } }", {}, "not defined"),
+            ("query($n: Int!) { hands(limit: $n) { site } }", {}, "cannot be null"),
+        ):
+            response = execute(db, query, variables)
+            assert response["data"] is None, query
+            assert message in response["errors"][0]["message"], response
+    assert "hands(query: String, limit: Int, offset: Int): [Hand!]!" in schema_sdl()
+
+
+def test_cost_limit():
+    cycle = "hands { seats { player { %s } } }"
+    query = "name"
+    for _ in range(2):
+        query = cycle % query
+    with tempfile.TemporaryDirectory() as tmp, _database(tmp) as db:
+        response = execute(db, "{ %s }" % query)
+        assert response["data"] is None
+        assert "the limit is 50,000" in response["errors"][0]["message"], response
+        # Aliases add up like any other selection
+        wide = " ".join(f"h{i}: hands(limit: 500) {{ handId }}" for i in range(101))
+        assert "the limit is" in execute(db, "{ %s }" % wide)["errors"][0]["message"]
+        # One round of the loop is within budget
+        once = "{ %s }" % (cycle % "name")
+        assert "errors" not in execute(db, once)
+        # Seats estimated one a hand pass the estimate, then run over the limit
+        saved = graphql.MAX_COST, graphql.LIST_SIZES
+        graphql.MAX_COST, graphql.LIST_SIZES = 5, {"Seat": 1}
+        try:
+            response = execute(db, "{ hands(limit: 2) { seats { name } } }")
+            assert response["errors"] == [
+                {"message": "Query resolves more than 5 objects"}
+            ], response
+        finally:
+            graphql.MAX_COST, graphql.LIST_SIZES = saved
+
+
+def main():
+    print("GraphQL - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_parse,
+        test_nested_player_query,
+        test_hands_and_aliases,
+        test_errors,
+        test_cost_limit,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll GraphQL checks passed.")
+
+
+if __name__ == "__main__":
+    main()
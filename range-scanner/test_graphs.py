@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""
+Graph series checks on hand-built hands
+"""
+
+import sys
+import zlib
+
+sys.path.insert(0, ".")
+
+from graphs import line_chart_png, results_series, tournament_series
+from handhistory import Action, Hand, Player
+
+
+def _hand(hand_id, actions, collected, showdown=False):
+    """Heads-up, blinds 1/2: Hero on the button, Villain in the big blind"""
+    return Hand(
+        site="test",
+        hand_id=hand_id,
+        small_blind=1,
+        big_blind=2,
+        players=[
+            Player(1, "Hero", 200, [], "BTN"),
+            Player(2, "Villain", 200, [], "BB"),
+        ],
+        actions=[
+            Action("Hero", "preflop", "small_blind", 1),
+            Action("Villain", "preflop", "big_blind", 2),
+            *actions,
+        ],
+        collected=collected,
+        showdown=showdown,
+    )
+
+
+def _hands():
+    return [
+        # Villain folds to a raise: +2 without showdown
+        _hand(
+            "1",
+            [
+                Action("Hero", "preflop", "raise", 6, 4),
+                Action("Villain", "preflop", "fold"),
+            ],
+            {"Hero": 8},
+        ),
+        # Checked down and lost: -2 at showdown
+        _hand(
+            "2",
+            [
+                Action("Hero", "preflop", "call", 1),
+                Action("Villain", "preflop", "check"),
+            ],
+            {"Villain": 4},
+            showdown=True,
+        ),
+    ]
+
+
+def test_results_series():
+    data = results_series(_hands(), "Hero", iterations=100)
+    assert data.hands == ["1", "2"]
+    assert data.series["net"] == [2.0, 0.0]
+    assert data.series["non_showdown"] == [2.0, 2.0]
+    assert data.series["showdown"] == [0.0, -2.0]
+    # No all-in, so EV follows the actual results
+    assert data.series["ev"] == data.series["net"]
+    in_bb = results_series(_hands(), "Hero", unit="bb", iterations=100)
+    assert in_bb.series["net"] == [1.0, 0.0]
+    assert results_series(_hands(), "Nobody").hands == []
+    try:
+        results_series(_hands(), "Hero", unit="eur")
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("accepted an unknown unit")
+
+
+def test_tournament_series():
+    data = tournament_series(_hands(), "Hero")
+    assert data.hands == ["1", "2", "final"]
+    assert data.series["stack"] == [200, 200, 198]
+    assert data.series["stack_bb"] == [100.0, 100.0, 99.0]
+    assert tournament_series(_hands(), "Nobody").hands == []
+
+
+def test_line_chart_png():
+    png = line_chart_png({"net": [0.0, 5.0, -3.0], "ev": [0.0, 1.0]}, 60, 40)
+    assert png.startswith(b"\x89PNG\r\n\x1a\n")
+    # Decode the single IDAT chunk: one filter byte plus RGB per row
+    start = png.index(b"IDAT") + 4
+    length = int.from_bytes(png[start - 8 : start - 4], "big")
+    raw = zlib.decompress(png[start : start + length])
+    assert len(raw) == 40 * (1 + 60 * 3)
+    green = bytes((30, 150, 60))
+    assert green in raw
+    try:
+        line_chart_png({"net": []})
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("drew an empty chart")
+
+
+def main():
+    print("Graphs - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_results_series,
+        test_tournament_series,
+        test_line_chart_png,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll graph checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,202 @@
+#!/usr/bin/env python3
+"""
+Evaluator regression checks - exhaustive 5-card enumeration plus spot checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+import random
+import time
+from collections import Counter
+from itertools import combinations
+
+from cards import parse_cards
+from game_evaluators import get_evaluator
+from hand_evaluator import HandClass, compare, evaluate, evaluate_mask
+from lookup_evaluator import evaluate_indices
+from omaha_evaluator import describe_low, evaluate_omaha, evaluate_omaha_hilo
+
+# Known frequencies over all 2,598,960 five-card hands
+EXPECTED_5_CARD_COUNTS = {
+    HandClass.STRAIGHT_FLUSH: 40,
+    HandClass.QUADS: 624,
+    HandClass.FULL_HOUSE: 3744,
+    HandClass.FLUSH: 5108,
+    HandClass.STRAIGHT: 10200,
+    HandClass.TRIPS: 54912,
+    HandClass.TWO_PAIR: 123552,
+    HandClass.PAIR: 1098240,
+    HandClass.HIGH_CARD: 1302540,
+}
+DISTINCT_5_CARD_VALUES = 7462
+
+# (winner, loser) pairs that exercise tie-breaks and edge cases
+ORDERED_PAIRS = [
+    ("AsKsQsJsTs", "9h8h7h6h5h"),
+    ("5c4c3c2cAc", "AhAdAsAcKd"),  # steel wheel still beats quads
+    ("2h2d2s2cAd", "AhAdAsKcKd"),
+    ("AhAdAsKcKd", "KhKdKsAcAd"),
+    ("2h3h4h5h7h", "AsKdQcJhTs"),
+    ("6s5d4c3h2s", "5s4d3c2hAs"),  # wheel is the lowest straight
+    ("2h2d2sAcKd", "AhAdKsKcQd"),
+    ("AhAdKsKc2d", "AsAcQsQcKd"),
+    ("KhKdQsQcAd", "KsKcQhQdJd"),
+    ("AhAd5s4c3d", "KsKcAdQcJh"),
+    ("AhKdQs9c8d", "AsKcQd9h7s"),
+]
+
+SPLIT_PAIRS = [
+    ("AsKdQcJhTs", "AhKcQdJsTh"),
+    ("2s2dAhKcQd7h6s", "2h2cAsKdQs5c4d"),  # sixth and seventh cards don't play
+]
+
+SEVEN_CARD_CASES = [
+    ("AsKsQsJsTs2d3c", HandClass.STRAIGHT_FLUSH),
+    ("AhAdAsKcKdKs2c", HandClass.FULL_HOUSE),
+    ("2h3h4h5h7d6d8c", HandClass.STRAIGHT),
+    ("2h3h4h5h9hAd6h", HandClass.STRAIGHT_FLUSH),
+    ("QhQdQcQs2h2d2c", HandClass.QUADS),
+    ("AhKh9h4h2hAdAc", HandClass.FLUSH),
+    ("7c7d5s5h3c3d2s", HandClass.TWO_PAIR),
+]
+
+
+def test_five_card_exhaustive():
+    start = time.time()
+    counts = Counter()
+    values = set()
+    for hand in combinations(range(52), 5):
+        mask = (1 << hand[0]) | (1 << hand[1]) | (1 << hand[2]) | (1 << hand[3]) | (
+            1 << hand[4]
+        )
+        strength = evaluate_mask(mask)
+        counts[HandClass(strength >> 20)] += 1
+        values.add(strength)
+    print(f"  enumerated 2,598,960 hands in {time.time() - start:.1f}s")
+    for hand_class, expected in EXPECTED_5_CARD_COUNTS.items():
+        assert counts[hand_class] == expected, (hand_class, counts[hand_class])
+    assert len(values) == DISTINCT_5_CARD_VALUES, len(values)
+
+
+def test_ordering():
+    for winner, loser in ORDERED_PAIRS:
+        assert compare(parse_cards(winner), parse_cards(loser)) == 1, (winner, loser)
+        assert compare(parse_cards(loser), parse_cards(winner)) == -1, (loser, winner)
+    for first, second in SPLIT_PAIRS:
+        assert compare(parse_cards(first), parse_cards(second)) == 0, (first, second)
+
+
+def test_seven_card_classes():
+    for hand, expected in SEVEN_CARD_CASES:
+        assert evaluate(parse_cards(hand)).hand_class == expected, hand
+
+
+def test_seven_matches_best_five(samples: int = 20000):
+    """7-card results must equal the best of the 21 five-card subsets"""
+    rng = random.Random(7)
+    for _ in range(samples):
+        hand = rng.sample(range(52), 7)
+        best = max(
+            evaluate_mask(sum(1 << card for card in subset))
+            for subset in combinations(hand, 5)
+        )
+        assert evaluate_mask(sum(1 << card for card in hand)) == best, hand
+
+
+def test_lookup_matches_reference(samples: int = 100000):
+    """The table-driven evaluator must agree with the reference on 5-7 cards"""
+    rng = random.Random(11)
+    for idx in range(samples):
+        hand = rng.sample(range(52), 5 + idx % 3)
+        expected = evaluate_mask(sum(1 << card for card in hand))
+        assert evaluate_indices(hand) == expected, hand
+    for hand, _ in SEVEN_CARD_CASES:
+        indices = [card.index for card in parse_cards(hand)]
+        assert evaluate_indices(indices) == evaluate(parse_cards(hand)).strength
+
+
+# (hole, board, expected high class, expected low) - Omaha must play 2 + 3
+OMAHA_CASES = [
+    ("AhKc2c3c", "QhJhTh9h3s", HandClass.STRAIGHT, None),  # one heart: no flush
+    ("AhKh2c3c", "QhJhTh2s3s", HandClass.STRAIGHT_FLUSH, None),
+    ("AsAdAcKd", "2h7s9cJdQh", HandClass.PAIR, None),  # third ace can't play
+    ("As2dKhQh", "3c5d8hKsJd", HandClass.PAIR, "8-5-3-2-A"),
+    ("As2d3h4h", "5c6d7h8sKd", HandClass.STRAIGHT, "7-6-5-2-A"),
+    ("AsAd3h4h", "2c2d9hTsKd", HandClass.TWO_PAIR, "No low"),
+]
+
+
+def test_omaha():
+    for hole, board, expected_class, expected_low in OMAHA_CASES:
+        high = evaluate_omaha(parse_cards(hole), parse_cards(board))
+        assert high.hand_class == expected_class, (hole, board, high.describe())
+        if expected_low is not None:
+            _, low = evaluate_omaha_hilo(parse_cards(hole), parse_cards(board))
+            assert describe_low(low) == expected_low, (hole, board, describe_low(low))
+
+
+SHORT_DECK_PAIRS = [
+    ("AhKhQh9h7h", "KdKcKsQcQd"),  # flush beats a full house
+    ("As6d7c8h9s", "AsAdAcKhQs"),  # A-6-7-8-9 is a straight
+    ("Ts9d8c7h6s", "As6d7c8h9c"),  # ...and the lowest one
+]
+
+
+def test_short_deck():
+    for winner, loser in SHORT_DECK_PAIRS:
+        assert compare(parse_cards(winner), parse_cards(loser), short_deck=True) == 1
+    assert compare(parse_cards("AhKhQh9h7h"), parse_cards("KdKcKsQcQd")) == -1
+    rng = random.Random(5)
+    short_cards = list(range(16, 52))
+    for _ in range(20000):
+        hand = rng.sample(short_cards, 7)
+        expected = evaluate_mask(sum(1 << card for card in hand), short_deck=True)
+        assert evaluate_indices(hand, short_deck=True) == expected, hand
+
+
+# (game, better, worse) - each pair is ordered best first under that game's rules
+GAME_ORDERINGS = [
+    ("razz", "As2d3c4h5sKdKc", "As2d3c4h6sKdKc"),  # the wheel is the nuts
+    ("razz", "8s7d6c5h4sQdQc", "AsAd2c3h4sQdQc"),  # any unpaired low beats a pair
+    ("razz", "AsAd2c3h4sQdJc", "2s2d3c4h5sQdJc"),  # lower pair wins
+    ("27lowball", "7s5d4c3h2c", "7s6d4c3h2c"),  # 7-5 is the nuts
+    ("27lowball", "As5d4c3h2c", "7s6d5c4h3c"),  # A-5-4-3-2 is ace high, no straight
+    ("27lowball", "8s6d4c3h2c", "7s5s4s3s2s"),  # flushes count against
+    ("stud", "AsAdKcKh2s3d9c", "AsAdQcQh2s3d9c"),
+]
+
+
+def test_game_evaluators():
+    for game, better, worse in GAME_ORDERINGS:
+        evaluator = get_evaluator(game)
+        assert evaluator.evaluate(parse_cards(better)) > evaluator.evaluate(
+            parse_cards(worse)
+        ), (game, better, worse)
+    razz = get_evaluator("razz")
+    wheel = razz.evaluate(parse_cards("As2d3c4h5sKdKc"))
+    assert razz.describe(wheel) == "5-4-3-2-A", razz.describe(wheel)
+
+
+def main():
+    print("Hand Evaluator - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_ordering,
+        test_seven_card_classes,
+        test_seven_matches_best_five,
+        test_lookup_matches_reference,
+        test_omaha,
+        test_short_deck,
+        test_game_evaluators,
+        test_five_card_exhaustive,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll evaluator checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,84 @@
+#!/usr/bin/env python3
+"""
+Range notation parser and set-operation checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from equity import calculate_equity
+from hand_range import PREFLOP_ORDER, Range
+
+
+def test_expansion_counts():
+    cases = {
+        "22+": 78,
+        "A2s+": 48,
+        "KTo+": 36,
+        "76s-54s": 12,
+        "TT-77": 24,
+        "AK": 16,
+        "AsKs,AdKd": 2,
+        "22+, A2s+, KTo+, 76s-54s": 174,
+    }
+    for text, expected in cases.items():
+        assert len(Range.parse(text)) == expected, (text, len(Range.parse(text)))
+
+
+def test_percent_ranges():
+    assert len(PREFLOP_ORDER) == 169
+    assert len(Range.parse("[100%]")) == 1326
+    top = Range.parse("[10%]")
+    assert "AA" in top.hand_classes()
+    assert abs(top.percent() - 10) < 1.5, top.percent()
+
+
+def test_weights_and_dead_cards():
+    weighted = Range.parse("QQ+, AKs:0.5")
+    assert weighted.combo_count() == 18 + 2
+    # Later terms override earlier weights
+    assert Range.parse("AA, AA:0.25").combo_count() == 1.5
+    assert weighted.combo_count(dead="As") == 15 + 1.5
+    assert len(weighted.without("As")) == 18 + 4 - 3 - 1
+
+
+def test_set_operations():
+    a = Range.parse("TT+, AKs")
+    b = Range.parse("QQ+, AKo")
+    assert len(a | b) == 30 + 4 + 12
+    assert len(a & b) == 18
+    assert len(a - b) == 12 + 4
+    assert (a - b) | (a & b) == a
+    assert Range.parse(str(a | b)) == a | b
+
+
+def test_range_equity():
+    # A one-class range must match the equally weighted combos it expands to
+    ranged = calculate_equity(["AsKs", Range.parse("QQ")], board="Ah7c2d", workers=1)
+    listed = calculate_equity(
+        ["AsKs", [(combo, 1.0) for combo in Range.parse("QQ").combos()]],
+        board="Ah7c2d",
+        workers=1,
+    )
+    assert ranged.equities == listed.equities
+
+
+def main():
+    print("Range Parser - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_expansion_counts,
+        test_percent_ranges,
+        test_weights_and_dead_cards,
+        test_set_operations,
+        test_range_equity,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll range checks passed.")
+
+
+if __name__ == "__main__":
+    main()
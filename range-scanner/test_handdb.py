@@ -0,0 +1,113 @@
+#!/usr/bin/env python3
+"""
+SQLite hand database checks: round trips, dedupe, and migrations
+"""
+
+import sqlite3
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import MIGRATIONS, HandDB, import_histories, normalize_timestamp
+from handhistory import parse_hand, parse_hands
+from test_handhistory import (
+    GGPOKER_HAND,
+    POKERSTARS_HAND,
+    RUN_TWICE_HAND,
+    WINAMAX_HAND,
+)
+
+
+def test_round_trip():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        for text in (POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND, RUN_TWICE_HAND):
+            original = parse_hand(text)
+            assert db.insert_hand(original)
+            stored = db.get_hand(original.site, original.hand_id)
+            assert stored.to_dict() == original.to_dict(), original.site
+
+
+def test_duplicates_are_ignored():
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND, POKERSTARS_HAND]))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        assert db.insert_hands(hands) == 2
+        assert db.insert_hands(hands) == 0
+        summary = db.summary()
+        assert summary["hands"] == 2
+        assert summary["sites"] == {"pokerstars": 1, "ggpoker": 1}
+        assert summary["first_hand"] == "2024-01-05 20:15:00"
+        # Indexed lookups by player and position
+        rows = db.conn.execute(
+            "SELECT COUNT(*) FROM hand_players WHERE position = 'BTN'"
+        ).fetchone()
+        assert rows[0] == 2
+
+
+def test_bulk_import():
+    with tempfile.TemporaryDirectory() as tmp:
+        root = Path(tmp)
+        (root / "a").mkdir()
+        (root / "b").mkdir()
+        (root / "a" / "one.txt").write_text(f"{POKERSTARS_HAND}\n\n{GGPOKER_HAND}")
+        (root / "b" / "two.txt").write_text(
+            f"{POKERSTARS_HAND}\n\nnot a hand history\n\n{WINAMAX_HAND}"
+        )
+        seen = []
+        with HandDB(root / "h.sqlite") as db:
+            report = import_histories(
+                db, [root, root / "missing.txt"], workers=2, progress=seen.append
+            )
+            assert report.files == 3 and len(seen) == 3
+            assert (report.parsed, report.inserted, report.duplicates) == (4, 3, 1)
+            # The garbage block and the missing file are reported, not fatal
+            problems = sorted(Path(path).name for path, _ in report.errors)
+            assert problems == ["missing.txt", "two.txt"]
+            again = import_histories(db, [root], workers=1)
+            assert again.inserted == 0 and again.duplicates == 4
+            assert db.summary()["hands"] == 3
+
+
+def test_migrations():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "h.sqlite"
+        with HandDB(path) as db:
+            assert db.schema_version == len(MIGRATIONS)
+        # Reopening applies nothing new and keeps the data layout
+        with HandDB(path) as db:
+            assert db.migrate() == len(MIGRATIONS)
+        conn = sqlite3.connect(path.as_posix())
+        conn.execute(f"PRAGMA user_version = {len(MIGRATIONS) + 1}")
+        conn.close()
+        try:
+            HandDB(path)
+        except RuntimeError:
+            pass
+        else:
+            raise AssertionError("newer schema should be refused")
+
+
+def test_timestamps_sort():
+    assert normalize_timestamp("2024/01/05 9:05:00 ET") == "2024-01-05 09:05:00"
+    assert normalize_timestamp("") is None
+
+
+def main():
+    print("Hand Database - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_round_trip,
+        test_duplicates_are_ignored,
+        test_bulk_import,
+        test_migrations,
+        test_timestamps_sort,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll hand database checks passed.")
+
+
+if __name__ == "__main__":
+    main()
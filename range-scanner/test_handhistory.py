@@ -0,0 +1,319 @@
+#!/usr/bin/env python3
+"""
+Site hand history parser checks on one sample hand per site
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from handhistory import parse_hand, parse_hands
+
+
+POKERSTARS_HAND = """\
+PokerStars Hand #230000000001: Tournament #3000000001, $1.00+$0.10 USD Hold'em No Limit - Level II (15/30) - 2024/01/05 20:15:00 ET
+Table '3000000001 1' 9-max Seat #1 is the button
+Seat 1: Alice (1500 in chips)
+Seat 2: Bob Smith (1500 in chips)
+Seat 3: Carol (800 in chips)
+Seat 4: Dave (2000 in chips) is sitting out
+Alice: posts the ante 3
+Bob Smith: posts the ante 3
+Carol: posts the ante 3
+Dave: posts the ante 3
+Bob Smith: posts small blind 15
+Carol: posts big blind 30
+*** HOLE CARDS ***
+Dealt to Alice [Ah Kh]
+Dave: folds
+Alice: raises 45 to 75
+Bob Smith: calls 60
+Carol: raises 722 to 797 and is all-in
+Alice: calls 722
+Bob Smith: folds
+*** FLOP *** [Kd 7c 2h]
+*** TURN *** [Kd 7c 2h] [9s]
+*** RIVER *** [Kd 7c 2h 9s] [3d]
+*** SHOW DOWN ***
+Carol: shows [Qs Qd] (a pair of Queens)
+Alice: shows [Ah Kh] (a pair of Kings)
+Alice collected 1681 from pot
+*** SUMMARY ***
+Total pot 1681 | Rake 0
+Board [Kd 7c 2h 9s 3d]
+Seat 1: Alice (button) showed [Ah Kh] and won (1681) with a pair of Kings
+Seat 2: Bob Smith (small blind) folded before Flop
+Seat 3: Carol (big blind) showed [Qs Qd] and lost with a pair of Queens
+Seat 4: Dave folded before Flop (didn't bet)
+"""
+
+GGPOKER_HAND = """\
+Poker Hand #HD1234567: Hold'em No Limit ($0.02/$0.05) - 2024/01/05 21:00:00
+Table 'NLHWhite12' 6-max Seat #3 is the button
+Seat 1: a1b2c3 ($4.10 in chips)
+Seat 3: Hero ($6.20 in chips)
+Seat 5: 4f5e6d7c ($5.00 in chips)
+4f5e6d7c: posts small blind $0.02
+a1b2c3: posts big blind $0.05
+*** HOLE CARDS ***
+Dealt to 4f5e6d7c
+Dealt to Hero [Jc Jd]
+Dealt to a1b2c3
+Hero: raises $0.10 to $0.15
+4f5e6d7c: folds
+a1b2c3: calls $0.10
+*** FLOP *** [8s 5h 2c]
+a1b2c3: checks
+Hero: bets $0.20
+a1b2c3: folds
+Uncalled bet ($0.20) returned to Hero
+Hero collected $0.31 from pot
+*** SHOWDOWN ***
+*** SUMMARY ***
+Total pot $0.32 | Rake $0.01 | Jackpot $0 | Bingo $0 | Fortune $0 | Tax $0
+Board [8s 5h 2c]
+Seat 3: Hero (button) won ($0.31)
+"""
+
+WINAMAX_HAND = """\
+Winamax Poker - CashGame - HandId: #1234567-89-1700000000 - Holdem no limit (0.01€/0.02€) - 2024/01/05 22:00:00 UTC
+Table: 'Nice 05' 6-max (real money) Seat #2 is the button
+Seat 1: Player1 (2€)
+Seat 2: Player Two (1.95€)
+Seat 4: Hero (2.10€)
+*** ANTE/BLINDS ***
+Hero posts small blind 0.01€
+Player1 posts big blind 0.02€
+Dealt to Hero [Tc 9c]
+*** PRE-FLOP ***
+Player Two raises 0.04€ to 0.06€
+Hero calls 0.05€
+Player1 folds
+*** FLOP *** [8c 7d 2c]
+Hero checks
+Player Two bets 0.10€
+Hero raises 0.20€ to 0.30€
+Player Two calls 0.20€
+*** TURN *** [8c 7d 2c][Jc]
+Hero bets 0.50€
+Player Two folds
+Hero collected 0.71€ from pot
+*** SUMMARY ***
+Total pot 0.74€ | Rake 0.03€
+Board: [8c 7d 2c Jc]
+Seat 4: Hero won 0.71€
+"""
+
+
+STRADDLE_HAND = """\
+Poker Hand #HD1234600: Hold'em No Limit ($0.02/$0.05) - 2024/01/05 21:10:00
+Table 'NLHWhite12' 6-max Seat #4 is the button
+Seat 1: a1b2c3 ($5.00 in chips)
+Seat 2: 4f5e6d7c ($5.00 in chips)
+Seat 3: 9e8d7c6b ($5.00 in chips)
+Seat 4: Hero ($5.00 in chips)
+a1b2c3: posts small blind $0.02
+4f5e6d7c: posts big blind $0.05
+Hero: posts straddle $0.10
+*** HOLE CARDS ***
+Dealt to Hero [As Ad]
+9e8d7c6b: calls $0.10
+Hero: raises $0.30 to $0.40
+a1b2c3: folds
+4f5e6d7c: folds
+9e8d7c6b: calls $0.30
+*** FLOP *** [Kd 7c 2h]
+9e8d7c6b: checks
+Hero: bets $0.50
+9e8d7c6b: folds
+Uncalled bet ($0.50) returned to Hero
+Hero collected $0.84 from pot
+*** SHOWDOWN ***
+*** SUMMARY ***
+Total pot $0.87 | Rake $0.03 | Jackpot $0 | Bingo $0 | Fortune $0 | Tax $0
+Board [Kd 7c 2h]
+Seat 4: Hero (button) won ($0.84)
+"""
+
+BOMB_POT_HAND = """\
+Poker Hand #HD1234601: Hold'em No Limit ($0.02/$0.05) - 2024/01/05 21:12:00
+Table 'NLHWhite12' 6-max Seat #3 is the button
+Seat 1: a1b2c3 ($5.00 in chips)
+Seat 2: 4f5e6d7c ($5.00 in chips)
+Seat 3: Hero ($5.00 in chips)
+a1b2c3: posts bomb pot $0.25
+4f5e6d7c: posts bomb pot $0.25
+Hero: posts bomb pot $0.25
+*** HOLE CARDS ***
+Dealt to Hero [Kc Qc]
+*** FLOP *** [Kd 7c 2h]
+a1b2c3: checks
+4f5e6d7c: bets $0.50
+Hero: calls $0.50
+a1b2c3: folds
+*** TURN *** [Kd 7c 2h] [3s]
+4f5e6d7c: checks
+Hero: checks
+*** RIVER *** [Kd 7c 2h 3s] [4d]
+4f5e6d7c: checks
+Hero: checks
+*** SHOWDOWN ***
+4f5e6d7c: shows [Jd Jh] (a pair of Jacks)
+Hero: shows [Kc Qc] (a pair of Kings)
+Hero collected $1.70 from pot
+*** SUMMARY ***
+Total pot $1.75 | Rake $0.05 | Jackpot $0 | Bingo $0 | Fortune $0 | Tax $0
+Board [Kd 7c 2h 3s 4d]
+Seat 3: Hero (button) showed [Kc Qc] and won ($1.70)
+"""
+
+RUN_TWICE_HAND = """\
+PokerStars Hand #230000000077:  Hold'em No Limit ($0.50/$1.00 USD) - 2024/01/05 22:00:00 ET
+Table 'Ariadne III' 6-max Seat #1 is the button
+Seat 1: Alice ($100 in chips)
+Seat 2: Bob ($80 in chips)
+Alice: posts small blind $0.50
+Bob: posts big blind $1
+*** HOLE CARDS ***
+Dealt to Alice [Ah Ad]
+Alice: raises $99 to $100 and is all-in
+Bob: calls $79 and is all-in
+Uncalled bet ($20) returned to Alice
+*** FIRST FLOP *** [Kd 7c 2h]
+*** FIRST TURN *** [Kd 7c 2h] [9s]
+*** FIRST RIVER *** [Kd 7c 2h 9s] [3d]
+*** SECOND FLOP *** [Ks Kh 5c]
+*** SECOND TURN *** [Ks Kh 5c] [Jd]
+*** SECOND RIVER *** [Ks Kh 5c Jd] [4s]
+*** FIRST SHOW DOWN ***
+Alice: shows [Ah Ad] (a pair of Aces)
+Bob: shows [Kc Qc] (a pair of Kings)
+Alice collected $79 from pot
+*** SECOND SHOW DOWN ***
+Bob collected $79 from pot
+*** SUMMARY ***
+Total pot $160 | Rake $2
+Hand was run twice
+FIRST Board [Kd 7c 2h 9s 3d]
+SECOND Board [Ks Kh 5c Jd 4s]
+Seat 1: Alice (button) (small blind) showed [Ah Ad] and won ($79)
+Seat 2: Bob (big blind) showed [Kc Qc] and won ($79)
+"""
+
+
+def test_pokerstars_tournament():
+    hand = parse_hand(POKERSTARS_HAND)
+    assert hand.site == "pokerstars" and hand.hand_id == "230000000001"
+    assert hand.tournament_id == "3000000001" and hand.is_tournament
+    # The $1.00+$0.10 buy-in is not the chips' currency
+    assert hand.currency == ""
+    assert (hand.small_blind, hand.big_blind) == (15, 30)
+    assert hand.board == ["Kd", "7c", "2h", "9s", "3d"]
+    assert hand.hero == "Alice" and hand.showdown
+    # Names with spaces and sitting-out seats
+    assert hand.player("Bob Smith").position == "SB"
+    assert hand.player("Dave").sitting_out and not hand.player("Dave").position
+    assert hand.player("Carol").hole_cards == ["Qs", "Qd"]
+    raise_action = hand.actions_on("preflop")[-3]
+    assert raise_action.kind == "raise" and raise_action.all_in
+    assert raise_action.amount == 797 and raise_action.raise_by == 722
+    assert hand.contributions() == {
+        "Alice": 800,
+        "Bob Smith": 78,
+        "Carol": 800,
+        "Dave": 3,
+    }
+    assert sum(hand.contributions().values()) == hand.total_pot == 1681
+    assert hand.net()["Alice"] == 881
+
+
+def test_ggpoker_cash():
+    hand = parse_hand(GGPOKER_HAND)
+    assert hand.site == "ggpoker" and hand.hand_id == "HD1234567"
+    assert not hand.is_tournament and hand.currency == "USD"
+    assert (hand.small_blind, hand.big_blind) == (0.02, 0.05)
+    assert hand.uncalled == {"Hero": 0.20}
+    assert hand.contributions() == {"4f5e6d7c": 0.02, "Hero": 0.15, "a1b2c3": 0.15}
+    assert hand.rake == 0.01
+    assert hand.net()["Hero"] == 0.16
+    assert [player.position for player in hand.players] == ["BB", "BTN", "SB"]
+
+
+def test_winamax_cash():
+    hand = parse_hand(WINAMAX_HAND)
+    assert hand.site == "winamax" and hand.hand_id == "1234567-89-1700000000"
+    assert hand.table == "Nice 05" and hand.max_seats == 6
+    assert hand.currency == "EUR"
+    assert hand.board == ["8c", "7d", "2c", "Jc"]
+    assert [action.kind for action in hand.actions_on("flop")] == [
+        "check",
+        "bet",
+        "raise",
+        "call",
+    ]
+    assert hand.player("Player Two").stack == 1.95
+    assert hand.contributions() == {"Player1": 0.02, "Player Two": 0.36, "Hero": 0.36}
+    # Winamax prints no uncalled-bet line; the parser infers it
+    assert hand.uncalled == {"Hero": 0.5}
+    assert hand.net()["Hero"] == 0.35
+
+
+def test_straddle_and_bomb_pot():
+    hand = parse_hand(STRADDLE_HAND)
+    assert hand.player("Hero").position == "BTN" and not hand.is_bomb_pot
+    assert hand.actions_on("preflop")[2].kind == "straddle"
+    # The button straddle is a live blind the raise to 0.40 includes
+    assert hand.contributions() == {
+        "a1b2c3": 0.02,
+        "4f5e6d7c": 0.05,
+        "9e8d7c6b": 0.40,
+        "Hero": 0.40,
+    }
+    assert round(sum(hand.contributions().values()), 2) == hand.total_pot
+    assert hand.net()["Hero"] == 0.44
+    bomb = parse_hand(BOMB_POT_HAND)
+    assert bomb.is_bomb_pot
+    assert [action.kind for action in bomb.actions_on("preflop")] == ["bomb_pot"] * 3
+    # Bomb pot posts are dead money: the flop bet starts from zero
+    assert bomb.contributions() == {"a1b2c3": 0.25, "4f5e6d7c": 0.75, "Hero": 0.75}
+    assert bomb.net()["Hero"] == 0.95 and bomb.showdown
+    assert bomb.dead_money() == {"a1b2c3": 0.25, "4f5e6d7c": 0.25, "Hero": 0.25}
+
+
+def test_run_it_twice():
+    hand = parse_hand(RUN_TWICE_HAND)
+    assert hand.run_count == 2 and hand.showdown
+    assert hand.board == ["Kd", "7c", "2h", "9s", "3d"]
+    assert hand.runouts == [hand.board, ["Ks", "Kh", "5c", "Jd", "4s"]]
+    assert hand.run_collected == [{"Alice": 79}, {"Bob": 79}]
+    assert hand.collected == {"Alice": 79, "Bob": 79}
+    assert hand.contributions() == {"Alice": 80, "Bob": 80}
+    assert hand.net() == {"Alice": -1, "Bob": -1}
+    assert parse_hand(POKERSTARS_HAND).run_count == 1
+
+
+def test_mixed_file():
+    text = "\n\n".join([POKERSTARS_HAND, "garbage line", GGPOKER_HAND, WINAMAX_HAND])
+    hands = parse_hands(text)
+    assert [hand.site for hand in hands] == ["pokerstars", "ggpoker", "winamax"]
+
+
+def main():
+    print("Hand History Parsers - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_pokerstars_tournament,
+        test_ggpoker_cash,
+        test_winamax_cash,
+        test_straddle_and_bomb_pot,
+        test_run_it_twice,
+        test_mixed_file,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll hand history checks passed.")
+
+
+if __name__ == "__main__":
+    main()
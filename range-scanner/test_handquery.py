@@ -0,0 +1,139 @@
+#!/usr/bin/env python3
+"""
+Hand query language checks against the three sample hands
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import parse_hands
+from handquery import QueryError, compile_query, search
+from test_handhistory import (
+    BOMB_POT_HAND,
+    GGPOKER_HAND,
+    POKERSTARS_HAND,
+    STRADDLE_HAND,
+    WINAMAX_HAND,
+)
+
+
+def _with_db(check):
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND]))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        check(db)
+
+
+def _sites(db, query):
+    return [hand["site"] for hand in search(db, query)["hands"]]
+
+
+def test_fields_and_big_blind_units():
+    def check(db):
+        assert _sites(db, "position=btn") == ["ggpoker", "pokerstars"]
+        assert _sites(db, "position=BTN and pot>50bb") == ["pokerstars"]
+        assert _sites(db, "pot<=6.4bb or site=winamax") == ["winamax", "ggpoker"]
+        assert _sites(db, "showdown=true and pot>50bb") == ["pokerstars"]
+        assert _sites(db, "date=2024-01-05 and bb>=0.05") == ["ggpoker", "pokerstars"]
+        assert _sites(db, "cards=JJ") == ["ggpoker"]
+        assert _sites(db, "range=QQ+,AKs") == ["pokerstars"]
+
+    _with_db(check)
+
+
+def test_lines_and_players():
+    def check(db):
+        assert _sites(db, "line=check-raise-flop") == ["winamax"]
+        assert _sites(db, "line~raise-preflop") == ["ggpoker", "pokerstars"]
+        assert _sites(db, "line~call-preflop") == ["winamax", "pokerstars"]
+        assert _sites(db, "player=a1b2c3 and line=check-fold-flop") == ["ggpoker"]
+        result = search(db, 'player=* and (cards=QQ or player="Bob Smith")')
+        assert [hand["player"] for hand in result["hands"]] == ["Bob Smith", "Carol"]
+
+    _with_db(check)
+
+
+def test_forced_posts():
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, STRADDLE_HAND, BOMB_POT_HAND]))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        assert [hand["hand_id"] for hand in search(db, "post=straddle")["hands"]] == [
+            "HD1234600"
+        ]
+        assert _sites(db, "post=bomb_pot and line=call-flop") == ["ggpoker"]
+        assert _sites(db, "post!=ante") == ["ggpoker", "ggpoker"]
+
+
+def test_cards():
+    def check(db):
+        for query in ("cards=AKs", "cards=aks", "cards=KA", "cards=ahkh"):
+            assert _sites(db, query) == ["pokerstars"], query
+        assert _sites(db, "cards=AhKd") == []
+        assert _sites(db, "cards~j") == ["ggpoker"]
+        assert _sites(db, "cards!=jj") == ["winamax", "pokerstars"]
+        # A stored row the card parser rejects reads as unknown cards
+        db.conn.execute(
+            "UPDATE hand_players SET hole_cards = 'Zz Kh' WHERE name = 'Alice'"
+        )
+        assert _sites(db, "cards=AKs") == [] and _sites(db, "range=AKs") == []
+
+    _with_db(check)
+
+
+def test_pagination():
+    def check(db):
+        first = search(db, "player=*", page=1, per_page=4)
+        last = search(db, "player=*", page=3, per_page=4)
+        assert first["total"] == 10 and first["pages"] == 3
+        assert len(first["hands"]) == 4 and len(last["hands"]) == 2
+        # Newest hand first
+        assert first["hands"][0]["site"] == "winamax"
+        assert search(db, "player=*", page=4, per_page=4)["hands"] == []
+
+    _with_db(check)
+
+
+def test_invalid_queries():
+    for query in (
+        "pot>>5",
+        "colour=red",
+        "position=BTN and",
+        "line=raise",
+        "(net<0",
+        "post=limp",
+        "cards=AhAh",
+        "cards=QQs",
+        "cards=zz",
+        "cards=QQ+",
+        "cards>AK",
+    ):
+        try:
+            compile_query(query)
+        except QueryError:
+            continue
+        raise AssertionError(f"{query!r} should not compile")
+
+
+def main():
+    print("Hand Query - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_fields_and_big_blind_units,
+        test_lines_and_players,
+        test_forced_posts,
+        test_cards,
+        test_pagination,
+        test_invalid_queries,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll hand query checks passed.")
+
+
+if __name__ == "__main__":
+    main()
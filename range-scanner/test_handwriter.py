@@ -0,0 +1,101 @@
+#!/usr/bin/env python3
+"""
+PokerStars hand history writer checks: every sample hand survives a round trip
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import parse_hand, parse_hands
+from handwriter import format_hand, format_hands, stars_id
+from test_handhistory import (
+    BOMB_POT_HAND,
+    GGPOKER_HAND,
+    POKERSTARS_HAND,
+    RUN_TWICE_HAND,
+    STRADDLE_HAND,
+    WINAMAX_HAND,
+)
+
+SAMPLES = [
+    POKERSTARS_HAND,
+    GGPOKER_HAND,
+    WINAMAX_HAND,
+    STRADDLE_HAND,
+    BOMB_POT_HAND,
+    RUN_TWICE_HAND,
+]
+# What a PokerStars file can't carry over from another site
+SITE_FIELDS = ("site", "hand_id", "tournament_id")
+
+
+def _same_hand(original, written):
+    expected, found = original.to_dict(), written.to_dict()
+    for name in SITE_FIELDS:
+        expected.pop(name), found.pop(name)
+    assert found == expected, original.hand_id
+
+
+def test_round_trip():
+    for text in SAMPLES:
+        hand = parse_hand(text)
+        written = parse_hand(format_hand(hand))
+        assert written.site == "pokerstars"
+        _same_hand(hand, written)
+    # PokerStars ids stay as they are
+    assert parse_hand(format_hand(parse_hand(POKERSTARS_HAND))).hand_id == (
+        "230000000001"
+    )
+
+
+def test_stars_lines():
+    text = format_hand(parse_hand(POKERSTARS_HAND))
+    assert "Tournament #3000000001, Hold'em No Limit - (15/30)" in text
+    assert "Seat 4: Dave (2000 in chips) is sitting out" in text
+    assert "Carol: raises 722 to 797 and is all-in" in text
+    assert "Seat 3: Carol (big blind) showed [Qs Qd] and lost" in text
+    cash = format_hand(parse_hand(WINAMAX_HAND))
+    assert "(€0.01/€0.02 EUR)" in cash and "Uncalled bet (€0.50)" in cash
+    assert "Seat 4: Hero (small blind) collected (€0.71)" in cash
+    # A lone winner behind a GGPoker show down header shows nothing
+    straddle = format_hand(parse_hand(STRADDLE_HAND))
+    assert "Hero: shows" not in straddle and "Hero: posts straddle $0.10" in straddle
+    twice = format_hand(parse_hand(RUN_TWICE_HAND))
+    assert "*** SECOND RIVER *** [Ks Kh 5c Jd] [4s]" in twice
+    assert "Hand was run twice" in twice
+
+
+def test_ids_and_stored_hands():
+    gg_id = stars_id("ggpoker", "HD1234567")
+    assert gg_id.isdigit() and gg_id == stars_id("ggpoker", "HD1234567")
+    assert gg_id != stars_id("winamax", "HD1234567")
+    hands = parse_hands("\n\n".join(SAMPLES))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        stored = list(db.hands())
+    written = parse_hands(format_hands(stored))
+    assert len(written) == len(SAMPLES)
+    for hand, again in zip(stored, written):
+        _same_hand(hand, again)
+
+
+def main():
+    print("Hand Writer - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_round_trip,
+        test_stars_lines,
+        test_ids_and_stored_hands,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll hand writer checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""
+Range heatmap checks: cell shading, solver frequencies, and each output format
+"""
+
+import argparse
+import contextlib
+import io
+import struct
+import sys
+import xml.etree.ElementTree as ElementTree
+
+sys.path.insert(0, ".")
+
+from cards import parse_cards
+import hand_range
+from hand_range import Range, class_combos
+from heatmap import Heatmap, solver_frequencies
+
+
+def test_shades():
+    heatmap = Heatmap.of(Range.parse("QQ+, AKs:0.5, AsQs, KQo"))
+    assert heatmap.shades["AA"] == heatmap.shades["QQ"] == heatmap.shades["KQo"] == 1
+    assert heatmap.shades["AKs"] == 0.5
+    assert heatmap.shades["AQs"] == 0.25
+    assert heatmap.shades["JJ"] == heatmap.shades["AKo"] == 0
+    assert heatmap.combos == 18 + 2 + 1 + 12
+    assert round(heatmap.percent, 2) == round(33 / 1326 * 100, 2)
+    grid = heatmap.to_dict()["grid"]
+    assert len(grid) == 13 and all(len(row) == 13 for row in grid)
+    assert grid[0][1] == {"hand": "AKs", "shade": 0.5}
+    assert grid[1][0]["hand"] == "AKo" and grid[12][12]["hand"] == "22"
+
+
+def test_frequencies():
+    first, second = parse_cards("AsKs")
+    strategy = {
+        "": {
+            "player": "OOP",
+            "actions": ["x", "b50"],
+            "combos": {"AsKs": [0.2, 0.8], "AhKh": [1.0, 0.0], "QdQc": [0.5, 0.5]},
+        }
+    }
+    frequencies = solver_frequencies(strategy, "", "b50")
+    assert frequencies[(first.index, second.index)] == 0.8
+    heatmap = Heatmap.of(Range.parse("QQ, AKs"), frequencies)
+    assert heatmap.shades["AKs"] == 0.2
+    assert heatmap.shades["QQ"] == round(0.5 / len(class_combos("QQ")), 4)
+    assert heatmap.combos == 1.3
+    for node, action in (("x", "b50"), ("", "b100")):
+        try:
+            solver_frequencies(strategy, node, action)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted node {node!r} action {action!r}")
+
+
+def test_formats():
+    heatmap = Heatmap.of(Range.parse("AA, AKs:0.5"))
+    plain = heatmap.text(color=False).splitlines()
+    assert len(plain) == 13
+    assert plain[0].split()[:3] == ["AA", "~AKs", "."]
+    colored = heatmap.text()
+    assert colored.count("\x1b[48;2;") == 169 and "\x1b[0m" in colored
+    svg = ElementTree.fromstring(heatmap.svg("Hero <BTN> open"))
+    namespace = "{http://www.w3.org/2000/svg}"
+    assert len(svg.findall(f"{namespace}rect")) == 169
+    texts = [element.text for element in svg.findall(f"{namespace}text")]
+    assert texts[0] == "Hero <BTN> open" and "50%" in texts
+    png = heatmap.png()
+    assert png.startswith(b"\x89PNG\r\n\x1a\n")
+    width, height = struct.unpack(">II", png[16:24])
+    assert width > 13 and height > 13
+
+
+def test_views():
+    parser = argparse.ArgumentParser()
+    hand_range.add_arguments(parser)
+    args = parser.parse_args(["show", "22+", "--no-color", "--format", "svg"])
+    assert (args.view, args.range, args.format) == ("show", ["22+"], "svg")
+    assert parser.parse_args(["count", "QQ+,", "AK"]).range == ["QQ+,", "AK"]
+    for argv in (["show"], ["count", "--format", "svg", "22+"], ["22+"]):
+        try:
+            with contextlib.redirect_stderr(io.StringIO()):
+                parser.parse_args(argv)
+        except SystemExit:
+            continue
+        raise AssertionError(f"accepted {argv}")
+
+
+def main():
+    print("Heatmap - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_shades,
+        test_frequencies,
+        test_formats,
+        test_views,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll heatmap checks passed.")
+
+
+if __name__ == "__main__":
+    main()
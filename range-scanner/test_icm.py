@@ -0,0 +1,71 @@
+#!/usr/bin/env python3
+"""
+ICM (Malmuth-Harville) checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from icm import icm_equities
+
+
+def test_heads_up_closed_form():
+    # Second place is guaranteed; first is won with the chip share
+    result = icm_equities([3000, 1000], [70, 30])
+    assert result.exact
+    assert [round(value, 6) for value in result.equities] == [60.0, 40.0]
+
+
+def test_three_handed():
+    result = icm_equities([5000, 3000, 2000], [50, 30, 20])
+    first = 0.5 * 50 + 0.3 * (5 / 7) * 30 + 0.2 * (5 / 8) * 30
+    first += (0.3 * (2 / 7) + 0.2 * (3 / 8)) * 20
+    assert abs(result.equities[0] - first) < 1e-9
+    assert abs(sum(result.equities) - 100) < 1e-9
+    # Big stacks are worth less than their chip share, short stacks more
+    chop = result.chip_chop
+    assert result.equities[0] < chop[0] and result.equities[2] > chop[2]
+
+    # Busted players take the places nobody with chips is left for
+    busted = icm_equities([100, 0, 50], [10, 5, 1])
+    assert abs(busted.equities[1] - 1) < 1e-9
+
+
+def test_sampling_matches_exact():
+    stacks = [5000, 3000, 2000, 1500, 1000, 800, 600, 500, 400, 300, 200, 100]
+    payouts = [50, 30, 20]
+    exact = icm_equities(stacks, payouts, exact_limit=len(stacks))
+    sampled = icm_equities(stacks, payouts, iterations=20_000, seed=3)
+    assert exact.exact and not sampled.exact
+    assert abs(sum(sampled.equities) - 100) < 1e-9
+    for want, got in zip(exact.equities, sampled.equities):
+        assert abs(want - got) < 0.6
+    assert icm_equities(stacks, payouts, iterations=500, seed=3) == icm_equities(
+        stacks, payouts, iterations=500, seed=3
+    )
+
+    for stacks, payouts in (([100], [1]), ([0, 0], [1]), ([1, 2], []), ([1, 2], [-1])):
+        try:
+            icm_equities(stacks, payouts)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {stacks} {payouts}")
+
+
+def main():
+    print("ICM - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_heads_up_closed_form,
+        test_three_handed,
+        test_sampling_matches_exact,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll ICM checks passed.")
+
+
+if __name__ == "__main__":
+    main()
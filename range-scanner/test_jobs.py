@@ -0,0 +1,243 @@
+#!/usr/bin/env python3
+"""
+Job queue checks: lifecycle, failures, cancels, and resuming after a restart
+"""
+
+import sqlite3
+import sys
+import tempfile
+import time
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+import jobs
+from jobs import JobKind, JobQueue, QueueFull
+
+
+class Crash(BaseException):
+    """Stands in for the process dying under a running job"""
+
+
+def _check(params):
+    if not isinstance(params.get("steps"), int) or params["steps"] < 1:
+        raise ValueError("steps must be a positive integer")
+    return params
+
+
+def _count(params, control):
+    """Counts to `steps`, checkpointing each step; crashes at `crash_at`"""
+    start = int(control.checkpoint or 0)
+    for step in range(start + 1, params["steps"] + 1):
+        control.report({"step": step}, checkpoint=str(step))
+        if step == params.get("crash_at") and start == 0:
+            raise Crash()
+    return {"steps": params["steps"], "resumed_from": start}
+
+
+def _fail(params, control):
+    raise RuntimeError("engine blew up")
+
+
+KINDS = {"count": JobKind(_check, _count), "fail": JobKind(lambda p: p, _fail)}
+
+
+def test_lifecycle():
+    queue = JobQueue(KINDS, workers=0)
+    for kind, params in (("nope", {}), ("count", {"steps": 0}), ("count", [])):
+        try:
+            queue.submit(kind, params)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {kind} {params}")
+    job = queue.submit("count", {"steps": 3})
+    assert queue.get(job.id).status == "queued"
+    assert queue.run_next() is not None
+    done = queue.get(job.id)
+    assert (done.status, done.result, done.progress) == (
+        "done",
+        {"steps": 3, "resumed_from": 0},
+        {"step": 3},
+    )
+    # Each report and each status change bumped the version
+    assert done.version == 5 and done.started_at and done.finished_at
+    assert not done.to_dict()["resumable"]
+    assert "result" not in done.to_dict(result=False)
+    assert queue.run_next() is None
+    second = queue.submit("count", {"steps": 1})
+    assert [old.id for old in queue.jobs()] == [second.id, job.id]
+    assert [old.id for old in queue.jobs(status="done")] == [job.id]
+    assert queue.jobs(kind="fail") == []
+    try:
+        queue.get("missing")
+    except KeyError:
+        pass
+    else:
+        raise AssertionError("found a missing job")
+    queue.close()
+
+
+def test_failures_and_cancels():
+    queue = JobQueue(KINDS, workers=0)
+    failed = queue.submit("fail", {})
+    queue.run_next()
+    failed = queue.get(failed.id)
+    assert (failed.status, failed.error) == ("failed", "engine blew up")
+    # A queued job is cancelled at once
+    queued = queue.submit("count", {"steps": 2})
+    assert queue.cancel(queued.id).status == "cancelled"
+    assert queue.run_next() is None
+    for job_id in (queued.id, failed.id):
+        try:
+            queue.cancel(job_id)
+        except ValueError:
+            continue
+        raise AssertionError(f"cancelled finished job {job_id}")
+
+    # A running job stops at its next report
+    def cancel_midway(params, control):
+        control.report({"step": 1})
+        queue.cancel(control.job.id)
+        control.report({"step": 2})
+        return {"steps": 2}
+
+    queue.kinds["midway"] = JobKind(lambda p: p, cancel_midway)
+    running = queue.submit("midway", {})
+    queue.run_next()
+    cancelled = queue.get(running.id)
+    assert (cancelled.status, cancelled.progress, cancelled.result) == (
+        "cancelled",
+        {"step": 1},
+        None,
+    )
+    queue.close()
+
+
+def test_resume_after_restart():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "jobs.sqlite"
+        queue = JobQueue(KINDS, path, workers=0)
+        job = queue.submit("count", {"steps": 5, "crash_at": 3})
+        try:
+            queue.run_next()
+        except Crash:
+            pass
+        assert queue.get(job.id).status == "running"
+        queue.close()
+        # The restarted queue runs it again from its checkpoint
+        queue = JobQueue(KINDS, path, workers=0)
+        requeued = queue.get(job.id)
+        assert requeued.status == "queued" and requeued.to_dict()["resumable"]
+        queue.run_next()
+        done = queue.get(job.id)
+        assert done.status == "done" and done.result["resumed_from"] == 3
+        assert done.checkpoint is None
+        queue.close()
+
+
+def test_workers():
+    queue = JobQueue(KINDS, workers=2)
+    submitted = [queue.submit("count", {"steps": steps}) for steps in (1, 2, 3)]
+    deadline = time.monotonic() + 10
+    while time.monotonic() < deadline:
+        if all(queue.get(job.id).finished for job in submitted):
+            break
+        time.sleep(0.01)
+    assert [queue.get(job.id).result["steps"] for job in submitted] == [1, 2, 3]
+    queue.close()
+
+
+def test_retention():
+    saved, jobs.MAX_FINISHED_JOBS = jobs.MAX_FINISHED_JOBS, 2
+    try:
+        queue = JobQueue(KINDS, workers=0)
+        submitted = [queue.submit("count", {"steps": 1}) for _ in range(4)]
+        waiting = queue.submit("count", {"steps": 1})
+        for _ in submitted:
+            queue.run_next()
+        kept = {job.id for job in queue.jobs()}
+        assert kept == {submitted[2].id, submitted[3].id, waiting.id}
+        queue.close()
+    finally:
+        jobs.MAX_FINISHED_JOBS = saved
+
+
+def test_limits_and_owners():
+    saved = jobs.MAX_UNFINISHED_JOBS, jobs.MAX_UNFINISHED_PER_OWNER
+    jobs.MAX_UNFINISHED_JOBS, jobs.MAX_UNFINISHED_PER_OWNER = 3, 2
+    try:
+        queue = JobQueue(KINDS, workers=0)
+        first = queue.submit("count", {"steps": 1}, owner="7")
+        queue.submit("count", {"steps": 1}, owner="7")
+        try:
+            queue.submit("count", {"steps": 1}, owner="7")
+        except QueueFull:
+            pass
+        else:
+            raise AssertionError("took a third job from one owner")
+        other = queue.submit("count", {"steps": 1}, owner="8")
+        assert queue.get(other.id).owner == "8"
+        try:
+            queue.submit("count", {"steps": 1})
+        except QueueFull:
+            pass
+        else:
+            raise AssertionError("took a fourth unfinished job")
+        # Only the owner cancels; without an owner anyone may
+        try:
+            queue.cancel(first.id, owner="8")
+        except PermissionError:
+            pass
+        else:
+            raise AssertionError("cancelled another owner's job")
+        assert queue.cancel(first.id, owner="7").status == "cancelled"
+        assert queue.cancel(other.id).status == "cancelled"
+        # Finished jobs no longer count
+        assert queue.submit("count", {"steps": 1}, owner="7").owner == "7"
+        queue.close()
+    finally:
+        jobs.MAX_UNFINISHED_JOBS, jobs.MAX_UNFINISHED_PER_OWNER = saved
+
+
+def test_owner_column_added():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "jobs.sqlite"
+        conn = sqlite3.connect(path)
+        conn.execute(
+            "CREATE TABLE jobs (id TEXT PRIMARY KEY, kind TEXT NOT NULL, "
+            "status TEXT NOT NULL, params TEXT NOT NULL, created_at TEXT NOT NULL, "
+            "started_at TEXT, finished_at TEXT, progress TEXT, result TEXT, "
+            "error TEXT, version INTEGER NOT NULL DEFAULT 0, checkpoint TEXT)"
+        )
+        conn.execute(
+            "INSERT INTO jobs (id, kind, status, params, created_at) "
+            "VALUES ('old', 'count', 'queued', '{\"steps\": 1}', '2024-01-01 00:00:00')"
+        )
+        conn.commit()
+        conn.close()
+        queue = JobQueue(KINDS, path, workers=0)
+        assert queue.get("old").owner is None
+        assert queue.run_next().status == "done"
+        queue.close()
+
+
+def main():
+    print("Jobs - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_lifecycle,
+        test_failures_and_cancels,
+        test_resume_after_restart,
+        test_workers,
+        test_retention,
+        test_limits_and_owners,
+        test_owner_column_added,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll job checks passed.")
+
+
+if __name__ == "__main__":
+    main()
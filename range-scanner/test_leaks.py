@@ -0,0 +1,143 @@
+#!/usr/bin/env python3
+"""
+Leak detection checks on synthetic hands
+"""
+
+import json
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import Action, Hand, Player
+from leaks import find_leaks, leak_report, load_baselines, wilson_interval
+from stats import compute_stats
+
+
+def _hand(hand_id: int, hero_limps: bool) -> Hand:
+    """Hero (UTG) limps or opens; the button 3-bets an open and Hero folds"""
+    players = [
+        Player(1, "Hero", 100, [], "UTG"),
+        Player(2, "Villain", 100, [], "BTN"),
+        Player(3, "Small", 100, [], "SB"),
+        Player(4, "Big", 100, [], "BB"),
+    ]
+    actions = [
+        Action("Small", "preflop", "small_blind", 0.5),
+        Action("Big", "preflop", "big_blind", 1),
+    ]
+    if hero_limps:
+        actions += [
+            Action("Hero", "preflop", "call", 1),
+            Action("Villain", "preflop", "fold", 0),
+            Action("Small", "preflop", "fold", 0),
+            Action("Big", "preflop", "check", 0),
+        ]
+        collected = {"Big": 2.5}
+    else:
+        actions += [
+            Action("Hero", "preflop", "raise", 3, 2),
+            Action("Villain", "preflop", "raise", 9, 6),
+            Action("Small", "preflop", "fold", 0),
+            Action("Big", "preflop", "fold", 0),
+            Action("Hero", "preflop", "fold", 0),
+        ]
+        collected = {"Villain": 13.5}
+    return Hand(
+        site="test",
+        hand_id=str(hand_id),
+        small_blind=0.5,
+        big_blind=1,
+        players=players,
+        actions=actions,
+        collected=collected,
+        total_pot=sum(collected.values()),
+    )
+
+
+def _hands(limps: int, opens: int):
+    return [_hand(idx, idx < limps) for idx in range(limps + opens)]
+
+
+def test_wilson_interval():
+    low, high = wilson_interval(3, 4)
+    assert low < 35 < 75 < high
+    low, high = wilson_interval(0, 100)
+    assert low == 0 and high < 4
+    assert wilson_interval(0, 0) == (0.0, 100.0)
+
+
+def test_flags_significant_leaks():
+    table = compute_stats(_hands(limps=20, opens=40), by="position")
+    hero = table["Hero"]
+    assert hero["all"].fold_to_three_bet_pct == 100.0
+    assert hero["UTG"].limp_pct == round(20 / 60 * 100, 1)
+    flagged = {leak.name: leak for leak in find_leaks(table) if leak.player == "Hero"}
+    assert flagged["fold_to_three_bet"].direction == "high"
+    assert flagged["fold_to_three_bet"].sample == 40
+    assert flagged["ep_limp"].direction == "high"
+    assert flagged["ep_limp"].sample == 60
+    # Villain 3-bet every open, but 40 chances is under the 100-hand VPIP sample
+    villain = [leak.name for leak in find_leaks(table) if leak.player == "Villain"]
+    assert "vpip" not in villain
+
+    # Too few hands to be sure
+    small = compute_stats(_hands(limps=2, opens=4), by="position")
+    assert find_leaks(small) == []
+
+
+def test_baseline_overrides_and_report():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "baselines.json"
+        path.write_text(
+            json.dumps(
+                {
+                    "fold_to_three_bet": {"high": 100},
+                    "ep_limp": {"min_sample": 1000},
+                    "utg_open": {
+                        "count": "pfr",
+                        "chances": "open_chances",
+                        "positions": ["UTG"],
+                        "low": 80,
+                        "high": 100,
+                    },
+                }
+            )
+        )
+        baselines = load_baselines(path)
+        bad = {"count": "nope", "chances": "hands", "low": 0, "high": 1}
+        path.write_text(json.dumps({"bad": bad}))
+        try:
+            load_baselines(path)
+        except ValueError:
+            pass
+        else:
+            raise AssertionError("accepted an unknown stat")
+
+        with HandDB(Path(tmp) / "h.sqlite") as db:
+            db.insert_hands(_hands(limps=20, opens=40))
+            report = leak_report(db, ["Hero"], baselines)
+    assert report["hands"] == {"Hero": 60}
+    names = [leak["name"] for leak in report["leaks"]]
+    assert names == ["utg_open"]
+    assert report["leaks"][0]["direction"] == "low"
+
+
+def main():
+    print("Leak Detection - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_wilson_interval,
+        test_flags_significant_leaks,
+        test_baseline_overrides_and_report,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll leak detection checks passed.")
+
+
+if __name__ == "__main__":
+    main()
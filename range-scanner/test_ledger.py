@@ -0,0 +1,137 @@
+#!/usr/bin/env python3
+"""
+Home game ledger checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from ledger import Ledger, _greedy, settle_up
+from money import ExchangeRates
+
+
+def _apply(nets, transfers):
+    left = dict(nets)
+    for transfer in transfers:
+        left[transfer.payer] += transfer.amount
+        left[transfer.payee] -= transfer.amount
+    return {name: round(value, 2) for name, value in left.items()}
+
+
+def test_settle_up_fewest_transfers():
+    nets = {"A": 9, "B": -8, "C": 6, "D": -2, "E": 3, "F": 4, "G": -12}
+    transfers = settle_up(nets)
+    # {A, E, G} and {B, C, D, F} each settle on their own; greedy needs six
+    assert len(_greedy({name: net * 100 for name, net in nets.items()})) == 6
+    assert len(transfers) == 5
+    assert set(_apply(nets, transfers).values()) == {0}
+    assert all(transfer.amount > 0 for transfer in transfers)
+
+    assert settle_up({"A": 0.1, "B": 0.2, "C": -0.3}) == settle_up(
+        {"C": -0.3, "B": 0.2, "A": 0.1}
+    )
+    assert settle_up({"A": 0, "B": 0}) == []
+    try:
+        settle_up({"A": 10, "B": -5})
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("unbalanced nets accepted")
+
+
+def test_game_books():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        ledger = Ledger(db)
+        game_id = ledger.create_game("Friday", "2024-03-01 19:00")
+        for player, kind, amount in (
+            ("Alice", "buy_in", 200),
+            ("Bob", "buy_in", 100),
+            ("Bob", "buy_in", 100),
+            ("Cara", "buy_in", 100),
+            ("Alice", "cash_out", 320),
+            ("Bob", "cash_out", 60),
+        ):
+            game = ledger.add_entry(game_id, player, kind, amount)
+        assert game.players["Bob"].net == -140 and not game.balanced
+        assert game.discrepancy == -120 and game.to_dict()["transfers"] is None
+        try:
+            ledger.settle(game_id)
+        except ValueError as exc:
+            assert "off by 120.00" in str(exc)
+        else:
+            raise AssertionError("unbalanced game settled")
+
+        game = ledger.add_entry(game_id, "Cara", "cash_out", 120)
+        assert game.balanced
+        assert [transfer.to_dict() for transfer in game.transfers()] == [
+            {"payer": "Bob", "payee": "Alice", "amount": 120.0},
+            {"payer": "Bob", "payee": "Cara", "amount": 20.0},
+        ]
+        settled = ledger.settle(game_id)
+        assert settled.settled_at is not None
+        for args in ((game_id, "Dan", "buy_in", 50), (99, "Dan", "buy_in", 50)):
+            try:
+                ledger.add_entry(*args)
+            except (KeyError, ValueError):
+                continue
+            raise AssertionError(f"accepted {args}")
+
+        second = ledger.create_game("Saturday", "2024-03-02 19:00")
+        ledger.add_entry(second, "Alice", "buy_in", 100)
+        ledger.add_entry(second, "Alice", "cash_out", 40)
+        alice = ledger.players()[0]
+        assert alice["player"] == "Alice" and alice["games"] == 2
+        assert alice["net"] == 60
+        assert [game.name for game in ledger.games()] == ["Friday", "Saturday"]
+        assert ledger.delete_game(second) and not ledger.delete_game(second)
+
+
+def test_currencies():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        ledger = Ledger(db)
+        for name, currency, played_at in (
+            ("Paris", "eur", "2024-03-01 19:00"),
+            ("London", "GBP", "2024-06-01 19:00"),
+        ):
+            game_id = ledger.create_game(name, played_at, currency)
+            ledger.add_entry(game_id, "Alice", "buy_in", 100)
+            ledger.add_entry(game_id, "Alice", "cash_out", 200)
+            ledger.add_entry(game_id, "Bob", "buy_in", 100)
+        assert ledger.game(game_id).to_dict()["currency"] == "GBP"
+        try:
+            ledger.players()
+        except ValueError as exc:
+            assert "EUR, GBP" in str(exc)
+        else:
+            raise AssertionError("added euros to pounds")
+        rates = ExchangeRates(db)
+        rates.set_rate("EUR", "USD", 1.1, "2024-01-01")
+        rates.set_rate("GBP", "USD", 1.25, "2024-01-01")
+        # London is priced at the snapshot in force on its night
+        rates.set_rate("GBP", "USD", 1.3, "2024-05-01")
+        alice = ledger.players("USD")[0]
+        assert alice["currency"] == "USD" and alice["net"] == 240
+        bob = ledger.players("EUR")[1]
+        assert bob["net"] == round(-100 - 100 * 1.3 / 1.1, 2)
+
+
+def main():
+    print("Ledger - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_settle_up_fewest_transfers,
+        test_game_books,
+        test_currencies,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll ledger checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,108 @@
+#!/usr/bin/env python3
+"""
+Betting line report checks on hand-built hands
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from handhistory import Action, Hand, Player
+from lines import compute_lines, filter_samples, hand_decisions, lines_to_dict
+
+
+def _hand(hand_id, board, postflop):
+    """Heads-up: Hero opens the button, Villain calls in the big blind"""
+    return Hand(
+        site="test",
+        hand_id=hand_id,
+        small_blind=1,
+        big_blind=2,
+        board=board,
+        players=[
+            Player(1, "Hero", 200, [], "BTN"),
+            Player(2, "Villain", 200, [], "BB"),
+        ],
+        actions=[
+            Action("Villain", "preflop", "big_blind", 2),
+            Action("Hero", "preflop", "raise", 6, 4),
+            Action("Villain", "preflop", "call", 4),
+            *postflop,
+        ],
+    )
+
+
+def test_probe_after_check_back():
+    hand = _hand(
+        "1",
+        ["Kh", "7d", "2c", "9s"],
+        [
+            Action("Villain", "flop", "check"),
+            Action("Hero", "flop", "check"),
+            Action("Villain", "turn", "bet", 8),
+            Action("Hero", "turn", "fold"),
+        ],
+    )
+    assert hand_decisions(hand) == [
+        ("Villain", "flop", "SRP caller OOP | flop unopened", "check"),
+        ("Hero", "flop", "SRP PFA IP | flop unopened", "check"),
+        ("Villain", "turn", "SRP caller OOP flop:x | turn unopened", "bet"),
+        ("Hero", "turn", "SRP PFA IP flop:x | turn vs bet", "fold"),
+    ]
+
+
+def test_report_by_texture():
+    hands = [
+        _hand(
+            "2",
+            ["Ah", "Kh", "3h"],
+            [
+                Action("Villain", "flop", "check"),
+                Action("Hero", "flop", "bet", 4),
+                Action("Villain", "flop", "raise", 16, 12),
+                Action("Hero", "flop", "call", 12),
+            ],
+        ),
+        _hand(
+            "3",
+            ["8d", "7c", "2s"],
+            [
+                Action("Villain", "flop", "check"),
+                Action("Hero", "flop", "bet", 4),
+                Action("Villain", "flop", "fold"),
+            ],
+        ),
+        _hand("4", [], [Action("Villain", "preflop", "fold")]),
+    ]
+    table = compute_lines(hands, ["Hero"], "flop")
+    cbet = table["SRP PFA IP | flop unopened"]["all"]
+    assert cbet.samples == 2 and cbet.frequency("bet") == 100.0
+    assert set(table) == {"SRP PFA IP | flop unopened", "SRP PFA IP | flop vs raise"}
+    by_suits = compute_lines(hands, ["Hero"], "flop", "suits")
+    assert set(by_suits["SRP PFA IP | flop unopened"]) == {"monotone", "rainbow"}
+    # The check-raise spot only has one sample
+    kept = filter_samples(table, 2)
+    assert list(lines_to_dict(kept)) == ["SRP PFA IP | flop unopened"]
+    for street, by in (("preflop", None), (None, "weather")):
+        try:
+            compute_lines(hands, street=street, by=by)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted street={street} by={by}")
+
+
+def main():
+    print("Betting Lines - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_probe_after_check_back,
+        test_report_by_texture,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll betting line checks passed.")
+
+
+if __name__ == "__main__":
+    main()
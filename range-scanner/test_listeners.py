@@ -0,0 +1,195 @@
+#!/usr/bin/env python3
+"""
+Listener spec, admin route, IPv6, and TLS checks on loopback servers
+"""
+
+import json
+import shutil
+import socket
+import ssl
+import subprocess
+import sys
+import tempfile
+import threading
+import urllib.request
+from http.server import BaseHTTPRequestHandler
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from listeners import Listener, check_listeners, make_servers, parse_listener
+
+
+class EchoHandler(BaseHTTPRequestHandler):
+    """Answers with the path, or 404 where the server hides the route"""
+
+    def do_GET(self):
+        status = 200 if self.server.exposes("GET", self.path) else 404
+        body = json.dumps({"path": self.path}).encode("utf-8")
+        self.send_response(status)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, format, *args):
+        pass
+
+
+def _status(url, context=None) -> int:
+    try:
+        with urllib.request.urlopen(url, timeout=5, context=context) as response:
+            return response.status
+    except urllib.error.HTTPError as exc:
+        return exc.code
+
+
+def _serving(listeners):
+    servers = make_servers(listeners, EchoHandler)
+    for server in servers:
+        threading.Thread(target=server.serve_forever, daemon=True).start()
+    return servers
+
+
+def _stop(servers):
+    for server in servers:
+        server.shutdown()
+        server.server_close()
+
+
+def test_parse():
+    assert parse_listener("127.0.0.1:9001") == Listener("127.0.0.1", 9001)
+    assert parse_listener(":9000").host == "0.0.0.0"
+    six = parse_listener("[::]:9000,cert=a.pem,key=a.key", admin=True)
+    assert (six.host, six.port, six.admin, six.cert, six.key) == (
+        "::",
+        9000,
+        True,
+        "a.pem",
+        "a.key",
+    )
+    assert six.url == "https://[::]:9000"
+    assert parse_listener("[::1]:80").url == "http://[::1]:80"
+    # A combined certificate and key file is enough
+    assert parse_listener("0.0.0.0:443,cert=both.pem").key is None
+    for spec in (
+        "9000",
+        "::1:9000",
+        "[::1]9000",
+        "host:http",
+        "host:70000",
+        "host:1,key=a.key",
+        "host:1,ca=x",
+        "host:1,cert=",
+    ):
+        try:
+            parse_listener(spec)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {spec!r}")
+
+
+def test_check():
+    public, admin = Listener("0.0.0.0", 9000), Listener("127.0.0.1", 9001, True)
+    check_listeners([public, admin])
+    check_listeners([Listener("0.0.0.0", 0), Listener("0.0.0.0", 0)])
+    for listeners in (
+        [],
+        [admin],
+        [public, Listener("0.0.0.0", 9000, True)],
+        [Listener("0.0.0.0", 443, cert="missing.pem")],
+    ):
+        try:
+            check_listeners(listeners)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {listeners}")
+
+
+def test_admin_routes():
+    servers = _serving(
+        [Listener("127.0.0.1", 0), Listener("127.0.0.1", 0, admin=True)]
+    )
+    public, admin = (server.listener.url for server in servers)
+    try:
+        for path in ("/api/keys", "/api/audit", "/api/export", "/api/ledger/games"):
+            assert _status(public + path) == 404, path
+            assert _status(admin + path) == 200, path
+        for path in ("/health", "/api/stats", "/api/tourney/clock"):
+            assert _status(public + path) == 200, path
+            assert _status(admin + path) == 200, path
+    finally:
+        _stop(servers)
+    # Without an admin listener nothing is hidden
+    (server,) = _serving([Listener("127.0.0.1", 0)])
+    try:
+        assert _status(server.listener.url + "/api/keys") == 200
+    finally:
+        _stop([server])
+
+
+def test_ipv6():
+    if not socket.has_ipv6:
+        print("  (no IPv6, skipped)")
+        return
+    try:
+        (server,) = _serving([parse_listener("[::1]:0")])
+    except OSError:
+        print("  (no IPv6 loopback, skipped)")
+        return
+    try:
+        assert server.address_family == socket.AF_INET6
+        assert _status(server.listener.url + "/health") == 200
+    finally:
+        _stop([server])
+
+
+def test_tls():
+    if not shutil.which("openssl"):
+        print("  (no openssl, skipped)")
+        return
+    with tempfile.TemporaryDirectory() as tmp:
+        cert, key = Path(tmp) / "server.pem", Path(tmp) / "server.key"
+        subprocess.run(
+            ["openssl", "req", "-x509", "-newkey", "rsa:2048", "-nodes"]
+            + ["-keyout", str(key), "-out", str(cert), "-days", "1"]
+            + ["-subj", "/CN=localhost"],
+            check=True,
+            capture_output=True,
+        )
+        tls = parse_listener(f"127.0.0.1:0,cert={cert},key={key}")
+        servers = _serving([tls, Listener("127.0.0.1", 0)])
+        secure, plain = (server.listener.url for server in servers)
+        try:
+            assert secure.startswith("https://") and plain.startswith("http://")
+            context = ssl.create_default_context(cafile=str(cert))
+            context.check_hostname = False
+            assert _status(secure + "/health", context) == 200
+            # A plain HTTP client fails the handshake without stopping the server
+            try:
+                _status(secure.replace("https", "http", 1) + "/health")
+            except (OSError, ValueError):
+                pass
+            assert _status(secure + "/health", context) == 200
+            assert _status(plain + "/health") == 200
+        finally:
+            _stop(servers)
+
+
+def main():
+    print("Listeners - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_parse,
+        test_check,
+        test_admin_routes,
+        test_ipv6,
+        test_tls,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll listener checks passed.")
+
+
+if __name__ == "__main__":
+    main()
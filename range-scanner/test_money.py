@@ -0,0 +1,82 @@
+#!/usr/bin/env python3
+"""
+Currency codes and exchange-rate snapshot checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from money import ExchangeRates, normalize_currency, output_currency
+
+
+def test_currency_codes():
+    assert normalize_currency("eur") == normalize_currency("€") == "EUR"
+    assert normalize_currency(None) == normalize_currency(" ") == ""
+    for bad in ("euro", "E1", "$$"):
+        try:
+            normalize_currency(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad!r}")
+    assert output_currency(["EUR", "", "EUR"]) == "EUR"
+    assert output_currency(["EUR", "GBP"], "usd") == "USD"
+    assert output_currency([]) == ""
+    try:
+        output_currency(["EUR", "GBP"])
+    except ValueError as exc:
+        assert "EUR, GBP" in str(exc)
+    else:
+        raise AssertionError("mixed currencies without a target")
+
+
+def test_snapshots():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        rates = ExchangeRates(db)
+        rates.set_rate("EUR", "USD", 1.10, "2024-01-01")
+        rates.set_rate("EUR", "USD", 1.20, "2024-06-01")
+        rates.set_rate("GBP", "USD", 1.25, "2024-01-01")
+        # Latest snapshot at or before the time, the earliest before them all
+        assert rates.rate("EUR", "USD", "2024-03-01") == 1.10
+        assert rates.rate("EUR", "USD", "2024/07/01 9:05:00") == 1.20
+        assert rates.rate("EUR", "USD", "2023-01-01") == 1.10
+        # Inverted and crossed through USD
+        assert rates.rate("USD", "EUR", "2024-03-01") == 1 / 1.10
+        assert round(rates.rate("GBP", "EUR", "2024-03-01"), 4) == 1.1364
+        assert rates.convert(50, "", "EUR") == rates.convert(50, "EUR", "EUR") == 50
+        # Re-recording a snapshot replaces it
+        rates.set_rate("eur", "usd", 1.15, "2024-01-01")
+        assert rates.rate("EUR", "USD", "2024-03-01") == 1.15
+        assert len(rates.snapshots()) == 3
+        for args in (("JPY", "USD"), ("EUR", "USD", "someday")):
+            try:
+                rates.rate(*args)
+            except ValueError:
+                continue
+            raise AssertionError(f"priced {args}")
+        for args in (("EUR", "EUR", 1.0), ("EUR", "USD", 0), ("EUR", "", 1.0)):
+            try:
+                rates.set_rate(*args)
+            except ValueError:
+                continue
+            raise AssertionError(f"stored {args}")
+
+
+def main():
+    print("Money - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_currency_codes,
+        test_snapshots,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll money checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,210 @@
+#!/usr/bin/env python3
+"""
+Webhook notifier checks
+"""
+
+import json
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+import notify
+from handdb import HandDB
+from handhistory import Action, Hand, Player
+from notify import (
+    Rule,
+    Webhook,
+    check_database,
+    encode,
+    hand_alerts,
+    load_config,
+    seating_alerts,
+    send,
+    verify_signature,
+)
+
+
+def _cooler() -> Hand:
+    """Aces all-in preflop against kings, a king on the river"""
+    return Hand(
+        site="test",
+        hand_id="77",
+        tournament_id="T1",
+        small_blind=1,
+        big_blind=2,
+        board=["2c", "7d", "9h", "Ts", "Kc"],
+        players=[
+            Player(1, "Aces", 50, ["As", "Ad"], "SB"),
+            Player(2, "Kings", 300, ["Ks", "Kd"], "BB"),
+        ],
+        actions=[
+            Action("Aces", "preflop", "raise", 50, 48, all_in=True),
+            Action("Kings", "preflop", "call", 48),
+        ],
+        collected={"Kings": 100},
+        total_pot=100,
+        showdown=True,
+    )
+
+
+RULES = [
+    Rule("big_pot", min_bb=40),
+    Rule("bad_beat", min_equity=0.8),
+    Rule("elimination"),
+]
+
+
+def test_hand_alerts():
+    alerts = hand_alerts(_cooler(), RULES)
+    assert [alert.rule for alert in alerts] == ["big_pot", "bad_beat", "elimination"]
+    assert "50 bb pot won by Kings" in alerts[0].text
+    assert "Aces (As Ad) lost all-in on the preflop" in alerts[1].text
+    assert alerts[2].text == "Aces is eliminated: Hand #77 (tournament T1)"
+    assert hand_alerts(_cooler(), [Rule("big_pot", min_bb=60)]) == []
+    assert hand_alerts(_cooler(), [Rule("elimination", players=["Bob"])]) == []
+    events = [
+        {"version": 1, "type": "draw", "players": 20},
+        {"version": 2, "type": "bust", "player": "Carol", "table": 3, "seat": 4},
+    ]
+    busts = seating_alerts(events, RULES)
+    assert [alert.text for alert in busts] == ["Carol is eliminated (table 3)"]
+
+
+def test_send_and_config():
+    posted = []
+
+    def post(url, body):
+        if "broken" in url:
+            raise OSError("connection refused")
+        posted.append((url, body))
+
+    hooks = [
+        Webhook.from_config("https://discord.com/api/webhooks/1/x"),
+        Webhook.from_config({"url": "https://hooks.slack.com/services/T/B/x"}),
+        Webhook.from_config({"url": "https://broken.example", "format": "slack"}),
+    ]
+    failures = send(hand_alerts(_cooler(), RULES[:1]), hooks, post)
+    assert len(failures) == 1 and "connection refused" in failures[0]
+    assert [sorted(body) for _, body in posted] == [["content"], ["text"]]
+
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "notify.json"
+        path.write_text(json.dumps({"webhooks": [], "rules": [{"type": "bad_beat"}]}))
+        webhooks, rules = load_config(path)
+        assert webhooks == [] and rules[0].min_equity == 0.8
+        for bad in ({"rules": []}, {"rules": [{"type": "weather"}]}):
+            path.write_text(json.dumps(bad))
+            try:
+                load_config(path)
+            except ValueError:
+                continue
+            raise AssertionError(f"accepted {bad}")
+
+        db = HandDB(Path(tmp) / "hands.sqlite")
+        db.insert_hand(_cooler())
+        alerts, last = check_database(db, RULES)
+        assert len(alerts) == 3 and last == 1
+        assert check_database(db, RULES, last) == ([], 1)
+        db.close()
+
+
+def test_signed_json_webhooks():
+    posted = []
+
+    def post(url, body, headers=None):
+        posted.append((url, body, headers))
+
+    hook = Webhook.from_config({"url": "https://league.example/hooks", "secret": "s"})
+    assert hook.format == "json"
+    rules = [Rule("hand_completed"), Rule("elimination")]
+    alerts = hand_alerts(_cooler(), rules)
+    assert [alert.rule for alert in alerts] == ["hand_completed", "elimination"]
+    discord = Webhook.from_config("https://discord.com/api/webhooks/1/x")
+    assert send(alerts, [hook, discord], post) == []
+    (_, body, headers), (_, plain, none) = posted[0], posted[1]
+    assert body["event"] == "hand_completed" and body["hand_id"] == "77"
+    assert body["data"]["collected"] == {"Kings": 100}
+    assert posted[2][1]["data"]["player"] == "Aces"
+    assert sorted(plain) == ["content"] and none is None
+    timestamp = headers["X-Pokertools-Timestamp"]
+    signature = headers["X-Pokertools-Signature"]
+    assert signature.startswith("sha256=")
+    assert verify_signature("s", encode(body), timestamp, signature)
+    assert not verify_signature("t", encode(body), timestamp, signature)
+    assert not verify_signature("s", encode({}), timestamp, signature)
+    late = int(timestamp) + 3600
+    assert not verify_signature("s", encode(body), timestamp, signature, now=late)
+
+
+def test_query_rules():
+    rule = Rule.from_dict(
+        {"type": "query", "query": "player=* and pot>40bb", "label": "Cooler"}
+    )
+    for bad in (
+        {"type": "query"},
+        {"type": "query", "query": "pot>>1"},
+        {"type": "query", "query": "pot>1bb", "max_seconds": 0},
+    ):
+        try:
+            Rule.from_dict(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+    with tempfile.TemporaryDirectory() as tmp:
+        db = HandDB(Path(tmp) / "hands.sqlite")
+        db.insert_hand(_cooler())
+        # Query rules need the database; hand_alerts alone skips them
+        assert hand_alerts(_cooler(), [rule]) == []
+        alerts, last = check_database(db, [rule])
+        assert [alert.text for alert in alerts] == ["Hand #77 (tournament T1): Cooler"]
+        assert alerts[0].data["query"] == rule.query
+        assert check_database(db, [rule], last) == ([], last)
+        quiet = Rule("query", query="pot>500bb")
+        bob = Rule("query", query="player=* and showdown=true", players=["Bob"])
+        assert check_database(db, [quiet, bob]) == ([], 1)
+        db.close()
+
+
+def test_query_timeout():
+    slow = Rule.from_dict(
+        {"type": "query", "query": "player=* and pot>40bb", "max_seconds": 1e-9}
+    )
+    saved, notify.PROGRESS_STEPS = notify.PROGRESS_STEPS, 1
+    try:
+        with tempfile.TemporaryDirectory() as tmp:
+            db = HandDB(Path(tmp) / "hands.sqlite")
+            db.insert_hand(_cooler())
+            # The slow rule is skipped with a warning; the others still run
+            warnings = []
+            alerts, last = check_database(db, [slow, RULES[0]], 0, warnings)
+            assert [alert.rule for alert in alerts] == ["big_pot"] and last == 1
+            assert warnings == [
+                "query rule 'player=* and pot>40bb' ran past 1e-09s and was skipped"
+            ], warnings
+            # The deadline does not outlive the rule
+            assert db.conn.execute("SELECT COUNT(*) FROM hands").fetchone() == (1,)
+            db.close()
+    finally:
+        notify.PROGRESS_STEPS = saved
+
+
+def main():
+    print("Notifier - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_hand_alerts,
+        test_send_and_config,
+        test_signed_json_webhooks,
+        test_query_rules,
+        test_query_timeout,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll notifier checks passed.")
+
+
+if __name__ == "__main__":
+    main()
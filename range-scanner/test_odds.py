@@ -0,0 +1,87 @@
+#!/usr/bin/env python3
+"""
+Pot odds and outs checks against textbook examples
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from hand_evaluator import HandClass
+from odds import (
+    count_outs,
+    draw_probability,
+    implied_odds_needed,
+    pot_odds,
+    required_equity,
+    rule_of_2_and_4,
+)
+
+
+def approx(value: float, expected: float, tolerance: float) -> bool:
+    return abs(value - expected) <= tolerance
+
+
+def test_pot_odds():
+    # Half-pot bet: 150 in the middle, 50 to call -> 3:1, 25%
+    assert pot_odds(150, 50) == 3.0
+    assert required_equity(150, 50) == 0.25
+    # Pot-sized bet -> 2:1, 33.3%
+    assert approx(required_equity(200, 100), 1 / 3, 1e-12)
+
+
+def test_draw_probabilities():
+    # Flush draw on the flop: 9 outs, 47 unseen
+    assert approx(draw_probability(9, 47, 1), 0.1915, 0.0001)
+    assert approx(draw_probability(9, 47, 2), 0.3497, 0.0001)
+    # Open-ender on the turn: 8 outs, 46 unseen
+    assert approx(draw_probability(8, 46, 1), 0.1739, 0.0001)
+    assert rule_of_2_and_4(9, 2) == 0.36
+    assert rule_of_2_and_4(8, 1) == 0.16
+
+
+def test_implied_odds():
+    # 100 pot, 20 call, 20% equity: already break-even on direct odds
+    assert implied_odds_needed(100, 20, 0.2) == 0.0
+    # 100 pot, 50 call, 1-in-5 to hit: needs 100 more on later streets
+    assert approx(implied_odds_needed(100, 50, 0.2), 100.0, 1e-9)
+
+
+def test_flush_draw_outs():
+    outs = count_outs("9h8h", "Ah7h2c", min_class=HandClass.FLUSH)
+    assert outs.count == 9
+    # The deuce of hearts pairs the board, so full houses become possible
+    assert [str(card) for card in outs.tainted] == ["2h"]
+    assert outs.discounted == 8.5
+
+
+def test_straight_draw_outs():
+    outs = count_outs("JhTh", "9c8d2s", min_class=HandClass.STRAIGHT)
+    assert outs.count == 8 and not outs.tainted
+    # Overcards also improve, but a board pair is never an out
+    everything = count_outs("JhTh", "9c8d2s")
+    assert everything.count == 14
+    assert all(card.rank not in (0, 6, 7) for card in everything.clean)
+    # With a two-tone board the straight cards of that suit are tainted
+    two_tone = count_outs("JhTh", "9c8c2s", min_class=HandClass.STRAIGHT)
+    assert sorted(str(card) for card in two_tone.tainted) == ["7c", "Qc"]
+
+
+def main():
+    print("Odds Helpers - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_pot_odds,
+        test_draw_probabilities,
+        test_implied_odds,
+        test_flush_draw_outs,
+        test_straight_draw_outs,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll odds checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,67 @@
+#!/usr/bin/env python3
+"""
+Table pace checks on hand start times
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from pace import table_sessions
+
+
+def _starts(table, times):
+    return [("live", table, f"2024-01-05 {time}") for time in times]
+
+
+def test_sessions():
+    rows = _starts(
+        "Table 1",
+        # Two minutes a hand, a 15-minute break, then two more hands;
+        # then the table closes for the evening and reopens later
+        ["20:00:00", "20:02:00", "20:04:00", "20:19:00", "20:21:00", "23:00:00"],
+    ) + _starts("Table 2", ["20:00:00", "20:01:30"])
+    first, late, other = table_sessions(rows)
+    assert (first.table, first.hands) == ("Table 1", 5)
+    assert (first.started_at, first.ended_at) == (
+        "2024-01-05 20:00:00",
+        "2024-01-05 20:21:00",
+    )
+    # Four intervals in 21 minutes, breaks included
+    assert first.hands_per_hour == round(4 / 21 * 60, 1)
+    # The break is left out of the hand duration
+    assert first.avg_hand_seconds == first.median_hand_seconds == 120
+    assert first.break_seconds == first.longest_gap_seconds == 900
+    assert (late.hands, late.hands_per_hour, late.avg_hand_seconds) == (1, 0.0, 0.0)
+    assert other.table == "Table 2" and other.avg_hand_seconds == 90
+    assert table_sessions([]) == []
+
+
+def test_gap_options():
+    rows = _starts("T", ["20:00:00", "20:05:00", "20:10:00"])
+    (session,) = table_sessions(rows, session_gap=600, break_gap=240)
+    assert session.avg_hand_seconds == 0.0 and session.break_seconds == 600
+    assert len(table_sessions(rows, session_gap=240, break_gap=240)) == 3
+    for session_gap, break_gap in ((600, 0), (60, 120)):
+        try:
+            table_sessions(rows, session_gap, break_gap)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted gaps {session_gap}/{break_gap}")
+
+
+def main():
+    print("Pace - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_sessions,
+        test_gap_options,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll pace checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,86 @@
+#!/usr/bin/env python3
+"""
+Tournament payout checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from payouts import calculate_payouts, parse_rake, standard_shares
+
+
+def test_pool_and_rake():
+    assert parse_rake("10%", 110) == 11 and parse_rake(10, 110) == 10
+    table = calculate_payouts(180, 110, "10", reentries=40)
+    assert table.entries == 220 and table.prize_pool == 22_000
+    assert table.rake_total == 2_200 and table.overlay == 0
+    # 15% of the entries, rounded to whole units with the rest going to first
+    assert len(table.amounts) == 33 and sum(table.amounts) == 22_000
+    assert all(amount == int(amount) for amount in table.amounts)
+    assert table.amounts == sorted(table.amounts, reverse=True)
+
+    # Re-entries never pay more places than there are players
+    assert len(calculate_payouts(4, 10, reentries=40).amounts) == 4
+
+    guaranteed = calculate_payouts(50, 100, guarantee=10_000)
+    assert guaranteed.prize_pool == 10_000 and guaranteed.overlay == 5_000
+
+
+def test_standard_min_cash():
+    shares = standard_shares(150, 100_000, 100, min_cash=1.5)
+    assert abs(sum(shares) - 1) < 1e-9
+    assert shares[-1] * 100_000 == 150 and shares[0] > shares[1] > shares[-1]
+
+
+def test_models():
+    assert calculate_payouts(10, 20, model="wta").amounts == [200]
+    flat = calculate_payouts(100, 10, model="flat", paid_fraction=0.1)
+    assert flat.amounts == [100] * 10
+    custom = calculate_payouts(9, 50, "10%", model="custom", percentages=[65, 35])
+    assert custom.amounts == [264, 141]
+    for kwargs in (
+        dict(model="custom", percentages=[50, 40]),
+        dict(model="custom", percentages=[30, 70]),
+        dict(model="custom", percentages=[10] * 10),
+        dict(model="tiered"),
+        dict(rake="120%"),
+    ):
+        try:
+            calculate_payouts(9, 50, **kwargs)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {kwargs}")
+
+
+def test_bubble_refunds():
+    table = calculate_payouts(180, 110, 10, reentries=40, bubble_refunds=1)
+    assert table.bubble == [110.0] and len(table.amounts) == 33
+    assert sum(table.amounts) + sum(table.bubble) == table.prize_pool
+    places = table.to_dict()["places"]
+    assert places[-1] == {"place": 34, "amount": 110, "percent": 0.5, "buy_ins": 1}
+    try:
+        calculate_payouts(10, 100, bubble_refunds=7)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("refunds bigger than a min-cash accepted")
+
+
+def main():
+    print("Payouts - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_pool_and_rake,
+        test_standard_min_cash,
+        test_models,
+        test_bubble_refunds,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll payout checks passed.")
+
+
+if __name__ == "__main__":
+    main()
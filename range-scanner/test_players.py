@@ -0,0 +1,82 @@
+#!/usr/bin/env python3
+"""
+Player notes and tags checks
+"""
+
+import sqlite3
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import MIGRATIONS, HandDB
+from players import PlayerNotes
+
+
+def test_update_and_partial_update():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        notes = PlayerNotes(db)
+        assert notes.get("Villain") is None
+        created = notes.update("Villain", "Overfolds rivers", "Red", ["Reg", "nit", ""])
+        assert (created.color, created.tags) == ("red", ["nit", "reg"])
+
+        # Only the color changes; notes and tags stay
+        recolored = notes.update("Villain", color="#00FF00")
+        assert recolored.notes == "Overfolds rivers"
+        assert recolored.tags == ["nit", "reg"] and recolored.color == "#00ff00"
+        cleared = notes.update("Villain", tags=[], clear_color=True)
+        assert cleared.color is None and cleared.tags == []
+        assert notes.get("Villain") == cleared
+
+        for bad in ({"color": "pink"}, {"tags": ["x" * 40]}):
+            try:
+                notes.update("Villain", **bad)
+            except ValueError:
+                continue
+            raise AssertionError(f"accepted {bad}")
+
+
+def test_tags_listing_and_delete():
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        notes = PlayerNotes(db)
+        notes.update("Alice", tags=["fish"])
+        notes.update("Bob", tags=["reg", "fish"])
+        notes.update("Carol", "Just a note")
+        assert [note.name for note in notes.all()] == ["Alice", "Bob", "Carol"]
+        assert [note.name for note in notes.all("FISH")] == ["Alice", "Bob"]
+        assert set(notes.lookup(["Alice", "Dave"])) == {"Alice"}
+        assert notes.delete("Bob") and not notes.delete("Bob")
+        tags = db.conn.execute("SELECT COUNT(*) FROM player_tags").fetchone()[0]
+        assert tags == 1
+
+
+def test_upgrade_from_v2():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "old.sqlite"
+        conn = sqlite3.connect(path)
+        conn.executescript(
+            f"{MIGRATIONS[0]}; {MIGRATIONS[1]}; PRAGMA user_version = 2;"
+        )
+        conn.close()
+        with HandDB(path) as db:
+            assert db.schema_version == len(MIGRATIONS) >= 3
+            assert PlayerNotes(db).update("Hero", "me").notes == "me"
+
+
+def main():
+    print("Player Notes - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_update_and_partial_update,
+        test_tags_listing_and_delete,
+        test_upgrade_from_v2,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll player notes checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,119 @@
+#!/usr/bin/env python3
+"""
+Side-pot construction and payout checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from game_evaluators import HandScore
+from pots import (
+    Pot,
+    build_pots,
+    net_results,
+    resolve,
+    return_uncalled,
+    settle,
+    settle_runs,
+)
+
+
+def test_three_way_all_in():
+    # A all-in for 100, B and C play for 300, D folded after putting in 50
+    pots = build_pots([100, 300, 300, 50], folded=[False, False, False, True])
+    assert pots == [Pot(350, [0, 1, 2]), Pot(400, [1, 2])]
+    # A has the best hand, B beats C for the side pot
+    payouts = settle(pots, scores=[9, 5, 3, 0])
+    assert payouts == [350, 400, 0, 0]
+    assert net_results([100, 300, 300, 50], payouts) == [250, 100, -300, -50]
+
+
+def test_uncalled_bet_is_returned():
+    amounts, player, refund = return_uncalled([500, 200, 200])
+    assert (amounts, player, refund) == ([200, 200, 200], 0, 300)
+    payouts = resolve([500, 200, 200], scores=[1, 2, 3])
+    assert payouts == [300, 0, 600]
+
+
+def test_dead_money_is_not_a_call():
+    # Big-blind ante: BB posts 30 + a 30 ante, BTN raises to 75, BB folds
+    amounts, player, refund = return_uncalled([75, 60], dead=[0, 30])
+    assert (amounts, player, refund) == ([30, 60], 0, 45)
+    # Bomb pot of 25 each, then a 100 bet on the flop that one player calls
+    contributions = [25, 125, 125]
+    pots = build_pots(contributions, [True, False, False], dead=[25, 25, 25])
+    assert pots == [Pot(275, [1, 2])]
+    try:
+        return_uncalled([10, 20], dead=[20, 0])
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("accepted more dead money than put in")
+
+
+def test_odd_chip_goes_left_of_button():
+    pots = build_pots([25, 25, 25])
+    # Seats 0 and 2 chop 75: seat 0 is first left of the button on seat 2
+    assert settle(pots, scores=[4, 1, 4], button=2) == [38, 0, 37]
+    assert settle(pots, scores=[4, 1, 4], button=0) == [37, 0, 38]
+
+
+def test_hi_lo_split():
+    scores = [HandScore(10, 0), HandScore(5, 7), HandScore(3, 9)]
+    # 101 chips: high half keeps the odd chip
+    assert settle([Pot(101, [0, 1, 2])], scores, split=True) == [51, 0, 50]
+    # No qualifying low: high takes everything
+    no_low = [HandScore(10), HandScore(5), HandScore(3)]
+    assert settle(build_pots([10, 10, 10]), no_low, split=True) == [30, 0, 0]
+
+
+def test_multiple_side_pots_and_rake():
+    contributions = [50, 120, 300, 300, 20]
+    folded = [False, False, False, False, True]
+    pots = build_pots(contributions, folded)
+    assert [pot.amount for pot in pots] == [220, 210, 360]
+    assert [pot.eligible for pot in pots] == [[0, 1, 2, 3], [1, 2, 3], [2, 3]]
+    # Shortest stack wins the main pot; rake comes out of it first
+    payouts = settle(pots, scores=[9, 8, 7, 6, 0], rake=10)
+    assert payouts == [210, 210, 360, 0, 0]
+    assert sum(payouts) == sum(contributions) - 10
+
+
+def test_run_it_twice():
+    pots = [Pot(201, [0, 1, 2]), Pot(200, [1, 2])]
+    # Seat 0 wins the main pot on the first run only; seat 1 wins the rest
+    runs = settle_runs(pots, [[9, 5, 3], [1, 5, 3]])
+    assert runs == [[101, 100, 0], [0, 200, 0]]
+    # The odd chip went to the first run; with a chip of rake there is none
+    runs = settle_runs(pots, [[9, 5, 3], [1, 5, 3]], rake=1)
+    assert runs == [[100, 100, 0], [0, 200, 0]]
+    assert settle_runs(pots, [[9, 5, 3]]) == [settle(pots, [9, 5, 3])]
+    try:
+        settle_runs(pots, [])
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("accepted a hand with no runs")
+
+
+def main():
+    print("Side Pots - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_three_way_all_in,
+        test_uncalled_bet_is_returned,
+        test_dead_money_is_not_a_call,
+        test_odd_chip_goes_left_of_button,
+        test_hi_lo_split,
+        test_multiple_side_pots_and_rake,
+        test_run_it_twice,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll side-pot checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,122 @@
+#!/usr/bin/env python3
+"""
+Push/fold solver checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from hand_range import PREFLOP_ORDER
+from pushfold import (
+    EquityTable,
+    PushFoldSpot,
+    _equity_row,
+    format_grid,
+    grid_png,
+    nash_chart,
+    range_labels,
+    solve,
+)
+
+
+def ranked_table() -> EquityTable:
+    """Stand-in for the Monte Carlo table: equity follows PREFLOP_ORDER"""
+    classes = list(PREFLOP_ORDER)
+    size = len(classes)
+    equity = [
+        [0.5 + 0.35 * (other - hand) / size for other in range(size)]
+        for hand in range(size)
+    ]
+    return EquityTable(classes, equity, samples=0)
+
+
+def test_equity_table():
+    aces, kings, trash = _equity_row((0, ["AA", "KK", "72o"], 300, 1))
+    assert abs(aces - 0.5) < 0.05
+    assert 0.75 < kings < 0.88 and trash > 0.8
+
+    table = ranked_table()
+    index = table.classes.index
+    # Card removal: AKs shares an ace with three of the six AA combos
+    assert table.weights[index("AA")][index("AKs")] == 12
+    assert table.weights[index("72o")][index("33")] == 72
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "equity.json"
+        table.save(path)
+        loaded = EquityTable.load(path)
+        assert loaded.weights == table.weights
+        assert loaded.equity[0][-1] == round(table.equity[0][-1], 4)
+
+
+def test_outcomes():
+    spot = PushFoldSpot([10, 20, 8], ante=0.5)
+    values = spot.outcomes()
+    # Antes 1.5 plus blinds 0.5 and 1 in the middle
+    assert values["fold"] == [9.5, 19, 9.5]
+    assert values["steal"] == [12.5, 19, 6.5]
+    # Pusher vs the big blind: 7.5 behind each, the small blind's 0.5 is dead
+    assert values[("win", 2)] == [19, 19, 0]
+    assert values[("lose", 2)] == [2, 19, 17]
+    assert sum(values[("win", 1)]) == sum(values["fold"]) == 38
+
+    icm = PushFoldSpot([10, 10], payouts=[65, 35], others=[10]).outcomes()
+    # Busting on the bubble is worth nothing; doubling up less than double
+    assert icm[("lose", 1)][0] == 0
+    assert icm[("win", 1)][0] < 2 * icm["fold"][0]
+    for bad in ([10], [10, 0]):
+        try:
+            PushFoldSpot(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_solver():
+    table = ranked_table()
+    short = solve(PushFoldSpot([2, 2]), table, iterations=60)
+    deep = solve(PushFoldSpot([20, 20]), table, iterations=60)
+    assert "AA" in deep.push_range() and "AA" in deep.call_range()
+    assert len(short.push_range()) > len(deep.push_range()) > 0
+    assert set(deep.push_range()) <= set(short.push_range())
+
+    # ICM: calling off a stack on a bubble needs a stronger hand than chips do
+    chips = solve(PushFoldSpot([10, 10]), table, iterations=60)
+    bubble = PushFoldSpot([10, 10], payouts=[50, 30], others=[10])
+    icm = solve(bubble, table, iterations=60)
+    assert len(icm.call_range()) < len(chips.call_range())
+
+    chart = nash_chart(table, [2, 20], iterations=40)
+    assert chart["push"]["AA"] == 20 and chart["push"]["72o"] in (None, 2)
+
+
+def test_grid_output():
+    freqs = {name: 1.0 if name in ("AA", "AKs") else 0.0 for name in PREFLOP_ORDER}
+    freqs["AKo"] = 0.5
+    rows = format_grid(range_labels(freqs)).splitlines()
+    assert len(rows) == 13
+    assert rows[0].split()[:3] == ["AA", "AKs", "."]
+    assert rows[1].split()[0] == "~AKo" and rows[-1].split()[-1] == "."
+    png = grid_png(freqs)
+    assert png.startswith(b"\x89PNG\r\n\x1a\n") and png.endswith(b"IEND\xaeB`\x82")
+
+
+def main():
+    print("Push/Fold - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_equity_table,
+        test_outcomes,
+        test_solver,
+        test_grid_output,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll push/fold checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,86 @@
+#!/usr/bin/env python3
+"""
+Rake model checks: percentage, caps, tiers, and no-flop-no-drop
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from rake import RakeLedger, RakeModel, RakeTier
+
+
+def test_percentage_and_cap():
+    model = RakeModel(percent=5, cap=300)
+    assert model.rake_for(1000) == 50
+    assert model.rake_for(10_000) == 300
+    # Fractional chips round down
+    assert model.rake_for(39) == 1
+
+
+def test_no_flop_no_drop():
+    model = RakeModel(percent=5, cap=300)
+    assert model.rake_for(1000, saw_flop=False) == 0
+    assert RakeModel(no_flop_no_drop=False).rake_for(1000, saw_flop=False) == 50
+
+
+def test_stakes_tiers():
+    model = RakeModel.from_dict(
+        {
+            "percent": 4,
+            "cap": 500,
+            "tiers": [
+                {"max_big_blind": 200, "percent": 5, "cap": 300},
+                {"max_big_blind": 50, "percent": 6, "cap": 100},
+            ],
+        }
+    )
+    assert model.terms(50) == RakeTier(50, 6, 100)
+    assert model.rake_for(1000, big_blind=50) == 60
+    assert model.rake_for(10_000, big_blind=100) == 300
+    # Above every tier the defaults apply
+    assert model.rake_for(10_000, big_blind=400) == 400
+
+
+def test_settle_hand_and_ledger():
+    model = RakeModel(percent=5, cap=300)
+    # Uncalled 400 goes back to seat 0 and is never raked
+    result = model.settle_hand([1000, 600], scores=[3, 7], big_blind=100)
+    assert result.pot_total == 1200 and result.rake == 60
+    assert result.payouts == [400, 1140]
+    assert result.net == [-600, 540]
+
+    folded_preflop = model.settle_hand(
+        [150, 100], scores=[1, 0], folded=[False, True], saw_flop=False
+    )
+    assert folded_preflop.rake == 0 and folded_preflop.payouts == [250, 0]
+
+    ledger = RakeLedger()
+    ledger.record("h1", result, big_blind=100)
+    ledger.record("h2", folded_preflop, big_blind=100)
+    assert ledger.total == 60
+    assert ledger.by_stakes()[100] == {
+        "hands": 2,
+        "raked_hands": 1,
+        "pot": 1400,
+        "rake": 60,
+    }
+
+
+def main():
+    print("Rake Model - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_percentage_and_cap,
+        test_no_flop_no_drop,
+        test_stakes_tiers,
+        test_settle_hand_and_ledger,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll rake checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,91 @@
+#!/usr/bin/env python3
+"""
+Token-bucket rate limiter checks with a fake clock
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from ratelimit import RateLimiter, is_limited
+
+
+class FakeClock:
+    def __init__(self):
+        self.now = 0.0
+
+    def __call__(self) -> float:
+        return self.now
+
+
+def test_limited_routes():
+    for path in ("/api/equity", "/api/equity/stream", "/api/hands", "/api/stats"):
+        assert is_limited(path), path
+    assert is_limited("/api/hands/42/replay")
+    # Starting a job or a solve is limited; polling one is not
+    assert is_limited("/api/jobs") and is_limited("/api/solver")
+    for path in (
+        "/health",
+        "/api/tourney/clock",
+        "/api/statsx",
+        "/api/export",
+        "/api/jobs/3f2a/stream",
+        "/api/solver/3f2a",
+    ):
+        assert not is_limited(path), path
+    assert is_limited("/api/jobs/3f2a", "DELETE")
+    assert is_limited("/api/players/Hero/notes", "PUT")
+
+
+def test_buckets():
+    clock = FakeClock()
+    limiter = RateLimiter(per_minute=60, burst=3, clock=clock)
+    assert all(limiter.acquire(["ip:a"])[0] for _ in range(3))
+    allowed, retry_after = limiter.acquire(["ip:a"])
+    assert not allowed and retry_after == 1.0
+    # Other clients keep their own buckets
+    assert limiter.acquire(["ip:b"])[0]
+    clock.now = 1.0
+    assert limiter.acquire(["ip:a"])[0]
+    assert not limiter.acquire(["ip:a"])[0]
+    # Refills never exceed the burst
+    clock.now = 1000.0
+    assert all(limiter.acquire(["ip:a"])[0] for _ in range(3))
+    assert not limiter.acquire(["ip:a"])[0]
+
+
+def test_key_and_address():
+    clock = FakeClock()
+    limiter = RateLimiter(per_minute=60, burst=2, clock=clock)
+    # One key from two addresses shares the key's bucket
+    assert limiter.acquire(["ip:a", "key:1"])[0]
+    assert limiter.acquire(["ip:b", "key:1"])[0]
+    assert not limiter.acquire(["ip:c", "key:1"])[0]
+    # A refusal takes nothing, so ip:c still has its whole burst
+    assert limiter.acquire(["ip:c", "key:2"])[0]
+    assert limiter.acquire(["ip:c"])[0]
+    assert not limiter.acquire(["ip:c", "key:3"])[0]
+    for per_minute, burst in ((0, 1), (60, 0)):
+        try:
+            RateLimiter(per_minute, burst)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {per_minute}/min, burst {burst}")
+
+
+def main():
+    print("Rate Limit - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_limited_routes,
+        test_buckets,
+        test_key_and_address,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll rate limit checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""
+Hand replayer timeline checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import parse_hand, parse_hands
+from replay import build_replay, find_hand, format_frames
+from test_handhistory import (
+    GGPOKER_HAND,
+    POKERSTARS_HAND,
+    RUN_TWICE_HAND,
+    WINAMAX_HAND,
+)
+
+
+def test_chips_are_conserved():
+    for text in (POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND, RUN_TWICE_HAND):
+        hand = parse_hand(text)
+        frames = build_replay(hand, equity=False)
+        start, end = frames[0], frames[-1]
+        # Whatever left the stacks is the rake still sitting in the pot
+        assert round(sum(start.stacks.values()) - sum(end.stacks.values()), 2) == (
+            end.pot
+        )
+        assert end.pot == hand.rake, hand.site
+        for name, net in hand.net().items():
+            assert round(end.stacks[name] - start.stacks[name], 2) == net
+
+
+def test_timeline_shape():
+    frames = build_replay(parse_hand(WINAMAX_HAND), equity=False)
+    kinds = [frame.kind for frame in frames]
+    assert kinds[0] == "start" and kinds[1:3] == ["post", "post"]
+    boards = [frame for frame in frames if frame.kind == "board"]
+    assert [len(frame.board) for frame in boards] == [3, 4]
+    raise_frame = next(frame for frame in frames if frame.action == "raise")
+    assert raise_frame.deltas == {"Player Two": -0.06} and raise_frame.pot == 0.09
+    # No-one called the turn bet: it comes back and there is nothing to show
+    assert kinds[-2:] == ["uncalled", "collect"] and "show" not in kinds
+    assert "Hero raises to 0.3" in format_frames(frames)
+
+
+def test_run_it_twice():
+    frames = build_replay(parse_hand(RUN_TWICE_HAND), equity=False)
+    # Nothing was shared, so there is no board frame before the runs
+    assert not [frame for frame in frames if frame.kind == "board"]
+    runs = [frame for frame in frames if frame.kind == "run"]
+    assert [frame.board for frame in runs] == [
+        ["Kd", "7c", "2h", "9s", "3d"],
+        ["Ks", "Kh", "5c", "Jd", "4s"],
+    ]
+    assert runs[0].text.startswith("RUN 1 [Kd 7c 2h 9s 3d]: Alice with")
+    assert runs[1].text.startswith("RUN 2 [Ks Kh 5c Jd 4s]: Bob with")
+    kinds = [frame.kind for frame in frames]
+    assert kinds[-4:] == ["run", "collect", "run", "collect"]
+
+
+def test_equity_at_decisions():
+    frames = build_replay(parse_hand(POKERSTARS_HAND), iterations=2_000)
+    call = next(frame for frame in frames if frame.text == "Alice calls 722")
+    assert set(call.equities) == {"Alice", "Bob Smith", "Carol"}
+    assert abs(sum(call.equities.values()) - 1) < 1e-3
+    # Heads-up on the river with both hands known: Alice's kings hold
+    river = next(frame for frame in frames if frame.street == "river")
+    assert river.equities == {"Alice": 1.0, "Carol": 0.0}
+    assert all(frame.equities is None for frame in frames if frame.kind == "post")
+
+
+def test_lookup_by_hand_id():
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND]))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        assert find_hand(db, "HD1234567").site == "ggpoker"
+        assert find_hand(db, "HD1234567", site="ggpoker").hand_id == "HD1234567"
+        try:
+            find_hand(db, "HD1234567", site="pokerstars")
+        except KeyError:
+            pass
+        else:
+            raise AssertionError("site filter was ignored")
+
+
+def main():
+    print("Hand Replayer - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_chips_are_conserved,
+        test_timeline_shape,
+        test_run_it_twice,
+        test_equity_at_decisions,
+        test_lookup_by_hand_id,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll replayer checks passed.")
+
+
+if __name__ == "__main__":
+    main()
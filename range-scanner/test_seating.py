@@ -0,0 +1,108 @@
+#!/usr/bin/env python3
+"""
+Tournament seating checks
+"""
+
+import random
+import sys
+
+sys.path.insert(0, ".")
+
+from seating import Seating
+
+PLAYERS = [f"Player {number}" for number in range(1, 21)]
+
+
+def _sizes(seating):
+    return [len(seats) for _, seats in sorted(seating.tables.items())]
+
+
+def test_draw():
+    seating = Seating(9, random.Random(1))
+    state = seating.draw(PLAYERS)
+    assert _sizes(seating) == [7, 7, 6] and state["players"] == 20
+    seated = [name for table in state["tables"] for name in table["seats"].values()]
+    assert sorted(seated) == sorted(PLAYERS)
+    for table in state["tables"]:
+        assert str(table["button"]) in table["seats"]
+    for bad in (["Solo"], ["Same", "Same"], ["A", ""]):
+        try:
+            seating.draw(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_balance_moves_the_next_big_blind():
+    full = {str(seat): f"A{seat}" for seat in range(1, 7)}
+    short = {str(seat): f"B{seat}" for seat in range(1, 6)}
+    seating = Seating.from_dict(
+        {
+            "seats_per_table": 6,
+            "tables": [
+                {"table": 1, "button": 2, "seats": full},
+                {"table": 2, "button": 1, "seats": short},
+            ],
+        },
+        random.Random(2),
+    )
+    # Button 2: seat 3 posts the small blind, 4 the big blind, 5 is due next
+    assert seating.big_blind_seat(1) == 4
+    moves = seating.bust("B1")
+    assert [(move.player, move.reason) for move in moves] == [("A5", "balance")]
+    assert [event["type"] for event in seating.events] == ["bust", "move"]
+    assert seating.bust("B2") == []
+    assert _sizes(seating) == [5, 4] and seating.locate("A5")[0] == 2
+
+    # Heads-up the button is the small blind
+    heads_up = Seating.from_dict(
+        {"tables": [{"table": 1, "button": 7, "seats": {"3": "X", "7": "Y"}}]}
+    )
+    assert heads_up.big_blind_seat(1) == 3
+
+
+def test_tables_break():
+    seating = Seating(9, random.Random(3))
+    seating.draw(PLAYERS)
+    moves = []
+    for player in PLAYERS[:2]:
+        moves += seating.bust(player)
+    # 18 players fit on two full tables: table 3 breaks
+    assert sorted(seating.tables) == [1, 2] and _sizes(seating) == [9, 9]
+    broken = [move for move in moves if move.reason == "break"]
+    assert broken and all(move.from_table == 3 for move in broken)
+    last = seating.events[-1]
+    assert (last["type"], last["table"]) == ("break", 3)
+    for player in PLAYERS[2:18]:
+        seating.bust(player)
+    assert len(seating.tables) == 1 and seating.player_count == 2
+
+    table, seat = seating.add("Late")
+    assert (table, seating.tables[table][seat]) == (1, "Late")
+    version = seating.version
+    seating.set_button(1, seat)
+    assert [event["type"] for event in seating.events_since(version)] == ["button"]
+    try:
+        seating.bust("Player 1")
+    except KeyError:
+        pass
+    else:
+        raise AssertionError("busted a player twice")
+
+
+def main():
+    print("Seating - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_draw,
+        test_balance_moves_the_next_big_blind,
+        test_tables_break,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll seating checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,67 @@
+#!/usr/bin/env python3
+"""
+Deal simulator checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from hand_evaluator import HandClass
+from sim import SEVEN_CARD_PERCENT, parse_hand_class, simulate
+
+
+def test_class_frequencies_match_the_textbook():
+    result = simulate("holdem", 1, 20_000, seed=7).to_dict()
+    assert result["hero"] == "random"
+    assert abs(sum(result["outcomes"].values()) - 100) < 0.01
+    # Heads-up and unconstrained, both seats are the same: wins ~ losses
+    assert abs(result["outcomes"]["win"] - result["outcomes"]["lose"]) < 3
+    for hand, expected in SEVEN_CARD_PERCENT.items():
+        assert abs(result["hero_classes"][hand.label] - expected) < 1.5, hand
+    # The winner's hand is never worse than the hero's on average
+    assert result["winning_classes"]["High Card"] < result["hero_classes"]["High Card"]
+
+
+def test_hero_constraints():
+    aces = simulate("holdem", 4, 3_000, hero="AsAh", seed=1)
+    trash = simulate("holdem", 4, 3_000, hero="72o", seed=1)
+    assert aces.wins > 2 * trash.wins
+    assert aces.hero_classes["High Card"] == 0
+    flopped = simulate("holdem", 1, 500, hero="AsKs", board="QsJsTs", seed=2)
+    assert flopped.wins + flopped.ties == 500
+    assert flopped.hero_classes["Straight Flush"] == 500
+    for bad in ({"hero": "AsKs", "board": "AsQd2c"}, {"opponents": 0}):
+        try:
+            simulate("holdem", **{"opponents": 1, "hands": 10, **bad})
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_coolers_and_lowball():
+    result = simulate("omaha", 5, 2_000, cooler_class=HandClass.FLUSH, seed=3)
+    data = result.to_dict()
+    assert 0 < data["coolers"]["hero_lost"] < data["coolers"]["rate"] < 100
+    assert parse_hand_class("full house") == HandClass.FULL_HOUSE
+    assert parse_hand_class("set") == HandClass.TRIPS
+    razz = simulate("razz", 3, 500, seed=4).to_dict()
+    assert "coolers" not in razz and razz["players"] == 4
+
+
+def main():
+    print("Deal Simulator - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_class_frequencies_match_the_textbook,
+        test_hero_constraints,
+        test_coolers_and_lowball,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll deal simulator checks passed.")
+
+
+if __name__ == "__main__":
+    main()
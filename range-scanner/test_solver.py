@@ -0,0 +1,102 @@
+#!/usr/bin/env python3
+"""
+CFR+ river solver checks
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from solver import Solver, SolverConfig
+
+# Nuts (straights) and air against a bluff catcher that can only call or fold
+TOY_SPOT = dict(
+    board="AhKhQd7c2s",
+    ranges=["JT,65", "A9"],
+    pot=10,
+    stack=10,
+    bet_sizes=[1.0],
+    raise_sizes=[],
+    max_raises=0,
+)
+
+
+def test_tree():
+    solver = Solver(SolverConfig("Ks7d4c2h2s", ["QQ+", "TT+"], 10, 20, [0.5, 3.0]))
+    # A 3x pot bet is more than the stack, so it becomes the all-in
+    assert solver.root.actions == ["x", "b50", "a"]
+    # 5 + (15 + 5) > 20: the pot-sized raise is an all-in too
+    assert solver.node("b50").actions == ["f", "c", "a"]
+    assert solver.node("b50-a").committed == (5.0, 20.0)
+    assert solver.node("b50-a").actions == ["f", "c"]
+    assert solver.node("x").player == 1 and solver.node("x-x").player is None
+    for bad in (["QQ+"], ["QQ+", "KsKh"]):
+        try:
+            Solver(SolverConfig("Ks7d4c2h2s", bad, 10, 20))
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_showdown_card_removal():
+    solver = Solver(SolverConfig("Ks7d4c2h2s", ["AA", "AK"], 10, 20))
+    reach = list(solver.hands[1].weights)
+    wins, losses = solver._showdown(0, reach)
+    met = solver._met(0, reach)
+    # Each AA combo blocks two aces: 2 x 3 of the 12 AK combos are left
+    assert met == [6.0] * 6 and wins == met and losses == [0.0] * 6
+    assert solver._met(1, list(solver.hands[0].weights)) == [3.0] * 12
+
+
+def test_toy_game_equilibrium():
+    solver = Solver(SolverConfig(**TOY_SPOT))
+    progress = solver.run(300)
+    assert progress.iteration == 300 and progress.exploitability < 0.5
+    # Pot-sized bet: one bluff per two value bets, called half the time
+    root = solver.summary("")
+    assert root["classes"]["JTo"][1] > 0.99
+    assert abs(root["classes"]["65o"][1] - 0.5) < 0.05
+    assert abs(solver.summary("a")["overall"][1] - 0.5) < 0.05
+
+    buckets = Solver(SolverConfig(**TOY_SPOT, buckets=2))
+    assert buckets.hands[0].bucket_count == 2
+    buckets.run(300)
+    assert abs(buckets.summary("")["classes"]["65s"][1] - 0.5) < 0.05
+
+
+def test_save_and_resume():
+    solver = Solver(SolverConfig(**TOY_SPOT))
+    reports = []
+    solver.run(40, reports.append, report_every=20)
+    assert [report.iteration for report in reports] == [20, 40]
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "solve.json"
+        solver.save(path)
+        resumed = Solver.load(path)
+    assert resumed.iteration == 40
+    assert resumed.strategy() == solver.strategy()
+    resumed.run(20)
+    solver.run(20)
+    assert resumed.strategy("a") == solver.strategy("a")
+    assert set(solver.strategy("")[""]["combos"]) >= {"JhTh", "6s5s"}
+
+
+def main():
+    print("Solver - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_tree,
+        test_showdown_card_removal,
+        test_toy_game_equilibrium,
+        test_save_and_resume,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll solver checks passed.")
+
+
+if __name__ == "__main__":
+    main()
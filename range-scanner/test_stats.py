@@ -0,0 +1,109 @@
+#!/usr/bin/env python3
+"""
+Player statistics checks on the sample hands
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import parse_hand, parse_hands
+from money import ExchangeRates
+from stats import compute_stats, hand_stats, load_stats
+from test_handhistory import GGPOKER_HAND, POKERSTARS_HAND, WINAMAX_HAND
+
+
+def test_preflop_counters():
+    stats = hand_stats(parse_hand(POKERSTARS_HAND))
+    # Dave is sitting out and is not dealt in
+    assert sorted(stats) == ["Alice", "Bob Smith", "Carol"]
+    alice, bob, carol = stats["Alice"], stats["Bob Smith"], stats["Carol"]
+    assert (alice.vpip, alice.pfr, alice.three_bet_chances) == (1, 1, 0)
+    assert (bob.vpip, bob.pfr, bob.three_bet_chances, bob.three_bets) == (1, 0, 1, 0)
+    assert (carol.pfr, carol.three_bet_chances, carol.three_bets) == (1, 1, 1)
+    # Alice opened, so she is the one facing the 3-bet (and calls it)
+    assert (alice.open_chances, alice.limps) == (1, 0)
+    assert (alice.fold_to_three_bet_chances, alice.folds_to_three_bet) == (1, 0)
+    assert bob.open_chances == bob.fold_to_three_bet_chances == 0
+    # The all-in 3-bettor never gets to c-bet; both remaining players show down
+    assert carol.cbet_chances == 0
+    assert alice.showdowns == carol.showdowns == 1
+    assert alice.showdowns_won == 1 and carol.showdowns_won == 0
+    assert round(alice.net_bb, 2) == 29.37
+
+
+def test_postflop_counters():
+    stats = hand_stats(parse_hand(GGPOKER_HAND))
+    hero, caller = stats["Hero"], stats["a1b2c3"]
+    assert (hero.cbet_chances, hero.cbets) == (1, 1)
+    assert hero.saw_flop == caller.saw_flop == 1
+    # Nobody called the c-bet, so nobody reached showdown
+    assert hero.showdowns == caller.showdowns == 0
+    assert stats["4f5e6d7c"].three_bet_chances == 1
+    assert stats["4f5e6d7c"].vpip == 0
+
+    winamax = hand_stats(parse_hand(WINAMAX_HAND))
+    assert (winamax["Player Two"].cbet_chances, winamax["Player Two"].cbets) == (1, 1)
+    assert winamax["Hero"].vpip and not winamax["Hero"].pfr
+
+
+def test_aggregates_and_breakdowns():
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND]))
+    table = compute_stats(hands, players=["Hero"], by="position")
+    assert list(table) == ["Hero"]
+    hero = table["Hero"]
+    assert hero["all"].hands == 2 and hero["all"].vpip_pct == 100.0
+    assert hero["all"].pfr_pct == 50.0 and hero["all"].cbet_pct == 100.0
+    assert hero["BTN"].hands == hero["SB"].hands == 1
+    assert hero["all"].wtsd_pct == 0.0 and hero["all"].wsd_pct is None
+    # 3.2bb won at 0.05 plus 17.5bb at 0.02 over two hands
+    assert hero["all"].bb_per_100 == 1035.0
+
+    by_stakes = compute_stats(hands, by="stakes")
+    assert set(by_stakes["Alice"]) == {"all", "T15/30"}
+
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        stored = load_stats(db, ["Hero"], "stakes")
+        assert set(stored["Hero"]) == {"all", "0.02/0.05", "0.01/0.02"}
+        assert stored["Hero"]["all"].to_dict() == hero["all"].to_dict()
+
+
+def test_currencies():
+    hands = parse_hands("\n\n".join([POKERSTARS_HAND, GGPOKER_HAND, WINAMAX_HAND]))
+    with tempfile.TemporaryDirectory() as tmp, HandDB(Path(tmp) / "h.sqlite") as db:
+        db.insert_hands(hands)
+        raw = load_stats(db, by="currency")
+        assert set(raw["Hero"]) == {"all", "USD", "EUR"}
+        assert set(raw["Alice"]) == {"all", "chips"}
+        ExchangeRates(db).set_rate("EUR", "USD", 1.2, "2024-01-01")
+        converted = load_stats(db, by="currency", currency="usd")
+    hero = converted["Hero"]
+    # $0.16 plus 0.35 EUR at 1.2
+    assert round(hero["all"].net, 2) == 0.58
+    assert hero["all"].bb_per_100 == raw["Hero"]["all"].bb_per_100
+    # Tournament chips are not dollars
+    assert converted["Alice"]["all"].net == 0
+    assert converted["Alice"]["all"].net_bb == raw["Alice"]["all"].net_bb
+
+
+def main():
+    print("Player Stats - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_preflop_counters,
+        test_postflop_counters,
+        test_aggregates_and_breakdowns,
+        test_currencies,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll player stats checks passed.")
+
+
+if __name__ == "__main__":
+    main()
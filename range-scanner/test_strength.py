@@ -0,0 +1,65 @@
+#!/usr/bin/env python3
+"""
+Hand strength percentile checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from strength import hand_strength, rank_combos
+
+
+def test_against_all_hands():
+    # Beaten by AK (8), AA (1), A7 and A2 (6 each), 77 and 22 (3 each), 72 (9)
+    top_pair = hand_strength("AsQd", "Ah7c2d")
+    assert (top_pair.better, top_pair.ties, top_pair.total) == (36, 6, 1081)
+    assert top_pair.hand_class == "Pair"
+    assert round(top_pair.top_percent, 2) == round((36 + 3) / 1081 * 100, 2)
+    aces = hand_strength("AsAh")
+    assert (aces.better, aces.ties, aces.worse) == (0, 1, 1224)
+    assert aces.hand_class == "AA" and aces.percentile > 99.9
+    royal = hand_strength("2c3d", "AhKhQhJhTh")
+    assert royal.percentile == 50 and royal.better == royal.worse == 0
+
+
+def test_against_a_range():
+    result = hand_strength("AsQd", "Ah7c2d", "AK, 77, KK:0.5")
+    assert (result.better, result.worse) == (8 + 3, 3)
+    assert result.against == "AK, 77, KK:0.5"
+    for bad in (("AsQd", "AsKd7c"), ("As", ""), ("AsQd", "Kd7c")):
+        try:
+            hand_strength(*bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_rank_combos():
+    rows = rank_combos("QQ+, AK", "Kd7c2h")
+    # AA, KK (one king on board), QQ, then AK
+    assert len(rows) == 6 + 3 + 6 + 12
+    assert rows[0]["combo"] in ("KsKh", "KsKc", "KhKc")
+    assert [row["percentile"] for row in rows] == sorted(
+        (row["percentile"] for row in rows), reverse=True
+    )
+    order = [row["combo"][0] + row["combo"][2] for row in rows]
+    assert order == ["KK"] * 3 + ["AA"] * 6 + ["AK"] * 12 + ["QQ"] * 6
+
+
+def main():
+    print("Hand Strength - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_against_all_hands,
+        test_against_a_range,
+        test_rank_combos,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll hand strength checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,68 @@
+#!/usr/bin/env python3
+"""
+Board texture checks
+"""
+
+import math
+import sys
+
+sys.path.insert(0, ".")
+
+from texture import canonical, classify, distinct_flops, flop_buckets
+
+
+def test_classify():
+    dry = classify("Ks7d2c")
+    assert (dry.street, dry.suits, dry.pairing, dry.height) == (
+        "flop",
+        "rainbow",
+        "unpaired",
+        "high",
+    )
+    assert dry.connectedness == "disconnected" and not dry.flush_possible
+    wet = classify("JhTh9c")
+    assert wet.bucket == "high two-tone unpaired connected" and wet.straight_possible
+    assert wet.dynamic > dry.dynamic
+    assert classify("8c8d3s").dynamic == 0
+    assert classify("5c4d2h").connectedness == "connected"  # wheel window
+    assert classify("9h8h7h").suits == "monotone"
+    assert classify("9h8h7h2c").suits == "three-flush"
+    assert classify("KcKdKh7c7d").pairing == "full-house"
+    assert classify("AsKs7d4c2h").dynamic == 0
+    for bad in ("AsKs", "AsKsAs", "AsKsQsJsTs9s"):
+        try:
+            classify(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_distinct_flops():
+    assert canonical("AsKs7d") == canonical("AhKh7c") != canonical("AsKd7c")
+    flops = distinct_flops()
+    assert len(flops) == 1755 and sum(flops.values()) == 22100
+    buckets = flop_buckets()
+    assert sum(len(entry["flops"]) for entry in buckets.values()) == 1755
+    assert abs(sum(entry["frequency"] for entry in buckets.values()) - 100) < 0.01
+    # Three cards of one suit: four suits, C(13, 3) rank sets each
+    monotone = sum(
+        entry["combos"] for name, entry in buckets.items() if " monotone " in name
+    )
+    assert monotone == 4 * math.comb(13, 3)
+
+
+def main():
+    print("Board Texture - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_classify,
+        test_distinct_flops,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll board texture checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,130 @@
+#!/usr/bin/env python3
+"""
+Blind structure and tournament clock checks
+"""
+
+import sys
+
+sys.path.insert(0, ".")
+
+from tourney import BlindStructure, TournamentClock, nice_blind, standard_structure
+
+
+class FakeTime:
+    def __init__(self):
+        self.now = 0.0
+
+    def __call__(self) -> float:
+        return self.now
+
+
+STRUCTURE = BlindStructure.from_dict(
+    {
+        "name": "Test",
+        "levels": [
+            {"small_blind": 25, "big_blind": 50, "minutes": 10},
+            {"small_blind": 50, "big_blind": 100, "ante": 100, "minutes": 10},
+            {"break": True, "minutes": 5},
+            {"small_blind": 100, "big_blind": 200, "ante": 200, "minutes": 10},
+        ],
+    }
+)
+
+
+def test_structures():
+    assert [nice_blind(value) for value in (33, 53, 80, 106, 1234)] == [
+        40,
+        60,
+        80,
+        150,
+        1500,
+    ]
+    structure = standard_structure(levels=9, break_every=4)
+    playing = [level for level in structure.levels if not level.is_break]
+    assert len(playing) == 9 and len(structure.levels) == 11
+    assert all(
+        later.small_blind > earlier.small_blind
+        for earlier, later in zip(playing, playing[1:])
+    )
+    assert playing[0].ante == 0 and playing[3].ante == playing[3].big_blind
+    assert BlindStructure.from_dict(structure.to_dict()) == structure
+    assert STRUCTURE.level_number(2) == 2 and STRUCTURE.level_number(3) == 3
+
+    for bad in (
+        {"levels": [{"break": True, "minutes": 5}]},
+        {"levels": [{"small_blind": 50, "big_blind": 25, "minutes": 10}]},
+        {"levels": [{"small_blind": 25, "big_blind": 50}]},
+    ):
+        try:
+            BlindStructure.from_dict(bad)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def test_clock_runs_and_rolls_over():
+    now = FakeTime()
+    clock = TournamentClock(STRUCTURE, now)
+    state = clock.snapshot()
+    assert not state["running"] and state["remaining"] == 600
+    assert state["next_break_in"] == 1200
+
+    # Paused time doesn't count
+    now.now = 100
+    assert clock.snapshot()["remaining"] == 600
+    clock.start()
+    now.now = 800
+    state = clock.snapshot()
+    assert state["level"] == 2 and state["remaining"] == 500
+    assert state["ante"] == 100 and state["next"]["big_blind"] == 200
+
+    now.now = 1450
+    state = clock.snapshot()
+    assert state["is_break"] and state["remaining"] == 150
+    assert state["next_break_in"] is None
+
+    # The last level stops at zero instead of running off the end
+    now.now = 99_999
+    state = clock.snapshot()
+    assert state["final_level"] and state["remaining"] == 0
+
+
+def test_controls():
+    now = FakeTime()
+    clock = TournamentClock(STRUCTURE, now)
+    version = clock.snapshot()["version"]
+    clock.start()
+    now.now = 60
+    assert clock.pause()["remaining"] == 540
+    now.now = 600
+    assert clock.adjust(-40)["remaining"] == 500
+    assert clock.adjust(-9999)["remaining"] == 0
+    assert clock.next_level()["index"] == 1
+    assert clock.previous_level()["index"] == 0
+    state = clock.set_level(3)
+    assert state["level"] == 3 and state["remaining"] == 600
+    assert state["version"] > version
+    try:
+        clock.set_level(4)
+    except ValueError:
+        pass
+    else:
+        raise AssertionError("accepted a level past the end")
+
+
+def main():
+    print("Tournament Clock - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_structures,
+        test_clock_runs_and_rolls_over,
+        test_controls,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll tournament clock checks passed.")
+
+
+if __name__ == "__main__":
+    main()
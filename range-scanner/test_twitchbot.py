@@ -0,0 +1,87 @@
+#!/usr/bin/env python3
+"""
+Twitch chat bot checks (no network: a fake socket records what is sent)
+"""
+
+import sys
+import tempfile
+from pathlib import Path
+
+sys.path.insert(0, ".")
+
+from handdb import HandDB
+from handhistory import parse_hand
+from test_handhistory import POKERSTARS_HAND
+from twitchbot import Commands, RateLimiter, TwitchBot, parse_privmsg
+
+
+class FakeSocket:
+    def __init__(self):
+        self.sent = []
+
+    def sendall(self, data):
+        self.sent.append(data.decode("utf-8").rstrip("\r\n"))
+
+
+def _line(user, text, tags="badges=;mod=0"):
+    return f"@{tags} :{user}!{user}@{user}.tmi.twitch.tv PRIVMSG #stream :{text}"
+
+
+def test_commands():
+    commands = Commands(iterations=2_000)
+    reply = commands.reply("!equity AsAd vs 7c2d")
+    first, second = reply.split(" vs ")
+    assert first.startswith("AsAd 8") and second.startswith("7c2d 1")
+    assert commands.reply("!equity AhKh vs QQ+ on Qh7h2c").endswith(" on Qh7h2c")
+    assert commands.reply("!equity AsKs").startswith("Sorry: try !equity")
+    assert commands.reply("!stats Alice") == "Sorry: no hand database is loaded"
+    assert commands.reply("hello chat") is None
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "hands.sqlite"
+        with HandDB(path) as db:
+            db.insert_hand(parse_hand(POKERSTARS_HAND))
+        commands = Commands(path)
+        assert commands.reply("!stats Alice").startswith("Alice: 1 hand, VPIP 100")
+        assert commands.reply("!stats Nobody") == "Sorry: no hands for Nobody"
+
+
+def test_bot_rate_limit():
+    now = [100.0]
+    bot = TwitchBot(
+        "#Stream", "Bot", "secret", Commands(), RateLimiter(30, lambda: now[0])
+    )
+    bot.sock = FakeSocket()
+    assert bot.token == "oauth:secret"
+    bot.handle("PING :tmi.twitch.tv")
+    assert bot.sock.sent == ["PONG :tmi.twitch.tv"]
+    assert bot.handle(_line("viewer", "!pokertools")).startswith("@viewer Commands")
+    assert bot.sock.sent[-1].startswith("PRIVMSG #stream :@viewer Commands")
+    # Within the cooldown the viewer is ignored; moderators never are
+    assert bot.handle(_line("viewer", "!pokertools")) is None
+    assert bot.handle(_line("mod", "!pokertools", "badges=;mod=1")) is not None
+    host = "badges=broadcaster/1;mod=0"
+    assert bot.handle(_line("stream", "!pokertools", host)) is not None
+    # Chatter and unknown commands do not use up the cooldown
+    assert bot.handle(_line("other", "!uptime")) is None
+    assert bot.handle(_line("other", "!pokertools")) is not None
+    now[0] += 31
+    assert bot.handle(_line("viewer", "!pokertools")) is not None
+    assert bot.handle(_line("bot", "!pokertools")) is None
+    assert parse_privmsg(":tmi.twitch.tv 001 bot :Welcome, GLHF!") is None
+
+
+def main():
+    print("Twitch Bot - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_commands,
+        test_bot_rate_limit,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll Twitch bot checks passed.")
+
+
+if __name__ == "__main__":
+    main()
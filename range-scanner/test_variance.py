@@ -0,0 +1,72 @@
+#!/usr/bin/env python3
+"""
+Variance and risk-of-ruin simulator checks
+"""
+
+import math
+import sys
+
+sys.path.insert(0, ".")
+
+from variance import (
+    BAND_PERCENTILES,
+    analytic_required_bankroll,
+    analytic_risk_of_ruin,
+    simulate,
+)
+
+
+def test_closed_form():
+    risk = analytic_risk_of_ruin(5, 90, 3000)
+    assert math.isclose(risk, math.exp(-2 * 5 * 3000 / 90**2))
+    assert math.isclose(analytic_required_bankroll(5, 90, risk), 3000)
+    assert analytic_risk_of_ruin(-1, 90, 3000) == 1.0
+    assert analytic_required_bankroll(0, 90, 0.05) is None
+
+
+def test_simulation_tracks_closed_form():
+    result = simulate(5, 90, 2000, hands=100_000, trials=600, seed=7)
+    # A finite horizon can only be safer than forever, and 100k hands is close
+    assert result.risk_of_ruin <= result.risk_of_ruin_analytic + 0.02
+    assert abs(result.risk_of_ruin - result.risk_of_ruin_analytic) < 0.03
+    assert result.required_bankroll < result.required_bankroll_analytic * 1.1
+    assert result.expected_result == 5_000
+    chances = list(result.downswings.values())
+    assert chances == sorted(chances, reverse=True)
+    assert simulate(5, 90, 2000, hands=100_000, trials=600, seed=7) == result
+
+
+def test_bands():
+    result = simulate(3, 80, 1000, hands=10_000, trials=200, points=10, seed=1)
+    bands = result.bands
+    assert bands["hands"] == list(range(1_000, 10_001, 1_000))
+    for idx in range(len(bands["hands"])):
+        column = [bands[f"p{pct}"][idx] for pct in BAND_PERCENTILES]
+        assert column == sorted(column)
+    data = result.to_dict()
+    assert set(data["bands"]) == {"hands"} | {f"p{pct}" for pct in BAND_PERCENTILES}
+    for bad in ({"std_dev": 0}, {"hands": 50}, {"target_risk": 1}):
+        args = {"std_dev": 80, "hands": 10_000, "target_risk": 0.05, **bad}
+        try:
+            simulate(3, bankroll=1000, trials=10, **args)
+        except ValueError:
+            continue
+        raise AssertionError(f"accepted {bad}")
+
+
+def main():
+    print("Variance - TEST MODE")
+    print("=" * 80)
+    tests = [
+        test_closed_form,
+        test_simulation_tracks_closed_form,
+        test_bands,
+    ]
+    for test in tests:
+        print(f"Running {test.__name__}...")
+        test()
+    print("\nAll variance checks passed.")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,257 @@
+#!/usr/bin/env python3
+"""
+Board texture: classify a flop, turn, or river and bucket every flop.
+
+`classify` describes a board along the axes players talk about:
+
+- suits: rainbow, two-tone, monotone (every card one suit), or on later streets
+  three-flush / four-flush by the most cards of one suit
+- pairing: unpaired, paired, double-paired, trips, full-house, quads
+- connectedness: connected when a straight is already possible (three ranks in
+  some five-rank window, the wheel included), semi-connected when two ranks
+  are close enough for a straight draw, otherwise disconnected
+- height: high (top card T or better), middle (9), or low (8 or lower)
+- dynamic: the share of unseen next cards, 0-100, that bring a flush or
+  straight closer: the third or later card of a suit, or a card that puts more
+  ranks in a straight window once three fit. Pairing cards do not count, so
+  a dry paired board scores 0; a river has no next card and scores 0 too.
+
+`bucket` joins height, suits, pairing, and connectedness into one label
+("high two-tone unpaired connected"), the key the solver abstraction and the
+leak reports group by. There are 22,100 flops but only 1,755 once suits are
+interchangeable (`canonical`); `flop_buckets` walks all of them, each weighted
+by how many real flops it stands for.
+
+Example:
+    python3 pokertools.py texture AsKd7c JhTh9c2h
+    python3 pokertools.py texture --all
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+from collections import Counter
+from dataclasses import asdict, dataclass
+from itertools import combinations, permutations
+from typing import Dict, List, Sequence, Tuple, Union
+
+from cards import Card, CardLike, Rank, format_cards, parse_cards, to_card
+
+
+# Lowest top card that makes a board "high" / highest that makes it "low"
+HIGH_RANK = Rank.TEN
+LOW_RANK = Rank.EIGHT
+PAIRING = {
+    (1,): "unpaired",
+    (2,): "paired",
+    (2, 2): "double-paired",
+    (3,): "trips",
+    (3, 2): "full-house",
+    (4,): "quads",
+}
+# Five-rank straight windows as rank bitmasks, the wheel (A2345) first
+STRAIGHT_WINDOWS = [(1 << Rank.ACE) | 0b1111] + [
+    0b11111 << low for low in range(Rank.JACK)
+]
+
+
+@dataclass
+class Texture:
+    board: str
+    street: str
+    suits: str
+    pairing: str
+    connectedness: str
+    height: str
+    flush_possible: bool
+    straight_possible: bool
+    dynamic: int
+
+    @property
+    def bucket(self) -> str:
+        return f"{self.height} {self.suits} {self.pairing} {self.connectedness}"
+
+    def to_dict(self) -> dict:
+        return {**asdict(self), "bucket": self.bucket}
+
+
+def _rank_mask(cards: Sequence[Card]) -> int:
+    mask = 0
+    for card in cards:
+        mask |= 1 << card.rank
+    return mask
+
+
+def _window_count(cards: Sequence[Card]) -> int:
+    """Most distinct board ranks inside one straight window"""
+    mask = _rank_mask(cards)
+    return max(bin(mask & window).count("1") for window in STRAIGHT_WINDOWS)
+
+
+def _flush_possible(cards: Sequence[Card]) -> bool:
+    return max(Counter(card.suit for card in cards).values()) >= 3
+
+
+def _suits_label(cards: Sequence[Card]) -> str:
+    most = max(Counter(card.suit for card in cards).values())
+    if most == len(cards):
+        return "monotone"
+    return {1: "rainbow", 2: "two-tone", 3: "three-flush"}.get(most, "four-flush")
+
+
+def _dynamic(cards: List[Card]) -> int:
+    if len(cards) >= 5:
+        return 0
+    window = _window_count(cards)
+    suits = Counter(card.suit for card in cards)
+    seen = {card.index for card in cards}
+    unseen = [Card(index) for index in range(52) if index not in seen]
+    changes = 0
+    for card in unseen:
+        after = _window_count(cards + [card])
+        if suits[card.suit] >= 2 or (after >= 3 and after > window):
+            changes += 1
+    return round(changes / len(unseen) * 100)
+
+
+def _parse_board(board: Union[str, Sequence[CardLike]]) -> List[Card]:
+    cards = parse_cards(board) if isinstance(board, str) else list(map(to_card, board))
+    if not 3 <= len(cards) <= 5:
+        raise ValueError("A board is 3 to 5 cards")
+    if len(set(cards)) != len(cards):
+        raise ValueError(f"Duplicate card in board {format_cards(cards)}")
+    return cards
+
+
+def classify(board: Union[str, Sequence[CardLike]]) -> Texture:
+    cards = _parse_board(board)
+    counts = sorted(Counter(card.rank for card in cards).values(), reverse=True)
+    pairing = PAIRING[tuple(count for count in counts if count > 1) or (1,)]
+    window = _window_count(cards)
+    if window >= 3:
+        connectedness = "connected"
+    elif window == 2:
+        connectedness = "semi-connected"
+    else:
+        connectedness = "disconnected"
+    top = max(card.rank for card in cards)
+    if top >= HIGH_RANK:
+        height = "high"
+    elif top <= LOW_RANK:
+        height = "low"
+    else:
+        height = "middle"
+    return Texture(
+        board=format_cards(sorted(cards, reverse=True)),
+        street={3: "flop", 4: "turn", 5: "river"}[len(cards)],
+        suits=_suits_label(cards),
+        pairing=pairing,
+        connectedness=connectedness,
+        height=height,
+        flush_possible=_flush_possible(cards),
+        straight_possible=window >= 3,
+        dynamic=_dynamic(cards),
+    )
+
+
+def canonical(board: Union[str, Sequence[CardLike]]) -> str:
+    """The suit-isomorphic representative: the same string for AsKs7d and AhKh7c"""
+    cards = _parse_board(board) if isinstance(board, str) else list(map(to_card, board))
+    best: Tuple[int, ...] = ()
+    for order in permutations(range(4)):
+        mapped = tuple(
+            sorted(((card.index & ~3) | order[card.index & 3] for card in cards))[::-1]
+        )
+        if not best or mapped > best:
+            best = mapped
+    return format_cards(best)
+
+
+def distinct_flops() -> Dict[str, int]:
+    """{canonical flop: number of real flops it stands for}; 1,755 entries"""
+    weights: Counter = Counter()
+    for flop in combinations(range(52), 3):
+        weights[canonical(flop)] += 1
+    return dict(weights)
+
+
+def flop_buckets() -> Dict[str, dict]:
+    """Every distinct flop grouped by texture bucket"""
+    buckets: Dict[str, dict] = {}
+    for flop, weight in distinct_flops().items():
+        texture = classify(flop)
+        entry = buckets.setdefault(
+            texture.bucket, {"flops": [], "combos": 0, "frequency": 0.0}
+        )
+        entry["flops"].append(flop)
+        entry["combos"] += weight
+    total = sum(entry["combos"] for entry in buckets.values())
+    for entry in buckets.values():
+        entry["frequency"] = round(entry["combos"] / total * 100, 3)
+    return dict(sorted(buckets.items(), key=lambda item: -item[1]["combos"]))
+
+
+def format_texture(texture: Texture) -> str:
+    flags = []
+    if texture.flush_possible:
+        flags.append("flush possible")
+    if texture.straight_possible:
+        flags.append("straight possible")
+    extra = f" ({', '.join(flags)})" if flags else ""
+    return (
+        f"{texture.board:<11} {texture.street:<5} {texture.bucket}, "
+        f"dynamic {texture.dynamic}{extra}"
+    )
+
+
+def format_buckets(buckets: Dict[str, dict]) -> str:
+    distinct = sum(len(entry["flops"]) for entry in buckets.values())
+    lines = [
+        f"{distinct} distinct flops in {len(buckets)} buckets",
+        f"{'Bucket':<44} {'Flops':>6} {'Freq %':>7}  Example",
+    ]
+    for name, entry in buckets.items():
+        lines.append(
+            f"{name:<44} {len(entry['flops']):>6} {entry['frequency']:>7.2f}  "
+            f"{entry['flops'][0]}"
+        )
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("boards", nargs="*", help="Boards to classify, e.g. AsKd7c")
+    parser.add_argument(
+        "--all", action="store_true", help="Bucket all 1,755 distinct flops"
+    )
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    if not args.all and not args.boards:
+        raise SystemExit("error: give one or more boards, or --all")
+    if args.all:
+        buckets = flop_buckets()
+        if args.json:
+            print(json.dumps(buckets, indent=2))
+        else:
+            print(format_buckets(buckets))
+        return
+    try:
+        textures = [classify(board) for board in args.boards]
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps([texture.to_dict() for texture in textures], indent=2))
+    else:
+        print("\n".join(format_texture(texture) for texture in textures))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Board texture classifier")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
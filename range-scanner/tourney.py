@@ -0,0 +1,361 @@
+#!/usr/bin/env python3
+"""
+Tournament blind structures and a running tournament clock.
+
+A `BlindStructure` is an ordered list of `BlindLevel`s, each either a playing
+level (blinds, ante, minutes) or a break. Structures load from JSON:
+
+    {"name": "Nightly", "levels": [
+        {"small_blind": 25, "big_blind": 50, "ante": 0, "minutes": 20},
+        {"break": true, "minutes": 10}, ...]}
+
+or come from `standard_structure`, which grows the blinds about a third a
+level, rounded to chip-friendly amounts, with a break every few levels.
+
+`TournamentClock` runs a structure: start, pause/resume, add or remove time,
+jump to a level. Time is read from an injectable monotonic clock and levels
+roll over lazily whenever the state is read, so nothing ticks in the
+background. `snapshot()` is what displays show: current level and blinds,
+time left, what comes next, and time until the next break. `version` goes up
+on every change so subscribers (`GET /api/tourney/clock/stream` on the HTTP
+service) can tell when to redraw.
+
+Example:
+    python3 pokertools.py clock structure --levels 12 --minutes 15
+    python3 pokertools.py clock run --structure nightly.json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import threading
+import time
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Callable, List
+
+
+DEFAULT_LEVEL_MINUTES = 20.0
+DEFAULT_BREAK_MINUTES = 10.0
+DEFAULT_LEVEL_COUNT = 20
+DEFAULT_BREAK_EVERY = 4
+# Chip-friendly blind steps; bigger blinds repeat them in powers of ten
+NICE_BLINDS = (10, 15, 20, 25, 30, 40, 50, 60, 75, 80)
+
+
+@dataclass
+class BlindLevel:
+    small_blind: float = 0.0
+    big_blind: float = 0.0
+    ante: float = 0.0
+    minutes: float = DEFAULT_LEVEL_MINUTES
+    is_break: bool = False
+
+    @property
+    def seconds(self) -> float:
+        return self.minutes * 60
+
+    def label(self) -> str:
+        if self.is_break:
+            return "Break"
+        ante = f" ante {self.ante:g}" if self.ante else ""
+        return f"{self.small_blind:g}/{self.big_blind:g}{ante}"
+
+    def to_dict(self) -> dict:
+        if self.is_break:
+            return {"break": True, "minutes": self.minutes}
+        data = asdict(self)
+        del data["is_break"]
+        return data
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "BlindLevel":
+        if not isinstance(data, dict):
+            raise ValueError("Each level must be an object")
+        minutes = float(data.get("minutes") or 0)
+        if minutes <= 0:
+            raise ValueError("Every level needs positive minutes")
+        if data.get("break"):
+            return cls(minutes=minutes, is_break=True)
+        try:
+            level = cls(
+                float(data["small_blind"]),
+                float(data["big_blind"]),
+                float(data.get("ante") or 0),
+                minutes,
+            )
+        except KeyError as exc:
+            raise ValueError(f"Level is missing {exc.args[0]}") from None
+        if not 0 < level.small_blind <= level.big_blind or level.ante < 0:
+            raise ValueError(f"Invalid blinds: {level.label()}")
+        return level
+
+
+@dataclass
+class BlindStructure:
+    levels: List[BlindLevel]
+    name: str = ""
+
+    def __post_init__(self):
+        if not any(not level.is_break for level in self.levels):
+            raise ValueError("A structure needs at least one playing level")
+
+    def to_dict(self) -> dict:
+        return {
+            "name": self.name,
+            "levels": [level.to_dict() for level in self.levels],
+        }
+
+    @classmethod
+    def from_dict(cls, data: dict) -> "BlindStructure":
+        if not isinstance(data, dict) or not isinstance(data.get("levels"), list):
+            raise ValueError("A structure needs a list of levels")
+        levels = [BlindLevel.from_dict(level) for level in data["levels"]]
+        return cls(levels, str(data.get("name") or ""))
+
+    @classmethod
+    def load(cls, path: Path) -> "BlindStructure":
+        return cls.from_dict(json.loads(Path(path).read_text()))
+
+    def level_number(self, index: int) -> int:
+        """1-based playing level at `index`; a break shares the previous one"""
+        return max(
+            1, sum(1 for level in self.levels[: index + 1] if not level.is_break)
+        )
+
+
+def nice_blind(amount: float) -> float:
+    """Round up to the next chip-friendly blind (10, 15, 20, 25, 30, 40, ...)"""
+    scale = 1
+    while amount > NICE_BLINDS[-1] * scale:
+        scale *= 10
+    while scale > 1 and amount <= NICE_BLINDS[0] * scale // 10:
+        scale //= 10
+    for step in NICE_BLINDS:
+        if step * scale >= amount:
+            return float(step * scale)
+    return float(NICE_BLINDS[-1] * scale)
+
+
+def standard_structure(
+    levels: int = DEFAULT_LEVEL_COUNT,
+    minutes: float = DEFAULT_LEVEL_MINUTES,
+    starting_big_blind: float = 50,
+    growth: float = 1.33,
+    ante_from: int = 4,
+    break_every: int = DEFAULT_BREAK_EVERY,
+    break_minutes: float = DEFAULT_BREAK_MINUTES,
+) -> BlindStructure:
+    """Blinds grow by `growth` a level; a big-blind ante from `ante_from`"""
+    if levels <= 0 or minutes <= 0 or starting_big_blind <= 0 or growth <= 1:
+        raise ValueError("levels, minutes, and blinds must be positive; growth > 1")
+    result = []
+    small_blind = starting_big_blind / 2
+    for number in range(1, levels + 1):
+        big_blind = small_blind * 2
+        ante = big_blind if ante_from and number >= ante_from else 0.0
+        result.append(BlindLevel(small_blind, big_blind, ante, minutes))
+        if break_every and number % break_every == 0 and number < levels:
+            result.append(BlindLevel(minutes=break_minutes, is_break=True))
+        small_blind = nice_blind(small_blind * growth)
+    return BlindStructure(result, f"Standard {levels} x {minutes:g} min")
+
+
+class TournamentClock:
+    """Thread-safe clock over a blind structure"""
+
+    def __init__(
+        self,
+        structure: BlindStructure,
+        clock: Callable[[], float] = time.monotonic,
+    ):
+        self.lock = threading.Lock()
+        self.clock = clock
+        self.version = 0
+        self.load(structure)
+
+    def load(self, structure: BlindStructure):
+        """Swap the structure and reset to the first level, paused"""
+        with self.lock:
+            self.structure = structure
+            self.index = 0
+            self.remaining = structure.levels[0].seconds
+            self.running = False
+            self.anchor = self.clock()
+            self.version += 1
+
+    def _advance(self):
+        """Bring index/remaining up to now (lock held)"""
+        now = self.clock()
+        if self.running:
+            elapsed = now - self.anchor
+            last = len(self.structure.levels) - 1
+            while elapsed >= self.remaining and self.index < last:
+                elapsed -= self.remaining
+                self.index += 1
+                self.remaining = self.structure.levels[self.index].seconds
+                self.version += 1
+            self.remaining = max(0.0, self.remaining - elapsed)
+        self.anchor = now
+
+    def _change(self, action: Callable[[], None]) -> dict:
+        with self.lock:
+            self._advance()
+            action()
+            self.version += 1
+            return self._snapshot()
+
+    def start(self) -> dict:
+        return self._change(lambda: setattr(self, "running", True))
+
+    def pause(self) -> dict:
+        return self._change(lambda: setattr(self, "running", False))
+
+    def adjust(self, seconds: float) -> dict:
+        """Add (or with a negative value, take off) time on the current level"""
+
+        def apply():
+            self.remaining = max(0.0, self.remaining + seconds)
+
+        return self._change(apply)
+
+    def set_level(self, index: int) -> dict:
+        """Jump to a level (0-based index into the structure, breaks included)"""
+        if not 0 <= index < len(self.structure.levels):
+            last = len(self.structure.levels) - 1
+            raise ValueError(f"Level index must be 0-{last}")
+
+        def apply():
+            self.index = index
+            self.remaining = self.structure.levels[index].seconds
+
+        return self._change(apply)
+
+    def next_level(self) -> dict:
+        return self.set_level(min(self.index + 1, len(self.structure.levels) - 1))
+
+    def previous_level(self) -> dict:
+        return self.set_level(max(self.index - 1, 0))
+
+    def snapshot(self) -> dict:
+        with self.lock:
+            self._advance()
+            return self._snapshot()
+
+    def _snapshot(self) -> dict:
+        levels = self.structure.levels
+        level = levels[self.index]
+        upcoming = next(
+            (later for later in levels[self.index + 1 :] if not later.is_break), None
+        )
+        until_break = None
+        if not level.is_break:
+            until_break = self.remaining
+            for later in levels[self.index + 1 :]:
+                if later.is_break:
+                    break
+                until_break += later.seconds
+            else:
+                until_break = None
+        return {
+            "name": self.structure.name,
+            "version": self.version,
+            "running": self.running,
+            "index": self.index,
+            "level": self.structure.level_number(self.index),
+            "is_break": level.is_break,
+            "small_blind": level.small_blind,
+            "big_blind": level.big_blind,
+            "ante": level.ante,
+            "remaining": round(self.remaining, 1),
+            "next": upcoming.to_dict() if upcoming else None,
+            "next_break_in": None if until_break is None else round(until_break, 1),
+            "final_level": self.index == len(levels) - 1,
+        }
+
+
+def format_clock(state: dict) -> str:
+    minutes, seconds = divmod(int(state["remaining"]), 60)
+    if state["is_break"]:
+        current = "BREAK"
+    else:
+        ante = f" ante {state['ante']:g}" if state["ante"] else ""
+        blinds = f"{state['small_blind']:g}/{state['big_blind']:g}"
+        current = f"Level {state['level']}: {blinds}{ante}"
+    upcoming = state["next"]
+    following = ""
+    if upcoming:
+        following = f"  next {upcoming['small_blind']:g}/{upcoming['big_blind']:g}"
+    paused = "" if state["running"] else "  [paused]"
+    return f"{current}  {minutes:02d}:{seconds:02d}{following}{paused}"
+
+
+def format_structure(structure: BlindStructure) -> str:
+    lines = [structure.name or "Blind structure"]
+    elapsed = 0.0
+    for index, level in enumerate(structure.levels):
+        start = f"{int(elapsed // 3600)}:{int(elapsed % 3600 // 60):02d}"
+        number = "" if level.is_break else structure.level_number(index)
+        lines.append(
+            f"{number:>4}  {start:>6}  {level.minutes:>4g} min  {level.label()}"
+        )
+        elapsed += level.seconds
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--structure", type=Path, help="Structure JSON file")
+    parser.add_argument("--levels", type=int, default=DEFAULT_LEVEL_COUNT)
+    parser.add_argument("--minutes", type=float, default=DEFAULT_LEVEL_MINUTES)
+    parser.add_argument("--starting-bb", type=float, default=50)
+    parser.add_argument("--break-every", type=int, default=DEFAULT_BREAK_EVERY)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+    parser.add_argument(
+        "action",
+        choices=("structure", "run"),
+        help="Print the structure, or run a clock in the terminal",
+    )
+
+
+def run(args: argparse.Namespace):
+    try:
+        if args.structure:
+            structure = BlindStructure.load(args.structure)
+        else:
+            structure = standard_structure(
+                args.levels,
+                args.minutes,
+                args.starting_bb,
+                break_every=args.break_every,
+            )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.action == "structure":
+        if args.json:
+            print(json.dumps(structure.to_dict(), indent=2))
+        else:
+            print(format_structure(structure))
+        return
+    clock = TournamentClock(structure)
+    clock.start()
+    try:
+        while True:
+            state = clock.snapshot()
+            print(f"\r{format_clock(state):<70}", end="", flush=True)
+            if state["final_level"] and not state["remaining"]:
+                break
+            time.sleep(1)
+    except KeyboardInterrupt:
+        pass
+    print()
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Blind structures and clock")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
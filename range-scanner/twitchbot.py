@@ -0,0 +1,263 @@
+#!/usr/bin/env python3
+"""
+Optional Twitch chat bot answering viewer commands from the poker engines.
+
+Commands (anyone in chat):
+
+    !equity AsKs vs QQ              preflop equity, hands or ranges
+    !equity AhKh vs QQ+ vs 77 on Qh7h2c
+    !stats PlayerX                  VPIP / PFR / 3-bet / bb/100 from the hand DB
+    !pokertools                     the command list
+
+Each viewer may run one command per `--cooldown` seconds (moderators and the
+broadcaster are not limited); commands over the limit are ignored silently so
+the bot never floods chat. Equity is Monte Carlo capped at `--iterations`.
+
+The bot speaks plain IRC over TLS to irc.chat.twitch.tv. Its OAuth token is
+read from the TWITCH_OAUTH_TOKEN environment variable, never from the command
+line, so it stays out of shell history and `ps`.
+
+Example:
+    TWITCH_OAUTH_TOKEN=oauth:... python3 pokertools.py twitch \\
+        --channel mystream --nick pokertoolsbot --db hands.sqlite
+"""
+
+from __future__ import annotations
+
+import argparse
+import os
+import re
+import socket
+import ssl
+import time
+from pathlib import Path
+from typing import Callable, Dict, Optional
+
+from equity import calculate_equity, parse_player_arg
+from handdb import HandDB
+from stats import ALL_GROUP, load_stats
+
+
+TWITCH_HOST = "irc.chat.twitch.tv"
+TWITCH_PORT = 6697
+TOKEN_ENV = "TWITCH_OAUTH_TOKEN"
+DEFAULT_COOLDOWN = 20.0
+DEFAULT_ITERATIONS = 20_000
+# Twitch drops chat messages longer than this
+MAX_REPLY = 500
+PRIVMSG = re.compile(
+    r"^(?:@(?P<tags>\S+) )?:(?P<user>[^!\s]+)!\S+ "
+    r"PRIVMSG #(?P<channel>\S+) :(?P<text>.*)$"
+)
+COMMANDS = ("!equity", "!stats", "!pokertools")
+HELP = "Commands: !equity AsKs vs QQ [on Qh7h2c] | !stats <player>"
+
+
+class RateLimiter:
+    """One command per user per `cooldown` seconds"""
+
+    def __init__(self, cooldown: float, clock: Callable[[], float] = time.monotonic):
+        self.cooldown = cooldown
+        self.clock = clock
+        self.last: Dict[str, float] = {}
+
+    def allow(self, user: str) -> bool:
+        now = self.clock()
+        last = self.last.get(user.lower())
+        if last is not None and now - last < self.cooldown:
+            return False
+        self.last[user.lower()] = now
+        # Forget users whose cooldown ran out so the table stays small
+        if len(self.last) > 1000:
+            self.last = {
+                name: seen
+                for name, seen in self.last.items()
+                if now - seen < self.cooldown
+            }
+        return True
+
+
+class Commands:
+    """Turns a chat message into a reply (or None when it is not a command)"""
+
+    def __init__(
+        self, db_path: Optional[Path] = None, iterations: int = DEFAULT_ITERATIONS
+    ):
+        self.db_path = db_path
+        self.iterations = iterations
+
+    def reply(self, text: str) -> Optional[str]:
+        command, _, rest = text.strip().partition(" ")
+        command = command.lower()
+        try:
+            if command == "!equity":
+                return self.equity(rest)
+            if command == "!stats":
+                return self.stats(rest.strip())
+            if command == "!pokertools":
+                return HELP
+        except (KeyError, ValueError) as exc:
+            message = exc.args[0] if isinstance(exc, KeyError) else str(exc)
+            return f"Sorry: {message}"
+        return None
+
+    def equity(self, text: str) -> str:
+        parts = re.split(r"\s+on\s+", text.strip(), maxsplit=1, flags=re.I)
+        board = parts[1].strip() if len(parts) > 1 else ""
+        names = re.split(r"\s+vs\.?\s+", parts[0].strip(), flags=re.I)
+        if len(names) < 2 or not all(names):
+            raise ValueError("try !equity AsKs vs QQ")
+        result = calculate_equity(
+            [parse_player_arg(name) for name in names],
+            board=board.replace(" ", ""),
+            iterations=self.iterations,
+            workers=1,
+        )
+        shares = " vs ".join(
+            f"{name} {share * 100:.1f}%" for name, share in zip(names, result.equities)
+        )
+        return shares + (f" on {board}" if board else "")
+
+    def stats(self, player: str) -> str:
+        if not player:
+            raise ValueError("try !stats <player>")
+        if self.db_path is None:
+            raise ValueError("no hand database is loaded")
+        with HandDB(self.db_path) as db:
+            table = load_stats(db, [player])
+        if player not in table:
+            raise KeyError(f"no hands for {player}")
+        stats = table[player][ALL_GROUP]
+
+        def pct(value: Optional[float]) -> str:
+            return "-" if value is None else f"{value:.0f}"
+
+        noun = "hand" if stats.hands == 1 else "hands"
+        return (
+            f"{player}: {stats.hands:,} {noun}, VPIP {pct(stats.vpip_pct)} / "
+            f"PFR {pct(stats.pfr_pct)} / 3-bet {pct(stats.three_bet_pct)}, "
+            f"{stats.bb_per_100:+.1f} bb/100"
+        )
+
+
+def parse_privmsg(line: str) -> Optional[dict]:
+    """{"user", "channel", "text", "privileged"} for a chat line, else None"""
+    match = PRIVMSG.match(line)
+    if not match:
+        return None
+    tags = dict(
+        tag.split("=", 1)
+        for tag in (match.group("tags") or "").split(";")
+        if "=" in tag
+    )
+    badges = tags.get("badges", "")
+    privileged = tags.get("mod") == "1" or "broadcaster/" in badges
+    return {
+        "user": match.group("user"),
+        "channel": match.group("channel"),
+        "text": match.group("text"),
+        "privileged": privileged,
+    }
+
+
+class TwitchBot:
+    def __init__(
+        self,
+        channel: str,
+        nick: str,
+        token: str,
+        commands: Commands,
+        limiter: RateLimiter,
+    ):
+        self.channel = channel.lstrip("#").lower()
+        self.nick = nick.lower()
+        self.token = token if token.startswith("oauth:") else f"oauth:{token}"
+        self.commands = commands
+        self.limiter = limiter
+        self.sock: Optional[socket.socket] = None
+
+    def send(self, line: str):
+        self.sock.sendall(f"{line}\r\n".encode("utf-8"))
+
+    def handle(self, line: str) -> Optional[str]:
+        """React to one IRC line; returns the reply sent, if any"""
+        if line.startswith("PING"):
+            self.send("PONG" + line[4:])
+            return None
+        message = parse_privmsg(line)
+        if message is None or not message["text"].strip():
+            return None
+        if message["text"].split()[0].lower() not in COMMANDS:
+            return None
+        if message["user"].lower() == self.nick:
+            return None
+        if not message["privileged"] and not self.limiter.allow(message["user"]):
+            return None
+        reply = self.commands.reply(message["text"])
+        if reply is None:
+            return None
+        reply = f"@{message['user']} {reply}"[:MAX_REPLY]
+        self.send(f"PRIVMSG #{self.channel} :{reply}")
+        return reply
+
+    def run(self, host: str = TWITCH_HOST, port: int = TWITCH_PORT):
+        context = ssl.create_default_context()
+        raw = socket.create_connection((host, port))
+        self.sock = context.wrap_socket(raw, server_hostname=host)
+        self.send(f"PASS {self.token}")
+        self.send(f"NICK {self.nick}")
+        # Tags carry the mod/broadcaster badges the rate limit skips
+        self.send("CAP REQ :twitch.tv/tags")
+        self.send(f"JOIN #{self.channel}")
+        buffer = b""
+        try:
+            while True:
+                chunk = self.sock.recv(4096)
+                if not chunk:
+                    raise ConnectionError("Twitch closed the connection")
+                buffer += chunk
+                *lines, buffer = buffer.split(b"\r\n")
+                for line in lines:
+                    self.handle(line.decode("utf-8", "replace"))
+        finally:
+            self.sock.close()
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--channel", required=True, help="Channel to join")
+    parser.add_argument("--nick", required=True, help="The bot's Twitch login")
+    parser.add_argument("--db", type=Path, help="Hand database for !stats")
+    parser.add_argument("--cooldown", type=float, default=DEFAULT_COOLDOWN)
+    parser.add_argument("--iterations", type=int, default=DEFAULT_ITERATIONS)
+
+
+def run(args: argparse.Namespace):
+    token = os.environ.get(TOKEN_ENV)
+    if not token:
+        raise SystemExit(f"error: set {TOKEN_ENV} to the bot's OAuth token")
+    if args.db and not args.db.exists():
+        raise SystemExit(f"error: {args.db} not found")
+    bot = TwitchBot(
+        args.channel,
+        args.nick,
+        token,
+        Commands(args.db, args.iterations),
+        RateLimiter(args.cooldown),
+    )
+    print(f"Joining #{bot.channel} as {bot.nick} (Ctrl+C to stop)")
+    try:
+        bot.run()
+    except KeyboardInterrupt:
+        pass
+    except OSError as exc:
+        raise SystemExit(f"error: {exc}") from None
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Twitch chat bot")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()
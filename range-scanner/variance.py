@@ -0,0 +1,246 @@
+#!/usr/bin/env python3
+"""
+Variance and risk-of-ruin simulator.
+
+Given a win rate and standard deviation (both in bb/100, as tracking software
+reports them) and a bankroll in big blinds, `simulate` runs Monte Carlo
+trajectories in 100-hand steps and reports:
+
+- risk of ruin over the horizon (the bankroll hits zero at any point), next to
+  the closed-form infinite-horizon figure exp(-2 * wr * B / sd^2)
+- the bankroll needed for a target risk, from the same trajectories and from
+  the closed form
+- how often downswings of a given size happen, and the median worst downswing
+- percentile bands (5/25/50/75/95) of the running result as JSON series
+
+Example:
+    python3 variance.py --win-rate 5 --std-dev 90 --bankroll 3000 --hands 100000
+    python3 pokertools.py variance --win-rate 2.5 --std-dev 100 --bankroll 2000 --json
+"""
+
+from __future__ import annotations
+
+import argparse
+import json
+import math
+import random
+from dataclasses import asdict, dataclass, field
+from typing import Dict, List, Optional, Sequence
+
+
+HANDS_PER_STEP = 100
+DEFAULT_HANDS = 100_000
+DEFAULT_TRIALS = 1_000
+DEFAULT_TARGET_RISK = 0.05
+DEFAULT_DOWNSWINGS = (500, 1_000, 2_000, 5_000)
+BAND_PERCENTILES = (5, 25, 50, 75, 95)
+DEFAULT_POINTS = 50
+
+
+def analytic_risk_of_ruin(win_rate: float, std_dev: float, bankroll: float) -> float:
+    """Infinite-horizon risk of ruin for a Brownian bankroll"""
+    if win_rate <= 0:
+        return 1.0
+    return math.exp(-2 * win_rate * bankroll / std_dev**2)
+
+
+def analytic_required_bankroll(
+    win_rate: float, std_dev: float, risk: float
+) -> Optional[float]:
+    """Bankroll (bb) whose infinite-horizon risk of ruin is `risk`"""
+    if win_rate <= 0:
+        return None
+    return -(std_dev**2) * math.log(risk) / (2 * win_rate)
+
+
+def _percentile(ordered: Sequence[float], pct: float) -> float:
+    """Nearest-rank percentile of an already sorted sequence"""
+    index = min(len(ordered) - 1, max(0, math.ceil(pct / 100 * len(ordered)) - 1))
+    return ordered[index]
+
+
+@dataclass
+class VarianceResult:
+    win_rate: float
+    std_dev: float
+    bankroll: float
+    hands: int
+    trials: int
+    target_risk: float
+    expected_result: float
+    risk_of_ruin: float
+    risk_of_ruin_analytic: float
+    required_bankroll: float
+    required_bankroll_analytic: Optional[float]
+    median_max_downswing: float
+    downswings: Dict[int, float] = field(default_factory=dict)
+    bands: Dict[str, List[float]] = field(default_factory=dict)
+
+    def to_dict(self) -> dict:
+        data = asdict(self)
+        for name in (
+            "risk_of_ruin",
+            "risk_of_ruin_analytic",
+            "required_bankroll",
+            "median_max_downswing",
+        ):
+            data[name] = round(data[name], 4)
+        if self.required_bankroll_analytic is not None:
+            data["required_bankroll_analytic"] = round(
+                self.required_bankroll_analytic, 1
+            )
+        return data
+
+
+def simulate(
+    win_rate: float,
+    std_dev: float,
+    bankroll: float,
+    hands: int = DEFAULT_HANDS,
+    trials: int = DEFAULT_TRIALS,
+    target_risk: float = DEFAULT_TARGET_RISK,
+    downswings: Sequence[int] = DEFAULT_DOWNSWINGS,
+    points: int = DEFAULT_POINTS,
+    seed: Optional[int] = None,
+) -> VarianceResult:
+    """Monte Carlo trajectories of `hands` hands, in bb, 100 hands per step"""
+    if std_dev <= 0:
+        raise ValueError("std_dev must be positive")
+    if bankroll <= 0:
+        raise ValueError("bankroll must be positive")
+    if hands < HANDS_PER_STEP:
+        raise ValueError(f"hands must be at least {HANDS_PER_STEP}")
+    if trials <= 0:
+        raise ValueError("trials must be positive")
+    if not 0 < target_risk < 1:
+        raise ValueError("target_risk must be between 0 and 1")
+
+    rng = random.Random(seed)
+    steps = hands // HANDS_PER_STEP
+    points = max(1, min(points, steps))
+    checkpoints = sorted({round(steps * (idx + 1) / points) for idx in range(points)})
+    samples: List[List[float]] = [[] for _ in checkpoints]
+    ruined = 0
+    lowest: List[float] = []
+    drawdowns: List[float] = []
+    for _ in range(trials):
+        total = peak = low = worst = 0.0
+        checkpoint = 0
+        for step in range(1, steps + 1):
+            total += rng.gauss(win_rate, std_dev)
+            peak = max(peak, total)
+            low = min(low, total)
+            worst = max(worst, peak - total)
+            if step == checkpoints[checkpoint]:
+                samples[checkpoint].append(total)
+                checkpoint += 1
+        ruined += low <= -bankroll
+        lowest.append(-low)
+        drawdowns.append(worst)
+
+    lowest.sort()
+    drawdowns.sort()
+    bands: Dict[str, List[float]] = {
+        "hands": [step * HANDS_PER_STEP for step in checkpoints]
+    }
+    for pct in BAND_PERCENTILES:
+        bands[f"p{pct}"] = []
+    for values in samples:
+        values.sort()
+        for pct in BAND_PERCENTILES:
+            bands[f"p{pct}"].append(round(_percentile(values, pct), 1))
+
+    return VarianceResult(
+        win_rate=win_rate,
+        std_dev=std_dev,
+        bankroll=bankroll,
+        hands=steps * HANDS_PER_STEP,
+        trials=trials,
+        target_risk=target_risk,
+        expected_result=round(win_rate * steps, 1),
+        risk_of_ruin=ruined / trials,
+        risk_of_ruin_analytic=analytic_risk_of_ruin(win_rate, std_dev, bankroll),
+        # Ruin with bankroll B <=> the running result ever falls to -B
+        required_bankroll=_percentile(lowest, (1 - target_risk) * 100),
+        required_bankroll_analytic=analytic_required_bankroll(
+            win_rate, std_dev, target_risk
+        ),
+        median_max_downswing=_percentile(drawdowns, 50),
+        downswings={
+            size: round(sum(worst >= size for worst in drawdowns) / trials, 4)
+            for size in downswings
+        },
+        bands=bands,
+    )
+
+
+def format_result(result: VarianceResult) -> str:
+    required = result.required_bankroll_analytic
+    lines = [
+        f"Win rate {result.win_rate:g} bb/100, std dev {result.std_dev:g} bb/100, "
+        f"bankroll {result.bankroll:g} bb, {result.hands:,} hands x {result.trials:,}",
+        f"Expected result:        {result.expected_result:+,.1f} bb",
+        f"Risk of ruin:           {result.risk_of_ruin:.2%} over the horizon, "
+        f"{result.risk_of_ruin_analytic:.2%} forever",
+        f"Bankroll for {result.target_risk:.0%} risk:   "
+        f"{result.required_bankroll:,.0f} bb over the horizon, "
+        + ("never enough" if required is None else f"{required:,.0f} bb forever"),
+        f"Median worst downswing: {result.median_max_downswing:,.0f} bb",
+    ]
+    for size, chance in result.downswings.items():
+        lines.append(f"  downswing >= {size:>6,} bb: {chance:.1%}")
+    lines.append("Result percentiles (bb):")
+    bands = result.bands
+    for idx in sorted({0, len(bands["hands"]) // 2, len(bands["hands"]) - 1}):
+        values = " ".join(
+            f"p{pct}={bands[f'p{pct}'][idx]:+,.0f}" for pct in BAND_PERCENTILES
+        )
+        lines.append(f"  {bands['hands'][idx]:>9,} hands: {values}")
+    return "\n".join(lines)
+
+
+def add_arguments(parser: argparse.ArgumentParser):
+    parser.add_argument("--win-rate", type=float, required=True, help="bb/100")
+    parser.add_argument("--std-dev", type=float, required=True, help="bb/100")
+    parser.add_argument("--bankroll", type=float, required=True, help="In big blinds")
+    parser.add_argument("--hands", type=int, default=DEFAULT_HANDS)
+    parser.add_argument("--trials", type=int, default=DEFAULT_TRIALS)
+    parser.add_argument("--target-risk", type=float, default=DEFAULT_TARGET_RISK)
+    parser.add_argument(
+        "--downswing",
+        type=int,
+        action="append",
+        help="Downswing size in bb to report (repeatable)",
+    )
+    parser.add_argument("--seed", type=int)
+    parser.add_argument("--json", action="store_true", help="Print JSON")
+
+
+def run(args: argparse.Namespace):
+    try:
+        result = simulate(
+            args.win_rate,
+            args.std_dev,
+            args.bankroll,
+            hands=args.hands,
+            trials=args.trials,
+            target_risk=args.target_risk,
+            downswings=args.downswing or DEFAULT_DOWNSWINGS,
+            seed=args.seed,
+        )
+    except ValueError as exc:
+        raise SystemExit(f"error: {exc}") from None
+    if args.json:
+        print(json.dumps(result.to_dict(), indent=2))
+    else:
+        print(format_result(result))
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Variance and risk-of-ruin simulator")
+    add_arguments(parser)
+    run(parser.parse_args())
+
+
+if __name__ == "__main__":
+    main()